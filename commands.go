@@ -0,0 +1,973 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/email"
+	"github.com/perbu/activity/internal/github"
+	"github.com/perbu/activity/internal/llm"
+	"github.com/perbu/activity/internal/secretref"
+	"github.com/perbu/activity/internal/service"
+	"github.com/perbu/activity/internal/web"
+	"gopkg.in/yaml.v3"
+)
+
+// runAnalyze runs incremental analysis for the named repositories, or for all
+// active repositories if none are given, and prints a line per repository
+func runAnalyze(services *service.Services, repoNames []string) error {
+	ctx := context.Background()
+
+	var results []*service.AnalyzeResult
+	if len(repoNames) == 0 {
+		var err error
+		results, err = services.Report.AnalyzeAllNew(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, name := range repoNames {
+			result, err := services.Report.AnalyzeNew(ctx, name)
+			if err != nil {
+				return fmt.Errorf("failed to analyze %s: %w", name, err)
+			}
+			results = append(results, result)
+		}
+	}
+
+	for _, r := range results {
+		if r.NewCommits == 0 {
+			fmt.Printf("%s: up to date (%s)\n", r.RepoName, shortSHA(r.ToSHA))
+			continue
+		}
+		fmt.Printf("%s: analyzed %d new commits (%s..%s), updated %s\n",
+			r.RepoName, r.NewCommits, shortSHA(r.FromSHA), shortSHA(r.ToSHA), strings.Join(r.Weeks, ", "))
+	}
+	return nil
+}
+
+// runAsk answers a question about a repository with the agent, printing
+// tool calls to stderr as the agent works and the answer to stdout. With
+// --reports the question is answered from the stored weekly reports and
+// commit metadata instead, and the weeks used are listed after the answer.
+func runAsk(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("ask", flag.ContinueOnError)
+	reports := fs.Bool("reports", false, "Answer from stored weekly reports instead of running the agent")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: ask [--reports] <repo> <question>")
+	}
+	repoName, question := fs.Arg(0), strings.Join(fs.Args()[1:], " ")
+
+	if *reports {
+		answer, err := services.Chat.Ask(context.Background(), repoName, question, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Println(answer.Answer)
+		if len(answer.Sources) > 0 {
+			weeks := make([]string, 0, len(answer.Sources))
+			for _, src := range answer.Sources {
+				weeks = append(weeks, src.Week)
+			}
+			fmt.Printf("\nSources: %s\n", strings.Join(weeks, ", "))
+		}
+		return nil
+	}
+
+	answer, err := services.Chat.AskAgent(context.Background(), repoName, question, func(e analyzer.ProgressEvent) {
+		if e.Type == analyzer.ProgressStatus {
+			fmt.Fprintln(os.Stderr, e.Text)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(answer)
+	return nil
+}
+
+// runFsck checks the repository clones in the data directory against the
+// database and prints the problems found. It fails if any are left, so it
+// can run from monitoring.
+func runFsck(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "Re-clone missing and broken clones and reset drifted origin URLs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := services.Repo.Fsck(context.Background(), *repair)
+	if err != nil {
+		return err
+	}
+	if len(result.Issues) == 0 {
+		fmt.Printf("Checked %d repositories, no problems found\n", result.Repos)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tPROBLEM\tPATH\tDETAIL")
+	for _, issue := range result.Issues {
+		detail := issue.Detail
+		if issue.Repaired {
+			detail += " (repaired)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Repo, issue.Kind, issue.Path, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	left := result.Unrepaired()
+	fmt.Printf("\nChecked %d repositories, %d problems, %d repaired\n", result.Repos, len(result.Issues), len(result.Issues)-left)
+	if left > 0 {
+		if !*repair {
+			return fmt.Errorf("%d problems found (--repair fixes all but orphaned clones)", left)
+		}
+		return fmt.Errorf("%d problems left", left)
+	}
+	return nil
+}
+
+// runRepo runs the repo describe, set-branch and reclone subcommands
+func runRepo(services *service.Services, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "describe":
+			return runRepoDescribe(services, args[1:])
+		case "set-branch":
+			return runRepoSetBranch(services, args[1:])
+		case "reclone":
+			return runRepoReclone(services, args[1:])
+		}
+	}
+	return fmt.Errorf("usage: repo describe|set-branch|reclone")
+}
+
+// runRepoReclone replaces a repository's clone with a fresh one
+func runRepoReclone(services *service.Services, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: repo reclone <repo>")
+	}
+	if err := services.Repo.Reclone(context.Background(), args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Re-cloned %s\n", args[0])
+	return nil
+}
+
+// runRepoSetBranch switches the branch a repository's reports follow, e.g.
+// after upstream renamed master to main
+func runRepoSetBranch(services *service.Services, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: repo set-branch <repo> <branch>")
+	}
+	if err := services.Repo.SetBranch(context.Background(), args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("%s now follows %s\n", args[0], args[1])
+	return nil
+}
+
+// runRepoDescribe regenerates the descriptions of the named repositories, or
+// of all active repositories if none are given; with --auto-refresh only
+// descriptions whose README changed since they were generated are
+// regenerated.
+func runRepoDescribe(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("repo describe", flag.ContinueOnError)
+	autoRefresh := fs.Bool("auto-refresh", false, "Only regenerate descriptions whose README changed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var results []*service.DescribeResult
+	if fs.NArg() == 0 {
+		var err error
+		results, err = services.Repo.DescribeAll(ctx, *autoRefresh)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, name := range fs.Args() {
+			result, err := services.Repo.Describe(ctx, name, *autoRefresh)
+			if err != nil {
+				return fmt.Errorf("failed to describe %s: %w", name, err)
+			}
+			results = append(results, result)
+		}
+	}
+
+	for _, r := range results {
+		if !r.Regenerated {
+			fmt.Printf("%s: README unchanged\n", r.Name)
+			continue
+		}
+		fmt.Printf("%s: %s\n", r.Name, r.Description)
+	}
+	return nil
+}
+
+// runReport runs the report generate and diff subcommands
+func runReport(services *service.Services, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "generate":
+			return runReportGenerate(services, args[1:])
+		case "diff":
+			return runReportDiff(services, args)
+		case "list":
+			return runReportList(services, args[1:])
+		}
+	}
+	return fmt.Errorf("usage: report generate|diff|list")
+}
+
+// runReportGenerate generates weekly reports for a repository, or for all
+// active repositories if none is given. With --dry-run it prints the commits
+// and the estimated tokens and cost of each report instead, without calling
+// the LLM.
+func runReportGenerate(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("report generate", flag.ContinueOnError)
+	week := fs.String("week", "", "ISO week to generate, like 2026-W02 (default: the previous week)")
+	since := fs.String("since", "", "Generate all weeks since this date (YYYY-MM-DD)")
+	force := fs.Bool("force", false, "Regenerate existing reports")
+	dryRun := fs.Bool("dry-run", false, "Estimate tokens and cost without calling the LLM")
+
+	// Flags may come before or after the repository name
+	var repos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		repos = append(repos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(repos) > 1 {
+		return fmt.Errorf("usage: report generate [repo] [--week W | --since YYYY-MM-DD] [--force] [--dry-run]")
+	}
+	opts := service.GenerateOptions{Week: *week, Since: *since, Force: *force}
+	if len(repos) == 1 {
+		opts.RepoName = repos[0]
+	}
+	ctx := context.Background()
+
+	if *dryRun {
+		plan, err := services.Report.Plan(ctx, opts)
+		if err != nil {
+			return err
+		}
+		printPlan(plan)
+		return nil
+	}
+
+	results, err := services.Report.Generate(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Printf("%s %s: %d generated, %d skipped, %d without commits\n",
+			r.RepoName, r.WeekLabel, r.Generated, r.Skipped, r.NoCommits)
+	}
+	return nil
+}
+
+// printPlan prints a line per planned report and the totals
+func printPlan(plan []*service.PlannedReport) {
+	var reports, commits, promptTokens int
+	var minCost, maxCost float64
+	for _, p := range plan {
+		switch {
+		case p.Skipped:
+			fmt.Printf("%s %s: exists, skipped (use --force to regenerate)\n", p.RepoName, p.WeekLabel)
+			continue
+		case !p.Generate():
+			fmt.Printf("%s %s: no commits", p.RepoName, p.WeekLabel)
+			if p.Automated > 0 {
+				fmt.Printf(" (%d automated)", p.Automated)
+			}
+			fmt.Println()
+			continue
+		}
+
+		est := p.Estimate
+		fmt.Printf("%s %s: %d commits, %d prompt tokens", p.RepoName, p.WeekLabel, p.Commits, est.PromptTokens)
+		if est.AgentMode {
+			fmt.Printf(", up to %d diff fetches (%d tokens)", est.DiffFetches, est.DiffTokens)
+		}
+		if est.Batches > 0 {
+			fmt.Printf(", in %d batches", est.Batches)
+		}
+		fmt.Printf(", %s\n", formatCostRange(est.MinCost, est.MaxCost))
+
+		reports++
+		commits += p.Commits
+		promptTokens += est.PromptTokens
+		minCost += est.MinCost
+		maxCost += est.MaxCost
+	}
+	fmt.Printf("Total: %d reports, %d commits, %d prompt tokens, %s\n",
+		reports, commits, promptTokens, formatCostRange(minCost, maxCost))
+}
+
+// formatCostRange formats an estimated cost in USD, as a range if it varies
+func formatCostRange(minCost, maxCost float64) string {
+	if maxCost > minCost {
+		return fmt.Sprintf("$%.4f-$%.4f", minCost, maxCost)
+	}
+	return fmt.Sprintf("$%.4f", minCost)
+}
+
+// runReportDiff runs "report diff <repo> [week]", which prints a weekly
+// report (the latest if no week is given) next to the previous week's with a
+// description of what changed
+func runReportDiff(services *service.Services, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("usage: report diff <repo> [week]")
+	}
+	var week string
+	if len(args) == 3 {
+		week = args[2]
+	}
+
+	comparison, err := services.Report.CompareWeek(context.Background(), args[1], week)
+	if comparison == nil {
+		return err
+	}
+
+	current := comparison.Current
+	if comparison.Previous == nil {
+		return fmt.Errorf("no report for the week before %d-W%02d", current.Year, current.Week)
+	}
+	for _, report := range []*db.WeeklyReport{comparison.Previous, current} {
+		fmt.Printf("# %s %d-W%02d (%d commits)\n\n%s\n\n", comparison.Repo.Name, report.Year, report.Week,
+			report.CommitCount, strings.TrimSpace(report.Summary.String))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# What changed\n\n%s\n", comparison.Delta)
+	return nil
+}
+
+// runReportList runs "report list [repo]", which prints a page of weekly
+// reports, newest first, optionally filtered by year and commit count
+func runReportList(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("report list", flag.ContinueOnError)
+	year := fs.Int("year", 0, "Only list reports of this ISO year")
+	minCommits := fs.Int("min-commits", 0, "Only list reports with at least this many commits")
+	limit := fs.Int("limit", 20, "Reports per page")
+	page := fs.Int("page", 1, "Page to list")
+
+	// Flags may come before or after the repository name
+	var repos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		repos = append(repos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(repos) > 1 || *limit < 1 || *page < 1 {
+		return fmt.Errorf("usage: report list [repo] [--year Y] [--min-commits N] [--limit N] [--page N]")
+	}
+	var repoName string
+	if len(repos) == 1 {
+		repoName = repos[0]
+	}
+
+	filter := db.ReportFilter{
+		Year:       *year,
+		MinCommits: *minCommits,
+		Limit:      *limit,
+		Offset:     (*page - 1) * *limit,
+	}
+	reports, total, err := services.Report.ListPage(context.Background(), repoName, filter)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		fmt.Printf("No reports found (%d matching)\n", total)
+		return nil
+	}
+
+	repoNames := make(map[int64]string)
+	all, err := services.Repo.List(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	for _, repo := range all {
+		repoNames[repo.ID] = repo.Name
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWEEK\tREPO\tCOMMITS\tGENERATED")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%d\t%d-W%02d\t%s\t%d\t%s\n", r.ID, r.Year, r.Week, repoNames[r.RepoID],
+			r.CommitCount, r.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nShowing %d-%d of %d", filter.Offset+1, filter.Offset+len(reports), total)
+	if filter.Offset+len(reports) < total {
+		fmt.Printf(" (next: --page %d)", *page+1)
+	}
+	fmt.Println()
+	return nil
+}
+
+// runNewsletter runs the newsletter send subcommand
+func runNewsletter(services *service.Services, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "send":
+			return runNewsletterSend(services, args[1:])
+		case "subscriber":
+			if len(args) > 1 && args[1] == "import" {
+				return runSubscriberImport(services, args[2:])
+			}
+			if len(args) > 1 && args[1] == "export" {
+				return runSubscriberExport(services, args[2:])
+			}
+		}
+	}
+	return fmt.Errorf("usage: newsletter send|subscriber import|subscriber export")
+}
+
+// runNewsletterSend sends newsletters for weeks that ended within --since to
+// all subscribers, ignoring their send schedule, or to the subscriber --to
+// only. With --test, the newsletter of --as (default: --to) is sent to the
+// --to address only and not recorded as sent, so --to need not subscribe.
+func runNewsletterSend(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("newsletter send", flag.ContinueOnError)
+	sinceStr := fs.String("since", "7d", "Send reports for weeks that ended within this period, like 7d or 2w")
+	dryRun := fs.Bool("dry-run", false, "Print what would be sent without sending")
+	to := fs.String("to", "", "Send only to this address")
+	test := fs.Bool("test", false, "Send a test to the --to address without recording it as sent")
+	as := fs.String("as", "", "With --test, send the newsletter of this subscriber (default: --to)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (*test && *to == "") || (*as != "" && !*test) {
+		return fmt.Errorf("usage: newsletter send [--since D] [--dry-run] [--to ADDR [--test [--as SUBSCRIBER]]]")
+	}
+	since, err := service.ParseSinceDuration(*sinceStr)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	ctx := context.Background()
+
+	switch {
+	case *test:
+		subscriber := *as
+		if subscriber == "" {
+			subscriber = *to
+		}
+		return services.Newsletter.SendTest(ctx, subscriber, *to, since, *dryRun, os.Stdout)
+	case *to != "":
+		return services.Newsletter.SendTo(ctx, *to, since, *dryRun, "", os.Stdout)
+	}
+
+	result, err := services.Newsletter.Send(ctx, since, *dryRun, "", os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Sent %d newsletters to %d subscribers (skipped %d, errors %d)\n",
+		result.Sent, result.TotalSubscribers, result.Skipped, result.Errors)
+	return nil
+}
+
+// runSubscriberImport adds the subscribers listed in a CSV file ("-" for
+// stdin), printing a summary and the rows that failed
+func runSubscriberImport(services *service.Services, args []string) error {
+	fs := flag.NewFlagSet("newsletter subscriber import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Check the file without adding subscribers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: newsletter subscriber import [--dry-run] <file.csv>")
+	}
+
+	in := os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	result, err := services.Newsletter.ImportSubscribers(context.Background(), in, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Printf("Line %d %s: %s\n", rowErr.Line, rowErr.Email, rowErr.Err)
+	}
+	for _, addr := range result.Duplicates {
+		fmt.Printf("Skipped %s: already subscribed\n", addr)
+	}
+	verb := "Added"
+	if result.DryRun {
+		verb = "Would add"
+	}
+	fmt.Printf("%s %d subscribers (%d duplicates skipped, %d errors)\n",
+		verb, result.Added, len(result.Duplicates), len(result.Errors))
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d rows were not imported", len(result.Errors))
+	}
+	return nil
+}
+
+// runSubscriberExport writes the subscribers as CSV to a file, or stdout
+func runSubscriberExport(services *service.Services, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: newsletter subscriber export [file.csv]")
+	}
+	if len(args) == 0 || args[0] == "-" {
+		return services.Newsletter.ExportSubscribers(context.Background(), os.Stdout)
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", args[0], err)
+	}
+	if err := services.Newsletter.ExportSubscribers(context.Background(), f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", args[0], err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported subscribers to %s\n", args[0])
+	return nil
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if sha == "" {
+		return "start of week"
+	}
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// configCheckTimeout bounds each live credential check
+const configCheckTimeout = 30 * time.Second
+
+// runConfig runs the config check and show subcommands
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			if len(args) == 1 {
+				return runConfigCheck(cfg)
+			}
+		case "show":
+			return runConfigShow(cfg, args[1:])
+		}
+	}
+	return fmt.Errorf("usage: config check|show [--effective]")
+}
+
+// runConfigShow prints the loaded config as YAML with secrets redacted, or
+// with --effective a line per setting with its value, where the value came
+// from (flag > env > file > default) and the environment variable that sets it
+func runConfigShow(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	effective := fs.Bool("effective", false, "Show each setting's source and environment variable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !*effective {
+		out, err := yaml.Marshal(cfg.Redacted())
+		if err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE\tENV")
+	for _, s := range cfg.Settings() {
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+		if first, _, multiline := strings.Cut(value, "\n"); multiline || len(value) > 60 {
+			value = first[:min(len(first), 57)] + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, value, s.Source, s.Env)
+	}
+	return w.Flush()
+}
+
+// runConfigCheck resolves secret references, validates the config and any
+// template overrides, and then verifies the LLM, email provider and GitHub
+// App credentials with lightweight live calls. It prints a line per check and fails if any check failed.
+func runConfigCheck(cfg *config.Config) error {
+	failed := false
+	report := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Printf("%-10s FAIL %v\n", name, err)
+			return
+		}
+		fmt.Printf("%-10s ok\n", name)
+	}
+
+	report("secrets", cfg.ResolveSecrets(context.Background(), secretref.Resolve))
+
+	if err := cfg.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			report("config", errors.New(line))
+		}
+	} else {
+		report("config", nil)
+	}
+
+	if dir := cfg.GetTemplatesDir(); dir != "" {
+		if _, err := web.ParseTemplates(cfg.GetBasePath(), dir); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				report("templates", errors.New(line))
+			}
+		} else {
+			report("templates", nil)
+		}
+	}
+
+	ctx := context.Background()
+	check := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(ctx, configCheckTimeout)
+		defer cancel()
+		report(name, fn(ctx))
+	}
+
+	check("llm", func(ctx context.Context) error {
+		client, err := llm.NewClient(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if _, err := client.GenerateText(ctx, "Reply with the single word OK."); err != nil {
+			return err
+		}
+		if cfg.UsesEmbeddings() {
+			if _, err := client.Embed(ctx, []string{"ok"}, llm.TaskQuery); err != nil {
+				return fmt.Errorf("embeddings: %w", err)
+			}
+		}
+		return nil
+	})
+
+	if cfg.Newsletter.Enabled {
+		check("email", func(ctx context.Context) error {
+			client, err := service.NewEmailClient(cfg)
+			if err != nil {
+				return err
+			}
+			checker, ok := client.(email.Checker)
+			if !ok {
+				return fmt.Errorf("provider %q cannot be checked", cfg.Newsletter.Provider)
+			}
+			return checker.Check(ctx)
+		})
+	}
+
+	if _, err := cfg.GetGitHubPrivateKey(); cfg.HasGitHubApp() && errors.Is(err, config.ErrNoGitHubPrivateKey) && cfg.HasMasterKey() {
+		// Checking a key stored in the database needs the database
+		fmt.Printf("%-10s skipped, private key not in the config (stored as the %s secret?)\n", "github", service.GitHubPrivateKeySecret)
+	} else if cfg.HasGitHubApp() {
+		check("github", func(ctx context.Context) error {
+			privateKey, err := cfg.GetGitHubPrivateKey()
+			if err != nil {
+				return err
+			}
+			provider, err := github.NewTokenProvider(cfg.GetGitHubAppID(), cfg.GetGitHubInstallationID(), privateKey)
+			if err != nil {
+				return err
+			}
+			_, err = provider.GetToken()
+			return err
+		})
+	}
+
+	if failed {
+		return fmt.Errorf("config check failed")
+	}
+	return nil
+}
+
+// runDB runs the db backup, export and import subcommands
+func runDB(database *db.DB, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: db backup|export|import|prune|status|migrate")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "backup":
+		fs := flag.NewFlagSet("db backup", flag.ContinueOnError)
+		dir := fs.String("dir", filepath.Join(cfg.DataDir, "backups"), "Directory to write the backup to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		path, err := database.Backup(ctx, *dir)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+
+	case "export":
+		fs := flag.NewFlagSet("db export", flag.ContinueOnError)
+		format := fs.String("format", "json", "Export format (json)")
+		output := fs.String("output", "", "Output file (default stdout)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *format != "json" {
+			return fmt.Errorf("unsupported export format: %s", *format)
+		}
+		if *output == "" {
+			return database.ExportJSON(ctx, os.Stdout)
+		}
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		if err := database.ExportJSON(ctx, f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case "import":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: db import <file>")
+		}
+		r, closeFn, err := openExport(args[1])
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if err := database.ImportJSON(ctx, r); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s\n", args[1])
+		return nil
+
+	case "prune":
+		fs := flag.NewFlagSet("db prune", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "Show what would be deleted without deleting it")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		result, err := service.NewRetentionService(database, cfg).Prune(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+		verb := "Deleted"
+		if *dryRun {
+			verb = "Would delete"
+		}
+		fmt.Printf("%s %d activity runs, %d newsletter sends, %d newsletter batches, %d weekly reports, %d report vectors, %d commit vectors\n",
+			verb, result.ActivityRuns, result.NewsletterSends, result.NewsletterBatches, result.WeeklyReports, result.ReportVectors, result.CommitVectors)
+		if result.RawData > 0 {
+			verb = "Cleared"
+			if *dryRun {
+				verb = "Would clear"
+			}
+			fmt.Printf("%s the raw data of %d activity runs\n", verb, result.RawData)
+		}
+		return nil
+
+	case "status":
+		migrations, err := database.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		var version int64
+		pending := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tMIGRATION\tAPPLIED")
+		for _, m := range migrations {
+			applied := "pending"
+			if m.Applied {
+				applied = m.AppliedAt.Local().Format("2006-01-02 15:04")
+				version = m.Version
+			} else {
+				pending++
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, applied)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nSchema version %d, %d pending\n", version, pending)
+		return nil
+
+	case "migrate":
+		fs := flag.NewFlagSet("db migrate", flag.ContinueOnError)
+		to := fs.Int64("to", -1, "Version to migrate up or roll back to (default: the latest; 0 rolls back everything)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *to < 0 {
+			latest, err := database.LatestMigration()
+			if err != nil {
+				return err
+			}
+			*to = latest
+		}
+		ran, err := database.MigrateTo(ctx, *to)
+		for _, m := range ran {
+			verb := "Applied"
+			if !m.Applied {
+				verb = "Rolled back"
+			}
+			fmt.Printf("%s %s\n", verb, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Printf("Schema already at version %d\n", *to)
+			return nil
+		}
+		fmt.Printf("Schema at version %d\n", *to)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown db command: %s", args[0])
+	}
+}
+
+// runSecrets handles the secrets commands, which manage secrets stored
+// encrypted in the database
+func runSecrets(secrets *service.SecretService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: secrets set|list|delete|rotate|keygen")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "set":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: secrets set <name> [file] (reads stdin without a file)")
+		}
+		var value []byte
+		var err error
+		if len(args) == 3 {
+			value, err = os.ReadFile(args[2])
+		} else {
+			value, err = io.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		if err := secrets.Set(ctx, args[1], value); err != nil {
+			return err
+		}
+		fmt.Printf("Stored secret %s\n", args[1])
+		return nil
+
+	case "list":
+		stored, err := secrets.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(stored) == 0 {
+			fmt.Println("No secrets stored")
+			return nil
+		}
+		for _, secret := range stored {
+			key := "current key"
+			if !secrets.IsCurrent(secret) {
+				key = "old key, run secrets rotate"
+			}
+			fmt.Printf("%-30s updated %s (%s)\n", secret.Name, secret.UpdatedAt.Format("2006-01-02 15:04"), key)
+		}
+		return nil
+
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: secrets delete <name>")
+		}
+		if err := secrets.Delete(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted secret %s\n", args[1])
+		return nil
+
+	case "rotate":
+		n, err := secrets.Rotate(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Re-encrypted %d secrets with the current master key\n", n)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown secrets command: %s", args[0])
+	}
+}
+
+// openExport opens an export for reading, transparently decompressing gzipped
+// backups. A path of "-" reads from stdin.
+func openExport(path string) (io.Reader, func(), error) {
+	var f *os.File
+	if path == "-" {
+		f = os.Stdin
+	} else {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open import file: %w", err)
+		}
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to read gzipped import file: %w", err)
+		}
+		return gz, func() { gz.Close(); f.Close() }, nil
+	}
+	return br, func() { f.Close() }, nil
+}
@@ -6,8 +6,10 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/jira"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/runner"
@@ -16,80 +18,108 @@ import (
 	"google.golang.org/genai"
 )
 
-// buildAgentPrompt creates the user prompt for the agent
-func buildAgentPrompt(repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, maxMessageLength int, previousSummary string) string {
+// buildAgentPrompt creates the user prompt for the agent, trimmed to fit the
+// context window alongside the system instruction and the diffs the agent
+// may fetch
+func buildAgentPrompt(repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, tickets []jira.Ticket, submodules []submoduleMove, cfg *config.Config, previousSummary string) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("Repository: %s\n", repo.Name))
-	if repo.Description.Valid && repo.Description.String != "" {
-		sb.WriteString(fmt.Sprintf("About: %s\n", repo.Description.String))
-	}
+	sb.WriteString(repoContext(repo))
 	sb.WriteString(fmt.Sprintf("Branch: %s\n", repo.Branch))
-	sb.WriteString(fmt.Sprintf("Analyzing %d commits\n\n", len(commits)))
+	sb.WriteString(fmt.Sprintf("Analyzing %d commits\n", len(commits)))
+	sb.WriteString(mergeContext(commits))
+	sb.WriteString("\n")
 	sb.WriteString("Commits (newest first):\n\n")
 
-	for i, commit := range commits {
-		sb.WriteString(fmt.Sprintf("Commit %d:\n", i+1))
-		sb.WriteString(fmt.Sprintf("  SHA: %s\n", commit.SHA[:8]))
-		sb.WriteString(fmt.Sprintf("  Author: %s\n", commit.Author))
-		sb.WriteString(fmt.Sprintf("  Date: %s\n", commit.Date.Format("2006-01-02")))
-
-		message := commit.Message
-		truncated := false
-		if len(message) > maxMessageLength {
-			message = message[:maxMessageLength]
-			truncated = true
-		}
-		sb.WriteString(fmt.Sprintf("  Message: %s", message))
-		if truncated {
-			sb.WriteString(" [truncated - use get_full_commit_message for complete text]")
-		}
-		sb.WriteString("\n\n")
-	}
-
-	// Include branch activity if present
-	if len(branchActivity) > 0 {
-		sb.WriteString("## Other Branch Activity\n")
-		sb.WriteString("The following feature branches had commits this week that haven't been merged to the main branch:\n")
-		for _, ba := range branchActivity {
-			sb.WriteString(fmt.Sprintf("- %s: %d commits (", ba.BranchName, ba.CommitCount))
-			first := true
-			for author, count := range ba.AuthorCounts {
-				if !first {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(fmt.Sprintf("%s: %d", author, count))
-				first = false
-			}
-			sb.WriteString(")\n")
-		}
-		sb.WriteString("\nInclude a brief mention of this parallel work in your summary.\n\n")
+	p := &promptBuilder{}
+	p.add(sb.String())
+
+	entry := func(i int, short bool) string {
+		return agentCommitEntry(commits[i], i+1, cfg.LLM.MaxMessageLength, short)
 	}
+	var listing strings.Builder
+	for i := range commits {
+		listing.WriteString(entry(i, false))
+	}
+	p.shrinkable(listing.String(), func(tokens int) (string, string) {
+		return fitCommits(len(commits), entry, tokens)
+	})
+
+	p.optional("referenced tickets", ticketContext(tickets), dropTickets)
+	p.optional("submodule updates", submoduleContext(submodules), dropSubmodules)
 
-	// Include previous week's summary for context
-	if previousSummary != "" {
-		sb.WriteString("## Previous Week's Summary (for context)\n")
-		sb.WriteString(previousSummary)
-		sb.WriteString("\n\nUse this context to maintain narrative continuity and reference ongoing work where relevant.\n\n")
+	p.optional("branch activity", branchActivityContext(branchActivity), dropBranchActivity)
+	p.optional("previous week's summary", previousSummaryContext(previousSummary), dropPreviousSummary)
+
+	p.add("Please analyze these commits and provide a summary.\n")
+
+	system := fmt.Sprintf(cfg.GetAgentSystemPrompt(), cfg.LLM.MaxDiffFetches)
+	reserve := estimateTokens(system) + agentDiffTokens(cfg) + estimatedSummaryTokens
+	return p.build(repo.Name, promptBudget(cfg, reserve))
+}
+
+// agentCommitEntry returns the agent prompt lines describing commit number
+// n, with a long message truncated, or only its subject if short is set
+func agentCommitEntry(commit git.Commit, n, maxMessageLength int, short bool) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Commit %d:\n", n))
+	sb.WriteString(fmt.Sprintf("  SHA: %s\n", commit.SHA[:8]))
+	sb.WriteString(fmt.Sprintf("  Author: %s\n", commit.Author))
+	if len(commit.CoAuthors) > 0 {
+		sb.WriteString(fmt.Sprintf("  Co-authors: %s\n", strings.Join(commit.CoAuthorNames(), ", ")))
 	}
+	sb.WriteString(fmt.Sprintf("  Date: %s\n", commit.Date.Format("2006-01-02")))
 
-	sb.WriteString("Please analyze these commits and provide a summary.\n")
+	message := commit.Message
+	truncated := false
+	if short {
+		var body string
+		message, body, _ = strings.Cut(message, "\n")
+		truncated = strings.TrimSpace(body) != ""
+	}
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength]
+		truncated = true
+	}
+	sb.WriteString(fmt.Sprintf("  Message: %s", message))
+	if truncated {
+		sb.WriteString(" [truncated - use get_full_commit_message for complete text]")
+	}
+	sb.WriteString("\n\n")
 	return sb.String()
 }
 
+// agentDiffTokens returns the most tokens the diffs fetched by the agent can
+// add to its conversation
+func agentDiffTokens(cfg *config.Config) int {
+	fetches := max(cfg.LLM.MaxDiffFetches, 0)
+	perDiff := cfg.LLM.MaxDiffSizeKB * 1024 / bytesPerToken
+	if fetches > 0 && cfg.LLM.MaxTotalTokens > 0 {
+		perDiff = min(perDiff, cfg.LLM.MaxTotalTokens/fetches)
+	}
+	return fetches * perDiff
+}
+
+// diffOptions returns the diff filtering configured for a repository
+func (a *Analyzer) diffOptions(repoName string) git.DiffOptions {
+	return git.DiffOptions{Excludes: a.config.GetRepoConfig(repoName).DiffExcludes}
+}
+
 // createAnalyzerAgent creates an ADK agent with tools for commit analysis
-func (a *Analyzer) createAnalyzerAgent(ctx context.Context, repoPath string, costTracker *CostTracker) (agent.Agent, error) {
-	// Get the Gemini model from the LLM client
-	geminiModel, err := a.llmClient.GetGeminiModel(ctx)
+func (a *Analyzer) createAnalyzerAgent(ctx context.Context, repoPath string, diffOpts git.DiffOptions, costTracker *CostTracker) (agent.Agent, error) {
+	// Get the model (Gemini or Azure OpenAI) from the LLM client
+	llmModel, err := a.llmClient.GetModel(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Gemini model: %w", err)
+		return nil, fmt.Errorf("failed to get LLM model: %w", err)
 	}
 
 	// Create tools
-	diffTool := NewGetCommitDiffTool(repoPath, costTracker)
+	diffTool := NewGetCommitDiffTool(repoPath, diffOpts, a.llmClient, costTracker)
+	diffStatTool := NewGetCommitDiffStatTool(repoPath, costTracker)
 	diffFullTool := NewGetCommitDiffFullTool(repoPath, costTracker)
 	msgTool := NewGetFullCommitMessageTool(repoPath)
-	authorTool := NewGetAuthorStatsTool(repoPath)
+	authorTool := NewGetAuthorStatsTool(repoPath, a.loadAuthorMap(ctx))
 
 	// Get system prompt from config (with default fallback)
 	systemPrompt := a.config.GetAgentSystemPrompt()
@@ -98,15 +128,42 @@ func (a *Analyzer) createAnalyzerAgent(ctx context.Context, repoPath string, cos
 	agentConfig := llmagent.Config{
 		Name:        "git_analyzer",
 		Description: "Analyzes git commits and provides summaries",
-		Model:       geminiModel,
+		Model:       llmModel,
 		Instruction: fmt.Sprintf(systemPrompt, a.config.LLM.MaxDiffFetches),
-		Tools:       []tool.Tool{diffTool, diffFullTool, msgTool, authorTool},
+		Tools:       []tool.Tool{diffTool, diffStatTool, diffFullTool, msgTool, authorTool},
 	}
 
 	// Create the agent
 	return llmagent.New(agentConfig)
 }
 
+// describeToolCall formats an agent tool call as a progress status line
+func describeToolCall(call *genai.FunctionCall) string {
+	for _, key := range []string{"commit_sha", "author_name", "path", "grep"} {
+		if v, ok := call.Args[key].(string); ok {
+			if key == "commit_sha" {
+				v = shortSHA(v)
+			}
+			return fmt.Sprintf("Calling %s(%s)", call.Name, v)
+		}
+	}
+	return fmt.Sprintf("Calling %s", call.Name)
+}
+
+// loadAuthorMap loads author aliases so the agent's author lookups cover every
+// known identity. Failures are logged and result in a nil (empty) map.
+func (a *Analyzer) loadAuthorMap(ctx context.Context) git.AuthorMap {
+	if a.db == nil {
+		return nil
+	}
+	aliases, err := a.db.GetAuthorAliasMap(ctx)
+	if err != nil {
+		slog.Warn("Failed to load author aliases", "error", err)
+		return nil
+	}
+	return git.NewAuthorMap(aliases)
+}
+
 // analyzeWithAgent performs commit analysis using an ADK agent
 func (a *Analyzer) analyzeWithAgent(ctx context.Context, repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (string, *CostTracker, error) {
 	// Create cost tracker
@@ -120,16 +177,32 @@ func (a *Analyzer) analyzeWithAgent(ctx context.Context, repo *db.Repository, co
 	repoPath := db.RepoLocalPath(a.config.DataDir, repo.Name)
 
 	// Create agent
-	agt, err := a.createAnalyzerAgent(ctx, repoPath, costTracker)
+	agt, err := a.createAnalyzerAgent(ctx, repoPath, a.diffOptions(repo.Name), costTracker)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to create agent: %w", err)
 	}
 
 	// Build user prompt
-	userPrompt := buildAgentPrompt(repo, commits, branchActivity, a.config.LLM.MaxMessageLength, previousSummary)
+	userPrompt := buildAgentPrompt(repo, commits, branchActivity, a.referencedTickets(ctx, commits), a.submoduleMoves(ctx, repo, commits), a.config, previousSummary)
 
 	slog.Debug("agent starting analysis", "repo", repo.Name, "commits", len(commits))
+	emitProgress(ctx, ProgressStatus, fmt.Sprintf("Agent analyzing %d commits", len(commits)))
+
+	summary, err := runAgent(ctx, agt, userPrompt)
+	if err != nil {
+		return "", costTracker, err
+	}
 
+	slog.Debug("agent analysis complete", "diffs_fetched", costTracker.GetDiffsFetched(), "tokens", costTracker.GetEstimatedTokens())
+	slog.Info("analysis complete", "repo", repo.Name, "commits", len(commits), "diffs", costTracker.GetDiffsFetched())
+
+	return summary, costTracker, nil
+}
+
+// runAgent runs an agent on a single user message in a fresh in-memory
+// session and returns the text of its final response. Partial responses are
+// streamed as progress events when a progress listener is attached.
+func runAgent(ctx context.Context, agt agent.Agent, userPrompt string) (string, error) {
 	// Create a runner with in-memory session
 	sessionService := session.InMemoryService()
 	r, err := runner.New(runner.Config{
@@ -138,7 +211,7 @@ func (a *Analyzer) analyzeWithAgent(ctx context.Context, repo *db.Repository, co
 		SessionService: sessionService,
 	})
 	if err != nil {
-		return "", costTracker, fmt.Errorf("failed to create runner: %w", err)
+		return "", fmt.Errorf("failed to create runner: %w", err)
 	}
 
 	// Create the session before running
@@ -148,30 +221,47 @@ func (a *Analyzer) analyzeWithAgent(ctx context.Context, repo *db.Repository, co
 		SessionID: "session1",
 	})
 	if err != nil {
-		return "", costTracker, fmt.Errorf("failed to create session: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 
 	// Create user message content
 	userMessage := genai.NewContentFromText(userPrompt, genai.RoleUser)
 
+	// Stream partial responses when a progress listener is attached
+	runConfig := agent.RunConfig{}
+	if progressFromContext(ctx) != nil {
+		runConfig.StreamingMode = agent.StreamingModeSSE
+	}
+
 	// Execute agent with the user message
-	var summary strings.Builder
-	for event, err := range r.Run(ctx, "user1", "session1", userMessage, agent.RunConfig{}) {
+	var text strings.Builder
+	for event, err := range r.Run(ctx, "user1", "session1", userMessage, runConfig) {
 		if err != nil {
-			return "", costTracker, fmt.Errorf("agent execution failed: %w", err)
+			return "", fmt.Errorf("agent execution failed: %w", err)
+		}
+		if event == nil || event.Content == nil {
+			continue
 		}
-		if event != nil && event.Content != nil {
-			// Extract text from all parts in the content
+		// Partial events carry streamed text that is repeated in the final
+		// aggregated event, so they are only forwarded as progress
+		if event.Partial {
 			for _, part := range event.Content.Parts {
-				if part.Text != "" {
-					summary.WriteString(part.Text)
+				if part.Text != "" && !part.Thought {
+					emitProgress(ctx, ProgressText, part.Text)
 				}
 			}
+			continue
+		}
+		// Extract text from all parts in the content
+		for _, part := range event.Content.Parts {
+			if part.FunctionCall != nil {
+				emitProgress(ctx, ProgressStatus, describeToolCall(part.FunctionCall))
+			}
+			if part.Text != "" {
+				text.WriteString(part.Text)
+			}
 		}
 	}
 
-	slog.Debug("agent analysis complete", "diffs_fetched", costTracker.GetDiffsFetched(), "tokens", costTracker.GetEstimatedTokens())
-	slog.Info("analysis complete", "repo", repo.Name, "commits", len(commits), "diffs", costTracker.GetDiffsFetched())
-
-	return summary.String(), costTracker, nil
+	return text.String(), nil
 }
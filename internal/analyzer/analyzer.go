@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/perbu/activity/internal/blob"
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/jira"
 	"github.com/perbu/activity/internal/llm"
 )
 
@@ -18,6 +21,7 @@ type Analyzer struct {
 	llmClient *llm.Client
 	db        *db.DB
 	config    *config.Config
+	blobs     blob.Store // Where raw run data is offloaded (nil keeps it in the database)
 }
 
 // New creates a new Analyzer
@@ -26,17 +30,24 @@ func New(llmClient *llm.Client, database *db.DB, cfg *config.Config) *Analyzer {
 		llmClient: llmClient,
 		db:        database,
 		config:    cfg,
+		blobs:     blob.New(cfg),
 	}
 }
 
 // AnalyzeCommits analyzes a range of commits and returns a summary
-// Routes to either Phase 2 (simple LLM) or Phase 3 (agent) based on config
+// Routes to either Phase 2 (simple LLM) or Phase 3 (agent) based on config,
+// or to batch analysis when there are more than max_commits commits
 // previousSummary provides context from the previous week's report for narrative continuity
 func (a *Analyzer) AnalyzeCommits(ctx context.Context, repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (string, error) {
 	if len(commits) == 0 {
 		return "No new commits to analyze.", nil
 	}
 
+	if needsBatches(a.config, commits) {
+		summary, _, err := a.analyzeInBatches(ctx, repo, commits, branchActivity, previousSummary)
+		return summary, err
+	}
+
 	// Route to agent-based or simple analyzer
 	if a.config.LLM.UseAgent {
 		summary, _, err := a.analyzeWithAgent(ctx, repo, commits, branchActivity, previousSummary)
@@ -49,11 +60,21 @@ func (a *Analyzer) AnalyzeCommits(ctx context.Context, repo *db.Repository, comm
 
 // analyzeWithSimpleLLM performs simple LLM-based analysis (Phase 2)
 func (a *Analyzer) analyzeWithSimpleLLM(ctx context.Context, repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (string, error) {
-	// Build prompt from commits
-	prompt := buildAnalysisPrompt(repo, commits, branchActivity, a.config, previousSummary)
+	// Build prompt from commits and the tickets they reference
+	tickets := a.referencedTickets(ctx, commits)
+	prompt := buildAnalysisPrompt(repo, commits, branchActivity, tickets, a.submoduleMoves(ctx, repo, commits), a.config, previousSummary)
 
-	// Call LLM
-	summary, err := a.llmClient.GenerateText(ctx, prompt)
+	// Call LLM, streaming partial text when a progress listener is attached
+	var summary string
+	var err error
+	if progress := progressFromContext(ctx); progress != nil {
+		emitProgress(ctx, ProgressStatus, fmt.Sprintf("Analyzing %d commits", len(commits)))
+		summary, err = a.llmClient.GenerateTextStream(ctx, prompt, func(chunk string) {
+			progress(ProgressEvent{Type: ProgressText, Text: chunk})
+		})
+	} else {
+		summary, err = a.llmClient.GenerateText(ctx, prompt)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to generate summary: %w", err)
 	}
@@ -61,14 +82,27 @@ func (a *Analyzer) analyzeWithSimpleLLM(ctx context.Context, repo *db.Repository
 	return summary, nil
 }
 
-// AnalyzeAndSave performs analysis and saves to database
+// Analyze starts an activity run and returns it with the analysis results
+// filled in. The run is only completed in the database once the caller saves
+// it with UpdateActivityRun, in the same transaction as the report linking to
+// it; if the report is not saved the caller discards the run with Discard.
 // previousSummary provides context from the previous week's report for narrative continuity
-func (a *Analyzer) AnalyzeAndSave(ctx context.Context, repo *db.Repository, fromSHA, toSHA string, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (*db.ActivityRun, error) {
+func (a *Analyzer) Analyze(ctx context.Context, repo *db.Repository, fromSHA, toSHA string, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (_ *db.ActivityRun, err error) {
+	// Don't leave an incomplete run behind if the caller has already given up
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("analysis cancelled: %w", err)
+	}
+
 	// Create activity run record
-	run, err := a.db.CreateActivityRun(repo.ID, fromSHA, toSHA)
+	run, err := a.db.CreateActivityRun(ctx, repo.ID, fromSHA, toSHA)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create activity run: %w", err)
 	}
+	defer func() {
+		if err != nil {
+			a.Discard(ctx, run)
+		}
+	}()
 
 	// Store metadata as JSON
 	metadata := map[string]interface{}{
@@ -81,11 +115,20 @@ func (a *Analyzer) AnalyzeAndSave(ctx context.Context, repo *db.Repository, from
 	}
 
 	// Track whether agent mode was used
-	run.AgentMode = a.config.LLM.UseAgent
+	run.AgentMode = a.config.LLM.UseAgent && !needsBatches(a.config, commits)
 
 	// Generate summary
 	var summary string
-	if a.config.LLM.UseAgent {
+	if needsBatches(a.config, commits) {
+		// Too many commits for one prompt: summarize them in batches and keep
+		// the batch summaries for inspection
+		var batches []BatchSummary
+		summary, batches, err = a.analyzeInBatches(ctx, repo, commits, branchActivity, previousSummary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze commits in batches: %w", err)
+		}
+		metadata["batches"] = batches
+	} else if a.config.LLM.UseAgent {
 		// Use agent analyzer and capture cost tracking
 		var costTracker *CostTracker
 		summary, costTracker, err = a.analyzeWithAgent(ctx, repo, commits, branchActivity, previousSummary)
@@ -113,104 +156,208 @@ func (a *Analyzer) AnalyzeAndSave(ctx context.Context, repo *db.Repository, from
 
 	// Update run with results
 	run.Summary = sql.NullString{String: summary, Valid: true}
-	run.RawData = sql.NullString{String: string(rawData), Valid: true}
+	run.RawData, run.RawDataRef = a.storeRawData(ctx, run.ID, rawData)
 	run.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
 
-	if err := a.db.UpdateActivityRun(run); err != nil {
-		return nil, fmt.Errorf("failed to update activity run: %w", err)
+	return run, nil
+}
+
+// Discard deletes a run returned by Analyze that is not going to be saved,
+// along with its offloaded raw data. It also works after ctx is cancelled.
+func (a *Analyzer) Discard(ctx context.Context, run *db.ActivityRun) {
+	ctx = context.WithoutCancel(ctx)
+	if run.RawDataRef.Valid && a.blobs != nil {
+		if err := a.blobs.Delete(ctx, run.RawDataRef.String); err != nil {
+			slog.Warn("Failed to delete raw run data", "run", run.ID, "error", err)
+		}
+	}
+	if err := a.db.DeleteActivityRun(ctx, run.ID); err != nil {
+		slog.Warn("Failed to discard activity run", "run", run.ID, "error", err)
 	}
+}
 
-	return run, nil
+// storeRawData returns the raw data of a run to save in the database: the
+// data itself, or a reference to it once written to the blob store. If the
+// blob cannot be written the data stays in the database.
+func (a *Analyzer) storeRawData(ctx context.Context, runID int64, data []byte) (raw, ref sql.NullString) {
+	if a.blobs == nil || len(data) < a.config.Blobs.MinSizeKB<<10 {
+		return sql.NullString{String: string(data), Valid: true}, sql.NullString{}
+	}
+	blobRef, err := a.blobs.Put(ctx, fmt.Sprintf("runs/%d/raw_data.json", runID), data)
+	if err != nil {
+		slog.Warn("Failed to offload raw run data, keeping it in the database", "run", runID, "error", err)
+		return sql.NullString{String: string(data), Valid: true}, sql.NullString{}
+	}
+	return sql.NullString{}, sql.NullString{String: blobRef, Valid: true}
 }
 
-// buildAnalysisPrompt creates the prompt for LLM analysis
-func buildAnalysisPrompt(repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, cfg *config.Config, previousSummary string) string {
+// repoContext returns the prompt lines describing a repository: its
+// generated description and the context notes written by admins, which give
+// the team's own names for components and domain terms
+func repoContext(repo *db.Repository) string {
 	var sb strings.Builder
-
-	sb.WriteString("You are analyzing git commits for a software project.\n\n")
-	sb.WriteString(fmt.Sprintf("Repository: %s\n", repo.Name))
 	if repo.Description.Valid && repo.Description.String != "" {
 		sb.WriteString(fmt.Sprintf("About: %s\n", repo.Description.String))
 	}
-	sb.WriteString(fmt.Sprintf("Branch: %s\n", repo.Branch))
-	sb.WriteString(fmt.Sprintf("Total commits: %d\n\n", len(commits)))
+	if repo.ContextNotes.Valid && repo.ContextNotes.String != "" {
+		sb.WriteString("Context notes from the team (use this vocabulary for components, teams and domain terms):\n")
+		sb.WriteString(repo.ContextNotes.String)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
 
-	sb.WriteString("Commits (newest first):\n\n")
+// mergeContext returns a prompt line separating merged pull requests and
+// merge commits from direct commits, or "" if there are no merges
+func mergeContext(commits []git.Commit) string {
+	stats := git.CountMerges(commits)
+	if stats.PullRequests == 0 && stats.MergeCommits == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Merged pull requests: %d, merge commits: %d, direct commits: %d\n",
+		stats.PullRequests, stats.MergeCommits, stats.DirectCommits)
+}
 
-	// Use configurable max commits limit
-	maxCommits := cfg.LLM.MaxCommits
-	if maxCommits <= 0 {
-		maxCommits = 50 // Fallback to default
+// branchActivityContext returns the prompt section listing unmerged feature
+// branch activity, or "" if there is none
+func branchActivityContext(branchActivity []git.BranchActivity) string {
+	if len(branchActivity) == 0 {
+		return ""
 	}
+	var sb strings.Builder
+	sb.WriteString("## Other Branch Activity\n")
+	sb.WriteString("The following feature branches had commits this week that haven't been merged to the main branch:\n")
+	for _, ba := range branchActivity {
+		sb.WriteString(fmt.Sprintf("- %s: %d commits (", ba.BranchName, ba.CommitCount))
+		first := true
+		for author, count := range ba.AuthorCounts {
+			if !first {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(fmt.Sprintf("%s: %d", author, count))
+			first = false
+		}
+		sb.WriteString(")\n")
+	}
+	sb.WriteString("\nInclude a brief mention of this parallel work in your summary.\n\n")
+	return sb.String()
+}
+
+// previousSummaryContext returns the prompt section with the previous week's
+// summary, or "" if there is none
+func previousSummaryContext(previousSummary string) string {
+	if previousSummary == "" {
+		return ""
+	}
+	return "## Previous Week's Summary (for context)\n" + previousSummary +
+		"\n\nUse this context to maintain narrative continuity and reference ongoing work where relevant.\n\n"
+}
+
+// maxCommitsPerPrompt returns how many commits are listed in one prompt
+func maxCommitsPerPrompt(cfg *config.Config) int {
+	if cfg.LLM.MaxCommits <= 0 {
+		return 50 // Fallback to default
+	}
+	return cfg.LLM.MaxCommits
+}
 
-	limit := len(commits)
-	if limit > maxCommits {
-		limit = maxCommits
+// commitListing returns the prompt lines describing commits, numbered from
+// first, with long messages truncated
+func commitListing(commits []git.Commit, first int, cfg *config.Config) string {
+	var sb strings.Builder
+	for i, commit := range commits {
+		sb.WriteString(commitEntry(commit, first+i, cfg, false))
 	}
+	return sb.String()
+}
 
+// fitCommitListing returns a function shrinking the commitListing of commits
+// to fit a token budget, for promptBuilder.shrinkable
+func fitCommitListing(commits []git.Commit, first int, cfg *config.Config) func(int) (string, string) {
+	return func(tokens int) (string, string) {
+		return fitCommits(len(commits), func(i int, short bool) string {
+			return commitEntry(commits[i], first+i, cfg, short)
+		}, tokens)
+	}
+}
+
+// commitEntry returns the prompt lines describing commit number n, with a
+// long message truncated, or only its subject if short is set
+func commitEntry(commit git.Commit, n int, cfg *config.Config, short bool) string {
 	// Max message length
 	maxMsgLen := cfg.LLM.MaxMessageLength
 	if maxMsgLen <= 0 {
 		maxMsgLen = 1000 // Fallback to default
 	}
 
-	for i := 0; i < limit; i++ {
-		commit := commits[i]
-		sb.WriteString(fmt.Sprintf("Commit %d:\n", i+1))
-		sb.WriteString(fmt.Sprintf("  SHA: %s\n", commit.SHA[:8]))
-		sb.WriteString(fmt.Sprintf("  Author: %s\n", commit.Author))
-		sb.WriteString(fmt.Sprintf("  Date: %s\n", commit.Date.Format("2006-01-02 15:04")))
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Commit %d:\n", n))
+	sb.WriteString(fmt.Sprintf("  SHA: %s\n", commit.SHA[:8]))
+	sb.WriteString(fmt.Sprintf("  Author: %s\n", commit.Author))
+	if len(commit.CoAuthors) > 0 {
+		sb.WriteString(fmt.Sprintf("  Co-authors: %s\n", strings.Join(commit.CoAuthorNames(), ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("  Date: %s\n", commit.Date.Format("2006-01-02 15:04")))
 
-		// Truncate long commit messages
-		message := commit.Message
-		if len(message) > maxMsgLen {
-			message = message[:maxMsgLen] + "... [truncated]"
-		}
-		sb.WriteString(fmt.Sprintf("  Message: %s\n\n", message))
+	// Truncate long commit messages
+	message := commit.Message
+	if short {
+		message, _, _ = strings.Cut(message, "\n")
+	}
+	if len(message) > maxMsgLen {
+		message = message[:maxMsgLen] + "... [truncated]"
 	}
+	sb.WriteString(fmt.Sprintf("  Message: %s\n\n", message))
+	return sb.String()
+}
+
+// buildAnalysisPrompt creates the prompt for LLM analysis, trimmed to fit the
+// context window
+func buildAnalysisPrompt(repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, tickets []jira.Ticket, submodules []submoduleMove, cfg *config.Config, previousSummary string) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are analyzing git commits for a software project.\n\n")
+	sb.WriteString(fmt.Sprintf("Repository: %s\n", repo.Name))
+	sb.WriteString(repoContext(repo))
+	sb.WriteString(fmt.Sprintf("Branch: %s\n", repo.Branch))
+	sb.WriteString(fmt.Sprintf("Total commits: %d\n", len(commits)))
+	sb.WriteString(mergeContext(commits))
+	sb.WriteString("\n")
+
+	sb.WriteString("Commits (newest first):\n\n")
+
+	p := &promptBuilder{}
+	p.add(sb.String())
+
+	// Use configurable max commits limit
+	maxCommits := maxCommitsPerPrompt(cfg)
+	listed := commits[:min(len(commits), maxCommits)]
+	p.shrinkable(commitListing(listed, 1, cfg), fitCommitListing(listed, 1, cfg))
 
 	if len(commits) > maxCommits {
-		sb.WriteString(fmt.Sprintf("... and %d more commits\n\n", len(commits)-maxCommits))
-	}
-
-	// Include branch activity if present
-	if len(branchActivity) > 0 {
-		sb.WriteString("## Other Branch Activity\n")
-		sb.WriteString("The following feature branches had commits this week that haven't been merged to the main branch:\n")
-		for _, ba := range branchActivity {
-			sb.WriteString(fmt.Sprintf("- %s: %d commits (", ba.BranchName, ba.CommitCount))
-			first := true
-			for author, count := range ba.AuthorCounts {
-				if !first {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(fmt.Sprintf("%s: %d", author, count))
-				first = false
-			}
-			sb.WriteString(")\n")
-		}
-		sb.WriteString("\nInclude a brief mention of this parallel work in your summary.\n\n")
+		p.add(fmt.Sprintf("... and %d more commits\n\n", len(commits)-maxCommits))
 	}
 
-	// Include previous week's summary for context
-	if previousSummary != "" {
-		sb.WriteString("## Previous Week's Summary (for context)\n")
-		sb.WriteString(previousSummary)
-		sb.WriteString("\n\nUse this context to maintain narrative continuity and reference ongoing work where relevant.\n\n")
-	}
+	p.optional("referenced tickets", ticketContext(tickets), dropTickets)
+	p.optional("submodule updates", submoduleContext(submodules), dropSubmodules)
+
+	p.optional("branch activity", branchActivityContext(branchActivity), dropBranchActivity)
+	p.optional("previous week's summary", previousSummaryContext(previousSummary), dropPreviousSummary)
 
 	// Use configured prompt (or default)
-	sb.WriteString(cfg.GetPhase2Prompt())
-	sb.WriteString("\n")
+	p.add(cfg.GetPhase2Prompt() + "\n")
 
-	return sb.String()
+	return p.build(repo.Name, promptBudget(cfg, estimatedSummaryTokens))
 }
 
-// extractAuthors gets unique author list from commits
+// extractAuthors gets unique author list from commits, including co-authors
 func extractAuthors(commits []git.Commit) []string {
 	authors := make(map[string]bool)
 	for _, c := range commits {
 		authors[c.Author] = true
+		for _, id := range c.CoAuthors {
+			authors[id.Name] = true
+		}
 	}
 
 	result := make([]string, 0, len(authors))
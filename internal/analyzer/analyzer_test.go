@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"database/sql"
 	"strings"
 	"testing"
@@ -9,6 +10,8 @@ import (
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/jira"
+	"google.golang.org/genai"
 )
 
 func TestShortSHA(t *testing.T) {
@@ -63,6 +66,14 @@ func TestExtractAuthors(t *testing.T) {
 			},
 			want: []string{"John Doe", "Jane Smith", "Bob Wilson"},
 		},
+		{
+			name: "co-authors",
+			commits: []git.Commit{
+				{Author: "John Doe", CoAuthors: []git.Identity{{Name: "Jane Smith"}}},
+				{Author: "Jane Smith"},
+			},
+			want: []string{"John Doe", "Jane Smith"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -111,7 +122,7 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 	}
 
 	t.Run("basic prompt structure", func(t *testing.T) {
-		prompt := buildAnalysisPrompt(repo, commits, nil, cfg, "")
+		prompt := buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, "")
 
 		// Check that key elements are present
 		if !strings.Contains(prompt, "test-repo") {
@@ -141,13 +152,30 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 			Description: sql.NullString{String: "A test repository for testing", Valid: true},
 		}
 
-		prompt := buildAnalysisPrompt(repoWithDesc, commits, nil, cfg, "")
+		prompt := buildAnalysisPrompt(repoWithDesc, commits, nil, nil, nil, cfg, "")
 
 		if !strings.Contains(prompt, "A test repository for testing") {
 			t.Error("prompt should contain repository description")
 		}
 	})
 
+	t.Run("with context notes", func(t *testing.T) {
+		repoWithNotes := &db.Repository{
+			Name:         "test-repo",
+			Branch:       "main",
+			ContextNotes: sql.NullString{String: "Team Falcon owns the ingest pipeline", Valid: true},
+		}
+
+		prompt := buildAnalysisPrompt(repoWithNotes, commits, nil, nil, nil, cfg, "")
+
+		if !strings.Contains(prompt, "Team Falcon owns the ingest pipeline") {
+			t.Error("prompt should contain context notes")
+		}
+		if strings.Contains(buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, ""), "Context notes") {
+			t.Error("prompt without notes should not contain a context notes section")
+		}
+	})
+
 	t.Run("with branch activity", func(t *testing.T) {
 		branchActivity := []git.BranchActivity{
 			{
@@ -158,7 +186,7 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 			},
 		}
 
-		prompt := buildAnalysisPrompt(repo, commits, branchActivity, cfg, "")
+		prompt := buildAnalysisPrompt(repo, commits, branchActivity, nil, nil, cfg, "")
 
 		if !strings.Contains(prompt, "Other Branch Activity") {
 			t.Error("prompt should contain branch activity section")
@@ -171,10 +199,73 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 		}
 	})
 
+	t.Run("with merged pull requests", func(t *testing.T) {
+		if strings.Contains(buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, ""), "Merged pull requests") {
+			t.Error("prompt without merges should not contain merge counts")
+		}
+
+		merged := append([]git.Commit{{
+			SHA:         "0123456789ab",
+			Author:      "Jane Smith",
+			Date:        time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC),
+			Message:     "Add login page (#42)",
+			PullRequest: 42,
+		}}, commits...)
+		prompt := buildAnalysisPrompt(repo, merged, nil, nil, nil, cfg, "")
+
+		if !strings.Contains(prompt, "Merged pull requests: 1, merge commits: 0, direct commits: 2") {
+			t.Error("prompt should separate merged pull requests from direct commits")
+		}
+	})
+
+	t.Run("with referenced tickets", func(t *testing.T) {
+		if strings.Contains(buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, ""), "Referenced Jira Tickets") {
+			t.Error("prompt without tickets should not contain a ticket section")
+		}
+
+		tickets := []jira.Ticket{{Key: "PROJ-42", Summary: "Parser crashes on empty input", Type: "Bug", Status: "Done"}}
+		prompt := buildAnalysisPrompt(repo, commits, nil, tickets, nil, cfg, "")
+
+		if !strings.Contains(prompt, "- PROJ-42 (Bug, Done): Parser crashes on empty input") {
+			t.Error("prompt should list referenced tickets with their summaries")
+		}
+		if !strings.Contains(buildAgentPrompt(repo, commits, nil, tickets, nil, cfg, ""), "PROJ-42 (Bug, Done)") {
+			t.Error("agent prompt should list referenced tickets")
+		}
+	})
+
+	t.Run("with submodule updates", func(t *testing.T) {
+		if strings.Contains(buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, ""), "Submodule Updates") {
+			t.Error("prompt without submodule updates should not contain a submodule section")
+		}
+
+		moves := []submoduleMove{
+			{
+				SubmoduleUpdate: git.SubmoduleUpdate{Path: "lib/parser", From: "aaaaaaaaaa", To: "bbbbbbbbbb", Bumps: 2},
+				Repo:            "parser",
+				Commits:         []git.Commit{{Message: "Handle empty input\n\nDetails", Author: "Jane Smith"}},
+			},
+			{SubmoduleUpdate: git.SubmoduleUpdate{Path: "vendor/ui", URL: "https://example.com/ui.git", From: "cccccccccc", To: "dddddddddd", Bumps: 1}},
+		}
+		prompt := buildAnalysisPrompt(repo, commits, nil, nil, moves, cfg, "")
+		for _, want := range []string{
+			"- lib/parser (tracked repository parser): 1 new commit (aaaaaaaa..bbbbbbbb) in 2 bumps",
+			"  - Handle empty input (Jane Smith)",
+			"- vendor/ui (https://example.com/ui.git): moved cccccccc..dddddddd in 1 bump; its commits are not available",
+		} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt should contain %q", want)
+			}
+		}
+		if !strings.Contains(buildAgentPrompt(repo, commits, nil, nil, moves, cfg, ""), "Submodule Updates") {
+			t.Error("agent prompt should list submodule updates")
+		}
+	})
+
 	t.Run("with previous summary", func(t *testing.T) {
 		previousSummary := "Last week the team focused on bug fixes and code refactoring."
 
-		prompt := buildAnalysisPrompt(repo, commits, nil, cfg, previousSummary)
+		prompt := buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, previousSummary)
 
 		if !strings.Contains(prompt, "Previous Week's Summary") {
 			t.Error("prompt should contain previous summary section header")
@@ -195,7 +286,7 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 			},
 		}
 
-		prompt := buildAnalysisPrompt(repo, commitsWithLongMsg, nil, cfg, "")
+		prompt := buildAnalysisPrompt(repo, commitsWithLongMsg, nil, nil, nil, cfg, "")
 
 		if !strings.Contains(prompt, "[truncated]") {
 			t.Error("long message should be truncated")
@@ -214,7 +305,7 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 			}
 		}
 
-		prompt := buildAnalysisPrompt(repo, manyCommits, nil, cfg, "")
+		prompt := buildAnalysisPrompt(repo, manyCommits, nil, nil, nil, cfg, "")
 
 		// Should mention remaining commits
 		if !strings.Contains(prompt, "... and 10 more commits") {
@@ -223,6 +314,24 @@ func TestBuildAnalysisPrompt(t *testing.T) {
 	})
 }
 
+func TestBuildComparePrompt(t *testing.T) {
+	repo := &db.Repository{Name: "test-repo"}
+	previous := &db.WeeklyReport{Year: 2026, Week: 1, CommitCount: 3}
+	current := &db.WeeklyReport{
+		Year:    2026,
+		Week:    2,
+		Summary: sql.NullString{String: "Finished the parser rewrite.", Valid: true},
+	}
+
+	prompt := buildComparePrompt(repo, previous, current)
+	for _, want := range []string{`"test-repo"`, "Previous week (2026-W01)", "(no summary, 3 commits)",
+		"This week (2026-W02)", "Finished the parser rewrite."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt should contain %q", want)
+		}
+	}
+}
+
 func TestNewAnalyzer(t *testing.T) {
 	cfg := config.DefaultConfig()
 
@@ -235,3 +344,97 @@ func TestNewAnalyzer(t *testing.T) {
 		t.Error("New() did not set config correctly")
 	}
 }
+
+func TestProgressContext(t *testing.T) {
+	ctx := context.Background()
+	if progressFromContext(ctx) != nil {
+		t.Error("progressFromContext() should be nil without WithProgress")
+	}
+	// Emitting without a listener must be a no-op
+	emitProgress(ctx, ProgressStatus, "ignored")
+
+	var events []ProgressEvent
+	ctx = WithProgress(ctx, func(ev ProgressEvent) {
+		events = append(events, ev)
+	})
+	emitProgress(ctx, ProgressStatus, "starting")
+	emitProgress(ctx, ProgressText, "partial")
+
+	if len(events) != 2 || events[0].Type != ProgressStatus || events[1].Text != "partial" {
+		t.Errorf("progress events = %+v", events)
+	}
+}
+
+func TestDescribeToolCall(t *testing.T) {
+	tests := []struct {
+		call *genai.FunctionCall
+		want string
+	}{
+		{&genai.FunctionCall{Name: "get_commit_diff", Args: map[string]any{"commit_sha": "abcdef1234567890"}}, "Calling get_commit_diff(abcdef12)"},
+		{&genai.FunctionCall{Name: "get_author_stats", Args: map[string]any{"author_name": "Jane"}}, "Calling get_author_stats(Jane)"},
+		{&genai.FunctionCall{Name: "other"}, "Calling other"},
+	}
+	for _, tt := range tests {
+		if got := describeToolCall(tt.call); got != tt.want {
+			t.Errorf("describeToolCall() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestBuildBatchPrompts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LLM.MaxCommits = 2
+
+	repo := &db.Repository{Name: "test-repo", Branch: "main"}
+	commits := []git.Commit{
+		{SHA: "aaaa1111", Author: "Jane Smith", Message: "Fourth"},
+		{SHA: "bbbb2222", Author: "John Doe", Message: "Third"},
+		{SHA: "cccc3333", Author: "Jane Smith", Message: "Second"},
+		{SHA: "dddd4444", Author: "Jane Smith", Message: "First"},
+	}
+
+	batches := commitBatches(cfg, commits)
+	if !needsBatches(cfg, commits) || len(batches) != 2 {
+		t.Fatalf("4 commits with max_commits 2 should make 2 batches, got %d", len(batches))
+	}
+
+	prompt := buildBatchPrompt(repo, batches[1], 3, len(commits), nil, cfg)
+	for _, want := range []string{"Commits 3-4 of 4", "Commit 3:", "Commit 4:", "Second", batchInstructions} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("batch prompt should contain %q", want)
+		}
+	}
+	if strings.Contains(prompt, "Fourth") {
+		t.Error("batch prompt should only list the batch's commits")
+	}
+
+	summaries := []BatchSummary{
+		{First: 1, Commits: 2, Summary: "Newest work."},
+		{First: 3, Commits: 2, Summary: "Oldest work."},
+	}
+	prompt = buildSynthesisPrompt(repo, commits, summaries, nil, nil, cfg, "")
+	for _, want := range []string{"Total commits: 4", "Jane Smith (3), John Doe (1)",
+		"### Batch 2 (commits 3-4)", "Oldest work.", cfg.GetPhase2Prompt()} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("synthesis prompt should contain %q", want)
+		}
+	}
+}
+
+func TestSubmoduleURLKey(t *testing.T) {
+	tests := []struct {
+		superURL, subURL, want string
+	}{
+		{"https://github.com/org/app.git", "https://github.com/org/lib.git", "github.com/org/lib"},
+		{"https://github.com/org/app.git", "git@github.com:Org/lib.git", "github.com/org/lib"},
+		{"https://github.com/org/app.git", "ssh://git@github.com/org/lib/", "github.com/org/lib"},
+		{"https://github.com/org/app.git", "../lib.git", "github.com/org/lib"},
+		{"git@github.com:org/app.git", "./tools", "github.com/org/app/tools"},
+		{"", "../lib.git", ""},
+	}
+	for _, tt := range tests {
+		if got := submoduleURLKey(tt.superURL, tt.subURL); got != tt.want {
+			t.Errorf("submoduleURLKey(%q, %q) = %q, want %q", tt.superURL, tt.subURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// Ask answers an ad-hoc question about a repository using an agent with
+// tools for searching history, reading diffs, commit messages, author stats
+// and files in the local clone. Diff fetches and tokens are bounded by the
+// same limits as commit analysis.
+func (a *Analyzer) Ask(ctx context.Context, repo *db.Repository, question string) (string, *CostTracker, error) {
+	costTracker := NewCostTracker(
+		a.config.LLM.MaxDiffFetches,
+		a.config.LLM.MaxDiffSizeKB*1024,
+		a.config.LLM.MaxTotalTokens,
+	)
+	repoPath := db.RepoLocalPath(a.config.DataDir, repo.Name)
+
+	llmModel, err := a.llmClient.GetModel(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get LLM model: %w", err)
+	}
+
+	agt, err := llmagent.New(llmagent.Config{
+		Name:        "repo_assistant",
+		Description: "Answers questions about a git repository",
+		Model:       llmModel,
+		Instruction: fmt.Sprintf(config.DefaultAskSystemPrompt, a.config.LLM.MaxDiffFetches),
+		Tools: []tool.Tool{
+			NewSearchCommitsTool(repoPath),
+			NewGetFullCommitMessageTool(repoPath),
+			NewGetCommitDiffTool(repoPath, a.diffOptions(repo.Name), a.llmClient, costTracker),
+			NewGetCommitDiffStatTool(repoPath, costTracker),
+			NewGetAuthorStatsTool(repoPath, a.loadAuthorMap(ctx)),
+			NewReadFileTool(repoPath, costTracker),
+		},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	prompt := fmt.Sprintf("Repository: %s\n", repo.Name)
+	prompt += repoContext(repo)
+	prompt += fmt.Sprintf("Branch: %s\n\nQuestion: %s\n", repo.Branch, question)
+
+	slog.Debug("agent answering question", "repo", repo.Name)
+
+	answer, err := runAgent(ctx, agt, prompt)
+	if err != nil {
+		return "", costTracker, err
+	}
+
+	slog.Debug("agent answered question", "diffs_fetched", costTracker.GetDiffsFetched(),
+		"files_read", costTracker.GetFilesRead(), "tokens", costTracker.GetEstimatedTokens())
+
+	return answer, costTracker, nil
+}
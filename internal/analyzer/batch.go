@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/jira"
+)
+
+// batchInstructions ends the prompt of each batch of a week analyzed in batches
+const batchInstructions = `Summarize this batch of commits in a few bullet points: the main changes, features and fixes, and who worked on what.
+Mention the short SHAs of notable commits. Do not write an introduction or a conclusion; your notes are combined with
+those of the other batches into the weekly summary.`
+
+// BatchSummary is the summary of one batch of commits of a week that was
+// analyzed in batches. Batch summaries are stored in the run's raw data.
+type BatchSummary struct {
+	First   int    `json:"first"` // Position of the batch's first commit, newest first, from 1
+	Commits int    `json:"commits"`
+	FromSHA string `json:"from_sha"` // Oldest commit of the batch
+	ToSHA   string `json:"to_sha"`   // Newest commit of the batch
+	Summary string `json:"summary"`
+}
+
+// needsBatches reports whether commits are too many for one prompt and are
+// analyzed in batches
+func needsBatches(cfg *config.Config, commits []git.Commit) bool {
+	return len(commits) > maxCommitsPerPrompt(cfg)
+}
+
+// commitBatches splits commits into batches of at most max_commits
+func commitBatches(cfg *config.Config, commits []git.Commit) [][]git.Commit {
+	return slices.Collect(slices.Chunk(commits, maxCommitsPerPrompt(cfg)))
+}
+
+// analyzeInBatches summarizes commits in batches of max_commits (map), then
+// writes the weekly summary from the batch summaries (reduce). Both steps are
+// plain LLM calls in either analysis mode.
+func (a *Analyzer) analyzeInBatches(ctx context.Context, repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) (string, []BatchSummary, error) {
+	batches := commitBatches(a.config, commits)
+	summaries := make([]BatchSummary, 0, len(batches))
+	first := 1
+	for i, batch := range batches {
+		emitProgress(ctx, ProgressStatus, fmt.Sprintf("Summarizing batch %d of %d (%d commits)", i+1, len(batches), len(batch)))
+		prompt := buildBatchPrompt(repo, batch, first, len(commits), a.referencedTickets(ctx, batch), a.config)
+		summary, err := a.llmClient.GenerateText(ctx, prompt)
+		if err != nil {
+			return "", summaries, fmt.Errorf("failed to summarize batch %d of %d: %w", i+1, len(batches), err)
+		}
+		summaries = append(summaries, BatchSummary{
+			First:   first,
+			Commits: len(batch),
+			FromSHA: batch[len(batch)-1].SHA,
+			ToSHA:   batch[0].SHA,
+			Summary: summary,
+		})
+		first += len(batch)
+	}
+
+	prompt := buildSynthesisPrompt(repo, commits, summaries, branchActivity, a.submoduleMoves(ctx, repo, commits), a.config, previousSummary)
+	var summary string
+	var err error
+	if progress := progressFromContext(ctx); progress != nil {
+		emitProgress(ctx, ProgressStatus, fmt.Sprintf("Combining %d batch summaries", len(summaries)))
+		summary, err = a.llmClient.GenerateTextStream(ctx, prompt, func(chunk string) {
+			progress(ProgressEvent{Type: ProgressText, Text: chunk})
+		})
+	} else {
+		summary, err = a.llmClient.GenerateText(ctx, prompt)
+	}
+	if err != nil {
+		return "", summaries, fmt.Errorf("failed to combine batch summaries: %w", err)
+	}
+	return summary, summaries, nil
+}
+
+// buildBatchPrompt creates the prompt summarizing one batch of a week's
+// commits, trimmed to fit the context window; first is the position of the
+// batch's first commit and total the number of commits in the week
+func buildBatchPrompt(repo *db.Repository, batch []git.Commit, first, total int, tickets []jira.Ticket, cfg *config.Config) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are analyzing git commits for a software project. This week has too many commits for one prompt, ")
+	sb.WriteString("so they are summarized in batches first.\n\n")
+	sb.WriteString(fmt.Sprintf("Repository: %s\n", repo.Name))
+	sb.WriteString(repoContext(repo))
+	sb.WriteString(fmt.Sprintf("Branch: %s\n", repo.Branch))
+	sb.WriteString(fmt.Sprintf("Commits %d-%d of %d (newest first):\n\n", first, first+len(batch)-1, total))
+
+	p := &promptBuilder{}
+	p.add(sb.String())
+	p.shrinkable(commitListing(batch, first, cfg), fitCommitListing(batch, first, cfg))
+	p.optional("referenced tickets", ticketContext(tickets), dropTickets)
+	p.add(batchInstructions + "\n")
+
+	return p.build(repo.Name, promptBudget(cfg, estimatedBatchSummaryTokens))
+}
+
+// buildSynthesisPrompt creates the prompt writing the weekly summary from the
+// summaries of a week's batches, trimmed to fit the context window
+func buildSynthesisPrompt(repo *db.Repository, commits []git.Commit, batches []BatchSummary, branchActivity []git.BranchActivity, submodules []submoduleMove, cfg *config.Config, previousSummary string) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are analyzing git commits for a software project.\n\n")
+	sb.WriteString(fmt.Sprintf("Repository: %s\n", repo.Name))
+	sb.WriteString(repoContext(repo))
+	sb.WriteString(fmt.Sprintf("Branch: %s\n", repo.Branch))
+	sb.WriteString(fmt.Sprintf("Total commits: %d\n", len(commits)))
+	sb.WriteString(mergeContext(commits))
+	sb.WriteString(authorContext(commits))
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("## Batch Summaries\nThe commits were too many for one prompt and were summarized in %d batches, newest first:\n\n", len(batches)))
+	for i, b := range batches {
+		sb.WriteString(fmt.Sprintf("### Batch %d (commits %d-%d)\n", i+1, b.First, b.First+b.Commits-1))
+		sb.WriteString(strings.TrimSpace(b.Summary))
+		sb.WriteString("\n\n")
+	}
+
+	p := &promptBuilder{}
+	p.add(sb.String())
+	p.optional("submodule updates", submoduleContext(submodules), dropSubmodules)
+	p.optional("branch activity", branchActivityContext(branchActivity), dropBranchActivity)
+	p.optional("previous week's summary", previousSummaryContext(previousSummary), dropPreviousSummary)
+
+	// Use configured prompt (or default)
+	p.add(cfg.GetPhase2Prompt() + "\n")
+
+	return p.build(repo.Name, promptBudget(cfg, estimatedSummaryTokens))
+}
+
+// authorContext returns a prompt line with each author's commit count, most
+// active first, so the synthesis keeps the week's proportions
+func authorContext(commits []git.Commit) string {
+	counts := make(map[string]int)
+	for _, c := range commits {
+		counts[c.Author]++
+	}
+	authors := slices.SortedFunc(maps.Keys(counts), func(x, y string) int {
+		return cmp.Or(counts[y]-counts[x], strings.Compare(x, y))
+	})
+	parts := make([]string, len(authors))
+	for i, author := range authors {
+		parts[i] = fmt.Sprintf("%s (%d)", author, counts[author])
+	}
+	return "Commits per author: " + strings.Join(parts, ", ") + "\n"
+}
@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+)
+
+// Order in which optional prompt sections are dropped to fit the context
+// window, least important first
+const (
+	dropPreviousSummary = iota + 1
+	dropBranchActivity
+	dropSubmodules
+	dropTickets
+)
+
+// promptSection is a part of a prompt. Sections with a drop rank are left
+// out, lowest rank first, when the prompt is over its token budget; if it is
+// still over, sections with a shrink function are shortened.
+type promptSection struct {
+	name   string // What the section holds, for logging what was dropped
+	text   string
+	drop   int                                 // 0 never drops the section
+	shrink func(tokens int) (text, cut string) // Shortens the section to at most tokens, describing what was cut
+}
+
+// promptBuilder assembles a prompt from sections in order
+type promptBuilder struct {
+	sections []promptSection
+}
+
+// add appends text that is always kept
+func (p *promptBuilder) add(text string) {
+	p.sections = append(p.sections, promptSection{text: text})
+}
+
+// optional appends a section that is dropped with the given rank when the
+// prompt is over budget
+func (p *promptBuilder) optional(name, text string, drop int) {
+	if text != "" {
+		p.sections = append(p.sections, promptSection{name: name, text: text, drop: drop})
+	}
+}
+
+// shrinkable appends a section that is shortened with shrink when dropping
+// the optional sections is not enough
+func (p *promptBuilder) shrinkable(text string, shrink func(tokens int) (string, string)) {
+	p.sections = append(p.sections, promptSection{text: text, shrink: shrink})
+}
+
+// build joins the sections, dropping and shrinking them to fit budget tokens
+// (0 leaves the prompt whole). What was cut is logged for repo.
+func (p *promptBuilder) build(repo string, budget int) string {
+	total := 0
+	for _, s := range p.sections {
+		total += estimateTokens(s.text)
+	}
+
+	var cut []string
+	if budget > 0 && total > budget {
+		byRank := slices.Clone(p.sections)
+		slices.SortStableFunc(byRank, func(a, b promptSection) int { return a.drop - b.drop })
+		dropped := make(map[string]bool)
+		for _, s := range byRank {
+			if total <= budget {
+				break
+			}
+			if s.drop == 0 {
+				continue
+			}
+			dropped[s.name] = true
+			total -= estimateTokens(s.text)
+			cut = append(cut, s.name)
+		}
+
+		var kept []promptSection
+		for _, s := range p.sections {
+			if dropped[s.name] && s.drop != 0 {
+				continue
+			}
+			if s.shrink != nil && total > budget {
+				tokens := estimateTokens(s.text)
+				text, what := s.shrink(max(tokens-(total-budget), 0))
+				total += estimateTokens(text) - tokens
+				s.text = text
+				if what != "" {
+					cut = append(cut, what)
+				}
+			}
+			kept = append(kept, s)
+		}
+		p.sections = kept
+	}
+
+	if len(cut) > 0 {
+		slog.Warn("Trimmed prompt to fit the context window", "repo", repo, "budget_tokens", budget,
+			"estimated_tokens", total, "dropped", strings.Join(cut, "; "))
+	}
+
+	var sb strings.Builder
+	for _, s := range p.sections {
+		sb.WriteString(s.text)
+	}
+	return sb.String()
+}
+
+// promptBudget returns the tokens a prompt may use, leaving reserve tokens of
+// the context window for the rest of the exchange, or 0 if prompts are not
+// trimmed
+func promptBudget(cfg *config.Config, reserve int) int {
+	if cfg.LLM.ContextWindowTokens <= 0 {
+		return 0
+	}
+	return max(cfg.LLM.ContextWindowTokens-reserve, 1)
+}
+
+// fitCommits lists n commits, newest first, in at most tokens: all of them
+// if they fit, else all with only their subjects, else the newest ones that
+// fit. entry formats the i'th commit, with only its subject if short is set.
+// It returns the listing and a description of what was left out.
+func fitCommits(n int, entry func(i int, short bool) string, tokens int) (string, string) {
+	var full, short strings.Builder
+	for i := range n {
+		full.WriteString(entry(i, false))
+		short.WriteString(entry(i, true))
+	}
+	if estimateTokens(full.String()) <= tokens {
+		return full.String(), ""
+	}
+	if estimateTokens(short.String()) <= tokens {
+		return short.String(), "commit message bodies"
+	}
+
+	// Keep room for the line saying how many commits were left out
+	var sb strings.Builder
+	used := estimateTokens(fmt.Sprintf("... and %d older commits left out to fit the context window\n\n", n))
+	listed := 0
+	for ; listed < n; listed++ {
+		e := entry(listed, true)
+		if used+estimateTokens(e) > tokens {
+			break
+		}
+		used += estimateTokens(e)
+		sb.WriteString(e)
+	}
+	fmt.Fprintf(&sb, "... and %d older commits left out to fit the context window\n\n", n-listed)
+	return sb.String(), fmt.Sprintf("commit message bodies and the %d oldest commits", n-listed)
+}
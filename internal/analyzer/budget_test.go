@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+func TestFitCommits(t *testing.T) {
+	entry := func(i int, short bool) string {
+		if short {
+			return fmt.Sprintf("Commit %d: subject\n", i+1)
+		}
+		return fmt.Sprintf("Commit %d: subject\n%s\n", i+1, strings.Repeat("body ", 20))
+	}
+
+	t.Run("everything fits", func(t *testing.T) {
+		listing, cut := fitCommits(3, entry, 1000)
+		if cut != "" || !strings.Contains(listing, "body") {
+			t.Errorf("fitCommits() cut %q, want the full listing", cut)
+		}
+	})
+
+	t.Run("subjects only", func(t *testing.T) {
+		listing, cut := fitCommits(3, entry, 20)
+		if cut != "commit message bodies" {
+			t.Errorf("fitCommits() cut = %q, want commit message bodies", cut)
+		}
+		if strings.Contains(listing, "body") || !strings.Contains(listing, "Commit 3:") {
+			t.Errorf("fitCommits() = %q, want all subjects without bodies", listing)
+		}
+	})
+
+	t.Run("oldest commits left out", func(t *testing.T) {
+		listing, cut := fitCommits(10, entry, 30)
+		if !strings.Contains(listing, "Commit 1:") || strings.Contains(listing, "Commit 10:") {
+			t.Errorf("fitCommits() = %q, want the newest commits only", listing)
+		}
+		if !strings.Contains(listing, "older commits left out") || !strings.Contains(cut, "oldest commits") {
+			t.Errorf("fitCommits() = %q, cut %q, want a note on the left out commits", listing, cut)
+		}
+		if estimateTokens(listing) > 30 {
+			t.Errorf("fitCommits() used %d tokens, want at most 30", estimateTokens(listing))
+		}
+	})
+}
+
+func TestPromptBuilder(t *testing.T) {
+	build := func(budget int) string {
+		p := &promptBuilder{}
+		p.add("header\n")
+		p.optional("tickets", strings.Repeat("t", 400), dropTickets)
+		p.optional("previous week's summary", strings.Repeat("p", 400), dropPreviousSummary)
+		p.optional("empty", "", dropBranchActivity)
+		p.shrinkable(strings.Repeat("c", 400), func(tokens int) (string, string) {
+			return strings.Repeat("c", tokens*bytesPerToken), "commits"
+		})
+		p.add("instructions\n")
+		return p.build("test-repo", budget)
+	}
+
+	if got := build(0); len(got) != 1220 {
+		t.Errorf("build() without a budget = %d bytes, want the whole prompt", len(got))
+	}
+
+	got := build(250)
+	if strings.Contains(got, "pppp") || !strings.Contains(got, "tttt") || !strings.Contains(got, "cccc") {
+		t.Error("build() should drop the previous summary first")
+	}
+
+	got = build(60)
+	if strings.Contains(got, "tttt") {
+		t.Error("build() should drop the tickets and shorten the commits when dropping the summary is not enough")
+	}
+	if !strings.HasPrefix(got, "header\n") || !strings.HasSuffix(got, "instructions\n") {
+		t.Error("build() should keep the required sections")
+	}
+	if estimateTokens(got) > 60 {
+		t.Errorf("build() = %d tokens, want at most 60", estimateTokens(got))
+	}
+}
+
+func TestBuildAnalysisPromptBudget(t *testing.T) {
+	cfg := config.DefaultConfig()
+	repo := &db.Repository{Name: "test-repo", Branch: "main"}
+	commits := []git.Commit{{SHA: "abc123def456", Author: "John Doe", Message: "Add new feature"}}
+	previous := strings.Repeat("Last week was busy. ", 200)
+
+	prompt := buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, previous)
+	if !strings.Contains(prompt, "Last week was busy.") {
+		t.Error("prompt should contain the previous summary when it fits")
+	}
+
+	cfg.LLM.ContextWindowTokens = estimatedSummaryTokens + estimateTokens(prompt) - 500
+	prompt = buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, previous)
+	if strings.Contains(prompt, "Last week was busy.") {
+		t.Error("prompt should drop the previous summary to fit the context window")
+	}
+	if !strings.Contains(prompt, "Add new feature") {
+		t.Error("prompt should keep the commits")
+	}
+}
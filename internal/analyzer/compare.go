@@ -0,0 +1,39 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+)
+
+// CompareWeeks returns a short paragraph describing what changed between a
+// repository's previous and current weekly reports
+func (a *Analyzer) CompareWeeks(ctx context.Context, repo *db.Repository, previous, current *db.WeeklyReport) (string, error) {
+	delta, err := a.llmClient.GenerateText(ctx, buildComparePrompt(repo, previous, current))
+	if err != nil {
+		return "", fmt.Errorf("failed to compare reports: %w", err)
+	}
+	return strings.TrimSpace(delta), nil
+}
+
+// buildComparePrompt creates the prompt for comparing two weekly reports
+func buildComparePrompt(repo *db.Repository, previous, current *db.WeeklyReport) string {
+	return fmt.Sprintf(config.DefaultComparePrompt, repo.Name,
+		weekLabel(previous), reportText(previous), weekLabel(current), reportText(current))
+}
+
+// weekLabel formats a report's ISO week, e.g. "2026-W02"
+func weekLabel(report *db.WeeklyReport) string {
+	return fmt.Sprintf("%d-W%02d", report.Year, report.Week)
+}
+
+// reportText returns a report's summary, or a note if it has none
+func reportText(report *db.WeeklyReport) string {
+	if !report.Summary.Valid || strings.TrimSpace(report.Summary.String) == "" {
+		return fmt.Sprintf("(no summary, %d commits)", report.CommitCount)
+	}
+	return report.Summary.String
+}
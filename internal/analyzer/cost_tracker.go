@@ -22,6 +22,8 @@ type CostTracker struct {
 	// Runtime tracking
 	diffsFetched    int
 	totalDiffBytes  int
+	filesRead       int
+	diffStats       int
 	estimatedTokens int
 	diffFetchLog    []DiffFetchRecord
 }
@@ -61,11 +63,44 @@ func (ct *CostTracker) RecordDiffFetch(sha string, size int, reason string) {
 	})
 }
 
+// CanRead checks if content of the given size can be read within limits.
+// Reads share the per-diff size limit and the total token budget with diffs
+// but do not count against the diff fetch limit.
+func (ct *CostTracker) CanRead(size int) (bool, string) {
+	if size > ct.maxDiffSizeBytes {
+		return false, fmt.Sprintf("content too large (%d bytes, max %d)", size, ct.maxDiffSizeBytes)
+	}
+	if ct.estimatedTokens >= ct.maxTotalTokens {
+		return false, fmt.Sprintf("reached maximum estimated tokens (%d)", ct.maxTotalTokens)
+	}
+	return true, ""
+}
+
+// RecordFileRead records reading a file's content
+func (ct *CostTracker) RecordFileRead(size int) {
+	ct.filesRead++
+	ct.estimatedTokens += size / 4
+}
+
+// RecordDiffStat records fetching a commit's diffstat. Diffstats share the
+// token budget but do not count against the diff fetch limit.
+func (ct *CostTracker) RecordDiffStat(size int) {
+	ct.diffStats++
+	ct.estimatedTokens += size / 4
+}
+
+// RemainingTokens returns how many estimated tokens are left in the budget
+func (ct *CostTracker) RemainingTokens() int {
+	return max(ct.maxTotalTokens-ct.estimatedTokens, 0)
+}
+
 // GetMetadata returns metadata about cost tracking
 func (ct *CostTracker) GetMetadata() map[string]interface{} {
 	return map[string]interface{}{
 		"diffs_fetched":    ct.diffsFetched,
 		"total_diff_bytes": ct.totalDiffBytes,
+		"files_read":       ct.filesRead,
+		"diff_stats":       ct.diffStats,
 		"estimated_tokens": ct.estimatedTokens,
 		"fetch_log":        ct.diffFetchLog,
 	}
@@ -86,6 +121,11 @@ func (ct *CostTracker) GetTotalDiffBytes() int {
 	return ct.totalDiffBytes
 }
 
+// GetFilesRead returns the number of files read so far
+func (ct *CostTracker) GetFilesRead() int {
+	return ct.filesRead
+}
+
 // GetEstimatedTokens returns the estimated total tokens used
 func (ct *CostTracker) GetEstimatedTokens() int {
 	return ct.estimatedTokens
@@ -312,3 +312,50 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestCanRead(t *testing.T) {
+	ct := NewCostTracker(1, 1000, 300)
+
+	if ok, msg := ct.CanRead(2000); ok || !contains(msg, "too large") {
+		t.Errorf("CanRead(2000) = %v, %q, want false with size message", ok, msg)
+	}
+	if ok, _ := ct.CanRead(800); !ok {
+		t.Error("CanRead(800) = false, want true")
+	}
+
+	// File reads do not use up diff fetches
+	ct.RecordFileRead(800)
+	if ok, _ := ct.CanFetchMore(); !ok {
+		t.Error("CanFetchMore() after file read = false, want true")
+	}
+	if got := ct.GetFilesRead(); got != 1 {
+		t.Errorf("GetFilesRead() = %d, want 1", got)
+	}
+
+	// But they do use up the token budget (800 / 4 = 200, then 400 >= 300)
+	ct.RecordFileRead(800)
+	if ok, msg := ct.CanRead(10); ok || !contains(msg, "tokens") {
+		t.Errorf("CanRead() over budget = %v, %q, want false with token message", ok, msg)
+	}
+}
+
+func TestRecordDiffStat(t *testing.T) {
+	ct := NewCostTracker(1, 1000, 300)
+
+	// Diffstats do not use up diff fetches, only tokens (400 / 4 = 100)
+	ct.RecordDiffStat(400)
+	if ok, _ := ct.CanFetchMore(); !ok {
+		t.Error("CanFetchMore() after diffstat = false, want true")
+	}
+	if got := ct.RemainingTokens(); got != 200 {
+		t.Errorf("RemainingTokens() = %d, want 200", got)
+	}
+	if got := ct.GetMetadata()["diff_stats"].(int); got != 1 {
+		t.Errorf("metadata diff_stats = %d, want 1", got)
+	}
+
+	ct.RecordDiffFetch("sha1", 2000, "test")
+	if got := ct.RemainingTokens(); got != 0 {
+		t.Errorf("RemainingTokens() over budget = %d, want 0", got)
+	}
+}
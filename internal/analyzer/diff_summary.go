@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
+)
+
+// maxDiffSummaryParts caps the LLM calls made to summarize one oversized diff
+const maxDiffSummaryParts = 4
+
+// diffSummaryPrompt asks for a summary of one part of an oversized diff.
+// Arguments: part number, number of parts, commit SHA and the diff.
+const diffSummaryPrompt = `This is part %d of %d of the diff of commit %s, which is too large to read at once.
+Summarize what it changes, file by file, in a few short bullet points. Describe behavior and intent rather than
+individual lines, and say so if a file looks generated or mechanical.
+
+%s`
+
+// diffPart is a run of consecutive files of a diff small enough for one prompt
+type diffPart struct {
+	Paths []string
+	Diff  string
+}
+
+// diffParts groups a diff's files into parts of at most size bytes, in
+// order. A file whose diff alone is larger than size is cut off.
+func diffParts(diff string, size int) []diffPart {
+	var parts []diffPart
+	var current diffPart
+	for _, file := range git.SplitDiff(diff) {
+		text := file.Diff
+		if len(text) > size {
+			text = text[:size] + "\n[rest of this file's diff cut off]\n"
+		}
+		if current.Diff != "" && len(current.Diff)+len(text) > size {
+			parts = append(parts, current)
+			current = diffPart{}
+		}
+		if file.Path != "" {
+			current.Paths = append(current.Paths, file.Path)
+		}
+		current.Diff += text
+	}
+	if current.Diff != "" {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// summarizeDiff summarizes a diff too large for the agent with a plain LLM
+// call per part of at most partSize bytes, reading at most budget bytes and
+// maxDiffSummaryParts parts of it. It returns the combined summary and the
+// number of bytes summarized, which is set even if a call fails.
+func summarizeDiff(ctx context.Context, client *llm.Client, sha, diff string, partSize, budget int) (string, int, error) {
+	parts := diffParts(diff, partSize)
+	var sb strings.Builder
+	summarized := 0
+	for i, part := range parts {
+		if i == maxDiffSummaryParts || summarized+len(part.Diff) > budget {
+			var skipped []string
+			for _, p := range parts[i:] {
+				skipped = append(skipped, p.Paths...)
+			}
+			fmt.Fprintf(&sb, "Not summarized (limit reached): %s\n", strings.Join(skipped, ", "))
+			break
+		}
+		summary, err := client.GenerateText(ctx, fmt.Sprintf(diffSummaryPrompt, i+1, len(parts), shortSHA(sha), part.Diff))
+		if err != nil {
+			return "", summarized, fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(parts), err)
+		}
+		summarized += len(part.Diff)
+		fmt.Fprintf(&sb, "Part %d (%s):\n%s\n\n", i+1, strings.Join(part.Paths, ", "), strings.TrimSpace(summary))
+	}
+	return sb.String(), summarized, nil
+}
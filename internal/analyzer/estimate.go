@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// bytesPerToken approximates tokenization, matching the cost tracker's
+// estimate for diffs
+const bytesPerToken = 4
+
+// estimatedSummaryTokens is the assumed length of a generated summary
+const estimatedSummaryTokens = 800
+
+// estimatedBatchSummaryTokens is the assumed length of the summary of a batch
+// of commits
+const estimatedBatchSummaryTokens = 400
+
+// Estimate is the planned LLM usage for analyzing a set of commits, computed
+// from the prompt that would be sent without calling the LLM
+type Estimate struct {
+	AgentMode    bool
+	Batches      int // Batches the commits are summarized in (0 if analyzed in one prompt)
+	PromptTokens int // System instruction and prompt, or all batch and synthesis prompts
+	DiffFetches  int // Diffs the agent may fetch (0 in simple mode)
+	DiffTokens   int // Upper bound of tokens added by fetched diffs
+	OutputTokens int
+	MinCost      float64 // USD if no diffs are fetched
+	MaxCost      float64 // USD if the agent uses its whole diff budget
+}
+
+// EstimateAnalysis estimates the tokens and cost of analyzing commits with
+// the configured mode and limits. In agent mode every turn resends the
+// conversation, so the upper bound assumes one turn per diff fetch with each
+// diff at the size limit, capped by max_total_tokens. Referenced Jira tickets
+// are not looked up, so their summaries are left out. Commits beyond
+// max_commits are estimated as a batch analysis in either mode.
+func EstimateAnalysis(cfg *config.Config, repo *db.Repository, commits []git.Commit, branchActivity []git.BranchActivity, previousSummary string) *Estimate {
+	est := &Estimate{
+		AgentMode:    cfg.LLM.UseAgent,
+		OutputTokens: estimatedSummaryTokens,
+	}
+
+	if needsBatches(cfg, commits) {
+		est.AgentMode = false
+		first := 1
+		var summaries []BatchSummary
+		for _, batch := range commitBatches(cfg, commits) {
+			est.PromptTokens += estimateTokens(buildBatchPrompt(repo, batch, first, len(commits), nil, cfg))
+			summaries = append(summaries, BatchSummary{First: first, Commits: len(batch)})
+			first += len(batch)
+		}
+		est.Batches = len(summaries)
+		batchTokens := est.Batches * estimatedBatchSummaryTokens
+		est.PromptTokens += estimateTokens(buildSynthesisPrompt(repo, commits, summaries, branchActivity, nil, cfg, previousSummary)) + batchTokens
+		est.OutputTokens += batchTokens
+		est.MinCost = cfg.LLMCost(est.PromptTokens, est.OutputTokens)
+		est.MaxCost = est.MinCost
+		return est
+	}
+
+	if !cfg.LLM.UseAgent {
+		est.PromptTokens = estimateTokens(buildAnalysisPrompt(repo, commits, branchActivity, nil, nil, cfg, previousSummary))
+		est.MinCost = cfg.LLMCost(est.PromptTokens, est.OutputTokens)
+		est.MaxCost = est.MinCost
+		return est
+	}
+
+	system := fmt.Sprintf(cfg.GetAgentSystemPrompt(), cfg.LLM.MaxDiffFetches)
+	est.PromptTokens = estimateTokens(system) + estimateTokens(buildAgentPrompt(repo, commits, branchActivity, nil, nil, cfg, previousSummary))
+
+	fetches := max(cfg.LLM.MaxDiffFetches, 0)
+	est.DiffFetches = fetches
+	est.DiffTokens = agentDiffTokens(cfg)
+	perDiff := 0
+	if fetches > 0 {
+		perDiff = est.DiffTokens / fetches
+	}
+
+	// Turn i sends the prompt plus the i diffs fetched so far
+	maxInput := (fetches+1)*est.PromptTokens + perDiff*fetches*(fetches+1)/2
+	est.MinCost = cfg.LLMCost(est.PromptTokens, est.OutputTokens)
+	est.MaxCost = cfg.LLMCost(maxInput, est.OutputTokens)
+	return est
+}
+
+// estimateTokens approximates the number of tokens in text
+func estimateTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+func TestEstimateAnalysis(t *testing.T) {
+	repo := &db.Repository{Name: "test-repo", Branch: "main"}
+	commits := []git.Commit{
+		{SHA: "abc123def456", Author: "John Doe", Date: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), Message: "Add new feature"},
+		{SHA: "def789ghi012", Author: "Jane Smith", Date: time.Date(2024, 1, 14, 9, 0, 0, 0, time.UTC), Message: "Fix bug in parser"},
+	}
+
+	t.Run("simple mode", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LLM.UseAgent = false
+
+		est := EstimateAnalysis(cfg, repo, commits, nil, "")
+		if est.AgentMode {
+			t.Error("AgentMode should be false")
+		}
+		want := estimateTokens(buildAnalysisPrompt(repo, commits, nil, nil, nil, cfg, ""))
+		if est.PromptTokens != want {
+			t.Errorf("PromptTokens = %d, want %d", est.PromptTokens, want)
+		}
+		if est.DiffFetches != 0 || est.DiffTokens != 0 {
+			t.Errorf("simple mode should not fetch diffs, got %d fetches and %d tokens", est.DiffFetches, est.DiffTokens)
+		}
+		if est.MinCost <= 0 || est.MaxCost != est.MinCost {
+			t.Errorf("costs = %f-%f, want a single positive cost", est.MinCost, est.MaxCost)
+		}
+	})
+
+	t.Run("previous summary adds tokens", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LLM.UseAgent = false
+
+		without := EstimateAnalysis(cfg, repo, commits, nil, "")
+		with := EstimateAnalysis(cfg, repo, commits, nil, "Last week the parser was rewritten.")
+		if with.PromptTokens <= without.PromptTokens {
+			t.Errorf("PromptTokens with previous summary = %d, want more than %d", with.PromptTokens, without.PromptTokens)
+		}
+	})
+
+	t.Run("agent mode", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LLM.UseAgent = true
+		cfg.LLM.MaxDiffFetches = 4
+		cfg.LLM.MaxDiffSizeKB = 8
+		cfg.LLM.MaxTotalTokens = 100000
+
+		est := EstimateAnalysis(cfg, repo, commits, nil, "")
+		if !est.AgentMode {
+			t.Error("AgentMode should be true")
+		}
+		if est.DiffFetches != 4 {
+			t.Errorf("DiffFetches = %d, want 4", est.DiffFetches)
+		}
+		if want := 4 * 8 * 1024 / bytesPerToken; est.DiffTokens != want {
+			t.Errorf("DiffTokens = %d, want %d", est.DiffTokens, want)
+		}
+		if est.MaxCost <= est.MinCost {
+			t.Errorf("MaxCost = %f, want more than MinCost %f", est.MaxCost, est.MinCost)
+		}
+	})
+
+	t.Run("agent diff budget capped by max total tokens", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LLM.UseAgent = true
+		cfg.LLM.MaxDiffFetches = 4
+		cfg.LLM.MaxDiffSizeKB = 100
+		cfg.LLM.MaxTotalTokens = 10000
+
+		est := EstimateAnalysis(cfg, repo, commits, nil, "")
+		if est.DiffTokens != 10000 {
+			t.Errorf("DiffTokens = %d, want 10000", est.DiffTokens)
+		}
+	})
+
+	t.Run("commits beyond max commits are batched", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LLM.UseAgent = true
+		cfg.LLM.MaxCommits = 1
+
+		est := EstimateAnalysis(cfg, repo, commits, nil, "")
+		if est.AgentMode || est.Batches != 2 {
+			t.Errorf("AgentMode = %v, Batches = %d, want a plain analysis in 2 batches", est.AgentMode, est.Batches)
+		}
+		if want := estimatedSummaryTokens + 2*estimatedBatchSummaryTokens; est.OutputTokens != want {
+			t.Errorf("OutputTokens = %d, want %d", est.OutputTokens, want)
+		}
+		if est.DiffFetches != 0 || est.MaxCost != est.MinCost {
+			t.Errorf("batched analysis should not fetch diffs, got %d fetches", est.DiffFetches)
+		}
+	})
+}
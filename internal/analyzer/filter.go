@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perbu/activity/internal/git"
+)
+
+// FilterIgnoredAuthors splits commits into those to analyze and those made by
+// ignored authors (bots such as dependabot or renovate). An author is ignored
+// if their name or email matches an entry case-insensitively; a "*" in an
+// entry matches any run of characters, so "*[bot]" ignores every GitHub App.
+// Ignored co-authors are dropped from the kept commits.
+func FilterIgnoredAuthors(commits []git.Commit, ignored []string) (kept, automated []git.Commit) {
+	if len(ignored) == 0 {
+		return commits, nil
+	}
+
+	kept = make([]git.Commit, 0, len(commits))
+	for _, c := range commits {
+		if isIgnoredAuthor(c.Author, c.Email, ignored) {
+			automated = append(automated, c)
+			continue
+		}
+		if len(c.CoAuthors) > 0 {
+			coAuthors := make([]git.Identity, 0, len(c.CoAuthors))
+			for _, id := range c.CoAuthors {
+				if !isIgnoredAuthor(id.Name, id.Email, ignored) {
+					coAuthors = append(coAuthors, id)
+				}
+			}
+			c.CoAuthors = coAuthors
+		}
+		kept = append(kept, c)
+	}
+	return kept, automated
+}
+
+// FilterIgnoredBranchActivity removes ignored authors from feature branch
+// activity, dropping branches that only contain ignored authors' commits
+func FilterIgnoredBranchActivity(activity []git.BranchActivity, ignored []string) []git.BranchActivity {
+	if len(ignored) == 0 {
+		return activity
+	}
+
+	var filtered []git.BranchActivity
+	for _, ba := range activity {
+		counts := make(map[string]int)
+		var authors []string
+		total := 0
+		for author, count := range ba.AuthorCounts {
+			if isIgnoredAuthor(author, "", ignored) {
+				continue
+			}
+			counts[author] = count
+			authors = append(authors, author)
+			total += count
+		}
+		if total == 0 {
+			continue
+		}
+		filtered = append(filtered, git.BranchActivity{
+			BranchName:   ba.BranchName,
+			CommitCount:  total,
+			Authors:      authors,
+			AuthorCounts: counts,
+		})
+	}
+	return filtered
+}
+
+// AutomatedChangesFootnote returns a markdown footnote summarizing commits
+// excluded from the report, or an empty string if there are none
+func AutomatedChangesFootnote(automated []git.Commit) string {
+	if len(automated) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	for _, c := range automated {
+		counts[c.Author]++
+	}
+	authors := make([]string, 0, len(counts))
+	for author := range counts {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	parts := make([]string, 0, len(authors))
+	for _, author := range authors {
+		parts = append(parts, fmt.Sprintf("%s (%d)", author, counts[author]))
+	}
+
+	noun := "commits"
+	if len(automated) == 1 {
+		noun = "commit"
+	}
+	return fmt.Sprintf("\n\n---\n\n*Automated changes: %d %s by %s not included in this summary.*\n",
+		len(automated), noun, strings.Join(parts, ", "))
+}
+
+// MatchesAuthor reports whether an author name matches any of patterns, as
+// in ignore_authors: case-insensitive, with "*" wildcards
+func MatchesAuthor(name string, patterns []string) bool {
+	return isIgnoredAuthor(name, "", patterns)
+}
+
+// isIgnoredAuthor checks a commit author's name and email against the ignore list
+func isIgnoredAuthor(name, email string, ignored []string) bool {
+	for _, entry := range ignored {
+		if matchAuthorPattern(entry, name) || (email != "" && matchAuthorPattern(entry, email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAuthorPattern reports whether s matches pattern case-insensitively,
+// where "*" matches any run of characters and everything else is literal.
+// Brackets are literal so that names like "dependabot[bot]" match themselves.
+func matchAuthorPattern(pattern, s string) bool {
+	pattern, s = strings.ToLower(pattern), strings.ToLower(s)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	// The first part anchors the start and the last part the end; the parts
+	// in between must appear in order
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
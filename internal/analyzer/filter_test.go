@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/perbu/activity/internal/git"
+)
+
+func TestFilterIgnoredAuthors(t *testing.T) {
+	commits := []git.Commit{
+		{SHA: "a", Author: "John Doe", Email: "john@example.com"},
+		{SHA: "b", Author: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com"},
+		{SHA: "c", Author: "Renovate Bot", Email: "bot@renovateapp.com"},
+	}
+
+	tests := []struct {
+		name          string
+		ignored       []string
+		wantKept      int
+		wantAutomated int
+	}{
+		{"no ignore list", nil, 3, 0},
+		{"match by name", []string{"dependabot[bot]"}, 2, 1},
+		{"match by name case-insensitive", []string{"DEPENDABOT[BOT]"}, 2, 1},
+		{"match by email", []string{"bot@renovateapp.com"}, 2, 1},
+		{"multiple entries", []string{"dependabot[bot]", "bot@renovateapp.com"}, 1, 2},
+		{"wildcard suffix", []string{"*[bot]"}, 2, 1},
+		{"wildcard prefix case-insensitive", []string{"renovate*"}, 2, 1},
+		{"wildcard email domain", []string{"*@renovateapp.com"}, 2, 1},
+		{"wildcard does not match partial literal", []string{"dependabot"}, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, automated := FilterIgnoredAuthors(commits, tt.ignored)
+			if len(kept) != tt.wantKept {
+				t.Errorf("FilterIgnoredAuthors() kept = %d, want %d", len(kept), tt.wantKept)
+			}
+			if len(automated) != tt.wantAutomated {
+				t.Errorf("FilterIgnoredAuthors() automated = %d, want %d", len(automated), tt.wantAutomated)
+			}
+		})
+	}
+}
+
+func TestFilterIgnoredAuthorsCoAuthors(t *testing.T) {
+	commits := []git.Commit{{
+		SHA:    "a",
+		Author: "John Doe",
+		CoAuthors: []git.Identity{
+			{Name: "Jane Smith", Email: "jane@example.com"},
+			{Name: "github-actions[bot]", Email: "41898282+github-actions[bot]@users.noreply.github.com"},
+		},
+	}}
+
+	kept, automated := FilterIgnoredAuthors(commits, []string{"*[bot]"})
+	if len(kept) != 1 || len(automated) != 0 {
+		t.Fatalf("FilterIgnoredAuthors() kept %d, automated %d, want 1 and 0", len(kept), len(automated))
+	}
+	if got := kept[0].CoAuthorNames(); len(got) != 1 || got[0] != "Jane Smith" {
+		t.Errorf("kept co-authors = %v, want [Jane Smith]", got)
+	}
+	if len(commits[0].CoAuthors) != 2 {
+		t.Error("FilterIgnoredAuthors() must not modify the input commits")
+	}
+}
+
+func TestMatchAuthorPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"dependabot[bot]", "dependabot[bot]", true},
+		{"dependabot[bot]", "dependabotb", false},
+		{"*", "anyone", true},
+		{"*[bot]", "github-actions[bot]", true},
+		{"*[bot]", "Jane Doe", false},
+		{"ci-*-bot", "ci-release-bot", true},
+		{"ci-*-bot", "ci-bot", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXcYb", false},
+		{"ab*ba", "aba", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchAuthorPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchAuthorPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFilterIgnoredBranchActivity(t *testing.T) {
+	activity := []git.BranchActivity{
+		{
+			BranchName:   "dependabot/go_modules/x",
+			CommitCount:  2,
+			Authors:      []string{"dependabot[bot]"},
+			AuthorCounts: map[string]int{"dependabot[bot]": 2},
+		},
+		{
+			BranchName:   "feature/login",
+			CommitCount:  3,
+			Authors:      []string{"John Doe", "dependabot[bot]"},
+			AuthorCounts: map[string]int{"John Doe": 2, "dependabot[bot]": 1},
+		},
+	}
+
+	filtered := FilterIgnoredBranchActivity(activity, []string{"dependabot[bot]"})
+	if len(filtered) != 1 {
+		t.Fatalf("FilterIgnoredBranchActivity() returned %d branches, want 1", len(filtered))
+	}
+	if filtered[0].BranchName != "feature/login" || filtered[0].CommitCount != 2 {
+		t.Errorf("FilterIgnoredBranchActivity() = %+v, want feature/login with 2 commits", filtered[0])
+	}
+}
+
+func TestAutomatedChangesFootnote(t *testing.T) {
+	if got := AutomatedChangesFootnote(nil); got != "" {
+		t.Errorf("AutomatedChangesFootnote(nil) = %q, want empty", got)
+	}
+
+	footnote := AutomatedChangesFootnote([]git.Commit{
+		{Author: "renovate[bot]"},
+		{Author: "dependabot[bot]"},
+		{Author: "dependabot[bot]"},
+	})
+	if !strings.Contains(footnote, "3 commits by dependabot[bot] (2), renovate[bot] (1)") {
+		t.Errorf("AutomatedChangesFootnote() = %q", footnote)
+	}
+}
@@ -0,0 +1,40 @@
+package analyzer
+
+import "context"
+
+// Progress event types
+const (
+	ProgressStatus = "status" // human-readable status line (e.g. "fetching diff abc123")
+	ProgressText   = "text"   // partial summary text streamed from the LLM
+)
+
+// ProgressEvent is an incremental update emitted while an analysis is running
+type ProgressEvent struct {
+	Type string
+	Text string
+}
+
+// ProgressFunc receives progress events. It is called synchronously from the
+// analysis goroutine and should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+type progressKey struct{}
+
+// WithProgress returns a context that streams analysis progress to fn.
+// When set, the analyzer requests streaming responses from the LLM.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFromContext returns the progress callback, or nil if none is set
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// emitProgress sends an event to the context's progress callback, if any
+func emitProgress(ctx context.Context, eventType, text string) {
+	if fn := progressFromContext(ctx); fn != nil {
+		fn(ProgressEvent{Type: eventType, Text: text})
+	}
+}
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// maxSubmoduleCommits is how many of a tracked submodule's commits are listed
+// in the prompt
+const maxSubmoduleCommits = 10
+
+// submoduleMove is a submodule pointer update, with the submodule's own
+// commits when it is a tracked repository too
+type submoduleMove struct {
+	git.SubmoduleUpdate
+	Repo    string       // Tracked repository the submodule points to, "" if none
+	Commits []git.Commit // Commits between From and To in Repo, newest first
+}
+
+// submoduleMoves looks up the submodule pointers the commits moved and, for
+// submodules that are tracked repositories, the commits they moved over.
+// Failures are logged and leave out what could not be read.
+func (a *Analyzer) submoduleMoves(ctx context.Context, repo *db.Repository, commits []git.Commit) []submoduleMove {
+	repoCfg := a.config.GetRepoConfig(repo.Name)
+	opts := git.LogOptions{FirstParent: repoCfg.FirstParent, NoMerges: repoCfg.NoMerges}
+	updates, err := git.GetSubmoduleUpdates(db.RepoLocalPath(a.config.DataDir, repo.Name), commits, opts)
+	if err != nil {
+		slog.Warn("Failed to read submodule updates", "repo", repo.Name, "error", err)
+		return nil
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tracked := make(map[string]*db.Repository)
+	repos, err := a.db.ListRepositories(ctx, nil)
+	if err != nil {
+		slog.Warn("Failed to list repositories for submodules", "error", err)
+	}
+	for _, r := range repos {
+		tracked[repoURLKey(r.URL)] = r
+	}
+
+	moves := make([]submoduleMove, 0, len(updates))
+	for _, update := range updates {
+		move := submoduleMove{SubmoduleUpdate: update}
+		sub := tracked[submoduleURLKey(repo.URL, update.URL)]
+		if sub != nil && update.From != "" && update.To != "" {
+			log, err := git.GetCommitRange(db.RepoLocalPath(a.config.DataDir, sub.Name), update.From, update.To)
+			if err != nil {
+				slog.Debug("Failed to resolve submodule commits", "repo", repo.Name, "submodule", update.Path, "error", err)
+			} else {
+				move.Repo = sub.Name
+				move.Commits = log
+			}
+		}
+		moves = append(moves, move)
+	}
+	slog.Debug("Resolved submodule updates", "repo", repo.Name, "submodules", len(moves))
+	return moves
+}
+
+// repoURLKey reduces a repository URL to host/path, so the same repository
+// matches whether it is cloned over https or ssh
+func repoURLKey(url string) string {
+	if url == "" {
+		return ""
+	}
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	} else if host, rest, ok := strings.Cut(url, ":"); ok && !strings.Contains(host, "/") {
+		// scp-like syntax: [user@]host:path
+		url = host + "/" + rest
+	}
+	if at := strings.Index(url, "@"); at >= 0 && at < strings.Index(url, "/") {
+		url = url[at+1:]
+	}
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	return strings.ToLower(url)
+}
+
+// submoduleURLKey returns the repoURLKey of a submodule's URL, resolving
+// URLs relative to the superproject's as git does
+func submoduleURLKey(superURL, subURL string) string {
+	if strings.HasPrefix(subURL, "./") || strings.HasPrefix(subURL, "../") {
+		base := repoURLKey(superURL)
+		if base == "" {
+			return ""
+		}
+		return repoURLKey(path.Join(base, subURL))
+	}
+	return repoURLKey(subURL)
+}
+
+// submoduleContext returns the prompt section describing the week's
+// submodule pointer updates, or "" if there are none
+func submoduleContext(moves []submoduleMove) string {
+	if len(moves) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Submodule Updates\n")
+	sb.WriteString("Some commits move submodule pointers. Describe these by what changed in each submodule, not as opaque \"update submodule\" commits:\n")
+	for _, m := range moves {
+		bumps := fmt.Sprintf("%d bump", m.Bumps)
+		if m.Bumps != 1 {
+			bumps += "s"
+		}
+		switch {
+		case m.From == "":
+			sb.WriteString(fmt.Sprintf("- %s: added at %s\n", submoduleName(m), shortSHA(m.To)))
+		case m.To == "":
+			sb.WriteString(fmt.Sprintf("- %s: removed\n", submoduleName(m)))
+		case m.Repo == "":
+			sb.WriteString(fmt.Sprintf("- %s: moved %s..%s in %s; its commits are not available\n",
+				submoduleName(m), shortSHA(m.From), shortSHA(m.To), bumps))
+		case len(m.Commits) == 0:
+			sb.WriteString(fmt.Sprintf("- %s: moved %s..%s in %s with no new commits (rolled back or switched branch)\n",
+				submoduleName(m), shortSHA(m.From), shortSHA(m.To), bumps))
+		default:
+			commits := fmt.Sprintf("%d new commit", len(m.Commits))
+			if len(m.Commits) != 1 {
+				commits += "s"
+			}
+			sb.WriteString(fmt.Sprintf("- %s: %s (%s..%s) in %s\n",
+				submoduleName(m), commits, shortSHA(m.From), shortSHA(m.To), bumps))
+			for _, c := range m.Commits[:min(len(m.Commits), maxSubmoduleCommits)] {
+				subject, _, _ := strings.Cut(c.Message, "\n")
+				sb.WriteString(fmt.Sprintf("  - %s (%s)\n", subject, c.Author))
+			}
+			if len(m.Commits) > maxSubmoduleCommits {
+				sb.WriteString(fmt.Sprintf("  - ... and %d more\n", len(m.Commits)-maxSubmoduleCommits))
+			}
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// submoduleName describes a submodule by its path and, if known, the tracked
+// repository or URL it points to
+func submoduleName(m submoduleMove) string {
+	switch {
+	case m.Repo != "":
+		return fmt.Sprintf("%s (tracked repository %s)", m.Path, m.Repo)
+	case m.URL != "":
+		return fmt.Sprintf("%s (%s)", m.Path, m.URL)
+	}
+	return m.Path
+}
@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/jira"
+)
+
+// referencedTickets looks up the Jira tickets mentioned in the commits'
+// messages. It returns nil if Jira is not configured. Lookup failures are
+// logged and return the tickets found before the failure.
+func (a *Analyzer) referencedTickets(ctx context.Context, commits []git.Commit) []jira.Ticket {
+	cfg := a.config.Current().Jira
+	if cfg.BaseURL == "" {
+		return nil
+	}
+
+	var messages strings.Builder
+	for _, c := range commits {
+		messages.WriteString(c.Message)
+		messages.WriteString("\n")
+	}
+	keys := jira.Keys(messages.String(), cfg.Projects)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	tickets, err := jira.NewClient(cfg.BaseURL, cfg.Email, a.config.GetJiraToken()).GetTickets(ctx, keys)
+	if err != nil {
+		slog.Warn("Failed to look up Jira tickets", "error", err)
+	}
+	slog.Debug("Resolved Jira tickets", "referenced", len(keys), "found", len(tickets))
+	return tickets
+}
+
+// ticketContext returns the prompt section listing the Jira tickets the
+// commits reference, or "" if there are none
+func ticketContext(tickets []jira.Ticket) string {
+	if len(tickets) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Referenced Jira Tickets\n")
+	sb.WriteString("Commit messages reference these tickets. Use them to explain what the work was for, and refer to tickets by key (e.g. PROJ-123):\n")
+	for _, t := range tickets {
+		sb.WriteString(fmt.Sprintf("- %s (%s, %s): %s\n", t.Key, t.Type, t.Status, t.Summary))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
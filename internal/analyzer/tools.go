@@ -1,12 +1,15 @@
 package analyzer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 
 	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
@@ -23,13 +26,17 @@ func shortSHA(sha string) string {
 // GetCommitDiffTool provides access to commit diffs for the agent
 type GetCommitDiffTool struct {
 	repoPath    string
+	diffOpts    git.DiffOptions
+	llmClient   *llm.Client // Summarizes diffs over the size limit (nil rejects them)
 	costTracker *CostTracker
 }
 
 // NewGetCommitDiffTool creates a new GetCommitDiffTool
-func NewGetCommitDiffTool(repoPath string, costTracker *CostTracker) *GetCommitDiffTool {
+func NewGetCommitDiffTool(repoPath string, diffOpts git.DiffOptions, llmClient *llm.Client, costTracker *CostTracker) *GetCommitDiffTool {
 	return &GetCommitDiffTool{
 		repoPath:    repoPath,
+		diffOpts:    diffOpts,
+		llmClient:   llmClient,
 		costTracker: costTracker,
 	}
 }
@@ -41,7 +48,7 @@ func (t *GetCommitDiffTool) Name() string {
 
 // Description returns the tool description
 func (t *GetCommitDiffTool) Description() string {
-	return "Retrieves the code diff for a specific commit. Vendor directories (vendor/, node_modules/) and lock files are filtered out by default. The response will indicate how many lines were suppressed. Use get_commit_diff_full if you need the complete unfiltered diff. Use ONLY when the commit message is unclear, vague, or lacks sufficient detail to understand what was changed. This is an expensive operation, so use it wisely."
+	return "Retrieves the code diff for a specific commit. Vendor directories (vendor/, node_modules/), lock files and paths excluded for the repository are filtered out, and binary and generated files are listed by name instead of shown. The response will indicate how many lines were suppressed. Diffs over the size limit are summarized file by file instead (diff_summary); use get_commit_diffstat first to gauge a commit's size. Use get_commit_diff_full if you need the complete unfiltered diff. Use ONLY when the commit message is unclear, vague, or lacks sufficient detail to understand what was changed. This is an expensive operation, so use it wisely."
 }
 
 // IsLongRunning returns false as this is a quick operation
@@ -114,7 +121,7 @@ func (t *GetCommitDiffTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}
 
 	// Fetch the diff
-	result, err := git.GetCommitDiff(t.repoPath, commitSHA)
+	result, err := git.GetCommitDiffWithOptions(t.repoPath, commitSHA, t.diffOpts)
 	if err != nil {
 		slog.Debug("diff fetch error", "sha", shortSHA(commitSHA), "error", err)
 		return map[string]any{
@@ -125,14 +132,7 @@ func (t *GetCommitDiffTool) Run(ctx tool.Context, args any) (map[string]any, err
 
 	// Check size limit
 	if len(result.Diff) > t.costTracker.GetMaxDiffSizeBytes() {
-		slog.Debug("diff too large", "sha", shortSHA(commitSHA), "size", len(result.Diff), "max", t.costTracker.GetMaxDiffSizeBytes())
-		return map[string]any{
-			"error":      "Diff too large",
-			"commit_sha": commitSHA,
-			"size_bytes": len(result.Diff),
-			"max_bytes":  t.costTracker.GetMaxDiffSizeBytes(),
-			"message":    "The commit likely involves extensive changes. Consider this when summarizing.",
-		}, nil
+		return t.summarize(ctx, commitSHA, reason, result.Diff), nil
 	}
 
 	// Record the fetch
@@ -149,6 +149,174 @@ func (t *GetCommitDiffTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}, nil
 }
 
+// summarize answers a diff fetch whose diff is over the size limit with a
+// summary of the diff written part by part, or an error if there is no LLM
+// client or budget to summarize it
+func (t *GetCommitDiffTool) summarize(ctx context.Context, commitSHA, reason, diff string) map[string]any {
+	maxBytes := t.costTracker.GetMaxDiffSizeBytes()
+	slog.Debug("diff too large", "sha", shortSHA(commitSHA), "size", len(diff), "max", maxBytes)
+	tooLarge := map[string]any{
+		"error":      "Diff too large",
+		"commit_sha": commitSHA,
+		"size_bytes": len(diff),
+		"max_bytes":  maxBytes,
+		"message":    "The commit likely involves extensive changes. Use get_commit_diffstat to see which files it touched, and consider this when summarizing.",
+	}
+	if t.llmClient == nil {
+		return tooLarge
+	}
+
+	emitProgress(ctx, ProgressStatus, fmt.Sprintf("Summarizing the large diff of %s", shortSHA(commitSHA)))
+	summary, summarized, err := summarizeDiff(ctx, t.llmClient, commitSHA, diff, maxBytes, t.costTracker.RemainingTokens()*4)
+	if summarized > 0 {
+		t.costTracker.RecordDiffFetch(commitSHA, summarized, "summarized: "+reason)
+	}
+	if err != nil {
+		slog.Debug("diff summary error", "sha", shortSHA(commitSHA), "error", err)
+		return tooLarge
+	}
+	if summarized == 0 {
+		return tooLarge
+	}
+	slog.Debug("diff summarized", "sha", shortSHA(commitSHA), "size", len(diff), "summarized", summarized)
+
+	return map[string]any{
+		"commit_sha":   commitSHA,
+		"diff_summary": summary,
+		"size_bytes":   len(diff),
+		"reason":       reason,
+		"note":         "The diff was too large to show, so it was summarized file by file",
+	}
+}
+
+// maxDiffStatFiles is how many files get_commit_diffstat lists, largest changes first
+const maxDiffStatFiles = 100
+
+// GetCommitDiffStatTool lists the files a commit changed with line counts
+type GetCommitDiffStatTool struct {
+	repoPath    string
+	costTracker *CostTracker
+}
+
+// NewGetCommitDiffStatTool creates a new GetCommitDiffStatTool
+func NewGetCommitDiffStatTool(repoPath string, costTracker *CostTracker) *GetCommitDiffStatTool {
+	return &GetCommitDiffStatTool{
+		repoPath:    repoPath,
+		costTracker: costTracker,
+	}
+}
+
+// Name returns the tool name
+func (t *GetCommitDiffStatTool) Name() string {
+	return "get_commit_diffstat"
+}
+
+// Description returns the tool description
+func (t *GetCommitDiffStatTool) Description() string {
+	return "Lists the files a commit changed with lines added and deleted, largest changes first. Cheap, and does not count against the diff fetch limit: use it to gauge the scope of a commit before fetching its diff, or instead of the diff when the file names tell enough."
+}
+
+// IsLongRunning returns false as this is a quick operation
+func (t *GetCommitDiffStatTool) IsLongRunning() bool {
+	return false
+}
+
+// ProcessRequest adds this tool to the LLM request
+func (t *GetCommitDiffStatTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool
+func (t *GetCommitDiffStatTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"commit_sha": {
+					Type:        "string",
+					Description: "The commit SHA (can be full 40-char or shortened 8-char form)",
+				},
+			},
+			Required: []string{"commit_sha"},
+		},
+	}
+}
+
+// Run executes the tool
+func (t *GetCommitDiffStatTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	commitSHA, ok := argsMap["commit_sha"].(string)
+	if !ok {
+		return map[string]any{"error": "commit_sha must be a string"}, nil
+	}
+
+	slog.Debug("tool call", "tool", "get_commit_diffstat", "sha", shortSHA(commitSHA))
+
+	files, err := git.GetCommitDiffStat(t.repoPath, commitSHA)
+	if err != nil {
+		return map[string]any{
+			"error":      fmt.Sprintf("Error fetching diffstat: %v", err),
+			"commit_sha": commitSHA,
+		}, nil
+	}
+
+	stat, additions, deletions := formatDiffStat(files)
+	if ok, msg := t.costTracker.CanRead(len(stat)); !ok {
+		slog.Debug("diffstat denied", "sha", shortSHA(commitSHA), "reason", msg)
+		return map[string]any{
+			"error":      msg,
+			"commit_sha": commitSHA,
+		}, nil
+	}
+	t.costTracker.RecordDiffStat(len(stat))
+
+	return map[string]any{
+		"commit_sha":    commitSHA,
+		"files_changed": len(files),
+		"additions":     additions,
+		"deletions":     deletions,
+		"files":         stat,
+	}, nil
+}
+
+// formatDiffStat lists up to maxDiffStatFiles files, one per line with the
+// largest changes first, and returns the list with the total line counts
+func formatDiffStat(files []git.FileStat) (stat string, additions, deletions int) {
+	for _, f := range files {
+		additions += f.Additions
+		deletions += f.Deletions
+	}
+	sorted := slices.Clone(files)
+	slices.SortStableFunc(sorted, func(a, b git.FileStat) int {
+		return (b.Additions + b.Deletions) - (a.Additions + a.Deletions)
+	})
+
+	var sb strings.Builder
+	for _, f := range sorted[:min(len(sorted), maxDiffStatFiles)] {
+		if f.Binary {
+			fmt.Fprintf(&sb, "%s: binary\n", f.Path)
+		} else {
+			fmt.Fprintf(&sb, "%s: +%d -%d\n", f.Path, f.Additions, f.Deletions)
+		}
+	}
+	if len(sorted) > maxDiffStatFiles {
+		fmt.Fprintf(&sb, "... and %d more files\n", len(sorted)-maxDiffStatFiles)
+	}
+	return sb.String(), additions, deletions
+}
+
 // GetCommitDiffFullTool provides access to unfiltered commit diffs for the agent
 type GetCommitDiffFullTool struct {
 	repoPath    string
@@ -372,13 +540,16 @@ func (t *GetFullCommitMessageTool) Run(ctx tool.Context, args any) (map[string]a
 
 // GetAuthorStatsTool provides author statistics for the agent
 type GetAuthorStatsTool struct {
-	repoPath string
+	repoPath  string
+	authorMap git.AuthorMap
 }
 
-// NewGetAuthorStatsTool creates a new GetAuthorStatsTool
-func NewGetAuthorStatsTool(repoPath string) *GetAuthorStatsTool {
+// NewGetAuthorStatsTool creates a new GetAuthorStatsTool.
+// The author map (may be nil) is used to include all known aliases of an author.
+func NewGetAuthorStatsTool(repoPath string, authorMap git.AuthorMap) *GetAuthorStatsTool {
 	return &GetAuthorStatsTool{
-		repoPath: repoPath,
+		repoPath:  repoPath,
+		authorMap: authorMap,
 	}
 }
 
@@ -389,7 +560,7 @@ func (t *GetAuthorStatsTool) Name() string {
 
 // Description returns the tool description
 func (t *GetAuthorStatsTool) Description() string {
-	return "Retrieves statistics about an author's contributions to the repository, including total commits and when they started contributing. Use this to provide context about contributors in your summary."
+	return "Retrieves statistics about an author's contributions to the repository, including total commits, commits they are credited on as a co-author, and when they started contributing. Use this to provide context about contributors in your summary."
 }
 
 // IsLongRunning returns false as this is a quick operation
@@ -441,7 +612,8 @@ func (t *GetAuthorStatsTool) Run(ctx tool.Context, args any) (map[string]any, er
 
 	slog.Debug("tool call", "tool", "get_author_stats", "author", authorName)
 
-	stats, err := git.GetAuthorStats(t.repoPath, authorName)
+	identities := t.authorMap.Identities(authorName)
+	stats, err := git.GetAuthorStats(t.repoPath, identities[0], identities[1:]...)
 	if err != nil {
 		slog.Debug("author stats error", "author", authorName, "error", err)
 		return map[string]any{
@@ -450,7 +622,7 @@ func (t *GetAuthorStatsTool) Run(ctx tool.Context, args any) (map[string]any, er
 		}, nil
 	}
 
-	if stats.TotalCommits == 0 {
+	if stats.TotalCommits == 0 && stats.CoAuthoredCommits == 0 {
 		slog.Debug("author not found", "author", authorName)
 		return map[string]any{
 			"author_name":   authorName,
@@ -458,14 +630,264 @@ func (t *GetAuthorStatsTool) Run(ctx tool.Context, args any) (map[string]any, er
 			"message":       "No commits found for this author",
 		}, nil
 	}
+	if stats.TotalCommits == 0 {
+		return map[string]any{
+			"author_name":         authorName,
+			"total_commits":       0,
+			"co_authored_commits": stats.CoAuthoredCommits,
+			"message":             "This author has only been credited as a co-author",
+		}, nil
+	}
+
+	slog.Debug("author stats fetched", "author", stats.Name, "commits", stats.TotalCommits, "co_authored", stats.CoAuthoredCommits)
+
+	return map[string]any{
+		"author_name":         stats.Name,
+		"total_commits":       stats.TotalCommits,
+		"co_authored_commits": stats.CoAuthoredCommits,
+		"first_commit":        stats.FirstCommit.Format("2006-01-02"),
+		"last_commit":         stats.LastCommit.Format("2006-01-02"),
+	}, nil
+}
+
+// maxSearchResults caps the number of commits returned by search_commits
+const maxSearchResults = 50
+
+// SearchCommitsTool lets the agent find commits by message, author, path or date
+type SearchCommitsTool struct {
+	repoPath string
+}
+
+// NewSearchCommitsTool creates a new SearchCommitsTool
+func NewSearchCommitsTool(repoPath string) *SearchCommitsTool {
+	return &SearchCommitsTool{
+		repoPath: repoPath,
+	}
+}
+
+// Name returns the tool name
+func (t *SearchCommitsTool) Name() string {
+	return "search_commits"
+}
+
+// Description returns the tool description
+func (t *SearchCommitsTool) Description() string {
+	return "Searches the repository history for commits, newest first. Filter by a regular expression matched against commit messages, an author, a file or directory path, and a date range. Use this to find the commits relevant to a question."
+}
+
+// IsLongRunning returns false as this is a quick operation
+func (t *SearchCommitsTool) IsLongRunning() bool {
+	return false
+}
+
+// ProcessRequest adds this tool to the LLM request
+func (t *SearchCommitsTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool
+func (t *SearchCommitsTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"grep": {
+					Type:        "string",
+					Description: "Case-insensitive extended regular expression matched against commit messages (e.g., 'goose|migration')",
+				},
+				"author": {
+					Type:        "string",
+					Description: "Author name or email to filter by",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Only commits touching this file or directory (e.g., 'internal/db')",
+				},
+				"since": {
+					Type:        "string",
+					Description: "Only commits after this date (e.g., '2025-06-01' or '3 months ago')",
+				},
+				"until": {
+					Type:        "string",
+					Description: "Only commits before this date",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Maximum number of commits to return (default and max %d)", maxSearchResults),
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool
+func (t *SearchCommitsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	opts := git.SearchOptions{Limit: maxSearchResults}
+	for key, field := range map[string]*string{
+		"grep":   &opts.Grep,
+		"author": &opts.Author,
+		"path":   &opts.Path,
+		"since":  &opts.Since,
+		"until":  &opts.Until,
+	} {
+		if v, ok := argsMap[key]; ok {
+			str, ok := v.(string)
+			if !ok {
+				return map[string]any{"error": key + " must be a string"}, nil
+			}
+			*field = str
+		}
+	}
+	if limit, ok := argsMap["limit"].(float64); ok && limit > 0 {
+		opts.Limit = min(int(limit), maxSearchResults)
+	}
+
+	slog.Debug("tool call", "tool", "search_commits", "grep", opts.Grep, "author", opts.Author, "path", opts.Path)
+
+	commits, err := git.SearchCommits(t.repoPath, opts)
+	if err != nil {
+		slog.Debug("commit search error", "error", err)
+		return map[string]any{"error": fmt.Sprintf("Error searching commits: %v", err)}, nil
+	}
+
+	results := make([]map[string]any, 0, len(commits))
+	for _, c := range commits {
+		results = append(results, map[string]any{
+			"commit_sha": shortSHA(c.SHA),
+			"author":     c.Author,
+			"date":       c.Date.Format("2006-01-02"),
+			"subject":    c.Message,
+		})
+	}
+
+	slog.Debug("commits found", "count", len(results))
+
+	return map[string]any{
+		"count":   len(results),
+		"commits": results,
+	}, nil
+}
+
+// ReadFileTool lets the agent read files and list directories at HEAD
+type ReadFileTool struct {
+	repoPath    string
+	costTracker *CostTracker
+}
+
+// NewReadFileTool creates a new ReadFileTool
+func NewReadFileTool(repoPath string, costTracker *CostTracker) *ReadFileTool {
+	return &ReadFileTool{
+		repoPath:    repoPath,
+		costTracker: costTracker,
+	}
+}
+
+// Name returns the tool name
+func (t *ReadFileTool) Name() string {
+	return "read_file"
+}
+
+// Description returns the tool description
+func (t *ReadFileTool) Description() string {
+	return "Reads a file from the current version of the repository, or lists a directory if the path is a directory (use an empty path for the root). File size is limited and counts against the token budget."
+}
+
+// IsLongRunning returns false as this is a quick operation
+func (t *ReadFileTool) IsLongRunning() bool {
+	return false
+}
+
+// ProcessRequest adds this tool to the LLM request
+func (t *ReadFileTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool
+func (t *ReadFileTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"path": {
+					Type:        "string",
+					Description: "File or directory path relative to the repository root (e.g., 'go.mod' or 'internal/db')",
+				},
+			},
+			Required: []string{"path"},
+		},
+	}
+}
+
+// Run executes the tool
+func (t *ReadFileTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	path, ok := argsMap["path"].(string)
+	if !ok {
+		return map[string]any{"error": "path must be a string"}, nil
+	}
+
+	slog.Debug("tool call", "tool", "read_file", "path", path)
+
+	// Directories (and the root) are listed instead of read
+	if entries, err := git.ListFiles(t.repoPath, path); err == nil {
+		return map[string]any{
+			"path":    path,
+			"entries": entries,
+		}, nil
+	}
+
+	content, err := git.GetFileContent(t.repoPath, strings.Trim(path, "/"))
+	if err != nil {
+		slog.Debug("file read error", "path", path, "error", err)
+		return map[string]any{
+			"error": fmt.Sprintf("Error reading file: %v", err),
+			"path":  path,
+		}, nil
+	}
+
+	if ok, msg := t.costTracker.CanRead(len(content)); !ok {
+		slog.Debug("file read denied", "path", path, "reason", msg)
+		return map[string]any{
+			"error":      msg,
+			"path":       path,
+			"size_bytes": len(content),
+		}, nil
+	}
+	t.costTracker.RecordFileRead(len(content))
 
-	slog.Debug("author stats fetched", "author", stats.Name, "commits", stats.TotalCommits)
+	slog.Debug("file read", "path", path, "bytes", len(content))
 
 	return map[string]any{
-		"author_name":   stats.Name,
-		"total_commits": stats.TotalCommits,
-		"first_commit":  stats.FirstCommit.Format("2006-01-02"),
-		"last_commit":   stats.LastCommit.Format("2006-01-02"),
+		"path":       path,
+		"content":    content,
+		"size_bytes": len(content),
 	}, nil
 }
 
@@ -1,12 +1,16 @@
 package analyzer
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/perbu/activity/internal/git"
 )
 
 func TestGetCommitDiffTool_Metadata(t *testing.T) {
 	ct := NewCostTracker(5, 10, 100000)
-	tool := NewGetCommitDiffTool("/fake/path", ct)
+	tool := NewGetCommitDiffTool("/fake/path", git.DiffOptions{}, nil, ct)
 
 	if tool.Name() != "get_commit_diff" {
 		t.Errorf("Name() = %q, want %q", tool.Name(), "get_commit_diff")
@@ -37,7 +41,7 @@ func TestGetCommitDiffTool_Metadata(t *testing.T) {
 
 func TestGetCommitDiffTool_RunInvalidArgs(t *testing.T) {
 	ct := NewCostTracker(5, 10, 100000)
-	tool := NewGetCommitDiffTool("/fake/path", ct)
+	tool := NewGetCommitDiffTool("/fake/path", git.DiffOptions{}, nil, ct)
 
 	tests := []struct {
 		name string
@@ -72,7 +76,7 @@ func TestGetCommitDiffTool_RunInvalidArgs(t *testing.T) {
 func TestGetCommitDiffTool_RunDeniedByTracker(t *testing.T) {
 	// Create a tracker that's already at its limit
 	ct := NewCostTracker(0, 10, 100000) // 0 max fetches
-	tool := NewGetCommitDiffTool("/fake/path", ct)
+	tool := NewGetCommitDiffTool("/fake/path", git.DiffOptions{}, nil, ct)
 
 	result, err := tool.Run(nil, map[string]any{
 		"commit_sha": "abc123",
@@ -202,7 +206,7 @@ func TestGetFullCommitMessageTool_RunInvalidArgs(t *testing.T) {
 }
 
 func TestGetAuthorStatsTool_Metadata(t *testing.T) {
-	tool := NewGetAuthorStatsTool("/fake/path")
+	tool := NewGetAuthorStatsTool("/fake/path", nil)
 
 	if tool.Name() != "get_author_stats" {
 		t.Errorf("Name() = %q, want %q", tool.Name(), "get_author_stats")
@@ -229,7 +233,7 @@ func TestGetAuthorStatsTool_Metadata(t *testing.T) {
 }
 
 func TestGetAuthorStatsTool_RunInvalidArgs(t *testing.T) {
-	tool := NewGetAuthorStatsTool("/fake/path")
+	tool := NewGetAuthorStatsTool("/fake/path", nil)
 
 	tests := []struct {
 		name string
@@ -259,7 +263,7 @@ func TestGetAuthorStatsTool_RunInvalidArgs(t *testing.T) {
 
 func TestToolJSONArgs(t *testing.T) {
 	ct := NewCostTracker(0, 10, 100000) // 0 max to ensure we get the "denied" error
-	tool := NewGetCommitDiffTool("/fake/path", ct)
+	tool := NewGetCommitDiffTool("/fake/path", git.DiffOptions{}, nil, ct)
 
 	// Test with JSON string args
 	jsonArgs := `{"commit_sha": "abc123", "reason": "test reason"}`
@@ -273,3 +277,169 @@ func TestToolJSONArgs(t *testing.T) {
 	}
 	// Should get denied by tracker but args should parse correctly
 }
+
+func TestSearchCommitsTool_Metadata(t *testing.T) {
+	tool := NewSearchCommitsTool("/fake/path")
+
+	if tool.Name() != "search_commits" {
+		t.Errorf("Name() = %q, want %q", tool.Name(), "search_commits")
+	}
+
+	decl := tool.Declaration()
+	if decl == nil {
+		t.Fatal("Declaration() returned nil")
+	}
+	if len(decl.Parameters.Required) != 0 {
+		t.Errorf("Declaration() should have no required parameters, got %d", len(decl.Parameters.Required))
+	}
+}
+
+func TestSearchCommitsTool_RunInvalidArgs(t *testing.T) {
+	tool := NewSearchCommitsTool("/fake/path")
+
+	tests := []struct {
+		name string
+		args any
+	}{
+		{"nil args", nil},
+		{"wrong type grep", map[string]any{"grep": 123}},
+		{"wrong type path", map[string]any{"path": true}},
+		{"missing repository", map[string]any{"grep": "fix"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Run(nil, tt.args)
+			if err != nil {
+				t.Errorf("Run() returned unexpected error: %v", err)
+			}
+			if result == nil {
+				t.Error("Run() returned nil result")
+				return
+			}
+			if _, hasError := result["error"]; !hasError {
+				t.Error("Run() with invalid args should return error in result")
+			}
+		})
+	}
+}
+
+func TestReadFileTool_Metadata(t *testing.T) {
+	ct := NewCostTracker(5, 10, 100000)
+	tool := NewReadFileTool("/fake/path", ct)
+
+	if tool.Name() != "read_file" {
+		t.Errorf("Name() = %q, want %q", tool.Name(), "read_file")
+	}
+
+	decl := tool.Declaration()
+	if decl == nil {
+		t.Fatal("Declaration() returned nil")
+	}
+	if len(decl.Parameters.Required) != 1 {
+		t.Errorf("Declaration() should require 1 parameter, got %d", len(decl.Parameters.Required))
+	}
+}
+
+func TestReadFileTool_RunInvalidArgs(t *testing.T) {
+	ct := NewCostTracker(5, 10, 100000)
+	tool := NewReadFileTool("/fake/path", ct)
+
+	tests := []struct {
+		name string
+		args any
+	}{
+		{"nil args", nil},
+		{"empty map", map[string]any{}},
+		{"wrong type path", map[string]any{"path": 123}},
+		{"missing repository", map[string]any{"path": "go.mod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Run(nil, tt.args)
+			if err != nil {
+				t.Errorf("Run() returned unexpected error: %v", err)
+			}
+			if result == nil {
+				t.Error("Run() returned nil result")
+				return
+			}
+			if _, hasError := result["error"]; !hasError {
+				t.Error("Run() with invalid args should return error in result")
+			}
+		})
+	}
+}
+
+func TestGetCommitDiffStatTool_Metadata(t *testing.T) {
+	tool := NewGetCommitDiffStatTool("/fake/path", NewCostTracker(5, 10, 100000))
+
+	if tool.Name() != "get_commit_diffstat" {
+		t.Errorf("Name() = %q, want %q", tool.Name(), "get_commit_diffstat")
+	}
+
+	decl := tool.Declaration()
+	if decl == nil {
+		t.Fatal("Declaration() returned nil")
+	}
+	if len(decl.Parameters.Required) != 1 {
+		t.Errorf("Declaration() should require 1 parameter, got %d", len(decl.Parameters.Required))
+	}
+
+	result, err := tool.Run(nil, map[string]any{"commit_sha": 123})
+	if err != nil {
+		t.Errorf("Run() returned unexpected error: %v", err)
+	}
+	if _, hasError := result["error"]; !hasError {
+		t.Error("Run() with invalid args should return error in result")
+	}
+}
+
+func TestFormatDiffStat(t *testing.T) {
+	files := []git.FileStat{
+		{Path: "README.md", Additions: 1},
+		{Path: "logo.png", Binary: true},
+		{Path: "main.go", Additions: 40, Deletions: 10},
+	}
+	stat, additions, deletions := formatDiffStat(files)
+
+	if additions != 41 || deletions != 10 {
+		t.Errorf("formatDiffStat() totals = +%d -%d, want +41 -10", additions, deletions)
+	}
+	want := "main.go: +40 -10\nREADME.md: +1 -0\nlogo.png: binary\n"
+	if stat != want {
+		t.Errorf("formatDiffStat() =\n%s\nwant\n%s", stat, want)
+	}
+
+	many := make([]git.FileStat, maxDiffStatFiles+3)
+	for i := range many {
+		many[i] = git.FileStat{Path: fmt.Sprintf("f%d.go", i), Additions: 1}
+	}
+	if stat, _, _ := formatDiffStat(many); !strings.HasSuffix(stat, "... and 3 more files\n") {
+		t.Errorf("formatDiffStat() should note the files left out, got suffix %q", stat[len(stat)-30:])
+	}
+}
+
+func TestDiffParts(t *testing.T) {
+	file := func(path string, size int) string {
+		header := fmt.Sprintf("diff --git a/%s b/%s\n", path, path)
+		return header + strings.Repeat("+", size-len(header)-1) + "\n"
+	}
+	diff := file("a.go", 40) + file("b.go", 40) + file("c.go", 100) + file("d.go", 30)
+
+	parts := diffParts(diff, 90)
+	if len(parts) != 3 {
+		t.Fatalf("diffParts() returned %d parts, want 3", len(parts))
+	}
+	if got := strings.Join(parts[0].Paths, ","); got != "a.go,b.go" {
+		t.Errorf("part 1 paths = %s, want a.go,b.go", got)
+	}
+	// A file larger than the part size is cut off and gets a part of its own
+	if got := strings.Join(parts[1].Paths, ","); got != "c.go" || !strings.Contains(parts[1].Diff, "cut off") {
+		t.Errorf("part 2 = %v, want c.go cut off", parts[1].Paths)
+	}
+	if got := strings.Join(parts[2].Paths, ","); got != "d.go" {
+		t.Errorf("part 3 paths = %s, want d.go", got)
+	}
+}
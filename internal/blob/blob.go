@@ -0,0 +1,57 @@
+// Package blob stores large payloads, such as the raw data of analysis runs,
+// outside the database: in a local directory, S3 (or an S3-compatible store)
+// or Google Cloud Storage. The database keeps a reference to each blob.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+)
+
+// ErrNotFound is returned by Get for a blob that does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// Store puts, gets and deletes blobs. References returned by Put are URLs
+// (file://, s3:// or gs://) that identify the store and the blob, so blobs
+// written before the store was reconfigured are recognized as foreign.
+type Store interface {
+	// Put stores data under key, replacing any existing blob, and returns
+	// the blob's reference
+	Put(ctx context.Context, key string, data []byte) (string, error)
+	// Get returns the data of the referenced blob
+	Get(ctx context.Context, ref string) ([]byte, error)
+	// Delete removes the referenced blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, ref string) error
+}
+
+// New returns the blob store configured under blobs, or nil if raw data is
+// kept in the database. Credentials are only used, and checked, when the
+// store is first accessed.
+func New(cfg *config.Config) Store {
+	bc := cfg.Blobs
+	switch bc.Backend {
+	case "local":
+		return newLocalStore(cfg.GetBlobDir(), bc.Prefix)
+	case "s3":
+		id, secret := cfg.GetBlobAccessKeys()
+		return newS3Store(cfg.GetBlobEndpoint(), cfg.GetBlobRegion(), bc.Bucket, bc.Prefix, bc.PathStyle, id, secret)
+	case "gcs":
+		return newGCSStore(bc.Bucket, bc.Prefix)
+	default:
+		return nil
+	}
+}
+
+// trimRef returns the key of a reference with the given scheme and bucket
+// prefix, e.g. "s3://bucket/"
+func trimRef(ref, prefix string) (string, error) {
+	key, ok := strings.CutPrefix(ref, prefix)
+	if !ok || key == "" {
+		return "", fmt.Errorf("blob %s is not in this store (%s)", ref, prefix)
+	}
+	return key, nil
+}
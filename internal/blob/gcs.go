@@ -0,0 +1,140 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+)
+
+// gcsScope is the OAuth scope needed to read and write objects
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsStore keeps blobs in a Google Cloud Storage bucket, using the JSON API
+// with Application Default Credentials
+type gcsStore struct {
+	bucket string
+	prefix string
+
+	mu         sync.Mutex
+	httpClient *http.Client
+}
+
+func newGCSStore(bucket, prefix string) *gcsStore {
+	return &gcsStore{bucket: bucket, prefix: prefix}
+}
+
+// Put implements Store
+func (s *gcsStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	key = s.prefix + key
+	u := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(s.bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(key)
+	resp, err := s.do(ctx, http.MethodPost, u, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return "gs://" + s.bucket + "/" + key, nil
+}
+
+// Get implements Store
+func (s *gcsStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	u, err := s.objectURL(ref)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, u+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Delete implements Store
+func (s *gcsStore) Delete(ctx context.Context, ref string) error {
+	u, err := s.objectURL(ref)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, u, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// objectURL returns the JSON API URL of a referenced object
+func (s *gcsStore) objectURL(ref string) (string, error) {
+	key, err := trimRef(ref, "gs://"+s.bucket+"/")
+	if err != nil {
+		return "", err
+	}
+	return "https://storage.googleapis.com/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(key), nil
+}
+
+// client returns the authenticated HTTP client, detecting the default
+// credentials on first use
+func (s *gcsStore) client() (*http.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpClient != nil {
+		return s.httpClient, nil
+	}
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{gcsScope}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Google Cloud credentials: %w", err)
+	}
+	client, err := httptransport.NewClient(&httptransport.Options{Credentials: creds})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	s.httpClient = client
+	return client, nil
+}
+
+// do sends a request and checks its status. The caller must close the body
+// of the returned response.
+func (s *gcsStore) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GCS %s failed: %w", method, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GCS %s returned %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
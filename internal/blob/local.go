@@ -0,0 +1,94 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// localStore keeps blobs as files under a directory
+type localStore struct {
+	dir    string
+	prefix string
+}
+
+func newLocalStore(dir, prefix string) *localStore {
+	return &localStore{dir: dir, prefix: prefix}
+}
+
+// Put implements Store. The file is written under a temporary name and
+// renamed, so readers never see a partial blob.
+func (s *localStore) Put(_ context.Context, key string, data []byte) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(s.prefix+key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	return s.refPrefix() + filepath.ToSlash(s.prefix+key), nil
+}
+
+// Get implements Store
+func (s *localStore) Get(_ context.Context, ref string) ([]byte, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Delete implements Store
+func (s *localStore) Delete(_ context.Context, ref string) error {
+	path, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// refPrefix returns the start of the references of the store's blobs
+func (s *localStore) refPrefix() string {
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		dir = s.dir
+	}
+	return "file://" + filepath.ToSlash(dir) + "/"
+}
+
+// path returns the file of a blob, refusing references outside the directory
+func (s *localStore) path(ref string) (string, error) {
+	key, err := trimRef(ref, s.refPrefix())
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob reference: %s", ref)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
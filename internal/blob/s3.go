@@ -0,0 +1,131 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/sigv4"
+)
+
+// s3Store keeps blobs in an S3 bucket, or a bucket of any store speaking the
+// S3 API, signing requests with AWS Signature Version 4
+type s3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	pathStyle bool
+	keyID     string
+	secret    string
+
+	httpClient *http.Client
+}
+
+func newS3Store(endpoint, region, bucket, prefix string, pathStyle bool, keyID, secret string) *s3Store {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: "s3." + region + ".amazonaws.com"}
+	}
+	return &s3Store{
+		endpoint:   u,
+		region:     region,
+		bucket:     bucket,
+		prefix:     prefix,
+		pathStyle:  pathStyle,
+		keyID:      keyID,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Put implements Store
+func (s *s3Store) Put(ctx context.Context, key string, data []byte) (string, error) {
+	key = s.prefix + key
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return "s3://" + s.bucket + "/" + key, nil
+}
+
+// Get implements Store
+func (s *s3Store) Get(ctx context.Context, ref string) ([]byte, error) {
+	key, err := trimRef(ref, "s3://"+s.bucket+"/")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Delete implements Store. S3 reports success for missing objects.
+func (s *s3Store) Delete(ctx context.Context, ref string) error {
+	key, err := trimRef(ref, "s3://"+s.bucket+"/")
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for an object and checks its status. The caller
+// must close the body of the returned response.
+func (s *s3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	path, rawPath := "/"+key, "/"+sigv4.EscapePath(key)
+	if s.pathStyle {
+		path, rawPath = "/"+s.bucket+path, "/"+s.bucket+rawPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = s.endpoint.Path + path
+	u.RawPath = s.endpoint.EscapedPath() + rawPath
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	sigv4.Sign(req, body, sigv4.Credentials{AccessKeyID: s.keyID, SecretAccessKey: s.secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")},
+		s.region, "s3", time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s failed: %w", method, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("S3 %s returned %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
@@ -1,28 +1,116 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the application configuration
 type Config struct {
-	DataDir    string           `yaml:"data_dir"`
-	Debug      bool             `yaml:"debug"` // Enable debug logging
-	Database   DatabaseConfig   `yaml:"database"`
-	LLM        LLMConfig        `yaml:"llm"`
-	Newsletter NewsletterConfig `yaml:"newsletter"`
-	GitHub     GitHubConfig     `yaml:"github"`
-	Web        WebConfig        `yaml:"web"`
+	DataDir     string            `yaml:"data_dir"`
+	Debug       bool              `yaml:"debug"` // Enable debug logging
+	Database    DatabaseConfig    `yaml:"database"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Newsletter  NewsletterConfig  `yaml:"newsletter"`
+	GitHub      GitHubConfig      `yaml:"github"`
+	Issues      IssuesConfig      `yaml:"issues"`
+	Jira        JiraConfig        `yaml:"jira"`
+	Confluence  ConfluenceConfig  `yaml:"confluence"`
+	Notion      NotionConfig      `yaml:"notion"`
+	Web         WebConfig         `yaml:"web"`
+	Branding    BrandingConfig    `yaml:"branding"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Blobs       BlobConfig        `yaml:"blobs"`
+	Secrets     SecretsConfig     `yaml:"secrets"`
+
+	// How often the server regenerates repository descriptions whose README
+	// changed (default: 24, 0 disables)
+	DescriptionRefreshHours int `yaml:"description_refresh_hours"`
+
+	// Switch a repository to its upstream default branch when the branch it
+	// tracks disappears upstream, e.g. when master is renamed to main. Without
+	// it, updating such a repository fails with a hint to run repo set-branch.
+	FollowDefaultBranch bool `yaml:"follow_default_branch"`
+
+	// Flag repositories without commits for this many weeks as stale on the
+	// dashboard (default: 0, disabled; repos.<name>.stale_weeks overrides it),
+	// and with notify_stale tell the admins when one goes stale
+	StaleWeeks  int  `yaml:"stale_weeks"`
+	NotifyStale bool `yaml:"notify_stale"`
+
+	// Authors (name or email, case-insensitive) whose commits are excluded from
+	// analysis and commit counts, e.g. "dependabot[bot]". A "*" matches any run
+	// of characters. Applies to all repos.
+	IgnoreAuthors []string `yaml:"ignore_authors"`
+
+	// Add BotAuthorPatterns to the ignore list of every repo
+	IgnoreBots bool `yaml:"ignore_bots"`
+
+	// Per-repository overrides keyed by repository name
+	Repos map[string]RepoConfig `yaml:"repos"`
+
+	// Where each setting set by the file or environment came from, keyed by
+	// YAML path
+	sources map[string]string
+
+	// The config with the latest reloaded settings, nil until the first
+	// reload (see Current)
+	live *atomic.Pointer[Config]
+}
+
+// RepoConfig represents per-repository configuration overrides
+type RepoConfig struct {
+	IgnoreAuthors []string `yaml:"ignore_authors"` // Added to the global ignore_authors list
+	FirstParent   bool     `yaml:"first_parent"`   // Follow only the main line; merged branches count as their merge commit
+	NoMerges      bool     `yaml:"no_merges"`      // Skip merge commits themselves (merged commits are still counted)
+	StaleWeeks    int      `yaml:"stale_weeks"`    // Overrides the global stale_weeks; -1 never flags the repo
+	DiffExcludes  []string `yaml:"diff_excludes"`  // .gitignore-style patterns left out of diffs the agent fetches, e.g. *.pb.go or dist/
+
+	// Confluence space to publish the repo's weekly reports to
+	Confluence RepoConfluenceConfig `yaml:"confluence"`
+
+	// GitHub Discussions category to publish the repo's weekly reports to
+	Discussions RepoDiscussionsConfig `yaml:"discussions"`
+
+	// Notion database to publish the repo's weekly reports to
+	Notion RepoNotionConfig `yaml:"notion"`
+}
+
+// RepoNotionConfig selects the Notion database a repository's weekly reports
+// are published to, one page per week. The database must be shared with the
+// integration.
+type RepoNotionConfig struct {
+	DatabaseID string `yaml:"database_id"` // Database ID from its URL (empty disables publishing)
+}
+
+// RepoConfluenceConfig selects where a repository's weekly reports are
+// published in Confluence, one page per week
+type RepoConfluenceConfig struct {
+	Space    string `yaml:"space"`     // Space key, e.g. ENG (empty disables publishing)
+	ParentID string `yaml:"parent_id"` // ID of the page to create report pages under (default: the space's top level)
+}
+
+// RepoDiscussionsConfig selects the discussion category a GitHub repository's
+// weekly reports are posted to, one discussion per week. Posting needs the
+// GitHub App, with read and write access to discussions.
+type RepoDiscussionsConfig struct {
+	Category string `yaml:"category"` // Category name or slug, e.g. Announcements (empty disables posting)
 }
 
 // DatabaseConfig represents PostgreSQL database configuration
 type DatabaseConfig struct {
-	DSN                    string `yaml:"dsn"`                       // PostgreSQL connection string
+	DSN                    string `yaml:"dsn" secret:"true"`         // PostgreSQL connection string
 	MaxOpenConns           int    `yaml:"max_open_conns"`            // Maximum open connections (default: 25)
 	MaxIdleConns           int    `yaml:"max_idle_conns"`            // Maximum idle connections (default: 5)
 	ConnMaxLifetimeSeconds int    `yaml:"conn_max_lifetime_seconds"` // Connection max lifetime in seconds (default: 300)
@@ -34,6 +122,151 @@ type WebConfig struct {
 	SeedAdmin  string `yaml:"seed_admin"`  // First admin email to create on startup
 	DevMode    bool   `yaml:"dev_mode"`    // Bypass auth, use dev_user (for local development)
 	DevUser    string `yaml:"dev_user"`    // Email to use in dev mode (default: "dev@localhost")
+
+	// ReadOnly runs a public mirror: only report pages, feeds and the
+	// read-only APIs are served, requests are never authenticated, and no
+	// admin, chat, webhook or other mutating routes, scheduled jobs or gRPC
+	// API are started
+	ReadOnly bool `yaml:"read_only"`
+
+	// RequireLogin hides report pages, feeds and the JSON API from anonymous
+	// readers; per-repository visibility still applies to signed in users
+	RequireLogin bool `yaml:"require_login"`
+
+	// Path prefix the web UI is mounted under behind a reverse proxy, such
+	// as /activity (default: the root)
+	BasePath string `yaml:"base_path"`
+
+	// Directory of HTML templates replacing the built-in ones of the same
+	// name, such as base.html; the others keep their defaults. Read on
+	// startup (default: <data_dir>/templates if it exists).
+	TemplatesDir string `yaml:"templates_dir"`
+
+	// How long the server waits on shutdown for in-flight requests and
+	// scheduled jobs before cancelling them (default: 30)
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"`
+
+	// Key signing the CSRF tokens of admin forms. Random on each start if
+	// empty; set it to keep forms valid across restarts and replicas.
+	SessionKey string `yaml:"session_key" secret:"true"`
+
+	// Requests a minute allowed per client IP, in bursts of up to
+	// rate_limit_burst (defaults: 300 and 60; 0 disables). The public
+	// webhook endpoints have a lower limit of their own (default: 60).
+	RateLimitPerMinute        int `yaml:"rate_limit_per_minute"`
+	RateLimitBurst            int `yaml:"rate_limit_burst"`
+	WebhookRateLimitPerMinute int `yaml:"webhook_rate_limit_per_minute"`
+
+	// Header with the client IP set by a reverse proxy in front of the
+	// server, e.g. X-Forwarded-For. Without it every client behind the
+	// proxy shares the proxy's rate limit.
+	ClientIPHeader string `yaml:"client_ip_header"`
+
+	// Largest request body accepted, in KB (default: 1024, 0 disables)
+	MaxBodyKB int `yaml:"max_body_kb"`
+
+	// Domain whose subdomains select a workspace, e.g. activity.example.com:
+	// requests to team.activity.example.com only see the team workspace and
+	// cannot switch to another. The domain itself serves every workspace.
+	WorkspaceDomain string `yaml:"workspace_domain"`
+
+	// Address the gRPC API listens on, e.g. ":9090" (empty disables it).
+	// Calls authenticate with a workspace API token. API tokens are
+	// read-only unless GRPCGenerate lets them trigger report generation.
+	GRPCAddress  string `yaml:"grpc_address"`
+	GRPCGenerate bool   `yaml:"grpc_generate"`
+}
+
+// BrandingConfig customizes the look of the web UI
+type BrandingConfig struct {
+	SiteName    string `yaml:"site_name"`    // Shown in the nav bar and page titles (default: "activity")
+	LogoURL     string `yaml:"logo_url"`     // Image shown before the site name
+	FooterText  string `yaml:"footer_text"`  // Replaces the copyright line of the footer
+	AccentColor string `yaml:"accent_color"` // Color of links and highlights, as #rgb, #rrggbb or a CSS color name
+
+	// Color of links under the pointer (default: accent_color, if set)
+	AccentHoverColor string `yaml:"accent_hover_color"`
+
+	// Extra links in the nav bar, as "Label=URL" entries, e.g.
+	// "Wiki=https://wiki.example.com". URLs are used as they are, so local
+	// paths must include web.base_path.
+	NavLinks []string `yaml:"nav_links"`
+}
+
+// NavLink is an extra link in the web UI's nav bar
+type NavLink struct {
+	Label string
+	URL   string
+}
+
+// TracingConfig configures OpenTelemetry tracing of web requests, report
+// generation, repository updates, git operations and LLM calls
+type TracingConfig struct {
+	// Where spans go: "otlp" (OTLP over HTTP, e.g. to an OpenTelemetry
+	// Collector, Jaeger or Tempo), "stdout" (a JSON line per span on stderr),
+	// or empty to disable tracing
+	Exporter string `yaml:"exporter"`
+
+	// OTLP/HTTP endpoint; spans are posted to <endpoint>/v1/traces (default:
+	// OTEL_EXPORTER_OTLP_ENDPOINT, or http://localhost:4318). Headers such
+	// as API keys are read from OTEL_EXPORTER_OTLP_HEADERS.
+	Endpoint string `yaml:"endpoint"`
+
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of traces recorded, 0 to 1 (default: 1)
+	ServiceName string  `yaml:"service_name"` // service.name of the spans (default: "activity")
+}
+
+// BlobConfig configures offloading the raw data of analysis runs from the
+// database to a blob store, with a reference to the blob kept in its place
+type BlobConfig struct {
+	Backend   string `yaml:"backend"`     // "local", "s3" or "gcs"; empty keeps raw data in the database
+	Dir       string `yaml:"dir"`         // local: directory for blobs (default: <data_dir>/blobs)
+	Bucket    string `yaml:"bucket"`      // s3, gcs: bucket name
+	Prefix    string `yaml:"prefix"`      // Prefix of blob keys, e.g. "activity/"
+	MinSizeKB int    `yaml:"min_size_kb"` // Raw data smaller than this stays in the database (default: 0, none)
+
+	// S3 and S3-compatible stores (MinIO, Cloudflare R2, GCS with HMAC keys).
+	// Access keys default to the standard AWS environment variables.
+	Endpoint           string `yaml:"endpoint"`   // default: https://s3.<region>.amazonaws.com
+	Region             string `yaml:"region"`     // default: AWS_REGION, or us-east-1
+	PathStyle          bool   `yaml:"path_style"` // Address the bucket in the path rather than the host name
+	AccessKeyID        string `yaml:"access_key_id"`
+	AccessKeyIDEnv     string `yaml:"access_key_id_env"`
+	SecretAccessKey    string `yaml:"secret_access_key" secret:"true"`
+	SecretAccessKeyEnv string `yaml:"secret_access_key_env"`
+
+	// gcs uses Application Default Credentials
+}
+
+// SecretsConfig holds the master key that secrets stored in the database
+// (activity secrets set) are encrypted with. Keys are 32 random bytes, base64
+// encoded (activity secrets keygen). To rotate, make the old key the previous
+// one, set a new key and run activity secrets rotate.
+type SecretsConfig struct {
+	MasterKey            string `yaml:"master_key" secret:"true"`          // Direct key
+	MasterKeyEnv         string `yaml:"master_key_env"`                    // Environment variable name
+	PreviousMasterKey    string `yaml:"previous_master_key" secret:"true"` // Old key, only decrypted with
+	PreviousMasterKeyEnv string `yaml:"previous_master_key_env"`           // Environment variable name
+}
+
+// RetentionConfig controls how long historical data is kept. A value of 0
+// keeps that data forever.
+type RetentionConfig struct {
+	ActivityRunsDays    int `yaml:"activity_runs_days"`    // Raw analysis runs (default: 90)
+	RawDataDays         int `yaml:"raw_data_days"`         // Raw data of runs, including offloaded blobs (default: 0, as long as the run)
+	NewsletterSendsDays int `yaml:"newsletter_sends_days"` // Newsletter send records and send history (default: 365)
+	WeeklyReportsDays   int `yaml:"weekly_reports_days"`   // Weekly reports (default: 0, forever)
+	PruneIntervalHours  int `yaml:"prune_interval_hours"`  // How often the server prunes (default: 24, 0 disables)
+}
+
+// LeaderboardConfig controls the contributor leaderboard page
+type LeaderboardConfig struct {
+	Enabled bool `yaml:"enabled"` // Serve /leaderboard and link it in the nav bar
+
+	// Authors (names, case-insensitive, "*" wildcards as in ignore_authors)
+	// left off the leaderboard, for people and teams who would rather not
+	// be ranked
+	OptOut []string `yaml:"opt_out"`
 }
 
 // GitHubConfig represents GitHub App authentication configuration
@@ -41,29 +274,131 @@ type GitHubConfig struct {
 	AppID             int64  `yaml:"app_id"`
 	AppIDEnv          string `yaml:"app_id_env"`           // Env var with App ID
 	InstallationID    int64  `yaml:"installation_id"`
-	InstallationIDEnv string `yaml:"installation_id_env"`  // Env var with Installation ID
-	PrivateKeyPath    string `yaml:"private_key_path"`     // Path to PEM file
-	PrivateKeyEnv     string `yaml:"private_key_env"`      // Env var with PEM content
+	InstallationIDEnv string `yaml:"installation_id_env"`       // Env var with Installation ID
+	PrivateKey        string `yaml:"private_key" secret:"true"` // Direct PEM content (takes precedence)
+	PrivateKeyPath    string `yaml:"private_key_path"`          // Path to PEM file
+	PrivateKeyEnv     string `yaml:"private_key_env"`           // Env var with PEM content
+
+	// Add a CI health section, from the week's GitHub Actions runs, to weekly
+	// reports of repos the App is installed on (default: true)
+	CIHealth bool `yaml:"ci_health"`
+}
+
+// IssuesConfig controls the issue tracker section of weekly reports
+type IssuesConfig struct {
+	// Add the week's opened and closed issue counts and notable issues to
+	// weekly reports of GitHub and GitLab repos (default: false). GitHub
+	// issues are read with the GitHub App if configured.
+	Enabled bool `yaml:"enabled"`
+
+	// GitLab access token with the read_api scope, needed for private projects
+	GitLabToken    string `yaml:"gitlab_token" secret:"true"` // Direct token
+	GitLabTokenEnv string `yaml:"gitlab_token_env"`           // Environment variable name
+
+	// Hosts of self-managed GitLab instances, e.g. gitlab.example.com;
+	// gitlab.com is always recognized
+	GitLabHosts []string `yaml:"gitlab_hosts"`
+}
+
+// JiraConfig resolves Jira ticket keys, such as PROJ-123, in commit messages.
+// The analyzer is given the summaries of the tickets a week's commits
+// reference, and the keys are linked to Jira in rendered reports.
+type JiraConfig struct {
+	// Jira instance, e.g. https://example.atlassian.net (empty disables)
+	BaseURL string `yaml:"base_url"`
+
+	// Account email for Jira Cloud API tokens. Leave empty on Jira Server and
+	// Data Center, where the token is a personal access token.
+	Email    string `yaml:"email"`
+	Token    string `yaml:"token" secret:"true"` // Direct token
+	TokenEnv string `yaml:"token_env"`           // Environment variable name
+
+	// Project keys to resolve, e.g. PROJ; empty resolves any key-like
+	// reference. Keys of other projects are neither looked up nor linked.
+	Projects []string `yaml:"projects"`
+}
+
+// ConfluenceConfig is the Confluence instance weekly reports are published
+// to. Repos choose a space with repos.<name>.confluence.
+type ConfluenceConfig struct {
+	// Confluence base URL, including /wiki on Confluence Cloud, e.g.
+	// https://example.atlassian.net/wiki
+	BaseURL string `yaml:"base_url"`
+
+	// Account email for Confluence Cloud API tokens. Leave empty on Server
+	// and Data Center, where the token is a personal access token.
+	Email    string `yaml:"email"`
+	Token    string `yaml:"token" secret:"true"` // Direct token
+	TokenEnv string `yaml:"token_env"`           // Environment variable name
+}
+
+// NotionConfig holds the token of the Notion integration weekly reports are
+// published with. Repos choose a database with repos.<name>.notion.
+type NotionConfig struct {
+	Token    string `yaml:"token" secret:"true"` // Direct internal integration token
+	TokenEnv string `yaml:"token_env"`           // Environment variable name
 }
 
 // NewsletterConfig represents newsletter email configuration
 type NewsletterConfig struct {
 	Enabled        bool   `yaml:"enabled"`
-	SendGridAPIKey string `yaml:"sendgrid_api_key"`     // Direct API key
-	SendGridKeyEnv string `yaml:"sendgrid_api_key_env"` // Environment variable name
+	Provider       string `yaml:"provider"`                       // "sendgrid" (default), "postmark" or "mailgun"
+	SendGridAPIKey string `yaml:"sendgrid_api_key" secret:"true"` // Direct API key
+	SendGridKeyEnv string `yaml:"sendgrid_api_key_env"`           // Environment variable name
 	FromEmail      string `yaml:"from_email"`
 	FromName       string `yaml:"from_name"`
 	SubjectPrefix  string `yaml:"subject_prefix"`
+
+	// Postmark server token (provider: postmark)
+	PostmarkToken    string `yaml:"postmark_token" secret:"true"`
+	PostmarkTokenEnv string `yaml:"postmark_token_env"`
+
+	// Mailgun API key and sending domain (provider: mailgun). The base URL
+	// defaults to the US region; use https://api.eu.mailgun.net for the EU.
+	MailgunAPIKey  string `yaml:"mailgun_api_key" secret:"true"`
+	MailgunKeyEnv  string `yaml:"mailgun_api_key_env"`
+	MailgunDomain  string `yaml:"mailgun_domain"`
+	MailgunBaseURL string `yaml:"mailgun_base_url"`
+
+	// Verification key of SendGrid's signed event webhook. The webhook
+	// endpoint (/webhooks/sendgrid) is disabled unless a key is configured.
+	WebhookKey    string `yaml:"sendgrid_webhook_key" secret:"true"` // Direct key
+	WebhookKeyEnv string `yaml:"sendgrid_webhook_key_env"`           // Environment variable name
+
+	// Scheduled makes the server send each subscriber last week's reports on
+	// Monday at their send hour in their own timezone
+	Scheduled bool `yaml:"scheduled"`
+
+	// AutoApprove approves reports as they are generated (the default).
+	// Otherwise they are drafts that newsletters leave out until an admin
+	// approves them on /admin/reviews.
+	AutoApprove bool `yaml:"auto_approve"`
+
+	// MaxSections limits a newsletter to the most active repositories
+	// (default 10); the others are named in an "and N more repos" line.
+	// 0 includes every repository.
+	MaxSections int `yaml:"max_sections"`
+
+	// SendCorrections emails the recipients of a report that is regenerated
+	// after it was sent a short note of what changed, once the new summary is
+	// approved, if the lines that changed hold at least CorrectionThreshold
+	// of the words of both summaries (default 0.2).
+	SendCorrections     bool    `yaml:"send_corrections"`
+	CorrectionThreshold float64 `yaml:"correction_threshold"`
 }
 
 // LLMConfig represents LLM provider configuration
 type LLMConfig struct {
-	Provider         string `yaml:"provider"`
+	Provider         string `yaml:"provider"` // "gemini" (API key), "vertex" (Vertex AI with ADC) or "azure" (Azure OpenAI)
 	Model            string `yaml:"model"`
-	APIKey           string `yaml:"api_key"`            // Direct API key (takes precedence over api_key_env)
-	APIKeyEnv        string `yaml:"api_key_env"`        // Environment variable name containing API key
-	MaxCommits       int    `yaml:"max_commits"`        // Max commits to analyze per run
-	MaxMessageLength int    `yaml:"max_message_length"` // Max length of commit message to include
+	APIKey           string `yaml:"api_key" secret:"true"` // Direct API key (takes precedence over api_key_env)
+	APIKeyEnv        string `yaml:"api_key_env"`           // Environment variable name containing API key
+	MaxCommits       int    `yaml:"max_commits"`           // Max commits to analyze per run
+	MaxMessageLength int    `yaml:"max_message_length"`    // Max length of commit message to include
+
+	// Input tokens the model accepts. Prompts estimated to be larger are
+	// trimmed to fit, least important content first (0 disables trimming).
+	ContextWindowTokens int `yaml:"context_window_tokens"` // default: 128000
 
 	// Phase 3: Agent-based analysis configuration
 	UseAgent       bool `yaml:"use_agent"`        // Enable agent-based analysis (default: false)
@@ -72,6 +407,38 @@ type LLMConfig struct {
 	MaxTotalTokens int  `yaml:"max_total_tokens"` // Max total tokens for agent session (default: 100000)
 	EnableToolLogs bool `yaml:"enable_tool_logs"` // Enable detailed tool execution logs (default: true)
 
+	// Model prices in USD per million tokens, used for dry-run cost estimates
+	InputPricePerMTok  float64 `yaml:"input_price_per_mtok"`  // default: 0.10
+	OutputPricePerMTok float64 `yaml:"output_price_per_mtok"` // default: 0.40
+
+	// Request timeouts and retries. Each LLM call (including each agent turn)
+	// gets its own timeout; timeouts, rate limits and server errors are retried
+	// with exponential backoff.
+	TimeoutSeconds         int `yaml:"timeout_seconds"`           // Per-call timeout (default: 120)
+	MaxRetries             int `yaml:"max_retries"`               // Retries after the first attempt (default: 3, 0 disables)
+	RetryBackoffSeconds    int `yaml:"retry_backoff_seconds"`     // Initial backoff, doubled per retry (default: 2)
+	RetryMaxBackoffSeconds int `yaml:"retry_max_backoff_seconds"` // Backoff cap (default: 30)
+
+	// Weeks of a backfill (report generate --since) analyzed at the same time.
+	// A week analyzed alongside its previous week does not get that week's
+	// summary as context; 1 analyzes weeks in order, each with the last.
+	BackfillConcurrency int `yaml:"backfill_concurrency"` // default: 4
+
+	// Embeddings of report summaries power semantic search and "related weeks"
+	EmbeddingModel    string `yaml:"embedding_model"`    // Embedding model, or deployment for Azure (default: gemini-embedding-001 / text-embedding-3-small)
+	DisableEmbeddings bool   `yaml:"disable_embeddings"` // Skip computing embeddings (search and related weeks are unavailable)
+
+	// Vertex AI configuration (provider: vertex). Credentials come from
+	// Application Default Credentials, e.g. workload identity on GKE.
+	VertexProject  string `yaml:"vertex_project"`  // GCP project ID (falls back to GOOGLE_CLOUD_PROJECT)
+	VertexLocation string `yaml:"vertex_location"` // GCP region (falls back to GOOGLE_CLOUD_LOCATION, then us-central1)
+
+	// Azure OpenAI configuration (provider: azure). The API key is read from
+	// api_key / api_key_env, falling back to AZURE_OPENAI_API_KEY.
+	AzureEndpoint   string `yaml:"azure_endpoint"`    // e.g. https://myresource.openai.azure.com (falls back to AZURE_OPENAI_ENDPOINT)
+	AzureDeployment string `yaml:"azure_deployment"`  // Deployment name (defaults to model)
+	AzureAPIVersion string `yaml:"azure_api_version"` // REST API version (default: 2024-10-21)
+
 	// Prompt customization (optional overrides)
 	Phase2Prompt      string `yaml:"phase2_prompt"`       // Custom prompt for Phase 2 simple LLM analysis
 	AgentSystemPrompt string `yaml:"agent_system_prompt"` // Custom system instruction for Phase 3 agent
@@ -94,29 +461,86 @@ func DefaultConfig() *Config {
 			MaxCommits:       50,   // Limit to 50 commits per analysis
 			MaxMessageLength: 1000, // Truncate long commit messages
 
+			ContextWindowTokens: 128000, // Fits Gemini and Azure OpenAI models
+
 			// Phase 3: Agent mode (default) - intelligent diff fetching
 			UseAgent:       true,   // Agent mode by default (set false for Phase 2)
 			MaxDiffFetches: 5,      // Max 5 diffs per analysis
 			MaxDiffSizeKB:  10,     // Max 10KB per diff
 			MaxTotalTokens: 100000, // ~$0.01 cost limit
 			EnableToolLogs: true,   // Enable logging for debugging
+
+			InputPricePerMTok:  0.10,
+			OutputPricePerMTok: 0.40,
+
+			TimeoutSeconds:         120,
+			MaxRetries:             3,
+			RetryBackoffSeconds:    2,
+			RetryMaxBackoffSeconds: 30,
+
+			BackfillConcurrency: 4,
 		},
 		Newsletter: NewsletterConfig{
-			Enabled:        false,
-			SendGridKeyEnv: "SENDGRID_API_KEY",
-			FromEmail:      "activity@example.com",
-			FromName:       "Activity Digest",
-			SubjectPrefix:  "[Activity]",
+			Enabled:          false,
+			Provider:         "sendgrid",
+			SendGridKeyEnv:   "SENDGRID_API_KEY",
+			PostmarkTokenEnv: "POSTMARK_SERVER_TOKEN",
+			MailgunKeyEnv:    "MAILGUN_API_KEY",
+			MailgunBaseURL:   "https://api.mailgun.net",
+			WebhookKeyEnv:    "SENDGRID_WEBHOOK_KEY",
+			AutoApprove:      true,
+			MaxSections:      10,
+			FromEmail:        "activity@example.com",
+			FromName:         "Activity Digest",
+			SubjectPrefix:    "[Activity]",
 		},
 		GitHub: GitHubConfig{
 			AppIDEnv:          "GITHUB_APP_ID",
 			InstallationIDEnv: "GITHUB_INSTALLATION_ID",
 			PrivateKeyEnv:     "GITHUB_APP_PRIVATE_KEY",
+			CIHealth:          true,
+		},
+		Issues: IssuesConfig{
+			GitLabTokenEnv: "GITLAB_TOKEN",
+		},
+		Jira: JiraConfig{
+			TokenEnv: "JIRA_TOKEN",
+		},
+		Confluence: ConfluenceConfig{
+			TokenEnv: "CONFLUENCE_TOKEN",
+		},
+		Notion: NotionConfig{
+			TokenEnv: "NOTION_TOKEN",
 		},
 		Web: WebConfig{
-			AuthHeader: "oidc-email",
-			DevUser:    "dev@localhost",
+			AuthHeader:             "oidc-email",
+			DevUser:                "dev@localhost",
+			ShutdownTimeoutSeconds: 30,
+
+			RateLimitPerMinute:        300,
+			RateLimitBurst:            60,
+			WebhookRateLimitPerMinute: 60,
+			MaxBodyKB:                 1024,
 		},
+		Retention: RetentionConfig{
+			ActivityRunsDays:    90,
+			NewsletterSendsDays: 365,
+			WeeklyReportsDays:   0, // Keep reports forever
+			PruneIntervalHours:  24,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		Blobs: BlobConfig{
+			AccessKeyIDEnv:     "AWS_ACCESS_KEY_ID",
+			SecretAccessKeyEnv: "AWS_SECRET_ACCESS_KEY",
+		},
+		Secrets: SecretsConfig{
+			MasterKeyEnv:         "ACTIVITY_MASTER_KEY",
+			PreviousMasterKeyEnv: "ACTIVITY_PREVIOUS_MASTER_KEY",
+		},
+		DescriptionRefreshHours: 24,
+		live:                    new(atomic.Pointer[Config]),
 	}
 }
 
@@ -144,6 +568,89 @@ func (c *Config) GetDevUser() string {
 	return "dev@localhost"
 }
 
+// GetBasePath returns the path prefix of the web UI with a leading and no
+// trailing slash, or "" if it is served from the root
+func (c *Config) GetBasePath() string {
+	p := strings.Trim(strings.TrimSpace(c.Web.BasePath), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// GetTemplatesDir returns the directory of templates replacing the web UI's
+// built-in ones: web.templates_dir if set, otherwise <data_dir>/templates if
+// it exists, or "" for none
+func (c *Config) GetTemplatesDir() string {
+	if c.Web.TemplatesDir != "" {
+		return c.Web.TemplatesDir
+	}
+	if c.DataDir == "" {
+		return ""
+	}
+	dir := filepath.Join(c.DataDir, "templates")
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// GetSiteName returns the name the web UI shows for the site
+func (c *Config) GetSiteName() string {
+	c = c.Current()
+	if name := strings.TrimSpace(c.Branding.SiteName); name != "" {
+		return name
+	}
+	return "activity"
+}
+
+// GetNavLinks returns the extra nav bar links, skipping entries that are not
+// "Label=URL" pairs (see Validate)
+func (c *Config) GetNavLinks() []NavLink {
+	c = c.Current()
+	var links []NavLink
+	for _, entry := range c.Branding.NavLinks {
+		label, url, ok := strings.Cut(entry, "=")
+		label, url = strings.TrimSpace(label), strings.TrimSpace(url)
+		if !ok || label == "" || url == "" {
+			continue
+		}
+		links = append(links, NavLink{Label: label, URL: url})
+	}
+	return links
+}
+
+// GetShutdownTimeout returns how long the server drains requests and jobs on
+// shutdown
+func (c *Config) GetShutdownTimeout() time.Duration {
+	c = c.Current()
+	return time.Duration(max(c.Web.ShutdownTimeoutSeconds, 0)) * time.Second
+}
+
+// GetTracingEndpoint returns the OTLP/HTTP endpoint spans are exported to
+func (c *Config) GetTracingEndpoint() string {
+	if c.Tracing.Endpoint != "" {
+		return c.Tracing.Endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return "http://localhost:4318"
+}
+
+// GetTracingSampleRatio returns the fraction of traces to record, within 0 to 1
+func (c *Config) GetTracingSampleRatio() float64 {
+	return min(max(c.Tracing.SampleRatio, 0), 1)
+}
+
+// GetTracingServiceName returns the service name spans are reported under
+func (c *Config) GetTracingServiceName() string {
+	if c.Tracing.ServiceName != "" {
+		return c.Tracing.ServiceName
+	}
+	return "activity"
+}
+
 // GetDatabaseDSN returns the database DSN from config or environment
 func (c *Config) GetDatabaseDSN() string {
 	if c.Database.DSN != "" {
@@ -152,44 +659,56 @@ func (c *Config) GetDatabaseDSN() string {
 	return os.Getenv("DATABASE_URL")
 }
 
-// Load loads configuration from the specified path, falling back to defaults
+// Load loads configuration from the specified path, falling back to defaults,
+// and then applies ACTIVITY_* environment variables. Environment variables
+// take precedence over the file, so a container can be configured without
+// one.
 func Load(configPath string) (*Config, error) {
-	// If no path specified, use default location
-	if configPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
-		}
-		configPath = filepath.Join(homeDir, ".config", "activity", "config.yaml")
+	configPath, err := ResolvePath(configPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Expand ~ in path
-	configPath = expandPath(configPath)
-
 	// Start with defaults
 	cfg := DefaultConfig()
 
-	// Try to load from file
+	// Try to load from file; without one, defaults and the environment apply
 	data, err := os.ReadFile(configPath)
-	if err != nil {
-		// If file doesn't exist, return defaults
-		if os.IsNotExist(err) {
-			return cfg, nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		cfg.recordFileSources(data)
+	}
 
-	// Parse YAML
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
 	}
 
-	// Expand ~ in data_dir if present
+	// Expand ~ in data_dir, the blob and the templates directory if present
 	cfg.DataDir = expandPath(cfg.DataDir)
+	cfg.Blobs.Dir = expandPath(cfg.Blobs.Dir)
+	cfg.Web.TemplatesDir = expandPath(cfg.Web.TemplatesDir)
 
 	return cfg, nil
 }
 
+// ResolvePath returns the config file Load reads for configPath: the default
+// location (~/.config/activity/config.yaml) if it is empty, with ~ expanded
+func ResolvePath(configPath string) (string, error) {
+	if configPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configPath = filepath.Join(homeDir, ".config", "activity", "config.yaml")
+	}
+	return expandPath(configPath), nil
+}
+
 // expandPath expands ~ to home directory in paths
 func expandPath(path string) string {
 	if path == "" {
@@ -218,8 +737,345 @@ func (c *Config) EnsureDataDir() error {
 	return nil
 }
 
+// UsesVertexAI returns true if the LLM provider is Vertex AI
+func (c *Config) UsesVertexAI() bool {
+	return c.LLM.Provider == "vertex"
+}
+
+// GetVertexProject returns the GCP project for Vertex AI from config or environment
+func (c *Config) GetVertexProject() string {
+	if c.LLM.VertexProject != "" {
+		return c.LLM.VertexProject
+	}
+	return os.Getenv("GOOGLE_CLOUD_PROJECT")
+}
+
+// GetVertexLocation returns the GCP location for Vertex AI from config or environment
+func (c *Config) GetVertexLocation() string {
+	if c.LLM.VertexLocation != "" {
+		return c.LLM.VertexLocation
+	}
+	if loc := os.Getenv("GOOGLE_CLOUD_LOCATION"); loc != "" {
+		return loc
+	}
+	return "us-central1"
+}
+
+// UsesAzureOpenAI returns true if the LLM provider is Azure OpenAI
+func (c *Config) UsesAzureOpenAI() bool {
+	return c.LLM.Provider == "azure"
+}
+
+// GetAzureEndpoint returns the Azure OpenAI resource endpoint from config or environment
+func (c *Config) GetAzureEndpoint() string {
+	if c.LLM.AzureEndpoint != "" {
+		return strings.TrimSuffix(c.LLM.AzureEndpoint, "/")
+	}
+	return strings.TrimSuffix(os.Getenv("AZURE_OPENAI_ENDPOINT"), "/")
+}
+
+// GetAzureDeployment returns the Azure OpenAI deployment name, defaulting to the model name
+func (c *Config) GetAzureDeployment() string {
+	if c.LLM.AzureDeployment != "" {
+		return c.LLM.AzureDeployment
+	}
+	return c.LLM.Model
+}
+
+// GetAzureAPIVersion returns the Azure OpenAI REST API version
+func (c *Config) GetAzureAPIVersion() string {
+	if c.LLM.AzureAPIVersion != "" {
+		return c.LLM.AzureAPIVersion
+	}
+	return "2024-10-21"
+}
+
+// GetGeminiAPIKey returns the Gemini API key, checking direct key first then env var
+func (c *Config) GetGeminiAPIKey() string {
+	if c.LLM.APIKey != "" {
+		return c.LLM.APIKey
+	}
+	if c.LLM.APIKeyEnv != "" {
+		return os.Getenv(c.LLM.APIKeyEnv)
+	}
+	return ""
+}
+
+// GetAzureAPIKey returns the Azure OpenAI API key.
+// Priority: api_key > env var named by api_key_env > AZURE_OPENAI_API_KEY
+func (c *Config) GetAzureAPIKey() string {
+	if c.LLM.APIKey != "" {
+		return c.LLM.APIKey
+	}
+	if c.LLM.APIKeyEnv != "" {
+		if key := os.Getenv(c.LLM.APIKeyEnv); key != "" {
+			return key
+		}
+	}
+	return os.Getenv("AZURE_OPENAI_API_KEY")
+}
+
+// UsesEmbeddings returns true if report embeddings should be computed
+func (c *Config) UsesEmbeddings() bool {
+	return !c.LLM.DisableEmbeddings
+}
+
+// GetEmbeddingModel returns the embedding model for the configured provider.
+// For Azure OpenAI this is the name of the embeddings deployment.
+func (c *Config) GetEmbeddingModel() string {
+	if c.LLM.EmbeddingModel != "" {
+		return c.LLM.EmbeddingModel
+	}
+	if c.UsesAzureOpenAI() {
+		return "text-embedding-3-small"
+	}
+	return "gemini-embedding-001"
+}
+
+// GetLLMTimeout returns the timeout for a single LLM call
+func (c *Config) GetLLMTimeout() time.Duration {
+	if c.LLM.TimeoutSeconds > 0 {
+		return time.Duration(c.LLM.TimeoutSeconds) * time.Second
+	}
+	return 120 * time.Second
+}
+
+// GetLLMMaxRetries returns how many times a failed LLM call is retried
+func (c *Config) GetLLMMaxRetries() int {
+	return max(c.LLM.MaxRetries, 0)
+}
+
+// GetLLMRetryBackoff returns the initial and maximum backoff between LLM retries
+func (c *Config) GetLLMRetryBackoff() (initial, maxBackoff time.Duration) {
+	initial = 2 * time.Second
+	if c.LLM.RetryBackoffSeconds > 0 {
+		initial = time.Duration(c.LLM.RetryBackoffSeconds) * time.Second
+	}
+	maxBackoff = 30 * time.Second
+	if c.LLM.RetryMaxBackoffSeconds > 0 {
+		maxBackoff = time.Duration(c.LLM.RetryMaxBackoffSeconds) * time.Second
+	}
+	return initial, max(initial, maxBackoff)
+}
+
+// GetBackfillConcurrency returns how many weeks of a backfill are analyzed at
+// the same time
+func (c *Config) GetBackfillConcurrency() int {
+	c = c.Current()
+	return max(c.LLM.BackfillConcurrency, 1)
+}
+
+// LLMCost returns the price in USD of a call with the given token counts
+func (c *Config) LLMCost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*c.LLM.InputPricePerMTok + float64(outputTokens)*c.LLM.OutputPricePerMTok) / 1e6
+}
+
+// GetRetentionCutoffs returns the times before which activity runs, newsletter
+// send records and weekly reports expire. A zero time means the data is kept
+// forever.
+func (c *Config) GetRetentionCutoffs(now time.Time) (runs, sends, reports time.Time) {
+	c = c.Current()
+	cutoff := func(days int) time.Time {
+		if days <= 0 {
+			return time.Time{}
+		}
+		return now.AddDate(0, 0, -days)
+	}
+	return cutoff(c.Retention.ActivityRunsDays), cutoff(c.Retention.NewsletterSendsDays), cutoff(c.Retention.WeeklyReportsDays)
+}
+
+// GetRawDataCutoff returns the time before which the raw data of activity
+// runs expires, or a zero time if it is kept as long as the runs
+func (c *Config) GetRawDataCutoff(now time.Time) time.Time {
+	c = c.Current()
+	if c.Retention.RawDataDays <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -c.Retention.RawDataDays)
+}
+
+// GetBlobDir returns the directory of the local blob store
+func (c *Config) GetBlobDir() string {
+	if c.Blobs.Dir != "" {
+		return c.Blobs.Dir
+	}
+	return filepath.Join(c.DataDir, "blobs")
+}
+
+// GetBlobRegion returns the S3 region of the blob store
+func (c *Config) GetBlobRegion() string {
+	if c.Blobs.Region != "" {
+		return c.Blobs.Region
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// GetBlobEndpoint returns the S3 endpoint of the blob store
+func (c *Config) GetBlobEndpoint() string {
+	if c.Blobs.Endpoint != "" {
+		return c.Blobs.Endpoint
+	}
+	return "https://s3." + c.GetBlobRegion() + ".amazonaws.com"
+}
+
+// GetBlobAccessKeys returns the S3 access key ID and secret, checking direct
+// values first then env vars
+func (c *Config) GetBlobAccessKeys() (id, secret string) {
+	id, secret = c.Blobs.AccessKeyID, c.Blobs.SecretAccessKey
+	if id == "" && c.Blobs.AccessKeyIDEnv != "" {
+		id = os.Getenv(c.Blobs.AccessKeyIDEnv)
+	}
+	if secret == "" && c.Blobs.SecretAccessKeyEnv != "" {
+		secret = os.Getenv(c.Blobs.SecretAccessKeyEnv)
+	}
+	return id, secret
+}
+
+// HasMasterKey returns true if a master key for secrets stored in the
+// database is configured
+func (c *Config) HasMasterKey() bool {
+	current, _ := c.masterKeys()
+	return current != ""
+}
+
+// GetMasterKeys returns the decoded master key and, if set, the previous
+// master key, checking direct values first then env vars
+func (c *Config) GetMasterKeys() (current, previous []byte, err error) {
+	cur, prev := c.masterKeys()
+	if cur == "" {
+		return nil, nil, fmt.Errorf("no master key configured: set secrets.master_key or environment variable '%s'", c.Secrets.MasterKeyEnv)
+	}
+	if current, err = decodeMasterKey(cur); err != nil {
+		return nil, nil, fmt.Errorf("secrets.master_key: %w", err)
+	}
+	if prev != "" {
+		if previous, err = decodeMasterKey(prev); err != nil {
+			return nil, nil, fmt.Errorf("secrets.previous_master_key: %w", err)
+		}
+	}
+	return current, previous, nil
+}
+
+// masterKeys returns the encoded master keys
+func (c *Config) masterKeys() (current, previous string) {
+	current, previous = c.Secrets.MasterKey, c.Secrets.PreviousMasterKey
+	if current == "" && c.Secrets.MasterKeyEnv != "" {
+		current = os.Getenv(c.Secrets.MasterKeyEnv)
+	}
+	if previous == "" && c.Secrets.PreviousMasterKeyEnv != "" {
+		previous = os.Getenv(c.Secrets.PreviousMasterKeyEnv)
+	}
+	return current, previous
+}
+
+// decodeMasterKey decodes a base64-encoded 32-byte master key
+func decodeMasterKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64 encoded")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// GetPruneInterval returns how often the server prunes expired data, or 0 if
+// scheduled pruning is disabled
+func (c *Config) GetPruneInterval() time.Duration {
+	c = c.Current()
+	return time.Duration(max(c.Retention.PruneIntervalHours, 0)) * time.Hour
+}
+
+// GetDescriptionRefreshInterval returns how often the server refreshes
+// repository descriptions, or 0 if scheduled refreshes are disabled
+func (c *Config) GetDescriptionRefreshInterval() time.Duration {
+	c = c.Current()
+	return time.Duration(max(c.DescriptionRefreshHours, 0)) * time.Hour
+}
+
+// GetCorrectionThreshold returns the share of changed words that makes a
+// regenerated report worth a correction, between 0 and 1 (default 0.2)
+func (c *Config) GetCorrectionThreshold() float64 {
+	c = c.Current()
+	if c.Newsletter.CorrectionThreshold <= 0 {
+		return 0.2
+	}
+	return min(c.Newsletter.CorrectionThreshold, 1)
+}
+
+// NewsletterCheckInterval is how often the server checks for subscribers
+// whose scheduled newsletter is due
+const NewsletterCheckInterval = 15 * time.Minute
+
+// GetNewsletterScheduleInterval returns how often the server checks for due
+// newsletters, or 0 if newsletters are disabled or not scheduled
+func (c *Config) GetNewsletterScheduleInterval() time.Duration {
+	c = c.Current()
+	if !c.Newsletter.Enabled || !c.Newsletter.Scheduled {
+		return 0
+	}
+	return NewsletterCheckInterval
+}
+
+// StaleCheckInterval is how often the server checks for repositories that
+// went stale, to notify the admins
+const StaleCheckInterval = 24 * time.Hour
+
+// GetStaleCheckInterval returns how often to check for stale repositories,
+// or 0 unless notify_stale is set
+func (c *Config) GetStaleCheckInterval() time.Duration {
+	c = c.Current()
+	if !c.NotifyStale {
+		return 0
+	}
+	return StaleCheckInterval
+}
+
+// BotAuthorPatterns match common dependency update and CI bots. GitHub Apps
+// commit as "<app>[bot]".
+var BotAuthorPatterns = []string{
+	"*[bot]",
+	"dependabot*",
+	"renovate*",
+	"github-actions*",
+}
+
+// GetIgnoredAuthors returns the global ignore list combined with the repo's
+// own list, plus the bot patterns if ignore_bots is set
+func (c *Config) GetIgnoredAuthors(repoName string) []string {
+	ignored := append([]string{}, c.IgnoreAuthors...)
+	if c.IgnoreBots {
+		ignored = append(ignored, BotAuthorPatterns...)
+	}
+	if repoCfg, ok := c.Repos[repoName]; ok {
+		ignored = append(ignored, repoCfg.IgnoreAuthors...)
+	}
+	return ignored
+}
+
+// GetStaleWeeks returns the number of weeks without commits after which a
+// repository is stale, or 0 if it is never flagged
+func (c *Config) GetStaleWeeks(repoName string) int {
+	c = c.Current()
+	weeks := c.StaleWeeks
+	if repoWeeks := c.Repos[repoName].StaleWeeks; repoWeeks != 0 {
+		weeks = repoWeeks
+	}
+	return max(weeks, 0)
+}
+
+// GetRepoConfig returns the per-repository overrides for a repository (zero value if none)
+func (c *Config) GetRepoConfig(repoName string) RepoConfig {
+	return c.Repos[repoName]
+}
+
 // GetPhase2Prompt returns the Phase 2 prompt, either custom or default
 func (c *Config) GetPhase2Prompt() string {
+	c = c.Current()
 	if c.LLM.Phase2Prompt != "" {
 		return c.LLM.Phase2Prompt
 	}
@@ -228,6 +1084,7 @@ func (c *Config) GetPhase2Prompt() string {
 
 // GetAgentSystemPrompt returns the agent system prompt, either custom or default
 func (c *Config) GetAgentSystemPrompt() string {
+	c = c.Current()
 	if c.LLM.AgentSystemPrompt != "" {
 		return c.LLM.AgentSystemPrompt
 	}
@@ -246,6 +1103,7 @@ Keep the summary under 300 words and use clear, professional language.`
 
 // GetSendGridAPIKey returns the SendGrid API key, checking direct key first then env var
 func (c *Config) GetSendGridAPIKey() string {
+	c = c.Current()
 	if c.Newsletter.SendGridAPIKey != "" {
 		return c.Newsletter.SendGridAPIKey
 	}
@@ -255,6 +1113,91 @@ func (c *Config) GetSendGridAPIKey() string {
 	return ""
 }
 
+// GetGitLabToken returns the GitLab access token, checking direct token first then env var
+func (c *Config) GetGitLabToken() string {
+	c = c.Current()
+	if c.Issues.GitLabToken != "" {
+		return c.Issues.GitLabToken
+	}
+	if c.Issues.GitLabTokenEnv != "" {
+		return os.Getenv(c.Issues.GitLabTokenEnv)
+	}
+	return ""
+}
+
+// GetJiraToken returns the Jira API token, checking direct token first then env var
+func (c *Config) GetJiraToken() string {
+	c = c.Current()
+	if c.Jira.Token != "" {
+		return c.Jira.Token
+	}
+	if c.Jira.TokenEnv != "" {
+		return os.Getenv(c.Jira.TokenEnv)
+	}
+	return ""
+}
+
+// GetConfluenceToken returns the Confluence API token, checking direct token first then env var
+func (c *Config) GetConfluenceToken() string {
+	c = c.Current()
+	if c.Confluence.Token != "" {
+		return c.Confluence.Token
+	}
+	if c.Confluence.TokenEnv != "" {
+		return os.Getenv(c.Confluence.TokenEnv)
+	}
+	return ""
+}
+
+// GetNotionToken returns the Notion integration token, checking direct token first then env var
+func (c *Config) GetNotionToken() string {
+	c = c.Current()
+	if c.Notion.Token != "" {
+		return c.Notion.Token
+	}
+	if c.Notion.TokenEnv != "" {
+		return os.Getenv(c.Notion.TokenEnv)
+	}
+	return ""
+}
+
+// GetPostmarkToken returns the Postmark server token, checking direct token first then env var
+func (c *Config) GetPostmarkToken() string {
+	c = c.Current()
+	if c.Newsletter.PostmarkToken != "" {
+		return c.Newsletter.PostmarkToken
+	}
+	if c.Newsletter.PostmarkTokenEnv != "" {
+		return os.Getenv(c.Newsletter.PostmarkTokenEnv)
+	}
+	return ""
+}
+
+// GetMailgunAPIKey returns the Mailgun API key, checking direct key first then env var
+func (c *Config) GetMailgunAPIKey() string {
+	c = c.Current()
+	if c.Newsletter.MailgunAPIKey != "" {
+		return c.Newsletter.MailgunAPIKey
+	}
+	if c.Newsletter.MailgunKeyEnv != "" {
+		return os.Getenv(c.Newsletter.MailgunKeyEnv)
+	}
+	return ""
+}
+
+// GetSendGridWebhookKey returns the verification key of the SendGrid event
+// webhook, checking direct key first then env var
+func (c *Config) GetSendGridWebhookKey() string {
+	c = c.Current()
+	if c.Newsletter.WebhookKey != "" {
+		return c.Newsletter.WebhookKey
+	}
+	if c.Newsletter.WebhookKeyEnv != "" {
+		return os.Getenv(c.Newsletter.WebhookKeyEnv)
+	}
+	return ""
+}
+
 // HasGitHubApp returns true if GitHub App authentication is configured
 func (c *Config) HasGitHubApp() bool {
 	return c.GetGitHubAppID() != 0 && c.GetGitHubInstallationID() != 0
@@ -290,8 +1233,18 @@ func (c *Config) GetGitHubInstallationID() int64 {
 	return 0
 }
 
-// GetGitHubPrivateKey returns the GitHub App private key, checking file path first then env var
+// ErrNoGitHubPrivateKey is returned by GetGitHubPrivateKey if neither the
+// config, a key file nor an environment variable holds the key, which may then
+// be stored in the database as the github.private_key secret
+var ErrNoGitHubPrivateKey = errors.New("no GitHub App private key configured")
+
+// GetGitHubPrivateKey returns the GitHub App private key, checking the direct
+// value first, then the file path, then the env var
 func (c *Config) GetGitHubPrivateKey() ([]byte, error) {
+	if c.GitHub.PrivateKey != "" {
+		return []byte(c.GitHub.PrivateKey), nil
+	}
+
 	// Check file path first
 	if c.GitHub.PrivateKeyPath != "" {
 		path := expandPath(c.GitHub.PrivateKeyPath)
@@ -310,7 +1263,7 @@ func (c *Config) GetGitHubPrivateKey() ([]byte, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("no GitHub App private key configured")
+	return nil, ErrNoGitHubPrivateKey
 }
 
 // DefaultAgentSystemPrompt is the default system instruction for Phase 3 agent
@@ -328,7 +1281,8 @@ IMPORTANT GUIDELINES:
    - You need to verify the scope of a change
    - The message references a ticket/issue without explanation (e.g., "Fix #123")
 4. You have LIMITED diff fetches (max %d per analysis) - use them wisely
-5. Before fetching a diff, consider using get_full_commit_message if the message was truncated
+5. Before fetching a diff, consider using get_full_commit_message if the message was truncated,
+   and get_commit_diffstat (cheap, not limited) to see which files a commit touched and how much
 6. Prioritize diffs for:
    - Unclear messages that seem important
    - Commits that likely have significant impact
@@ -347,6 +1301,23 @@ Provide a summary with these sections:
 Keep the summary under 400 words and use clear, professional language.
 If you had to skip analyzing some commits due to limits, mention this briefly at the end.`
 
+// DefaultAskSystemPrompt is the system instruction for the agent answering ad-hoc
+// questions about a repository. Argument: maximum number of diff fetches.
+const DefaultAskSystemPrompt = `You are an assistant that answers questions about a git repository's history and code.
+
+Investigate the repository with your tools before answering:
+- search_commits finds commits by message, author, path and date range
+- get_full_commit_message shows a commit's full message
+- get_commit_diff shows what a commit changed (LIMITED: max %d per question)
+- get_commit_diffstat lists the files a commit changed with line counts (cheap)
+- get_author_stats shows an author's contribution history
+- read_file reads a file or lists a directory at the current version
+
+Start with search_commits and commit messages; only fetch diffs or read files
+when messages are not enough. Cite the commits (short SHA and date) your
+answer is based on. If you cannot find the answer, say what you looked for
+instead of guessing. Keep the answer concise.`
+
 // DefaultDescriptionPrompt is the prompt used to generate repository descriptions from README files
 const DefaultDescriptionPrompt = `Summarize this software project in 2-3 sentences for someone who will be reading commit summaries. Focus on:
 - What the project IS (tool, library, service, etc.)
@@ -365,3 +1336,39 @@ README content:
 ---
 
 Provide only the summary, no preamble.`
+
+// DefaultComparePrompt is the prompt used to describe what changed between
+// two consecutive weekly reports. Arguments: repository name, previous week
+// label, previous summary, current week label and current summary.
+const DefaultComparePrompt = `You compare two consecutive weekly development reports of the repository %q.
+
+Previous week (%s):
+---
+%s
+---
+
+This week (%s):
+---
+%s
+---
+
+Write one short paragraph (at most 100 words) on what changed since last week:
+work that started, continued, finished or stopped, and shifts in focus. Do not
+repeat this week's report. Base it only on the two reports. Provide only the
+paragraph, no preamble.`
+
+// DefaultChatPrompt is the prompt used to answer questions about a repository's
+// history. Arguments: repository name, repository description, retrieved
+// context, previous conversation and the question.
+const DefaultChatPrompt = `You answer questions about the development history of the repository %q.
+%s
+Answer using only the weekly reports and commit metadata below. Mention the
+week(s) your answer is based on, e.g. "(2026-W03)". If the context does not
+contain the answer, say so rather than guessing. Be concise.
+
+Context:
+---
+%s
+---
+%s
+Question: %s`
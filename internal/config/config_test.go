@@ -3,8 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestExpandPath(t *testing.T) {
@@ -139,6 +141,12 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("default Newsletter.SendGridKeyEnv = %q, want %q",
 			cfg.Newsletter.SendGridKeyEnv, "SENDGRID_API_KEY")
 	}
+	if !cfg.Newsletter.AutoApprove {
+		t.Error("default Newsletter.AutoApprove should be true")
+	}
+	if cfg.Newsletter.MaxSections != 10 {
+		t.Errorf("default Newsletter.MaxSections = %d, want 10", cfg.Newsletter.MaxSections)
+	}
 }
 
 func TestGetPhase2Prompt(t *testing.T) {
@@ -241,6 +249,60 @@ func TestGetSendGridAPIKey(t *testing.T) {
 	}
 }
 
+func TestGetProviderKeys(t *testing.T) {
+	t.Setenv("TEST_POSTMARK_TOKEN", "pm-env")
+	t.Setenv("TEST_MAILGUN_KEY", "mg-env")
+
+	cfg := &Config{
+		Newsletter: NewsletterConfig{
+			PostmarkTokenEnv: "TEST_POSTMARK_TOKEN",
+			MailgunKeyEnv:    "TEST_MAILGUN_KEY",
+		},
+	}
+	if got := cfg.GetPostmarkToken(); got != "pm-env" {
+		t.Errorf("GetPostmarkToken() with env var = %q, want %q", got, "pm-env")
+	}
+	if got := cfg.GetMailgunAPIKey(); got != "mg-env" {
+		t.Errorf("GetMailgunAPIKey() with env var = %q, want %q", got, "mg-env")
+	}
+
+	cfg.Newsletter.PostmarkToken = "pm-direct"
+	cfg.Newsletter.MailgunAPIKey = "mg-direct"
+	if got := cfg.GetPostmarkToken(); got != "pm-direct" {
+		t.Errorf("GetPostmarkToken() with direct token = %q, want %q", got, "pm-direct")
+	}
+	if got := cfg.GetMailgunAPIKey(); got != "mg-direct" {
+		t.Errorf("GetMailgunAPIKey() with direct key = %q, want %q", got, "mg-direct")
+	}
+
+	defaults := DefaultConfig().Newsletter
+	if defaults.Provider != "sendgrid" || defaults.MailgunBaseURL != "https://api.mailgun.net" {
+		t.Errorf("default provider = %q, mailgun base URL = %q", defaults.Provider, defaults.MailgunBaseURL)
+	}
+}
+
+func TestGetSendGridWebhookKey(t *testing.T) {
+	cfg := &Config{
+		Newsletter: NewsletterConfig{
+			WebhookKey:    "direct-key",
+			WebhookKeyEnv: "TEST_WEBHOOK_KEY_FOR_TEST",
+		},
+	}
+	if got := cfg.GetSendGridWebhookKey(); got != "direct-key" {
+		t.Errorf("GetSendGridWebhookKey() with direct key = %q, want %q", got, "direct-key")
+	}
+
+	t.Setenv("TEST_WEBHOOK_KEY_FOR_TEST", "env-key")
+	cfg.Newsletter.WebhookKey = ""
+	if got := cfg.GetSendGridWebhookKey(); got != "env-key" {
+		t.Errorf("GetSendGridWebhookKey() with env var = %q, want %q", got, "env-key")
+	}
+
+	if got := DefaultConfig().Newsletter.WebhookKeyEnv; got != "SENDGRID_WEBHOOK_KEY" {
+		t.Errorf("default WebhookKeyEnv = %q, want SENDGRID_WEBHOOK_KEY", got)
+	}
+}
+
 func TestGetGitHubAppID(t *testing.T) {
 	// Test direct value takes precedence
 	cfg := &Config{
@@ -352,3 +414,322 @@ func TestHasGitHubAppWithEnvVars(t *testing.T) {
 		t.Error("HasGitHubApp() should be true when env vars are set")
 	}
 }
+
+func TestVertexSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.UsesVertexAI() {
+		t.Error("UsesVertexAI() should be false by default")
+	}
+
+	cfg.LLM.Provider = "vertex"
+	if !cfg.UsesVertexAI() {
+		t.Error("UsesVertexAI() should be true for provider 'vertex'")
+	}
+
+	// Env var fallbacks
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "env-project")
+	t.Setenv("GOOGLE_CLOUD_LOCATION", "")
+	if got := cfg.GetVertexProject(); got != "env-project" {
+		t.Errorf("GetVertexProject() with env var = %q, want %q", got, "env-project")
+	}
+	if got := cfg.GetVertexLocation(); got != "us-central1" {
+		t.Errorf("GetVertexLocation() default = %q, want %q", got, "us-central1")
+	}
+
+	// Direct values take precedence
+	cfg.LLM.VertexProject = "my-project"
+	cfg.LLM.VertexLocation = "europe-north1"
+	if got := cfg.GetVertexProject(); got != "my-project" {
+		t.Errorf("GetVertexProject() = %q, want %q", got, "my-project")
+	}
+	if got := cfg.GetVertexLocation(); got != "europe-north1" {
+		t.Errorf("GetVertexLocation() = %q, want %q", got, "europe-north1")
+	}
+}
+
+func TestAzureSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LLM.Provider = "azure"
+	cfg.LLM.Model = "gpt-4o"
+	if !cfg.UsesAzureOpenAI() {
+		t.Error("UsesAzureOpenAI() should be true for provider 'azure'")
+	}
+
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://example.openai.azure.com/")
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+	t.Setenv("GOOGLE_API_KEY", "")
+
+	if got := cfg.GetAzureEndpoint(); got != "https://example.openai.azure.com" {
+		t.Errorf("GetAzureEndpoint() = %q, want trailing slash trimmed", got)
+	}
+	if got := cfg.GetAzureDeployment(); got != "gpt-4o" {
+		t.Errorf("GetAzureDeployment() default = %q, want %q", got, "gpt-4o")
+	}
+	if got := cfg.GetAzureAPIVersion(); got != "2024-10-21" {
+		t.Errorf("GetAzureAPIVersion() default = %q, want %q", got, "2024-10-21")
+	}
+	if got := cfg.GetAzureAPIKey(); got != "azure-key" {
+		t.Errorf("GetAzureAPIKey() fallback = %q, want %q", got, "azure-key")
+	}
+
+	cfg.LLM.AzureDeployment = "prod-gpt4o"
+	cfg.LLM.APIKey = "direct-key"
+	if got := cfg.GetAzureDeployment(); got != "prod-gpt4o" {
+		t.Errorf("GetAzureDeployment() = %q, want %q", got, "prod-gpt4o")
+	}
+	if got := cfg.GetAzureAPIKey(); got != "direct-key" {
+		t.Errorf("GetAzureAPIKey() = %q, want %q", got, "direct-key")
+	}
+}
+
+func TestGetEmbeddingModel(t *testing.T) {
+	cfg := DefaultConfig()
+	if !cfg.UsesEmbeddings() {
+		t.Error("UsesEmbeddings() should be true by default")
+	}
+	if got := cfg.GetEmbeddingModel(); got != "gemini-embedding-001" {
+		t.Errorf("GetEmbeddingModel() = %q, want %q", got, "gemini-embedding-001")
+	}
+
+	cfg.LLM.Provider = "azure"
+	if got := cfg.GetEmbeddingModel(); got != "text-embedding-3-small" {
+		t.Errorf("GetEmbeddingModel() azure = %q, want %q", got, "text-embedding-3-small")
+	}
+
+	cfg.LLM.EmbeddingModel = "my-embeddings"
+	if got := cfg.GetEmbeddingModel(); got != "my-embeddings" {
+		t.Errorf("GetEmbeddingModel() = %q, want %q", got, "my-embeddings")
+	}
+}
+
+func TestGetBasePath(t *testing.T) {
+	tests := []struct {
+		basePath string
+		want     string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"/activity", "/activity"},
+		{"activity/", "/activity"},
+		{" /tools/activity/ ", "/tools/activity"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Web.BasePath = tt.basePath
+		if got := cfg.GetBasePath(); got != tt.want {
+			t.Errorf("GetBasePath(%q) = %q, want %q", tt.basePath, got, tt.want)
+		}
+	}
+}
+
+func TestGetTemplatesDir(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	if got := cfg.GetTemplatesDir(); got != "" {
+		t.Errorf("GetTemplatesDir() without a templates directory = %q, want none", got)
+	}
+
+	dir := filepath.Join(cfg.DataDir, "templates")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.GetTemplatesDir(); got != dir {
+		t.Errorf("GetTemplatesDir() = %q, want %q", got, dir)
+	}
+
+	cfg.Web.TemplatesDir = "/etc/activity/templates"
+	if got := cfg.GetTemplatesDir(); got != "/etc/activity/templates" {
+		t.Errorf("GetTemplatesDir() with web.templates_dir = %q, want it", got)
+	}
+}
+
+func TestBranding(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.GetSiteName(); got != "activity" {
+		t.Errorf("GetSiteName() default = %q, want %q", got, "activity")
+	}
+	if links := cfg.GetNavLinks(); len(links) != 0 {
+		t.Errorf("GetNavLinks() default = %v, want none", links)
+	}
+
+	cfg.Branding.SiteName = "Eng Weekly"
+	cfg.Branding.NavLinks = []string{"Wiki = https://wiki.example.com/?a=b", "broken"}
+	if got := cfg.GetSiteName(); got != "Eng Weekly" {
+		t.Errorf("GetSiteName() = %q, want %q", got, "Eng Weekly")
+	}
+	want := []NavLink{{Label: "Wiki", URL: "https://wiki.example.com/?a=b"}}
+	if got := cfg.GetNavLinks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNavLinks() = %v, want %v", got, want)
+	}
+}
+
+func TestLLMRetrySettings(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.GetLLMTimeout(); got != 120*time.Second {
+		t.Errorf("GetLLMTimeout() default = %v, want %v", got, 120*time.Second)
+	}
+	if got := cfg.GetLLMMaxRetries(); got != 3 {
+		t.Errorf("GetLLMMaxRetries() default = %d, want 3", got)
+	}
+
+	cfg.LLM.TimeoutSeconds = 0
+	cfg.LLM.MaxRetries = -1
+	cfg.LLM.RetryBackoffSeconds = 5
+	cfg.LLM.RetryMaxBackoffSeconds = 1
+	if got := cfg.GetLLMTimeout(); got != 120*time.Second {
+		t.Errorf("GetLLMTimeout() unset = %v, want %v", got, 120*time.Second)
+	}
+	if got := cfg.GetLLMMaxRetries(); got != 0 {
+		t.Errorf("GetLLMMaxRetries() negative = %d, want 0", got)
+	}
+	initial, maxBackoff := cfg.GetLLMRetryBackoff()
+	if initial != 5*time.Second || maxBackoff != 5*time.Second {
+		t.Errorf("GetLLMRetryBackoff() = %v, %v, want cap raised to initial backoff", initial, maxBackoff)
+	}
+}
+
+func TestGetStaleWeeks(t *testing.T) {
+	cfg := &Config{
+		StaleWeeks: 8,
+		Repos: map[string]RepoConfig{
+			"slow":   {StaleWeeks: 26},
+			"frozen": {StaleWeeks: -1},
+		},
+	}
+	for repo, want := range map[string]int{"backend": 8, "slow": 26, "frozen": 0} {
+		if got := cfg.GetStaleWeeks(repo); got != want {
+			t.Errorf("GetStaleWeeks(%q) = %d, want %d", repo, got, want)
+		}
+	}
+
+	cfg.StaleWeeks = 0
+	if got := cfg.GetStaleWeeks("backend"); got != 0 {
+		t.Errorf("GetStaleWeeks with stale_weeks unset = %d, want 0", got)
+	}
+	if got := cfg.GetStaleWeeks("slow"); got != 26 {
+		t.Errorf("GetStaleWeeks of a repo with its own threshold = %d, want 26", got)
+	}
+}
+
+func TestGetIgnoredAuthors(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `
+ignore_authors:
+  - "dependabot[bot]"
+repos:
+  frontend:
+    ignore_authors:
+      - "renovate[bot]"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.GetIgnoredAuthors("backend"); len(got) != 1 || got[0] != "dependabot[bot]" {
+		t.Errorf("GetIgnoredAuthors(backend) = %v, want [dependabot[bot]]", got)
+	}
+	if got := cfg.GetIgnoredAuthors("frontend"); len(got) != 2 || got[1] != "renovate[bot]" {
+		t.Errorf("GetIgnoredAuthors(frontend) = %v, want [dependabot[bot] renovate[bot]]", got)
+	}
+
+	cfg.IgnoreBots = true
+	if got := cfg.GetIgnoredAuthors("backend"); len(got) != 1+len(BotAuthorPatterns) || got[1] != BotAuthorPatterns[0] {
+		t.Errorf("GetIgnoredAuthors(backend) with ignore_bots = %v, want the bot patterns appended", got)
+	}
+}
+
+func TestGetRetentionCutoffs(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	cfg := DefaultConfig()
+	runs, sends, reports := cfg.GetRetentionCutoffs(now)
+	if want := now.AddDate(0, 0, -90); !runs.Equal(want) {
+		t.Errorf("activity runs cutoff = %v, want %v", runs, want)
+	}
+	if want := now.AddDate(0, 0, -365); !sends.Equal(want) {
+		t.Errorf("newsletter sends cutoff = %v, want %v", sends, want)
+	}
+	if !reports.IsZero() {
+		t.Errorf("weekly reports cutoff = %v, want zero (keep forever)", reports)
+	}
+	if got := cfg.GetPruneInterval(); got != 24*time.Hour {
+		t.Errorf("GetPruneInterval() default = %v, want %v", got, 24*time.Hour)
+	}
+
+	cfg.Retention.ActivityRunsDays = 0
+	cfg.Retention.PruneIntervalHours = -1
+	if runs, _, _ := cfg.GetRetentionCutoffs(now); !runs.IsZero() {
+		t.Errorf("activity runs cutoff with 0 days = %v, want zero", runs)
+	}
+	if got := cfg.GetPruneInterval(); got != 0 {
+		t.Errorf("GetPruneInterval() negative = %v, want 0", got)
+	}
+}
+
+func TestGetNewsletterScheduleInterval(t *testing.T) {
+	cfg := &Config{}
+	cfg.Newsletter.Scheduled = true
+	if got := cfg.GetNewsletterScheduleInterval(); got != 0 {
+		t.Errorf("GetNewsletterScheduleInterval() with newsletter disabled = %v, want 0", got)
+	}
+
+	cfg.Newsletter.Enabled = true
+	if got := cfg.GetNewsletterScheduleInterval(); got != NewsletterCheckInterval {
+		t.Errorf("GetNewsletterScheduleInterval() = %v, want %v", got, NewsletterCheckInterval)
+	}
+
+	cfg.Newsletter.Scheduled = false
+	if got := cfg.GetNewsletterScheduleInterval(); got != 0 {
+		t.Errorf("GetNewsletterScheduleInterval() unscheduled = %v, want 0", got)
+	}
+}
+
+func TestGetCorrectionThreshold(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		want      float64
+	}{
+		{0, 0.2},
+		{-1, 0.2},
+		{0.5, 0.5},
+		{3, 1},
+	}
+	for _, tt := range tests {
+		cfg := &Config{}
+		cfg.Newsletter.CorrectionThreshold = tt.threshold
+		if got := cfg.GetCorrectionThreshold(); got != tt.want {
+			t.Errorf("GetCorrectionThreshold() with %v = %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestGetDescriptionRefreshInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.GetDescriptionRefreshInterval(); got != 24*time.Hour {
+		t.Errorf("GetDescriptionRefreshInterval() default = %v, want %v", got, 24*time.Hour)
+	}
+
+	cfg.DescriptionRefreshHours = 0
+	if got := cfg.GetDescriptionRefreshInterval(); got != 0 {
+		t.Errorf("GetDescriptionRefreshInterval() disabled = %v, want 0", got)
+	}
+}
+
+func TestLLMCost(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LLM.InputPricePerMTok = 1.25
+	cfg.LLM.OutputPricePerMTok = 10
+
+	if got, want := cfg.LLMCost(2_000_000, 100_000), 3.5; got != want {
+		t.Errorf("LLMCost() = %f, want %f", got, want)
+	}
+	if got := cfg.LLMCost(0, 0); got != 0 {
+		t.Errorf("LLMCost(0, 0) = %f, want 0", got)
+	}
+}
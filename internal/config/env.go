@@ -0,0 +1,257 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables that override config
+// settings. The rest of the name is the setting's YAML path in upper case
+// joined by underscores, e.g. ACTIVITY_LLM_MAX_DIFF_FETCHES for
+// llm.max_diff_fetches. Lists are comma-separated.
+const EnvPrefix = "ACTIVITY_"
+
+// redacted replaces secret values in output
+const redacted = "(redacted)"
+
+// Sources of a setting's value, in increasing order of precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag" // Command line flags such as --data-dir
+)
+
+// Setting is a config setting with its effective value and where it came from
+type Setting struct {
+	Key    string // YAML path, e.g. llm.max_diff_fetches
+	Env    string // Environment variable overriding the setting
+	Value  string // Effective value, redacted for secrets
+	Source string // SourceDefault, SourceFile, SourceEnv or SourceFlag
+}
+
+// Settings returns every setting that can be set from the environment, in
+// YAML order, with its effective value. Per-repository overrides (repos) can
+// only be set in the config file and are not included.
+func (c *Config) Settings() []Setting {
+	c = c.Current()
+	var settings []Setting
+	walkSettings(reflect.ValueOf(c).Elem(), nil, func(path []string, field reflect.StructField, v reflect.Value) {
+		key := strings.Join(path, ".")
+		s := Setting{
+			Key:    key,
+			Env:    envName(path),
+			Value:  formatSetting(v),
+			Source: SourceDefault,
+		}
+		if source, ok := c.sources[key]; ok {
+			s.Source = source
+		}
+		if field.Tag.Get("secret") == "true" && s.Value != "" {
+			s.Value = redacted
+		}
+		settings = append(settings, s)
+	})
+	return settings
+}
+
+// Redacted returns a copy of the config with secret values replaced, safe to
+// print
+func (c *Config) Redacted() *Config {
+	cp := *c.Current()
+	walkSettings(reflect.ValueOf(&cp).Elem(), nil, func(_ []string, field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("secret") == "true" && v.String() != "" {
+			v.SetString(redacted)
+		}
+	})
+	return &cp
+}
+
+// SecretResolver returns the secret a config value refers to, or the value
+// itself if it is not a reference
+type SecretResolver func(ctx context.Context, value string) (string, error)
+
+// ResolveSecrets replaces the values of secret settings, such as llm.api_key,
+// with what resolve returns for them, so they can hold references to an
+// external secret manager instead of the secret itself
+func (c *Config) ResolveSecrets(ctx context.Context, resolve SecretResolver) error {
+	var errs []string
+	walkSettings(reflect.ValueOf(c).Elem(), nil, func(path []string, field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("secret") != "true" || v.Kind() != reflect.String || v.String() == "" {
+			return
+		}
+		secret, err := resolve(ctx, v.String())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", strings.Join(path, "."), err))
+			return
+		}
+		v.SetString(secret)
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to resolve secrets: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// applyEnv overrides settings from ACTIVITY_* environment variables and
+// records them as coming from the environment
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []string
+	walkSettings(reflect.ValueOf(c).Elem(), nil, func(path []string, field reflect.StructField, v reflect.Value) {
+		name := envName(path)
+		raw, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if err := setSetting(v, raw); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		c.SetSource(strings.Join(path, "."), SourceEnv)
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// recordFileSources marks the settings present in a config file
+func (c *Config) recordFileSources(data []byte) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return
+	}
+	var visit func(prefix string, m map[string]any)
+	visit = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			key := prefix + k
+			if nested, ok := v.(map[string]any); ok {
+				visit(key+".", nested)
+				continue
+			}
+			c.SetSource(key, SourceFile)
+		}
+	}
+	visit("", doc)
+}
+
+// SetSource records where the setting with the given YAML path got its value,
+// for settings changed after Load such as by command line flags
+func (c *Config) SetSource(key, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+}
+
+// walkSettings calls fn for each scalar or list field of the struct v that
+// has a YAML name, recursing into nested structs. Maps are skipped.
+func walkSettings(v reflect.Value, path []string, fn func(path []string, field reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), name)
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			walkSettings(v.Field(i), fieldPath, fn)
+		case reflect.Map:
+			continue
+		default:
+			fn(fieldPath, field, v.Field(i))
+		}
+	}
+}
+
+// envName returns the environment variable for a setting's YAML path
+func envName(path []string) string {
+	return EnvPrefix + strings.ToUpper(strings.Join(path, "_"))
+}
+
+// setSetting parses raw into the setting v according to its type
+func setSetting(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// formatSetting formats a setting's value the way it is set from the environment
+func formatSetting(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// UnknownEnv returns the set ACTIVITY_* environment variables that match no
+// setting, sorted. They are likely misspelled.
+func (c *Config) UnknownEnv() []string {
+	return c.unknownEnv(os.Environ())
+}
+
+func (c *Config) unknownEnv(environ []string) []string {
+	known := make(map[string]bool)
+	walkSettings(reflect.ValueOf(c).Elem(), nil, func(path []string, _ reflect.StructField, _ reflect.Value) {
+		known[envName(path)] = true
+	})
+	var unknown []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, EnvPrefix) && !known[name] && !legacyEnv[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// legacyEnv lists ACTIVITY_* variables read outside the settings mapping
+var legacyEnv = map[string]bool{
+	"ACTIVITY_SEED_ADMIN": true,
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvOverrides(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+data_dir: /from/file
+llm:
+  model: file-model
+  max_diff_fetches: 3
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	t.Setenv("ACTIVITY_LLM_MAX_DIFF_FETCHES", "7")
+	t.Setenv("ACTIVITY_NEWSLETTER_ENABLED", "true")
+	t.Setenv("ACTIVITY_LLM_INPUT_PRICE_PER_MTOK", "1.5")
+	t.Setenv("ACTIVITY_IGNORE_AUTHORS", "dependabot[bot], *@ci.example.com")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.LLM.Model != "file-model" {
+		t.Errorf("LLM.Model = %q, want file value", cfg.LLM.Model)
+	}
+	if cfg.LLM.MaxDiffFetches != 7 {
+		t.Errorf("LLM.MaxDiffFetches = %d, want env value 7", cfg.LLM.MaxDiffFetches)
+	}
+	if !cfg.Newsletter.Enabled {
+		t.Error("Newsletter.Enabled should be set from env")
+	}
+	if cfg.LLM.InputPricePerMTok != 1.5 {
+		t.Errorf("LLM.InputPricePerMTok = %v, want 1.5", cfg.LLM.InputPricePerMTok)
+	}
+	if want := []string{"dependabot[bot]", "*@ci.example.com"}; !reflect.DeepEqual(cfg.IgnoreAuthors, want) {
+		t.Errorf("IgnoreAuthors = %v, want %v", cfg.IgnoreAuthors, want)
+	}
+
+	sources := make(map[string]string)
+	for _, s := range cfg.Settings() {
+		sources[s.Key] = s.Source
+	}
+	for key, want := range map[string]string{
+		"data_dir":             SourceFile,
+		"llm.model":            SourceFile,
+		"llm.max_diff_fetches": SourceEnv,
+		"llm.provider":         SourceDefault,
+	} {
+		if sources[key] != want {
+			t.Errorf("source of %s = %q, want %q", key, sources[key], want)
+		}
+	}
+}
+
+func TestLoadEnvWithoutFile(t *testing.T) {
+	t.Setenv("ACTIVITY_DATA_DIR", "/from/env")
+	t.Setenv("ACTIVITY_DATABASE_DSN", "postgres://localhost/activity")
+
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DataDir != "/from/env" {
+		t.Errorf("DataDir = %q, want /from/env", cfg.DataDir)
+	}
+	if cfg.GetDatabaseDSN() != "postgres://localhost/activity" {
+		t.Errorf("GetDatabaseDSN() = %q, want env value", cfg.GetDatabaseDSN())
+	}
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	t.Setenv("ACTIVITY_LLM_USE_AGENT", "sometimes")
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() with invalid boolean should fail")
+	}
+}
+
+func TestSettingsRedactsSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LLM.APIKey = "secret-key"
+
+	for _, s := range cfg.Settings() {
+		if s.Key == "llm.api_key" && s.Value != redacted {
+			t.Errorf("llm.api_key value = %q, want it redacted", s.Value)
+		}
+	}
+	if got := cfg.Redacted().LLM.APIKey; got != redacted {
+		t.Errorf("Redacted().LLM.APIKey = %q, want it redacted", got)
+	}
+	if cfg.LLM.APIKey != "secret-key" {
+		t.Error("Redacted() must not modify the original config")
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LLM.APIKey = "vault://secret/data/activity#llm"
+	cfg.Newsletter.SendGridAPIKey = "plain-key"
+	cfg.LLM.Model = "vault://not/a/secret"
+
+	resolve := func(_ context.Context, value string) (string, error) {
+		if value == "vault://secret/data/activity#llm" {
+			return "resolved-key", nil
+		}
+		return value, nil
+	}
+	if err := cfg.ResolveSecrets(context.Background(), resolve); err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if cfg.LLM.APIKey != "resolved-key" {
+		t.Errorf("LLM.APIKey = %q, want resolved-key", cfg.LLM.APIKey)
+	}
+	if cfg.Newsletter.SendGridAPIKey != "plain-key" {
+		t.Errorf("Newsletter.SendGridAPIKey = %q, want plain-key", cfg.Newsletter.SendGridAPIKey)
+	}
+	if cfg.LLM.Model != "vault://not/a/secret" {
+		t.Error("ResolveSecrets() must only resolve secret settings")
+	}
+
+	failing := func(context.Context, string) (string, error) {
+		return "", errors.New("vault unreachable")
+	}
+	err := cfg.ResolveSecrets(context.Background(), failing)
+	if err == nil || !strings.Contains(err.Error(), "llm.api_key: vault unreachable") {
+		t.Errorf("ResolveSecrets() error = %v, want it to name llm.api_key", err)
+	}
+}
+
+func TestUnknownEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	environ := []string{
+		"ACTIVITY_LLM_MODEL=x",
+		"ACTIVITY_LLM_MODLE=x",
+		"ACTIVITY_SEED_ADMIN=admin@example.com",
+		"HOME=/root",
+	}
+	if got, want := cfg.unknownEnv(environ), []string{"ACTIVITY_LLM_MODLE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unknownEnv() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// WatchInterval is how often Watch checks the config file for changes
+const WatchInterval = 5 * time.Second
+
+// reloadable lists the settings a running server picks up from a reloaded
+// config, as YAML paths or path prefixes ending in ".". They are read through
+// Config.Current each time they are used; everything else (database,
+// data_dir, LLM provider and credentials, GitHub App, web auth) is only read
+// on startup.
+var reloadable = []string{
+	"debug",
+	"llm.phase2_prompt",
+	"llm.agent_system_prompt",
+	"llm.backfill_concurrency",
+	"newsletter.",
+	"retention.",
+	"description_refresh_hours",
+	"follow_default_branch",
+	"stale_weeks",
+	"notify_stale",
+	"github.ci_health",
+	"issues.",
+	"jira.",
+	"confluence.",
+	"notion.",
+	"web.shutdown_timeout_seconds",
+	"web.rate_limit_per_minute",
+	"web.rate_limit_burst",
+	"web.webhook_rate_limit_per_minute",
+	"branding.",
+	"leaderboard.",
+}
+
+// isReloadable reports whether the setting with the given YAML path can
+// change without a restart
+func isReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || (strings.HasSuffix(r, ".") && strings.HasPrefix(key, r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the config with the latest reloaded settings: the last
+// snapshot published by ApplyReload, or c itself if there was none. The
+// snapshot is never changed once published, so readers holding it need no
+// locking; reloadable settings must be read through Current, while the rest
+// of c never changes after startup.
+func (c *Config) Current() *Config {
+	if c.live != nil {
+		if cur := c.live.Load(); cur != nil {
+			return cur
+		}
+	}
+	return c
+}
+
+// ApplyReload publishes a new snapshot of c, returned by Current, with the
+// reloadable settings that differ in next. It returns the YAML paths of the
+// settings it applied and of the changed settings that need a restart to take
+// effect. Reloads must not run concurrently, and a config not made by
+// DefaultConfig or Load must get its first reload before it is shared.
+func (c *Config) ApplyReload(next *Config) (applied, restart []string) {
+	if c.live == nil {
+		c.live = new(atomic.Pointer[Config])
+	}
+	nextValues := make(map[string]reflect.Value)
+	walkSettings(reflect.ValueOf(next).Elem(), nil, func(path []string, _ reflect.StructField, v reflect.Value) {
+		nextValues[strings.Join(path, ".")] = v
+	})
+
+	cur := c.Current()
+	snapshot := *cur
+	snapshot.sources = maps.Clone(cur.sources)
+	walkSettings(reflect.ValueOf(&snapshot).Elem(), nil, func(path []string, _ reflect.StructField, v reflect.Value) {
+		key := strings.Join(path, ".")
+		nv, ok := nextValues[key]
+		if !ok || reflect.DeepEqual(v.Interface(), nv.Interface()) {
+			return
+		}
+		if !isReloadable(key) {
+			restart = append(restart, key)
+			return
+		}
+		v.Set(nv)
+		source := SourceDefault
+		if s, ok := next.sources[key]; ok {
+			source = s
+		}
+		snapshot.SetSource(key, source)
+		applied = append(applied, key)
+	})
+	if len(applied) > 0 {
+		c.live.Store(&snapshot)
+	}
+	return applied, restart
+}
+
+// Watch reloads the config file at path whenever its modification time
+// changes or the process receives SIGHUP, until ctx is cancelled. Each
+// reloaded config has its secrets resolved with resolve, if not nil, and is
+// validated and passed to onReload; a config that fails to load, resolve or
+// validate is logged and the current settings are kept.
+func Watch(ctx context.Context, path string, interval time.Duration, resolve SecretResolver, onReload func(next *Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastMod := modTime(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading config", "path", path)
+			lastMod = modTime(path)
+		case <-ticker.C:
+			mod := modTime(path)
+			if mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			slog.Info("Config file changed, reloading", "path", path)
+		}
+
+		next, err := Load(path)
+		if err != nil {
+			slog.Error("Failed to reload config, keeping current settings", "error", err)
+			continue
+		}
+		if resolve != nil {
+			if err := next.ResolveSecrets(ctx, resolve); err != nil {
+				slog.Error("Failed to reload config, keeping current settings", "error", err)
+				continue
+			}
+		}
+		if err := next.Validate(); err != nil {
+			slog.Error("Reloaded config is invalid, keeping current settings", "error", err)
+			continue
+		}
+		onReload(next)
+	}
+}
+
+// modTime returns the modification time of a file, or the zero time if it
+// cannot be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestApplyReload(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataDir = "/data"
+
+	next := DefaultConfig()
+	next.DataDir = "/elsewhere"
+	next.Debug = true
+	next.LLM.Phase2Prompt = "Summarize briefly."
+	next.LLM.Model = "other-model"
+	next.Newsletter.SubjectPrefix = "[Weekly]"
+	next.Web.RateLimitPerMinute = 100
+	next.Retention.PruneIntervalHours = 6
+	next.SetSource("retention.prune_interval_hours", SourceEnv)
+
+	applied, restart := cfg.ApplyReload(next)
+
+	wantApplied := []string{"debug", "llm.phase2_prompt", "newsletter.subject_prefix", "web.rate_limit_per_minute",
+		"retention.prune_interval_hours"}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("applied = %v, want %v", applied, wantApplied)
+	}
+	if wantRestart := []string{"data_dir", "llm.model"}; !reflect.DeepEqual(restart, wantRestart) {
+		t.Errorf("restart = %v, want %v", restart, wantRestart)
+	}
+
+	cur := cfg.Current()
+	if !cur.Debug || cur.GetPhase2Prompt() != "Summarize briefly." || cur.Newsletter.SubjectPrefix != "[Weekly]" ||
+		cur.Retention.PruneIntervalHours != 6 {
+		t.Errorf("reloadable settings not applied: %+v", cur)
+	}
+	if cfg.GetPhase2Prompt() != "Summarize briefly." {
+		t.Error("getters should read the reloaded settings")
+	}
+	if cur.DataDir != "/data" || cur.LLM.Model != DefaultConfig().LLM.Model {
+		t.Error("settings that need a restart must not change")
+	}
+	if cur.sources["retention.prune_interval_hours"] != SourceEnv {
+		t.Errorf("source = %q, want %q", cur.sources["retention.prune_interval_hours"], SourceEnv)
+	}
+	if cfg.Debug || cfg.Newsletter.SubjectPrefix == "[Weekly]" || cfg.sources["retention.prune_interval_hours"] != "" {
+		t.Error("the shared config must not change in place")
+	}
+
+	if applied, restart := cfg.ApplyReload(cur); applied != nil || restart != nil {
+		t.Errorf("reloading an unchanged config applied %v, restart %v", applied, restart)
+	}
+	if cfg.Current() != cur {
+		t.Error("reloading an unchanged config should keep the snapshot")
+	}
+}
+
+// TestApplyReloadConcurrent reloads while other goroutines read reloadable
+// settings; run with -race
+func TestApplyReloadConcurrent(t *testing.T) {
+	cfg := DefaultConfig()
+	configs := []*Config{DefaultConfig(), DefaultConfig()}
+	configs[0].LLM.Phase2Prompt = "First prompt."
+	configs[0].Branding.NavLinks = []string{"Wiki=https://wiki.example.com"}
+	configs[1].LLM.Phase2Prompt = "Second prompt."
+	configs[1].Newsletter.SubjectPrefix = "[Second]"
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cur := cfg.Current()
+				_ = cur.GetPhase2Prompt() + cur.Newsletter.SubjectPrefix
+				_ = cfg.GetNavLinks()
+				_ = cfg.Settings()
+			}
+		}()
+	}
+
+	for i := range 200 {
+		cfg.ApplyReload(configs[i%2])
+	}
+	close(done)
+	wg.Wait()
+
+	if got := cfg.GetPhase2Prompt(); got != "Second prompt." {
+		t.Errorf("GetPhase2Prompt() = %q after the last reload, want %q", got, "Second prompt.")
+	}
+}
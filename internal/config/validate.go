@@ -0,0 +1,301 @@
+package config
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for problems that would otherwise only
+// surface when the affected feature runs: unknown providers, missing
+// credentials, unreadable key files and out of range settings. It returns all
+// problems found, joined into one error, or nil if there are none. Credentials
+// are only checked for presence; see the config check command for live checks.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	c.validateLLM(add)
+	c.validateNewsletter(add)
+	c.validateGitHub(add)
+
+	if c.HasMasterKey() {
+		if _, _, err := c.GetMasterKeys(); err != nil {
+			add("%v", err)
+		}
+	}
+
+	if d := c.Web.WorkspaceDomain; d != "" && (strings.ContainsAny(d, ":/") || strings.HasPrefix(d, ".")) {
+		add("web.workspace_domain must be a bare domain such as activity.example.com (got %q)", d)
+	}
+	if p := c.Web.BasePath; strings.ContainsAny(p, "?#:") || strings.Contains(p, "//") {
+		add("web.base_path must be a path such as /activity (got %q)", p)
+	}
+	c.validateBranding(add)
+	if c.Web.ReadOnly && c.Web.RequireLogin {
+		add("web.require_login cannot be used with web.read_only, which never authenticates requests")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "otlp", "stdout":
+	default:
+		add("tracing.exporter: unknown exporter %q (use otlp or stdout)", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1 (got %g)", c.Tracing.SampleRatio)
+	}
+
+	switch c.Blobs.Backend {
+	case "", "local":
+	case "s3":
+		if c.Blobs.Bucket == "" {
+			add("blobs.bucket is required for the s3 backend")
+		}
+		if endpoint, err := url.Parse(c.GetBlobEndpoint()); err != nil || endpoint.Host == "" {
+			add("blobs.endpoint must be a URL (got %q)", c.Blobs.Endpoint)
+		}
+		if id, secret := c.GetBlobAccessKeys(); id == "" || secret == "" {
+			add("blobs: S3 access keys not configured: set 'access_key_id' and 'secret_access_key' or environment variables '%s' and '%s'",
+				c.Blobs.AccessKeyIDEnv, c.Blobs.SecretAccessKeyEnv)
+		}
+	case "gcs":
+		if c.Blobs.Bucket == "" {
+			add("blobs.bucket is required for the gcs backend")
+		}
+	default:
+		add("blobs.backend %q is unknown (use local, s3 or gcs)", c.Blobs.Backend)
+	}
+
+	nonNegative := []struct {
+		name  string
+		value int
+	}{
+		{"description_refresh_hours", c.DescriptionRefreshHours},
+		{"stale_weeks", c.StaleWeeks},
+		{"retention.activity_runs_days", c.Retention.ActivityRunsDays},
+		{"retention.raw_data_days", c.Retention.RawDataDays},
+		{"retention.newsletter_sends_days", c.Retention.NewsletterSendsDays},
+		{"retention.weekly_reports_days", c.Retention.WeeklyReportsDays},
+		{"retention.prune_interval_hours", c.Retention.PruneIntervalHours},
+		{"database.max_open_conns", c.Database.MaxOpenConns},
+		{"database.max_idle_conns", c.Database.MaxIdleConns},
+		{"database.conn_max_lifetime_seconds", c.Database.ConnMaxLifetimeSeconds},
+		{"web.shutdown_timeout_seconds", c.Web.ShutdownTimeoutSeconds},
+		{"blobs.min_size_kb", c.Blobs.MinSizeKB},
+	}
+	for _, s := range nonNegative {
+		if s.value < 0 {
+			add("%s must not be negative (got %d)", s.name, s.value)
+		}
+	}
+
+	for _, entry := range c.IgnoreAuthors {
+		if entry == "" {
+			add("ignore_authors: empty entry")
+		}
+	}
+	for _, entry := range c.Leaderboard.OptOut {
+		if entry == "" {
+			add("leaderboard.opt_out: empty entry")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Repos)) {
+		for _, entry := range c.Repos[name].IgnoreAuthors {
+			if entry == "" {
+				add("repos.%s.ignore_authors: empty entry", name)
+			}
+		}
+		if c.Repos[name].StaleWeeks < -1 {
+			add("repos.%s.stale_weeks must be -1 (never) or more (got %d)", name, c.Repos[name].StaleWeeks)
+		}
+		if c.Repos[name].Confluence.Space != "" && c.Confluence.BaseURL == "" {
+			add("repos.%s.confluence.space is set but confluence.base_url is not", name)
+		}
+		if c.Repos[name].Notion.DatabaseID != "" && c.GetNotionToken() == "" {
+			add("repos.%s.notion.database_id is set but no Notion token is configured: set 'notion.token' or environment variable '%s'",
+				name, c.Notion.TokenEnv)
+		}
+		if c.Repos[name].Discussions.Category != "" && !c.HasGitHubApp() {
+			add("repos.%s.discussions.category is set but no GitHub App is configured", name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLLM checks the LLM provider, its credentials and limits
+func (c *Config) validateLLM(add func(string, ...any)) {
+	switch c.LLM.Provider {
+	case "", "gemini":
+		if c.LLM.Model == "" {
+			add("llm.model is not set")
+		}
+		if c.GetGeminiAPIKey() == "" {
+			add("llm: API key not configured: set 'api_key' or environment variable '%s'", c.LLM.APIKeyEnv)
+		}
+	case "vertex":
+		if c.LLM.Model == "" {
+			add("llm.model is not set")
+		}
+		if c.GetVertexProject() == "" {
+			add("llm: vertex project not configured: set 'vertex_project' or environment variable 'GOOGLE_CLOUD_PROJECT'")
+		}
+	case "azure":
+		endpoint := c.GetAzureEndpoint()
+		if endpoint == "" {
+			add("llm: azure endpoint not configured: set 'azure_endpoint' or environment variable 'AZURE_OPENAI_ENDPOINT'")
+		} else if u, err := url.Parse(endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			add("llm.azure_endpoint must be an https URL (got %q)", endpoint)
+		}
+		if c.GetAzureDeployment() == "" {
+			add("llm: azure deployment not configured: set 'azure_deployment' or 'model'")
+		}
+		if c.GetAzureAPIKey() == "" {
+			add("llm: API key not configured: set 'api_key' or environment variable 'AZURE_OPENAI_API_KEY'")
+		}
+	default:
+		add("llm.provider %q is unknown (use gemini, vertex or azure)", c.LLM.Provider)
+	}
+
+	nonNegative := []struct {
+		name  string
+		value int
+	}{
+		{"max_commits", c.LLM.MaxCommits},
+		{"max_message_length", c.LLM.MaxMessageLength},
+		{"context_window_tokens", c.LLM.ContextWindowTokens},
+		{"max_diff_fetches", c.LLM.MaxDiffFetches},
+		{"max_diff_size_kb", c.LLM.MaxDiffSizeKB},
+		{"max_total_tokens", c.LLM.MaxTotalTokens},
+		{"timeout_seconds", c.LLM.TimeoutSeconds},
+		{"max_retries", c.LLM.MaxRetries},
+		{"retry_backoff_seconds", c.LLM.RetryBackoffSeconds},
+		{"retry_max_backoff_seconds", c.LLM.RetryMaxBackoffSeconds},
+		{"backfill_concurrency", c.LLM.BackfillConcurrency},
+	}
+	for _, s := range nonNegative {
+		if s.value < 0 {
+			add("llm.%s must not be negative (got %d)", s.name, s.value)
+		}
+	}
+	if c.LLM.InputPricePerMTok < 0 || c.LLM.OutputPricePerMTok < 0 {
+		add("llm: token prices must not be negative")
+	}
+}
+
+// validateNewsletter checks the email provider and sender when the
+// newsletter is enabled
+func (c *Config) validateNewsletter(add func(string, ...any)) {
+	nc := c.Newsletter
+	if !nc.Enabled {
+		return
+	}
+
+	switch nc.Provider {
+	case "", "sendgrid":
+		if c.GetSendGridAPIKey() == "" {
+			add("newsletter: SendGrid API key not configured: set 'sendgrid_api_key' or environment variable '%s'", nc.SendGridKeyEnv)
+		}
+	case "postmark":
+		if c.GetPostmarkToken() == "" {
+			add("newsletter: Postmark server token not configured: set 'postmark_token' or environment variable '%s'", nc.PostmarkTokenEnv)
+		}
+	case "mailgun":
+		if c.GetMailgunAPIKey() == "" {
+			add("newsletter: Mailgun API key not configured: set 'mailgun_api_key' or environment variable '%s'", nc.MailgunKeyEnv)
+		}
+		if nc.MailgunDomain == "" {
+			add("newsletter.mailgun_domain is not set")
+		}
+		if u, err := url.Parse(nc.MailgunBaseURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			add("newsletter.mailgun_base_url must be an https URL (got %q)", nc.MailgunBaseURL)
+		}
+	default:
+		add("newsletter.provider %q is unknown (use sendgrid, postmark or mailgun)", nc.Provider)
+	}
+
+	if nc.FromEmail == "" {
+		add("newsletter.from_email is not set")
+	} else if _, err := mail.ParseAddress(nc.FromEmail); err != nil {
+		add("newsletter.from_email %q is not a valid address", nc.FromEmail)
+	}
+}
+
+// validateGitHub checks that GitHub App settings are complete and the private
+// key is readable PEM. It is skipped if no GitHub App setting has a value; the
+// default env var names alone do not enable the App.
+func (c *Config) validateGitHub(add func(string, ...any)) {
+	gh := c.GitHub
+	envSet := func(name string) bool { return name != "" && os.Getenv(name) != "" }
+	if gh.AppID == 0 && !envSet(gh.AppIDEnv) && gh.InstallationID == 0 && !envSet(gh.InstallationIDEnv) &&
+		gh.PrivateKey == "" && gh.PrivateKeyPath == "" && !envSet(gh.PrivateKeyEnv) {
+		return
+	}
+
+	checkID := func(name string, value int64, env string) {
+		if value != 0 {
+			return
+		}
+		if env == "" {
+			add("github.%s is not set", name)
+			return
+		}
+		v := os.Getenv(env)
+		if v == "" {
+			add("github: environment variable '%s' (%s_env) is empty", env, name)
+		} else if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			add("github: environment variable '%s' (%s_env) is not a number", env, name)
+		}
+	}
+	checkID("app_id", gh.AppID, gh.AppIDEnv)
+	checkID("installation_id", gh.InstallationID, gh.InstallationIDEnv)
+
+	key, err := c.GetGitHubPrivateKey()
+	if errors.Is(err, ErrNoGitHubPrivateKey) && c.HasMasterKey() {
+		return // The key may be stored in the database
+	}
+	if err != nil {
+		add("github: %v", err)
+		return
+	}
+	if block, _ := pem.Decode(key); block == nil {
+		add("github: private key is not PEM encoded")
+	}
+}
+
+// cssColorPattern matches the colors branding accepts: hex colors and CSS
+// color names, which need no escaping in a style sheet
+var cssColorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|[a-zA-Z]+)$`)
+
+// validateBranding checks the branding colors and nav links
+func (c *Config) validateBranding(add func(format string, args ...any)) {
+	b := c.Branding
+	for _, color := range []struct{ key, value string }{
+		{"branding.accent_color", b.AccentColor},
+		{"branding.accent_hover_color", b.AccentHoverColor},
+	} {
+		if color.value != "" && !cssColorPattern.MatchString(color.value) {
+			add("%s must be a hex color such as #58a6ff or a CSS color name (got %q)", color.key, color.value)
+		}
+	}
+	for _, entry := range b.NavLinks {
+		label, link, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(label) == "" || strings.TrimSpace(link) == "" {
+			add("branding.nav_links entries must be Label=URL (got %q)", entry)
+			continue
+		}
+		if u, err := url.Parse(strings.TrimSpace(link)); err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+			add("branding.nav_links: %q is not an http(s) URL or path", link)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package confluence publishes pages to Confluence through its REST API.
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client creates and updates pages on Confluence Cloud, Server or Data Center
+type Client struct {
+	baseURL    string
+	email      string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client for the Confluence instance at baseURL
+// (ending in /wiki on Confluence Cloud). With an email the token is sent as a
+// Confluence Cloud API token (basic auth), without one as a Server or Data
+// Center personal access token (bearer auth).
+func NewClient(baseURL, email, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		email:      email,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Page is a published Confluence page
+type Page struct {
+	ID      string
+	Title   string
+	Version int
+	URL     string // Web URL of the page
+}
+
+// content is the part of Confluence's content resource we read and write
+type content struct {
+	ID        string     `json:"id,omitempty"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Space     *spaceRef  `json:"space,omitempty"`
+	Ancestors []pageRef  `json:"ancestors,omitempty"`
+	Version   *version   `json:"version,omitempty"`
+	Body      *body      `json:"body,omitempty"`
+	Links     *pageLinks `json:"_links,omitempty"`
+}
+
+type spaceRef struct {
+	Key string `json:"key"`
+}
+
+type pageRef struct {
+	ID string `json:"id"`
+}
+
+type version struct {
+	Number int `json:"number"`
+}
+
+type body struct {
+	Storage storage `json:"storage"`
+}
+
+type storage struct {
+	Value          string `json:"value"`
+	Representation string `json:"representation"`
+}
+
+type pageLinks struct {
+	WebUI string `json:"webui"`
+}
+
+// Publish creates or updates the page with the given title in a space. New
+// pages are created under the page parentID, or at the top of the space if
+// parentID is empty; existing pages stay where they are. The body is XHTML in
+// Confluence's storage format.
+func (c *Client) Publish(ctx context.Context, space, parentID, title, xhtml string) (*Page, error) {
+	existing, err := c.findPage(ctx, space, title)
+	if err != nil {
+		return nil, err
+	}
+
+	page := content{
+		Type:  "page",
+		Title: title,
+		Body:  &body{Storage: storage{Value: xhtml, Representation: "storage"}},
+	}
+	var saved content
+	if existing != nil {
+		page.ID = existing.ID
+		page.Version = &version{Number: existing.Version.Number + 1}
+		err = c.do(ctx, http.MethodPut, c.baseURL+"/rest/api/content/"+url.PathEscape(existing.ID), page, &saved)
+	} else {
+		page.Space = &spaceRef{Key: space}
+		if parentID != "" {
+			page.Ancestors = []pageRef{{ID: parentID}}
+		}
+		err = c.do(ctx, http.MethodPost, c.baseURL+"/rest/api/content", page, &saved)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	published := &Page{ID: saved.ID, Title: saved.Title}
+	if saved.Version != nil {
+		published.Version = saved.Version.Number
+	}
+	if saved.Links != nil {
+		published.URL = c.baseURL + saved.Links.WebUI
+	}
+	return published, nil
+}
+
+// findPage returns the page with the given title in a space, or nil if there
+// is none
+func (c *Client) findPage(ctx context.Context, space, title string) (*content, error) {
+	query := url.Values{}
+	query.Set("spaceKey", space)
+	query.Set("title", title)
+	query.Set("type", "page")
+	query.Set("expand", "version")
+
+	var result struct {
+		Results []content `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.baseURL+"/rest/api/content?"+query.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to look up page %q: %w", title, err)
+	}
+	if len(result.Results) == 0 || result.Results[0].Version == nil {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
+
+// do calls a Confluence API endpoint with an optional JSON request body and
+// decodes the JSON response into v
+func (c *Client) do(ctx context.Context, method, endpoint string, in, v any) error {
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.token == "":
+	case c.email != "":
+		req.SetBasicAuth(c.email, c.token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Confluence API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Confluence API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Confluence API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Audit log operations
+
+// AuditFilter selects audit log entries. Empty fields match everything; a
+// Limit of 0 returns all matching entries.
+type AuditFilter struct {
+	Actor  string // Exact actor email, case-insensitive
+	Action string // Exact action, or a prefix ending in "." such as "repo."
+	Limit  int
+	Offset int
+}
+
+// RecordAudit appends an entry to the audit log of the context's workspace
+func (db *DB) RecordAudit(ctx context.Context, actor, action, target, details string) error {
+	_, err := db.q.ExecContext(ctx, `
+		INSERT INTO audit_log (workspace_id, actor, action, target, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, writeWorkspace(ctx), actor, action, target, details)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditLog returns the audit log entries of the context's workspace (all
+// workspaces if unscoped) selected by filter, newest first
+func (db *DB) ListAuditLog(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	var limit any
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	entries, err := queryRows[AuditEntry](ctx, db.q, `
+		SELECT `+auditEntryColumns+`
+		FROM audit_log
+		WHERE ($1 = 0 OR workspace_id = $1)
+			AND ($2 = '' OR LOWER(actor) = LOWER($2))
+			AND ($3 = '' OR action = $3 OR (RIGHT($3, 1) = '.' AND STARTS_WITH(action, $3)))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`, WorkspaceFromContext(ctx), filter.Actor, filter.Action, limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	return entries, nil
+}
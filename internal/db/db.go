@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -10,9 +11,18 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// DB wraps a database connection
+// DB wraps a database connection. Its query methods run on the connection
+// pool, or in a transaction for the DB passed to a WithTx callback.
 type DB struct {
 	*sql.DB
+	q querier
+}
+
+// querier is the subset of *sql.DB and *sql.Tx used by the query methods
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 // OpenConfig contains database connection configuration
@@ -21,9 +31,11 @@ type OpenConfig struct {
 	MaxOpenConns           int
 	MaxIdleConns           int
 	ConnMaxLifetimeSeconds int
+	SkipMigrations         bool // Leave the schema as it is, for inspecting or rolling back migrations
 }
 
-// Open opens a PostgreSQL database connection and runs migrations
+// Open opens a PostgreSQL database connection and runs migrations, unless
+// SkipMigrations is set
 func Open(cfg OpenConfig) (*DB, error) {
 	if cfg.DSN == "" {
 		return nil, fmt.Errorf("database DSN is required")
@@ -51,6 +63,10 @@ func Open(cfg OpenConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if cfg.SkipMigrations {
+		return &DB{DB: sqlDB, q: sqlDB}, nil
+	}
+
 	// Run goose migrations
 	goose.SetBaseFS(migrations.FS)
 	if err := goose.SetDialect("postgres"); err != nil {
@@ -62,5 +78,30 @@ func Open(cfg OpenConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	return &DB{DB: sqlDB}, nil
+	return &DB{DB: sqlDB, q: sqlDB}, nil
+}
+
+// WithTx runs fn in a transaction, which is committed if fn returns nil and
+// rolled back otherwise, including when ctx is cancelled. All query methods of
+// the DB passed to fn run in the transaction. Calling WithTx on that DB runs
+// fn in the same transaction, so methods using WithTx can be composed.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *DB) error) error {
+	if _, ok := db.q.(*sql.Tx); ok {
+		return fn(db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&DB{DB: db.DB, q: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
@@ -94,7 +95,7 @@ func TestRepository_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 	if err != nil {
 		t.Fatalf("CreateRepository() error = %v", err)
 	}
@@ -124,7 +125,7 @@ func TestRepository_CreateWithDescription(t *testing.T) {
 	defer cleanup()
 
 	desc := sql.NullString{String: "A test repository", Valid: true}
-	repo, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", true, desc)
+	repo, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", true, desc)
 	if err != nil {
 		t.Fatalf("CreateRepository() error = %v", err)
 	}
@@ -141,12 +142,12 @@ func TestRepository_CreateDuplicate(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	_, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	_, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 	if err != nil {
 		t.Fatalf("first CreateRepository() error = %v", err)
 	}
 
-	_, err = db.CreateRepository("test-repo", "https://github.com/other/repo", "main", false, sql.NullString{})
+	_, err = db.CreateRepository(t.Context(), "test-repo", "https://github.com/other/repo", "main", false, sql.NullString{})
 	if err == nil {
 		t.Error("expected error for duplicate name, got nil")
 	}
@@ -156,13 +157,13 @@ func TestRepository_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	created, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	created, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 	if err != nil {
 		t.Fatalf("CreateRepository() error = %v", err)
 	}
 
 	// Test GetRepository by ID
-	repo, err := db.GetRepository(created.ID)
+	repo, err := db.GetRepository(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetRepository() error = %v", err)
 	}
@@ -174,7 +175,7 @@ func TestRepository_Get(t *testing.T) {
 	}
 
 	// Test GetRepositoryByName
-	repo, err = db.GetRepositoryByName("test-repo")
+	repo, err = db.GetRepositoryByName(t.Context(), "test-repo")
 	if err != nil {
 		t.Fatalf("GetRepositoryByName() error = %v", err)
 	}
@@ -183,16 +184,92 @@ func TestRepository_Get(t *testing.T) {
 	}
 }
 
+func TestRepository_SetDescription(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+
+	desc := sql.NullString{String: "A test project", Valid: true}
+	hash := sql.NullString{String: "abc123", Valid: true}
+	if err := db.SetRepositoryDescription(t.Context(), created.ID, desc, hash); err != nil {
+		t.Fatalf("SetRepositoryDescription() error = %v", err)
+	}
+
+	repo, err := db.GetRepository(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("GetRepository() error = %v", err)
+	}
+	if repo.Description != desc {
+		t.Errorf("Description = %v, want %v", repo.Description, desc)
+	}
+	if repo.ReadmeHash != hash {
+		t.Errorf("ReadmeHash = %v, want %v", repo.ReadmeHash, hash)
+	}
+
+	notes := sql.NullString{String: "The billing team calls invoices 'bills'", Valid: true}
+	if err := db.SetRepositoryContextNotes(t.Context(), created.ID, notes); err != nil {
+		t.Fatalf("SetRepositoryContextNotes() error = %v", err)
+	}
+	repo, _ = db.GetRepository(t.Context(), created.ID)
+	if repo.ContextNotes != notes {
+		t.Errorf("ContextNotes = %v, want %v", repo.ContextNotes, notes)
+	}
+	if err := db.SetRepositoryContextNotes(t.Context(), 999, notes); err == nil {
+		t.Error("SetRepositoryContextNotes() expected error for non-existent ID, got nil")
+	}
+}
+
+func TestRepository_Staleness(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	if created.LastCommitAt.Valid || created.StaleNotifiedAt.Valid {
+		t.Errorf("new repository has LastCommitAt %v, StaleNotifiedAt %v, want both unset", created.LastCommitAt, created.StaleNotifiedAt)
+	}
+
+	at := time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)
+	if err := db.SetRepositoryLastCommit(t.Context(), created.ID, at); err != nil {
+		t.Fatalf("SetRepositoryLastCommit() error = %v", err)
+	}
+	if err := db.MarkRepositoryStaleNotified(t.Context(), created.ID); err != nil {
+		t.Fatalf("MarkRepositoryStaleNotified() error = %v", err)
+	}
+
+	// A later update of the repository keeps both
+	created.UpdatedAt = time.Now()
+	if err := db.UpdateRepository(t.Context(), created); err != nil {
+		t.Fatalf("UpdateRepository() error = %v", err)
+	}
+	repo, err := db.GetRepository(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("GetRepository() error = %v", err)
+	}
+	if !repo.LastCommitAt.Valid || !repo.LastCommitAt.Time.Equal(at) {
+		t.Errorf("LastCommitAt = %v, want %v", repo.LastCommitAt, at)
+	}
+	if !repo.StaleNotifiedAt.Valid || !repo.StaleNotifiedAt.Time.After(at) {
+		t.Errorf("StaleNotifiedAt = %v, want a time after %v", repo.StaleNotifiedAt, at)
+	}
+}
+
 func TestRepository_GetNotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	_, err := db.GetRepository(999)
+	_, err := db.GetRepository(t.Context(), 999)
 	if err == nil {
 		t.Error("GetRepository() expected error for non-existent ID, got nil")
 	}
 
-	_, err = db.GetRepositoryByName("nonexistent")
+	_, err = db.GetRepositoryByName(t.Context(), "nonexistent")
 	if err == nil {
 		t.Error("GetRepositoryByName() expected error for non-existent name, got nil")
 	}
@@ -203,15 +280,15 @@ func TestRepository_List(t *testing.T) {
 	defer cleanup()
 
 	// Create some repositories
-	repo1, _ := db.CreateRepository("repo-a", "https://github.com/test/a", "main", false, sql.NullString{})
-	db.CreateRepository("repo-b", "https://github.com/test/b", "main", false, sql.NullString{})
-	db.CreateRepository("repo-c", "https://github.com/test/c", "main", false, sql.NullString{})
+	repo1, _ := db.CreateRepository(t.Context(), "repo-a", "https://github.com/test/a", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "repo-b", "https://github.com/test/b", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "repo-c", "https://github.com/test/c", "main", false, sql.NullString{})
 
 	// Deactivate one
-	db.SetRepositoryActive(repo1.ID, false)
+	db.SetRepositoryActive(t.Context(), repo1.ID, false)
 
 	// List all
-	repos, err := db.ListRepositories(nil)
+	repos, err := db.ListRepositories(t.Context(), nil)
 	if err != nil {
 		t.Fatalf("ListRepositories(nil) error = %v", err)
 	}
@@ -221,7 +298,7 @@ func TestRepository_List(t *testing.T) {
 
 	// List active only
 	activeOnly := true
-	repos, err = db.ListRepositories(&activeOnly)
+	repos, err = db.ListRepositories(t.Context(), &activeOnly)
 	if err != nil {
 		t.Fatalf("ListRepositories(true) error = %v", err)
 	}
@@ -231,7 +308,7 @@ func TestRepository_List(t *testing.T) {
 
 	// List inactive only
 	activeOnly = false
-	repos, err = db.ListRepositories(&activeOnly)
+	repos, err = db.ListRepositories(t.Context(), &activeOnly)
 	if err != nil {
 		t.Fatalf("ListRepositories(false) error = %v", err)
 	}
@@ -244,11 +321,11 @@ func TestRepository_ListOrdering(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	db.CreateRepository("zebra", "https://github.com/test/z", "main", false, sql.NullString{})
-	db.CreateRepository("alpha", "https://github.com/test/a", "main", false, sql.NullString{})
-	db.CreateRepository("middle", "https://github.com/test/m", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "zebra", "https://github.com/test/z", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "alpha", "https://github.com/test/a", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "middle", "https://github.com/test/m", "main", false, sql.NullString{})
 
-	repos, err := db.ListRepositories(nil)
+	repos, err := db.ListRepositories(t.Context(), nil)
 	if err != nil {
 		t.Fatalf("ListRepositories() error = %v", err)
 	}
@@ -269,7 +346,7 @@ func TestRepository_Update(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 	if err != nil {
 		t.Fatalf("CreateRepository() error = %v", err)
 	}
@@ -282,12 +359,12 @@ func TestRepository_Update(t *testing.T) {
 	repo.LastRunAt = sql.NullTime{Time: time.Now(), Valid: true}
 	repo.LastRunSHA = sql.NullString{String: "abc123", Valid: true}
 
-	if err := db.UpdateRepository(repo); err != nil {
+	if err := db.UpdateRepository(t.Context(), repo); err != nil {
 		t.Fatalf("UpdateRepository() error = %v", err)
 	}
 
 	// Verify update
-	updated, err := db.GetRepository(repo.ID)
+	updated, err := db.GetRepository(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("GetRepository() error = %v", err)
 	}
@@ -315,16 +392,16 @@ func TestRepository_Delete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, err := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, err := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 	if err != nil {
 		t.Fatalf("CreateRepository() error = %v", err)
 	}
 
-	if err := db.DeleteRepository(repo.ID); err != nil {
+	if err := db.DeleteRepository(t.Context(), repo.ID); err != nil {
 		t.Fatalf("DeleteRepository() error = %v", err)
 	}
 
-	_, err = db.GetRepository(repo.ID)
+	_, err = db.GetRepository(t.Context(), repo.ID)
 	if err == nil {
 		t.Error("GetRepository() after delete expected error, got nil")
 	}
@@ -334,24 +411,24 @@ func TestRepository_SetActive(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	// Deactivate
-	if err := db.SetRepositoryActive(repo.ID, false); err != nil {
+	if err := db.SetRepositoryActive(t.Context(), repo.ID, false); err != nil {
 		t.Fatalf("SetRepositoryActive(false) error = %v", err)
 	}
 
-	updated, _ := db.GetRepository(repo.ID)
+	updated, _ := db.GetRepository(t.Context(), repo.ID)
 	if updated.Active {
 		t.Error("Active should be false after deactivation")
 	}
 
 	// Reactivate
-	if err := db.SetRepositoryActive(repo.ID, true); err != nil {
+	if err := db.SetRepositoryActive(t.Context(), repo.ID, true); err != nil {
 		t.Fatalf("SetRepositoryActive(true) error = %v", err)
 	}
 
-	updated, _ = db.GetRepository(repo.ID)
+	updated, _ = db.GetRepository(t.Context(), repo.ID)
 	if !updated.Active {
 		t.Error("Active should be true after reactivation")
 	}
@@ -363,9 +440,9 @@ func TestActivityRun_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
-	run, err := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	run, err := db.CreateActivityRun(t.Context(), repo.ID, "abc123", "def456")
 	if err != nil {
 		t.Fatalf("CreateActivityRun() error = %v", err)
 	}
@@ -391,10 +468,10 @@ func TestActivityRun_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	created, _ := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	created, _ := db.CreateActivityRun(t.Context(), repo.ID, "abc123", "def456")
 
-	run, err := db.GetActivityRun(created.ID)
+	run, err := db.GetActivityRun(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetActivityRun() error = %v", err)
 	}
@@ -408,7 +485,7 @@ func TestActivityRun_GetNotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	_, err := db.GetActivityRun(999)
+	_, err := db.GetActivityRun(t.Context(), 999)
 	if err == nil {
 		t.Error("GetActivityRun() expected error for non-existent ID, got nil")
 	}
@@ -418,10 +495,10 @@ func TestActivityRun_GetLatest(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	// No runs yet
-	run, err := db.GetLatestActivityRun(repo.ID)
+	run, err := db.GetLatestActivityRun(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("GetLatestActivityRun() error = %v", err)
 	}
@@ -430,10 +507,10 @@ func TestActivityRun_GetLatest(t *testing.T) {
 	}
 
 	// Create some runs
-	db.CreateActivityRun(repo.ID, "first", "first-end")
-	db.CreateActivityRun(repo.ID, "second", "second-end")
+	db.CreateActivityRun(t.Context(), repo.ID, "first", "first-end")
+	db.CreateActivityRun(t.Context(), repo.ID, "second", "second-end")
 
-	run, err = db.GetLatestActivityRun(repo.ID)
+	run, err = db.GetLatestActivityRun(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("GetLatestActivityRun() error = %v", err)
 	}
@@ -449,8 +526,8 @@ func TestActivityRun_Update(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	run, _ := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	run, _ := db.CreateActivityRun(t.Context(), repo.ID, "abc123", "def456")
 
 	// Update fields
 	run.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
@@ -459,11 +536,11 @@ func TestActivityRun_Update(t *testing.T) {
 	run.AgentMode = true
 	run.ToolUsageStats = sql.NullString{String: `{"diffs": 3}`, Valid: true}
 
-	if err := db.UpdateActivityRun(run); err != nil {
+	if err := db.UpdateActivityRun(t.Context(), run); err != nil {
 		t.Fatalf("UpdateActivityRun() error = %v", err)
 	}
 
-	updated, _ := db.GetActivityRun(run.ID)
+	updated, _ := db.GetActivityRun(t.Context(), run.ID)
 	if !updated.CompletedAt.Valid {
 		t.Error("CompletedAt should be valid")
 	}
@@ -484,7 +561,7 @@ func TestSubscriber_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	sub, err := db.CreateSubscriber("test@example.com", false)
+	sub, err := db.CreateSubscriber(t.Context(), "test@example.com", false)
 	if err != nil {
 		t.Fatalf("CreateSubscriber() error = %v", err)
 	}
@@ -498,13 +575,16 @@ func TestSubscriber_Create(t *testing.T) {
 	if sub.SubscribeAll {
 		t.Error("SubscribeAll should be false")
 	}
+	if sub.Timezone != "UTC" || sub.SendHour != 8 {
+		t.Errorf("schedule = %s %d, want UTC 8", sub.Timezone, sub.SendHour)
+	}
 }
 
 func TestSubscriber_CreateWithSubscribeAll(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	sub, err := db.CreateSubscriber("all@example.com", true)
+	sub, err := db.CreateSubscriber(t.Context(), "all@example.com", true)
 	if err != nil {
 		t.Fatalf("CreateSubscriber() error = %v", err)
 	}
@@ -518,12 +598,12 @@ func TestSubscriber_CreateDuplicate(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	_, err := db.CreateSubscriber("test@example.com", false)
+	_, err := db.CreateSubscriber(t.Context(), "test@example.com", false)
 	if err != nil {
 		t.Fatalf("first CreateSubscriber() error = %v", err)
 	}
 
-	_, err = db.CreateSubscriber("test@example.com", false)
+	_, err = db.CreateSubscriber(t.Context(), "test@example.com", false)
 	if err == nil {
 		t.Error("expected error for duplicate email, got nil")
 	}
@@ -533,10 +613,10 @@ func TestSubscriber_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	created, _ := db.CreateSubscriber("test@example.com", false)
+	created, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
 	// By ID
-	sub, err := db.GetSubscriber(created.ID)
+	sub, err := db.GetSubscriber(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetSubscriber() error = %v", err)
 	}
@@ -545,7 +625,7 @@ func TestSubscriber_Get(t *testing.T) {
 	}
 
 	// By email
-	sub, err = db.GetSubscriberByEmail("test@example.com")
+	sub, err = db.GetSubscriberByEmail(t.Context(), "test@example.com")
 	if err != nil {
 		t.Fatalf("GetSubscriberByEmail() error = %v", err)
 	}
@@ -558,12 +638,12 @@ func TestSubscriber_GetNotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	_, err := db.GetSubscriber(999)
+	_, err := db.GetSubscriber(t.Context(), 999)
 	if err == nil {
 		t.Error("GetSubscriber() expected error for non-existent ID, got nil")
 	}
 
-	_, err = db.GetSubscriberByEmail("nonexistent@example.com")
+	_, err = db.GetSubscriberByEmail(t.Context(), "nonexistent@example.com")
 	if err == nil {
 		t.Error("GetSubscriberByEmail() expected error for non-existent email, got nil")
 	}
@@ -573,11 +653,11 @@ func TestSubscriber_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	db.CreateSubscriber("zebra@example.com", false)
-	db.CreateSubscriber("alpha@example.com", false)
-	db.CreateSubscriber("middle@example.com", false)
+	db.CreateSubscriber(t.Context(), "zebra@example.com", false)
+	db.CreateSubscriber(t.Context(), "alpha@example.com", false)
+	db.CreateSubscriber(t.Context(), "middle@example.com", false)
 
-	subs, err := db.ListSubscribers()
+	subs, err := db.ListSubscribers(t.Context())
 	if err != nil {
 		t.Fatalf("ListSubscribers() error = %v", err)
 	}
@@ -596,35 +676,40 @@ func TestSubscriber_Update(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	sub, _ := db.CreateSubscriber("test@example.com", false)
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
 	sub.Email = "updated@example.com"
 	sub.SubscribeAll = true
+	sub.Timezone = "Europe/Oslo"
+	sub.SendHour = 7
 
-	if err := db.UpdateSubscriber(sub); err != nil {
+	if err := db.UpdateSubscriber(t.Context(), sub); err != nil {
 		t.Fatalf("UpdateSubscriber() error = %v", err)
 	}
 
-	updated, _ := db.GetSubscriber(sub.ID)
+	updated, _ := db.GetSubscriber(t.Context(), sub.ID)
 	if updated.Email != "updated@example.com" {
 		t.Errorf("Email = %q, want %q", updated.Email, "updated@example.com")
 	}
 	if !updated.SubscribeAll {
 		t.Error("SubscribeAll should be true")
 	}
+	if updated.Timezone != "Europe/Oslo" || updated.SendHour != 7 {
+		t.Errorf("schedule = %s %d, want Europe/Oslo 7", updated.Timezone, updated.SendHour)
+	}
 }
 
 func TestSubscriber_Delete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	sub, _ := db.CreateSubscriber("test@example.com", false)
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	if err := db.DeleteSubscriber(sub.ID); err != nil {
+	if err := db.DeleteSubscriber(t.Context(), sub.ID); err != nil {
 		t.Fatalf("DeleteSubscriber() error = %v", err)
 	}
 
-	_, err := db.GetSubscriber(sub.ID)
+	_, err := db.GetSubscriber(t.Context(), sub.ID)
 	if err == nil {
 		t.Error("GetSubscriber() after delete expected error, got nil")
 	}
@@ -636,10 +721,10 @@ func TestSubscription_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	subscription, err := db.CreateSubscription(sub.ID, repo.ID)
+	subscription, err := db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 	if err != nil {
 		t.Fatalf("CreateSubscription() error = %v", err)
 	}
@@ -659,15 +744,15 @@ func TestSubscription_CreateDuplicate(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	_, err := db.CreateSubscription(sub.ID, repo.ID)
+	_, err := db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 	if err != nil {
 		t.Fatalf("first CreateSubscription() error = %v", err)
 	}
 
-	_, err = db.CreateSubscription(sub.ID, repo.ID)
+	_, err = db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 	if err == nil {
 		t.Error("expected error for duplicate subscription, got nil")
 	}
@@ -677,12 +762,12 @@ func TestSubscription_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	created, _ := db.CreateSubscription(sub.ID, repo.ID)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
+	created, _ := db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 
 	// By ID
-	subscription, err := db.GetSubscription(created.ID)
+	subscription, err := db.GetSubscription(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetSubscription() error = %v", err)
 	}
@@ -691,7 +776,7 @@ func TestSubscription_Get(t *testing.T) {
 	}
 
 	// By subscriber and repo
-	subscription, err = db.GetSubscriptionBySubscriberAndRepo(sub.ID, repo.ID)
+	subscription, err = db.GetSubscriptionBySubscriberAndRepo(t.Context(), sub.ID, repo.ID)
 	if err != nil {
 		t.Fatalf("GetSubscriptionBySubscriberAndRepo() error = %v", err)
 	}
@@ -704,14 +789,14 @@ func TestSubscription_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo1, _ := db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	repo2, _ := db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	db.CreateSubscription(sub.ID, repo1.ID)
-	db.CreateSubscription(sub.ID, repo2.ID)
+	db.CreateSubscription(t.Context(), sub.ID, repo1.ID)
+	db.CreateSubscription(t.Context(), sub.ID, repo2.ID)
 
-	subs, err := db.ListSubscriptionsBySubscriber(sub.ID)
+	subs, err := db.ListSubscriptionsBySubscriber(t.Context(), sub.ID)
 	if err != nil {
 		t.Fatalf("ListSubscriptionsBySubscriber() error = %v", err)
 	}
@@ -725,15 +810,15 @@ func TestSubscription_Delete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	subscription, _ := db.CreateSubscription(sub.ID, repo.ID)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
+	subscription, _ := db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 
-	if err := db.DeleteSubscription(subscription.ID); err != nil {
+	if err := db.DeleteSubscription(t.Context(), subscription.ID); err != nil {
 		t.Fatalf("DeleteSubscription() error = %v", err)
 	}
 
-	_, err := db.GetSubscription(subscription.ID)
+	_, err := db.GetSubscription(t.Context(), subscription.ID)
 	if err == nil {
 		t.Error("GetSubscription() after delete expected error, got nil")
 	}
@@ -743,15 +828,15 @@ func TestSubscription_DeleteBySubscriberAndRepo(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	db.CreateSubscription(sub.ID, repo.ID)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
+	db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 
-	if err := db.DeleteSubscriptionBySubscriberAndRepo(sub.ID, repo.ID); err != nil {
+	if err := db.DeleteSubscriptionBySubscriberAndRepo(t.Context(), sub.ID, repo.ID); err != nil {
 		t.Fatalf("DeleteSubscriptionBySubscriberAndRepo() error = %v", err)
 	}
 
-	_, err := db.GetSubscriptionBySubscriberAndRepo(sub.ID, repo.ID)
+	_, err := db.GetSubscriptionBySubscriberAndRepo(t.Context(), sub.ID, repo.ID)
 	if err == nil {
 		t.Error("GetSubscriptionBySubscriberAndRepo() after delete expected error, got nil")
 	}
@@ -763,11 +848,10 @@ func TestNewsletterSend_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	run, _ := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	ns, err := db.CreateNewsletterSend(sub.ID, run.ID, "msg-123")
+	ns, err := db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "msg-123")
 	if err != nil {
 		t.Fatalf("CreateNewsletterSend() error = %v", err)
 	}
@@ -778,6 +862,14 @@ func TestNewsletterSend_Create(t *testing.T) {
 	if ns.SubscriberID != sub.ID {
 		t.Errorf("SubscriberID = %d, want %d", ns.SubscriberID, sub.ID)
 	}
+	if ns.RepoID != repo.ID || ns.Year != 2024 || ns.Week != 1 {
+		t.Errorf("send = repo %d week %d-W%02d, want repo %d week 2024-W01", ns.RepoID, ns.Year, ns.Week, repo.ID)
+	}
+
+	// The same report cannot be recorded twice
+	if _, err := db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "msg-456"); err == nil {
+		t.Error("CreateNewsletterSend() for an already sent report expected error, got nil")
+	}
 	if !ns.SendGridMessageID.Valid || ns.SendGridMessageID.String != "msg-123" {
 		t.Error("SendGridMessageID not set correctly")
 	}
@@ -787,11 +879,10 @@ func TestNewsletterSend_CreateWithoutMessageID(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	run, _ := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
-	ns, err := db.CreateNewsletterSend(sub.ID, run.ID, "")
+	ns, err := db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "")
 	if err != nil {
 		t.Fatalf("CreateNewsletterSend() error = %v", err)
 	}
@@ -805,12 +896,11 @@ func TestNewsletterSend_HasBeenSent(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	run, _ := db.CreateActivityRun(repo.ID, "abc123", "def456")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
 
 	// Not sent yet
-	sent, err := db.HasNewsletterBeenSent(sub.ID, run.ID)
+	sent, err := db.HasNewsletterBeenSent(t.Context(), sub.ID, repo.ID, 2024, 1)
 	if err != nil {
 		t.Fatalf("HasNewsletterBeenSent() error = %v", err)
 	}
@@ -819,15 +909,131 @@ func TestNewsletterSend_HasBeenSent(t *testing.T) {
 	}
 
 	// Send
-	db.CreateNewsletterSend(sub.ID, run.ID, "")
+	db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "")
 
-	sent, err = db.HasNewsletterBeenSent(sub.ID, run.ID)
+	sent, err = db.HasNewsletterBeenSent(t.Context(), sub.ID, repo.ID, 2024, 1)
 	if err != nil {
 		t.Fatalf("HasNewsletterBeenSent() error = %v", err)
 	}
 	if !sent {
 		t.Error("HasNewsletterBeenSent() should be true after sending")
 	}
+
+	// Other weeks are unaffected
+	sent, _ = db.HasNewsletterBeenSent(t.Context(), sub.ID, repo.ID, 2024, 2)
+	if sent {
+		t.Error("HasNewsletterBeenSent() should be false for another week")
+	}
+}
+
+func TestListReportRecipients(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	alice, _ := db.CreateSubscriber(t.Context(), "alice@example.com", true)
+	bob, _ := db.CreateSubscriber(t.Context(), "bob@example.com", true)
+	carol, _ := db.CreateSubscriber(t.Context(), "carol@example.com", true)
+	db.CreateSubscriber(t.Context(), "dave@example.com", true)
+
+	db.CreateNewsletterSend(t.Context(), alice.ID, repo.ID, 2024, 1, "")
+	db.CreateNewsletterSend(t.Context(), bob.ID, repo.ID, 2024, 1, "")
+	db.CreateNewsletterSend(t.Context(), carol.ID, repo.ID, 2024, 2, "")
+	db.SuppressSubscribers(t.Context(), "bob@example.com", "bounced")
+
+	recipients, err := db.ListReportRecipients(t.Context(), repo.ID, 2024, 1)
+	if err != nil {
+		t.Fatalf("ListReportRecipients() error = %v", err)
+	}
+	if len(recipients) != 1 || recipients[0].Email != "alice@example.com" {
+		t.Errorf("ListReportRecipients() = %v, want only alice@example.com", recipients)
+	}
+}
+
+func TestNewsletterBatches(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	alice, _ := db.CreateSubscriber(t.Context(), "alice@example.com", true)
+	batch, err := db.CreateNewsletterBatch(t.Context(), BatchManual, "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateNewsletterBatch() error = %v", err)
+	}
+	if batch.WorkspaceID != DefaultWorkspaceID || batch.StartedBy.String != "admin@example.com" {
+		t.Errorf("CreateNewsletterBatch() = %+v", batch)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sent, err := db.CreateNewsletterDelivery(t.Context(), &NewsletterDelivery{
+		BatchID:      batch.ID,
+		SubscriberID: sql.NullInt64{Int64: alice.ID, Valid: true},
+		Email:        alice.Email,
+		Status:       DeliverySent,
+		ReportCount:  2,
+		MessageID:    sql.NullString{String: "msg-1", Valid: true},
+		WindowStart:  start,
+		WindowEnd:    start.AddDate(0, 0, 7),
+	})
+	if err != nil {
+		t.Fatalf("CreateNewsletterDelivery() error = %v", err)
+	}
+	if sent.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", sent.Attempts)
+	}
+	failed, _ := db.CreateNewsletterDelivery(t.Context(), &NewsletterDelivery{
+		BatchID:     batch.ID,
+		Email:       "zed@example.com",
+		Status:      DeliveryFailed,
+		Error:       sql.NullString{String: "timeout", Valid: true},
+		WindowStart: start,
+		WindowEnd:   start.AddDate(0, 0, 7),
+	})
+
+	batches, err := db.ListNewsletterBatches(t.Context(), 10, 0)
+	if err != nil {
+		t.Fatalf("ListNewsletterBatches() error = %v", err)
+	}
+	if len(batches) != 1 || batches[0].Sent != 1 || batches[0].Failed != 1 {
+		t.Errorf("ListNewsletterBatches() = %+v, want one batch with 1 sent and 1 failed", batches)
+	}
+
+	deliveries, err := db.ListNewsletterDeliveries(t.Context(), batch.ID)
+	if err != nil {
+		t.Fatalf("ListNewsletterDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != failed.ID {
+		t.Errorf("ListNewsletterDeliveries() = %+v, want the failed delivery first", deliveries)
+	}
+
+	failed.Status = DeliverySent
+	failed.MessageID = sql.NullString{String: "msg-2", Valid: true}
+	failed.Error = sql.NullString{}
+	if err := db.UpdateNewsletterDelivery(t.Context(), failed); err != nil {
+		t.Fatalf("UpdateNewsletterDelivery() error = %v", err)
+	}
+	deliveries, _ = db.ListNewsletterDeliveries(t.Context(), batch.ID)
+	for _, d := range deliveries {
+		if d.ID == failed.ID && (d.Status != DeliverySent || d.Attempts != 2 || d.Error.Valid) {
+			t.Errorf("after resend = %+v, want sent on attempt 2", d)
+		}
+	}
+
+	// Deleting the subscriber keeps the history
+	if err := db.DeleteSubscriber(t.Context(), alice.ID); err != nil {
+		t.Fatalf("DeleteSubscriber() error = %v", err)
+	}
+	got, err := db.GetNewsletterBatch(t.Context(), batch.ID)
+	if err != nil {
+		t.Fatalf("GetNewsletterBatch() error = %v", err)
+	}
+	if got.Sent != 2 {
+		t.Errorf("Sent = %d after deleting subscriber, want 2", got.Sent)
+	}
+
+	other := WithWorkspace(t.Context(), 2)
+	if _, err := db.GetNewsletterBatch(other, batch.ID); err == nil {
+		t.Error("GetNewsletterBatch() from another workspace succeeded")
+	}
 }
 
 // WeeklyReport CRUD tests
@@ -836,7 +1042,7 @@ func TestWeeklyReport_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	report := &WeeklyReport{
 		RepoID:      repo.ID,
@@ -849,7 +1055,7 @@ func TestWeeklyReport_Create(t *testing.T) {
 		AgentMode:   true,
 	}
 
-	created, err := db.CreateWeeklyReport(report)
+	created, err := db.CreateWeeklyReport(t.Context(), report)
 	if err != nil {
 		t.Fatalf("CreateWeeklyReport() error = %v", err)
 	}
@@ -872,7 +1078,7 @@ func TestWeeklyReport_CreateDuplicate(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	report := &WeeklyReport{
 		RepoID:    repo.ID,
@@ -882,12 +1088,12 @@ func TestWeeklyReport_CreateDuplicate(t *testing.T) {
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	}
 
-	_, err := db.CreateWeeklyReport(report)
+	_, err := db.CreateWeeklyReport(t.Context(), report)
 	if err != nil {
 		t.Fatalf("first CreateWeeklyReport() error = %v", err)
 	}
 
-	_, err = db.CreateWeeklyReport(report)
+	_, err = db.CreateWeeklyReport(t.Context(), report)
 	if err == nil {
 		t.Error("expected error for duplicate year/week, got nil")
 	}
@@ -897,7 +1103,7 @@ func TestWeeklyReport_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	report := &WeeklyReport{
 		RepoID:    repo.ID,
@@ -906,10 +1112,10 @@ func TestWeeklyReport_Get(t *testing.T) {
 		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	}
-	created, _ := db.CreateWeeklyReport(report)
+	created, _ := db.CreateWeeklyReport(t.Context(), report)
 
 	// By ID
-	fetched, err := db.GetWeeklyReport(created.ID)
+	fetched, err := db.GetWeeklyReport(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetWeeklyReport() error = %v", err)
 	}
@@ -918,7 +1124,7 @@ func TestWeeklyReport_Get(t *testing.T) {
 	}
 
 	// By repo and week
-	fetched, err = db.GetWeeklyReportByRepoAndWeek(repo.ID, 2024, 1)
+	fetched, err = db.GetWeeklyReportByRepoAndWeek(t.Context(), repo.ID, 2024, 1)
 	if err != nil {
 		t.Fatalf("GetWeeklyReportByRepoAndWeek() error = %v", err)
 	}
@@ -927,7 +1133,7 @@ func TestWeeklyReport_Get(t *testing.T) {
 	}
 
 	// Non-existent week returns nil without error
-	fetched, err = db.GetWeeklyReportByRepoAndWeek(repo.ID, 2024, 52)
+	fetched, err = db.GetWeeklyReportByRepoAndWeek(t.Context(), repo.ID, 2024, 52)
 	if err != nil {
 		t.Fatalf("GetWeeklyReportByRepoAndWeek() error = %v", err)
 	}
@@ -940,10 +1146,10 @@ func TestWeeklyReport_GetLatest(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	// No reports yet
-	latest, err := db.GetLatestWeeklyReport(repo.ID)
+	latest, err := db.GetLatestWeeklyReport(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("GetLatestWeeklyReport() error = %v", err)
 	}
@@ -952,14 +1158,14 @@ func TestWeeklyReport_GetLatest(t *testing.T) {
 	}
 
 	// Create some reports
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      1,
 		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
-	expectedLatest, _ := db.CreateWeeklyReport(&WeeklyReport{
+	expectedLatest, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      5,
@@ -967,7 +1173,7 @@ func TestWeeklyReport_GetLatest(t *testing.T) {
 		WeekEnd:   time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC),
 	})
 
-	latest, err = db.GetLatestWeeklyReport(repo.ID)
+	latest, err = db.GetLatestWeeklyReport(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("GetLatestWeeklyReport() error = %v", err)
 	}
@@ -980,24 +1186,24 @@ func TestWeeklyReport_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	// Create reports for 2023 and 2024
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2023,
 		Week:      50,
 		WeekStart: time.Date(2023, 12, 11, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2023, 12, 17, 0, 0, 0, 0, time.UTC),
 	})
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      1,
 		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      2,
@@ -1006,7 +1212,7 @@ func TestWeeklyReport_List(t *testing.T) {
 	})
 
 	// List all
-	reports, err := db.ListWeeklyReportsByRepo(repo.ID, nil)
+	reports, err := db.ListWeeklyReportsByRepo(t.Context(), repo.ID, nil)
 	if err != nil {
 		t.Fatalf("ListWeeklyReportsByRepo(nil) error = %v", err)
 	}
@@ -1016,7 +1222,7 @@ func TestWeeklyReport_List(t *testing.T) {
 
 	// List by year
 	year := 2024
-	reports, err = db.ListWeeklyReportsByRepo(repo.ID, &year)
+	reports, err = db.ListWeeklyReportsByRepo(t.Context(), repo.ID, &year)
 	if err != nil {
 		t.Fatalf("ListWeeklyReportsByRepo(2024) error = %v", err)
 	}
@@ -1029,17 +1235,17 @@ func TestWeeklyReport_ListAll(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo1, _ := db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	repo2, _ := db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
 
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo1.ID,
 		Year:      2024,
 		Week:      1,
 		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo2.ID,
 		Year:      2024,
 		Week:      1,
@@ -1047,7 +1253,7 @@ func TestWeeklyReport_ListAll(t *testing.T) {
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
 
-	reports, err := db.ListAllWeeklyReports(nil)
+	reports, err := db.ListAllWeeklyReports(t.Context(), nil)
 	if err != nil {
 		t.Fatalf("ListAllWeeklyReports() error = %v", err)
 	}
@@ -1056,11 +1262,96 @@ func TestWeeklyReport_ListAll(t *testing.T) {
 	}
 }
 
+func TestWeeklyReport_ListFiltered(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+
+	for i, week := range []int{50, 51, 52} {
+		for _, repo := range []*Repository{repo1, repo2} {
+			start := time.Date(2023, 12, 11+7*i, 0, 0, 0, 0, time.UTC)
+			db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+				RepoID:      repo.ID,
+				Year:        2023,
+				Week:        week,
+				WeekStart:   start,
+				WeekEnd:     start.AddDate(0, 0, 6),
+				CommitCount: week - 50 + int(repo.ID-repo1.ID)*10,
+			})
+		}
+	}
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:    repo1.ID,
+		Year:      2024,
+		Week:      1,
+		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		Metadata:  sql.NullString{String: `{"authors":["Ada Lovelace","Bob"]}`, Valid: true},
+		Summary:   sql.NullString{String: "Reworked the 100% coverage Rate_Limiter", Valid: true},
+	})
+
+	tests := []struct {
+		name   string
+		filter ReportFilter
+		want   int // reports on the page
+		total  int // reports matching the filter
+	}{
+		{"all", ReportFilter{}, 7, 7},
+		{"first page", ReportFilter{Limit: 3}, 3, 7},
+		{"last page", ReportFilter{Limit: 3, Offset: 6}, 1, 7},
+		{"repo", ReportFilter{RepoID: repo2.ID}, 3, 3},
+		{"year", ReportFilter{Year: 2023}, 6, 6},
+		{"min commits", ReportFilter{MinCommits: 2}, 4, 4},
+		{"repo and min commits", ReportFilter{RepoID: repo1.ID, MinCommits: 1}, 2, 2},
+		{"max commits", ReportFilter{MaxCommits: 1}, 3, 3},
+		{"week range", ReportFilter{FromWeek: 202351, ToWeek: 202401}, 5, 5},
+		{"author", ReportFilter{Authors: []string{"ada lovelace", "Carol"}}, 1, 1},
+		{"text", ReportFilter{Text: "rate_limiter"}, 1, 1},
+		{"text with wildcards", ReportFilter{Text: "0%_c"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := db.ListWeeklyReports(t.Context(), tt.filter)
+			if err != nil {
+				t.Fatalf("ListWeeklyReports() error = %v", err)
+			}
+			if len(reports) != tt.want {
+				t.Errorf("ListWeeklyReports() returned %d reports, want %d", len(reports), tt.want)
+			}
+			total, err := db.CountWeeklyReports(t.Context(), tt.filter)
+			if err != nil {
+				t.Fatalf("CountWeeklyReports() error = %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("CountWeeklyReports() = %d, want %d", total, tt.total)
+			}
+		})
+	}
+
+	reports, _ := db.ListWeeklyReports(t.Context(), ReportFilter{Limit: 1})
+	if len(reports) != 1 || reports[0].Year != 2024 {
+		t.Errorf("ListWeeklyReports() does not start with the newest week")
+	}
+
+	years, err := db.ListWeeklyReportYears(t.Context(), repo2.ID)
+	if err != nil {
+		t.Fatalf("ListWeeklyReportYears() error = %v", err)
+	}
+	if len(years) != 1 || years[0] != 2023 {
+		t.Errorf("ListWeeklyReportYears(repo-2) = %v, want [2023]", years)
+	}
+	if years, _ := db.ListWeeklyReportYears(t.Context(), 0); len(years) != 2 || years[0] != 2024 {
+		t.Errorf("ListWeeklyReportYears(all) = %v, want [2024 2023]", years)
+	}
+}
+
 func TestWeeklyReport_Update(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	report := &WeeklyReport{
 		RepoID:    repo.ID,
@@ -1069,17 +1360,17 @@ func TestWeeklyReport_Update(t *testing.T) {
 		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	}
-	created, _ := db.CreateWeeklyReport(report)
+	created, _ := db.CreateWeeklyReport(t.Context(), report)
 
 	created.Summary = sql.NullString{String: "Updated summary", Valid: true}
 	created.CommitCount = 42
 	created.AgentMode = true
 
-	if err := db.UpdateWeeklyReport(created); err != nil {
+	if err := db.UpdateWeeklyReport(t.Context(), created); err != nil {
 		t.Fatalf("UpdateWeeklyReport() error = %v", err)
 	}
 
-	updated, _ := db.GetWeeklyReport(created.ID)
+	updated, _ := db.GetWeeklyReport(t.Context(), created.ID)
 	if !updated.Summary.Valid || updated.Summary.String != "Updated summary" {
 		t.Error("Summary not updated")
 	}
@@ -1091,14 +1382,57 @@ func TestWeeklyReport_Update(t *testing.T) {
 	}
 }
 
+func TestWeeklyReport_EditAndRestore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	created, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:    repo.ID,
+		Year:      2024,
+		Week:      1,
+		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		Summary:   sql.NullString{String: "Generated", Valid: true},
+	})
+
+	for _, summary := range []string{"First edit", "Second edit"} {
+		if err := db.EditWeeklyReportSummary(t.Context(), created.ID, summary, "admin@example.com"); err != nil {
+			t.Fatalf("EditWeeklyReportSummary() error = %v", err)
+		}
+	}
+	edited, _ := db.GetWeeklyReport(t.Context(), created.ID)
+	if edited.Summary.String != "Second edit" || edited.OriginalSummary.String != "Generated" ||
+		edited.EditedBy.String != "admin@example.com" || !edited.EditedAt.Valid {
+		t.Errorf("edited report = %+v, want the last edit with the generated summary kept", edited)
+	}
+
+	if err := db.SetRegenerationNote(t.Context(), created.ID, "admin@example.com", "wrong branch"); err != nil {
+		t.Fatalf("SetRegenerationNote() error = %v", err)
+	}
+	if err := db.RestoreWeeklyReportSummary(t.Context(), created.ID); err != nil {
+		t.Fatalf("RestoreWeeklyReportSummary() error = %v", err)
+	}
+	restored, _ := db.GetWeeklyReport(t.Context(), created.ID)
+	if restored.Summary.String != "Generated" || restored.OriginalSummary.Valid || restored.EditedAt.Valid {
+		t.Errorf("restored report = %+v, want the generated summary and no edit", restored)
+	}
+	if restored.RegenerationNote.String != "wrong branch" || restored.RegeneratedBy.String != "admin@example.com" {
+		t.Errorf("regeneration note = %q by %q, want it kept", restored.RegenerationNote.String, restored.RegeneratedBy.String)
+	}
+	if err := db.RestoreWeeklyReportSummary(t.Context(), created.ID); err == nil {
+		t.Error("RestoreWeeklyReportSummary() of an unedited report succeeded, want an error")
+	}
+}
+
 func TestWeeklyReport_Exists(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
 	// Doesn't exist yet
-	exists, err := db.WeeklyReportExists(repo.ID, 2024, 1)
+	exists, err := db.WeeklyReportExists(t.Context(), repo.ID, 2024, 1)
 	if err != nil {
 		t.Fatalf("WeeklyReportExists() error = %v", err)
 	}
@@ -1107,7 +1441,7 @@ func TestWeeklyReport_Exists(t *testing.T) {
 	}
 
 	// Create it
-	db.CreateWeeklyReport(&WeeklyReport{
+	db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      1,
@@ -1115,7 +1449,7 @@ func TestWeeklyReport_Exists(t *testing.T) {
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
 
-	exists, err = db.WeeklyReportExists(repo.ID, 2024, 1)
+	exists, err = db.WeeklyReportExists(t.Context(), repo.ID, 2024, 1)
 	if err != nil {
 		t.Fatalf("WeeklyReportExists() error = %v", err)
 	}
@@ -1128,9 +1462,9 @@ func TestWeeklyReport_Delete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
 
-	report, _ := db.CreateWeeklyReport(&WeeklyReport{
+	report, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
 		RepoID:    repo.ID,
 		Year:      2024,
 		Week:      1,
@@ -1138,11 +1472,11 @@ func TestWeeklyReport_Delete(t *testing.T) {
 		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
 	})
 
-	if err := db.DeleteWeeklyReport(report.ID); err != nil {
+	if err := db.DeleteWeeklyReport(t.Context(), report.ID); err != nil {
 		t.Fatalf("DeleteWeeklyReport() error = %v", err)
 	}
 
-	_, err := db.GetWeeklyReport(report.ID)
+	_, err := db.GetWeeklyReport(t.Context(), report.ID)
 	if err == nil {
 		t.Error("GetWeeklyReport() after delete expected error, got nil")
 	}
@@ -1150,11 +1484,97 @@ func TestWeeklyReport_Delete(t *testing.T) {
 
 // Admin CRUD tests
 
+func TestWeeklyReport_SaveIncremental(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+
+	existing, err := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:      repo.ID,
+		Year:        2024,
+		Week:        1,
+		WeekStart:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		Summary:     sql.NullString{String: "Monday", Valid: true},
+		CommitCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("CreateWeeklyReport() error = %v", err)
+	}
+
+	existing.Summary.String += " and Tuesday"
+	existing.CommitCount = 3
+	added := &WeeklyReport{
+		RepoID:      repo.ID,
+		Year:        2024,
+		Week:        2,
+		WeekStart:   time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC),
+		CommitCount: 1,
+	}
+
+	run, err := db.CreateActivityRun(t.Context(), repo.ID, "", "abc123")
+	if err != nil {
+		t.Fatalf("CreateActivityRun() error = %v", err)
+	}
+	run.Summary = sql.NullString{String: "Tuesday", Valid: true}
+	run.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	added.SourceRunID = sql.NullInt64{Int64: run.ID, Valid: true}
+
+	if err := db.SaveIncrementalReports(t.Context(), repo.ID, []*ActivityRun{run}, []*WeeklyReport{existing, added}, sql.NullString{}, "abc123"); err != nil {
+		t.Fatalf("SaveIncrementalReports() error = %v", err)
+	}
+	if added.ID == 0 {
+		t.Error("expected new report to be assigned an ID")
+	}
+	if saved, _ := db.GetActivityRun(t.Context(), run.ID); !saved.CompletedAt.Valid {
+		t.Error("expected the run to be completed")
+	}
+
+	updated, _ := db.GetWeeklyReport(t.Context(), existing.ID)
+	if updated.CommitCount != 3 || updated.Summary.String != "Monday and Tuesday" {
+		t.Errorf("updated report = %d %q, want 3 %q", updated.CommitCount, updated.Summary.String, "Monday and Tuesday")
+	}
+
+	got, _ := db.GetRepository(t.Context(), repo.ID)
+	if got.LastRunSHA.String != "abc123" || !got.LastRunAt.Valid {
+		t.Errorf("LastRunSHA = %q (at valid=%v), want %q", got.LastRunSHA.String, got.LastRunAt.Valid, "abc123")
+	}
+
+	// A stale previous SHA must not apply anything
+	existing.CommitCount = 99
+	stale, err := db.CreateActivityRun(t.Context(), repo.ID, "abc123", "def456")
+	if err != nil {
+		t.Fatalf("CreateActivityRun() error = %v", err)
+	}
+	stale.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	err = db.SaveIncrementalReports(t.Context(), repo.ID, []*ActivityRun{stale}, []*WeeklyReport{existing}, sql.NullString{}, "def456")
+	if err == nil {
+		t.Fatal("expected error for stale last run SHA, got nil")
+	}
+	unchanged, _ := db.GetWeeklyReport(t.Context(), existing.ID)
+	if unchanged.CommitCount != 3 {
+		t.Errorf("CommitCount = %d after failed save, want 3", unchanged.CommitCount)
+	}
+	if got, _ := db.GetActivityRun(t.Context(), stale.ID); got.CompletedAt.Valid {
+		t.Error("expected the run to stay incomplete after a failed save")
+	}
+
+	// Deleting a run unlinks the reports generated from it
+	if err := db.DeleteActivityRun(t.Context(), run.ID); err != nil {
+		t.Fatalf("DeleteActivityRun() error = %v", err)
+	}
+	if unlinked, _ := db.GetWeeklyReport(t.Context(), added.ID); unlinked.SourceRunID.Valid {
+		t.Error("expected the report to lose its source run")
+	}
+}
+
 func TestAdmin_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	admin, err := db.CreateAdmin("admin@example.com", "creator@example.com")
+	admin, err := db.CreateAdmin(t.Context(), "admin@example.com", "creator@example.com")
 	if err != nil {
 		t.Fatalf("CreateAdmin() error = %v", err)
 	}
@@ -1174,10 +1594,10 @@ func TestAdmin_Get(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	created, _ := db.CreateAdmin("admin@example.com", "")
+	created, _ := db.CreateAdmin(t.Context(), "admin@example.com", "")
 
 	// By ID
-	admin, err := db.GetAdmin(created.ID)
+	admin, err := db.GetAdmin(t.Context(), created.ID)
 	if err != nil {
 		t.Fatalf("GetAdmin() error = %v", err)
 	}
@@ -1186,7 +1606,7 @@ func TestAdmin_Get(t *testing.T) {
 	}
 
 	// By email
-	admin, err = db.GetAdminByEmail("admin@example.com")
+	admin, err = db.GetAdminByEmail(t.Context(), "admin@example.com")
 	if err != nil {
 		t.Fatalf("GetAdminByEmail() error = %v", err)
 	}
@@ -1200,7 +1620,7 @@ func TestAdmin_IsAdmin(t *testing.T) {
 	defer cleanup()
 
 	// Not an admin yet
-	isAdmin, err := db.IsAdmin("admin@example.com")
+	isAdmin, err := db.IsAdmin(t.Context(), "admin@example.com")
 	if err != nil {
 		t.Fatalf("IsAdmin() error = %v", err)
 	}
@@ -1209,9 +1629,9 @@ func TestAdmin_IsAdmin(t *testing.T) {
 	}
 
 	// Create admin
-	db.CreateAdmin("admin@example.com", "")
+	db.CreateAdmin(t.Context(), "admin@example.com", "")
 
-	isAdmin, err = db.IsAdmin("admin@example.com")
+	isAdmin, err = db.IsAdmin(t.Context(), "admin@example.com")
 	if err != nil {
 		t.Fatalf("IsAdmin() error = %v", err)
 	}
@@ -1224,7 +1644,7 @@ func TestAdmin_Count(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	count, err := db.AdminCount()
+	count, err := db.AdminCount(t.Context())
 	if err != nil {
 		t.Fatalf("AdminCount() error = %v", err)
 	}
@@ -1232,10 +1652,10 @@ func TestAdmin_Count(t *testing.T) {
 		t.Errorf("AdminCount() = %d, want 0", count)
 	}
 
-	db.CreateAdmin("admin1@example.com", "")
-	db.CreateAdmin("admin2@example.com", "")
+	db.CreateAdmin(t.Context(), "admin1@example.com", "")
+	db.CreateAdmin(t.Context(), "admin2@example.com", "")
 
-	count, err = db.AdminCount()
+	count, err = db.AdminCount(t.Context())
 	if err != nil {
 		t.Fatalf("AdminCount() error = %v", err)
 	}
@@ -1248,10 +1668,10 @@ func TestAdmin_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	db.CreateAdmin("zebra@example.com", "")
-	db.CreateAdmin("alpha@example.com", "")
+	db.CreateAdmin(t.Context(), "zebra@example.com", "")
+	db.CreateAdmin(t.Context(), "alpha@example.com", "")
 
-	admins, err := db.ListAdmins()
+	admins, err := db.ListAdmins(t.Context())
 	if err != nil {
 		t.Fatalf("ListAdmins() error = %v", err)
 	}
@@ -1270,98 +1690,243 @@ func TestAdmin_Delete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	admin, _ := db.CreateAdmin("admin@example.com", "")
+	admin, _ := db.CreateAdmin(t.Context(), "admin@example.com", "")
 
-	if err := db.DeleteAdmin(admin.ID); err != nil {
+	if err := db.DeleteAdmin(t.Context(), admin.ID); err != nil {
 		t.Fatalf("DeleteAdmin() error = %v", err)
 	}
 
-	_, err := db.GetAdmin(admin.ID)
+	_, err := db.GetAdmin(t.Context(), admin.ID)
 	if err == nil {
 		t.Error("GetAdmin() after delete expected error, got nil")
 	}
 }
 
+// Transaction tests
+
+func TestWithTx(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := t.Context()
+
+	// Returning an error rolls back all writes, including nested ones
+	err := db.WithTx(ctx, func(tx *DB) error {
+		if _, err := tx.CreateRepository(ctx, "rolled-back", "https://github.com/test/a", "main", false, sql.NullString{}); err != nil {
+			return err
+		}
+		return tx.WithTx(ctx, func(nested *DB) error {
+			if _, err := nested.CreateSubscriber(ctx, "rolled-back@example.com", false); err != nil {
+				return err
+			}
+			return fmt.Errorf("abort")
+		})
+	})
+	if err == nil || err.Error() != "abort" {
+		t.Fatalf("WithTx() error = %v, want abort", err)
+	}
+	if _, err := db.GetRepositoryByName(ctx, "rolled-back"); err == nil {
+		t.Error("repository created in rolled back transaction exists")
+	}
+	if _, err := db.GetSubscriberByEmail(ctx, "rolled-back@example.com"); err == nil {
+		t.Error("subscriber created in rolled back nested transaction exists")
+	}
+
+	// Writes are visible inside the transaction and committed on success
+	err = db.WithTx(ctx, func(tx *DB) error {
+		repo, err := tx.CreateRepository(ctx, "committed", "https://github.com/test/b", "main", false, sql.NullString{})
+		if err != nil {
+			return err
+		}
+		_, err = tx.GetRepository(ctx, repo.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if _, err := db.GetRepositoryByName(ctx, "committed"); err != nil {
+		t.Errorf("GetRepositoryByName() after commit error = %v", err)
+	}
+
+	// A cancelled context aborts the transaction
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := db.WithTx(cancelled, func(tx *DB) error { return nil }); err == nil {
+		t.Error("WithTx() with cancelled context expected error, got nil")
+	}
+}
+
 // Complex query tests
 
-func TestGetUnsentActivityRuns_SubscribeAll(t *testing.T) {
+// createFinishedReport creates a report with a summary for a week that has ended
+func createFinishedReport(t *testing.T, db *DB, repoID int64, weeksAgo int) *WeeklyReport {
+	t.Helper()
+	year, week := time.Now().AddDate(0, 0, -7*weeksAgo).ISOWeek()
+	weekStart := time.Now().AddDate(0, 0, -7*weeksAgo-int(time.Now().Weekday()+6)%7)
+	report, err := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:    repoID,
+		Year:      year,
+		Week:      week,
+		WeekStart: weekStart,
+		WeekEnd:   weekStart.AddDate(0, 0, 6),
+		Summary:   sql.NullString{String: "Summary", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateWeeklyReport() error = %v", err)
+	}
+	return report
+}
+
+func TestGetUnsentWeeklyReports_SubscribeAll(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo1, _ := db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	repo2, _ := db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
 
 	// Create subscriber with subscribe_all = true
-	sub, _ := db.CreateSubscriber("all@example.com", true)
+	sub, _ := db.CreateSubscriber(t.Context(), "all@example.com", true)
 
-	// Create completed activity runs
-	run1, _ := db.CreateActivityRun(repo1.ID, "abc", "def")
-	run1.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
-	run1.Summary = sql.NullString{String: "Run 1", Valid: true}
-	db.UpdateActivityRun(run1)
+	// Reports for last week, plus one for the current week that is still open
+	report1 := createFinishedReport(t, db, repo1.ID, 1)
+	report2 := createFinishedReport(t, db, repo2.ID, 1)
+	createFinishedReport(t, db, repo1.ID, 0)
 
-	run2, _ := db.CreateActivityRun(repo2.ID, "ghi", "jkl")
-	run2.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
-	run2.Summary = sql.NullString{String: "Run 2", Valid: true}
-	db.UpdateActivityRun(run2)
-
-	// Get unsent runs - should return both
-	since := time.Now().Add(-1 * time.Hour)
-	runs, err := db.GetUnsentActivityRuns(sub.ID, since)
+	// Get unsent reports - should return last week's two
+	since := time.Now().AddDate(0, 0, -14)
+	reports, err := db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
 	if err != nil {
-		t.Fatalf("GetUnsentActivityRuns() error = %v", err)
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
 	}
-	if len(runs) != 2 {
-		t.Errorf("GetUnsentActivityRuns() returned %d runs, want 2", len(runs))
+	if len(reports) != 2 {
+		t.Errorf("GetUnsentWeeklyReports() returned %d reports, want 2", len(reports))
 	}
 
 	// Mark one as sent
-	db.CreateNewsletterSend(sub.ID, run1.ID, "")
+	db.CreateNewsletterSend(t.Context(), sub.ID, report1.RepoID, report1.Year, report1.Week, "")
 
-	// Get unsent runs - should return only one
-	runs, err = db.GetUnsentActivityRuns(sub.ID, since)
+	// Get unsent reports - should return only one
+	reports, err = db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
 	if err != nil {
-		t.Fatalf("GetUnsentActivityRuns() error = %v", err)
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
 	}
-	if len(runs) != 1 {
-		t.Errorf("GetUnsentActivityRuns() returned %d runs, want 1", len(runs))
+	if len(reports) != 1 {
+		t.Fatalf("GetUnsentWeeklyReports() returned %d reports, want 1", len(reports))
 	}
-	if runs[0].ID != run2.ID {
-		t.Errorf("expected run2, got run %d", runs[0].ID)
+	if reports[0].ID != report2.ID {
+		t.Errorf("expected report2, got report %d", reports[0].ID)
+	}
+
+	// A regenerated report for an already sent week is not sent again
+	db.DeleteWeeklyReport(t.Context(), report1.ID)
+	createFinishedReport(t, db, repo1.ID, 1)
+	reports, _ = db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
+	if len(reports) != 1 {
+		t.Errorf("GetUnsentWeeklyReports() after regenerating returned %d reports, want 1", len(reports))
 	}
 }
 
-func TestGetUnsentActivityRuns_SpecificRepos(t *testing.T) {
+func TestGetUnsentWeeklyReports_SpecificRepos(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo1, _ := db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	repo2, _ := db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
 
 	// Create subscriber subscribed only to repo1
-	sub, _ := db.CreateSubscriber("specific@example.com", false)
-	db.CreateSubscription(sub.ID, repo1.ID)
+	sub, _ := db.CreateSubscriber(t.Context(), "specific@example.com", false)
+	db.CreateSubscription(t.Context(), sub.ID, repo1.ID)
+
+	// Create reports for both repos, and an old one outside the window
+	report1 := createFinishedReport(t, db, repo1.ID, 1)
+	createFinishedReport(t, db, repo2.ID, 1)
+	createFinishedReport(t, db, repo1.ID, 5)
+
+	// Get unsent reports - should return only report1
+	since := time.Now().AddDate(0, 0, -14)
+	reports, err := db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
+	if err != nil {
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("GetUnsentWeeklyReports() returned %d reports, want 1", len(reports))
+	}
+	if reports[0].ID != report1.ID {
+		t.Errorf("expected report1, got report %d", reports[0].ID)
+	}
+}
+
+func TestGetUnsentWeeklyReports_Drafts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "all@example.com", true)
+	report := createFinishedReport(t, db, repo.ID, 1)
+	if report.ReviewState != ReviewApproved {
+		t.Errorf("ReviewState = %q, want reports approved by default", report.ReviewState)
+	}
+
+	if err := db.SetReportReviewState(t.Context(), report.ID, ReviewDraft, "admin@example.com"); err != nil {
+		t.Fatalf("SetReportReviewState() error = %v", err)
+	}
+	since := time.Now().AddDate(0, 0, -14)
+	reports, _ := db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
+	if len(reports) != 0 {
+		t.Errorf("GetUnsentWeeklyReports() returned %d reports, want drafts left out", len(reports))
+	}
+	drafts, _ := db.CountWeeklyReports(t.Context(), ReportFilter{ReviewState: ReviewDraft})
+	if drafts != 1 {
+		t.Errorf("CountWeeklyReports(drafts) = %d, want 1", drafts)
+	}
+
+	if err := db.SetReportReviewState(t.Context(), report.ID, ReviewApproved, "admin@example.com"); err != nil {
+		t.Fatalf("SetReportReviewState() error = %v", err)
+	}
+	approved, _ := db.GetWeeklyReport(t.Context(), report.ID)
+	if approved.ApprovedBy.String != "admin@example.com" || !approved.ApprovedAt.Valid {
+		t.Errorf("approved by %q at %v, want the approver recorded", approved.ApprovedBy.String, approved.ApprovedAt)
+	}
+	reports, _ = db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, time.Now())
+	if len(reports) != 1 {
+		t.Errorf("GetUnsentWeeklyReports() after approval returned %d reports, want 1", len(reports))
+	}
+
+	if err := db.SetReportReviewState(t.Context(), report.ID, "published", "admin@example.com"); err == nil {
+		t.Error("SetReportReviewState() with an invalid state succeeded, want an error")
+	}
+}
+
+func TestGetUnsentWeeklyReports_Before(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
 
-	// Create completed activity runs for both repos
-	run1, _ := db.CreateActivityRun(repo1.ID, "abc", "def")
-	run1.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
-	db.UpdateActivityRun(run1)
+	repo, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "all@example.com", true)
+	report := createFinishedReport(t, db, repo.ID, 1)
 
-	run2, _ := db.CreateActivityRun(repo2.ID, "ghi", "jkl")
-	run2.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
-	db.UpdateActivityRun(run2)
+	since := time.Now().AddDate(0, 0, -14)
+	weekEnd := time.Date(report.WeekEnd.Year(), report.WeekEnd.Month(), report.WeekEnd.Day(), 23, 0, 0, 0, time.UTC)
+
+	// Not released on the last day of the week itself
+	reports, err := db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, weekEnd)
+	if err != nil {
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("GetUnsentWeeklyReports() before week end returned %d reports, want 0", len(reports))
+	}
 
-	// Get unsent runs - should return only run1
-	since := time.Now().Add(-1 * time.Hour)
-	runs, err := db.GetUnsentActivityRuns(sub.ID, since)
+	// Released from the following day, compared in the location of before
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
 	if err != nil {
-		t.Fatalf("GetUnsentActivityRuns() error = %v", err)
+		t.Skipf("timezone data unavailable: %v", err)
 	}
-	if len(runs) != 1 {
-		t.Errorf("GetUnsentActivityRuns() returned %d runs, want 1", len(runs))
+	reports, err = db.GetUnsentWeeklyReports(t.Context(), sub.ID, since, weekEnd.In(tokyo))
+	if err != nil {
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
 	}
-	if runs[0].ID != run1.ID {
-		t.Errorf("expected run1, got run %d", runs[0].ID)
+	if len(reports) != 1 {
+		t.Errorf("GetUnsentWeeklyReports() after week end returned %d reports, want 1", len(reports))
 	}
 }
 
@@ -1369,14 +1934,14 @@ func TestGetReposForSubscriber_SubscribeAll(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
-	repo3, _ := db.CreateRepository("repo-3", "https://github.com/test/3", "main", false, sql.NullString{})
-	db.SetRepositoryActive(repo3.ID, false) // Deactivate one
+	db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	repo3, _ := db.CreateRepository(t.Context(), "repo-3", "https://github.com/test/3", "main", false, sql.NullString{})
+	db.SetRepositoryActive(t.Context(), repo3.ID, false) // Deactivate one
 
-	sub, _ := db.CreateSubscriber("all@example.com", true)
+	sub, _ := db.CreateSubscriber(t.Context(), "all@example.com", true)
 
-	repos, err := db.GetReposForSubscriber(sub.ID)
+	repos, err := db.GetReposForSubscriber(t.Context(), sub.ID)
 	if err != nil {
 		t.Fatalf("GetReposForSubscriber() error = %v", err)
 	}
@@ -1391,15 +1956,15 @@ func TestGetReposForSubscriber_SpecificRepos(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo1, _ := db.CreateRepository("repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
-	repo2, _ := db.CreateRepository("repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
-	db.CreateRepository("repo-3", "https://github.com/test/3", "main", false, sql.NullString{})
+	repo1, _ := db.CreateRepository(t.Context(), "repo-1", "https://github.com/test/1", "main", false, sql.NullString{})
+	repo2, _ := db.CreateRepository(t.Context(), "repo-2", "https://github.com/test/2", "main", false, sql.NullString{})
+	db.CreateRepository(t.Context(), "repo-3", "https://github.com/test/3", "main", false, sql.NullString{})
 
-	sub, _ := db.CreateSubscriber("specific@example.com", false)
-	db.CreateSubscription(sub.ID, repo1.ID)
-	db.CreateSubscription(sub.ID, repo2.ID)
+	sub, _ := db.CreateSubscriber(t.Context(), "specific@example.com", false)
+	db.CreateSubscription(t.Context(), sub.ID, repo1.ID)
+	db.CreateSubscription(t.Context(), sub.ID, repo2.ID)
 
-	repos, err := db.GetReposForSubscriber(sub.ID)
+	repos, err := db.GetReposForSubscriber(t.Context(), sub.ID)
 	if err != nil {
 		t.Fatalf("GetReposForSubscriber() error = %v", err)
 	}
@@ -1415,39 +1980,941 @@ func TestRepository_DeleteCascadesActivityRuns(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	run, _ := db.CreateActivityRun(repo.ID, "abc", "def")
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	run, _ := db.CreateActivityRun(t.Context(), repo.ID, "abc", "def")
 
 	// Delete the repository - activity runs should cascade delete
-	err := db.DeleteRepository(repo.ID)
+	err := db.DeleteRepository(t.Context(), repo.ID)
 	if err != nil {
 		t.Fatalf("DeleteRepository() error = %v", err)
 	}
 
 	// Activity run should be deleted too
-	_, err = db.GetActivityRun(run.ID)
+	_, err = db.GetActivityRun(t.Context(), run.ID)
 	if err == nil {
 		t.Error("activity run should have been cascade deleted")
 	}
 }
 
+func TestRepository_DeleteRemovesAllDependents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	other, _ := db.CreateRepository(t.Context(), "other-repo", "https://github.com/test/other", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
+	db.CreateSubscription(t.Context(), sub.ID, repo.ID)
+	db.CreateSubscription(t.Context(), sub.ID, other.ID)
+	run, _ := db.CreateActivityRun(t.Context(), repo.ID, "abc", "def")
+	db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "msg-1")
+	report, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:      repo.ID,
+		Year:        2024,
+		Week:        1,
+		WeekStart:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		SourceRunID: sql.NullInt64{Int64: run.ID, Valid: true},
+	})
+	db.UpsertReportVector(t.Context(), &ReportVector{ReportID: report.ID, Model: "embed-1", ContentHash: "h", Embedding: []float32{1}})
+	db.UpsertCommitVector(t.Context(), &CommitVector{ReportID: report.ID, SHA: "abc", Message: "Fix", Author: "a", CommittedAt: time.Now(), Model: "embed-1", ContentHash: "h", Embedding: []float32{1}})
+
+	if err := db.DeleteRepository(t.Context(), repo.ID); err != nil {
+		t.Fatalf("DeleteRepository() error = %v", err)
+	}
+
+	for _, table := range []string{"activity_runs", "weekly_reports", "newsletter_sends", "report_vectors", "commit_vectors"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("%s has %d rows after deleting the repository, want 0", table, count)
+		}
+	}
+
+	// Only the deleted repository's subscription is removed
+	subs, _ := db.ListSubscriptionsBySubscriber(t.Context(), sub.ID)
+	if len(subs) != 1 || subs[0].RepoID != other.ID {
+		t.Errorf("subscriptions after delete = %d, want only the other repository", len(subs))
+	}
+
+	if err := db.DeleteRepository(t.Context(), repo.ID); err == nil {
+		t.Error("DeleteRepository() of missing repository expected error, got nil")
+	}
+}
+
 func TestSubscriber_DeleteCascadesSubscriptions(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	repo, _ := db.CreateRepository("test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
-	sub, _ := db.CreateSubscriber("test@example.com", false)
-	subscription, _ := db.CreateSubscription(sub.ID, repo.ID)
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "test@example.com", false)
+	subscription, _ := db.CreateSubscription(t.Context(), sub.ID, repo.ID)
 
 	// Delete the subscriber - subscriptions should cascade delete
-	err := db.DeleteSubscriber(sub.ID)
+	err := db.DeleteSubscriber(t.Context(), sub.ID)
 	if err != nil {
 		t.Fatalf("DeleteSubscriber() error = %v", err)
 	}
 
 	// Subscription should be deleted too
-	_, err = db.GetSubscription(subscription.ID)
+	_, err = db.GetSubscription(t.Context(), subscription.ID)
 	if err == nil {
 		t.Error("subscription should have been cascade deleted")
 	}
 }
+
+func TestAuthorAliases(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	alias, err := db.CreateAuthorAlias(t.Context(), "JD@Example.com", "John Doe", "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateAuthorAlias() error = %v", err)
+	}
+	if alias.Alias != "jd@example.com" {
+		t.Errorf("Alias = %q, want lowercased %q", alias.Alias, "jd@example.com")
+	}
+
+	// Duplicate aliases are rejected regardless of case
+	if _, err := db.CreateAuthorAlias(t.Context(), "jd@example.com", "Someone Else", ""); err == nil {
+		t.Error("CreateAuthorAlias() should reject duplicate alias")
+	}
+
+	if _, err := db.CreateAuthorAlias(t.Context(), "jdoe", "John Doe", ""); err != nil {
+		t.Fatalf("CreateAuthorAlias() error = %v", err)
+	}
+
+	m, err := db.GetAuthorAliasMap(t.Context())
+	if err != nil {
+		t.Fatalf("GetAuthorAliasMap() error = %v", err)
+	}
+	if len(m) != 2 || m["jdoe"] != "John Doe" || m["jd@example.com"] != "John Doe" {
+		t.Errorf("GetAuthorAliasMap() = %v", m)
+	}
+
+	if err := db.DeleteAuthorAlias(t.Context(), alias.ID); err != nil {
+		t.Fatalf("DeleteAuthorAlias() error = %v", err)
+	}
+	aliases, _ := db.ListAuthorAliases(t.Context())
+	if len(aliases) != 1 {
+		t.Errorf("ListAuthorAliases() returned %d aliases after delete, want 1", len(aliases))
+	}
+}
+
+func TestReportVectors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	report, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:    repo.ID,
+		Year:      2024,
+		Week:      1,
+		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+	})
+
+	missing, err := db.GetReportVector(t.Context(), report.ID)
+	if err != nil || missing != nil {
+		t.Fatalf("GetReportVector() = %v, %v, want nil, nil", missing, err)
+	}
+
+	v := &ReportVector{ReportID: report.ID, Model: "embed-1", ContentHash: "h1", Embedding: []float32{0.5, -1, 2}}
+	if err := db.UpsertReportVector(t.Context(), v); err != nil {
+		t.Fatalf("UpsertReportVector() error = %v", err)
+	}
+	v.ContentHash = "h2"
+	v.Embedding = []float32{1, 0}
+	if err := db.UpsertReportVector(t.Context(), v); err != nil {
+		t.Fatalf("UpsertReportVector() replace error = %v", err)
+	}
+
+	got, err := db.GetReportVector(t.Context(), report.ID)
+	if err != nil {
+		t.Fatalf("GetReportVector() error = %v", err)
+	}
+	if got.ContentHash != "h2" || len(got.Embedding) != 2 || got.Embedding[0] != 1 {
+		t.Errorf("GetReportVector() = %q %v, want %q [1 0]", got.ContentHash, got.Embedding, "h2")
+	}
+
+	vectors, _ := db.ListReportVectors(t.Context(), "embed-1")
+	if len(vectors) != 1 {
+		t.Errorf("ListReportVectors() returned %d vectors, want 1", len(vectors))
+	}
+	if other, _ := db.ListReportVectors(t.Context(), "embed-2"); len(other) != 0 {
+		t.Errorf("ListReportVectors(other model) returned %d vectors, want 0", len(other))
+	}
+	if vectors, _ := db.ListReportVectorsFor(t.Context(), "embed-1", []int64{report.ID}); len(vectors) != 1 {
+		t.Errorf("ListReportVectorsFor() returned %d vectors, want 1", len(vectors))
+	}
+	if vectors, _ := db.ListReportVectorsFor(t.Context(), "embed-1", []int64{report.ID + 1}); len(vectors) != 0 {
+		t.Errorf("ListReportVectorsFor(other report) returned %d vectors, want 0", len(vectors))
+	}
+
+	// Vectors are removed with their report
+	db.DeleteWeeklyReport(t.Context(), report.ID)
+	if gone, _ := db.GetReportVector(t.Context(), report.ID); gone != nil {
+		t.Error("expected vector to be deleted with its report")
+	}
+}
+
+func TestCommitVectors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	report, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:    repo.ID,
+		Year:      2024,
+		Week:      1,
+		WeekStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:   time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+	})
+
+	v := &CommitVector{
+		ReportID:    report.ID,
+		SHA:         "abc123",
+		Message:     "Switch to goose migrations",
+		Author:      "Alice",
+		CommittedAt: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		Model:       "embed-1",
+		ContentHash: "h1",
+		Embedding:   []float32{0.5, -1},
+	}
+	if err := db.UpsertCommitVector(t.Context(), v); err != nil {
+		t.Fatalf("UpsertCommitVector() error = %v", err)
+	}
+	v.ContentHash = "h2"
+	if err := db.UpsertCommitVector(t.Context(), v); err != nil {
+		t.Fatalf("UpsertCommitVector() replace error = %v", err)
+	}
+
+	vectors, err := db.ListCommitVectors(t.Context(), "embed-1")
+	if err != nil {
+		t.Fatalf("ListCommitVectors() error = %v", err)
+	}
+	if len(vectors) != 1 || vectors[0].ContentHash != "h2" || vectors[0].Message != v.Message {
+		t.Fatalf("ListCommitVectors() = %+v, want one vector with hash h2", vectors)
+	}
+	if other, _ := db.ListCommitVectors(t.Context(), "embed-2"); len(other) != 0 {
+		t.Errorf("ListCommitVectors(other model) returned %d vectors, want 0", len(other))
+	}
+	if vectors, _ := db.ListCommitVectorsFor(t.Context(), "embed-1", []int64{report.ID}); len(vectors) != 1 {
+		t.Errorf("ListCommitVectorsFor() returned %d vectors, want 1", len(vectors))
+	}
+	if vectors, _ := db.ListCommitVectorsFor(t.Context(), "embed-1", nil); len(vectors) != 0 {
+		t.Errorf("ListCommitVectorsFor(no reports) returned %d vectors, want 0", len(vectors))
+	}
+
+	// Vectors are removed with their report
+	db.DeleteWeeklyReport(t.Context(), report.ID)
+	if gone, _ := db.ListCommitVectors(t.Context(), "embed-1"); len(gone) != 0 {
+		t.Error("expected commit vector to be deleted with its report")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src, cleanupSrc := setupTestDB(t)
+	defer cleanupSrc()
+	dst, cleanupDst := setupTestDB(t)
+	defer cleanupDst()
+
+	repo, _ := src.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{String: "desc", Valid: true})
+	run, _ := src.CreateActivityRun(t.Context(), repo.ID, "abc", "def")
+	report, _ := src.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:      repo.ID,
+		Year:        2024,
+		Week:        1,
+		WeekStart:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		Summary:     sql.NullString{String: "Summary", Valid: true},
+		CommitCount: 3,
+		SourceRunID: sql.NullInt64{Int64: run.ID, Valid: true},
+	})
+	src.UpsertReportVector(t.Context(), &ReportVector{ReportID: report.ID, Model: "embed-1", ContentHash: "h", Embedding: []float32{1, 2}})
+	sub, _ := src.CreateSubscriber(t.Context(), "user@example.com", false)
+	src.CreateSubscription(t.Context(), sub.ID, repo.ID)
+	src.CreateAdmin(t.Context(), "admin@example.com", "system")
+	src.CreateAuthorAlias(t.Context(), "jdoe@example.com", "Jane Doe", "system")
+	ws, _ := src.CreateWorkspace(t.Context(), "team-b")
+	src.CreateAPIToken(WithWorkspace(t.Context(), ws.ID), "ci", "hash", "admin@example.com")
+
+	var buf bytes.Buffer
+	if err := src.ExportJSON(t.Context(), &buf); err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+	exported := buf.Bytes()
+
+	if err := dst.ImportJSON(t.Context(), bytes.NewReader(exported)); err != nil {
+		t.Fatalf("ImportJSON() error = %v", err)
+	}
+
+	gotRepo, err := dst.GetRepositoryByName(t.Context(), "test-repo")
+	if err != nil {
+		t.Fatalf("GetRepositoryByName() error = %v", err)
+	}
+	if gotRepo.ID != repo.ID || gotRepo.Description.String != "desc" {
+		t.Errorf("imported repo = %d %q, want %d %q", gotRepo.ID, gotRepo.Description.String, repo.ID, "desc")
+	}
+	gotReport, err := dst.GetWeeklyReport(t.Context(), report.ID)
+	if err != nil {
+		t.Fatalf("GetWeeklyReport() error = %v", err)
+	}
+	if gotReport.Summary.String != "Summary" || gotReport.SourceRunID.Int64 != run.ID || !gotReport.WeekStart.Equal(report.WeekStart) {
+		t.Errorf("imported report = %q run %d start %v, want %q run %d start %v",
+			gotReport.Summary.String, gotReport.SourceRunID.Int64, gotReport.WeekStart, "Summary", run.ID, report.WeekStart)
+	}
+	if v, _ := dst.GetReportVector(t.Context(), report.ID); v == nil || len(v.Embedding) != 2 || v.Embedding[1] != 2 {
+		t.Errorf("imported vector = %v, want [1 2]", v)
+	}
+	if admins, _ := dst.ListAdmins(t.Context()); len(admins) != 1 {
+		t.Errorf("imported %d admins, want 1", len(admins))
+	}
+	if token, err := dst.GetAPITokenByHash(t.Context(), "hash"); err != nil || token.WorkspaceID != ws.ID {
+		t.Errorf("imported API token = %v, %v, want workspace %d", token, err, ws.ID)
+	}
+
+	// Sequences continue after the imported ids
+	newRepo, err := dst.CreateRepository(t.Context(), "another-repo", "https://github.com/test/another", "main", false, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateRepository() after import error = %v", err)
+	}
+	if newRepo.ID <= repo.ID {
+		t.Errorf("CreateRepository() after import id = %d, want > %d", newRepo.ID, repo.ID)
+	}
+
+	// Importing into a non-empty database fails
+	if err := dst.ImportJSON(t.Context(), bytes.NewReader(exported)); err == nil {
+		t.Error("ImportJSON() into non-empty database expected error, got nil")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	sub, _ := db.CreateSubscriber(t.Context(), "user@example.com", true)
+	oldRun, _ := db.CreateActivityRun(t.Context(), repo.ID, "a", "b")
+	newRun, _ := db.CreateActivityRun(t.Context(), repo.ID, "b", "c")
+	db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 1, "msg-1")
+	db.CreateNewsletterSend(t.Context(), sub.ID, repo.ID, 2024, 2, "msg-2")
+	db.Exec(`UPDATE newsletter_sends SET sent_at = $1 WHERE week = 1`, time.Now().AddDate(0, 0, -400))
+	db.Exec(`UPDATE activity_runs SET started_at = $1 WHERE id = $2`, time.Now().AddDate(0, 0, -100), oldRun.ID)
+
+	report, _ := db.CreateWeeklyReport(t.Context(), &WeeklyReport{
+		RepoID:      repo.ID,
+		Year:        2024,
+		Week:        1,
+		WeekStart:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		SourceRunID: sql.NullInt64{Int64: oldRun.ID, Valid: true},
+	})
+	db.UpsertReportVector(t.Context(), &ReportVector{ReportID: report.ID, Model: "old-model", ContentHash: "h", Embedding: []float32{1}})
+
+	opts := PruneOptions{
+		ActivityRunsBefore:    time.Now().AddDate(0, 0, -90),
+		NewsletterSendsBefore: time.Now().AddDate(0, 0, -365),
+		VectorModel:           "new-model",
+		DryRun:                true,
+	}
+	result, err := db.Prune(t.Context(), opts)
+	if err != nil {
+		t.Fatalf("Prune(dry run) error = %v", err)
+	}
+	want := PruneResult{ActivityRuns: 1, NewsletterSends: 1, ReportVectors: 1}
+	if *result != want {
+		t.Errorf("Prune(dry run) = %+v, want %+v", *result, want)
+	}
+	if run, _ := db.GetActivityRun(t.Context(), oldRun.ID); run == nil {
+		t.Error("Prune(dry run) deleted the expired run")
+	}
+
+	opts.DryRun = false
+	result, err = db.Prune(t.Context(), opts)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if *result != want {
+		t.Errorf("Prune() = %+v, want %+v", *result, want)
+	}
+	if run, err := db.GetActivityRun(t.Context(), oldRun.ID); err == nil && run != nil {
+		t.Error("Prune() kept the expired run")
+	}
+	if run, err := db.GetActivityRun(t.Context(), newRun.ID); err != nil || run == nil {
+		t.Errorf("Prune() deleted the recent run: %v", err)
+	}
+
+	// Reports are kept forever by default and lose only their source run
+	got, err := db.GetWeeklyReport(t.Context(), report.ID)
+	if err != nil {
+		t.Fatalf("GetWeeklyReport() error = %v", err)
+	}
+	if got.SourceRunID.Valid {
+		t.Errorf("report source run = %d, want NULL", got.SourceRunID.Int64)
+	}
+}
+
+func TestPrune_RawData(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "test-repo", "https://github.com/test/repo", "main", false, sql.NullString{})
+	oldRun, _ := db.CreateActivityRun(t.Context(), repo.ID, "a", "b")
+	oldRun.RawDataRef = sql.NullString{String: "s3://bucket/runs/1/raw_data.json", Valid: true}
+	if err := db.UpdateActivityRun(t.Context(), oldRun); err != nil {
+		t.Fatalf("UpdateActivityRun() error = %v", err)
+	}
+	newRun, _ := db.CreateActivityRun(t.Context(), repo.ID, "b", "c")
+	newRun.RawData = sql.NullString{String: `{"commit_count": 1}`, Valid: true}
+	db.UpdateActivityRun(t.Context(), newRun)
+	db.Exec(`UPDATE activity_runs SET started_at = $1 WHERE id = $2`, time.Now().AddDate(0, 0, -40), oldRun.ID)
+
+	cutoff := time.Now().AddDate(0, 0, -30)
+	blobs, err := db.ListRawDataBlobs(t.Context(), cutoff, 0)
+	if err != nil {
+		t.Fatalf("ListRawDataBlobs() error = %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].RunID != oldRun.ID || blobs[0].Ref != oldRun.RawDataRef.String {
+		t.Errorf("ListRawDataBlobs() = %v, want the old run's blob", blobs)
+	}
+	if blobs, _ := db.ListRawDataBlobs(t.Context(), time.Time{}, repo.ID+1); len(blobs) != 0 {
+		t.Errorf("ListRawDataBlobs(other repo) = %v, want none", blobs)
+	}
+
+	result, err := db.Prune(t.Context(), PruneOptions{RawDataBefore: cutoff})
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if want := (PruneResult{RawData: 1}); *result != want {
+		t.Errorf("Prune() = %+v, want %+v", *result, want)
+	}
+	if run, _ := db.GetActivityRun(t.Context(), oldRun.ID); run == nil || run.RawDataRef.Valid {
+		t.Errorf("Prune() kept the raw data of the old run: %+v", run)
+	}
+	if run, _ := db.GetActivityRun(t.Context(), newRun.ID); run == nil || !run.RawData.Valid {
+		t.Errorf("Prune() cleared the raw data of the recent run: %+v", run)
+	}
+}
+
+func TestListAdminWorkspaces(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	teamB, _ := db.CreateWorkspace(t.Context(), "team-b")
+	teamC, _ := db.CreateWorkspace(t.Context(), "team-c")
+	db.CreateAdmin(WithWorkspace(t.Context(), teamC.ID), "alice@example.com", "")
+	db.CreateAdmin(WithWorkspace(t.Context(), DefaultWorkspaceID), "alice@example.com", "")
+	db.CreateAdmin(WithWorkspace(t.Context(), teamB.ID), "bob@example.com", "")
+
+	workspaces, err := db.ListAdminWorkspaces(t.Context(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("ListAdminWorkspaces() error = %v", err)
+	}
+	if len(workspaces) != 2 || workspaces[0].ID != DefaultWorkspaceID || workspaces[1].ID != teamC.ID {
+		t.Errorf("ListAdminWorkspaces(alice) = %v, want default and team-c", workspaces)
+	}
+	if workspaces, _ := db.ListAdminWorkspaces(t.Context(), "bob@example.com"); len(workspaces) != 1 || workspaces[0].ID != teamB.ID {
+		t.Errorf("ListAdminWorkspaces(bob) = %v, want only team-b", workspaces)
+	}
+	if workspaces, _ := db.ListAdminWorkspaces(t.Context(), "eve@example.com"); len(workspaces) != 0 {
+		t.Errorf("ListAdminWorkspaces(eve) = %v, want none", workspaces)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ws, err := db.CreateWorkspace(t.Context(), "team-b")
+	if err != nil {
+		t.Fatalf("CreateWorkspace() error = %v", err)
+	}
+	defaultCtx := WithWorkspace(t.Context(), DefaultWorkspaceID)
+	teamCtx := WithWorkspace(t.Context(), ws.ID)
+
+	if workspaces, _ := db.ListWorkspaces(t.Context()); len(workspaces) != 2 || workspaces[0].ID != DefaultWorkspaceID {
+		t.Errorf("ListWorkspaces() = %v, want default and team-b", workspaces)
+	}
+
+	// Unscoped writes go to the default workspace
+	repoA, _ := db.CreateRepository(t.Context(), "repo-a", "https://github.com/test/a", "main", false, sql.NullString{})
+	repoB, err := db.CreateRepository(teamCtx, "repo-b", "https://github.com/test/b", "main", false, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateRepository() in workspace error = %v", err)
+	}
+	if repoA.WorkspaceID != DefaultWorkspaceID || repoB.WorkspaceID != ws.ID {
+		t.Errorf("workspaces = %d, %d, want %d, %d", repoA.WorkspaceID, repoB.WorkspaceID, DefaultWorkspaceID, ws.ID)
+	}
+	reportB := createFinishedReport(t, db, repoB.ID, 1)
+
+	// Scoped reads only see their own workspace
+	if repos, _ := db.ListRepositories(teamCtx, nil); len(repos) != 1 || repos[0].ID != repoB.ID {
+		t.Errorf("ListRepositories(team-b) = %v, want only repo-b", repos)
+	}
+	if repos, _ := db.ListRepositories(t.Context(), nil); len(repos) != 2 {
+		t.Errorf("ListRepositories(unscoped) returned %d repos, want 2", len(repos))
+	}
+	if _, err := db.GetRepositoryByName(defaultCtx, "repo-b"); err == nil {
+		t.Error("GetRepositoryByName() found a repository of another workspace")
+	}
+	if _, err := db.GetWeeklyReport(defaultCtx, reportB.ID); err == nil {
+		t.Error("GetWeeklyReport() found a report of another workspace")
+	}
+	if reports, _ := db.ListAllWeeklyReports(defaultCtx, nil); len(reports) != 0 {
+		t.Errorf("ListAllWeeklyReports(default) returned %d reports, want 0", len(reports))
+	}
+
+	// Subscribers and admins are unique per workspace
+	if _, err := db.CreateAdmin(teamCtx, "admin@example.com", ""); err != nil {
+		t.Fatalf("CreateAdmin() error = %v", err)
+	}
+	if _, err := db.CreateAdmin(defaultCtx, "admin@example.com", ""); err != nil {
+		t.Errorf("CreateAdmin() in second workspace error = %v", err)
+	}
+	if _, err := db.CreateAdmin(teamCtx, "admin@example.com", ""); err == nil {
+		t.Error("CreateAdmin() duplicate in workspace expected error, got nil")
+	}
+	if isAdmin, _ := db.IsAdmin(teamCtx, "other@example.com"); isAdmin {
+		t.Error("IsAdmin() = true for a non-admin")
+	}
+
+	// A subscriber to all repositories only receives their workspace's reports
+	sub, _ := db.CreateSubscriber(defaultCtx, "all@example.com", true)
+	createFinishedReport(t, db, repoA.ID, 1)
+	reports, err := db.GetUnsentWeeklyReports(t.Context(), sub.ID, time.Now().AddDate(0, 0, -14), time.Now())
+	if err != nil {
+		t.Fatalf("GetUnsentWeeklyReports() error = %v", err)
+	}
+	if len(reports) != 1 || reports[0].RepoID != repoA.ID {
+		t.Errorf("GetUnsentWeeklyReports() = %v, want only repo-a's report", reports)
+	}
+
+	// API tokens are bound to a workspace
+	token, err := db.CreateAPIToken(teamCtx, "ci", "hash", "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+	got, err := db.GetAPITokenByHash(t.Context(), "hash")
+	if err != nil || got.ID != token.ID || got.WorkspaceID != ws.ID {
+		t.Errorf("GetAPITokenByHash() = %v, %v, want token %d in workspace %d", got, err, token.ID, ws.ID)
+	}
+	if err := db.DeleteAPIToken(defaultCtx, token.ID); err == nil {
+		t.Error("DeleteAPIToken() deleted a token of another workspace")
+	}
+
+	// Deleting a workspace removes its data; the default workspace cannot be deleted
+	if err := db.DeleteWorkspace(t.Context(), DefaultWorkspaceID); err == nil {
+		t.Error("DeleteWorkspace(default) expected error, got nil")
+	}
+	if err := db.DeleteWorkspace(t.Context(), ws.ID); err != nil {
+		t.Fatalf("DeleteWorkspace() error = %v", err)
+	}
+	if _, err := db.GetRepository(t.Context(), repoB.ID); err == nil {
+		t.Error("DeleteWorkspace() kept the workspace's repository")
+	}
+}
+
+func TestRepositoryVisibility(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	public, _ := db.CreateRepository(t.Context(), "public-repo", "https://github.com/test/public", "main", false, sql.NullString{})
+	internal, _ := db.CreateRepository(t.Context(), "internal-repo", "https://github.com/test/internal", "main", false, sql.NullString{})
+	secret, _ := db.CreateRepository(t.Context(), "secret-repo", "https://github.com/test/secret", "main", true, sql.NullString{})
+	if public.Visibility != VisibilityPublic {
+		t.Errorf("Visibility = %q, want %q", public.Visibility, VisibilityPublic)
+	}
+	if err := db.SetRepositoryVisibility(t.Context(), internal.ID, VisibilityAuthenticated); err != nil {
+		t.Fatalf("SetRepositoryVisibility() error = %v", err)
+	}
+	if err := db.SetRepositoryVisibility(t.Context(), secret.ID, VisibilityAdmin); err != nil {
+		t.Fatalf("SetRepositoryVisibility() error = %v", err)
+	}
+	if err := db.SetRepositoryVisibility(t.Context(), secret.ID, "everyone"); err == nil {
+		t.Error("SetRepositoryVisibility() with unknown visibility expected error, got nil")
+	}
+	createFinishedReport(t, db, public.ID, 1)
+	internalReport := createFinishedReport(t, db, internal.ID, 1)
+	secretReport := createFinishedReport(t, db, secret.ID, 1)
+
+	tests := []struct {
+		name       string
+		visibility string
+		want       int
+	}{
+		{"anonymous", VisibilityPublic, 1},
+		{"signed in", VisibilityAuthenticated, 2},
+		{"admin", VisibilityAdmin, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithVisibility(t.Context(), tt.visibility)
+			if repos, _ := db.ListRepositories(ctx, nil); len(repos) != tt.want {
+				t.Errorf("ListRepositories() returned %d repos, want %d", len(repos), tt.want)
+			}
+			if reports, _ := db.ListAllWeeklyReports(ctx, nil); len(reports) != tt.want {
+				t.Errorf("ListAllWeeklyReports() returned %d reports, want %d", len(reports), tt.want)
+			}
+			if count, _ := db.CountWeeklyReports(ctx, ReportFilter{}); count != tt.want {
+				t.Errorf("CountWeeklyReports() = %d, want %d", count, tt.want)
+			}
+		})
+	}
+
+	anonymous := WithVisibility(t.Context(), VisibilityPublic)
+	if _, err := db.GetRepositoryByName(anonymous, "internal-repo"); err == nil {
+		t.Error("GetRepositoryByName() found a repository hidden from anonymous readers")
+	}
+	if _, err := db.GetWeeklyReport(anonymous, internalReport.ID); err == nil {
+		t.Error("GetWeeklyReport() found a report hidden from anonymous readers")
+	}
+	signedIn := WithVisibility(t.Context(), VisibilityAuthenticated)
+	if _, err := db.GetRepository(signedIn, secret.ID); err == nil {
+		t.Error("GetRepository() found an admin-only repository for a signed in user")
+	}
+	if _, err := db.GetWeeklyReport(signedIn, secretReport.ID); err == nil {
+		t.Error("GetWeeklyReport() found an admin-only report for a signed in user")
+	}
+
+	// Contexts without a visibility, as in the CLI and background jobs, see everything
+	if repos, _ := db.ListRepositories(t.Context(), nil); len(repos) != 3 {
+		t.Errorf("ListRepositories(unscoped) returned %d repos, want 3", len(repos))
+	}
+}
+
+func TestEmailEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ws, _ := db.CreateWorkspace(t.Context(), "team-b")
+	subA, _ := db.CreateSubscriber(t.Context(), "user@example.com", true)
+	subB, _ := db.CreateSubscriber(WithWorkspace(t.Context(), ws.ID), "user@example.com", true)
+	other, _ := db.CreateSubscriber(t.Context(), "other@example.com", true)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Unscoped, an event is recorded for the address in every workspace
+	n, err := db.RecordEmailEvent(t.Context(), "USER@example.com", "delivered", "", "ev1", "msg1", now)
+	if err != nil {
+		t.Fatalf("RecordEmailEvent() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("RecordEmailEvent() recorded %d events, want 2", n)
+	}
+
+	// Retries of the same event are ignored
+	if n, _ := db.RecordEmailEvent(t.Context(), "user@example.com", "delivered", "", "ev1", "msg1", now); n != 0 {
+		t.Errorf("RecordEmailEvent(retry) recorded %d events, want 0", n)
+	}
+	if n, _ := db.RecordEmailEvent(t.Context(), "nobody@example.com", "delivered", "", "ev2", "", now); n != 0 {
+		t.Errorf("RecordEmailEvent(unknown address) recorded %d events, want 0", n)
+	}
+	db.RecordEmailEvent(t.Context(), "user@example.com", "bounce", "550 no such user", "ev3", "msg2", now)
+
+	// Opens count each newsletter once, however often it was opened
+	for _, id := range []string{"ev4", "ev5"} {
+		db.RecordEmailEvent(WithWorkspace(t.Context(), DefaultWorkspaceID), "user@example.com", "open", "", id, "msg1", now)
+	}
+
+	stats, err := db.ListEmailEventStats(WithWorkspace(t.Context(), DefaultWorkspaceID))
+	if err != nil {
+		t.Fatalf("ListEmailEventStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].SubscriberID != subA.ID || stats[0].Delivered != 1 || stats[0].Bounces != 1 {
+		t.Errorf("ListEmailEventStats() = %+v, want one delivered and one bounce for %d", stats, subA.ID)
+	}
+	if stats[0].Opened != 1 {
+		t.Errorf("Opened = %d, want 1", stats[0].Opened)
+	}
+	if !stats[0].LastEventAt.Equal(now) {
+		t.Errorf("LastEventAt = %v, want %v", stats[0].LastEventAt, now)
+	}
+
+	// Suppression applies to the address in every workspace, once
+	n, err = db.SuppressSubscribers(t.Context(), "user@example.com", "550 no such user")
+	if err != nil {
+		t.Fatalf("SuppressSubscribers() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("SuppressSubscribers() = %d, want 2", n)
+	}
+	if n, _ := db.SuppressSubscribers(t.Context(), "user@example.com", "again"); n != 0 {
+		t.Errorf("SuppressSubscribers(again) = %d, want 0", n)
+	}
+	got, _ := db.GetSubscriber(t.Context(), subB.ID)
+	if !got.SuppressedAt.Valid || got.SuppressedReason.String != "550 no such user" {
+		t.Errorf("subscriber suppression = %v, %v, want suppressed with reason", got.SuppressedAt, got.SuppressedReason)
+	}
+	if got, _ := db.GetSubscriber(t.Context(), other.ID); got.SuppressedAt.Valid {
+		t.Error("SuppressSubscribers() suppressed another address")
+	}
+
+	if err := db.UnsuppressSubscriber(t.Context(), subA.ID); err != nil {
+		t.Fatalf("UnsuppressSubscriber() error = %v", err)
+	}
+	if got, _ := db.GetSubscriber(t.Context(), subA.ID); got.SuppressedAt.Valid || got.SuppressedReason.Valid {
+		t.Error("UnsuppressSubscriber() left the subscriber suppressed")
+	}
+
+	// Events are deleted with their subscriber
+	if err := db.DeleteSubscriber(t.Context(), subA.ID); err != nil {
+		t.Fatalf("DeleteSubscriber() error = %v", err)
+	}
+	if stats, _ := db.ListEmailEventStats(WithWorkspace(t.Context(), DefaultWorkspaceID)); len(stats) != 0 {
+		t.Errorf("ListEmailEventStats() after delete = %+v, want none", stats)
+	}
+}
+
+func TestNewsletterDeliveryEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	alice, _ := db.CreateSubscriber(t.Context(), "alice@example.com", true)
+	bob, _ := db.CreateSubscriber(t.Context(), "bob@example.com", true)
+	batch, _ := db.CreateNewsletterBatch(t.Context(), BatchManual, "admin@example.com")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deliver := func(sub *Subscriber, messageID string) *NewsletterDelivery {
+		d, err := db.CreateNewsletterDelivery(t.Context(), &NewsletterDelivery{
+			BatchID:      batch.ID,
+			SubscriberID: sql.NullInt64{Int64: sub.ID, Valid: true},
+			Email:        sub.Email,
+			Status:       DeliverySent,
+			MessageID:    sql.NullString{String: messageID, Valid: true},
+			WindowStart:  start,
+			WindowEnd:    start.AddDate(0, 0, 7),
+		})
+		if err != nil {
+			t.Fatalf("CreateNewsletterDelivery() error = %v", err)
+		}
+		return d
+	}
+	toAlice := deliver(alice, "aliceMsg")
+	toBob := deliver(bob, "bobMsg")
+	deliver(alice, "quietMsg")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	db.RecordEmailEvent(t.Context(), "alice@example.com", "delivered", "", "ev1", "aliceMsg", now)
+	db.RecordEmailEvent(t.Context(), "alice@example.com", "open", "", "ev2", "aliceMsg", now)
+	db.RecordEmailEvent(t.Context(), "alice@example.com", "open", "", "ev3", "aliceMsg", now)
+	db.RecordEmailEvent(t.Context(), "bob@example.com", "bounce", "550 no such user", "ev4", "bobMsg", now)
+	// An event of an email sent outside any batch is not linked
+	db.RecordEmailEvent(t.Context(), "alice@example.com", "open", "", "ev5", "otherMsg", now)
+
+	events, err := db.ListNewsletterDeliveryEvents(t.Context(), batch.ID)
+	if err != nil {
+		t.Fatalf("ListNewsletterDeliveryEvents() error = %v", err)
+	}
+	want := []DeliveryEvents{
+		{DeliveryID: toAlice.ID, Delivered: true, Opened: true},
+		{DeliveryID: toBob.ID, Bounced: true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("ListNewsletterDeliveryEvents() = %+v, want %+v", events, want)
+	}
+	for i, e := range events {
+		if *e != want[i] {
+			t.Errorf("events[%d] = %+v, want %+v", i, *e, want[i])
+		}
+	}
+
+	other, _ := db.CreateNewsletterBatch(t.Context(), BatchManual, "admin@example.com")
+	if events, _ := db.ListNewsletterDeliveryEvents(t.Context(), other.ID); len(events) != 0 {
+		t.Errorf("ListNewsletterDeliveryEvents(other batch) = %+v, want none", events)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ws, _ := db.CreateWorkspace(t.Context(), "team-b")
+	wsCtx := WithWorkspace(t.Context(), ws.ID)
+	defaultCtx := WithWorkspace(t.Context(), DefaultWorkspaceID)
+
+	for _, e := range []struct{ actor, action, target string }{
+		{"alice@example.com", "repo.add", "repo-a"},
+		{"bob@example.com", "repo.remove", "repo-a"},
+		{"alice@example.com", "newsletter.send", ""},
+	} {
+		if err := db.RecordAudit(defaultCtx, e.actor, e.action, e.target, ""); err != nil {
+			t.Fatalf("RecordAudit() error = %v", err)
+		}
+	}
+	db.RecordAudit(wsCtx, "carol@example.com", "repo.add", "repo-b", "https://example.com/b.git")
+
+	entries, err := db.ListAuditLog(defaultCtx, AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAuditLog() error = %v", err)
+	}
+	if len(entries) != 3 || entries[0].Action != "newsletter.send" || entries[2].Action != "repo.add" {
+		t.Errorf("ListAuditLog() = %+v, want the default workspace's 3 entries, newest first", entries)
+	}
+
+	if entries, _ := db.ListAuditLog(defaultCtx, AuditFilter{Action: "repo."}); len(entries) != 2 {
+		t.Errorf("ListAuditLog(repo.) returned %d entries, want 2", len(entries))
+	}
+	if entries, _ := db.ListAuditLog(defaultCtx, AuditFilter{Actor: "ALICE@example.com", Action: "repo.add"}); len(entries) != 1 {
+		t.Errorf("ListAuditLog(alice, repo.add) returned %d entries, want 1", len(entries))
+	}
+	if entries, _ := db.ListAuditLog(defaultCtx, AuditFilter{Limit: 1, Offset: 1}); len(entries) != 1 || entries[0].Action != "repo.remove" {
+		t.Errorf("ListAuditLog(limit 1, offset 1) = %+v, want the repo.remove entry", entries)
+	}
+
+	entries, _ = db.ListAuditLog(wsCtx, AuditFilter{})
+	if len(entries) != 1 || entries[0].Actor != "carol@example.com" || entries[0].Details != "https://example.com/b.git" {
+		t.Errorf("ListAuditLog(team-b) = %+v, want carol's entry", entries)
+	}
+	if entries, _ := db.ListAuditLog(t.Context(), AuditFilter{}); len(entries) != 4 {
+		t.Errorf("ListAuditLog(unscoped) returned %d entries, want 4", len(entries))
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if secret, err := db.GetSecret(t.Context(), "github.private_key"); err != nil || secret != nil {
+		t.Fatalf("GetSecret(missing) = %v, %v, want nil", secret, err)
+	}
+
+	for _, s := range []struct{ name, ciphertext string }{
+		{"github.private_key", "v1:aaaa:one"},
+		{"repo.backend.token", "v1:aaaa:two"},
+		{"github.private_key", "v1:bbbb:three"},
+	} {
+		if err := db.PutSecret(t.Context(), s.name, s.ciphertext); err != nil {
+			t.Fatalf("PutSecret() error = %v", err)
+		}
+	}
+
+	secret, err := db.GetSecret(t.Context(), "github.private_key")
+	if err != nil || secret == nil || secret.Ciphertext != "v1:bbbb:three" {
+		t.Errorf("GetSecret() = %+v, %v, want the replaced ciphertext", secret, err)
+	}
+	secrets, err := db.ListSecrets(t.Context())
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if len(secrets) != 2 || secrets[0].Name != "github.private_key" || secrets[1].Name != "repo.backend.token" {
+		t.Errorf("ListSecrets() = %+v, want 2 secrets ordered by name", secrets)
+	}
+
+	if deleted, err := db.DeleteSecret(t.Context(), "repo.backend.token"); err != nil || !deleted {
+		t.Errorf("DeleteSecret() = %v, %v, want true", deleted, err)
+	}
+	if deleted, _ := db.DeleteSecret(t.Context(), "repo.backend.token"); deleted {
+		t.Error("DeleteSecret() of a missing secret = true, want false")
+	}
+}
+
+func TestUserPreferences(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	prefs, err := db.GetUserPreferences(t.Context(), "Alice@example.com")
+	if err != nil || prefs.Email != "alice@example.com" || len(prefs.FavoriteRepoIDs) != 0 || prefs.DigestFavoritesOnly {
+		t.Fatalf("GetUserPreferences(new user) = %+v, %v, want empty preferences", prefs, err)
+	}
+
+	repoA, _ := db.CreateRepository(t.Context(), "repo-a", "https://github.com/test/a", "main", true, sql.NullString{})
+	repoB, _ := db.CreateRepository(t.Context(), "repo-b", "https://github.com/test/b", "main", true, sql.NullString{})
+	for _, id := range []int64{repoA.ID, repoB.ID, repoA.ID} {
+		if err := db.SetFavorite(t.Context(), "alice@example.com", id, true); err != nil {
+			t.Fatalf("SetFavorite() error = %v", err)
+		}
+	}
+	if err := db.SetDigestFavoritesOnly(t.Context(), "ALICE@example.com", true); err != nil {
+		t.Fatalf("SetDigestFavoritesOnly() error = %v", err)
+	}
+
+	prefs, _ = db.GetUserPreferences(t.Context(), "alice@example.com")
+	if len(prefs.FavoriteRepoIDs) != 2 || !prefs.IsFavorite(repoA.ID) || !prefs.IsFavorite(repoB.ID) || !prefs.DigestFavoritesOnly {
+		t.Errorf("GetUserPreferences() = %+v, want both repos starred once and favorites-only digests", prefs)
+	}
+
+	if err := db.SetFavorite(t.Context(), "alice@example.com", repoA.ID, false); err != nil {
+		t.Fatalf("SetFavorite(false) error = %v", err)
+	}
+	if err := db.DeleteRepository(t.Context(), repoB.ID); err != nil {
+		t.Fatalf("DeleteRepository() error = %v", err)
+	}
+	prefs, _ = db.GetUserPreferences(t.Context(), "alice@example.com")
+	if len(prefs.FavoriteRepoIDs) != 0 {
+		t.Errorf("FavoriteRepoIDs = %v after unstarring and deleting, want none", prefs.FavoriteRepoIDs)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo, _ := db.CreateRepository(t.Context(), "repo-a", "https://github.com/test/a", "main", true, sql.NullString{})
+	db.SetFavorite(t.Context(), "alice@example.com", repo.ID, true)
+	db.SetFavorite(t.Context(), "bob@example.com", repo.ID, true)
+	db.CreateAdmin(t.Context(), "Carol@example.com", "")
+
+	if n, err := db.NotifyFavorites(t.Context(), repo.ID, "New report for repo-a", "/reports/1"); err != nil || n != 2 {
+		t.Fatalf("NotifyFavorites() = %d, %v, want 2", n, err)
+	}
+	if n, err := db.NotifyAdmins(t.Context(), NotificationJobFailed, "Scheduled job prune failed", "/admin"); err != nil || n != 1 {
+		t.Fatalf("NotifyAdmins() = %d, %v, want 1", n, err)
+	}
+
+	carol, err := db.ListNotifications(t.Context(), "carol@example.com", 0)
+	if err != nil || len(carol) != 1 || carol[0].Kind != NotificationJobFailed {
+		t.Errorf("ListNotifications(carol) = %v, %v, want the failed job", carol, err)
+	}
+
+	alice, _ := db.ListNotifications(t.Context(), "Alice@example.com", 10)
+	if len(alice) != 1 || alice[0].Kind != NotificationReport || alice[0].Link != "/reports/1" {
+		t.Fatalf("ListNotifications(alice) = %v, want the new report", alice)
+	}
+	if err := db.MarkNotificationsRead(t.Context(), "alice@example.com", alice[0].ID); err != nil {
+		t.Fatalf("MarkNotificationsRead() error = %v", err)
+	}
+	if unread, _ := db.CountUnreadNotifications(t.Context(), "alice@example.com"); unread != 0 {
+		t.Errorf("CountUnreadNotifications(alice) = %d after reading, want 0", unread)
+	}
+	if unread, _ := db.CountUnreadNotifications(t.Context(), "bob@example.com"); unread != 1 {
+		t.Errorf("CountUnreadNotifications(bob) = %d, want 1", unread)
+	}
+	if _, err := db.GetNotification(t.Context(), "bob@example.com", alice[0].ID); err == nil {
+		t.Error("GetNotification() of another user's notification succeeded")
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	latest, err := db.LatestMigration()
+	if err != nil {
+		t.Fatalf("LatestMigration() error = %v", err)
+	}
+	ran, err := db.MigrateTo(t.Context(), latest-1)
+	if err != nil {
+		t.Fatalf("MigrateTo(%d) error = %v", latest-1, err)
+	}
+	if len(ran) != 1 || ran[0].Version != latest || ran[0].Applied {
+		t.Fatalf("MigrateTo(%d) = %+v, want migration %d rolled back", latest-1, ran, latest)
+	}
+
+	status, err := db.MigrationStatus(t.Context())
+	if err != nil {
+		t.Fatalf("MigrationStatus() error = %v", err)
+	}
+	if last := status[len(status)-1]; last.Version != latest || last.Applied {
+		t.Errorf("last migration = %+v, want %d pending", last, latest)
+	}
+	if !status[0].Applied || status[0].AppliedAt.IsZero() {
+		t.Errorf("first migration = %+v, want applied", status[0])
+	}
+
+	if ran, err := db.MigrateTo(t.Context(), latest); err != nil || len(ran) != 1 || !ran[0].Applied {
+		t.Fatalf("MigrateTo(%d) = %+v, %v, want migration %d applied", latest, ran, err, latest)
+	}
+	if _, err := db.MigrateTo(t.Context(), latest+1); err == nil {
+		t.Error("expected error for unknown migration version, got nil")
+	}
+}
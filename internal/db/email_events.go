@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Email event operations
+
+// RecordEmailEvent stores a delivery event for every subscriber with the
+// given address in the context's workspace (all workspaces if unscoped) and
+// returns how many were recorded. Events already recorded are ignored, so
+// webhook retries are harmless. messageID is the ID returned when the
+// message was sent, which links the event to its newsletter delivery.
+func (db *DB) RecordEmailEvent(ctx context.Context, email, event, reason, eventID, messageID string, occurredAt time.Time) (int64, error) {
+	var reasonVal, messageIDVal interface{}
+	if reason != "" {
+		reasonVal = reason
+	}
+	if messageID != "" {
+		messageIDVal = messageID
+	}
+
+	result, err := db.q.ExecContext(ctx, `
+		INSERT INTO email_events (subscriber_id, event, reason, sendgrid_event_id, sendgrid_message_id, occurred_at)
+		SELECT id, $2, $3, $4, $5, $6
+		FROM subscribers
+		WHERE LOWER(email) = LOWER($1) AND ($7 = 0 OR workspace_id = $7)
+		ON CONFLICT (subscriber_id, sendgrid_event_id) DO NOTHING
+	`, email, event, reasonVal, eventID, messageIDVal, occurredAt, WorkspaceFromContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to record email event: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListEmailEventStats counts the delivery events of each subscriber in the
+// context's workspace, and the newsletters they opened (by message ID).
+// Subscribers without events are omitted.
+func (db *DB) ListEmailEventStats(ctx context.Context) ([]*EmailEventStats, error) {
+	stats, err := queryRows[EmailEventStats](ctx, db.q, `
+		SELECT e.subscriber_id,
+			COUNT(*) FILTER (WHERE e.event = 'delivered'),
+			COUNT(*) FILTER (WHERE e.event = 'bounce'),
+			COUNT(*) FILTER (WHERE e.event = 'spamreport'),
+			COUNT(*) FILTER (WHERE e.event = 'dropped'),
+			COUNT(DISTINCT e.sendgrid_message_id) FILTER (WHERE e.event = 'open'),
+			MAX(e.occurred_at)
+		FROM email_events e
+		JOIN subscribers s ON s.id = e.subscriber_id
+		WHERE ($1 = 0 OR s.workspace_id = $1)
+		GROUP BY e.subscriber_id
+		ORDER BY e.subscriber_id
+	`, WorkspaceFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email event stats: %w", err)
+	}
+	return stats, nil
+}
+
+// SuppressSubscribers marks every subscriber with the given address in the
+// context's workspace (all workspaces if unscoped) as suppressed, so
+// newsletters skip them, and returns how many were newly suppressed
+func (db *DB) SuppressSubscribers(ctx context.Context, email, reason string) (int64, error) {
+	var reasonVal interface{}
+	if reason != "" {
+		reasonVal = reason
+	}
+
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE subscribers
+		SET suppressed_at = NOW(), suppressed_reason = $2
+		WHERE LOWER(email) = LOWER($1) AND suppressed_at IS NULL AND ($3 = 0 OR workspace_id = $3)
+	`, email, reasonVal, WorkspaceFromContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to suppress subscriber: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// UnsuppressSubscriber clears a subscriber's suppression
+func (db *DB) UnsuppressSubscriber(ctx context.Context, id int64) error {
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE subscribers
+		SET suppressed_at = NULL, suppressed_reason = NULL
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, id, WorkspaceFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to unsuppress subscriber: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("subscriber not found")
+	}
+	return nil
+}
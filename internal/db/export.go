@@ -0,0 +1,202 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// ExportVersion is the version of the JSON export format
+const ExportVersion = 1
+
+// exportTable describes a table included in exports
+type exportTable struct {
+	name   string
+	key    string // Primary key column, used for ordering
+	serial bool   // Whether the key is a SERIAL whose sequence must be reset on import
+	seeded bool   // Whether migrations insert rows, which the import replaces
+}
+
+// exportTables lists all application tables in foreign key order, so that
+// importing them in this order never references a missing row. New tables
+// must be added here to be included in exports.
+var exportTables = []exportTable{
+	{name: "workspaces", key: "id", serial: true, seeded: true},
+	{name: "repositories", key: "id", serial: true},
+	{name: "activity_runs", key: "id", serial: true},
+	{name: "subscribers", key: "id", serial: true},
+	{name: "subscriptions", key: "id", serial: true},
+	{name: "newsletter_sends", key: "id", serial: true},
+	{name: "newsletter_batches", key: "id", serial: true},
+	{name: "newsletter_deliveries", key: "id", serial: true},
+	{name: "email_events", key: "id", serial: true},
+	{name: "weekly_reports", key: "id", serial: true},
+	{name: "admins", key: "id", serial: true},
+	{name: "author_aliases", key: "id", serial: true},
+	{name: "api_tokens", key: "id", serial: true},
+	{name: "audit_log", key: "id", serial: true},
+	{name: "secrets", key: "name"},
+	{name: "user_preferences", key: "email"},
+	{name: "notifications", key: "id", serial: true},
+	{name: "report_vectors", key: "report_id"},
+	{name: "commit_vectors", key: "report_id, sha"},
+}
+
+// Export is the JSON document written by ExportJSON. Each table is stored as
+// an array of row objects keyed by column name.
+type Export struct {
+	Version       int                        `json:"version"`
+	SchemaVersion int64                      `json:"schema_version"`
+	ExportedAt    time.Time                  `json:"exported_at"`
+	Tables        map[string]json.RawMessage `json:"tables"`
+}
+
+// ExportJSON writes all tables as a single JSON document. The export is taken
+// in a read-only repeatable read transaction, so it is a consistent snapshot
+// even while the server is running.
+func (db *DB) ExportJSON(ctx context.Context, w io.Writer) error {
+	schemaVersion, err := goose.GetDBVersionContext(ctx, db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+		return fmt.Errorf("failed to set transaction isolation: %w", err)
+	}
+
+	export := Export{
+		Version:       ExportVersion,
+		SchemaVersion: schemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Tables:        make(map[string]json.RawMessage, len(exportTables)),
+	}
+	for _, t := range exportTables {
+		query := fmt.Sprintf("SELECT COALESCE(json_agg(t ORDER BY t.%s), '[]') FROM %s t", t.key, t.name)
+		rows, err := queryValue[[]byte](ctx, tx, query)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+		export.Tables[t.name] = rows
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// ImportJSON loads a document written by ExportJSON into an empty database
+// in a single transaction. The export must come from the same schema version;
+// run the newer binary against the old database first to migrate it.
+func (db *DB) ImportJSON(ctx context.Context, r io.Reader) error {
+	var export Export
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return fmt.Errorf("failed to parse export: %w", err)
+	}
+	if export.Version != ExportVersion {
+		return fmt.Errorf("unsupported export version %d (want %d)", export.Version, ExportVersion)
+	}
+
+	schemaVersion, err := goose.GetDBVersionContext(ctx, db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if export.SchemaVersion != schemaVersion {
+		return fmt.Errorf("export has schema version %d but database has %d", export.SchemaVersion, schemaVersion)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range exportTables {
+		if t.seeded {
+			continue
+		}
+		exists, err := queryValue[bool](ctx, tx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", t.name))
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", t.name, err)
+		}
+		if exists {
+			return fmt.Errorf("table %s is not empty; import requires an empty database", t.name)
+		}
+	}
+
+	for _, t := range exportTables {
+		rows, ok := export.Tables[t.name]
+		if !ok {
+			continue
+		}
+		if t.seeded {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.name); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", t.name, err)
+			}
+		}
+		query := fmt.Sprintf("INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $1)", t.name)
+		if _, err := tx.ExecContext(ctx, query, string(rows)); err != nil {
+			return fmt.Errorf("failed to import %s: %w", t.name, err)
+		}
+		if t.serial {
+			query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', '%[2]s'), COALESCE(MAX(%[2]s), 1), MAX(%[2]s) IS NOT NULL) FROM %[1]s", t.name, t.key)
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("failed to reset %s sequence: %w", t.name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// Backup writes a gzipped JSON export to a timestamped file in dir, creating
+// dir if needed, and returns the path of the file. The file is written under
+// a temporary name and renamed when complete, so a failed backup never leaves
+// a truncated file behind.
+func (db *DB) Backup(ctx context.Context, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := fmt.Sprintf("activity-%s.json.gz", time.Now().UTC().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+	f, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	if err := db.ExportJSON(ctx, gz); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to finalize backup: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/perbu/activity/internal/db/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+// Migration is one schema migration and whether it is applied
+type Migration struct {
+	Version   int64
+	Name      string // File name, e.g. 00021_repository_staleness.sql
+	Applied   bool
+	AppliedAt time.Time // Zero if not applied
+}
+
+// migrator returns a goose provider for the embedded migrations. It must not
+// be closed, since that closes the database.
+func (db *DB) migrator() (*goose.Provider, error) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db.DB, migrations.FS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return provider, nil
+}
+
+// LatestMigration returns the version of the newest migration this build knows
+func (db *DB) LatestMigration() (int64, error) {
+	provider, err := db.migrator()
+	if err != nil {
+		return 0, err
+	}
+	sources := provider.ListSources()
+	if len(sources) == 0 {
+		return 0, nil
+	}
+	return sources[len(sources)-1].Version, nil
+}
+
+// MigrationStatus lists all migrations, oldest first, with whether each is
+// applied to the database
+func (db *DB) MigrationStatus(ctx context.Context) ([]Migration, error) {
+	provider, err := db.migrator()
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+	result := make([]Migration, 0, len(statuses))
+	for _, st := range statuses {
+		result = append(result, Migration{
+			Version:   st.Source.Version,
+			Name:      path.Base(st.Source.Path),
+			Applied:   st.State == goose.StateApplied,
+			AppliedAt: st.AppliedAt,
+		})
+	}
+	return result, nil
+}
+
+// MigrateTo applies the pending migrations up to and including version, or
+// rolls back the applied ones newer than it, using their Down sections.
+// Version 0 rolls back every migration. It returns the migrations run, in
+// the order they ran, with Applied false for those rolled back.
+func (db *DB) MigrateTo(ctx context.Context, version int64) ([]Migration, error) {
+	provider, err := db.migrator()
+	if err != nil {
+		return nil, err
+	}
+	known := slices.ContainsFunc(provider.ListSources(), func(s *goose.Source) bool { return s.Version == version })
+	if version != 0 && !known {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	current, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	var results []*goose.MigrationResult
+	if version < current {
+		results, err = provider.DownTo(ctx, version)
+	} else {
+		results, err = provider.UpTo(ctx, version)
+	}
+	ran := make([]Migration, 0, len(results))
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		ran = append(ran, Migration{
+			Version: r.Source.Version,
+			Name:    path.Base(r.Source.Path),
+			Applied: r.Direction == "up",
+		})
+	}
+	if err != nil {
+		return ran, fmt.Errorf("failed to migrate to version %d: %w", version, err)
+	}
+	return ran, nil
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Author aliases map alternate names/emails to a canonical author name.
+-- Resolution is global across repositories and applied on top of .mailmap.
+
+CREATE TABLE author_aliases (
+    id SERIAL PRIMARY KEY,
+    alias TEXT UNIQUE NOT NULL,           -- lowercased name or email
+    canonical_name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by TEXT
+);
+
+CREATE INDEX idx_author_aliases_canonical_name ON author_aliases(canonical_name);
+
+-- +goose Down
+DROP TABLE IF EXISTS author_aliases;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Embedding vectors of weekly report summaries for semantic search.
+-- content_hash identifies the summary text and model a vector was computed
+-- from, so stale vectors are recomputed after a report changes.
+
+CREATE TABLE report_vectors (
+    report_id INTEGER PRIMARY KEY REFERENCES weekly_reports(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_report_vectors_model ON report_vectors(model);
+
+-- +goose Down
+DROP TABLE IF EXISTS report_vectors;
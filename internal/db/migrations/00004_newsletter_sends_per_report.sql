@@ -0,0 +1,46 @@
+-- +goose Up
+-- Newsletters are sent per weekly report instead of per activity run. Sends
+-- are keyed by (repo, year, week) rather than report id, so a report that is
+-- regenerated or deleted and recreated is not sent again.
+
+ALTER TABLE newsletter_sends ADD COLUMN repo_id INTEGER REFERENCES repositories(id) ON DELETE CASCADE;
+ALTER TABLE newsletter_sends ADD COLUMN year INTEGER;
+ALTER TABLE newsletter_sends ADD COLUMN week INTEGER;
+
+-- Existing sends cover the ISO week their activity run completed in
+UPDATE newsletter_sends ns
+SET repo_id = ar.repo_id,
+    year = EXTRACT(ISOYEAR FROM COALESCE(ar.completed_at, ar.started_at))::INTEGER,
+    week = EXTRACT(WEEK FROM COALESCE(ar.completed_at, ar.started_at))::INTEGER
+FROM activity_runs ar
+WHERE ar.id = ns.activity_run_id;
+
+-- Several runs in one week collapse into a single send; keep the earliest
+DELETE FROM newsletter_sends ns
+USING newsletter_sends earlier
+WHERE ns.subscriber_id = earlier.subscriber_id
+  AND ns.repo_id = earlier.repo_id
+  AND ns.year = earlier.year
+  AND ns.week = earlier.week
+  AND ns.id > earlier.id;
+
+ALTER TABLE newsletter_sends DROP COLUMN activity_run_id;
+ALTER TABLE newsletter_sends ALTER COLUMN repo_id SET NOT NULL;
+ALTER TABLE newsletter_sends ALTER COLUMN year SET NOT NULL;
+ALTER TABLE newsletter_sends ALTER COLUMN week SET NOT NULL;
+ALTER TABLE newsletter_sends ADD CONSTRAINT newsletter_sends_subscriber_report_key UNIQUE (subscriber_id, repo_id, year, week);
+
+CREATE INDEX idx_newsletter_sends_repo_week ON newsletter_sends(repo_id, year, week);
+
+-- +goose Down
+-- Sends cannot be mapped back to activity runs, so send history is discarded
+DELETE FROM newsletter_sends;
+
+DROP INDEX IF EXISTS idx_newsletter_sends_repo_week;
+ALTER TABLE newsletter_sends DROP CONSTRAINT newsletter_sends_subscriber_report_key;
+ALTER TABLE newsletter_sends DROP COLUMN repo_id;
+ALTER TABLE newsletter_sends DROP COLUMN year;
+ALTER TABLE newsletter_sends DROP COLUMN week;
+ALTER TABLE newsletter_sends ADD COLUMN activity_run_id INTEGER NOT NULL REFERENCES activity_runs(id) ON DELETE CASCADE;
+ALTER TABLE newsletter_sends ADD CONSTRAINT newsletter_sends_subscriber_id_activity_run_id_key UNIQUE (subscriber_id, activity_run_id);
+CREATE INDEX idx_newsletter_sends_activity_run_id ON newsletter_sends(activity_run_id);
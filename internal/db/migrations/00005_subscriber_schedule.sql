@@ -0,0 +1,9 @@
+-- +goose Up
+-- Scheduled newsletters are delivered on Monday at each subscriber's
+-- preferred hour in their own timezone (an IANA name such as Europe/Oslo)
+ALTER TABLE subscribers ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE subscribers ADD COLUMN send_hour INTEGER NOT NULL DEFAULT 8 CHECK (send_hour BETWEEN 0 AND 23);
+
+-- +goose Down
+ALTER TABLE subscribers DROP COLUMN send_hour;
+ALTER TABLE subscribers DROP COLUMN timezone;
@@ -0,0 +1,60 @@
+-- +goose Up
+-- Workspaces let one deployment serve several teams. Repositories (and with
+-- them reports), subscribers and admins belong to a workspace; existing data
+-- belongs to the default workspace, which always has id 1.
+CREATE TABLE workspaces (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO workspaces (id, name) VALUES (1, 'default');
+SELECT setval(pg_get_serial_sequence('workspaces', 'id'), 1);
+
+ALTER TABLE repositories ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE subscribers ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE admins ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_repositories_workspace_id ON repositories(workspace_id);
+
+-- Subscribers and admins are unique per workspace. Repository names stay
+-- globally unique since they name the local clone.
+ALTER TABLE subscribers DROP CONSTRAINT subscribers_email_key;
+ALTER TABLE subscribers ADD CONSTRAINT subscribers_workspace_email_key UNIQUE (workspace_id, email);
+ALTER TABLE admins DROP CONSTRAINT admins_email_key;
+ALTER TABLE admins ADD CONSTRAINT admins_workspace_email_key UNIQUE (workspace_id, email);
+
+-- API tokens give read access to a single workspace. Only a SHA-256 hash of
+-- the token is stored.
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_tokens_workspace_id ON api_tokens(workspace_id);
+
+-- +goose Down
+-- Data outside the default workspace cannot be represented without
+-- workspaces, so it is discarded
+DROP TABLE IF EXISTS api_tokens;
+
+DELETE FROM repositories WHERE workspace_id <> 1;
+DELETE FROM subscribers WHERE workspace_id <> 1;
+DELETE FROM admins WHERE workspace_id <> 1;
+
+ALTER TABLE admins DROP CONSTRAINT admins_workspace_email_key;
+ALTER TABLE admins ADD CONSTRAINT admins_email_key UNIQUE (email);
+ALTER TABLE subscribers DROP CONSTRAINT subscribers_workspace_email_key;
+ALTER TABLE subscribers ADD CONSTRAINT subscribers_email_key UNIQUE (email);
+
+DROP INDEX IF EXISTS idx_repositories_workspace_id;
+ALTER TABLE admins DROP COLUMN workspace_id;
+ALTER TABLE subscribers DROP COLUMN workspace_id;
+ALTER TABLE repositories DROP COLUMN workspace_id;
+
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Delivery events reported by the SendGrid event webhook. Each event is
+-- stored once per subscriber with the address (sg_event_id makes webhook
+-- retries idempotent).
+CREATE TABLE email_events (
+    id SERIAL PRIMARY KEY,
+    subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    reason TEXT,
+    sendgrid_event_id TEXT NOT NULL,
+    sendgrid_message_id TEXT,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(subscriber_id, sendgrid_event_id)
+);
+
+CREATE INDEX idx_email_events_subscriber_id ON email_events(subscriber_id);
+
+-- Hard-bounced subscribers are suppressed: newsletters skip them until an
+-- admin clears the suppression
+ALTER TABLE subscribers ADD COLUMN suppressed_at TIMESTAMP;
+ALTER TABLE subscribers ADD COLUMN suppressed_reason TEXT;
+
+-- +goose Down
+ALTER TABLE subscribers DROP COLUMN suppressed_reason;
+ALTER TABLE subscribers DROP COLUMN suppressed_at;
+DROP TABLE email_events;
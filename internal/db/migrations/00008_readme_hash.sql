@@ -0,0 +1,7 @@
+-- +goose Up
+-- SHA-256 of the README the description was generated from, so descriptions
+-- are only regenerated when the README changes
+ALTER TABLE repositories ADD COLUMN readme_hash TEXT;
+
+-- +goose Down
+ALTER TABLE repositories DROP COLUMN readme_hash;
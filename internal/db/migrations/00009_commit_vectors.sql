@@ -0,0 +1,23 @@
+-- +goose Up
+-- Embedding vectors of commit subjects for semantic search. Commits are
+-- indexed per weekly report, so they are removed with the report and its
+-- week links the commit back to a page. The subject and author are stored
+-- so search results can be shown without the local clone.
+
+CREATE TABLE commit_vectors (
+    report_id INTEGER NOT NULL REFERENCES weekly_reports(id) ON DELETE CASCADE,
+    sha TEXT NOT NULL,
+    message TEXT NOT NULL,
+    author TEXT NOT NULL,
+    committed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    model TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (report_id, sha)
+);
+
+CREATE INDEX idx_commit_vectors_model ON commit_vectors(model);
+
+-- +goose Down
+DROP TABLE IF EXISTS commit_vectors;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Free-text notes written by admins (team names, domain terms, component
+-- map) that are added to the analyzer prompt for the repository
+ALTER TABLE repositories ADD COLUMN context_notes TEXT;
+
+-- +goose Down
+ALTER TABLE repositories DROP COLUMN context_notes;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Admin actions taken in the web UI: who changed repositories, subscribers,
+-- admins and workspaces, and who triggered report generation and newsletter
+-- sends. Entries belong to the workspace the action was taken in.
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_workspace_id ON audit_log(workspace_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_log;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Reference (file://, s3:// or gs:// URL) to the raw data of an analysis run
+-- when it is offloaded to a blob store; raw_data is then NULL.
+ALTER TABLE activity_runs ADD COLUMN raw_data_ref TEXT;
+
+-- +goose Down
+ALTER TABLE activity_runs DROP COLUMN raw_data_ref;
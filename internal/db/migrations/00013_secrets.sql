@@ -0,0 +1,13 @@
+-- +goose Up
+-- Secrets such as GitHub App private keys, encrypted with a master key kept
+-- outside the database (see internal/secrets). Names are dotted paths, e.g.
+-- github.private_key.
+CREATE TABLE secrets (
+    name TEXT PRIMARY KEY,
+    ciphertext TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE secrets;
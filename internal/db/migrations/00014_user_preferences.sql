@@ -0,0 +1,13 @@
+-- +goose Up
+-- Per-user settings of signed-in web users, keyed by the lowercased email
+-- from the auth header: the repositories they starred, shown first on the
+-- dashboard, and whether their newsletter only covers those repositories.
+CREATE TABLE user_preferences (
+    email TEXT PRIMARY KEY,
+    favorite_repo_ids INTEGER[] NOT NULL DEFAULT '{}',
+    digest_favorites_only BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE user_preferences;
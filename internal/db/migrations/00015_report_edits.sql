@@ -0,0 +1,19 @@
+-- +goose Up
+-- Admins can hand-edit a report's summary in the web UI. The generated
+-- summary is kept in original_summary on the first edit, so it can be shown
+-- or restored. A forced regeneration from the web UI records who asked for it
+-- and why. Regenerating a report clears both, since its summary is new.
+ALTER TABLE weekly_reports ADD COLUMN original_summary TEXT;
+ALTER TABLE weekly_reports ADD COLUMN edited_by TEXT;
+ALTER TABLE weekly_reports ADD COLUMN edited_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE weekly_reports ADD COLUMN regeneration_note TEXT;
+ALTER TABLE weekly_reports ADD COLUMN regenerated_by TEXT;
+ALTER TABLE weekly_reports ADD COLUMN regenerated_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE weekly_reports DROP COLUMN regenerated_at;
+ALTER TABLE weekly_reports DROP COLUMN regenerated_by;
+ALTER TABLE weekly_reports DROP COLUMN regeneration_note;
+ALTER TABLE weekly_reports DROP COLUMN edited_at;
+ALTER TABLE weekly_reports DROP COLUMN edited_by;
+ALTER TABLE weekly_reports DROP COLUMN original_summary;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- In-app notifications shown in the web UI's notification panel: new reports
+-- of repositories a user starred, and failed scheduled jobs for admins. Each
+-- recipient (a lowercased email) gets their own row, so read state is per
+-- user.
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    link TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_notifications_recipient ON notifications(recipient, created_at);
+
+-- +goose Down
+DROP TABLE notifications;
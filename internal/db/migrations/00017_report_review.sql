@@ -0,0 +1,15 @@
+-- +goose Up
+-- Review state of weekly reports. Unless newsletter.auto_approve is set,
+-- reports are saved as drafts and newsletters only include reports an admin
+-- approved. Existing reports were sent without review, so they are approved.
+ALTER TABLE weekly_reports ADD COLUMN review_state TEXT NOT NULL DEFAULT 'approved';
+ALTER TABLE weekly_reports ADD COLUMN approved_by TEXT;
+ALTER TABLE weekly_reports ADD COLUMN approved_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_weekly_reports_review_state ON weekly_reports(review_state) WHERE review_state <> 'approved';
+
+-- +goose Down
+DROP INDEX idx_weekly_reports_review_state;
+ALTER TABLE weekly_reports DROP COLUMN approved_at;
+ALTER TABLE weekly_reports DROP COLUMN approved_by;
+ALTER TABLE weekly_reports DROP COLUMN review_state;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Who may read a repository's reports in the web UI: anyone (public), signed
+-- in users (authenticated) or admins only (admin)
+ALTER TABLE repositories ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'authenticated', 'admin'));
+
+-- +goose Down
+ALTER TABLE repositories DROP COLUMN visibility;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- When a report that was already sent in a newsletter is regenerated, the
+-- summary its recipients received is kept in sent_summary until a correction
+-- is sent to them (newsletter.send_corrections) or found unnecessary.
+ALTER TABLE weekly_reports ADD COLUMN sent_summary TEXT;
+
+-- +goose Down
+ALTER TABLE weekly_reports DROP COLUMN sent_summary;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- History of newsletter sends for the admin send history page. A batch is one
+-- run of sending in a workspace: a send started from the web UI or command
+-- line, a scheduled send, or a send to a single subscriber. Each subscriber
+-- it tried to send to gets a delivery with the outcome, the provider's
+-- message ID or the error, and the window of report weeks, so failed
+-- deliveries can be resent. A resend updates the delivery and counts the
+-- attempt. Dry runs and test sends are not recorded.
+CREATE TABLE newsletter_batches (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL DEFAULT 1 REFERENCES workspaces(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    started_by TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_newsletter_batches_workspace ON newsletter_batches(workspace_id, started_at);
+
+CREATE TABLE newsletter_deliveries (
+    id SERIAL PRIMARY KEY,
+    batch_id INTEGER NOT NULL REFERENCES newsletter_batches(id) ON DELETE CASCADE,
+    subscriber_id INTEGER REFERENCES subscribers(id) ON DELETE SET NULL,
+    email TEXT NOT NULL,
+    status TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    report_count INTEGER NOT NULL DEFAULT 0,
+    message_id TEXT,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    window_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_newsletter_deliveries_batch ON newsletter_deliveries(batch_id);
+
+-- +goose Down
+DROP TABLE newsletter_deliveries;
+DROP TABLE newsletter_batches;
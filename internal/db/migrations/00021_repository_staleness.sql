@@ -0,0 +1,11 @@
+-- +goose Up
+-- The date of the newest commit on a repository's branch, recorded on each
+-- update, flags repositories without commits for stale_weeks weeks. Admins
+-- are notified once when a repository goes stale: stale_notified_at is set
+-- then, and a newer commit makes it stale anew.
+ALTER TABLE repositories ADD COLUMN last_commit_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE repositories ADD COLUMN stale_notified_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE repositories DROP COLUMN stale_notified_at;
+ALTER TABLE repositories DROP COLUMN last_commit_at;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Delivery events are linked by message ID to the newsletter delivery of the
+-- email they report on, which has the same message ID as its newsletter_sends
+-- rows. SendGrid's sg_message_id is the X-Message-Id returned when sending
+-- followed by a dot and routing details, so only the part before the first
+-- dot is kept.
+UPDATE email_events
+SET sendgrid_message_id = split_part(sendgrid_message_id, '.', 1)
+WHERE sendgrid_message_id LIKE '%.%';
+
+CREATE INDEX idx_email_events_message_id ON email_events(sendgrid_message_id);
+CREATE INDEX idx_newsletter_deliveries_message_id ON newsletter_deliveries(message_id);
+
+-- +goose Down
+DROP INDEX idx_newsletter_deliveries_message_id;
+DROP INDEX idx_email_events_message_id;
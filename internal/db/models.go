@@ -3,22 +3,32 @@ package db
 import (
 	"database/sql"
 	"path/filepath"
+	"slices"
 	"time"
 )
 
 // Repository represents a Git repository being tracked
 type Repository struct {
-	ID          int64
-	Name        string
-	URL         string
-	Branch      string
-	Active      bool
-	Private     bool           // Requires GitHub App authentication
-	Description sql.NullString // AI-generated description from README
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	LastRunAt   sql.NullTime
-	LastRunSHA  sql.NullString
+	ID           int64
+	Name         string
+	URL          string
+	Branch       string
+	Active       bool
+	Private      bool           // Requires GitHub App authentication
+	Description  sql.NullString // AI-generated description from README
+	ReadmeHash   sql.NullString // SHA-256 of the README the description was generated from
+	ContextNotes sql.NullString // Admin-written vocabulary and background added to analyzer prompts
+	Visibility   string         // Who may read its reports in the web UI (VisibilityPublic etc.)
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	LastRunAt    sql.NullTime
+	LastRunSHA   sql.NullString
+	WorkspaceID  int64
+
+	// Newest commit on the branch as of the last update, and when admins
+	// were told the repository went stale
+	LastCommitAt    sql.NullTime
+	StaleNotifiedAt sql.NullTime
 }
 
 // RepoLocalPath computes the local filesystem path for a repository.
@@ -38,18 +48,31 @@ type ActivityRun struct {
 	CompletedAt sql.NullTime
 	Summary     sql.NullString
 	RawData     sql.NullString // JSON
+	RawDataRef  sql.NullString // Blob holding RawData when it is offloaded (RawData is then NULL)
 
 	// Phase 3: Agent-based analysis fields
 	AgentMode      bool           // Whether agent-based analysis was used
 	ToolUsageStats sql.NullString // JSON: cost tracker metadata
 }
 
+// RawDataBlob is the offloaded raw data of an activity run
+type RawDataBlob struct {
+	RunID int64
+	Ref   string
+}
+
 // Subscriber represents an email subscriber for newsletters
 type Subscriber struct {
 	ID           int64
 	Email        string
-	SubscribeAll bool // If true, subscribed to all repos
+	SubscribeAll bool   // If true, subscribed to all repos
+	Timezone     string // IANA timezone scheduled newsletters are delivered in
+	SendHour     int    // Local hour (0-23) on Monday scheduled newsletters are delivered at
+	WorkspaceID  int64
 	CreatedAt    time.Time
+
+	SuppressedAt     sql.NullTime   // Set when the address hard-bounced; newsletters skip it
+	SuppressedReason sql.NullString // Bounce reason reported by SendGrid
 }
 
 // Subscription represents a subscriber's subscription to a specific repository
@@ -60,13 +83,150 @@ type Subscription struct {
 	CreatedAt    time.Time
 }
 
-// NewsletterSend tracks which activity runs have been sent to which subscribers
+// NewsletterSend tracks which weekly reports have been sent to which
+// subscribers. Reports are identified by repository and ISO week, so a
+// regenerated report is not sent again.
 type NewsletterSend struct {
 	ID                int64
 	SubscriberID      int64
-	ActivityRunID     int64
+	RepoID            int64
+	Year              int
+	Week              int
 	SentAt            time.Time
+	SendGridMessageID sql.NullString // Message ID returned by the email provider (SendGrid, Postmark or Mailgun)
+}
+
+// NewsletterBatch is one run of newsletter sending in a workspace, for the
+// send history page
+type NewsletterBatch struct {
+	ID          int64
+	WorkspaceID int64
+	Kind        string         // BatchManual, BatchScheduled or BatchSingle
+	StartedBy   sql.NullString // Admin who started the send; unset for scheduled sends
+	StartedAt   time.Time
+	Sent        int // Deliveries that were sent, counted when listed
+	Failed      int // Deliveries that failed, counted when listed
+}
+
+// Newsletter batch kinds
+const (
+	BatchManual    = "manual"    // Send to all subscribers from the web UI or command line
+	BatchScheduled = "scheduled" // Scheduled send
+	BatchSingle    = "single"    // Send to one subscriber
+)
+
+// NewsletterDelivery is the outcome of sending a batch's newsletter to one
+// subscriber. The window is the range of week ends the reports were picked
+// from, so a failed delivery can be resent with the same reports.
+type NewsletterDelivery struct {
+	ID           int64
+	BatchID      int64
+	SubscriberID sql.NullInt64 // Unset once the subscriber is deleted
+	Email        string
+	Status       string // DeliverySent, DeliveryFailed or DeliverySkipped
+	Subject      string
+	ReportCount  int
+	MessageID    sql.NullString // Message ID returned by the email provider
+	Error        sql.NullString // Error of the last failed attempt
+	Attempts     int
+	WindowStart  time.Time
+	WindowEnd    time.Time
+	UpdatedAt    time.Time
+}
+
+// Newsletter delivery statuses
+const (
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped" // A resend found the reports already sent
+)
+
+// EmailEvent is a delivery event (delivered, bounce, spamreport, ...)
+// reported by the SendGrid event webhook for a subscriber
+type EmailEvent struct {
+	ID                int64
+	SubscriberID      int64
+	Event             string
+	Reason            sql.NullString
+	SendGridEventID   string
 	SendGridMessageID sql.NullString
+	OccurredAt        time.Time
+	CreatedAt         time.Time
+}
+
+// AuditEntry records an admin action: who (Actor, an email address) did
+// what (Action, e.g. "repo.add") to which object (Target, e.g. a repository
+// name), with free-text Details
+type AuditEntry struct {
+	ID          int64
+	WorkspaceID int64
+	Actor       string
+	Action      string
+	Target      string
+	Details     string
+	CreatedAt   time.Time
+}
+
+// Secret is a secret stored in the database, such as a GitHub App private
+// key, encrypted with the master key
+type Secret struct {
+	Name       string
+	Ciphertext string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// UserPreferences holds a signed-in user's starred repositories and
+// newsletter personalization
+type UserPreferences struct {
+	Email               string
+	FavoriteRepoIDs     []int64
+	DigestFavoritesOnly bool // If true, newsletters only cover starred repositories
+	UpdatedAt           time.Time
+}
+
+// IsFavorite reports whether the user starred the repository
+func (p *UserPreferences) IsFavorite(repoID int64) bool {
+	return slices.Contains(p.FavoriteRepoIDs, repoID)
+}
+
+// Notification kinds
+const (
+	NotificationReport    = "report"     // A starred repository has a new report
+	NotificationJobFailed = "job_failed" // A scheduled job failed; sent to admins
+	NotificationStaleRepo = "stale_repo" // A repository has had no commits for stale_weeks; sent to admins
+)
+
+// Notification is an entry in a user's in-app notification panel
+type Notification struct {
+	ID          int64
+	WorkspaceID int64
+	Recipient   string // Lowercased email
+	Kind        string
+	Title       string
+	Link        string // Path in the web UI, empty if none
+	CreatedAt   time.Time
+	ReadAt      sql.NullTime
+}
+
+// EmailEventStats counts a subscriber's delivery events
+type EmailEventStats struct {
+	SubscriberID int64
+	Delivered    int
+	Bounces      int // Hard and soft bounces, including blocked messages
+	SpamReports  int
+	Dropped      int
+	Opened       int // Newsletters opened at least once
+	LastEventAt  time.Time
+}
+
+// DeliveryEvents is what the email provider reported for a newsletter
+// delivery, linked to it by message ID
+type DeliveryEvents struct {
+	DeliveryID int64
+	Delivered  bool
+	Bounced    bool // Hard or soft bounce, including blocked messages
+	Opened     bool
 }
 
 // WeeklyReport represents a week-indexed analysis summary for a repository
@@ -85,12 +245,114 @@ type WeeklyReport struct {
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	SourceRunID    sql.NullInt64
+
+	// Set when an admin edited the summary; OriginalSummary is the generated
+	// summary from before the first edit
+	OriginalSummary sql.NullString
+	EditedBy        sql.NullString
+	EditedAt        sql.NullTime
+
+	// Set when an admin forced regeneration in the web UI, with their reason
+	RegenerationNote sql.NullString
+	RegeneratedBy    sql.NullString
+	RegeneratedAt    sql.NullTime
+
+	// ReviewDraft or ReviewApproved; newsletters only include approved
+	// reports. ApprovedBy is unset for automatically approved reports.
+	ReviewState string
+	ApprovedBy  sql.NullString
+	ApprovedAt  sql.NullTime
+
+	// Summary newsletter recipients received, set when the report is
+	// regenerated after it was sent and cleared once a correction is sent
+	SentSummary sql.NullString
 }
 
+// Review states of weekly reports
+const (
+	ReviewDraft    = "draft"
+	ReviewApproved = "approved"
+)
+
 // Admin represents an admin user for web authentication
 type Admin struct {
+	ID          int64
+	Email       string
+	CreatedAt   time.Time
+	CreatedBy   sql.NullString // Email of admin who created this admin
+	WorkspaceID int64          // Workspace the admin may administer
+}
+
+// AuthorAlias maps an alternate author name or email to a canonical name
+type AuthorAlias struct {
+	ID            int64
+	Alias         string // lowercased name or email
+	CanonicalName string
+	CreatedAt     time.Time
+	CreatedBy     sql.NullString
+}
+
+// Workspace groups the repositories, subscribers and admins of one team
+type Workspace struct {
 	ID        int64
-	Email     string
+	Name      string
 	CreatedAt time.Time
-	CreatedBy sql.NullString // Email of admin who created this admin
+}
+
+// APIToken grants read access to one workspace. Only the SHA-256 hash of the
+// token is stored.
+type APIToken struct {
+	ID          int64
+	WorkspaceID int64
+	Name        string
+	TokenHash   string
+	CreatedBy   sql.NullString
+	CreatedAt   time.Time
+	LastUsedAt  sql.NullTime
+}
+
+// ReportVector is the embedding of a weekly report summary used for semantic search
+type ReportVector struct {
+	ReportID    int64
+	Model       string // embedding model that produced the vector
+	ContentHash string // hash of the embedded text, used to detect stale vectors
+	Embedding   []float32
+	CreatedAt   time.Time
+}
+
+// CommitVector is the embedding of a commit subject, indexed with the weekly
+// report whose week contains the commit
+type CommitVector struct {
+	ReportID    int64
+	SHA         string
+	Message     string
+	Author      string
+	CommittedAt time.Time
+	Model       string // embedding model that produced the vector
+	ContentHash string // hash of the embedded text, used to detect stale vectors
+	Embedding   []float32
+	CreatedAt   time.Time
+}
+
+// PruneOptions selects the data removed by Prune. A zero cutoff keeps that
+// data forever.
+type PruneOptions struct {
+	ActivityRunsBefore    time.Time // Delete activity runs started before this time
+	RawDataBefore         time.Time // Clear the raw data of activity runs started before this time
+	NewsletterSendsBefore time.Time // Delete newsletter send records and send history batches from before this time
+	WeeklyReportsBefore   time.Time // Delete weekly reports whose week ended before this time
+	VectorModel           string    // Delete report vectors from other embedding models (empty keeps all)
+	DryRun                bool      // Count the rows that would be deleted without deleting them
+}
+
+// PruneResult contains the number of rows deleted by Prune, including rows
+// removed by cascading deletes
+type PruneResult struct {
+	ActivityRuns      int64
+	RawData           int64 // Runs whose raw data was cleared (not deleted)
+	NewsletterSends   int64
+	NewsletterBatches int64 // Send history batches, with their deliveries
+	WeeklyReports     int64
+	ReportVectors     int64
+	CommitVectors     int64
 }
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Newsletter send history operations
+
+// CreateNewsletterBatch records the start of a newsletter send in the
+// context's workspace. startedBy is the admin who started it, or empty.
+func (db *DB) CreateNewsletterBatch(ctx context.Context, kind, startedBy string) (*NewsletterBatch, error) {
+	var startedByVal interface{}
+	if startedBy != "" {
+		startedByVal = startedBy
+	}
+	batch, err := queryRow[NewsletterBatch](ctx, db.q, `
+		INSERT INTO newsletter_batches (workspace_id, kind, started_by)
+		VALUES ($1, $2, $3)
+		RETURNING `+newsletterBatchColumns,
+		writeWorkspace(ctx), kind, startedByVal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create newsletter batch: %w", err)
+	}
+	return batch, nil
+}
+
+// GetNewsletterBatch retrieves a newsletter batch of the context's workspace
+// by ID
+func (db *DB) GetNewsletterBatch(ctx context.Context, id int64) (*NewsletterBatch, error) {
+	batch, err := queryRow[NewsletterBatch](ctx, db.q, `
+		SELECT `+newsletterBatchColumns+`
+		FROM newsletter_batches
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, id, WorkspaceFromContext(ctx))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("newsletter batch not found")
+		}
+		return nil, fmt.Errorf("failed to get newsletter batch: %w", err)
+	}
+	return batch, nil
+}
+
+// ListNewsletterBatches retrieves the newsletter batches of the context's
+// workspace, newest first
+func (db *DB) ListNewsletterBatches(ctx context.Context, limit, offset int) ([]*NewsletterBatch, error) {
+	batches, err := queryRows[NewsletterBatch](ctx, db.q, `
+		SELECT `+newsletterBatchColumns+`
+		FROM newsletter_batches
+		WHERE $1 = 0 OR workspace_id = $1
+		ORDER BY started_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, WorkspaceFromContext(ctx), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list newsletter batches: %w", err)
+	}
+	return batches, nil
+}
+
+// CreateNewsletterDelivery records the outcome of sending a batch's
+// newsletter to a subscriber
+func (db *DB) CreateNewsletterDelivery(ctx context.Context, d *NewsletterDelivery) (*NewsletterDelivery, error) {
+	created, err := queryRow[NewsletterDelivery](ctx, db.q, `
+		INSERT INTO newsletter_deliveries (batch_id, subscriber_id, email, status, subject, report_count, message_id, error, window_start, window_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+newsletterDeliveryColumns,
+		d.BatchID, d.SubscriberID, d.Email, d.Status, d.Subject, d.ReportCount, d.MessageID, d.Error,
+		d.WindowStart, d.WindowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create newsletter delivery: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateNewsletterDelivery records the outcome of resending a delivery,
+// counting the attempt
+func (db *DB) UpdateNewsletterDelivery(ctx context.Context, d *NewsletterDelivery) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE newsletter_deliveries
+		SET status = $1, subject = $2, report_count = $3, message_id = $4, error = $5,
+		    attempts = attempts + 1, updated_at = NOW()
+		WHERE id = $6
+	`, d.Status, d.Subject, d.ReportCount, d.MessageID, d.Error, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update newsletter delivery: %w", err)
+	}
+	return nil
+}
+
+// ListNewsletterDeliveries retrieves the deliveries of a batch, failed ones
+// first, then by address
+func (db *DB) ListNewsletterDeliveries(ctx context.Context, batchID int64) ([]*NewsletterDelivery, error) {
+	deliveries, err := queryRows[NewsletterDelivery](ctx, db.q, `
+		SELECT `+newsletterDeliveryColumns+`
+		FROM newsletter_deliveries
+		WHERE batch_id = $1
+		ORDER BY status <> 'failed', email, id
+	`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list newsletter deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ListNewsletterDeliveryEvents retrieves the provider events of a batch's
+// deliveries, matched by message ID. Deliveries without events are omitted.
+func (db *DB) ListNewsletterDeliveryEvents(ctx context.Context, batchID int64) ([]*DeliveryEvents, error) {
+	events, err := queryRows[DeliveryEvents](ctx, db.q, `
+		SELECT d.id,
+			BOOL_OR(e.event = 'delivered'),
+			BOOL_OR(e.event = 'bounce'),
+			BOOL_OR(e.event = 'open')
+		FROM newsletter_deliveries d
+		JOIN email_events e ON e.sendgrid_message_id = d.message_id
+		WHERE d.batch_id = $1
+		GROUP BY d.id
+		ORDER BY d.id
+	`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list newsletter delivery events: %w", err)
+	}
+	return events, nil
+}
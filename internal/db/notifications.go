@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Notification operations. Notifications are fanned out to one row per
+// recipient when created.
+
+// NotifyFavorites notifies every user who starred the repository, in the
+// repository's workspace, returning the number of notifications created
+func (db *DB) NotifyFavorites(ctx context.Context, repoID int64, title, link string) (int, error) {
+	result, err := db.q.ExecContext(ctx, `
+		INSERT INTO notifications (workspace_id, recipient, kind, title, link)
+		SELECT r.workspace_id, p.email, $2, $3, $4
+		FROM user_preferences p
+		JOIN repositories r ON r.id = $1
+		WHERE $1 = ANY(p.favorite_repo_ids)
+	`, repoID, NotificationReport, title, link)
+	if err != nil {
+		return 0, fmt.Errorf("failed to notify favorites: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// NotifyAdmins notifies the admins of the context's workspace (of every
+// workspace if unscoped), returning the number of notifications created
+func (db *DB) NotifyAdmins(ctx context.Context, kind, title, link string) (int, error) {
+	result, err := db.q.ExecContext(ctx, `
+		INSERT INTO notifications (workspace_id, recipient, kind, title, link)
+		SELECT workspace_id, LOWER(email), $2, $3, $4
+		FROM admins
+		WHERE $1 = 0 OR workspace_id = $1
+	`, WorkspaceFromContext(ctx), kind, title, link)
+	if err != nil {
+		return 0, fmt.Errorf("failed to notify admins: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// ListNotifications retrieves a user's notifications in the context's
+// workspace, newest first. A limit of 0 returns all of them.
+func (db *DB) ListNotifications(ctx context.Context, recipient string, limit int) ([]*Notification, error) {
+	var limitArg any
+	if limit > 0 {
+		limitArg = limit
+	}
+	notifications, err := queryRows[Notification](ctx, db.q, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE recipient = $1 AND ($2 = 0 OR workspace_id = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`, strings.ToLower(recipient), WorkspaceFromContext(ctx), limitArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// CountUnreadNotifications counts a user's unread notifications in the
+// context's workspace
+func (db *DB) CountUnreadNotifications(ctx context.Context, recipient string) (int, error) {
+	count, err := queryValue[int](ctx, db.q, `
+		SELECT COUNT(*) FROM notifications
+		WHERE recipient = $1 AND ($2 = 0 OR workspace_id = $2) AND read_at IS NULL
+	`, strings.ToLower(recipient), WorkspaceFromContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// GetNotification retrieves one of a user's notifications by ID
+func (db *DB) GetNotification(ctx context.Context, recipient string, id int64) (*Notification, error) {
+	notification, err := queryRow[Notification](ctx, db.q, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE id = $1 AND recipient = $2 AND ($3 = 0 OR workspace_id = $3)
+	`, id, strings.ToLower(recipient), WorkspaceFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return notification, nil
+}
+
+// MarkNotificationsRead marks one of a user's notifications as read, or all
+// of them in the context's workspace if id is 0
+func (db *DB) MarkNotificationsRead(ctx context.Context, recipient string, id int64) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW()
+		WHERE recipient = $1 AND ($2 = 0 OR workspace_id = $2) AND ($3 = 0 OR id = $3) AND read_at IS NULL
+	`, strings.ToLower(recipient), WorkspaceFromContext(ctx), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
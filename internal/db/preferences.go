@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// User preference operations. Users are identified by the email from the auth
+// header, compared case-insensitively.
+
+// GetUserPreferences retrieves a user's preferences, or empty preferences if
+// the user never saved any
+func (db *DB) GetUserPreferences(ctx context.Context, email string) (*UserPreferences, error) {
+	email = strings.ToLower(email)
+	prefs, err := queryRow[UserPreferences](ctx, db.q, `
+		SELECT `+userPreferencesColumns+`
+		FROM user_preferences
+		WHERE email = $1
+	`, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &UserPreferences{Email: email}, nil
+		}
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SetFavorite stars a repository for a user, or unstars it if favorite is
+// false. Starring a repository twice has no further effect.
+func (db *DB) SetFavorite(ctx context.Context, email string, repoID int64, favorite bool) error {
+	query := `
+		INSERT INTO user_preferences (email, favorite_repo_ids)
+		VALUES ($1, ARRAY[$2::INTEGER])
+		ON CONFLICT (email) DO UPDATE SET
+			favorite_repo_ids = CASE WHEN $2 = ANY(user_preferences.favorite_repo_ids)
+				THEN user_preferences.favorite_repo_ids
+				ELSE array_append(user_preferences.favorite_repo_ids, $2::INTEGER) END,
+			updated_at = NOW()
+	`
+	if !favorite {
+		query = `
+			UPDATE user_preferences
+			SET favorite_repo_ids = array_remove(favorite_repo_ids, $2::INTEGER), updated_at = NOW()
+			WHERE email = $1
+		`
+	}
+	if _, err := db.q.ExecContext(ctx, query, strings.ToLower(email), repoID); err != nil {
+		return fmt.Errorf("failed to update favorites: %w", err)
+	}
+	return nil
+}
+
+// SetDigestFavoritesOnly sets whether a user's newsletter only covers their
+// starred repositories
+func (db *DB) SetDigestFavoritesOnly(ctx context.Context, email string, favoritesOnly bool) error {
+	_, err := db.q.ExecContext(ctx, `
+		INSERT INTO user_preferences (email, digest_favorites_only)
+		VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE SET digest_favorites_only = EXCLUDED.digest_favorites_only, updated_at = NOW()
+	`, strings.ToLower(email), favoritesOnly)
+	if err != nil {
+		return fmt.Errorf("failed to update newsletter preference: %w", err)
+	}
+	return nil
+}
@@ -1,39 +1,37 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Repository CRUD operations
 
-// CreateRepository inserts a new repository into the database
-func (db *DB) CreateRepository(name, url, branch string, private bool, description sql.NullString) (*Repository, error) {
-	var id int64
-	err := db.QueryRow(`
-		INSERT INTO repositories (name, url, branch, active, private, description)
-		VALUES ($1, $2, $3, true, $4, $5)
-		RETURNING id
-	`, name, url, branch, private, description).Scan(&id)
+// CreateRepository inserts a new repository into the context's workspace
+func (db *DB) CreateRepository(ctx context.Context, name, url, branch string, private bool, description sql.NullString) (*Repository, error) {
+	repo, err := queryRow[Repository](ctx, db.q, `
+		INSERT INTO repositories (name, url, branch, active, private, description, workspace_id)
+		VALUES ($1, $2, $3, true, $4, $5, $6)
+		RETURNING `+repositoryColumns,
+		name, url, branch, private, description, writeWorkspace(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
-
-	return db.GetRepository(id)
+	return repo, nil
 }
 
 // GetRepository retrieves a repository by ID
-func (db *DB) GetRepository(id int64) (*Repository, error) {
-	repo := &Repository{}
-	err := db.QueryRow(`
-		SELECT id, name, url, branch, active, COALESCE(private, false), description, created_at, updated_at, last_run_at, last_run_sha
+func (db *DB) GetRepository(ctx context.Context, id int64) (*Repository, error) {
+	repo, err := queryRow[Repository](ctx, db.q, `
+		SELECT `+repositoryColumns+`
 		FROM repositories
-		WHERE id = $1
-	`, id).Scan(
-		&repo.ID, &repo.Name, &repo.URL, &repo.Branch,
-		&repo.Active, &repo.Private, &repo.Description, &repo.CreatedAt, &repo.UpdatedAt, &repo.LastRunAt, &repo.LastRunSHA,
-	)
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2) AND visibility = ANY($3)
+	`, id, WorkspaceFromContext(ctx), visibleLevels(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("repository not found")
@@ -44,16 +42,12 @@ func (db *DB) GetRepository(id int64) (*Repository, error) {
 }
 
 // GetRepositoryByName retrieves a repository by name
-func (db *DB) GetRepositoryByName(name string) (*Repository, error) {
-	repo := &Repository{}
-	err := db.QueryRow(`
-		SELECT id, name, url, branch, active, COALESCE(private, false), description, created_at, updated_at, last_run_at, last_run_sha
+func (db *DB) GetRepositoryByName(ctx context.Context, name string) (*Repository, error) {
+	repo, err := queryRow[Repository](ctx, db.q, `
+		SELECT `+repositoryColumns+`
 		FROM repositories
-		WHERE name = $1
-	`, name).Scan(
-		&repo.ID, &repo.Name, &repo.URL, &repo.Branch,
-		&repo.Active, &repo.Private, &repo.Description, &repo.CreatedAt, &repo.UpdatedAt, &repo.LastRunAt, &repo.LastRunSHA,
-	)
+		WHERE name = $1 AND ($2 = 0 OR workspace_id = $2) AND visibility = ANY($3)
+	`, name, WorkspaceFromContext(ctx), visibleLevels(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("repository not found")
@@ -64,46 +58,28 @@ func (db *DB) GetRepositoryByName(name string) (*Repository, error) {
 }
 
 // ListRepositories retrieves all repositories, optionally filtered by active status
-func (db *DB) ListRepositories(activeOnly *bool) ([]*Repository, error) {
-	query := `
-		SELECT id, name, url, branch, active, COALESCE(private, false), description, created_at, updated_at, last_run_at, last_run_sha
-		FROM repositories
-	`
-	var args []interface{}
+func (db *DB) ListRepositories(ctx context.Context, activeOnly *bool) ([]*Repository, error) {
+	query := `SELECT ` + repositoryColumns + ` FROM repositories WHERE ($1 = 0 OR workspace_id = $1) AND visibility = ANY($2)`
+	args := []interface{}{WorkspaceFromContext(ctx), visibleLevels(ctx)}
 
 	if activeOnly != nil {
-		query += " WHERE active = $1"
+		query += " AND active = $3"
 		args = append(args, *activeOnly)
 	}
 
 	query += " ORDER BY name"
 
-	rows, err := db.Query(query, args...)
+	repos, err := queryRows[Repository](ctx, db.q, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
-	defer rows.Close()
-
-	var repos []*Repository
-	for rows.Next() {
-		repo := &Repository{}
-		err := rows.Scan(
-			&repo.ID, &repo.Name, &repo.URL, &repo.Branch,
-			&repo.Active, &repo.Private, &repo.Description, &repo.CreatedAt, &repo.UpdatedAt, &repo.LastRunAt, &repo.LastRunSHA,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan repository: %w", err)
-		}
-		repos = append(repos, repo)
-	}
-
 	return repos, nil
 }
 
 // UpdateRepository updates a repository's fields
-func (db *DB) UpdateRepository(repo *Repository) error {
+func (db *DB) UpdateRepository(ctx context.Context, repo *Repository) error {
 	repo.UpdatedAt = time.Now()
-	_, err := db.Exec(`
+	_, err := db.q.ExecContext(ctx, `
 		UPDATE repositories
 		SET name = $1, url = $2, branch = $3, active = $4, private = $5, description = $6, updated_at = $7, last_run_at = $8, last_run_sha = $9
 		WHERE id = $10
@@ -115,17 +91,97 @@ func (db *DB) UpdateRepository(repo *Repository) error {
 }
 
 // DeleteRepository deletes a repository by ID
-func (db *DB) DeleteRepository(id int64) error {
-	_, err := db.Exec("DELETE FROM repositories WHERE id = $1", id)
+func (db *DB) DeleteRepository(ctx context.Context, id int64) error {
+	return db.WithTx(ctx, func(tx *DB) error {
+		// Delete dependent rows explicitly, children first, rather than relying
+		// on ON DELETE CASCADE alone, so removal never leaves orphans behind even
+		// if a table is added without a cascading foreign key
+		cleanup := []struct {
+			what  string
+			query string
+		}{
+			{"newsletter sends", `DELETE FROM newsletter_sends WHERE repo_id = $1`},
+			{"report vectors", `DELETE FROM report_vectors WHERE report_id IN (SELECT id FROM weekly_reports WHERE repo_id = $1)`},
+			{"commit vectors", `DELETE FROM commit_vectors WHERE report_id IN (SELECT id FROM weekly_reports WHERE repo_id = $1)`},
+			{"weekly reports", `DELETE FROM weekly_reports WHERE repo_id = $1`},
+			{"activity runs", `DELETE FROM activity_runs WHERE repo_id = $1`},
+			{"subscriptions", `DELETE FROM subscriptions WHERE repo_id = $1`},
+			{"favorites", `UPDATE user_preferences SET favorite_repo_ids = array_remove(favorite_repo_ids, $1) WHERE $1 = ANY(favorite_repo_ids)`},
+		}
+		for _, c := range cleanup {
+			if _, err := tx.q.ExecContext(ctx, c.query, id); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", c.what, err)
+			}
+		}
+
+		result, err := tx.q.ExecContext(ctx, "DELETE FROM repositories WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete repository: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("repository not found")
+		}
+		return nil
+	})
+}
+
+// SetRepositoryLastCommit records the date of the newest commit on a
+// repository's branch
+func (db *DB) SetRepositoryLastCommit(ctx context.Context, id int64, at time.Time) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE repositories SET last_commit_at = $1 WHERE id = $2
+	`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to set repository last commit: %w", err)
+	}
+	return nil
+}
+
+// MarkRepositoryStaleNotified records that admins were notified that a
+// repository went stale
+func (db *DB) MarkRepositoryStaleNotified(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE repositories SET stale_notified_at = NOW() WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark repository stale notified: %w", err)
+	}
+	return nil
+}
+
+// SetRepositoryDescription sets a repository's description and the hash of
+// the README it was generated from
+func (db *DB) SetRepositoryDescription(ctx context.Context, id int64, description, readmeHash sql.NullString) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE repositories
+		SET description = $1, readme_hash = $2, updated_at = NOW()
+		WHERE id = $3
+	`, description, readmeHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to set repository description: %w", err)
+	}
+	return nil
+}
+
+// SetRepositoryContextNotes sets the notes added to a repository's analyzer prompts
+func (db *DB) SetRepositoryContextNotes(ctx context.Context, id int64, notes sql.NullString) error {
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE repositories
+		SET context_notes = $1, updated_at = NOW()
+		WHERE id = $2 AND ($3 = 0 OR workspace_id = $3)
+	`, notes, id, WorkspaceFromContext(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to delete repository: %w", err)
+		return fmt.Errorf("failed to set repository context notes: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("repository not found")
 	}
 	return nil
 }
 
 // SetRepositoryActive sets the active status of a repository
-func (db *DB) SetRepositoryActive(id int64, active bool) error {
-	_, err := db.Exec(`
+func (db *DB) SetRepositoryActive(ctx context.Context, id int64, active bool) error {
+	_, err := db.q.ExecContext(ctx, `
 		UPDATE repositories
 		SET active = $1, updated_at = NOW()
 		WHERE id = $2
@@ -139,33 +195,25 @@ func (db *DB) SetRepositoryActive(id int64, active bool) error {
 // ActivityRun CRUD operations
 
 // CreateActivityRun inserts a new activity run into the database
-func (db *DB) CreateActivityRun(repoID int64, startSHA, endSHA string) (*ActivityRun, error) {
-	var id int64
-	err := db.QueryRow(`
+func (db *DB) CreateActivityRun(ctx context.Context, repoID int64, startSHA, endSHA string) (*ActivityRun, error) {
+	run, err := queryRow[ActivityRun](ctx, db.q, `
 		INSERT INTO activity_runs (repo_id, start_sha, end_sha)
 		VALUES ($1, $2, $3)
-		RETURNING id
-	`, repoID, startSHA, endSHA).Scan(&id)
+		RETURNING `+activityRunColumns,
+		repoID, startSHA, endSHA)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create activity run: %w", err)
 	}
-
-	return db.GetActivityRun(id)
+	return run, nil
 }
 
 // GetActivityRun retrieves an activity run by ID
-func (db *DB) GetActivityRun(id int64) (*ActivityRun, error) {
-	run := &ActivityRun{}
-	err := db.QueryRow(`
-		SELECT id, repo_id, start_sha, end_sha, started_at, completed_at, summary, raw_data,
-		       COALESCE(agent_mode, false), tool_usage_stats
+func (db *DB) GetActivityRun(ctx context.Context, id int64) (*ActivityRun, error) {
+	run, err := queryRow[ActivityRun](ctx, db.q, `
+		SELECT `+activityRunColumns+`
 		FROM activity_runs
 		WHERE id = $1
-	`, id).Scan(
-		&run.ID, &run.RepoID, &run.StartSHA, &run.EndSHA,
-		&run.StartedAt, &run.CompletedAt, &run.Summary, &run.RawData,
-		&run.AgentMode, &run.ToolUsageStats,
-	)
+	`, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("activity run not found")
@@ -176,20 +224,14 @@ func (db *DB) GetActivityRun(id int64) (*ActivityRun, error) {
 }
 
 // GetLatestActivityRun retrieves the most recent activity run for a repository
-func (db *DB) GetLatestActivityRun(repoID int64) (*ActivityRun, error) {
-	run := &ActivityRun{}
-	err := db.QueryRow(`
-		SELECT id, repo_id, start_sha, end_sha, started_at, completed_at, summary, raw_data,
-		       COALESCE(agent_mode, false), tool_usage_stats
+func (db *DB) GetLatestActivityRun(ctx context.Context, repoID int64) (*ActivityRun, error) {
+	run, err := queryRow[ActivityRun](ctx, db.q, `
+		SELECT `+activityRunColumns+`
 		FROM activity_runs
 		WHERE repo_id = $1
 		ORDER BY started_at DESC
 		LIMIT 1
-	`, repoID).Scan(
-		&run.ID, &run.RepoID, &run.StartSHA, &run.EndSHA,
-		&run.StartedAt, &run.CompletedAt, &run.Summary, &run.RawData,
-		&run.AgentMode, &run.ToolUsageStats,
-	)
+	`, repoID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No runs yet
@@ -200,43 +242,67 @@ func (db *DB) GetLatestActivityRun(repoID int64) (*ActivityRun, error) {
 }
 
 // UpdateActivityRun updates an activity run
-func (db *DB) UpdateActivityRun(run *ActivityRun) error {
-	_, err := db.Exec(`
+func (db *DB) UpdateActivityRun(ctx context.Context, run *ActivityRun) error {
+	_, err := db.q.ExecContext(ctx, `
 		UPDATE activity_runs
-		SET completed_at = $1, summary = $2, raw_data = $3, agent_mode = $4, tool_usage_stats = $5
-		WHERE id = $6
-	`, run.CompletedAt, run.Summary, run.RawData, run.AgentMode, run.ToolUsageStats, run.ID)
+		SET completed_at = $1, summary = $2, raw_data = $3, agent_mode = $4, tool_usage_stats = $5, raw_data_ref = $6
+		WHERE id = $7
+	`, run.CompletedAt, run.Summary, run.RawData, run.AgentMode, run.ToolUsageStats, run.RawDataRef, run.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update activity run: %w", err)
 	}
 	return nil
 }
 
+// DeleteActivityRun deletes an activity run. Reports generated from it keep
+// their summaries but lose the link to it.
+func (db *DB) DeleteActivityRun(ctx context.Context, id int64) error {
+	if _, err := db.q.ExecContext(ctx, `DELETE FROM activity_runs WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete activity run: %w", err)
+	}
+	return nil
+}
+
+// ListRawDataBlobs lists the offloaded raw data of activity runs started
+// before the given time, or of all runs if it is zero, optionally limited to
+// one repository (repoID 0 lists all)
+func (db *DB) ListRawDataBlobs(ctx context.Context, before time.Time, repoID int64) ([]*RawDataBlob, error) {
+	blobs, err := queryRows[RawDataBlob](ctx, db.q, `
+		SELECT `+rawDataBlobColumns+`
+		FROM activity_runs
+		WHERE raw_data_ref IS NOT NULL
+		  AND ($1::timestamptz IS NULL OR started_at < $1)
+		  AND ($2 = 0 OR repo_id = $2)
+		ORDER BY id
+	`, sql.NullTime{Time: before, Valid: !before.IsZero()}, repoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raw data blobs: %w", err)
+	}
+	return blobs, nil
+}
+
 // Subscriber CRUD operations
 
-// CreateSubscriber inserts a new subscriber into the database
-func (db *DB) CreateSubscriber(email string, subscribeAll bool) (*Subscriber, error) {
-	var id int64
-	err := db.QueryRow(`
-		INSERT INTO subscribers (email, subscribe_all)
-		VALUES ($1, $2)
-		RETURNING id
-	`, email, subscribeAll).Scan(&id)
+// CreateSubscriber inserts a new subscriber into the context's workspace
+func (db *DB) CreateSubscriber(ctx context.Context, email string, subscribeAll bool) (*Subscriber, error) {
+	sub, err := queryRow[Subscriber](ctx, db.q, `
+		INSERT INTO subscribers (email, subscribe_all, workspace_id)
+		VALUES ($1, $2, $3)
+		RETURNING `+subscriberColumns,
+		email, subscribeAll, writeWorkspace(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create subscriber: %w", err)
 	}
-
-	return db.GetSubscriber(id)
+	return sub, nil
 }
 
 // GetSubscriber retrieves a subscriber by ID
-func (db *DB) GetSubscriber(id int64) (*Subscriber, error) {
-	sub := &Subscriber{}
-	err := db.QueryRow(`
-		SELECT id, email, subscribe_all, created_at
+func (db *DB) GetSubscriber(ctx context.Context, id int64) (*Subscriber, error) {
+	sub, err := queryRow[Subscriber](ctx, db.q, `
+		SELECT `+subscriberColumns+`
 		FROM subscribers
-		WHERE id = $1
-	`, id).Scan(&sub.ID, &sub.Email, &sub.SubscribeAll, &sub.CreatedAt)
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, id, WorkspaceFromContext(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("subscriber not found")
@@ -247,13 +313,12 @@ func (db *DB) GetSubscriber(id int64) (*Subscriber, error) {
 }
 
 // GetSubscriberByEmail retrieves a subscriber by email
-func (db *DB) GetSubscriberByEmail(email string) (*Subscriber, error) {
-	sub := &Subscriber{}
-	err := db.QueryRow(`
-		SELECT id, email, subscribe_all, created_at
+func (db *DB) GetSubscriberByEmail(ctx context.Context, email string) (*Subscriber, error) {
+	sub, err := queryRow[Subscriber](ctx, db.q, `
+		SELECT `+subscriberColumns+`
 		FROM subscribers
-		WHERE email = $1
-	`, email).Scan(&sub.ID, &sub.Email, &sub.SubscribeAll, &sub.CreatedAt)
+		WHERE email = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, email, WorkspaceFromContext(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("subscriber not found")
@@ -264,36 +329,26 @@ func (db *DB) GetSubscriberByEmail(email string) (*Subscriber, error) {
 }
 
 // ListSubscribers retrieves all subscribers
-func (db *DB) ListSubscribers() ([]*Subscriber, error) {
-	rows, err := db.Query(`
-		SELECT id, email, subscribe_all, created_at
+func (db *DB) ListSubscribers(ctx context.Context) ([]*Subscriber, error) {
+	subs, err := queryRows[Subscriber](ctx, db.q, `
+		SELECT `+subscriberColumns+`
 		FROM subscribers
+		WHERE ($1 = 0 OR workspace_id = $1)
 		ORDER BY email
-	`)
+	`, WorkspaceFromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subscribers: %w", err)
 	}
-	defer rows.Close()
-
-	var subs []*Subscriber
-	for rows.Next() {
-		sub := &Subscriber{}
-		if err := rows.Scan(&sub.ID, &sub.Email, &sub.SubscribeAll, &sub.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
-		}
-		subs = append(subs, sub)
-	}
-
 	return subs, nil
 }
 
 // UpdateSubscriber updates a subscriber's fields
-func (db *DB) UpdateSubscriber(sub *Subscriber) error {
-	_, err := db.Exec(`
+func (db *DB) UpdateSubscriber(ctx context.Context, sub *Subscriber) error {
+	_, err := db.q.ExecContext(ctx, `
 		UPDATE subscribers
-		SET email = $1, subscribe_all = $2
-		WHERE id = $3
-	`, sub.Email, sub.SubscribeAll, sub.ID)
+		SET email = $1, subscribe_all = $2, timezone = $3, send_hour = $4
+		WHERE id = $5
+	`, sub.Email, sub.SubscribeAll, sub.Timezone, sub.SendHour, sub.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update subscriber: %w", err)
 	}
@@ -301,8 +356,8 @@ func (db *DB) UpdateSubscriber(sub *Subscriber) error {
 }
 
 // DeleteSubscriber deletes a subscriber by ID
-func (db *DB) DeleteSubscriber(id int64) error {
-	_, err := db.Exec("DELETE FROM subscribers WHERE id = $1", id)
+func (db *DB) DeleteSubscriber(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM subscribers WHERE id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscriber: %w", err)
 	}
@@ -312,28 +367,25 @@ func (db *DB) DeleteSubscriber(id int64) error {
 // Subscription CRUD operations
 
 // CreateSubscription creates a subscription between a subscriber and a repository
-func (db *DB) CreateSubscription(subscriberID, repoID int64) (*Subscription, error) {
-	var id int64
-	err := db.QueryRow(`
+func (db *DB) CreateSubscription(ctx context.Context, subscriberID, repoID int64) (*Subscription, error) {
+	sub, err := queryRow[Subscription](ctx, db.q, `
 		INSERT INTO subscriptions (subscriber_id, repo_id)
 		VALUES ($1, $2)
-		RETURNING id
-	`, subscriberID, repoID).Scan(&id)
+		RETURNING `+subscriptionColumns,
+		subscriberID, repoID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create subscription: %w", err)
 	}
-
-	return db.GetSubscription(id)
+	return sub, nil
 }
 
 // GetSubscription retrieves a subscription by ID
-func (db *DB) GetSubscription(id int64) (*Subscription, error) {
-	sub := &Subscription{}
-	err := db.QueryRow(`
-		SELECT id, subscriber_id, repo_id, created_at
+func (db *DB) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	sub, err := queryRow[Subscription](ctx, db.q, `
+		SELECT `+subscriptionColumns+`
 		FROM subscriptions
 		WHERE id = $1
-	`, id).Scan(&sub.ID, &sub.SubscriberID, &sub.RepoID, &sub.CreatedAt)
+	`, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("subscription not found")
@@ -344,13 +396,12 @@ func (db *DB) GetSubscription(id int64) (*Subscription, error) {
 }
 
 // GetSubscriptionBySubscriberAndRepo retrieves a subscription by subscriber and repo
-func (db *DB) GetSubscriptionBySubscriberAndRepo(subscriberID, repoID int64) (*Subscription, error) {
-	sub := &Subscription{}
-	err := db.QueryRow(`
-		SELECT id, subscriber_id, repo_id, created_at
+func (db *DB) GetSubscriptionBySubscriberAndRepo(ctx context.Context, subscriberID, repoID int64) (*Subscription, error) {
+	sub, err := queryRow[Subscription](ctx, db.q, `
+		SELECT `+subscriptionColumns+`
 		FROM subscriptions
 		WHERE subscriber_id = $1 AND repo_id = $2
-	`, subscriberID, repoID).Scan(&sub.ID, &sub.SubscriberID, &sub.RepoID, &sub.CreatedAt)
+	`, subscriberID, repoID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("subscription not found")
@@ -361,9 +412,9 @@ func (db *DB) GetSubscriptionBySubscriberAndRepo(subscriberID, repoID int64) (*S
 }
 
 // ListSubscriptionsBySubscriber retrieves all subscriptions for a subscriber
-func (db *DB) ListSubscriptionsBySubscriber(subscriberID int64) ([]*Subscription, error) {
-	rows, err := db.Query(`
-		SELECT id, subscriber_id, repo_id, created_at
+func (db *DB) ListSubscriptionsBySubscriber(ctx context.Context, subscriberID int64) ([]*Subscription, error) {
+	subs, err := queryRows[Subscription](ctx, db.q, `
+		SELECT `+subscriptionColumns+`
 		FROM subscriptions
 		WHERE subscriber_id = $1
 		ORDER BY created_at
@@ -371,23 +422,12 @@ func (db *DB) ListSubscriptionsBySubscriber(subscriberID int64) ([]*Subscription
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
 	}
-	defer rows.Close()
-
-	var subs []*Subscription
-	for rows.Next() {
-		sub := &Subscription{}
-		if err := rows.Scan(&sub.ID, &sub.SubscriberID, &sub.RepoID, &sub.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan subscription: %w", err)
-		}
-		subs = append(subs, sub)
-	}
-
 	return subs, nil
 }
 
 // DeleteSubscription deletes a subscription by ID
-func (db *DB) DeleteSubscription(id int64) error {
-	_, err := db.Exec("DELETE FROM subscriptions WHERE id = $1", id)
+func (db *DB) DeleteSubscription(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM subscriptions WHERE id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
@@ -395,8 +435,8 @@ func (db *DB) DeleteSubscription(id int64) error {
 }
 
 // DeleteSubscriptionBySubscriberAndRepo deletes a subscription by subscriber and repo
-func (db *DB) DeleteSubscriptionBySubscriberAndRepo(subscriberID, repoID int64) error {
-	_, err := db.Exec("DELETE FROM subscriptions WHERE subscriber_id = $1 AND repo_id = $2", subscriberID, repoID)
+func (db *DB) DeleteSubscriptionBySubscriberAndRepo(ctx context.Context, subscriberID, repoID int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM subscriptions WHERE subscriber_id = $1 AND repo_id = $2", subscriberID, repoID)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
@@ -405,34 +445,32 @@ func (db *DB) DeleteSubscriptionBySubscriberAndRepo(subscriberID, repoID int64)
 
 // NewsletterSend CRUD operations
 
-// CreateNewsletterSend records that an activity run was sent to a subscriber
-func (db *DB) CreateNewsletterSend(subscriberID, activityRunID int64, messageID string) (*NewsletterSend, error) {
+// CreateNewsletterSend records that the weekly report for a repository and
+// ISO week was sent to a subscriber
+func (db *DB) CreateNewsletterSend(ctx context.Context, subscriberID, repoID int64, year, week int, messageID string) (*NewsletterSend, error) {
 	var msgID interface{}
 	if messageID != "" {
 		msgID = messageID
 	}
 
-	var id int64
-	err := db.QueryRow(`
-		INSERT INTO newsletter_sends (subscriber_id, activity_run_id, sendgrid_message_id)
-		VALUES ($1, $2, $3)
-		RETURNING id
-	`, subscriberID, activityRunID, msgID).Scan(&id)
+	ns, err := queryRow[NewsletterSend](ctx, db.q, `
+		INSERT INTO newsletter_sends (subscriber_id, repo_id, year, week, sendgrid_message_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+newsletterSendColumns,
+		subscriberID, repoID, year, week, msgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create newsletter send: %w", err)
 	}
-
-	return db.GetNewsletterSend(id)
+	return ns, nil
 }
 
 // GetNewsletterSend retrieves a newsletter send by ID
-func (db *DB) GetNewsletterSend(id int64) (*NewsletterSend, error) {
-	ns := &NewsletterSend{}
-	err := db.QueryRow(`
-		SELECT id, subscriber_id, activity_run_id, sent_at, sendgrid_message_id
+func (db *DB) GetNewsletterSend(ctx context.Context, id int64) (*NewsletterSend, error) {
+	ns, err := queryRow[NewsletterSend](ctx, db.q, `
+		SELECT `+newsletterSendColumns+`
 		FROM newsletter_sends
 		WHERE id = $1
-	`, id).Scan(&ns.ID, &ns.SubscriberID, &ns.ActivityRunID, &ns.SentAt, &ns.SendGridMessageID)
+	`, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("newsletter send not found")
@@ -442,159 +480,127 @@ func (db *DB) GetNewsletterSend(id int64) (*NewsletterSend, error) {
 	return ns, nil
 }
 
-// HasNewsletterBeenSent checks if a specific activity run has been sent to a subscriber
-func (db *DB) HasNewsletterBeenSent(subscriberID, activityRunID int64) (bool, error) {
-	var count int
-	err := db.QueryRow(`
+// HasNewsletterBeenSent checks if the weekly report for a repository and ISO
+// week has been sent to a subscriber
+func (db *DB) HasNewsletterBeenSent(ctx context.Context, subscriberID, repoID int64, year, week int) (bool, error) {
+	count, err := queryValue[int](ctx, db.q, `
 		SELECT COUNT(*) FROM newsletter_sends
-		WHERE subscriber_id = $1 AND activity_run_id = $2
-	`, subscriberID, activityRunID).Scan(&count)
+		WHERE subscriber_id = $1 AND repo_id = $2 AND year = $3 AND week = $4
+	`, subscriberID, repoID, year, week)
 	if err != nil {
 		return false, fmt.Errorf("failed to check newsletter send: %w", err)
 	}
 	return count > 0, nil
 }
 
-// GetUnsentActivityRuns retrieves activity runs that haven't been sent to a subscriber
-// for the repositories they're subscribed to (or all repos if subscribe_all is true)
-func (db *DB) GetUnsentActivityRuns(subscriberID int64, since time.Time) ([]*ActivityRun, error) {
+// ListReportRecipients returns the subscribers who were sent the weekly
+// report for a repository and ISO week, leaving out suppressed ones
+func (db *DB) ListReportRecipients(ctx context.Context, repoID int64, year, week int) ([]*Subscriber, error) {
+	subscribers, err := queryRows[Subscriber](ctx, db.q, `
+		SELECT `+subscriberColumns+`
+		FROM subscribers
+		WHERE suppressed_at IS NULL
+		  AND id IN (
+		      SELECT subscriber_id FROM newsletter_sends
+		      WHERE repo_id = $1 AND year = $2 AND week = $3
+		  )
+		ORDER BY email
+	`, repoID, year, week)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report recipients: %w", err)
+	}
+	return subscribers, nil
+}
+
+// GetUnsentWeeklyReports retrieves weekly reports that haven't been sent to a
+// subscriber for the repositories they're subscribed to (or all repos of
+// their workspace if subscribe_all is true). Only reports with a summary for weeks that ended
+// on or after since and before the calendar date of before (in its location)
+// are returned, so a week that is still being appended to is not sent early.
+// Drafts are left out until approved. Reports are ordered oldest week first.
+func (db *DB) GetUnsentWeeklyReports(ctx context.Context, subscriberID int64, since, before time.Time) ([]*WeeklyReport, error) {
 	// Get the subscriber to check subscribe_all flag
-	sub, err := db.GetSubscriber(subscriberID)
+	sub, err := db.GetSubscriber(ctx, subscriberID)
 	if err != nil {
 		return nil, err
 	}
 
-	var query string
-	var args []interface{}
-
-	if sub.SubscribeAll {
-		// Get all completed activity runs since the given time that haven't been sent
-		query = `
-			SELECT ar.id, ar.repo_id, ar.start_sha, ar.end_sha, ar.started_at, ar.completed_at,
-			       ar.summary, ar.raw_data, COALESCE(ar.agent_mode, false), ar.tool_usage_stats
-			FROM activity_runs ar
-			WHERE ar.completed_at IS NOT NULL
-			  AND ar.completed_at >= $1
-			  AND ar.id NOT IN (
-			      SELECT activity_run_id FROM newsletter_sends WHERE subscriber_id = $2
-			  )
-			ORDER BY ar.completed_at DESC
-		`
-		args = []interface{}{since, subscriberID}
-	} else {
-		// Get activity runs for subscribed repos only
-		query = `
-			SELECT ar.id, ar.repo_id, ar.start_sha, ar.end_sha, ar.started_at, ar.completed_at,
-			       ar.summary, ar.raw_data, COALESCE(ar.agent_mode, false), ar.tool_usage_stats
-			FROM activity_runs ar
-			INNER JOIN subscriptions s ON ar.repo_id = s.repo_id
-			WHERE s.subscriber_id = $1
-			  AND ar.completed_at IS NOT NULL
-			  AND ar.completed_at >= $2
-			  AND ar.id NOT IN (
-			      SELECT activity_run_id FROM newsletter_sends WHERE subscriber_id = $3
-			  )
-			ORDER BY ar.completed_at DESC
-		`
-		args = []interface{}{subscriberID, since, subscriberID}
-	}
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get unsent activity runs: %w", err)
-	}
-	defer rows.Close()
-
-	var runs []*ActivityRun
-	for rows.Next() {
-		run := &ActivityRun{}
-		if err := rows.Scan(
-			&run.ID, &run.RepoID, &run.StartSHA, &run.EndSHA,
-			&run.StartedAt, &run.CompletedAt, &run.Summary, &run.RawData,
-			&run.AgentMode, &run.ToolUsageStats,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan activity run: %w", err)
-		}
-		runs = append(runs, run)
-	}
-
-	return runs, nil
+	reports, err := queryRows[WeeklyReport](ctx, db.q, `
+		SELECT `+weeklyReportColumns+`
+		FROM weekly_reports wr
+		WHERE summary IS NOT NULL
+		  AND review_state = 'approved'
+		  AND week_end >= $1::date
+		  AND week_end < $4::date
+		  AND repo_id IN (
+		      SELECT id FROM repositories WHERE $2 AND workspace_id = $5
+		      UNION SELECT repo_id FROM subscriptions WHERE subscriber_id = $3
+		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM newsletter_sends ns
+		      WHERE ns.subscriber_id = $3 AND ns.repo_id = wr.repo_id
+		        AND ns.year = wr.year AND ns.week = wr.week
+		  )
+		ORDER BY week_start, repo_id
+	`, since, sub.SubscribeAll, subscriberID, before.Format("2006-01-02"), sub.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsent weekly reports: %w", err)
+	}
+	return reports, nil
 }
 
 // GetReposForSubscriber returns the repositories a subscriber should receive updates for
-func (db *DB) GetReposForSubscriber(subscriberID int64) ([]*Repository, error) {
-	sub, err := db.GetSubscriber(subscriberID)
+func (db *DB) GetReposForSubscriber(ctx context.Context, subscriberID int64) ([]*Repository, error) {
+	sub, err := db.GetSubscriber(ctx, subscriberID)
 	if err != nil {
 		return nil, err
 	}
 
 	if sub.SubscribeAll {
-		// Return all active repos
+		// Return all active repos of the subscriber's workspace
 		activeOnly := true
-		return db.ListRepositories(&activeOnly)
+		return db.ListRepositories(WithWorkspace(ctx, sub.WorkspaceID), &activeOnly)
 	}
 
 	// Return only subscribed repos
-	rows, err := db.Query(`
-		SELECT r.id, r.name, r.url, r.branch, r.active, COALESCE(r.private, false), r.description, r.created_at, r.updated_at, r.last_run_at, r.last_run_sha
-		FROM repositories r
-		INNER JOIN subscriptions s ON r.id = s.repo_id
-		WHERE s.subscriber_id = $1
-		ORDER BY r.name
+	repos, err := queryRows[Repository](ctx, db.q, `
+		SELECT `+repositoryColumns+`
+		FROM repositories
+		WHERE id IN (SELECT repo_id FROM subscriptions WHERE subscriber_id = $1)
+		ORDER BY name
 	`, subscriberID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repos for subscriber: %w", err)
 	}
-	defer rows.Close()
-
-	var repos []*Repository
-	for rows.Next() {
-		repo := &Repository{}
-		if err := rows.Scan(
-			&repo.ID, &repo.Name, &repo.URL, &repo.Branch,
-			&repo.Active, &repo.Private, &repo.Description, &repo.CreatedAt, &repo.UpdatedAt, &repo.LastRunAt, &repo.LastRunSHA,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan repository: %w", err)
-		}
-		repos = append(repos, repo)
-	}
-
 	return repos, nil
 }
 
 // WeeklyReport CRUD operations
 
-// CreateWeeklyReport inserts a new weekly report into the database
-func (db *DB) CreateWeeklyReport(report *WeeklyReport) (*WeeklyReport, error) {
-	var id int64
-	err := db.QueryRow(`
-		INSERT INTO weekly_reports (repo_id, year, week, week_start, week_end, summary, commit_count, metadata, agent_mode, tool_usage_stats, source_run_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id
-	`, report.RepoID, report.Year, report.Week, report.WeekStart, report.WeekEnd,
+// CreateWeeklyReport inserts a new weekly report into the database. Reports
+// without a review state are approved.
+func (db *DB) CreateWeeklyReport(ctx context.Context, report *WeeklyReport) (*WeeklyReport, error) {
+	created, err := queryRow[WeeklyReport](ctx, db.q, `
+		INSERT INTO weekly_reports (repo_id, year, week, week_start, week_end, summary, commit_count, metadata, agent_mode, tool_usage_stats, source_run_id,
+		                            review_state, approved_by, approved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE(NULLIF($12, ''), 'approved'), $13, $14)
+		RETURNING `+weeklyReportColumns,
+		report.RepoID, report.Year, report.Week, report.WeekStart, report.WeekEnd,
 		report.Summary, report.CommitCount, report.Metadata, report.AgentMode,
-		report.ToolUsageStats, report.SourceRunID).Scan(&id)
+		report.ToolUsageStats, report.SourceRunID, report.ReviewState, report.ApprovedBy, report.ApprovedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create weekly report: %w", err)
 	}
-
-	return db.GetWeeklyReport(id)
+	return created, nil
 }
 
 // GetWeeklyReport retrieves a weekly report by ID
-func (db *DB) GetWeeklyReport(id int64) (*WeeklyReport, error) {
-	report := &WeeklyReport{}
-	err := db.QueryRow(`
-		SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-		       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
+func (db *DB) GetWeeklyReport(ctx context.Context, id int64) (*WeeklyReport, error) {
+	report, err := queryRow[WeeklyReport](ctx, db.q, `
+		SELECT `+weeklyReportColumns+`
 		FROM weekly_reports
-		WHERE id = $1
-	`, id).Scan(
-		&report.ID, &report.RepoID, &report.Year, &report.Week,
-		&report.WeekStart, &report.WeekEnd, &report.Summary, &report.CommitCount,
-		&report.Metadata, &report.AgentMode, &report.ToolUsageStats,
-		&report.CreatedAt, &report.UpdatedAt, &report.SourceRunID,
-	)
+		WHERE id = $1 AND repo_id IN (SELECT id FROM repositories WHERE ($2 = 0 OR workspace_id = $2) AND visibility = ANY($3))
+	`, id, WorkspaceFromContext(ctx), visibleLevels(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("weekly report not found")
@@ -605,19 +611,12 @@ func (db *DB) GetWeeklyReport(id int64) (*WeeklyReport, error) {
 }
 
 // GetWeeklyReportByRepoAndWeek retrieves a weekly report by repository, year, and week
-func (db *DB) GetWeeklyReportByRepoAndWeek(repoID int64, year, week int) (*WeeklyReport, error) {
-	report := &WeeklyReport{}
-	err := db.QueryRow(`
-		SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-		       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
+func (db *DB) GetWeeklyReportByRepoAndWeek(ctx context.Context, repoID int64, year, week int) (*WeeklyReport, error) {
+	report, err := queryRow[WeeklyReport](ctx, db.q, `
+		SELECT `+weeklyReportColumns+`
 		FROM weekly_reports
 		WHERE repo_id = $1 AND year = $2 AND week = $3
-	`, repoID, year, week).Scan(
-		&report.ID, &report.RepoID, &report.Year, &report.Week,
-		&report.WeekStart, &report.WeekEnd, &report.Summary, &report.CommitCount,
-		&report.Metadata, &report.AgentMode, &report.ToolUsageStats,
-		&report.CreatedAt, &report.UpdatedAt, &report.SourceRunID,
-	)
+	`, repoID, year, week)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found, return nil without error
@@ -628,21 +627,14 @@ func (db *DB) GetWeeklyReportByRepoAndWeek(repoID int64, year, week int) (*Weekl
 }
 
 // GetLatestWeeklyReport retrieves the most recent weekly report for a repository
-func (db *DB) GetLatestWeeklyReport(repoID int64) (*WeeklyReport, error) {
-	report := &WeeklyReport{}
-	err := db.QueryRow(`
-		SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-		       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
+func (db *DB) GetLatestWeeklyReport(ctx context.Context, repoID int64) (*WeeklyReport, error) {
+	report, err := queryRow[WeeklyReport](ctx, db.q, `
+		SELECT `+weeklyReportColumns+`
 		FROM weekly_reports
 		WHERE repo_id = $1
 		ORDER BY year DESC, week DESC
 		LIMIT 1
-	`, repoID).Scan(
-		&report.ID, &report.RepoID, &report.Year, &report.Week,
-		&report.WeekStart, &report.WeekEnd, &report.Summary, &report.CommitCount,
-		&report.Metadata, &report.AgentMode, &report.ToolUsageStats,
-		&report.CreatedAt, &report.UpdatedAt, &report.SourceRunID,
-	)
+	`, repoID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No reports yet
@@ -653,122 +645,251 @@ func (db *DB) GetLatestWeeklyReport(repoID int64) (*WeeklyReport, error) {
 }
 
 // ListWeeklyReportsByRepo retrieves all weekly reports for a repository, optionally filtered by year
-func (db *DB) ListWeeklyReportsByRepo(repoID int64, year *int) ([]*WeeklyReport, error) {
-	var query string
-	var args []interface{}
+func (db *DB) ListWeeklyReportsByRepo(ctx context.Context, repoID int64, year *int) ([]*WeeklyReport, error) {
+	query := `SELECT ` + weeklyReportColumns + ` FROM weekly_reports WHERE repo_id = $1`
+	args := []interface{}{repoID}
 
 	if year != nil {
-		query = `
-			SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-			       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
-			FROM weekly_reports
-			WHERE repo_id = $1 AND year = $2
-			ORDER BY year DESC, week DESC
-		`
-		args = []interface{}{repoID, *year}
-	} else {
-		query = `
-			SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-			       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
-			FROM weekly_reports
-			WHERE repo_id = $1
-			ORDER BY year DESC, week DESC
-		`
-		args = []interface{}{repoID}
-	}
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list weekly reports: %w", err)
+		query += " AND year = $2"
+		args = append(args, *year)
 	}
-	defer rows.Close()
 
-	var reports []*WeeklyReport
-	for rows.Next() {
-		report := &WeeklyReport{}
-		if err := rows.Scan(
-			&report.ID, &report.RepoID, &report.Year, &report.Week,
-			&report.WeekStart, &report.WeekEnd, &report.Summary, &report.CommitCount,
-			&report.Metadata, &report.AgentMode, &report.ToolUsageStats,
-			&report.CreatedAt, &report.UpdatedAt, &report.SourceRunID,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan weekly report: %w", err)
-		}
-		reports = append(reports, report)
-	}
+	query += " ORDER BY year DESC, week DESC"
 
+	reports, err := queryRows[WeeklyReport](ctx, db.q, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list weekly reports: %w", err)
+	}
 	return reports, nil
 }
 
 // ListAllWeeklyReports retrieves all weekly reports, optionally filtered by year
-func (db *DB) ListAllWeeklyReports(year *int) ([]*WeeklyReport, error) {
-	var query string
-	var args []interface{}
+func (db *DB) ListAllWeeklyReports(ctx context.Context, year *int) ([]*WeeklyReport, error) {
+	query := `SELECT ` + weeklyReportColumns + ` FROM weekly_reports
+		WHERE repo_id IN (SELECT id FROM repositories WHERE ($1 = 0 OR workspace_id = $1) AND visibility = ANY($2))`
+	args := []interface{}{WorkspaceFromContext(ctx), visibleLevels(ctx)}
 
 	if year != nil {
-		query = `
-			SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-			       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
-			FROM weekly_reports
-			WHERE year = $1
-			ORDER BY year DESC, week DESC, repo_id
-		`
-		args = []interface{}{*year}
-	} else {
-		query = `
-			SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count,
-			       metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id
-			FROM weekly_reports
-			ORDER BY year DESC, week DESC, repo_id
-		`
-	}
-
-	rows, err := db.Query(query, args...)
+		query += " AND year = $3"
+		args = append(args, *year)
+	}
+
+	query += " ORDER BY year DESC, week DESC, repo_id"
+
+	reports, err := queryRows[WeeklyReport](ctx, db.q, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list weekly reports: %w", err)
 	}
-	defer rows.Close()
+	return reports, nil
+}
 
-	var reports []*WeeklyReport
-	for rows.Next() {
-		report := &WeeklyReport{}
-		if err := rows.Scan(
-			&report.ID, &report.RepoID, &report.Year, &report.Week,
-			&report.WeekStart, &report.WeekEnd, &report.Summary, &report.CommitCount,
-			&report.Metadata, &report.AgentMode, &report.ToolUsageStats,
-			&report.CreatedAt, &report.UpdatedAt, &report.SourceRunID,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan weekly report: %w", err)
-		}
-		reports = append(reports, report)
+// ReportFilter selects weekly reports for ListWeeklyReports and
+// CountWeeklyReports. Zero fields do not filter.
+type ReportFilter struct {
+	RepoID      int64
+	Year        int
+	MinCommits  int
+	MaxCommits  int
+	ReviewState string
+
+	// Weeks from and to, inclusive, as year*100 + week (202602 for 2026-W02)
+	FromWeek int
+	ToWeek   int
+
+	// Reports crediting any of these authors, as recorded in the report
+	// metadata (case-insensitive)
+	Authors []string
+
+	// Reports whose summary contains this text (case-insensitive)
+	Text string
+
+	Limit  int
+	Offset int
+}
+
+// reportFilterWhere is the WHERE clause of a ReportFilter, with its values
+// as parameters $1 to $11 (see reportFilterArgs)
+const reportFilterWhere = `
+		WHERE repo_id IN (SELECT id FROM repositories WHERE ($1 = 0 OR workspace_id = $1) AND visibility = ANY($10))
+			AND ($2 = 0 OR repo_id = $2)
+			AND ($3 = 0 OR year = $3)
+			AND commit_count >= $4
+			AND ($5 = '' OR review_state = $5)
+			AND ($6 = 0 OR year * 100 + week >= $6)
+			AND ($7 = 0 OR year * 100 + week <= $7)
+			AND ($8 = 0 OR commit_count <= $8)
+			AND (cardinality($9::text[]) = 0 OR EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(COALESCE(NULLIF(metadata, '')::jsonb -> 'authors', '[]')) a
+				WHERE LOWER(a) = ANY($9)))
+			AND ($11 = '' OR summary ILIKE '%' || $11 || '%')`
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// reportFilterArgs returns the parameters of reportFilterWhere
+func reportFilterArgs(ctx context.Context, filter ReportFilter) []any {
+	authors := make([]string, 0, len(filter.Authors))
+	for _, a := range filter.Authors {
+		authors = append(authors, strings.ToLower(a))
 	}
+	return []any{WorkspaceFromContext(ctx), filter.RepoID, filter.Year, filter.MinCommits, filter.ReviewState,
+		filter.FromWeek, filter.ToWeek, filter.MaxCommits, pq.Array(authors), visibleLevels(ctx), likeEscaper.Replace(filter.Text)}
+}
 
+// ListWeeklyReports returns the weekly reports of the context's workspace
+// (all workspaces if unscoped) selected by filter, newest week first, one
+// page at a time
+func (db *DB) ListWeeklyReports(ctx context.Context, filter ReportFilter) ([]*WeeklyReport, error) {
+	var limit any
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	reports, err := queryRows[WeeklyReport](ctx, db.q, `
+		SELECT `+weeklyReportColumns+`
+		FROM weekly_reports`+reportFilterWhere+`
+		ORDER BY year DESC, week DESC, repo_id
+		LIMIT $12 OFFSET $13
+	`, append(reportFilterArgs(ctx, filter), limit, filter.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list weekly reports: %w", err)
+	}
 	return reports, nil
 }
 
+// CountWeeklyReports returns how many weekly reports filter selects,
+// ignoring its limit and offset
+func (db *DB) CountWeeklyReports(ctx context.Context, filter ReportFilter) (int, error) {
+	count, err := queryValue[int](ctx, db.q, `SELECT COUNT(*) FROM weekly_reports`+reportFilterWhere, reportFilterArgs(ctx, filter)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count weekly reports: %w", err)
+	}
+	return count, nil
+}
+
+// ListWeeklyReportYears returns the years with weekly reports of a
+// repository, or of the context's workspace if repoID is 0, newest first
+func (db *DB) ListWeeklyReportYears(ctx context.Context, repoID int64) ([]int, error) {
+	years, err := queryValues[int](ctx, db.q, `
+		SELECT DISTINCT year FROM weekly_reports`+reportFilterWhere+`
+		ORDER BY year DESC
+	`, reportFilterArgs(ctx, ReportFilter{RepoID: repoID})...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report years: %w", err)
+	}
+	return years, nil
+}
+
 // UpdateWeeklyReport updates an existing weekly report
-func (db *DB) UpdateWeeklyReport(report *WeeklyReport) error {
+func (db *DB) UpdateWeeklyReport(ctx context.Context, report *WeeklyReport) error {
 	report.UpdatedAt = time.Now()
-	_, err := db.Exec(`
+	_, err := db.q.ExecContext(ctx, `
 		UPDATE weekly_reports
 		SET summary = $1, commit_count = $2, metadata = $3, agent_mode = $4,
-		    tool_usage_stats = $5, updated_at = $6, source_run_id = $7
-		WHERE id = $8
+		    tool_usage_stats = $5, updated_at = $6, source_run_id = $7,
+		    original_summary = $8, edited_by = $9, edited_at = $10,
+		    regeneration_note = $11, regenerated_by = $12, regenerated_at = $13,
+		    review_state = COALESCE(NULLIF($14, ''), review_state), approved_by = $15, approved_at = $16,
+		    sent_summary = $17
+		WHERE id = $18
 	`, report.Summary, report.CommitCount, report.Metadata, report.AgentMode,
-		report.ToolUsageStats, report.UpdatedAt, report.SourceRunID, report.ID)
+		report.ToolUsageStats, report.UpdatedAt, report.SourceRunID,
+		report.OriginalSummary, report.EditedBy, report.EditedAt,
+		report.RegenerationNote, report.RegeneratedBy, report.RegeneratedAt,
+		report.ReviewState, report.ApprovedBy, report.ApprovedAt, report.SentSummary, report.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update weekly report: %w", err)
 	}
 	return nil
 }
 
+// EditWeeklyReportSummary replaces a report's summary with an admin's edit,
+// keeping the generated summary from before the first edit
+func (db *DB) EditWeeklyReportSummary(ctx context.Context, id int64, summary, editor string) error {
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE weekly_reports
+		SET original_summary = COALESCE(original_summary, summary), summary = $1,
+		    edited_by = $2, edited_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, summary, editor, id)
+	if err != nil {
+		return fmt.Errorf("failed to edit weekly report: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("weekly report not found")
+	}
+	return nil
+}
+
+// RestoreWeeklyReportSummary undoes the edits to a report's summary,
+// restoring the generated one
+func (db *DB) RestoreWeeklyReportSummary(ctx context.Context, id int64) error {
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE weekly_reports
+		SET summary = original_summary, original_summary = NULL,
+		    edited_by = NULL, edited_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND original_summary IS NOT NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore weekly report: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("weekly report not found or not edited")
+	}
+	return nil
+}
+
+// SetReportReviewState approves a report, recording who approved it, or
+// returns it to draft
+func (db *DB) SetReportReviewState(ctx context.Context, id int64, state, actor string) error {
+	if state != ReviewDraft && state != ReviewApproved {
+		return fmt.Errorf("invalid review state: %s", state)
+	}
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE weekly_reports
+		SET review_state = $1,
+		    approved_by = CASE WHEN $1 = 'approved' THEN $2 END,
+		    approved_at = CASE WHEN $1 = 'approved' THEN NOW() END
+		WHERE id = $3
+	`, state, actor, id)
+	if err != nil {
+		return fmt.Errorf("failed to set review state: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("weekly report not found")
+	}
+	return nil
+}
+
+// ClearSentSummary forgets the summary recipients of a regenerated report
+// received, once they were sent a correction or none was needed
+func (db *DB) ClearSentSummary(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, `UPDATE weekly_reports SET sent_summary = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to clear sent summary: %w", err)
+	}
+	return nil
+}
+
+// SetRegenerationNote records who forced a report's regeneration and why
+func (db *DB) SetRegenerationNote(ctx context.Context, id int64, actor, note string) error {
+	_, err := db.q.ExecContext(ctx, `
+		UPDATE weekly_reports
+		SET regeneration_note = NULLIF($1, ''), regenerated_by = $2, regenerated_at = NOW()
+		WHERE id = $3
+	`, note, actor, id)
+	if err != nil {
+		return fmt.Errorf("failed to set regeneration note: %w", err)
+	}
+	return nil
+}
+
 // WeeklyReportExists checks if a weekly report exists for the given repo, year, and week
-func (db *DB) WeeklyReportExists(repoID int64, year, week int) (bool, error) {
-	var count int
-	err := db.QueryRow(`
+func (db *DB) WeeklyReportExists(ctx context.Context, repoID int64, year, week int) (bool, error) {
+	count, err := queryValue[int](ctx, db.q, `
 		SELECT COUNT(*) FROM weekly_reports
 		WHERE repo_id = $1 AND year = $2 AND week = $3
-	`, repoID, year, week).Scan(&count)
+	`, repoID, year, week)
 	if err != nil {
 		return false, fmt.Errorf("failed to check weekly report existence: %w", err)
 	}
@@ -776,44 +897,86 @@ func (db *DB) WeeklyReportExists(repoID int64, year, week int) (bool, error) {
 }
 
 // DeleteWeeklyReport deletes a weekly report by ID
-func (db *DB) DeleteWeeklyReport(id int64) error {
-	_, err := db.Exec("DELETE FROM weekly_reports WHERE id = $1", id)
+func (db *DB) DeleteWeeklyReport(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM weekly_reports WHERE id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete weekly report: %w", err)
 	}
 	return nil
 }
 
+// SaveIncrementalReports completes the activity runs the reports were
+// generated from, creates or updates the weekly reports and advances the
+// repository's last run state in a single transaction. The update only applies
+// if last_run_sha still equals prevSHA, so concurrent incremental runs cannot
+// append the same commits twice.
+func (db *DB) SaveIncrementalReports(ctx context.Context, repoID int64, runs []*ActivityRun, reports []*WeeklyReport, prevSHA sql.NullString, newSHA string) error {
+	return db.WithTx(ctx, func(tx *DB) error {
+		for _, run := range runs {
+			if err := tx.UpdateActivityRun(ctx, run); err != nil {
+				return err
+			}
+		}
+		for _, report := range reports {
+			if report.ID == 0 {
+				created, err := tx.CreateWeeklyReport(ctx, report)
+				if err != nil {
+					return err
+				}
+				*report = *created
+				continue
+			}
+			if err := tx.UpdateWeeklyReport(ctx, report); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.q.ExecContext(ctx, `
+			UPDATE repositories
+			SET last_run_at = $1, last_run_sha = $2
+			WHERE id = $3 AND last_run_sha IS NOT DISTINCT FROM $4
+		`, time.Now(), newSHA, repoID, prevSHA)
+		if err != nil {
+			return fmt.Errorf("failed to update last run: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to update last run: %w", err)
+		}
+		if affected == 0 {
+			return fmt.Errorf("last run state changed concurrently")
+		}
+		return nil
+	})
+}
+
 // Admin CRUD operations
 
-// CreateAdmin inserts a new admin user into the database
-func (db *DB) CreateAdmin(email, createdBy string) (*Admin, error) {
+// CreateAdmin inserts a new admin user for the context's workspace
+func (db *DB) CreateAdmin(ctx context.Context, email, createdBy string) (*Admin, error) {
 	var createdByVal interface{}
 	if createdBy != "" {
 		createdByVal = createdBy
 	}
 
-	var id int64
-	err := db.QueryRow(`
-		INSERT INTO admins (email, created_by)
-		VALUES ($1, $2)
-		RETURNING id
-	`, email, createdByVal).Scan(&id)
+	admin, err := queryRow[Admin](ctx, db.q, `
+		INSERT INTO admins (email, created_by, workspace_id)
+		VALUES ($1, $2, $3)
+		RETURNING `+adminColumns,
+		email, createdByVal, writeWorkspace(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create admin: %w", err)
 	}
-
-	return db.GetAdmin(id)
+	return admin, nil
 }
 
 // GetAdmin retrieves an admin by ID
-func (db *DB) GetAdmin(id int64) (*Admin, error) {
-	admin := &Admin{}
-	err := db.QueryRow(`
-		SELECT id, email, created_at, created_by
+func (db *DB) GetAdmin(ctx context.Context, id int64) (*Admin, error) {
+	admin, err := queryRow[Admin](ctx, db.q, `
+		SELECT `+adminColumns+`
 		FROM admins
-		WHERE id = $1
-	`, id).Scan(&admin.ID, &admin.Email, &admin.CreatedAt, &admin.CreatedBy)
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, id, WorkspaceFromContext(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("admin not found")
@@ -824,13 +987,14 @@ func (db *DB) GetAdmin(id int64) (*Admin, error) {
 }
 
 // GetAdminByEmail retrieves an admin by email
-func (db *DB) GetAdminByEmail(email string) (*Admin, error) {
-	admin := &Admin{}
-	err := db.QueryRow(`
-		SELECT id, email, created_at, created_by
+func (db *DB) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
+	admin, err := queryRow[Admin](ctx, db.q, `
+		SELECT `+adminColumns+`
 		FROM admins
-		WHERE email = $1
-	`, email).Scan(&admin.ID, &admin.Email, &admin.CreatedAt, &admin.CreatedBy)
+		WHERE email = $1 AND ($2 = 0 OR workspace_id = $2)
+		ORDER BY id
+		LIMIT 1
+	`, email, WorkspaceFromContext(ctx))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("admin not found")
@@ -841,56 +1005,307 @@ func (db *DB) GetAdminByEmail(email string) (*Admin, error) {
 }
 
 // ListAdmins retrieves all admins
-func (db *DB) ListAdmins() ([]*Admin, error) {
-	rows, err := db.Query(`
-		SELECT id, email, created_at, created_by
+func (db *DB) ListAdmins(ctx context.Context) ([]*Admin, error) {
+	admins, err := queryRows[Admin](ctx, db.q, `
+		SELECT `+adminColumns+`
 		FROM admins
+		WHERE ($1 = 0 OR workspace_id = $1)
 		ORDER BY email
-	`)
+	`, WorkspaceFromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list admins: %w", err)
 	}
-	defer rows.Close()
-
-	var admins []*Admin
-	for rows.Next() {
-		admin := &Admin{}
-		if err := rows.Scan(&admin.ID, &admin.Email, &admin.CreatedAt, &admin.CreatedBy); err != nil {
-			return nil, fmt.Errorf("failed to scan admin: %w", err)
-		}
-		admins = append(admins, admin)
-	}
-
 	return admins, nil
 }
 
 // DeleteAdmin deletes an admin by ID
-func (db *DB) DeleteAdmin(id int64) error {
-	_, err := db.Exec("DELETE FROM admins WHERE id = $1", id)
+func (db *DB) DeleteAdmin(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM admins WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)", id, WorkspaceFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to delete admin: %w", err)
 	}
 	return nil
 }
 
-// IsAdmin checks if an email is an admin
-func (db *DB) IsAdmin(email string) (bool, error) {
-	var count int
-	err := db.QueryRow(`
-		SELECT COUNT(*) FROM admins WHERE email = $1
-	`, email).Scan(&count)
+// IsAdmin checks if an email is an admin of the context's workspace (of any
+// workspace if unscoped)
+func (db *DB) IsAdmin(ctx context.Context, email string) (bool, error) {
+	count, err := queryValue[int](ctx, db.q, `
+		SELECT COUNT(*) FROM admins WHERE email = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, email, WorkspaceFromContext(ctx))
 	if err != nil {
 		return false, fmt.Errorf("failed to check admin status: %w", err)
 	}
 	return count > 0, nil
 }
 
-// AdminCount returns the number of admins
-func (db *DB) AdminCount() (int, error) {
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM admins").Scan(&count)
+// AdminCount returns the number of admins of the context's workspace
+func (db *DB) AdminCount(ctx context.Context) (int, error) {
+	count, err := queryValue[int](ctx, db.q, "SELECT COUNT(*) FROM admins WHERE ($1 = 0 OR workspace_id = $1)", WorkspaceFromContext(ctx))
 	if err != nil {
 		return 0, fmt.Errorf("failed to count admins: %w", err)
 	}
 	return count, nil
 }
+
+// Author alias operations
+
+// CreateAuthorAlias inserts a new author alias. The alias is stored lowercased
+// so lookups are case-insensitive.
+func (db *DB) CreateAuthorAlias(ctx context.Context, alias, canonicalName, createdBy string) (*AuthorAlias, error) {
+	var createdByVal interface{}
+	if createdBy != "" {
+		createdByVal = createdBy
+	}
+
+	a, err := queryRow[AuthorAlias](ctx, db.q, `
+		INSERT INTO author_aliases (alias, canonical_name, created_by)
+		VALUES (LOWER($1), $2, $3)
+		RETURNING `+authorAliasColumns,
+		alias, canonicalName, createdByVal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create author alias: %w", err)
+	}
+	return a, nil
+}
+
+// ListAuthorAliases retrieves all author aliases ordered by canonical name
+func (db *DB) ListAuthorAliases(ctx context.Context) ([]*AuthorAlias, error) {
+	aliases, err := queryRows[AuthorAlias](ctx, db.q, `
+		SELECT `+authorAliasColumns+`
+		FROM author_aliases
+		ORDER BY canonical_name, alias
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list author aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// DeleteAuthorAlias deletes an author alias by ID
+func (db *DB) DeleteAuthorAlias(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "DELETE FROM author_aliases WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete author alias: %w", err)
+	}
+	return nil
+}
+
+// GetAuthorAliasMap returns all aliases as an alias -> canonical name map
+func (db *DB) GetAuthorAliasMap(ctx context.Context) (map[string]string, error) {
+	aliases, err := db.ListAuthorAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		m[a.Alias] = a.CanonicalName
+	}
+	return m, nil
+}
+
+// ReportVector operations
+
+// UpsertReportVector stores the embedding for a report, replacing any existing one
+func (db *DB) UpsertReportVector(ctx context.Context, v *ReportVector) error {
+	_, err := db.q.ExecContext(ctx, `
+		INSERT INTO report_vectors (report_id, model, content_hash, embedding, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (report_id) DO UPDATE
+		SET model = EXCLUDED.model, content_hash = EXCLUDED.content_hash,
+		    embedding = EXCLUDED.embedding, created_at = EXCLUDED.created_at
+	`, v.ReportID, v.Model, v.ContentHash, pq.Array(v.Embedding))
+	if err != nil {
+		return fmt.Errorf("failed to upsert report vector: %w", err)
+	}
+	return nil
+}
+
+// GetReportVector retrieves the embedding for a report, or nil if it has none
+func (db *DB) GetReportVector(ctx context.Context, reportID int64) (*ReportVector, error) {
+	v, err := queryRow[ReportVector](ctx, db.q, `
+		SELECT `+reportVectorColumns+`
+		FROM report_vectors
+		WHERE report_id = $1
+	`, reportID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get report vector: %w", err)
+	}
+	return v, nil
+}
+
+// ListReportVectors retrieves all embeddings produced by the given model
+func (db *DB) ListReportVectors(ctx context.Context, model string) ([]*ReportVector, error) {
+	vectors, err := queryRows[ReportVector](ctx, db.q, `
+		SELECT `+reportVectorColumns+`
+		FROM report_vectors
+		WHERE model = $1
+		ORDER BY report_id
+	`, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// ListReportVectorsFor retrieves the embeddings produced by the given model
+// of the given reports
+func (db *DB) ListReportVectorsFor(ctx context.Context, model string, reportIDs []int64) ([]*ReportVector, error) {
+	vectors, err := queryRows[ReportVector](ctx, db.q, `
+		SELECT `+reportVectorColumns+`
+		FROM report_vectors
+		WHERE model = $1 AND report_id = ANY($2)
+		ORDER BY report_id
+	`, model, pq.Array(reportIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// UpsertCommitVector stores the embedding for a commit, replacing any existing one
+func (db *DB) UpsertCommitVector(ctx context.Context, v *CommitVector) error {
+	_, err := db.q.ExecContext(ctx, `
+		INSERT INTO commit_vectors (report_id, sha, message, author, committed_at, model, content_hash, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (report_id, sha) DO UPDATE
+		SET message = EXCLUDED.message, author = EXCLUDED.author, committed_at = EXCLUDED.committed_at,
+		    model = EXCLUDED.model, content_hash = EXCLUDED.content_hash,
+		    embedding = EXCLUDED.embedding, created_at = EXCLUDED.created_at
+	`, v.ReportID, v.SHA, v.Message, v.Author, v.CommittedAt, v.Model, v.ContentHash, pq.Array(v.Embedding))
+	if err != nil {
+		return fmt.Errorf("failed to upsert commit vector: %w", err)
+	}
+	return nil
+}
+
+// ListCommitVectors retrieves all commit embeddings produced by the given model
+func (db *DB) ListCommitVectors(ctx context.Context, model string) ([]*CommitVector, error) {
+	vectors, err := queryRows[CommitVector](ctx, db.q, `
+		SELECT `+commitVectorColumns+`
+		FROM commit_vectors
+		WHERE model = $1
+		ORDER BY report_id, committed_at
+	`, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commit vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// ListCommitVectorsFor retrieves the commit embeddings produced by the given
+// model in the weeks of the given reports
+func (db *DB) ListCommitVectorsFor(ctx context.Context, model string, reportIDs []int64) ([]*CommitVector, error) {
+	vectors, err := queryRows[CommitVector](ctx, db.q, `
+		SELECT `+commitVectorColumns+`
+		FROM commit_vectors
+		WHERE model = $1 AND report_id = ANY($2)
+		ORDER BY report_id, committed_at
+	`, model, pq.Array(reportIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commit vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// Prune deletes expired and orphaned rows in a single transaction. With
+// DryRun set the deletes are rolled back, so the result reports what would
+// be deleted. Counts include rows removed by cascading deletes, e.g. the
+// vectors of a deleted weekly report.
+func (db *DB) Prune(ctx context.Context, opts PruneOptions) (*PruneResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Count before and after within one snapshot so concurrent writes do not
+	// skew the numbers
+	if _, err := tx.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		return nil, fmt.Errorf("failed to set transaction isolation: %w", err)
+	}
+
+	count := func(table string) (int64, error) {
+		n, err := queryValue[int64](ctx, tx, "SELECT COUNT(*) FROM "+table)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		return n, nil
+	}
+	tables := []string{"activity_runs", "newsletter_sends", "newsletter_batches", "weekly_reports", "report_vectors", "commit_vectors"}
+	before := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		if before[table], err = count(table); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.ActivityRunsBefore.IsZero() {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM activity_runs WHERE started_at < $1`, opts.ActivityRunsBefore); err != nil {
+			return nil, fmt.Errorf("failed to prune activity runs: %w", err)
+		}
+	}
+	var rawData int64
+	if !opts.RawDataBefore.IsZero() {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE activity_runs SET raw_data = NULL, raw_data_ref = NULL
+			WHERE started_at < $1 AND (raw_data IS NOT NULL OR raw_data_ref IS NOT NULL)
+		`, opts.RawDataBefore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune raw run data: %w", err)
+		}
+		if rawData, err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to prune raw run data: %w", err)
+		}
+	}
+	if !opts.NewsletterSendsBefore.IsZero() {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM newsletter_sends WHERE sent_at < $1`, opts.NewsletterSendsBefore); err != nil {
+			return nil, fmt.Errorf("failed to prune newsletter sends: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM newsletter_batches WHERE started_at < $1`, opts.NewsletterSendsBefore); err != nil {
+			return nil, fmt.Errorf("failed to prune newsletter batches: %w", err)
+		}
+	}
+	if !opts.WeeklyReportsBefore.IsZero() {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM weekly_reports WHERE week_end < $1`, opts.WeeklyReportsBefore); err != nil {
+			return nil, fmt.Errorf("failed to prune weekly reports: %w", err)
+		}
+	}
+	if opts.VectorModel != "" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM report_vectors WHERE model <> $1`, opts.VectorModel); err != nil {
+			return nil, fmt.Errorf("failed to prune report vectors: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM commit_vectors WHERE model <> $1`, opts.VectorModel); err != nil {
+			return nil, fmt.Errorf("failed to prune commit vectors: %w", err)
+		}
+	}
+
+	deleted := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		after, err := count(table)
+		if err != nil {
+			return nil, err
+		}
+		deleted[table] = before[table] - after
+	}
+
+	if !opts.DryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit prune: %w", err)
+		}
+	}
+
+	return &PruneResult{
+		ActivityRuns:      deleted["activity_runs"],
+		RawData:           rawData,
+		NewsletterSends:   deleted["newsletter_sends"],
+		NewsletterBatches: deleted["newsletter_batches"],
+		WeeklyReports:     deleted["weekly_reports"],
+		ReportVectors:     deleted["report_vectors"],
+		CommitVectors:     deleted["commit_vectors"],
+	}, nil
+}
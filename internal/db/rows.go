@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// Columns selected for each model. A query that scans a model must select
+// exactly its column list, so adding a column only takes a change here and in
+// the model's fields method (plus a migration).
+const (
+	repositoryColumns      = `id, name, url, branch, active, COALESCE(private, false), description, created_at, updated_at, last_run_at, last_run_sha, workspace_id, readme_hash, context_notes, visibility, last_commit_at, stale_notified_at`
+	activityRunColumns     = `id, repo_id, start_sha, end_sha, started_at, completed_at, summary, raw_data, COALESCE(agent_mode, false), tool_usage_stats, raw_data_ref`
+	rawDataBlobColumns     = `id, raw_data_ref`
+	subscriberColumns      = `id, email, subscribe_all, timezone, send_hour, workspace_id, created_at, suppressed_at, suppressed_reason`
+	subscriptionColumns    = `id, subscriber_id, repo_id, created_at`
+	newsletterSendColumns  = `id, subscriber_id, repo_id, year, week, sent_at, sendgrid_message_id`
+	weeklyReportColumns    = `id, repo_id, year, week, week_start, week_end, summary, commit_count, metadata, COALESCE(agent_mode, false), tool_usage_stats, created_at, updated_at, source_run_id, original_summary, edited_by, edited_at, regeneration_note, regenerated_by, regenerated_at, review_state, approved_by, approved_at, sent_summary`
+	adminColumns           = `id, email, created_at, created_by, workspace_id`
+	authorAliasColumns     = `id, alias, canonical_name, created_at, created_by`
+	reportVectorColumns    = `report_id, model, content_hash, embedding, created_at`
+	workspaceColumns       = `id, name, created_at`
+	apiTokenColumns        = `id, workspace_id, name, token_hash, created_by, created_at, last_used_at`
+	commitVectorColumns    = `report_id, sha, message, author, committed_at, model, content_hash, embedding, created_at`
+	emailEventColumns      = `id, subscriber_id, event, reason, sendgrid_event_id, sendgrid_message_id, occurred_at, created_at`
+	auditEntryColumns      = `id, workspace_id, actor, action, target, details, created_at`
+	secretColumns          = `name, ciphertext, created_at, updated_at`
+	userPreferencesColumns = `email, favorite_repo_ids, digest_favorites_only, updated_at`
+	notificationColumns    = `id, workspace_id, recipient, kind, title, link, created_at, read_at`
+	newsletterBatchColumns = `id, workspace_id, kind, started_by, started_at,
+		(SELECT COUNT(*) FROM newsletter_deliveries d WHERE d.batch_id = newsletter_batches.id AND d.status = 'sent'),
+		(SELECT COUNT(*) FROM newsletter_deliveries d WHERE d.batch_id = newsletter_batches.id AND d.status = 'failed')`
+	newsletterDeliveryColumns = `id, batch_id, subscriber_id, email, status, subject, report_count, message_id, error, attempts, window_start, window_end, updated_at`
+)
+
+// model is a pointer to a struct that can be scanned from its column list
+type model[T any] interface {
+	*T
+	fields() []any
+}
+
+// queryRow runs a query returning at most one row and scans it into a new T.
+// It returns sql.ErrNoRows if the query returned no rows.
+func queryRow[T any, P model[T]](ctx context.Context, q querier, query string, args ...any) (*T, error) {
+	var v T
+	if err := q.QueryRowContext(ctx, query, args...).Scan(P(&v).fields()...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// queryRows runs a query and scans each row into a new T
+func queryRows[T any, P model[T]](ctx context.Context, q querier, query string, args ...any) ([]*T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*T
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(P(&v).fields()...); err != nil {
+			return nil, err
+		}
+		result = append(result, &v)
+	}
+	return result, rows.Err()
+}
+
+// queryValue runs a query returning one row of a single column, such as a
+// count, and scans it into a T
+func queryValue[T any](ctx context.Context, q querier, query string, args ...any) (T, error) {
+	var v T
+	err := q.QueryRowContext(ctx, query, args...).Scan(&v)
+	return v, err
+}
+
+// queryValues runs a query returning a single column and scans each row
+// into a T
+func queryValues[T any](ctx context.Context, q querier, query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+func (r *Repository) fields() []any {
+	return []any{&r.ID, &r.Name, &r.URL, &r.Branch, &r.Active, &r.Private, &r.Description,
+		&r.CreatedAt, &r.UpdatedAt, &r.LastRunAt, &r.LastRunSHA, &r.WorkspaceID, &r.ReadmeHash, &r.ContextNotes, &r.Visibility,
+		&r.LastCommitAt, &r.StaleNotifiedAt}
+}
+
+func (r *ActivityRun) fields() []any {
+	return []any{&r.ID, &r.RepoID, &r.StartSHA, &r.EndSHA, &r.StartedAt, &r.CompletedAt,
+		&r.Summary, &r.RawData, &r.AgentMode, &r.ToolUsageStats, &r.RawDataRef}
+}
+
+func (b *RawDataBlob) fields() []any {
+	return []any{&b.RunID, &b.Ref}
+}
+
+func (s *Subscriber) fields() []any {
+	return []any{&s.ID, &s.Email, &s.SubscribeAll, &s.Timezone, &s.SendHour, &s.WorkspaceID, &s.CreatedAt,
+		&s.SuppressedAt, &s.SuppressedReason}
+}
+
+func (s *Subscription) fields() []any {
+	return []any{&s.ID, &s.SubscriberID, &s.RepoID, &s.CreatedAt}
+}
+
+func (s *NewsletterSend) fields() []any {
+	return []any{&s.ID, &s.SubscriberID, &s.RepoID, &s.Year, &s.Week, &s.SentAt, &s.SendGridMessageID}
+}
+
+func (r *WeeklyReport) fields() []any {
+	return []any{&r.ID, &r.RepoID, &r.Year, &r.Week, &r.WeekStart, &r.WeekEnd, &r.Summary, &r.CommitCount,
+		&r.Metadata, &r.AgentMode, &r.ToolUsageStats, &r.CreatedAt, &r.UpdatedAt, &r.SourceRunID,
+		&r.OriginalSummary, &r.EditedBy, &r.EditedAt, &r.RegenerationNote, &r.RegeneratedBy, &r.RegeneratedAt,
+		&r.ReviewState, &r.ApprovedBy, &r.ApprovedAt, &r.SentSummary}
+}
+
+func (a *Admin) fields() []any {
+	return []any{&a.ID, &a.Email, &a.CreatedAt, &a.CreatedBy, &a.WorkspaceID}
+}
+
+func (a *AuthorAlias) fields() []any {
+	return []any{&a.ID, &a.Alias, &a.CanonicalName, &a.CreatedAt, &a.CreatedBy}
+}
+
+func (v *ReportVector) fields() []any {
+	return []any{&v.ReportID, &v.Model, &v.ContentHash, pq.Array(&v.Embedding), &v.CreatedAt}
+}
+
+func (v *CommitVector) fields() []any {
+	return []any{&v.ReportID, &v.SHA, &v.Message, &v.Author, &v.CommittedAt, &v.Model, &v.ContentHash,
+		pq.Array(&v.Embedding), &v.CreatedAt}
+}
+
+func (w *Workspace) fields() []any {
+	return []any{&w.ID, &w.Name, &w.CreatedAt}
+}
+
+func (t *APIToken) fields() []any {
+	return []any{&t.ID, &t.WorkspaceID, &t.Name, &t.TokenHash, &t.CreatedBy, &t.CreatedAt, &t.LastUsedAt}
+}
+
+func (e *EmailEvent) fields() []any {
+	return []any{&e.ID, &e.SubscriberID, &e.Event, &e.Reason, &e.SendGridEventID, &e.SendGridMessageID,
+		&e.OccurredAt, &e.CreatedAt}
+}
+
+func (e *AuditEntry) fields() []any {
+	return []any{&e.ID, &e.WorkspaceID, &e.Actor, &e.Action, &e.Target, &e.Details, &e.CreatedAt}
+}
+
+func (s *Secret) fields() []any {
+	return []any{&s.Name, &s.Ciphertext, &s.CreatedAt, &s.UpdatedAt}
+}
+
+func (p *UserPreferences) fields() []any {
+	return []any{&p.Email, pq.Array(&p.FavoriteRepoIDs), &p.DigestFavoritesOnly, &p.UpdatedAt}
+}
+
+func (n *Notification) fields() []any {
+	return []any{&n.ID, &n.WorkspaceID, &n.Recipient, &n.Kind, &n.Title, &n.Link, &n.CreatedAt, &n.ReadAt}
+}
+
+func (b *NewsletterBatch) fields() []any {
+	return []any{&b.ID, &b.WorkspaceID, &b.Kind, &b.StartedBy, &b.StartedAt, &b.Sent, &b.Failed}
+}
+
+func (d *NewsletterDelivery) fields() []any {
+	return []any{&d.ID, &d.BatchID, &d.SubscriberID, &d.Email, &d.Status, &d.Subject, &d.ReportCount,
+		&d.MessageID, &d.Error, &d.Attempts, &d.WindowStart, &d.WindowEnd, &d.UpdatedAt}
+}
+
+func (e *DeliveryEvents) fields() []any {
+	return []any{&e.DeliveryID, &e.Delivered, &e.Bounced, &e.Opened}
+}
+
+func (s *EmailEventStats) fields() []any {
+	return []any{&s.SubscriberID, &s.Delivered, &s.Bounces, &s.SpamReports, &s.Dropped, &s.Opened, &s.LastEventAt}
+}
@@ -0,0 +1,55 @@
+package db
+
+import "testing"
+
+// countColumns counts the top-level entries in a column list, ignoring
+// commas inside function calls such as COALESCE(x, false)
+func countColumns(columns string) int {
+	n, depth := 1, 0
+	for _, c := range columns {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestColumnsMatchFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns string
+		fields  []any
+	}{
+		{"repositories", repositoryColumns, (&Repository{}).fields()},
+		{"activity_runs", activityRunColumns, (&ActivityRun{}).fields()},
+		{"subscribers", subscriberColumns, (&Subscriber{}).fields()},
+		{"subscriptions", subscriptionColumns, (&Subscription{}).fields()},
+		{"newsletter_sends", newsletterSendColumns, (&NewsletterSend{}).fields()},
+		{"weekly_reports", weeklyReportColumns, (&WeeklyReport{}).fields()},
+		{"admins", adminColumns, (&Admin{}).fields()},
+		{"author_aliases", authorAliasColumns, (&AuthorAlias{}).fields()},
+		{"report_vectors", reportVectorColumns, (&ReportVector{}).fields()},
+		{"commit_vectors", commitVectorColumns, (&CommitVector{}).fields()},
+		{"workspaces", workspaceColumns, (&Workspace{}).fields()},
+		{"api_tokens", apiTokenColumns, (&APIToken{}).fields()},
+		{"email_events", emailEventColumns, (&EmailEvent{}).fields()},
+		{"secrets", secretColumns, (&Secret{}).fields()},
+		{"user_preferences", userPreferencesColumns, (&UserPreferences{}).fields()},
+		{"notifications", notificationColumns, (&Notification{}).fields()},
+		{"newsletter_batches", newsletterBatchColumns, (&NewsletterBatch{}).fields()},
+		{"newsletter_deliveries", newsletterDeliveryColumns, (&NewsletterDelivery{}).fields()},
+	}
+
+	for _, tt := range tests {
+		if got := countColumns(tt.columns); got != len(tt.fields) {
+			t.Errorf("%s: %d columns but %d fields", tt.name, got, len(tt.fields))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Secret operations. Values are stored encrypted; see internal/secrets.
+
+// PutSecret stores an encrypted secret, replacing any secret with that name
+func (db *DB) PutSecret(ctx context.Context, name, ciphertext string) error {
+	_, err := db.q.ExecContext(ctx, `
+		INSERT INTO secrets (name, ciphertext)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET ciphertext = EXCLUDED.ciphertext, updated_at = NOW()
+	`, name, ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return nil
+}
+
+// GetSecret retrieves an encrypted secret by name, or nil if there is none
+func (db *DB) GetSecret(ctx context.Context, name string) (*Secret, error) {
+	secret, err := queryRow[Secret](ctx, db.q, `
+		SELECT `+secretColumns+`
+		FROM secrets
+		WHERE name = $1
+	`, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+	return secret, nil
+}
+
+// ListSecrets retrieves all encrypted secrets, ordered by name
+func (db *DB) ListSecrets(ctx context.Context) ([]*Secret, error) {
+	secrets, err := queryRows[Secret](ctx, db.q, `
+		SELECT `+secretColumns+`
+		FROM secrets
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// DeleteSecret deletes a secret, returning false if it did not exist
+func (db *DB) DeleteSecret(ctx context.Context, name string) (bool, error) {
+	result, err := db.q.ExecContext(ctx, `DELETE FROM secrets WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete secret: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return n > 0, nil
+}
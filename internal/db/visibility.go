@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// Visibility of a repository and its weekly reports in the web UI
+const (
+	VisibilityPublic        = "public"        // Anyone, signed in or not
+	VisibilityAuthenticated = "authenticated" // Signed in users
+	VisibilityAdmin         = "admin"         // Admins only
+)
+
+// visibilityLevels lists the visibilities from least to most restricted
+var visibilityLevels = []string{VisibilityPublic, VisibilityAuthenticated, VisibilityAdmin}
+
+// ValidVisibility reports whether v is a known repository visibility
+func ValidVisibility(v string) bool {
+	return slices.Contains(visibilityLevels, v)
+}
+
+// visibilityKey is the context key for the most restricted visibility queries
+// may see
+type visibilityKey struct{}
+
+// WithVisibility returns a context that hides repositories more restricted
+// than visibility, along with their weekly reports: VisibilityPublic for
+// anonymous readers, VisibilityAuthenticated for signed in users and
+// VisibilityAdmin for admins. Contexts without it see every repository.
+func WithVisibility(ctx context.Context, visibility string) context.Context {
+	return context.WithValue(ctx, visibilityKey{}, visibility)
+}
+
+// VisibilityFromContext returns the most restricted visibility ctx may see
+func VisibilityFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(visibilityKey{}).(string); ok && ValidVisibility(v) {
+		return v
+	}
+	return VisibilityAdmin
+}
+
+// visibleLevels returns the visibilities ctx may see, as a query parameter
+func visibleLevels(ctx context.Context) any {
+	i := slices.Index(visibilityLevels, VisibilityFromContext(ctx))
+	return pq.Array(visibilityLevels[:i+1])
+}
+
+// SetRepositoryVisibility sets who may read a repository's reports
+func (db *DB) SetRepositoryVisibility(ctx context.Context, id int64, visibility string) error {
+	if !ValidVisibility(visibility) {
+		return fmt.Errorf("invalid visibility %q", visibility)
+	}
+	result, err := db.q.ExecContext(ctx, `
+		UPDATE repositories
+		SET visibility = $1, updated_at = NOW()
+		WHERE id = $2 AND ($3 = 0 OR workspace_id = $3)
+	`, visibility, id, WorkspaceFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set repository visibility: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("repository not found")
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DefaultWorkspaceID is the workspace created by the migrations. Rows written
+// with an unscoped context belong to it.
+const DefaultWorkspaceID int64 = 1
+
+// workspaceKey is the context key for the workspace queries are scoped to
+type workspaceKey struct{}
+
+// WithWorkspace returns a context that scopes queries to a workspace: reads
+// of repositories, weekly reports, subscribers, admins and API tokens only
+// see rows of that workspace, and new rows are created in it. A workspaceID
+// of 0 removes the scope, so queries see all workspaces.
+func WithWorkspace(ctx context.Context, workspaceID int64) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspaceID)
+}
+
+// WorkspaceFromContext returns the workspace ctx is scoped to, or 0 if it is
+// unscoped
+func WorkspaceFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(workspaceKey{}).(int64)
+	return id
+}
+
+// writeWorkspace returns the workspace new rows are created in
+func writeWorkspace(ctx context.Context) int64 {
+	if id := WorkspaceFromContext(ctx); id != 0 {
+		return id
+	}
+	return DefaultWorkspaceID
+}
+
+// Workspace operations
+
+// CreateWorkspace inserts a new workspace
+func (db *DB) CreateWorkspace(ctx context.Context, name string) (*Workspace, error) {
+	ws, err := queryRow[Workspace](ctx, db.q, `
+		INSERT INTO workspaces (name)
+		VALUES ($1)
+		RETURNING `+workspaceColumns,
+		name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// GetWorkspace retrieves a workspace by ID
+func (db *DB) GetWorkspace(ctx context.Context, id int64) (*Workspace, error) {
+	ws, err := queryRow[Workspace](ctx, db.q, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workspace not found")
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// GetWorkspaceByName retrieves a workspace by name
+func (db *DB) GetWorkspaceByName(ctx context.Context, name string) (*Workspace, error) {
+	ws, err := queryRow[Workspace](ctx, db.q, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces
+		WHERE name = $1
+	`, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workspace not found")
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// ListWorkspaces retrieves all workspaces, the default workspace first
+func (db *DB) ListWorkspaces(ctx context.Context) ([]*Workspace, error) {
+	workspaces, err := queryRows[Workspace](ctx, db.q, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces
+		ORDER BY id <> $1, name
+	`, DefaultWorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+// ListAdminWorkspaces retrieves the workspaces email is an admin of, the
+// default workspace first
+func (db *DB) ListAdminWorkspaces(ctx context.Context, email string) ([]*Workspace, error) {
+	workspaces, err := queryRows[Workspace](ctx, db.q, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces w
+		WHERE EXISTS (SELECT 1 FROM admins a WHERE a.workspace_id = w.id AND a.email = $1)
+		ORDER BY id <> $2, name
+	`, email, DefaultWorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+// DeleteWorkspace deletes a workspace with all of its repositories (and
+// their reports), subscribers, admins and API tokens in one transaction.
+// The default workspace cannot be deleted.
+func (db *DB) DeleteWorkspace(ctx context.Context, id int64) error {
+	if id == DefaultWorkspaceID {
+		return fmt.Errorf("the default workspace cannot be deleted")
+	}
+
+	return db.WithTx(ctx, func(tx *DB) error {
+		repos, err := tx.ListRepositories(WithWorkspace(ctx, id), nil)
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			if err := tx.DeleteRepository(ctx, repo.ID); err != nil {
+				return err
+			}
+		}
+
+		cleanup := []struct {
+			what  string
+			query string
+		}{
+			{"subscribers", `DELETE FROM subscribers WHERE workspace_id = $1`},
+			{"admins", `DELETE FROM admins WHERE workspace_id = $1`},
+			{"API tokens", `DELETE FROM api_tokens WHERE workspace_id = $1`},
+		}
+		for _, c := range cleanup {
+			if _, err := tx.q.ExecContext(ctx, c.query, id); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", c.what, err)
+			}
+		}
+
+		result, err := tx.q.ExecContext(ctx, "DELETE FROM workspaces WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete workspace: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("workspace not found")
+		}
+		return nil
+	})
+}
+
+// API token operations
+
+// CreateAPIToken stores the hash of a new API token for the context's workspace
+func (db *DB) CreateAPIToken(ctx context.Context, name, tokenHash, createdBy string) (*APIToken, error) {
+	var createdByVal interface{}
+	if createdBy != "" {
+		createdByVal = createdBy
+	}
+
+	token, err := queryRow[APIToken](ctx, db.q, `
+		INSERT INTO api_tokens (workspace_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+apiTokenColumns,
+		writeWorkspace(ctx), name, tokenHash, createdByVal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+	return token, nil
+}
+
+// GetAPITokenByHash retrieves an API token by the hash of its value. Tokens
+// are looked up before the workspace is known, so this is never scoped.
+func (db *DB) GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error) {
+	token, err := queryRow[APIToken](ctx, db.q, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE token_hash = $1
+	`, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("API token not found")
+		}
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+// ListAPITokens retrieves the API tokens of the context's workspace
+func (db *DB) ListAPITokens(ctx context.Context) ([]*APIToken, error) {
+	tokens, err := queryRows[APIToken](ctx, db.q, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE ($1 = 0 OR workspace_id = $1)
+		ORDER BY created_at
+	`, WorkspaceFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteAPIToken deletes an API token of the context's workspace
+func (db *DB) DeleteAPIToken(ctx context.Context, id int64) error {
+	result, err := db.q.ExecContext(ctx, `
+		DELETE FROM api_tokens
+		WHERE id = $1 AND ($2 = 0 OR workspace_id = $2)
+	`, id, WorkspaceFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("API token not found")
+	}
+	return nil
+}
+
+// TouchAPIToken records that an API token was used
+func (db *DB) TouchAPIToken(ctx context.Context, id int64) error {
+	_, err := db.q.ExecContext(ctx, "UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MailgunClient sends email through the Mailgun messages API
+type MailgunClient struct {
+	apiKey     string
+	domain     string
+	baseURL    string
+	fromEmail  string
+	fromName   string
+	httpClient *http.Client
+}
+
+// NewMailgunClient creates a new Mailgun client sending from domain. baseURL
+// selects the region, e.g. https://api.mailgun.net or https://api.eu.mailgun.net.
+func NewMailgunClient(apiKey, domain, baseURL, fromEmail, fromName string) *MailgunClient {
+	return &MailgunClient{
+		apiKey:     apiKey,
+		domain:     domain,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		fromEmail:  fromEmail,
+		fromName:   fromName,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// mailgunResponse is the response body of the Mailgun messages API
+type mailgunResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// Send sends an email via Mailgun and returns the message ID
+func (c *MailgunClient) Send(ctx context.Context, email Email) (string, error) {
+	from := mail.Address{Name: c.fromName, Address: c.fromEmail}
+	form := url.Values{}
+	form.Set("from", from.String())
+	form.Set("to", email.To)
+	form.Set("subject", email.Subject)
+	if email.TextContent != "" {
+		form.Set("text", email.TextContent)
+	}
+	if email.HTMLContent != "" {
+		form.Set("html", email.HTMLContent)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", c.baseURL, url.PathEscape(c.domain))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("mailgun returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result mailgunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse mailgun response: %w", err)
+	}
+
+	// Mailgun returns the Message-Id header value, e.g. "<20260105.1@example.com>"
+	return strings.Trim(result.ID, "<>"), nil
+}
+
+// Check verifies that the API key is valid and the sending domain exists in
+// the account
+func (c *MailgunClient) Check(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/v3/domains/%s", c.baseURL, url.PathEscape(c.domain))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("api", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach mailgun: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mailgun returned status %d for domain %s: %s", resp.StatusCode, c.domain, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
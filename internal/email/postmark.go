@@ -0,0 +1,117 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+// postmarkURL is the Postmark endpoint for sending a single email
+const postmarkURL = "https://api.postmarkapp.com/email"
+
+// postmarkServerURL is the Postmark endpoint describing the token's server
+const postmarkServerURL = "https://api.postmarkapp.com/server"
+
+// PostmarkClient sends email through the Postmark API
+type PostmarkClient struct {
+	serverToken string
+	fromEmail   string
+	fromName    string
+	httpClient  *http.Client
+}
+
+// NewPostmarkClient creates a new Postmark client
+func NewPostmarkClient(serverToken, fromEmail, fromName string) *PostmarkClient {
+	return &PostmarkClient{
+		serverToken: serverToken,
+		fromEmail:   fromEmail,
+		fromName:    fromName,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// postmarkMessage is the request body of the Postmark send API
+type postmarkMessage struct {
+	From          string `json:"From"`
+	To            string `json:"To"`
+	Subject       string `json:"Subject"`
+	HTMLBody      string `json:"HtmlBody,omitempty"`
+	TextBody      string `json:"TextBody,omitempty"`
+	MessageStream string `json:"MessageStream"`
+}
+
+// postmarkResponse is the response body of the Postmark send API
+type postmarkResponse struct {
+	ErrorCode int    `json:"ErrorCode"`
+	Message   string `json:"Message"`
+	MessageID string `json:"MessageID"`
+}
+
+// Send sends an email via Postmark and returns the message ID
+func (c *PostmarkClient) Send(ctx context.Context, email Email) (string, error) {
+	from := mail.Address{Name: c.fromName, Address: c.fromEmail}
+	body, err := json.Marshal(postmarkMessage{
+		From:          from.String(),
+		To:            email.To,
+		Subject:       email.Subject,
+		HTMLBody:      email.HTMLContent,
+		TextBody:      email.TextContent,
+		MessageStream: "outbound",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postmarkURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", c.serverToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result postmarkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("postmark returned status %d with unreadable body: %w", resp.StatusCode, err)
+	}
+
+	// Postmark reports failures with a non-zero error code, also on some 200 responses
+	if resp.StatusCode != http.StatusOK || result.ErrorCode != 0 {
+		return "", fmt.Errorf("postmark returned status %d (error %d): %s", resp.StatusCode, result.ErrorCode, result.Message)
+	}
+
+	return result.MessageID, nil
+}
+
+// Check verifies that the server token is valid
+func (c *PostmarkClient) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, postmarkServerURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", c.serverToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach postmark: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result postmarkResponse
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("postmark returned status %d (error %d): %s", resp.StatusCode, result.ErrorCode, result.Message)
+	}
+	return nil
+}
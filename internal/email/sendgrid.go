@@ -2,7 +2,9 @@ package email
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -58,6 +60,30 @@ func (c *Client) Send(ctx context.Context, email Email) (string, error) {
 	return messageID, nil
 }
 
+// Check verifies that the API key is valid and allowed to send mail
+func (c *Client) Check(ctx context.Context) error {
+	request := sendgrid.GetRequest(c.apiKey, "/v3/scopes", "")
+	request.Method = "GET"
+	response, err := sendgrid.MakeRequestWithContext(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to reach sendgrid: %w", err)
+	}
+	if response.StatusCode != 200 {
+		return fmt.Errorf("sendgrid returned status %d: %s", response.StatusCode, response.Body)
+	}
+
+	var result struct {
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil {
+		return fmt.Errorf("failed to parse sendgrid response: %w", err)
+	}
+	if !slices.Contains(result.Scopes, "mail.send") {
+		return fmt.Errorf("sendgrid API key lacks the mail.send scope")
+	}
+	return nil
+}
+
 // DryRunClient is a client that doesn't actually send emails
 type DryRunClient struct {
 	fromEmail string
@@ -82,3 +108,9 @@ func (c *DryRunClient) Send(ctx context.Context, email Email) (string, error) {
 type Sender interface {
 	Send(ctx context.Context, email Email) (string, error)
 }
+
+// Checker is implemented by senders that can verify their credentials
+// without sending an email
+type Checker interface {
+	Check(ctx context.Context) error
+}
@@ -0,0 +1,127 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying the signature of SendGrid's signed event webhook
+const (
+	SignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	TimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// webhookTolerance is how far a webhook's timestamp may be from now. The
+// signature covers the timestamp, so older requests cannot be replayed.
+const webhookTolerance = 5 * time.Minute
+
+// Event is a delivery event posted by the SendGrid event webhook. Only the
+// fields used for bounce handling and delivery stats are decoded.
+type Event struct {
+	Email     string `json:"email"`
+	Timestamp int64  `json:"timestamp"`
+	Event     string `json:"event"`       // processed, delivered, deferred, bounce, dropped, spamreport, ...
+	Type      string `json:"type"`        // For bounce events: "bounce" (hard) or "blocked" (soft)
+	Reason    string `json:"reason"`      // Bounce or drop reason
+	EventID   string `json:"sg_event_id"` // Unique per event, used to ignore retries
+	MessageID string `json:"sg_message_id"`
+}
+
+// Time returns when the event occurred
+func (e Event) Time() time.Time {
+	return time.Unix(e.Timestamp, 0).UTC()
+}
+
+// SentMessageID returns the message ID the provider returned when the
+// message was sent. SendGrid reports it in events followed by a dot and
+// routing details.
+func (e Event) SentMessageID() string {
+	id, _, _ := strings.Cut(e.MessageID, ".")
+	return id
+}
+
+// HardBounce reports whether the event is a permanent bounce, meaning the
+// address should not be sent to again. Blocked messages are soft bounces.
+func (e Event) HardBounce() bool {
+	return e.Event == "bounce" && e.Type != "blocked"
+}
+
+// ParseEvents decodes a webhook payload, which is a JSON array of events
+func ParseEvents(payload []byte) ([]Event, error) {
+	var events []Event
+	if err := json.Unmarshal(payload, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook events: %w", err)
+	}
+	return events, nil
+}
+
+// WebhookVerifier verifies the signatures of SendGrid's signed event webhook
+type WebhookVerifier struct {
+	key *ecdsa.PublicKey
+}
+
+// NewWebhookVerifier creates a verifier from the webhook's verification key
+// as shown in the SendGrid settings (base64 DER, PEM is accepted too)
+func NewWebhookVerifier(publicKey string) (*WebhookVerifier, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(publicKey)); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		der, err = base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode webhook verification key: %w", err)
+		}
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook verification key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("webhook verification key is not an ECDSA key")
+	}
+	return &WebhookVerifier{key: ecKey}, nil
+}
+
+// Verify checks the base64 ECDSA signature of a payload, which SendGrid
+// computes over the timestamp header followed by the raw request body, and
+// rejects timestamps more than webhookTolerance from now
+func (v *WebhookVerifier) Verify(signature, timestamp string, payload []byte) error {
+	return v.verifyAt(signature, timestamp, payload, time.Now())
+}
+
+// verifyAt is Verify at the given time
+func (v *WebhookVerifier) verifyAt(signature, timestamp string, payload []byte, now time.Time) error {
+	if signature == "" || timestamp == "" {
+		return fmt.Errorf("missing webhook signature")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook timestamp: %s", timestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return fmt.Errorf("webhook timestamp is more than %s from now", webhookTolerance)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid webhook signature: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(timestamp))
+	h.Write(payload)
+	if !ecdsa.VerifyASN1(v.key, h.Sum(nil), sig) {
+		return fmt.Errorf("webhook signature does not match")
+	}
+	return nil
+}
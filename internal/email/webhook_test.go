@@ -0,0 +1,100 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSigner returns a verifier for a fresh key and a function signing like
+// SendGrid does
+func newSigner(t *testing.T) (*WebhookVerifier, func(timestamp string, payload []byte) string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	verifier, err := NewWebhookVerifier(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatalf("NewWebhookVerifier() error = %v", err)
+	}
+
+	sign := func(timestamp string, payload []byte) string {
+		h := sha256.Sum256(append([]byte(timestamp), payload...))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	return verifier, sign
+}
+
+func TestVerify(t *testing.T) {
+	verifier, sign := newSigner(t)
+	payload := []byte(`[{"email":"user@example.com","event":"bounce","sg_event_id":"ev1"}]`)
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := sign(timestamp, payload)
+
+	if err := verifier.verifyAt(signature, timestamp, payload, now.Add(time.Minute)); err != nil {
+		t.Fatalf("verifyAt() of a fresh request error = %v", err)
+	}
+	if err := verifier.Verify(signature, timestamp, payload); err == nil {
+		t.Error("Verify() accepted a request signed in 2023")
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		payload   []byte
+		now       time.Time
+		want      string
+	}{
+		{"stale timestamp", signature, timestamp, payload, now.Add(6 * time.Minute), "more than 5m0s from now"},
+		{"future timestamp", signature, timestamp, payload, now.Add(-6 * time.Minute), "more than 5m0s from now"},
+		{"invalid timestamp", sign("soon", payload), "soon", payload, now, "invalid webhook timestamp"},
+		{"missing signature", "", timestamp, payload, now, "missing webhook signature"},
+		{"missing timestamp", signature, "", payload, now, "missing webhook signature"},
+		{"tampered payload", signature, timestamp, []byte(`[]`), now, "does not match"},
+		{"moved timestamp", signature, strconv.FormatInt(now.Unix()+1, 10), payload, now, "does not match"},
+		{"invalid base64", "not base64!", timestamp, payload, now, "invalid webhook signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.verifyAt(tt.signature, tt.timestamp, tt.payload, tt.now)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("verifyAt() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentMessageID(t *testing.T) {
+	tests := []struct {
+		messageID string
+		want      string
+	}{
+		{"14c5d75ce93.dfd.64b469.filter0001.16648.5515E0B88.0", "14c5d75ce93"},
+		{"W86EgYT6SQKk0lRflfLRsA.filterdrecv-5645d9c87f-6r4lf-1-6480F5E5-16.0", "W86EgYT6SQKk0lRflfLRsA"},
+		{"W86EgYT6SQKk0lRflfLRsA", "W86EgYT6SQKk0lRflfLRsA"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Event{MessageID: tt.messageID}).SentMessageID(); got != tt.want {
+			t.Errorf("SentMessageID() of %q = %q, want %q", tt.messageID, got, tt.want)
+		}
+	}
+}
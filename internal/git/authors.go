@@ -0,0 +1,131 @@
+package git
+
+import (
+	"sort"
+	"strings"
+)
+
+// AuthorMap maps author identities (names or emails) to a canonical author name.
+// Keys are matched case-insensitively. It complements .mailmap, which git
+// already applies through the %aN/%aE placeholders, with aliases managed in
+// the database.
+type AuthorMap map[string]string
+
+// NewAuthorMap builds an AuthorMap from alias -> canonical name pairs
+func NewAuthorMap(aliases map[string]string) AuthorMap {
+	m := make(AuthorMap, len(aliases))
+	for alias, canonical := range aliases {
+		m[strings.ToLower(strings.TrimSpace(alias))] = canonical
+	}
+	return m
+}
+
+// Resolve returns the canonical name for an author. The email is checked
+// first since it is the more specific identity; if neither matches, the
+// name is returned unchanged.
+func (m AuthorMap) Resolve(name, email string) string {
+	if email != "" {
+		if canonical, ok := m[strings.ToLower(email)]; ok {
+			return canonical
+		}
+	}
+	if canonical, ok := m[strings.ToLower(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// Identities returns all aliases that resolve to the given author, including
+// the author itself. Useful for querying git history by every known identity.
+func (m AuthorMap) Identities(author string) []string {
+	identities := []string{author}
+	canonical := m.Resolve(author, "")
+	if canonical != author {
+		identities = append(identities, canonical)
+	}
+	for alias, c := range m {
+		if c == canonical && !strings.EqualFold(alias, author) {
+			identities = append(identities, alias)
+		}
+	}
+	sort.Strings(identities[1:])
+	return identities
+}
+
+// ResolveCommits rewrites commit authors and co-authors to their canonical
+// names in place. Co-authors that resolve to the author or to an earlier
+// co-author are dropped.
+func (m AuthorMap) ResolveCommits(commits []Commit) {
+	if len(m) == 0 {
+		return
+	}
+	for i := range commits {
+		c := &commits[i]
+		c.Author = m.Resolve(c.Author, c.Email)
+		if len(c.CoAuthors) == 0 {
+			continue
+		}
+		resolved := make([]Identity, 0, len(c.CoAuthors))
+		for _, id := range c.CoAuthors {
+			id.Name = m.Resolve(id.Name, id.Email)
+			duplicate := strings.EqualFold(id.Name, c.Author)
+			for _, other := range resolved {
+				duplicate = duplicate || strings.EqualFold(id.Name, other.Name)
+			}
+			if !duplicate {
+				resolved = append(resolved, id)
+			}
+		}
+		c.CoAuthors = resolved
+	}
+}
+
+// ResolveBranchActivity merges per-author counts of feature branch activity
+// under canonical names in place
+func (m AuthorMap) ResolveBranchActivity(activity []BranchActivity) {
+	if len(m) == 0 {
+		return
+	}
+	for i := range activity {
+		merged := make(map[string]int, len(activity[i].AuthorCounts))
+		for author, count := range activity[i].AuthorCounts {
+			merged[m.Resolve(author, "")] += count
+		}
+		authors := make([]string, 0, len(merged))
+		for author := range merged {
+			authors = append(authors, author)
+		}
+		activity[i].Authors = authors
+		activity[i].AuthorCounts = merged
+	}
+}
+
+// ResolveNames maps author names to their canonical names, dropping
+// duplicates while keeping the order of first appearance
+func (m AuthorMap) ResolveNames(names []string) []string {
+	if len(m) == 0 {
+		return names
+	}
+	resolved := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		canonical := m.Resolve(name, "")
+		if !seen[canonical] {
+			seen[canonical] = true
+			resolved = append(resolved, canonical)
+		}
+	}
+	return resolved
+}
+
+// ResolveCounts merges per-author counts under canonical names
+func (m AuthorMap) ResolveCounts(counts map[string]int) map[string]int {
+	if len(m) == 0 || counts == nil {
+		return counts
+	}
+	merged := make(map[string]int, len(counts))
+	for author, count := range counts {
+		merged[m.Resolve(author, "")] += count
+	}
+	return merged
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthorMapResolve(t *testing.T) {
+	m := NewAuthorMap(map[string]string{
+		"jd":                "John Doe",
+		"John@Personal.com": "John Doe",
+	})
+
+	tests := []struct {
+		name   string
+		author string
+		email  string
+		want   string
+	}{
+		{"alias by name", "jd", "", "John Doe"},
+		{"alias by name is case-insensitive", "JD", "", "John Doe"},
+		{"alias by email", "Johnny", "john@personal.com", "John Doe"},
+		{"unknown author unchanged", "Jane Smith", "jane@example.com", "Jane Smith"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Resolve(tt.author, tt.email); got != tt.want {
+				t.Errorf("Resolve(%q, %q) = %q, want %q", tt.author, tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorMapIdentities(t *testing.T) {
+	m := NewAuthorMap(map[string]string{
+		"jd":               "John Doe",
+		"john@example.com": "John Doe",
+	})
+
+	got := m.Identities("John Doe")
+	want := []string{"John Doe", "jd", "john@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Identities() = %v, want %v", got, want)
+	}
+
+	// Querying by an alias includes the canonical name
+	got = m.Identities("jd")
+	want = []string{"jd", "John Doe", "john@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Identities(alias) = %v, want %v", got, want)
+	}
+}
+
+func TestAuthorMapResolveCommits(t *testing.T) {
+	m := NewAuthorMap(map[string]string{"jd": "John Doe"})
+	commits := []Commit{
+		{SHA: "a", Author: "jd"},
+		{SHA: "b", Author: "John Doe"},
+		{SHA: "c", Author: "Jane"},
+	}
+	m.ResolveCommits(commits)
+
+	for i, want := range []string{"John Doe", "John Doe", "Jane"} {
+		if commits[i].Author != want {
+			t.Errorf("commits[%d].Author = %q, want %q", i, commits[i].Author, want)
+		}
+	}
+}
+
+func TestAuthorMapResolveCommitsCoAuthors(t *testing.T) {
+	m := NewAuthorMap(map[string]string{"jd@example.com": "John Doe", "janes": "Jane"})
+	commits := []Commit{{
+		SHA:    "a",
+		Author: "John Doe",
+		CoAuthors: []Identity{
+			{Name: "jd", Email: "jd@example.com"},
+			{Name: "janes"},
+			{Name: "Jane", Email: "jane@example.com"},
+		},
+	}}
+	m.ResolveCommits(commits)
+
+	want := []Identity{{Name: "Jane"}}
+	if !reflect.DeepEqual(commits[0].CoAuthors, want) {
+		t.Errorf("CoAuthors = %v, want %v", commits[0].CoAuthors, want)
+	}
+}
+
+func TestAuthorMapResolveBranchActivity(t *testing.T) {
+	m := NewAuthorMap(map[string]string{"jd": "John Doe"})
+	activity := []BranchActivity{{
+		BranchName:   "feature",
+		CommitCount:  3,
+		Authors:      []string{"jd", "John Doe"},
+		AuthorCounts: map[string]int{"jd": 1, "John Doe": 2},
+	}}
+	m.ResolveBranchActivity(activity)
+
+	if len(activity[0].Authors) != 1 || activity[0].Authors[0] != "John Doe" {
+		t.Errorf("Authors = %v, want [John Doe]", activity[0].Authors)
+	}
+	if activity[0].AuthorCounts["John Doe"] != 3 {
+		t.Errorf("AuthorCounts[John Doe] = %d, want 3", activity[0].AuthorCounts["John Doe"])
+	}
+}
+
+func TestAuthorMapResolveNames(t *testing.T) {
+	m := NewAuthorMap(map[string]string{"jd": "John Doe"})
+
+	got := m.ResolveNames([]string{"jd", "Jane", "John Doe"})
+	if want := []string{"John Doe", "Jane"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveNames() = %v, want %v", got, want)
+	}
+
+	names := []string{"jd"}
+	if got := AuthorMap(nil).ResolveNames(names); !reflect.DeepEqual(got, names) {
+		t.Errorf("ResolveNames() with empty map = %v, want %v", got, names)
+	}
+}
+
+func TestAuthorMapResolveCounts(t *testing.T) {
+	m := NewAuthorMap(map[string]string{"jd": "John Doe"})
+
+	got := m.ResolveCounts(map[string]int{"jd": 2, "John Doe": 1, "Jane": 4})
+	if want := map[string]int{"John Doe": 3, "Jane": 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveCounts() = %v, want %v", got, want)
+	}
+	if got := m.ResolveCounts(nil); got != nil {
+		t.Errorf("ResolveCounts(nil) = %v, want nil", got)
+	}
+}
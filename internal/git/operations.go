@@ -2,8 +2,11 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,10 +15,69 @@ import (
 type Commit struct {
 	SHA     string
 	Author  string
+	Email   string
 	Date    time.Time
 	Message string
+
+	// CoAuthors are the other people credited with the commit: those named in
+	// Co-authored-by trailers and, with first-parent history, the authors of
+	// the merged branch. The commit author is never included.
+	CoAuthors []Identity
+
+	// Merge is set for commits with more than one parent
+	Merge bool
+	// PullRequest is the number of the GitHub pull request the commit merged,
+	// from a merge subject ("Merge pull request #12 from ...") or a squash
+	// merge subject ("Add login page (#12)"), or 0
+	PullRequest int
+}
+
+// Identity is a person as recorded in git: a name and an optional email
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// CoAuthorNames returns the names of the commit's co-authors
+func (c Commit) CoAuthorNames() []string {
+	names := make([]string, len(c.CoAuthors))
+	for i, id := range c.CoAuthors {
+		names[i] = id.Name
+	}
+	return names
+}
+
+// addCoAuthor credits id as a co-author unless it is the commit author or
+// already credited. Identities match on email, or on name if either has no email.
+func (c *Commit) addCoAuthor(id Identity) {
+	same := func(other Identity) bool {
+		if id.Email != "" && other.Email != "" {
+			return strings.EqualFold(id.Email, other.Email)
+		}
+		return strings.EqualFold(id.Name, other.Name)
+	}
+	if id.Name == "" || same(Identity{Name: c.Author, Email: c.Email}) {
+		return
+	}
+	for _, other := range c.CoAuthors {
+		if same(other) {
+			return
+		}
+	}
+	c.CoAuthors = append(c.CoAuthors, id)
 }
 
+// commitLogFormat is the git log format used for commit listings:
+// SHA and parent SHAs, author name and email (each pair separated by \x1f),
+// unix timestamp, subject and Co-authored-by trailer values (separated by
+// \x1d), delimited by the record separator (\x1e). %aN/%aE apply .mailmap,
+// which git reads from HEAD:.mailmap in bare repositories; trailers are not
+// mailmapped.
+const commitLogFormat = "%H%x1f%P%x1e%aN%x1f%aE%x1e%at%x1e%s%x1e%(trailers:key=Co-authored-by,valueonly,separator=%x1d)"
+
+// coAuthorTrailer is the trailer GitHub and other tools use to credit co-authors
+const coAuthorTrailer = "co-authored-by:"
+
 // Clone clones a repository to the specified path
 // Deprecated: Use CloneMirror for bare repositories
 func Clone(url, path, branch string) error {
@@ -101,12 +163,32 @@ func GetBranchSHA(repoPath, branch string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// GetCommitTime returns the committer date of a commit, such as a branch's
+// tip, which is when it landed rather than when it was written
+func GetCommitTime(repoPath, rev string) (time.Time, error) {
+	cmd := exec.Command("git", "-C", repoPath, "log", "-1", "--format=%ct", rev)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return time.Time{}, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
+	}
+
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit time: %w", err)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
 // GetCommitRange retrieves commits between two SHAs
 func GetCommitRange(repoPath, fromSHA, toSHA string) ([]Commit, error) {
-	// Format: SHA|Author|UnixTimestamp|Subject
-	// Using %x1e (record separator) as delimiter to avoid conflicts
-	format := "%H%x1e%an%x1e%at%x1e%s"
+	return GetCommitRangeWithOptions(repoPath, fromSHA, toSHA, LogOptions{})
+}
 
+// GetCommitRangeWithOptions retrieves commits between two SHAs using the given log options
+func GetCommitRangeWithOptions(repoPath, fromSHA, toSHA string, opts LogOptions) ([]Commit, error) {
 	var commitRange string
 	if fromSHA == "" {
 		// All commits up to toSHA
@@ -116,7 +198,11 @@ func GetCommitRange(repoPath, fromSHA, toSHA string) ([]Commit, error) {
 		commitRange = fmt.Sprintf("%s..%s", fromSHA, toSHA)
 	}
 
-	cmd := exec.Command("git", "-C", repoPath, "log", "--format="+format, commitRange)
+	args := []string{"-C", repoPath, "log", "--format=" + commitLogFormat}
+	args = append(args, opts.args()...)
+	args = append(args, commitRange)
+
+	cmd := exec.Command("git", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -125,31 +211,15 @@ func GetCommitRange(repoPath, fromSHA, toSHA string) ([]Commit, error) {
 		return nil, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
 	}
 
-	output := strings.TrimSpace(stdout.String())
-	if output == "" {
-		return []Commit{}, nil
+	commits, err := parseCommitOutput(stdout.String())
+	if err != nil {
+		return nil, err
 	}
 
-	lines := strings.Split(output, "\n")
-	commits := make([]Commit, 0, len(lines))
-
-	for _, line := range lines {
-		parts := strings.Split(line, "\x1e")
-		if len(parts) != 4 {
-			continue
-		}
-
-		var timestamp int64
-		fmt.Sscanf(parts[2], "%d", &timestamp)
-
-		commits = append(commits, Commit{
-			SHA:     parts[0],
-			Author:  parts[1],
-			Date:    time.Unix(timestamp, 0),
-			Message: parts[3],
-		})
+	creditSquashMerges(repoPath, commits)
+	if opts.FirstParent {
+		collapseMergeMessages(repoPath, commits)
 	}
-
 	return commits, nil
 }
 
@@ -173,6 +243,48 @@ var defaultDiffExcludes = []string{
 type DiffResult struct {
 	Diff            string
 	SuppressedLines int
+	Binary          []string // Binary files left out of Diff
+	Generated       []string // Generated or minified files left out of Diff
+}
+
+// DiffOptions controls which files GetCommitDiffWithOptions leaves out
+type DiffOptions struct {
+	// Excludes are extra patterns to leave out besides vendor directories and
+	// lock files, matched like .gitignore patterns (e.g. *.pb.go or dist/)
+	Excludes []string
+}
+
+// pathspecs returns the git pathspecs excluding the default and configured files
+func (o DiffOptions) pathspecs() []string {
+	specs := append([]string(nil), defaultDiffExcludes...)
+	for _, pattern := range o.Excludes {
+		if spec := excludePathspec(pattern); spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// excludePathspec turns a .gitignore-style pattern into an exclude pathspec:
+// a trailing slash matches a directory and its contents, and a pattern
+// without a slash (other than a trailing one) matches at any depth unless it
+// starts with one
+func excludePathspec(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return ""
+	}
+	if anchored, ok := strings.CutPrefix(pattern, "/"); ok {
+		pattern = anchored
+	} else if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	if dir {
+		pattern += "/**"
+	}
+	return ":(exclude,glob)" + pattern
 }
 
 // GetCommitDiff returns the diff for a specific commit with vendor/lock files filtered out.
@@ -180,9 +292,16 @@ type DiffResult struct {
 // The response includes a note showing how many lines were suppressed.
 // Use GetCommitDiffFull if you need the complete unfiltered diff.
 func GetCommitDiff(repoPath, sha string) (*DiffResult, error) {
+	return GetCommitDiffWithOptions(repoPath, sha, DiffOptions{})
+}
+
+// GetCommitDiffWithOptions returns the diff for a commit without vendor
+// directories, lock files and the configured excludes. Binary files and
+// generated or minified files are elided and named in a note instead.
+func GetCommitDiffWithOptions(repoPath, sha string, opts DiffOptions) (*DiffResult, error) {
 	// Get filtered diff (excluding vendor/node_modules/lock files)
 	args := []string{"-C", repoPath, "show", "--format=", sha, "--"}
-	args = append(args, defaultDiffExcludes...)
+	args = append(args, opts.pathspecs()...)
 	filteredCmd := exec.Command("git", args...)
 	var filteredOut, filteredErr bytes.Buffer
 	filteredCmd.Stdout = &filteredOut
@@ -202,26 +321,124 @@ func GetCommitDiff(repoPath, sha string) (*DiffResult, error) {
 		return nil, fmt.Errorf("git show (full) failed: %w: %s", err, fullErr.String())
 	}
 
-	filtered := filteredOut.String()
+	result := &DiffResult{}
+	filtered := elideFiles(filteredOut.String(), result)
 	full := fullOut.String()
 
 	filteredLines := strings.Count(filtered, "\n")
 	fullLines := strings.Count(full, "\n")
-	suppressed := fullLines - filteredLines
+	result.SuppressedLines = fullLines - filteredLines
 
-	result := &DiffResult{
-		SuppressedLines: suppressed,
+	var notes strings.Builder
+	if len(result.Binary) > 0 {
+		fmt.Fprintf(&notes, "[binary files not shown: %s]\n", strings.Join(result.Binary, ", "))
+	}
+	if len(result.Generated) > 0 {
+		fmt.Fprintf(&notes, "[generated or minified files not shown: %s]\n", strings.Join(result.Generated, ", "))
+	}
+	if result.SuppressedLines > 0 {
+		fmt.Fprintf(&notes, "[%d lines suppressed from vendor/node_modules/lock files, excluded paths and the files above]\n",
+			result.SuppressedLines)
 	}
 
-	if suppressed > 0 {
-		result.Diff = fmt.Sprintf("%s\n[%d lines suppressed from vendor/node_modules/lock files]\n",
-			filtered, suppressed)
+	if notes.Len() > 0 {
+		result.Diff = filtered + "\n" + notes.String()
 	} else {
 		result.Diff = filtered
 	}
 	return result, nil
 }
 
+// generatedMarker matches the header comment of generated files: Go's "Code
+// generated ... DO NOT EDIT." and the @generated tag other tools use
+var generatedMarker = regexp.MustCompile(`^\s*(//|#|/?\*|--)\s*(Code generated .* DO NOT EDIT|@generated\b)`)
+
+// minifiedLineLength is the length of an added line beyond which its file is
+// taken to be minified or otherwise machine-written
+const minifiedLineLength = 1000
+
+// elideFiles removes the binary and generated files from a diff, recording
+// their paths in result, and returns what is left
+func elideFiles(diff string, result *DiffResult) string {
+	var kept strings.Builder
+	for _, file := range SplitDiff(diff) {
+		switch {
+		case file.Path == "":
+			kept.WriteString(file.Diff)
+		case isBinaryDiff(file.Diff):
+			result.Binary = append(result.Binary, file.Path)
+		case isGeneratedDiff(file.Diff):
+			result.Generated = append(result.Generated, file.Path)
+		default:
+			kept.WriteString(file.Diff)
+		}
+	}
+	return kept.String()
+}
+
+// FileDiff is one file's part of a diff
+type FileDiff struct {
+	Path string // "" for text before the first file's diff
+	Diff string // From the "diff --git" line up to the next file's
+}
+
+// SplitDiff splits a diff into its files' parts, in order
+func SplitDiff(diff string) []FileDiff {
+	var files []FileDiff
+	for diff != "" {
+		end := len(diff)
+		if i := strings.Index(diff, "\ndiff --git "); i >= 0 {
+			end = i + 1
+		}
+		files = append(files, FileDiff{Path: diffFilePath(diff[:end]), Diff: diff[:end]})
+		diff = diff[end:]
+	}
+	return files
+}
+
+// diffFilePath returns the path of the file a diff section is for, or "" if
+// the section is not a file's diff
+func diffFilePath(section string) string {
+	header, _, _ := strings.Cut(section, "\n")
+	if !strings.HasPrefix(header, "diff --git ") {
+		return ""
+	}
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+len(" b/"):]
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+// isBinaryDiff reports whether a file's diff section is for a binary file
+func isBinaryDiff(section string) bool {
+	for line := range strings.SplitSeq(section, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			return false
+		}
+		if (strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ")) || line == "GIT binary patch" {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedDiff reports whether a file's diff section shows a generated
+// file marker or adds a minified line
+func isGeneratedDiff(section string) bool {
+	for line := range strings.SplitSeq(section, "\n") {
+		if strings.HasPrefix(line, "+++ ") || !(strings.HasPrefix(line, "+") || strings.HasPrefix(line, " ")) {
+			continue
+		}
+		if generatedMarker.MatchString(line[1:]) {
+			return true
+		}
+		if strings.HasPrefix(line, "+") && len(line) > minifiedLineLength {
+			return true
+		}
+	}
+	return false
+}
+
 // GetCommitDiffFull returns the complete diff for a commit without any filtering.
 // Use this when you need to see vendor directories or lock file changes.
 func GetCommitDiffFull(repoPath, sha string) (string, error) {
@@ -237,13 +454,40 @@ func GetCommitDiffFull(repoPath, sha string) (string, error) {
 	return stdout.String(), nil
 }
 
+// LogOptions controls how commits are collected from history
+type LogOptions struct {
+	// FirstParent follows only the first parent of merge commits, so a merged
+	// branch shows up as its merge commit instead of every commit it contained
+	FirstParent bool
+	// NoMerges omits merge commits themselves; the merged commits are still listed
+	NoMerges bool
+}
+
+// args returns the git log arguments for these options
+func (o LogOptions) args() []string {
+	var args []string
+	if o.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if o.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	return args
+}
+
 // GetCommitsSince retrieves commits since a date (optionally until a date)
 // Uses git's native --since and --until flags which handle date parsing
 // (relative dates like "1 week ago" work automatically)
 func GetCommitsSince(repoPath, since, until string) ([]Commit, error) {
-	format := "%H%x1e%an%x1e%at%x1e%s"
+	return GetCommitsSinceWithOptions(repoPath, since, until, LogOptions{})
+}
 
-	args := []string{"-C", repoPath, "log", "--format=" + format}
+// GetCommitsSinceWithOptions retrieves commits since a date using the given log options.
+// With FirstParent, merge commits are collapsed into one logical change: their
+// message is replaced by the merged pull request's title when available.
+func GetCommitsSinceWithOptions(repoPath, since, until string, opts LogOptions) ([]Commit, error) {
+	args := []string{"-C", repoPath, "log", "--format=" + commitLogFormat}
+	args = append(args, opts.args()...)
 	if since != "" {
 		args = append(args, "--since="+since)
 	}
@@ -260,14 +504,205 @@ func GetCommitsSince(repoPath, since, until string) ([]Commit, error) {
 		return nil, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
 	}
 
+	commits, err := parseCommitOutput(stdout.String())
+	if err != nil {
+		return nil, err
+	}
+
+	creditSquashMerges(repoPath, commits)
+	if opts.FirstParent {
+		collapseMergeMessages(repoPath, commits)
+	}
+	return commits, nil
+}
+
+// collapseMergeMessages replaces generic merge subjects ("Merge pull request #12
+// from user/branch") with the pull request title from the merge commit body,
+// so each merge reads as the logical change it represents. The authors of the
+// merged branch are credited as co-authors, since the merge commit itself is
+// usually made by whoever pressed the merge button.
+func collapseMergeMessages(repoPath string, commits []Commit) {
+	for i := range commits {
+		if !isMergeSubject(commits[i].Message) {
+			continue
+		}
+		info, err := GetCommitInfo(repoPath, commits[i].SHA)
+		if err != nil {
+			continue
+		}
+		if title := mergeTitle(info.Message); title != "" {
+			commits[i].Message = title
+		}
+		merged, err := mergedCommits(repoPath, commits[i].SHA)
+		if err != nil {
+			continue
+		}
+		for _, m := range merged {
+			commits[i].addCoAuthor(Identity{Name: m.Author, Email: m.Email})
+			for _, id := range m.CoAuthors {
+				commits[i].addCoAuthor(id)
+			}
+		}
+	}
+}
+
+// mergedCommits returns the non-merge commits a merge commit brought in,
+// i.e. those reachable from it but not from its first parent
+func mergedCommits(repoPath, sha string) ([]Commit, error) {
+	cmd := exec.Command("git", "-C", repoPath, "log", "--format="+commitLogFormat, "--no-merges", sha+"^1.."+sha)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
+	}
+
 	return parseCommitOutput(stdout.String())
 }
 
+// squashMergeSubject matches the " (#123)" suffix GitHub appends to the
+// subject of squash-merged pull requests
+var squashMergeSubject = regexp.MustCompile(`\(#(\d+)\)$`)
+
+// pullRequestMergeSubject matches the subject of GitHub pull request merge commits
+var pullRequestMergeSubject = regexp.MustCompile(`^Merge pull request #(\d+) `)
+
+// pullRequestNumber returns the pull request number in a GitHub merge or
+// squash merge subject, or 0
+func pullRequestNumber(subject string) int {
+	m := pullRequestMergeSubject.FindStringSubmatch(subject)
+	if m == nil {
+		m = squashMergeSubject.FindStringSubmatch(subject)
+	}
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// creditSquashMerges credits every Co-authored-by line in the body of
+// squash-merged pull requests. GitHub lists them after the squashed commit
+// messages, where git does not always recognize them as trailers.
+func creditSquashMerges(repoPath string, commits []Commit) {
+	for i := range commits {
+		if !squashMergeSubject.MatchString(commits[i].Message) {
+			continue
+		}
+		info, err := GetCommitInfo(repoPath, commits[i].SHA)
+		if err != nil {
+			continue
+		}
+		for _, id := range coAuthorLines(info.Message) {
+			commits[i].addCoAuthor(id)
+		}
+	}
+}
+
+// coAuthorLines returns the identities of all Co-authored-by lines in a
+// commit message, wherever they appear
+func coAuthorLines(message string) []Identity {
+	var ids []Identity
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > len(coAuthorTrailer) && strings.EqualFold(line[:len(coAuthorTrailer)], coAuthorTrailer) {
+			ids = append(ids, parseIdentity(line[len(coAuthorTrailer):]))
+		}
+	}
+	return ids
+}
+
+// parseIdentity parses a "Name <email>" trailer value. A value without an
+// email is taken as the name.
+func parseIdentity(value string) Identity {
+	value = strings.TrimSpace(value)
+	open := strings.LastIndex(value, "<")
+	if open < 0 || !strings.HasSuffix(value, ">") {
+		return Identity{Name: value}
+	}
+	id := Identity{
+		Name:  strings.TrimSpace(value[:open]),
+		Email: strings.TrimSpace(value[open+1 : len(value)-1]),
+	}
+	if id.Name == "" {
+		id.Name = id.Email
+	}
+	return id
+}
+
+// isMergeSubject reports whether a subject line is a default git/GitHub merge message
+func isMergeSubject(subject string) bool {
+	return strings.HasPrefix(subject, "Merge pull request ") ||
+		strings.HasPrefix(subject, "Merge branch ") ||
+		strings.HasPrefix(subject, "Merge remote-tracking branch ")
+}
+
+// mergeTitle extracts the first non-empty body line of a merge commit message
+// (GitHub puts the pull request title there). Returns "" if the body is empty.
+func mergeTitle(message string) string {
+	lines := strings.Split(message, "\n")
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
 // GetLastNCommits retrieves the last N commits from a repository
 func GetLastNCommits(repoPath string, n int) ([]Commit, error) {
-	format := "%H%x1e%an%x1e%at%x1e%s"
+	cmd := exec.Command("git", "-C", repoPath, "log", "--format="+commitLogFormat, fmt.Sprintf("-n%d", n))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	cmd := exec.Command("git", "-C", repoPath, "log", "--format="+format, fmt.Sprintf("-n%d", n))
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
+	}
+
+	return parseCommitOutput(stdout.String())
+}
+
+// SearchOptions filters a commit search. Empty fields are not applied.
+type SearchOptions struct {
+	Grep   string // Case-insensitive regular expression matched against commit messages
+	Author string // Matched against author name and email
+	Path   string // Only commits touching this file or directory
+	Since  string // Date or relative date, e.g. "2025-06-01" or "3 months ago"
+	Until  string
+	Limit  int // Maximum number of commits (0 means no limit)
+}
+
+// args returns the git log arguments for these options
+func (o SearchOptions) args() []string {
+	var args []string
+	if o.Grep != "" {
+		args = append(args, "--regexp-ignore-case", "--extended-regexp", "--grep="+o.Grep)
+	}
+	if o.Author != "" {
+		args = append(args, "--author="+o.Author)
+	}
+	if o.Since != "" {
+		args = append(args, "--since="+o.Since)
+	}
+	if o.Until != "" {
+		args = append(args, "--until="+o.Until)
+	}
+	if o.Limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", o.Limit))
+	}
+	if o.Path != "" {
+		args = append(args, "--", o.Path)
+	}
+	return args
+}
+
+// SearchCommits finds commits reachable from HEAD matching the search options,
+// newest first
+func SearchCommits(repoPath string, opts SearchOptions) ([]Commit, error) {
+	args := append([]string{"-C", repoPath, "log", "--format=" + commitLogFormat}, opts.args()...)
+	cmd := exec.Command("git", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -291,88 +726,123 @@ func parseCommitOutput(output string) ([]Commit, error) {
 
 	for _, line := range lines {
 		parts := strings.Split(line, "\x1e")
-		if len(parts) != 4 {
+		if len(parts) != 4 && len(parts) != 5 {
 			continue
 		}
 
 		var timestamp int64
 		fmt.Sscanf(parts[2], "%d", &timestamp)
 
-		commits = append(commits, Commit{
-			SHA:     parts[0],
-			Author:  parts[1],
-			Date:    time.Unix(timestamp, 0),
-			Message: parts[3],
-		})
+		sha, parents, _ := strings.Cut(parts[0], "\x1f")
+		name, email := splitAuthor(parts[1])
+		commit := Commit{
+			SHA:         sha,
+			Author:      name,
+			Email:       email,
+			Date:        time.Unix(timestamp, 0),
+			Message:     parts[3],
+			Merge:       strings.Contains(strings.TrimSpace(parents), " "),
+			PullRequest: pullRequestNumber(parts[3]),
+		}
+		if len(parts) == 5 && parts[4] != "" {
+			for _, value := range strings.Split(parts[4], "\x1d") {
+				commit.addCoAuthor(parseIdentity(value))
+			}
+		}
+		commits = append(commits, commit)
 	}
 
 	return commits, nil
 }
 
+// splitAuthor splits the "name\x1femail" author field; email is empty if absent
+func splitAuthor(field string) (name, email string) {
+	name, email, _ = strings.Cut(field, "\x1f")
+	return name, email
+}
+
 // AuthorStats contains statistics about an author's contributions
 type AuthorStats struct {
 	Name         string
 	TotalCommits int
 	FirstCommit  time.Time
 	LastCommit   time.Time
-}
 
-// GetAuthorStats retrieves statistics about an author in the repository
-func GetAuthorStats(repoPath, authorName string) (*AuthorStats, error) {
-	// Get total commit count for this author
-	countCmd := exec.Command("git", "-C", repoPath, "rev-list", "--count", "--author="+authorName, "HEAD")
-	var countOut, countErr bytes.Buffer
-	countCmd.Stdout = &countOut
-	countCmd.Stderr = &countErr
+	// Commits by others crediting the author in a Co-authored-by trailer
+	CoAuthoredCommits int
+}
 
-	if err := countCmd.Run(); err != nil {
-		return nil, fmt.Errorf("git rev-list count failed: %w: %s", err, countErr.String())
+// GetAuthorStats retrieves statistics about an author in the repository.
+// Additional identities (aliases) are matched as well, so contributions made
+// under other names or emails are counted towards the same author. git matches
+// --author against the mailmapped identity, so .mailmap entries apply too.
+func GetAuthorStats(repoPath, authorName string, aliases ...string) (*AuthorStats, error) {
+	args := []string{"-C", repoPath, "log", "--format=%at", "HEAD"}
+	for _, identity := range append([]string{authorName}, aliases...) {
+		args = append(args, "--author="+regexp.QuoteMeta(identity))
 	}
 
-	var totalCommits int
-	fmt.Sscanf(strings.TrimSpace(countOut.String()), "%d", &totalCommits)
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	if totalCommits == 0 {
-		return &AuthorStats{Name: authorName, TotalCommits: 0}, nil
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
 	}
 
-	// Get first commit date (oldest)
-	firstCmd := exec.Command("git", "-C", repoPath, "log", "--author="+authorName, "--format=%at", "--reverse", "-1")
-	var firstOut, firstErr bytes.Buffer
-	firstCmd.Stdout = &firstOut
-	firstCmd.Stderr = &firstErr
+	coAuthored, err := countCoAuthored(repoPath, append([]string{authorName}, aliases...))
+	if err != nil {
+		return nil, err
+	}
 
-	if err := firstCmd.Run(); err != nil {
-		return nil, fmt.Errorf("git log (first) failed: %w: %s", err, firstErr.String())
+	output := strings.TrimSpace(stdout.String())
+	if output == "" {
+		return &AuthorStats{Name: authorName, TotalCommits: 0, CoAuthoredCommits: coAuthored}, nil
 	}
 
-	var firstTimestamp int64
-	fmt.Sscanf(strings.TrimSpace(firstOut.String()), "%d", &firstTimestamp)
+	// Output is newest first
+	lines := strings.Split(output, "\n")
+	var firstTimestamp, lastTimestamp int64
+	fmt.Sscanf(lines[len(lines)-1], "%d", &firstTimestamp)
+	fmt.Sscanf(lines[0], "%d", &lastTimestamp)
 
-	// Get last commit date (most recent)
-	lastCmd := exec.Command("git", "-C", repoPath, "log", "--author="+authorName, "--format=%at", "-1")
-	var lastOut, lastErr bytes.Buffer
-	lastCmd.Stdout = &lastOut
-	lastCmd.Stderr = &lastErr
+	return &AuthorStats{
+		Name:              authorName,
+		TotalCommits:      len(lines),
+		FirstCommit:       time.Unix(firstTimestamp, 0),
+		LastCommit:        time.Unix(lastTimestamp, 0),
+		CoAuthoredCommits: coAuthored,
+	}, nil
+}
 
-	if err := lastCmd.Run(); err != nil {
-		return nil, fmt.Errorf("git log (last) failed: %w: %s", err, lastErr.String())
+// countCoAuthored counts the commits with a Co-authored-by line naming any of
+// the identities. Unlike --author, trailers are not mailmapped.
+func countCoAuthored(repoPath string, identities []string) (int, error) {
+	args := []string{"-C", repoPath, "log", "--format=%H", "--regexp-ignore-case", "--extended-regexp", "HEAD"}
+	for _, identity := range identities {
+		args = append(args, "--grep=^"+coAuthorTrailer+".*"+regexp.QuoteMeta(identity))
 	}
 
-	var lastTimestamp int64
-	fmt.Sscanf(strings.TrimSpace(lastOut.String()), "%d", &lastTimestamp)
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	return &AuthorStats{
-		Name:         authorName,
-		TotalCommits: totalCommits,
-		FirstCommit:  time.Unix(firstTimestamp, 0),
-		LastCommit:   time.Unix(lastTimestamp, 0),
-	}, nil
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("git log failed: %w: %s", err, stderr.String())
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	if output == "" {
+		return 0, nil
+	}
+	return strings.Count(output, "\n") + 1, nil
 }
 
 // GetCommitInfo retrieves detailed information about a commit
 func GetCommitInfo(repoPath, sha string) (*Commit, error) {
-	format := "%H%x1e%an%x1e%at%x1e%B"
+	format := "%H%x1e%aN%x1f%aE%x1e%at%x1e%B"
 	cmd := exec.Command("git", "-C", repoPath, "show", "--format="+format, "--no-patch", sha)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -390,9 +860,11 @@ func GetCommitInfo(repoPath, sha string) (*Commit, error) {
 	var timestamp int64
 	fmt.Sscanf(parts[2], "%d", &timestamp)
 
+	name, email := splitAuthor(parts[1])
 	return &Commit{
 		SHA:     parts[0],
-		Author:  parts[1],
+		Author:  name,
+		Email:   email,
 		Date:    time.Unix(timestamp, 0),
 		Message: parts[3],
 	}, nil
@@ -421,13 +893,18 @@ func ISOWeekBounds(year, week int) (start, end time.Time) {
 
 // GetCommitsForWeek retrieves commits for a specific ISO week
 func GetCommitsForWeek(repoPath string, year, week int) ([]Commit, error) {
+	return GetCommitsForWeekWithOptions(repoPath, year, week, LogOptions{})
+}
+
+// GetCommitsForWeekWithOptions retrieves commits for a specific ISO week using the given log options
+func GetCommitsForWeekWithOptions(repoPath string, year, week int, opts LogOptions) ([]Commit, error) {
 	start, end := ISOWeekBounds(year, week)
 
 	// Format dates for git --since/--until (ISO 8601 format)
 	sinceStr := start.Format("2006-01-02T15:04:05")
 	untilStr := end.Format("2006-01-02T15:04:05")
 
-	return GetCommitsSince(repoPath, sinceStr, untilStr)
+	return GetCommitsSinceWithOptions(repoPath, sinceStr, untilStr, opts)
 }
 
 // ParseISOWeek parses a string in "2026-W02" format into year and week
@@ -499,6 +976,71 @@ func GetRemoteURL(repoPath string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// GetRemoteDefaultBranch returns the branch a remote's HEAD points to, i.e.
+// its default branch, without cloning it
+func GetRemoteDefaultBranch(url string) (string, error) {
+	cmd := exec.Command("git", "ls-remote", "--symref", url, "HEAD")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git ls-remote failed: %w: %s", err, stderr.String())
+	}
+
+	return parseSymrefHEAD(stdout.String())
+}
+
+// GetRemoteDefaultBranchWithAuth returns the default branch of a remote
+// using an authenticated URL
+func GetRemoteDefaultBranchWithAuth(url, token string) (string, error) {
+	authURL, err := injectToken(url, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authenticated URL: %w", err)
+	}
+
+	branch, err := GetRemoteDefaultBranch(authURL)
+	if err != nil {
+		// git may echo the URL, token included, in its error
+		return "", errors.New(strings.ReplaceAll(err.Error(), token, "***"))
+	}
+	return branch, nil
+}
+
+// parseSymrefHEAD extracts the branch from the output of
+// git ls-remote --symref <url> HEAD, whose first line is
+// "ref: refs/heads/main\tHEAD"
+func parseSymrefHEAD(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		symref, ok := strings.CutPrefix(line, "ref: ")
+		if !ok {
+			continue
+		}
+		ref, name, ok := strings.Cut(symref, "\t")
+		if !ok || name != "HEAD" {
+			continue
+		}
+		if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok && branch != "" {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("remote HEAD does not point to a branch")
+}
+
+// SetHEAD points a bare repository's HEAD at a branch. Analysis reads the
+// history and files of HEAD, so this switches the branch analyzed.
+func SetHEAD(repoPath, branch string) error {
+	cmd := exec.Command("git", "-C", repoPath, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git symbolic-ref HEAD failed: %w: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
 // GetFileContent retrieves the content of a file from HEAD in a bare repository
 func GetFileContent(repoPath, filepath string) (string, error) {
 	cmd := exec.Command("git", "-C", repoPath, "show", "HEAD:"+filepath)
@@ -513,6 +1055,37 @@ func GetFileContent(repoPath, filepath string) (string, error) {
 	return stdout.String(), nil
 }
 
+// ListFiles lists the entries of a directory at HEAD. Directories are
+// returned with a trailing slash. An empty dir lists the repository root.
+func ListFiles(repoPath, dir string) ([]string, error) {
+	treeish := "HEAD"
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		treeish += ":" + dir
+	}
+	cmd := exec.Command("git", "-C", repoPath, "ls-tree", treeish)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git ls-tree %s failed: %w: %s", treeish, err, stderr.String())
+	}
+
+	// Each line is "<mode> <type> <object>\t<name>"
+	var entries []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if fields := strings.Fields(meta); len(fields) >= 2 && fields[1] == "tree" {
+			name += "/"
+		}
+		entries = append(entries, name)
+	}
+	return entries, nil
+}
+
 // IsBareRepo checks if a repository is a bare repository
 func IsBareRepo(repoPath string) bool {
 	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--is-bare-repository")
@@ -735,11 +1308,11 @@ func GetFeatureBranchActivity(repoPath, mainBranch string, year, week int) ([]Br
 		}
 
 		// Get commits on this branch that aren't on main, within the date range
-		// Format: author name only
+		// Format: author name only (mailmap-aware)
 		logCmd := exec.Command("git", "-C", repoPath, "log",
 			branch, "--not", mainBranch,
 			"--since="+sinceStr, "--until="+untilStr,
-			"--format=%an")
+			"--format=%aN")
 		var logOut, logErr bytes.Buffer
 		logCmd.Stdout = &logOut
 		logCmd.Stderr = &logErr
@@ -784,3 +1357,137 @@ func GetFeatureBranchActivity(repoPath, mainBranch string, year, week int) ([]Br
 
 	return activities, nil
 }
+
+// ChurnStats summarizes line changes over a set of commits
+type ChurnStats struct {
+	Additions    int
+	Deletions    int
+	FilesChanged int
+}
+
+// GetChurnForWeek returns the lines added and deleted during a specific ISO week.
+// Merge commits are not counted (git log shows no diff for them by default),
+// except with FirstParent where each merge is diffed against its first parent
+// so merged branches are counted once. Binary files are skipped since they
+// have no line counts.
+func GetChurnForWeek(repoPath string, year, week int, opts LogOptions) (*ChurnStats, error) {
+	start, end := ISOWeekBounds(year, week)
+	sinceStr := start.Format("2006-01-02T15:04:05")
+	untilStr := end.Format("2006-01-02T15:04:05")
+
+	args := []string{"-C", repoPath, "log", "--numstat", "--format=",
+		"--since=" + sinceStr, "--until=" + untilStr}
+	args = append(args, opts.args()...)
+	if opts.FirstParent {
+		args = append(args, "--diff-merges=first-parent")
+	}
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git log --numstat failed: %w: %s", err, stderr.String())
+	}
+
+	return parseNumstat(stdout.String()), nil
+}
+
+// MergeStats separates merged pull requests and merge commits from commits
+// made directly on the branch
+type MergeStats struct {
+	PullRequests  int // Merged pull requests, as merge commits or squash merges
+	MergeCommits  int // Merge commits, whether or not they merged a pull request
+	DirectCommits int // Commits that are neither merges nor squash-merged pull requests
+}
+
+// CountMerges classifies commits as merged pull requests, merge commits and
+// direct commits. Without first-parent history the commits of merged branches
+// are listed too and count as direct commits.
+func CountMerges(commits []Commit) MergeStats {
+	var stats MergeStats
+	for _, c := range commits {
+		if c.PullRequest > 0 {
+			stats.PullRequests++
+		}
+		if c.Merge {
+			stats.MergeCommits++
+		}
+		if !c.Merge && c.PullRequest == 0 {
+			stats.DirectCommits++
+		}
+	}
+	return stats
+}
+
+// GetPullRequestLines returns the lines added plus deleted by the pull
+// requests merged in commits, diffing each merge against its first parent
+func GetPullRequestLines(repoPath string, commits []Commit) (int, error) {
+	lines := 0
+	for _, c := range commits {
+		if c.PullRequest == 0 {
+			continue
+		}
+		cmd := exec.Command("git", "-C", repoPath, "show", "--numstat", "--format=", "--diff-merges=first-parent", c.SHA)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return 0, fmt.Errorf("git show --numstat failed: %w: %s", err, stderr.String())
+		}
+		stats := parseNumstat(stdout.String())
+		lines += stats.Additions + stats.Deletions
+	}
+	return lines, nil
+}
+
+// FileStat is the lines a commit changed in one file
+type FileStat struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// GetCommitDiffStat returns the files a commit changed with their line
+// counts, in path order. Merge commits are diffed against their first parent.
+func GetCommitDiffStat(repoPath, sha string) ([]FileStat, error) {
+	cmd := exec.Command("git", "-C", repoPath, "show", "--numstat", "--format=", "--diff-merges=first-parent", sha)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git show --numstat failed: %w: %s", err, stderr.String())
+	}
+	return parseNumstatFiles(stdout.String()), nil
+}
+
+// parseNumstat sums git --numstat output lines ("added<TAB>deleted<TAB>path")
+func parseNumstat(output string) *ChurnStats {
+	stats := &ChurnStats{}
+	for _, file := range parseNumstatFiles(output) {
+		stats.FilesChanged++
+		stats.Additions += file.Additions
+		stats.Deletions += file.Deletions
+	}
+	return stats
+}
+
+// parseNumstatFiles parses git --numstat output lines ("added<TAB>deleted<TAB>path")
+func parseNumstatFiles(output string) []FileStat {
+	var files []FileStat
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		// Binary files are reported as "-\t-\tpath"
+		file := FileStat{Path: fields[2], Binary: fields[0] == "-" && fields[1] == "-"}
+		file.Additions, _ = strconv.Atoi(fields[0])
+		file.Deletions, _ = strconv.Atoi(fields[1])
+		files = append(files, file)
+	}
+	return files
+}
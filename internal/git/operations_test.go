@@ -1,6 +1,8 @@
 package git
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -363,6 +365,18 @@ def456` + "\x1e" + `Jane Smith` + "\x1e" + `1700001000` + "\x1e" + `Second commi
 				Message: "First commit",
 			},
 		},
+		{
+			name:    "author with email",
+			input:   "abc123\x1eJohn Doe\x1fjohn@example.com\x1e1700000000\x1eInitial commit",
+			wantLen: 1,
+			wantFirst: &Commit{
+				SHA:     "abc123",
+				Author:  "John Doe",
+				Email:   "john@example.com",
+				Date:    time.Unix(1700000000, 0),
+				Message: "Initial commit",
+			},
+		},
 		{
 			name:    "malformed line (too few parts)",
 			input:   "abc123\x1eJohn Doe\x1e1700000000",
@@ -395,6 +409,9 @@ def456` + "\x1e" + `Jane` + "\x1e" + `1700001000` + "\x1e" + `Another valid`,
 				if commits[0].Author != tt.wantFirst.Author {
 					t.Errorf("first commit Author = %q, want %q", commits[0].Author, tt.wantFirst.Author)
 				}
+				if commits[0].Email != tt.wantFirst.Email {
+					t.Errorf("first commit Email = %q, want %q", commits[0].Email, tt.wantFirst.Email)
+				}
 				if !commits[0].Date.Equal(tt.wantFirst.Date) {
 					t.Errorf("first commit Date = %v, want %v", commits[0].Date, tt.wantFirst.Date)
 				}
@@ -466,6 +483,49 @@ func TestInjectToken(t *testing.T) {
 	}
 }
 
+func TestParseSymrefHEAD(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "main",
+			output: "ref: refs/heads/main\tHEAD\n3f4e2a1b\tHEAD\n",
+			want:   "main",
+		},
+		{
+			name:   "branch with slash",
+			output: "ref: refs/heads/release/2.x\tHEAD\n3f4e2a1b\tHEAD\n",
+			want:   "release/2.x",
+		},
+		{
+			name:    "detached HEAD",
+			output:  "3f4e2a1b\tHEAD\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			output:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSymrefHEAD(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSymrefHEAD() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseSymrefHEAD() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCurrentISOWeek(t *testing.T) {
 	// Test that CurrentISOWeek returns the same result as time.Now().ISOWeek()
 	year, week := CurrentISOWeek()
@@ -513,3 +573,258 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParseNumstat(t *testing.T) {
+	output := "10\t2\tmain.go\n" +
+		"-\t-\tlogo.png\n" +
+		"\n" +
+		"3\t0\tREADME.md\n"
+
+	stats := parseNumstat(output)
+	if stats.Additions != 13 {
+		t.Errorf("parseNumstat() additions = %d, want 13", stats.Additions)
+	}
+	if stats.Deletions != 2 {
+		t.Errorf("parseNumstat() deletions = %d, want 2", stats.Deletions)
+	}
+	if stats.FilesChanged != 3 {
+		t.Errorf("parseNumstat() files = %d, want 3", stats.FilesChanged)
+	}
+}
+
+func TestLogOptionsArgs(t *testing.T) {
+	tests := []struct {
+		opts LogOptions
+		want string
+	}{
+		{LogOptions{}, ""},
+		{LogOptions{FirstParent: true}, "--first-parent"},
+		{LogOptions{NoMerges: true}, "--no-merges"},
+		{LogOptions{FirstParent: true, NoMerges: true}, "--first-parent --no-merges"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.opts.args(), " "); got != tt.want {
+			t.Errorf("LogOptions%+v.args() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTitle(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Merge pull request #12 from user/feature\n\nAdd login page", "Add login page"},
+		{"Merge branch 'main' into feature", ""},
+		{"Merge pull request #3 from a/b\n\n  Fix crash  \nmore details", "Fix crash"},
+	}
+	for _, tt := range tests {
+		if got := mergeTitle(tt.message); got != tt.want {
+			t.Errorf("mergeTitle(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+
+	if !isMergeSubject("Merge pull request #12 from user/feature") {
+		t.Error("isMergeSubject() should match GitHub merge subjects")
+	}
+	if isMergeSubject("Merge sort implementation") {
+		t.Error("isMergeSubject() should not match ordinary subjects")
+	}
+}
+
+func TestParseCommitOutputCoAuthors(t *testing.T) {
+	input := "abc123\x1eJohn Doe\x1fjohn@example.com\x1e1700000000\x1ePair on login\x1e" +
+		"Jane Smith <jane@example.com>\x1dJohn Doe <JOHN@example.com>\x1dJane Smith <jane@example.com>\x1dBob\n" +
+		"def456\x1eJane Smith\x1fjane@example.com\x1e1700001000\x1eSolo work\x1e"
+	commits, err := parseCommitOutput(input)
+	if err != nil {
+		t.Fatalf("parseCommitOutput() error = %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("parseCommitOutput() returned %d commits, want 2", len(commits))
+	}
+
+	// The author and repeated trailers are not credited again
+	want := []Identity{{Name: "Jane Smith", Email: "jane@example.com"}, {Name: "Bob"}}
+	if !reflect.DeepEqual(commits[0].CoAuthors, want) {
+		t.Errorf("CoAuthors = %v, want %v", commits[0].CoAuthors, want)
+	}
+	if got := commits[0].CoAuthorNames(); !reflect.DeepEqual(got, []string{"Jane Smith", "Bob"}) {
+		t.Errorf("CoAuthorNames() = %v", got)
+	}
+	if commits[1].CoAuthors != nil {
+		t.Errorf("CoAuthors = %v, want none", commits[1].CoAuthors)
+	}
+}
+
+func TestCoAuthorLines(t *testing.T) {
+	// A GitHub squash merge body: the co-authors follow the squashed commit
+	// messages in the same paragraph, so git does not parse them as trailers
+	message := "Add login page (#42)\n\n" +
+		"* Add form\n" +
+		"* Fix review comments\n" +
+		"Co-authored-by: Jane Smith <jane@example.com>\n" +
+		"co-authored-by: <bot@example.com>\n" +
+		"Co-authored-by-line in prose is not a trailer\n"
+
+	want := []Identity{
+		{Name: "Jane Smith", Email: "jane@example.com"},
+		{Name: "bot@example.com", Email: "bot@example.com"},
+	}
+	if got := coAuthorLines(message); !reflect.DeepEqual(got, want) {
+		t.Errorf("coAuthorLines() = %v, want %v", got, want)
+	}
+
+	for subject, want := range map[string]bool{
+		"Add login page (#42)":      true,
+		"Fix (#42) in parser":       false,
+		"Bump version (release #2)": false,
+	} {
+		if got := squashMergeSubject.MatchString(subject); got != want {
+			t.Errorf("squashMergeSubject matches %q = %v, want %v", subject, got, want)
+		}
+	}
+}
+
+func TestParseCommitOutputMerges(t *testing.T) {
+	input := "m1\x1fp1 p2\x1eJane\x1e1700000000\x1eMerge pull request #12 from jane/login\x1e\n" +
+		"s1\x1fp1\x1eJohn\x1e1700000000\x1eAdd search (#13)\x1e\n" +
+		"m2\x1fp1 p3\x1eJohn\x1e1700000000\x1eMerge branch 'main' into feature\x1e\n" +
+		"d1\x1fp1\x1eJohn\x1e1700000000\x1eFix typo\x1e"
+	commits, err := parseCommitOutput(input)
+	if err != nil {
+		t.Fatalf("parseCommitOutput() error = %v", err)
+	}
+
+	want := []struct {
+		sha         string
+		merge       bool
+		pullRequest int
+	}{
+		{"m1", true, 12},
+		{"s1", false, 13},
+		{"m2", true, 0},
+		{"d1", false, 0},
+	}
+	if len(commits) != len(want) {
+		t.Fatalf("parseCommitOutput() returned %d commits, want %d", len(commits), len(want))
+	}
+	for i, w := range want {
+		c := commits[i]
+		if c.SHA != w.sha || c.Merge != w.merge || c.PullRequest != w.pullRequest {
+			t.Errorf("commit %d = {%s merge=%v pr=%d}, want {%s merge=%v pr=%d}",
+				i, c.SHA, c.Merge, c.PullRequest, w.sha, w.merge, w.pullRequest)
+		}
+	}
+
+	stats := CountMerges(commits)
+	if stats != (MergeStats{PullRequests: 2, MergeCommits: 2, DirectCommits: 1}) {
+		t.Errorf("CountMerges() = %+v", stats)
+	}
+}
+
+func TestSearchOptionsArgs(t *testing.T) {
+	tests := []struct {
+		opts SearchOptions
+		want string
+	}{
+		{SearchOptions{}, ""},
+		{SearchOptions{Grep: "goose|migrat"}, "--regexp-ignore-case --extended-regexp --grep=goose|migrat"},
+		{SearchOptions{Author: "jane", Limit: 20}, "--author=jane -n20"},
+		{SearchOptions{Since: "3 months ago", Until: "2026-01-01"}, "--since=3 months ago --until=2026-01-01"},
+		{SearchOptions{Path: "internal/db", Limit: 5}, "-n5 -- internal/db"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.opts.args(), " "); got != tt.want {
+			t.Errorf("SearchOptions%+v.args() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestExcludePathspec(t *testing.T) {
+	tests := []struct {
+		pattern, want string
+	}{
+		{"*.pb.go", ":(exclude,glob)**/*.pb.go"},
+		{"dist/", ":(exclude,glob)**/dist/**"},
+		{"/dist/", ":(exclude,glob)dist/**"},
+		{"web/static/*.min.js", ":(exclude,glob)web/static/*.min.js"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := excludePathspec(tt.pattern); got != tt.want {
+			t.Errorf("excludePathspec(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+
+	specs := DiffOptions{Excludes: []string{"*.pb.go"}}.pathspecs()
+	if len(specs) != len(defaultDiffExcludes)+1 || specs[len(specs)-1] != ":(exclude,glob)**/*.pb.go" {
+		t.Errorf("pathspecs() = %v, want the defaults followed by the configured exclude", specs)
+	}
+}
+
+func TestElideFiles(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n" +
+		"diff --git a/logo.png b/logo.png\n" +
+		"index 1111111..2222222 100644\nBinary files a/logo.png and b/logo.png differ\n" +
+		"diff --git a/api/api.pb.go b/api/api.pb.go\n" +
+		"--- /dev/null\n+++ b/api/api.pb.go\n@@ -0,0 +1,2 @@\n+// Code generated by protoc-gen-go. DO NOT EDIT.\n+package api\n" +
+		"diff --git a/app.min.js b/app.min.js\n" +
+		"--- a/app.min.js\n+++ b/app.min.js\n@@ -1 +1 @@\n+" + strings.Repeat("x", minifiedLineLength) + "\n" +
+		"diff --git a/gen.go b/gen.go\n" +
+		"--- a/gen.go\n+++ b/gen.go\n@@ -1 +1 @@\n+var marker = \"Code generated by tool. DO NOT EDIT.\"\n"
+
+	result := &DiffResult{}
+	kept := elideFiles(diff, result)
+
+	if !strings.Contains(kept, "diff --git a/main.go b/main.go") || !strings.Contains(kept, "diff --git a/gen.go b/gen.go") {
+		t.Errorf("elideFiles() should keep regular files, got:\n%s", kept)
+	}
+	if strings.Contains(kept, "logo.png") || strings.Contains(kept, "api.pb.go") || strings.Contains(kept, "app.min.js") {
+		t.Errorf("elideFiles() should drop binary and generated files, got:\n%s", kept)
+	}
+	if len(result.Binary) != 1 || result.Binary[0] != "logo.png" {
+		t.Errorf("Binary = %v, want [logo.png]", result.Binary)
+	}
+	if len(result.Generated) != 2 || result.Generated[0] != "api/api.pb.go" || result.Generated[1] != "app.min.js" {
+		t.Errorf("Generated = %v, want [api/api.pb.go app.min.js]", result.Generated)
+	}
+}
+
+func TestParseNumstatFiles(t *testing.T) {
+	output := "10\t2\tmain.go\n-\t-\tlogo.png\n\n0\t5\tdocs/old.md\n"
+	want := []FileStat{
+		{Path: "main.go", Additions: 10, Deletions: 2},
+		{Path: "logo.png", Binary: true},
+		{Path: "docs/old.md", Deletions: 5},
+	}
+	got := parseNumstatFiles(output)
+	if len(got) != len(want) {
+		t.Fatalf("parseNumstatFiles() returned %d files, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitDiff(t *testing.T) {
+	first := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n"
+	second := "diff --git a/docs/b c.md b/docs/b c.md\n@@ -1 +1 @@\n+z\n"
+
+	files := SplitDiff(first + second)
+	if len(files) != 2 {
+		t.Fatalf("SplitDiff() returned %d files, want 2", len(files))
+	}
+	if files[0].Path != "a.go" || files[0].Diff != first {
+		t.Errorf("first file = %+v, want a.go with its whole diff", files[0])
+	}
+	if files[1].Path != "docs/b c.md" || files[1].Diff != second {
+		t.Errorf("second file = %+v, want docs/b c.md with its whole diff", files[1])
+	}
+	if files := SplitDiff(""); len(files) != 0 {
+		t.Errorf("SplitDiff(\"\") = %v, want no files", files)
+	}
+}
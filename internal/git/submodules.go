@@ -0,0 +1,156 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// gitlinkMode is the tree entry mode of a submodule pointer
+const gitlinkMode = "160000"
+
+// SubmoduleUpdate is a submodule whose pinned commit changed in a range of
+// commits, from the commit pinned before the first change to the one pinned
+// after the last
+type SubmoduleUpdate struct {
+	Path  string
+	URL   string // As listed in .gitmodules, possibly relative; "" if not listed
+	From  string // "" if the submodule was added
+	To    string // "" if the submodule was removed
+	Bumps int    // Commits that changed the pointer
+}
+
+// GetSubmoduleUpdates returns the submodules whose pointers commits moved,
+// sorted by path. Commits are newest first, as git log lists them. Merge
+// commits are diffed against their first parent with FirstParent, and not
+// at all otherwise, matching GetChurnForWeek.
+func GetSubmoduleUpdates(repoPath string, commits []Commit, opts LogOptions) ([]SubmoduleUpdate, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	// Oldest first, so the pointer changes are replayed in order
+	var stdin strings.Builder
+	for i := len(commits) - 1; i >= 0; i-- {
+		stdin.WriteString(commits[i].SHA)
+		stdin.WriteString("\n")
+	}
+	args := []string{"-C", repoPath, "diff-tree", "--stdin", "-r", "--root", "--no-abbrev"}
+	if opts.FirstParent {
+		args = append(args, "--diff-merges=first-parent")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(stdin.String())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git diff-tree failed: %w: %s", err, stderr.String())
+	}
+
+	updates := parseSubmoduleChanges(stdout.String())
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	urls, err := GetSubmoduleURLs(repoPath, commits[0].SHA)
+	if err != nil {
+		return nil, err
+	}
+	for i := range updates {
+		updates[i].URL = urls[updates[i].Path]
+	}
+	return updates, nil
+}
+
+// parseSubmoduleChanges folds the submodule pointer changes in git
+// diff-tree --raw output, oldest commit first, into one update per path
+func parseSubmoduleChanges(output string) []SubmoduleUpdate {
+	byPath := make(map[string]*SubmoduleUpdate)
+	for line := range strings.SplitSeq(output, "\n") {
+		// ":<old mode> <new mode> <old sha> <new sha> <status>\t<path>"
+		meta, path, ok := strings.Cut(line, "\t")
+		if !ok || !strings.HasPrefix(meta, ":") {
+			continue
+		}
+		fields := strings.Fields(meta[1:])
+		if len(fields) != 5 || (fields[0] != gitlinkMode && fields[1] != gitlinkMode) {
+			continue
+		}
+		from, to := fields[2], fields[3]
+		if fields[0] != gitlinkMode {
+			from = ""
+		}
+		if fields[1] != gitlinkMode {
+			to = ""
+		}
+
+		update, seen := byPath[path]
+		if !seen {
+			update = &SubmoduleUpdate{Path: path, From: from}
+			byPath[path] = update
+		}
+		update.To = to
+		update.Bumps++
+	}
+
+	updates := make([]SubmoduleUpdate, 0, len(byPath))
+	for _, update := range byPath {
+		updates = append(updates, *update)
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Path < updates[j].Path })
+	return updates
+}
+
+// GetSubmoduleURLs returns the URL of each submodule listed in .gitmodules
+// at rev, by path. It returns an empty map if rev has no .gitmodules.
+func GetSubmoduleURLs(repoPath, rev string) (map[string]string, error) {
+	cmd := exec.Command("git", "-C", repoPath, "config", "--blob", rev+":.gitmodules", "--get-regexp", `^submodule\..*\.(path|url)$`)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Exit status 1 means no .gitmodules or no submodules in it
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("git config failed: %w: %s", err, stderr.String())
+	}
+	return parseGitmodules(stdout.String()), nil
+}
+
+// parseGitmodules maps submodule paths to URLs from git config
+// --get-regexp output ("submodule.<name>.<key> <value>" per line)
+func parseGitmodules(output string) map[string]string {
+	paths := make(map[string]string) // By submodule name
+	urls := make(map[string]string)
+	for line := range strings.SplitSeq(output, "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		// Names may contain dots, so the key is everything after the last one
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			continue
+		}
+		name := strings.TrimPrefix(key[:dot], "submodule.")
+		switch key[dot+1:] {
+		case "path":
+			paths[name] = value
+		case "url":
+			urls[name] = value
+		}
+	}
+
+	result := make(map[string]string, len(paths))
+	for name, path := range paths {
+		result[path] = urls[name]
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSubmoduleChanges(t *testing.T) {
+	// Oldest commit first: lib/a is added then bumped twice, lib/b bumped
+	// once and lib/c removed; the regular file change is ignored
+	output := "c1\n" +
+		":000000 160000 0000000000000000000000000000000000000000 aaaa1 A\tlib/a\n" +
+		"c2\n" +
+		":100644 100644 f1 f2 M\tREADME.md\n" +
+		":160000 160000 aaaa1 aaaa2 M\tlib/a\n" +
+		":160000 160000 bbbb1 bbbb2 M\tlib/b\n" +
+		"c3\n" +
+		":160000 160000 aaaa2 aaaa3 M\tlib/a\n" +
+		":160000 000000 cccc1 0000000000000000000000000000000000000000 D\tlib/c\n"
+
+	got := parseSubmoduleChanges(output)
+	want := []SubmoduleUpdate{
+		{Path: "lib/a", From: "", To: "aaaa3", Bumps: 3},
+		{Path: "lib/b", From: "bbbb1", To: "bbbb2", Bumps: 1},
+		{Path: "lib/c", From: "cccc1", To: "", Bumps: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSubmoduleChanges() = %+v, want %+v", got, want)
+	}
+
+	if got := parseSubmoduleChanges("c1\n:100644 100644 f1 f2 M\tmain.go\n"); len(got) != 0 {
+		t.Errorf("parseSubmoduleChanges() without submodules = %+v, want none", got)
+	}
+}
+
+func TestParseGitmodules(t *testing.T) {
+	output := "submodule.lib/a.path lib/a\n" +
+		"submodule.lib/a.url ../a.git\n" +
+		"submodule.v1.2.path vendor/b\n" +
+		"submodule.v1.2.url https://example.com/b.git\n"
+
+	got := parseGitmodules(output)
+	want := map[string]string{
+		"lib/a":    "../a.git",
+		"vendor/b": "https://example.com/b.git",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitmodules() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag is a tag in a repository, such as a release
+type Tag struct {
+	Name    string    `json:"name"`
+	SHA     string    `json:"sha"`               // The tagged commit
+	Date    time.Time `json:"date"`              // When the tag was made, or the commit's date for lightweight tags
+	Subject string    `json:"subject,omitempty"` // First line of an annotated tag's message
+}
+
+// tagFormat prints a tag per line with fields separated by \x1f: name,
+// tagged commit (peeled for annotated tags), creation time, object type and
+// subject
+const tagFormat = "%(refname:short)%1f%(if)%(*objectname)%(then)%(*objectname)%(else)%(objectname)%(end)%1f" +
+	"%(creatordate:unix)%1f%(objecttype)%1f%(contents:subject)"
+
+// GetTags returns the tags made between since and until inclusive, newest
+// first. A zero since or until leaves that end open.
+func GetTags(repoPath string, since, until time.Time) ([]Tag, error) {
+	cmd := exec.Command("git", "-C", repoPath, "for-each-ref", "refs/tags", "--sort=-creatordate", "--format="+tagFormat)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w: %s", err, stderr.String())
+	}
+
+	tags, err := parseTagOutput(stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	var result []Tag
+	for _, tag := range tags {
+		if (!since.IsZero() && tag.Date.Before(since)) || (!until.IsZero() && tag.Date.After(until)) {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// parseTagOutput parses the output of git for-each-ref with tagFormat
+func parseTagOutput(output string) ([]Tag, error) {
+	var tags []Tag
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\x1f", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected tag line: %q", line)
+		}
+		unix, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date of tag %s: %w", fields[0], err)
+		}
+		tag := Tag{Name: fields[0], SHA: fields[1], Date: time.Unix(unix, 0)}
+		// Lightweight tags point at the commit, whose subject is not the tag's
+		if fields[3] == "tag" {
+			tag.Subject = fields[4]
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
@@ -0,0 +1,35 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTagOutput(t *testing.T) {
+	output := "v1.1.0\x1fabc123\x1f1704790800\x1ftag\x1fRelease 1.1: faster imports\n" +
+		"v1.0.1\x1fdef456\x1f1704445200\x1fcommit\x1fFix crash on empty config\n"
+
+	tags, err := parseTagOutput(output)
+	if err != nil {
+		t.Fatalf("parseTagOutput() error = %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("parseTagOutput() returned %d tags, want 2", len(tags))
+	}
+
+	want := Tag{Name: "v1.1.0", SHA: "abc123", Date: time.Unix(1704790800, 0), Subject: "Release 1.1: faster imports"}
+	if tags[0] != want {
+		t.Errorf("annotated tag = %+v, want %+v", tags[0], want)
+	}
+	// A lightweight tag has no message of its own
+	if tags[1].Name != "v1.0.1" || tags[1].Subject != "" {
+		t.Errorf("lightweight tag = %+v, want v1.0.1 without subject", tags[1])
+	}
+
+	if tags, err := parseTagOutput(""); err != nil || len(tags) != 0 {
+		t.Errorf("parseTagOutput(\"\") = %v, %v, want no tags", tags, err)
+	}
+	if _, err := parseTagOutput("v1\x1fabc\x1fnot-a-date\x1ftag\x1f\n"); err == nil {
+		t.Error("expected error for invalid date, got nil")
+	}
+}
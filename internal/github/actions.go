@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxRunPages caps how many pages of workflow runs are fetched for one
+// period; at 100 runs per page this is more than any week needs
+const maxRunPages = 10
+
+// WorkflowRun is a completed GitHub Actions workflow run. Only the latest
+// attempt of a run is listed; RunAttempt counts its re-runs.
+type WorkflowRun struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	Event      string    `json:"event"`
+	Conclusion string    `json:"conclusion"` // success, failure, cancelled, skipped, timed_out, ...
+	RunAttempt int       `json:"run_attempt"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// workflowRunsResponse is the response body of the list workflow runs API
+type workflowRunsResponse struct {
+	TotalCount   int           `json:"total_count"`
+	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+}
+
+// ListWorkflowRuns returns the completed workflow runs of a repository created
+// between since and until inclusive, optionally only those on branch. A
+// repository the App is not installed on fails with a not found error.
+func (c *Client) ListWorkflowRuns(ctx context.Context, owner, repo, branch string, since, until time.Time) ([]WorkflowRun, error) {
+	query := url.Values{}
+	query.Set("status", "completed")
+	query.Set("per_page", "100")
+	query.Set("created", since.UTC().Format(time.RFC3339)+".."+until.UTC().Format(time.RFC3339))
+	if branch != "" {
+		query.Set("branch", branch)
+	}
+
+	var runs []WorkflowRun
+	for page := 1; page <= maxRunPages; page++ {
+		query.Set("page", fmt.Sprint(page))
+		endpoint := fmt.Sprintf("%s/repos/%s/%s/actions/runs?%s", apiURL, url.PathEscape(owner), url.PathEscape(repo), query.Encode())
+
+		var body workflowRunsResponse
+		if err := c.get(ctx, endpoint, &body); err != nil {
+			return nil, err
+		}
+		runs = append(runs, body.WorkflowRuns...)
+		if len(body.WorkflowRuns) == 0 || len(runs) >= body.TotalCount {
+			break
+		}
+	}
+	return runs, nil
+}
+
+// ParseRepoURL returns the owner and name of the repository at a GitHub URL
+// Input: https://github.com/owner/repo.git
+// Output: owner, repo
+func ParseRepoURL(repoURL string) (owner, repo string, err error) {
+	if !IsGitHubURL(repoURL) {
+		return "", "", fmt.Errorf("not a GitHub URL: %s", repoURL)
+	}
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("URL does not name a repository: %s", repoURL)
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+}
+
+// CIHealth summarizes the outcomes of a period's workflow runs
+type CIHealth struct {
+	Runs      int              `json:"runs"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"` // Failures and time-outs
+	Workflows []WorkflowHealth `json:"workflows,omitempty"`
+}
+
+// WorkflowHealth summarizes the runs of one workflow
+type WorkflowHealth struct {
+	Name      string `json:"name"`
+	Runs      int    `json:"runs"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// Commits on which the workflow both failed and passed, including runs
+	// that passed after a re-run
+	Flaky int `json:"flaky,omitempty"`
+}
+
+// SuccessRate returns the share of decided runs (passed or failed) that
+// passed, from 0 to 1, or -1 if no run was decided. Cancelled and skipped
+// runs are not counted.
+func (h CIHealth) SuccessRate() float64 {
+	if h.Succeeded+h.Failed == 0 {
+		return -1
+	}
+	return float64(h.Succeeded) / float64(h.Succeeded+h.Failed)
+}
+
+// FlakyWorkflows returns the workflows with flaky commits, most flaky first
+func (h CIHealth) FlakyWorkflows() []WorkflowHealth {
+	var flaky []WorkflowHealth
+	for _, w := range h.Workflows {
+		if w.Flaky > 0 {
+			flaky = append(flaky, w)
+		}
+	}
+	sort.SliceStable(flaky, func(i, j int) bool { return flaky[i].Flaky > flaky[j].Flaky })
+	return flaky
+}
+
+// SummarizeRuns computes the CI health of a set of workflow runs. Workflows
+// are sorted by name.
+func SummarizeRuns(runs []WorkflowRun) CIHealth {
+	type outcomes struct{ passed, failed bool }
+	byWorkflow := make(map[string]*WorkflowHealth)
+	bySHA := make(map[string]map[string]*outcomes) // workflow -> head SHA -> outcomes
+
+	var h CIHealth
+	for _, r := range runs {
+		w, ok := byWorkflow[r.Name]
+		if !ok {
+			w = &WorkflowHealth{Name: r.Name}
+			byWorkflow[r.Name] = w
+			bySHA[r.Name] = make(map[string]*outcomes)
+		}
+		o, ok := bySHA[r.Name][r.HeadSHA]
+		if !ok {
+			o = &outcomes{}
+			bySHA[r.Name][r.HeadSHA] = o
+		}
+
+		h.Runs++
+		w.Runs++
+		switch r.Conclusion {
+		case "success":
+			h.Succeeded++
+			w.Succeeded++
+			o.passed = true
+			// A re-run is usually a retry of a failure
+			if r.RunAttempt > 1 {
+				o.failed = true
+			}
+		case "failure", "timed_out":
+			h.Failed++
+			w.Failed++
+			o.failed = true
+		}
+	}
+
+	for name, w := range byWorkflow {
+		for _, o := range bySHA[name] {
+			if o.passed && o.failed {
+				w.Flaky++
+			}
+		}
+		h.Workflows = append(h.Workflows, *w)
+	}
+	sort.Slice(h.Workflows, func(i, j int) bool { return h.Workflows[i].Name < h.Workflows[j].Name })
+	return h
+}
@@ -0,0 +1,110 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiURL is the GitHub REST API base URL
+const apiURL = "https://api.github.com"
+
+// Client reads repository data from the GitHub REST API
+type Client struct {
+	tokens     *TokenProvider
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client authenticated with the App's installation
+// token. With a nil TokenProvider requests are anonymous, which only reaches
+// public repositories and is rate limited per IP address.
+func NewClient(tokens *TokenProvider) *Client {
+	return &Client{
+		tokens:     tokens,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// get fetches a GitHub API endpoint and decodes its JSON response into v
+func (c *Client) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.do(req, v)
+}
+
+// graphQLResponse is the envelope of a GraphQL API response
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQL runs a GraphQL query or mutation and decodes its data into v. The
+// GraphQL API needs a token, so it fails without a TokenProvider.
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]any, v any) error {
+	if c.tokens == nil {
+		return fmt.Errorf("the GitHub GraphQL API requires GitHub App authentication")
+	}
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode GraphQL request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/graphql", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp graphQLResponse
+	if err := c.do(req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		msgs := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			msgs[i] = e.Message
+		}
+		return fmt.Errorf("GitHub GraphQL API returned errors: %s", strings.Join(msgs, "; "))
+	}
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		return fmt.Errorf("failed to decode GitHub GraphQL response: %w", err)
+	}
+	return nil
+}
+
+// do sends an API request, authenticated if a TokenProvider is set, and
+// decodes its JSON response into v
+func (c *Client) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.tokens != nil {
+		token, err := c.tokens.GetToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GitHub API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode GitHub API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Discussion is a GitHub Discussion
+type Discussion struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// discussionTargetQuery looks up a repository's ID, discussion categories and
+// the latest discussions, to find the category and any existing discussion
+const discussionTargetQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    id
+    hasDiscussionsEnabled
+    discussionCategories(first: 100) { nodes { id name slug } }
+    discussions(first: 100, orderBy: {field: CREATED_AT, direction: DESC}) {
+      nodes { id title url category { id } }
+    }
+  }
+}`
+
+const createDiscussionMutation = `mutation($repositoryId: ID!, $categoryId: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repositoryId, categoryId: $categoryId, title: $title, body: $body}) {
+    discussion { id title url }
+  }
+}`
+
+const updateDiscussionMutation = `mutation($discussionId: ID!, $body: String!) {
+  updateDiscussion(input: {discussionId: $discussionId, body: $body}) {
+    discussion { id title url }
+  }
+}`
+
+// discussionTarget is the response to discussionTargetQuery
+type discussionTarget struct {
+	Repository *struct {
+		ID                    string `json:"id"`
+		HasDiscussionsEnabled bool   `json:"hasDiscussionsEnabled"`
+		DiscussionCategories  struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				Slug string `json:"slug"`
+			} `json:"nodes"`
+		} `json:"discussionCategories"`
+		Discussions struct {
+			Nodes []struct {
+				Discussion
+				Category struct {
+					ID string `json:"id"`
+				} `json:"category"`
+			} `json:"nodes"`
+		} `json:"discussions"`
+	} `json:"repository"`
+}
+
+// PublishDiscussion creates a discussion with the given title and markdown
+// body in a repository's discussion category, named or given by its slug, or
+// updates the body of the discussion with that title in the category. Only the
+// latest 100 discussions are searched for an existing one. The App needs read
+// and write access to discussions.
+func (c *Client) PublishDiscussion(ctx context.Context, owner, repo, category, title, body string) (*Discussion, error) {
+	var target discussionTarget
+	if err := c.graphQL(ctx, discussionTargetQuery, map[string]any{"owner": owner, "name": repo}, &target); err != nil {
+		return nil, fmt.Errorf("failed to look up discussions: %w", err)
+	}
+	r := target.Repository
+	if r == nil {
+		return nil, fmt.Errorf("repository %s/%s not found", owner, repo)
+	}
+	if !r.HasDiscussionsEnabled {
+		return nil, fmt.Errorf("discussions are not enabled on %s/%s", owner, repo)
+	}
+
+	var categoryID string
+	for _, cat := range r.DiscussionCategories.Nodes {
+		if strings.EqualFold(cat.Name, category) || cat.Slug == category {
+			categoryID = cat.ID
+			break
+		}
+	}
+	if categoryID == "" {
+		return nil, fmt.Errorf("discussion category %q not found in %s/%s", category, owner, repo)
+	}
+
+	for _, d := range r.Discussions.Nodes {
+		if d.Category.ID != categoryID || d.Title != title {
+			continue
+		}
+		var resp struct {
+			UpdateDiscussion struct {
+				Discussion Discussion `json:"discussion"`
+			} `json:"updateDiscussion"`
+		}
+		vars := map[string]any{"discussionId": d.ID, "body": body}
+		if err := c.graphQL(ctx, updateDiscussionMutation, vars, &resp); err != nil {
+			return nil, fmt.Errorf("failed to update discussion: %w", err)
+		}
+		return &resp.UpdateDiscussion.Discussion, nil
+	}
+
+	var resp struct {
+		CreateDiscussion struct {
+			Discussion Discussion `json:"discussion"`
+		} `json:"createDiscussion"`
+	}
+	vars := map[string]any{"repositoryId": r.ID, "categoryId": categoryID, "title": title, "body": body}
+	if err := c.graphQL(ctx, createDiscussionMutation, vars, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create discussion: %w", err)
+	}
+	return &resp.CreateDiscussion.Discussion, nil
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// maxIssuePages caps how many pages of issues are fetched for one period
+const maxIssuePages = 10
+
+// Issue is a GitHub issue
+type Issue struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	HTMLURL   string     `json:"html_url"`
+	State     string     `json:"state"` // open or closed
+	Comments  int        `json:"comments"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+
+	// Set for pull requests, which the issues API lists too
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// ListIssues returns the issues of a repository updated since the given
+// time, which includes every issue opened or closed since then. Pull requests
+// are left out.
+func (c *Client) ListIssues(ctx context.Context, owner, repo string, since time.Time) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("state", "all")
+	query.Set("since", since.UTC().Format(time.RFC3339))
+	query.Set("per_page", "100")
+
+	var issues []Issue
+	for page := 1; page <= maxIssuePages; page++ {
+		query.Set("page", fmt.Sprint(page))
+		endpoint := fmt.Sprintf("%s/repos/%s/%s/issues?%s", apiURL, url.PathEscape(owner), url.PathEscape(repo), query.Encode())
+
+		var batch []Issue
+		if err := c.get(ctx, endpoint, &batch); err != nil {
+			return nil, err
+		}
+		for _, issue := range batch {
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,121 @@
+// Package gitlab reads project data from the GitLab REST API.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxIssuePages caps how many pages of issues are fetched for one period
+const maxIssuePages = 10
+
+// Client reads project data from gitlab.com or a self-managed GitLab
+type Client struct {
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client. With an empty token requests are
+// anonymous, which only reaches public projects.
+func NewClient(token string) *Client {
+	return &Client{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Project identifies a GitLab project by its instance and path
+type Project struct {
+	BaseURL string // e.g. https://gitlab.com
+	Path    string // Namespace and name, e.g. group/subgroup/project
+}
+
+// ParseProjectURL returns the project at a GitLab URL. URLs on gitlab.com and
+// on the given self-managed hosts are recognized.
+// Input: https://gitlab.com/group/project.git
+// Output: Project{BaseURL: https://gitlab.com, Path: group/project}
+func ParseProjectURL(repoURL string, hosts []string) (Project, bool) {
+	parsed, err := url.Parse(repoURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return Project{}, false
+	}
+	host := strings.ToLower(parsed.Host)
+	if host != "gitlab.com" && !slices.ContainsFunc(hosts, func(h string) bool { return strings.EqualFold(h, host) }) {
+		return Project{}, false
+	}
+	path := strings.TrimSuffix(strings.Trim(parsed.Path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return Project{}, false
+	}
+	return Project{BaseURL: parsed.Scheme + "://" + parsed.Host, Path: path}, true
+}
+
+// Issue is a GitLab issue
+type Issue struct {
+	IID            int        `json:"iid"` // Number within the project
+	Title          string     `json:"title"`
+	WebURL         string     `json:"web_url"`
+	State          string     `json:"state"` // opened or closed
+	UserNotesCount int        `json:"user_notes_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+}
+
+// ListIssues returns the issues of a project updated since the given time,
+// which includes every issue opened or closed since then
+func (c *Client) ListIssues(ctx context.Context, project Project, since time.Time) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("updated_after", since.UTC().Format(time.RFC3339))
+	query.Set("scope", "all")
+	query.Set("per_page", "100")
+
+	var issues []Issue
+	for page := 1; page <= maxIssuePages; page++ {
+		query.Set("page", fmt.Sprint(page))
+		endpoint := fmt.Sprintf("%s/api/v4/projects/%s/issues?%s", project.BaseURL, url.PathEscape(project.Path), query.Encode())
+
+		var batch []Issue
+		if err := c.get(ctx, endpoint, &batch); err != nil {
+			return nil, err
+		}
+		issues = append(issues, batch...)
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return issues, nil
+}
+
+// get fetches a GitLab API endpoint and decodes its JSON response into v
+func (c *Client) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitLab API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GitLab API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode GitLab API response: %w", err)
+	}
+	return nil
+}
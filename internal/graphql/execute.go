@@ -0,0 +1,609 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// maxDepth limits how deeply fields of a request can be nested
+const maxDepth = 10
+
+// maxSelections limits the fields and fragment spreads a query selects, with
+// a fragment's selections counted each time it is spread, so that aliases and
+// fragments cannot multiply the work of a small query
+const maxSelections = 500
+
+// Request is a GraphQL request as posted by clients
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is nil when the request failed
+// before execution, and then left out of the JSON encoding.
+type Response struct {
+	Data     any      `json:"data"`
+	Errors   []*Error `json:"errors,omitempty"`
+	executed bool
+}
+
+// MarshalJSON encodes the response, with data only if the request executed
+func (r *Response) MarshalJSON() ([]byte, error) {
+	if r.executed {
+		type response Response
+		return json.Marshal((*response)(r))
+	}
+	return json.Marshal(struct {
+		Errors []*Error `json:"errors"`
+	}{r.Errors})
+}
+
+// Error is an error of a request, with the response path of the field that
+// failed for execution errors
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Execute parses, validates and executes a query. Failures are reported in
+// the response's errors rather than returned.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return requestError(err)
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return requestError(err)
+	}
+	if op.kind != "query" {
+		return requestError(fmt.Errorf("%s operations are not supported", op.kind))
+	}
+	if err := (&validator{doc: doc, op: op}).validate(s.Query); err != nil {
+		return requestError(err)
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return requestError(err)
+	}
+
+	e := &executor{doc: doc, vars: vars}
+	data, ok := e.executeFields(ctx, s.Query, nil, op.selections, nil)
+	resp := &Response{Errors: e.errors, executed: true}
+	if ok {
+		resp.Data = data
+	}
+	return resp
+}
+
+func requestError(err error) *Response {
+	return &Response{Errors: []*Error{{Message: err.Error()}}}
+}
+
+// operation returns the operation to execute
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// validator checks a query against the schema before it executes
+type validator struct {
+	doc *document
+	op  *operation
+	// spreading holds the fragments being validated, to detect cycles
+	spreading map[string]bool
+	// selected counts the selections validated, up to maxSelections
+	selected int
+}
+
+func (v *validator) validate(query *Object) error {
+	defined := map[string]bool{}
+	for _, def := range v.op.variables {
+		if defined[def.name] {
+			return fmt.Errorf("variable $%s is defined more than once", def.name)
+		}
+		defined[def.name] = true
+		if _, err := inputType(def.typ); err != nil {
+			return fmt.Errorf("variable $%s: %w", def.name, err)
+		}
+	}
+	v.spreading = map[string]bool{}
+	return v.selections(query, v.op.selections, 1, defined)
+}
+
+func (v *validator) selections(obj *Object, sels []selection, depth int, defined map[string]bool) error {
+	if depth > maxDepth {
+		return fmt.Errorf("query is nested more than %d levels deep", maxDepth)
+	}
+	for _, sel := range sels {
+		if v.selected++; v.selected > maxSelections {
+			return fmt.Errorf("query selects more than %d fields", maxSelections)
+		}
+		if err := v.directives(sel.selectionDirectives(), defined); err != nil {
+			return err
+		}
+		switch sel := sel.(type) {
+		case *fieldNode:
+			if err := v.field(obj, sel, depth, defined); err != nil {
+				return err
+			}
+		case *fragmentSpread:
+			frag, ok := v.doc.fragments[sel.name]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", sel.name)
+			}
+			if v.spreading[sel.name] {
+				return fmt.Errorf("fragment %q spreads itself", sel.name)
+			}
+			if frag.on != obj.Name {
+				return fmt.Errorf("fragment %q on %s cannot be spread within %s", sel.name, frag.on, obj.Name)
+			}
+			if err := v.directives(frag.directives, defined); err != nil {
+				return err
+			}
+			v.spreading[sel.name] = true
+			err := v.selections(obj, frag.selections, depth, defined)
+			delete(v.spreading, sel.name)
+			if err != nil {
+				return err
+			}
+		case *inlineFragment:
+			if sel.on != "" && sel.on != obj.Name {
+				return fmt.Errorf("fragment on %s cannot be spread within %s", sel.on, obj.Name)
+			}
+			if err := v.selections(obj, sel.selections, depth, defined); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) field(obj *Object, f *fieldNode, depth int, defined map[string]bool) error {
+	if f.name == "__typename" {
+		if len(f.args) > 0 || f.selections != nil {
+			return fmt.Errorf("field \"__typename\" takes no arguments or selections")
+		}
+		return nil
+	}
+	def := obj.field(f.name)
+	if def == nil {
+		return fmt.Errorf("cannot query field %q on type %s", f.name, obj.Name)
+	}
+
+	for _, arg := range f.args {
+		argDef := def.argument(arg.name)
+		if argDef == nil {
+			return fmt.Errorf("unknown argument %q on field %s.%s", arg.name, obj.Name, f.name)
+		}
+		if err := checkVariables(arg.value, defined); err != nil {
+			return err
+		}
+		if hasVariables(arg.value) {
+			continue
+		}
+		if _, err := coerceInput(argDef.Type, arg.value, nil); err != nil {
+			return fmt.Errorf("argument %q on field %s.%s: %w", arg.name, obj.Name, f.name, err)
+		}
+	}
+	for _, argDef := range def.Args {
+		if _, required := argDef.Type.(NonNull); !required || argDef.Default != nil {
+			continue
+		}
+		given := false
+		for _, arg := range f.args {
+			given = given || arg.name == argDef.Name
+		}
+		if !given {
+			return fmt.Errorf("field %s.%s requires argument %q", obj.Name, f.name, argDef.Name)
+		}
+	}
+
+	child, isObject := namedType(def.Type).(*Object)
+	switch {
+	case isObject && f.selections == nil:
+		return fmt.Errorf("field %q of type %s must have a selection of subfields", f.name, def.Type)
+	case !isObject && f.selections != nil:
+		return fmt.Errorf("field %q of type %s cannot have a selection of subfields", f.name, def.Type)
+	case isObject:
+		return v.selections(child, f.selections, depth+1, defined)
+	}
+	return nil
+}
+
+func (v *validator) directives(dirs []*directive, defined map[string]bool) error {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			return fmt.Errorf("unknown directive @%s", d.name)
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			return fmt.Errorf("directive @%s requires a single argument \"if\"", d.name)
+		}
+		if err := checkVariables(d.args[0].value, defined); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkVariables checks that the variables a value refers to are defined
+func checkVariables(value any, defined map[string]bool) error {
+	switch value := value.(type) {
+	case variableRef:
+		if !defined[string(value)] {
+			return fmt.Errorf("variable $%s is not defined", value)
+		}
+	case []any:
+		for _, item := range value {
+			if err := checkVariables(item, defined); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasVariables reports whether a value refers to variables, so that it can
+// only be coerced at execution
+func hasVariables(value any) bool {
+	switch value := value.(type) {
+	case variableRef:
+		return true
+	case []any:
+		for _, item := range value {
+			if hasVariables(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namedType returns the type inside lists and non-nulls
+func namedType(t Type) Type {
+	for {
+		switch u := t.(type) {
+		case List:
+			t = u.Of
+		case NonNull:
+			t = u.Of
+		default:
+			return t
+		}
+	}
+}
+
+// inputType returns the type a variable is declared with
+func inputType(ref typeRef) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := inputType(*ref.elem)
+		if err != nil {
+			return nil, err
+		}
+		t = List{Of: elem}
+	} else {
+		scalar, ok := builtinScalars[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown input type %s", ref.name)
+		}
+		t = scalar
+	}
+	if ref.nonNull {
+		t = NonNull{Of: t}
+	}
+	return t, nil
+}
+
+// coerceVariables coerces the request's variables to their declared types
+func coerceVariables(op *operation, given map[string]any) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, def := range op.variables {
+		t, _ := inputType(def.typ)
+		value, ok := given[def.name]
+		if !ok && def.hasDef {
+			value, ok = def.defValue, true
+		}
+		if !ok {
+			if _, required := t.(NonNull); required {
+				return nil, fmt.Errorf("variable $%s of type %s was not provided", def.name, def.typ)
+			}
+			continue
+		}
+		coerced, err := coerceInput(t, value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", def.name, err)
+		}
+		vars[def.name] = coerced
+	}
+	return vars, nil
+}
+
+// coerceInput coerces an input value to a type, substituting variables
+func coerceInput(t Type, value any, vars map[string]any) (any, error) {
+	if ref, ok := value.(variableRef); ok {
+		value = vars[string(ref)]
+	}
+	switch t := t.(type) {
+	case NonNull:
+		if value == nil {
+			return nil, fmt.Errorf("expected a value of type %s, found null", t)
+		}
+		return coerceInput(t.Of, value, vars)
+	case List:
+		if value == nil {
+			return nil, nil
+		}
+		items, ok := value.([]any)
+		if !ok {
+			items = []any{value}
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(t.Of, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	case *Scalar:
+		if value == nil {
+			return nil, nil
+		}
+		return t.parse(value)
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// executor executes a validated query
+type executor struct {
+	doc    *document
+	vars   map[string]any
+	errors []*Error
+}
+
+// object is a response object, which keeps its fields in query order
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// executeFields resolves the selected fields of an object. It returns false
+// when a non-null field is null, which makes the object null too.
+func (e *executor) executeFields(ctx context.Context, obj *Object, source any, sels []selection, path []any) (any, bool) {
+	keys, fields := e.collectFields(obj, sels, nil, map[string][]*fieldNode{})
+	result := &object{values: make(map[string]any, len(keys))}
+	for _, key := range keys {
+		value, ok := e.executeField(ctx, obj, source, fields[key], append(path[:len(path):len(path)], key))
+		if !ok {
+			return nil, false
+		}
+		result.keys = append(result.keys, key)
+		result.values[key] = value
+	}
+	return result, true
+}
+
+// collectFields groups the fields selected on an object by response key,
+// applying directives and fragments
+func (e *executor) collectFields(obj *Object, sels []selection, keys []string, fields map[string][]*fieldNode) ([]string, map[string][]*fieldNode) {
+	for _, sel := range sels {
+		if !e.included(sel.selectionDirectives()) {
+			continue
+		}
+		switch sel := sel.(type) {
+		case *fieldNode:
+			key := sel.responseKey()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+		case *fragmentSpread:
+			frag := e.doc.fragments[sel.name]
+			if e.included(frag.directives) {
+				keys, fields = e.collectFields(obj, frag.selections, keys, fields)
+			}
+		case *inlineFragment:
+			keys, fields = e.collectFields(obj, sel.selections, keys, fields)
+		}
+	}
+	return keys, fields
+}
+
+// included applies @skip and @include
+func (e *executor) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		cond, _ := coerceInput(Boolean, d.args[0].value, e.vars)
+		if b, _ := cond.(bool); b == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// executeField resolves a field and completes its value
+func (e *executor) executeField(ctx context.Context, obj *Object, source any, nodes []*fieldNode, path []any) (any, bool) {
+	node := nodes[0]
+	if node.name == "__typename" {
+		return obj.Name, true
+	}
+	def := obj.field(node.name)
+
+	args, err := e.arguments(def, node)
+	if err == nil {
+		var value any
+		if value, err = resolve(ctx, def, source, args); err == nil {
+			var sels []selection
+			for _, n := range nodes {
+				sels = append(sels, n.selections...)
+			}
+			result, ok := e.completeValue(ctx, def.Type, value, sels, path)
+			return nullable(def.Type, result, ok)
+		}
+	}
+	e.fieldError(err, path)
+	return nullable(def.Type, nil, false)
+}
+
+// arguments coerces the arguments of a field, applying defaults
+func (e *executor) arguments(def *Field, node *fieldNode) (map[string]any, error) {
+	args := map[string]any{}
+	for _, argDef := range def.Args {
+		var arg *argumentNode
+		for _, a := range node.args {
+			if a.name == argDef.Name {
+				arg = a
+			}
+		}
+		if arg != nil {
+			if ref, isVar := arg.value.(variableRef); isVar {
+				if _, given := e.vars[string(ref)]; !given {
+					arg = nil
+				}
+			}
+		}
+		if arg == nil {
+			if argDef.Default != nil {
+				args[argDef.Name] = argDef.Default
+			} else if _, required := argDef.Type.(NonNull); required {
+				return nil, fmt.Errorf("argument %q of type %s is required", argDef.Name, argDef.Type)
+			}
+			continue
+		}
+		value, err := coerceInput(argDef.Type, arg.value, e.vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", argDef.Name, err)
+		}
+		args[argDef.Name] = value
+	}
+	return args, nil
+}
+
+// resolve calls the field's resolver, or looks the field up in a map source
+func resolve(ctx context.Context, def *Field, source any, args map[string]any) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to resolve %s: %v", def.Name, r)
+		}
+	}()
+	if def.Resolve != nil {
+		return def.Resolve(ctx, source, args)
+	}
+	if m, ok := source.(map[string]any); ok {
+		return m[def.Name], nil
+	}
+	return nil, fmt.Errorf("field %s has no resolver", def.Name)
+}
+
+// completeValue converts a resolved value to its response form. It returns
+// false when the value is null because of an error, which is already
+// recorded; the nearest nullable field or list item becomes null then.
+func (e *executor) completeValue(ctx context.Context, t Type, value any, sels []selection, path []any) (any, bool) {
+	if nn, ok := t.(NonNull); ok {
+		result, ok := e.completeValue(ctx, nn.Of, value, sels, path)
+		if ok && result == nil {
+			e.fieldError(fmt.Errorf("cannot return null for non-null field"), path)
+			return nil, false
+		}
+		return result, ok
+	}
+
+	if isNil(value) {
+		return nil, true
+	}
+	switch t := t.(type) {
+	case List:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(fmt.Errorf("expected a list, got %T", value), path)
+			return nil, false
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			item, ok := e.completeValue(ctx, t.Of, rv.Index(i).Interface(), sels, append(path[:len(path):len(path)], i))
+			if item, ok = nullable(t.Of, item, ok); !ok {
+				return nil, false
+			}
+			list[i] = item
+		}
+		return list, true
+	case *Scalar:
+		result, err := t.serialize(value)
+		if err != nil {
+			e.fieldError(err, path)
+			return nil, false
+		}
+		return result, true
+	case *Object:
+		return e.executeFields(ctx, t, value, sels, path)
+	}
+	return nil, false
+}
+
+// nullable turns a value that is null because of an error into a plain null
+// if its type is nullable
+func nullable(t Type, value any, ok bool) (any, bool) {
+	if _, nonNull := t.(NonNull); !ok && !nonNull {
+		return nil, true
+	}
+	return value, ok
+}
+
+// fieldError records an execution error
+func (e *executor) fieldError(err error, path []any) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+}
+
+// isNil reports whether a value is nil, including nil pointers and maps
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
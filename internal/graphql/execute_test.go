@@ -0,0 +1,317 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRepo struct {
+	name   string
+	active bool
+	stars  int
+}
+
+var testRepos = []*testRepo{
+	{"activity", true, 12},
+	{"archive", false, 3},
+	{"website", true, 7},
+}
+
+// testSchema is a small schema with the kinds of fields web/graphql.go
+// builds: filtered lists, lookups by argument and nested objects
+func testSchema() *Schema {
+	report := &Object{Name: "Report", Fields: []*Field{
+		{Name: "week", Type: NonNull{Of: Int}},
+		{Name: "created", Type: Time},
+	}}
+	repo := &Object{Name: "Repository"}
+	repo.Fields = []*Field{
+		{Name: "name", Type: NonNull{Of: String}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source.(*testRepo).name, nil
+		}},
+		{Name: "stars", Type: Int, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source.(*testRepo).stars, nil
+		}},
+		{Name: "reports", Type: NonNull{Of: List{Of: NonNull{Of: report}}},
+			Args: []*Argument{{Name: "limit", Type: Int, Default: 2}},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				var reports []map[string]any
+				for week := 1; week <= args["limit"].(int); week++ {
+					reports = append(reports, map[string]any{"week": week, "created": time.Date(2026, 1, week, 0, 0, 0, 0, time.UTC)})
+				}
+				return reports, nil
+			}},
+		// Nests without bound, for the depth limit
+		{Name: "self", Type: repo, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source, nil
+		}},
+		{Name: "broken", Type: String, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return nil, errors.New("storage unavailable")
+		}},
+		{Name: "required", Type: NonNull{Of: String}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return nil, nil
+		}},
+		{Name: "panics", Type: String, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			panic("boom")
+		}},
+	}
+	query := &Object{Name: "Query", Fields: []*Field{
+		{Name: "repositories", Type: NonNull{Of: List{Of: NonNull{Of: repo}}},
+			Args: []*Argument{
+				{Name: "active", Type: Boolean},
+				{Name: "names", Type: List{Of: NonNull{Of: String}}},
+				{Name: "minStars", Type: Int, Default: 0},
+			},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				var repos []*testRepo
+				for _, r := range testRepos {
+					if active, ok := args["active"].(bool); ok && r.active != active {
+						continue
+					}
+					if names, ok := args["names"].([]any); ok && !containsName(names, r.name) {
+						continue
+					}
+					if r.stars < args["minStars"].(int) {
+						continue
+					}
+					repos = append(repos, r)
+				}
+				return repos, nil
+			}},
+		{Name: "repository", Type: repo,
+			Args: []*Argument{{Name: "name", Type: NonNull{Of: String}}},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				for _, r := range testRepos {
+					if r.name == args["name"] {
+						return r, nil
+					}
+				}
+				return nil, nil
+			}},
+	}}
+	return NewSchema(query)
+}
+
+func containsName(names []any, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// execute runs a query against the test schema and returns the JSON response
+func execute(t *testing.T, query string, vars map[string]any) string {
+	t.Helper()
+	resp := testSchema().Execute(context.Background(), Request{Query: query, Variables: vars})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  string
+	}{
+		{
+			name:  "fields in query order with aliases",
+			query: `{ repositories { stars, title: name, __typename } }`,
+			want:  `{"data":{"repositories":[{"stars":12,"title":"activity","__typename":"Repository"},{"stars":3,"title":"archive","__typename":"Repository"},{"stars":7,"title":"website","__typename":"Repository"}]}}`,
+		},
+		{
+			name:  "filter arguments",
+			query: `{ repositories(active: true, minStars: 8) { name } }`,
+			want:  `{"data":{"repositories":[{"name":"activity"}]}}`,
+		},
+		{
+			name:  "a single value for a list argument",
+			query: `{ repositories(names: "website") { name } }`,
+			want:  `{"data":{"repositories":[{"name":"website"}]}}`,
+		},
+		{
+			name:  "argument defaults and scalars",
+			query: `{ repository(name: "activity") { reports { week created } } }`,
+			want:  `{"data":{"repository":{"reports":[{"week":1,"created":"2026-01-01T00:00:00Z"},{"week":2,"created":"2026-01-02T00:00:00Z"}]}}}`,
+		},
+		{
+			name:  "no match is null",
+			query: `{ repository(name: "missing") { name } }`,
+			want:  `{"data":{"repository":null}}`,
+		},
+		{
+			name:  "variables",
+			query: `query ($names: [String!], $limit: Int = 1) { repositories(names: $names) { name reports(limit: $limit) { week } } }`,
+			vars:  map[string]any{"names": []any{"archive"}},
+			want:  `{"data":{"repositories":[{"name":"archive","reports":[{"week":1}]}]}}`,
+		},
+		{
+			name:  "variables from JSON numbers",
+			query: `query ($min: Int) { repositories(minStars: $min) { name } }`,
+			vars:  map[string]any{"min": json.Number("10")},
+			want:  `{"data":{"repositories":[{"name":"activity"}]}}`,
+		},
+		{
+			name:  "an unset variable leaves the argument's default",
+			query: `query ($min: Int) { repositories(minStars: $min) { name } }`,
+			want:  `{"data":{"repositories":[{"name":"activity"},{"name":"archive"},{"name":"website"}]}}`,
+		},
+		{
+			name: "fragments merge fields",
+			query: `{ repository(name: "website") { ...Names ... on Repository { stars } ... { name } } }
+				fragment Names on Repository { name, self { name } }`,
+			want: `{"data":{"repository":{"name":"website","self":{"name":"website"},"stars":7}}}`,
+		},
+		{
+			name:  "directives",
+			query: `query ($full: Boolean!) { repository(name: "activity") { name stars @include(if: $full) ... @skip(if: true) { self { name } } } }`,
+			vars:  map[string]any{"full": false},
+			want:  `{"data":{"repository":{"name":"activity"}}}`,
+		},
+		{
+			name:  "resolver errors make the field null",
+			query: `{ repository(name: "activity") { name broken panics } }`,
+			want:  `{"data":{"repository":{"name":"activity","broken":null,"panics":null}},"errors":[{"message":"storage unavailable","path":["repository","broken"]},{"message":"failed to resolve panics: boom","path":["repository","panics"]}]}`,
+		},
+		{
+			name:  "null in a non-null field nulls the nearest nullable parent",
+			query: `{ repository(name: "activity") { name required } }`,
+			want:  `{"data":{"repository":null},"errors":[{"message":"cannot return null for non-null field","path":["repository","required"]}]}`,
+		},
+		{
+			name:  "null propagates to data through non-null lists",
+			query: `{ repositories { required } }`,
+			want:  `{"data":null,"errors":[{"message":"cannot return null for non-null field","path":["repositories",0,"required"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, tt.query, tt.vars); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteOperationName(t *testing.T) {
+	schema := testSchema()
+	doc := `query A { repository(name: "activity") { name } } query B { repository(name: "archive") { name } }`
+
+	resp := schema.Execute(context.Background(), Request{Query: doc, OperationName: "B"})
+	data, _ := json.Marshal(resp)
+	if want := `{"data":{"repository":{"name":"archive"}}}`; string(data) != want {
+		t.Errorf("operation B = %s, want %s", data, want)
+	}
+	for name, want := range map[string]string{"": "operationName is required", "C": `unknown operation "C"`} {
+		resp := schema.Execute(context.Background(), Request{Query: doc, OperationName: name})
+		if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, want) {
+			t.Errorf("operation %q errors = %v, want %q", name, resp.Errors, want)
+		}
+	}
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  string
+	}{
+		{"syntax error", `{ repositories { name }`, nil, "syntax error at line 1"},
+		{"mutation", `mutation { repositories { name } }`, nil, "mutation operations are not supported"},
+		{"unknown field", `{ repositories { owner } }`, nil, `cannot query field "owner" on type Repository`},
+		{"unknown argument", `{ repositories(sort: "name") { name } }`, nil, `unknown argument "sort" on field Query.repositories`},
+		{"missing required argument", `{ repository { name } }`, nil, `field Query.repository requires argument "name"`},
+		{"wrong argument type", `{ repositories(minStars: "many") { name } }`, nil, `argument "minStars" on field Query.repositories: Int cannot represent "many"`},
+		{"null in a non-null list item", `{ repositories(names: ["a", null]) { name } }`, nil, "expected a value of type String!, found null"},
+		{"object without selection", `{ repositories }`, nil, `field "repositories" of type [Repository!]! must have a selection of subfields`},
+		{"scalar with selection", `{ repositories { name { x } } }`, nil, `field "name" of type String! cannot have a selection of subfields`},
+		{"unknown directive", `{ repositories @cached { name } }`, nil, "unknown directive @cached"},
+		{"undefined variable", `{ repositories(minStars: $min) { name } }`, nil, "variable $min is not defined"},
+		{"variable defined twice", `query ($a: Int, $a: Int) { repositories { name } }`, nil, "variable $a is defined more than once"},
+		{"variable of an unknown type", `query ($a: Repository) { repositories { name } }`, nil, "variable $a: unknown input type Repository"},
+		{"required variable missing", `query ($a: Int!) { repositories(minStars: $a) { name } }`, nil, "variable $a of type Int! was not provided"},
+		{"variable of the wrong type", `query ($a: Int) { repositories(minStars: $a) { name } }`, map[string]any{"a": "ten"}, `variable $a: Int cannot represent "ten"`},
+		{"Int out of range", `query ($a: Int) { repositories(minStars: $a) { name } }`, map[string]any{"a": json.Number("3000000000")}, "Int cannot represent 3000000000"},
+		{"unknown fragment", `{ repositories { ...Missing } }`, nil, `unknown fragment "Missing"`},
+		{"fragment on another type", `{ ...R } fragment R on Repository { name }`, nil, `fragment "R" on Repository cannot be spread within Query`},
+		{"inline fragment on another type", `{ ... on Repository { name } }`, nil, "fragment on Repository cannot be spread within Query"},
+		{"fragment cycle", `{ repositories { ...A } } fragment A on Repository { name ...B } fragment B on Repository { ...A }`, nil, `fragment "A" spreads itself`},
+		{"__typename with selection", `{ __typename { x } }`, nil, `field "__typename" takes no arguments or selections`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := execute(t, tt.query, tt.vars)
+			var resp struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []Error         `json:"errors"`
+			}
+			if err := json.Unmarshal([]byte(got), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", got, err)
+			}
+			if resp.Data != nil {
+				t.Errorf("response %s should have no data", got)
+			}
+			if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, tt.want) {
+				t.Errorf("response %s, want an error containing %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	nested := func(levels int) string {
+		return `{ repository(name: "activity") {` + strings.Repeat(" self {", levels-2) + " name" + strings.Repeat(" }", levels-2) + " } }"
+	}
+	if got := execute(t, nested(maxDepth), nil); strings.Contains(got, "errors") {
+		t.Errorf("query %d levels deep = %s, want data", maxDepth, got)
+	}
+	if got := execute(t, nested(maxDepth+1), nil); !strings.Contains(got, fmt.Sprintf("nested more than %d levels deep", maxDepth)) {
+		t.Errorf("query %d levels deep = %s, want the depth limit", maxDepth+1, got)
+	}
+
+	// Fragments spread within fragments cannot get around the depth limit
+	frags := `{ repository(name: "activity") { ...F0 } }`
+	for i := range maxDepth {
+		frags += fmt.Sprintf(" fragment F%d on Repository { self { ...F%d } }", i, i+1)
+	}
+	frags += fmt.Sprintf(" fragment F%d on Repository { name }", maxDepth)
+	if got := execute(t, frags, nil); !strings.Contains(got, "nested more than") {
+		t.Errorf("query nested through fragments = %s, want the depth limit", got)
+	}
+
+	var aliases strings.Builder
+	aliases.WriteString("{")
+	for i := range maxSelections {
+		fmt.Fprintf(&aliases, " r%d: repositories { name }", i)
+	}
+	aliases.WriteString(" }")
+	if got := execute(t, aliases.String(), nil); !strings.Contains(got, fmt.Sprintf("selects more than %d fields", maxSelections)) {
+		t.Errorf("query with %d aliased lists = %.200s, want the selection limit", maxSelections, got)
+	}
+
+	// Each fragment spreads the next twice, selecting 2^20 fields from a
+	// document of a few hundred bytes
+	bomb := `{ repositories { ...B0 } }`
+	for i := range 20 {
+		bomb += fmt.Sprintf(" fragment B%d on Repository { ...B%d ...B%d }", i, i+1, i+1)
+	}
+	bomb += " fragment B20 on Repository { name }"
+	start := time.Now()
+	if got := execute(t, bomb, nil); !strings.Contains(got, "selects more than") {
+		t.Errorf("fragment bomb = %.200s, want the selection limit", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("rejecting the fragment bomb took %v", elapsed)
+	}
+}
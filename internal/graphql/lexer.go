@@ -0,0 +1,254 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical token and its byte offset in the source
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lexer splits a GraphQL document into tokens
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token, skipping whitespace, commas and comments
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", pos: start}, nil
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), pos: start}, nil
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, syntaxError(l.src, start, fmt.Sprintf("unexpected character %q", r))
+}
+
+// skipIgnored skips whitespace, commas, comments and byte order marks
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+// number lexes an integer or float
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.digits() {
+		return token{}, syntaxError(l.src, start, "invalid number")
+	}
+	kind := tokenInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if !l.digits() {
+			return token{}, syntaxError(l.src, start, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.digits() {
+			return token{}, syntaxError(l.src, start, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, syntaxError(l.src, start, "invalid number")
+	}
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+// digits skips a run of digits, reporting whether there was one
+func (l *lexer) digits() bool {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+// string lexes a string or block string
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.pos += 3
+		var b strings.Builder
+		for l.pos < len(l.src) {
+			switch {
+			case strings.HasPrefix(l.src[l.pos:], `\"""`):
+				b.WriteString(`"""`)
+				l.pos += 4
+			case strings.HasPrefix(l.src[l.pos:], `"""`):
+				l.pos += 3
+				return token{kind: tokenString, value: blockString(b.String()), pos: start}, nil
+			default:
+				b.WriteByte(l.src[l.pos])
+				l.pos++
+			}
+		}
+		return token{}, syntaxError(l.src, start, "unterminated string")
+	}
+
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: b.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, syntaxError(l.src, start, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, syntaxError(l.src, start, "unterminated string")
+			}
+			escape := l.src[l.pos+1]
+			l.pos += 2
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, syntaxError(l.src, l.pos-2, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, syntaxError(l.src, l.pos-2, "invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, syntaxError(l.src, l.pos-2, fmt.Sprintf("invalid escape \\%c", escape))
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, syntaxError(l.src, start, "unterminated string")
+}
+
+// blockString removes the common indentation and surrounding blank lines of
+// a block string
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// syntaxError returns an error for the source position pos, with its line
+// and column
+func syntaxError(src string, pos int, msg string) error {
+	line, col := 1, 1
+	for _, r := range src[:min(pos, len(src))] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("syntax error at line %d, column %d: %s", line, col, msg)
+}
@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+// lexAll returns the tokens of src up to the end or the first error
+func lexAll(src string) ([]token, error) {
+	l := &lexer{src: src}
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return tokens, err
+		}
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		kinds []tokenKind
+		vals  []string
+	}{
+		{"punctuation and names", "{ repo(name: $n) @skip ...F }",
+			[]tokenKind{tokenPunct, tokenName, tokenPunct, tokenName, tokenPunct, tokenPunct, tokenName, tokenPunct, tokenPunct, tokenName, tokenPunct, tokenName, tokenPunct},
+			[]string{"{", "repo", "(", "name", ":", "$", "n", ")", "@", "skip", "...", "F", "}"}},
+		{"ignored commas, comments and BOM", "\uFEFFa, b # comment, c\r\n,d",
+			[]tokenKind{tokenName, tokenName, tokenName},
+			[]string{"a", "b", "d"}},
+		{"numbers", "0 -12 3.5 1e10 -2.5E-3",
+			[]tokenKind{tokenInt, tokenInt, tokenFloat, tokenFloat, tokenFloat},
+			[]string{"0", "-12", "3.5", "1e10", "-2.5E-3"}},
+		{"string escapes", `"a\"b\\c\/d\n\té"`,
+			[]tokenKind{tokenString},
+			[]string{"a\"b\\c/d\n\té"}},
+		{"block string", "\"\"\"\n    first\n      indented\n    quote \\\"\"\"\n\n  \"\"\"",
+			[]tokenKind{tokenString},
+			[]string{"first\n  indented\nquote \"\"\""}},
+		{"empty string", `""`,
+			[]tokenKind{tokenString},
+			[]string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexAll(tt.src)
+			if err != nil {
+				t.Fatalf("lexing failed: %v", err)
+			}
+			if len(tokens) != len(tt.kinds) {
+				t.Fatalf("got %d tokens %v, want %d", len(tokens), tokens, len(tt.kinds))
+			}
+			for i, tok := range tokens {
+				if tok.kind != tt.kinds[i] || tok.value != tt.vals[i] {
+					t.Errorf("token %d = %d %q, want %d %q", i, tok.kind, tok.value, tt.kinds[i], tt.vals[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"{ a }\n  %", "line 2, column 3: unexpected character '%'"},
+		{"1.", "invalid number"},
+		{"-", "invalid number"},
+		{"1e", "invalid number"},
+		{"12abc", "invalid number"},
+		{"1.5.2", "invalid number"},
+		{`"open`, "unterminated string"},
+		{"\"line\nbreak\"", "unterminated string"},
+		{`"trailing\`, "unterminated string"},
+		{`"""open`, "unterminated string"},
+		{`"\x"`, `invalid escape \x`},
+		{`"\u12"`, "invalid unicode escape"},
+		{`"\uZZZZ"`, "invalid unicode escape"},
+	}
+	for _, tt := range tests {
+		_, err := lexAll(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("lexing %q: error = %v, want it to contain %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,580 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// document is a parsed GraphQL request document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDef
+	selections []selection
+}
+
+// variableDef declares a variable of an operation
+type variableDef struct {
+	name     string
+	typ      typeRef
+	defValue any
+	hasDef   bool
+}
+
+// typeRef is a type as written in a variable definition
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// fragment is a named fragment definition
+type fragment struct {
+	name       string
+	on         string
+	directives []*directive
+	selections []selection
+}
+
+// selection is a field, fragment spread or inline fragment
+type selection interface {
+	selectionDirectives() []*directive
+}
+
+// fieldNode selects a field
+type fieldNode struct {
+	alias      string
+	name       string
+	args       []*argumentNode
+	directives []*directive
+	selections []selection
+}
+
+// responseKey is the key of the field in the response
+func (f *fieldNode) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread includes a named fragment
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+// inlineFragment includes selections, optionally for a type only
+type inlineFragment struct {
+	on         string
+	directives []*directive
+	selections []selection
+}
+
+func (f *fieldNode) selectionDirectives() []*directive      { return f.directives }
+func (f *fragmentSpread) selectionDirectives() []*directive { return f.directives }
+func (f *inlineFragment) selectionDirectives() []*directive { return f.directives }
+
+// argumentNode is an argument of a field or directive
+type argumentNode struct {
+	name  string
+	value any
+}
+
+// directive is a directive such as @skip(if: true)
+type directive struct {
+	name string
+	args []*argumentNode
+}
+
+// Values of a document are int64, float64, string, bool, nil, []any,
+// map[string]any or one of these
+type (
+	variableRef string
+	enumValue   string
+)
+
+// maxNesting limits how deeply selection sets, values and list types can be
+// nested in a document, so that parsing cannot recurse without bound; the validator
+// limits the depth of fields further
+const maxNesting = 32
+
+// parser is a recursive descent parser of request documents
+type parser struct {
+	lex   lexer
+	tok   token
+	depth int // Selection sets, values and list types being parsed
+}
+
+// parse parses a request document
+func parse(src string) (*document, error) {
+	p := &parser{lex: lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels})
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(tokenName, "fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, fmt.Errorf("fragment %q is defined more than once", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunct, "(") {
+		vars, err := p.variableDefs()
+		if err != nil {
+			return nil, err
+		}
+		op.variables = vars
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) variableDefs() ([]*variableDef, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+	var defs []*variableDef
+	for !p.peek(tokenPunct, ")") {
+		if err := p.expect(tokenPunct, "$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		def := &variableDef{name: name, typ: typ}
+		if p.peek(tokenPunct, "=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if def.defValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+			def.hasDef = true
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+func (p *parser) typeRef() (typeRef, error) {
+	var t typeRef
+	if p.peek(tokenPunct, "[") {
+		if err := p.nest(); err != nil {
+			return t, err
+		}
+		defer p.unnest()
+		if err := p.advance(); err != nil {
+			return t, err
+		}
+		elem, err := p.typeRef()
+		if err != nil {
+			return t, err
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return t, err
+		}
+		t.elem = &elem
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return t, err
+		}
+		t.name = name
+	}
+	if p.peek(tokenPunct, "!") {
+		t.nonNull = true
+		return t, p.advance()
+	}
+	return t, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("fragment cannot be named \"on\"")
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return nil, err
+	}
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := p.directives()
+	if err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, on: on, directives: dirs, selections: sels}, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer p.unnest()
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.peek(tokenPunct, "}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, syntaxError(p.lex.src, p.tok.pos, "empty selection set")
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.peek(tokenPunct, "...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			name := p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			dirs, err := p.directives()
+			if err != nil {
+				return nil, err
+			}
+			return &fragmentSpread{name: name, directives: dirs}, nil
+		}
+		frag := &inlineFragment{}
+		if p.peek(tokenName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			on, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			frag.on = on
+		}
+		var err error
+		if frag.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if frag.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+		return frag, nil
+	}
+
+	f := &fieldNode{}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.name = name
+	if p.peek(tokenPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunct, "(") {
+		if f.args, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunct, "{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*argumentNode, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+	var args []*argumentNode
+	for !p.peek(tokenPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args {
+			if arg.name == name {
+				return nil, fmt.Errorf("argument %q is given more than once", name)
+			}
+		}
+		args = append(args, &argumentNode{name: name, value: value})
+	}
+	if len(args) == 0 {
+		return nil, syntaxError(p.lex.src, p.tok.pos, "empty argument list")
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := &directive{name: name}
+		if p.peek(tokenPunct, "(") {
+			if d.args, err = p.arguments(false); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value parses a value; constant values cannot contain variables
+func (p *parser) value(constant bool) (any, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, syntaxError(p.lex.src, tok.pos, "integer out of range")
+		}
+		return n, p.advance()
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, syntaxError(p.lex.src, tok.pos, "float out of range")
+		}
+		return f, p.advance()
+	case tokenString:
+		return tok.value, p.advance()
+	case tokenName:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.value), nil
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, syntaxError(p.lex.src, tok.pos, "unexpected variable in constant value")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return variableRef(name), nil
+		case "[":
+			if err := p.nest(); err != nil {
+				return nil, err
+			}
+			defer p.unnest()
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			list := []any{}
+			for !p.peek(tokenPunct, "]") {
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, p.advance()
+		case "{":
+			if err := p.nest(); err != nil {
+				return nil, err
+			}
+			defer p.unnest()
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			obj := map[string]any{}
+			for !p.peek(tokenPunct, "}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(tokenPunct, ":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, p.advance()
+		}
+	}
+	return nil, p.unexpected()
+}
+
+// nest enters a selection set, a list or object value or a list type
+func (p *parser) nest() error {
+	if p.depth++; p.depth > maxNesting {
+		return syntaxError(p.lex.src, p.tok.pos, fmt.Sprintf("document is nested more than %d levels deep", maxNesting))
+	}
+	return nil
+}
+
+// unnest leaves what nest entered
+func (p *parser) unnest() {
+	p.depth--
+}
+
+// advance reads the next token
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is the given one
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// expect consumes the given token or fails
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.peek(kind, value) {
+		return syntaxError(p.lex.src, p.tok.pos, fmt.Sprintf("expected %q, found %s", value, p.describe()))
+	}
+	return p.advance()
+}
+
+// name consumes a name
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", syntaxError(p.lex.src, p.tok.pos, "expected a name, found "+p.describe())
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	return syntaxError(p.lex.src, p.tok.pos, "unexpected "+p.describe())
+}
+
+// describe describes the current token for error messages
+func (p *parser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "end of document"
+	}
+	return strconv.Quote(p.tok.value)
+}
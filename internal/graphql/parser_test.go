@@ -0,0 +1,158 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		query Reports($repo: String!, $years: [Int!] = [2025, 2026], $brief: Boolean = false) {
+			latest: reports(repository: $repo, filter: {years: $years, min: 1.5}) @include(if: $brief) {
+				...ReportFields
+				... on Report { id }
+				... @skip(if: true) { summary }
+			}
+		}
+		fragment ReportFields on Report { week, commits: commitCount }
+		{ __typename }
+	`)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if len(doc.operations) != 2 || len(doc.fragments) != 1 {
+		t.Fatalf("parse() = %d operations and %d fragments, want 2 and 1", len(doc.operations), len(doc.fragments))
+	}
+
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Reports" {
+		t.Errorf("operation = %s %s, want query Reports", op.kind, op.name)
+	}
+	var types []string
+	for _, v := range op.variables {
+		types = append(types, v.name+":"+v.typ.String())
+	}
+	if got := strings.Join(types, " "); got != "repo:String! years:[Int!] brief:Boolean" {
+		t.Errorf("variables = %s", got)
+	}
+	if v := op.variables[1]; !v.hasDef || !reflect.DeepEqual(v.defValue, []any{int64(2025), int64(2026)}) {
+		t.Errorf("default of $years = %#v", v.defValue)
+	}
+
+	f := op.selections[0].(*fieldNode)
+	if f.name != "reports" || f.responseKey() != "latest" {
+		t.Errorf("field = %s as %s, want reports as latest", f.name, f.responseKey())
+	}
+	if len(f.directives) != 1 || f.directives[0].name != "include" || f.directives[0].args[0].value != variableRef("brief") {
+		t.Errorf("directives = %#v", f.directives)
+	}
+	wantFilter := map[string]any{"years": variableRef("years"), "min": 1.5}
+	if len(f.args) != 2 || f.args[0].value != variableRef("repo") || !reflect.DeepEqual(f.args[1].value, wantFilter) {
+		t.Errorf("arguments = %#v", f.args)
+	}
+	if _, ok := f.selections[0].(*fragmentSpread); !ok {
+		t.Errorf("selection 0 = %T, want a fragment spread", f.selections[0])
+	}
+	if frag, ok := f.selections[1].(*inlineFragment); !ok || frag.on != "Report" {
+		t.Errorf("selection 1 = %#v, want an inline fragment on Report", f.selections[1])
+	}
+	if frag, ok := f.selections[2].(*inlineFragment); !ok || frag.on != "" || len(frag.directives) != 1 {
+		t.Errorf("selection 2 = %#v, want an inline fragment with a directive", f.selections[2])
+	}
+
+	frag := doc.fragments["ReportFields"]
+	if frag.on != "Report" || len(frag.selections) != 2 || frag.selections[1].(*fieldNode).alias != "commits" {
+		t.Errorf("fragment = %#v", frag)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"42", int64(42)},
+		{"-1.25e2", -125.0},
+		{`"text"`, "text"},
+		{"true", true},
+		{"false", false},
+		{"null", nil},
+		{"OPEN", enumValue("OPEN")},
+		{"[]", []any{}},
+		{"[1, [2], {a: null}]", []any{int64(1), []any{int64(2)}, map[string]any{"a": nil}}},
+	}
+	for _, tt := range tests {
+		p := &parser{lex: lexer{src: tt.src}}
+		if err := p.advance(); err != nil {
+			t.Fatalf("lexing %q failed: %v", tt.src, err)
+		}
+		got, err := p.value(true)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("value(%q) = %#v, %v, want %#v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty document", "  # nothing", "document has no operations"},
+		{"empty selection set", "{ }", "empty selection set"},
+		{"unclosed selection set", "{ a { b }", "expected a name, found end of document"},
+		{"missing field name", "{ a: }", `expected a name, found "}"`},
+		{"empty arguments", "{ a() }", "empty argument list"},
+		{"repeated argument", "{ a(x: 1, x: 2) }", `argument "x" is given more than once`},
+		{"variable in default", "query ($a: Int = $b) { a }", "unexpected variable in constant value"},
+		{"variable without type", "query ($a) { a }", `expected ":"`},
+		{"unclosed list type", "query ($a: [Int) { a }", `expected "]"`},
+		{"integer out of range", "{ a(x: 99999999999999999999) }", "integer out of range"},
+		{"unclosed list value", "{ a(x: [1, 2) }", `unexpected ")"`},
+		{"duplicate fragment", "{ ...F } fragment F on Q { a } fragment F on Q { b }", `fragment "F" is defined more than once`},
+		{"fragment without type", "{ ...F } fragment F { a }", `expected "on"`},
+		{"stray token", "{ a } }", `unexpected "}"`},
+		{"error position", "{\n  a(x: \"open\n}", "line 2, column 8: unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parse(%q) error = %v, want it to contain %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	nested := func(open, close string, n int) string {
+		return strings.Repeat(open, n) + "1" + strings.Repeat(close, n)
+	}
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"selection sets", "{" + strings.Repeat("a {", maxNesting) + "b" + strings.Repeat("}", maxNesting+1)},
+		{"list values", "{ a(x: " + nested("[", "]", maxNesting+1) + ") }"},
+		{"object values", "{ a(x: " + strings.Repeat("{x: ", maxNesting+1) + "1" + strings.Repeat("}", maxNesting+1) + ") }"},
+		{"list types", "query ($a: " + strings.Repeat("[", maxNesting+1) + "Int" + strings.Repeat("]", maxNesting+1) + ") { a }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src)
+			if err == nil || !strings.Contains(err.Error(), "nested more than") {
+				t.Errorf("parse() error = %v, want the nesting limit", err)
+			}
+		})
+	}
+
+	// Without the limit, these would recurse a million levels deep
+	if _, err := parse("{ a(x: " + strings.Repeat("[", 1<<20) + ") }"); err == nil {
+		t.Error("parse() of deeply nested lists should fail")
+	}
+	if _, err := parse("{ a(x: " + nested("[", "]", maxNesting-1) + ") }"); err != nil {
+		t.Errorf("parse() within the nesting limit error = %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a field or argument: a *Scalar, an *Object, or a
+// List or NonNull of another type
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name        string
+	Description string
+	serialize   func(v any) (any, error)
+	parse       func(v any) (any, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// The built-in scalars, and Time for timestamps in RFC 3339 format.
+// Arguments of these types are passed to resolvers as int, float64, string,
+// bool and time.Time.
+var (
+	Int = &Scalar{
+		Name:      "Int",
+		serialize: serializeInt,
+		parse: func(v any) (any, error) {
+			n, err := parseInt(v)
+			if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", describeValue(v))
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		serialize: func(v any) (any, error) {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Float32, reflect.Float64:
+				return rv.Float(), nil
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return float64(rv.Int()), nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %T", v)
+		},
+		parse: func(v any) (any, error) {
+			switch v := v.(type) {
+			case float64:
+				return v, nil
+			case int:
+				return float64(v), nil
+			case int64:
+				return float64(v), nil
+			case json.Number:
+				if f, err := v.Float64(); err == nil {
+					return f, nil
+				}
+			}
+			return nil, fmt.Errorf("Float cannot represent %v", describeValue(v))
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		serialize: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %T", v)
+		},
+		parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %v", describeValue(v))
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		serialize: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %T", v)
+		},
+		parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %v", describeValue(v))
+		},
+	}
+	ID = &Scalar{
+		Name: "ID",
+		serialize: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			n, err := serializeInt64(v)
+			if err != nil {
+				return nil, fmt.Errorf("ID cannot represent %T", v)
+			}
+			return strconv.FormatInt(n, 10), nil
+		},
+		parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			n, err := parseInt(v)
+			if err != nil {
+				return nil, fmt.Errorf("ID cannot represent %v", describeValue(v))
+			}
+			return strconv.FormatInt(n, 10), nil
+		},
+	}
+	Time = &Scalar{
+		Name:        "Time",
+		Description: "A timestamp in RFC 3339 format",
+		serialize: func(v any) (any, error) {
+			if t, ok := v.(time.Time); ok {
+				return t.Format(time.RFC3339), nil
+			}
+			return nil, fmt.Errorf("Time cannot represent %T", v)
+		},
+		parse: func(v any) (any, error) {
+			switch v := v.(type) {
+			case time.Time:
+				return v, nil
+			case string:
+				if t, err := time.Parse(time.RFC3339, v); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("Time cannot represent %v", describeValue(v))
+		},
+	}
+)
+
+// builtinScalars are the scalars variables can be declared with
+var builtinScalars = map[string]*Scalar{
+	Int.Name:     Int,
+	Float.Name:   Float,
+	String.Name:  String,
+	Boolean.Name: Boolean,
+	ID.Name:      ID,
+	Time.Name:    Time,
+}
+
+// Object is an object type
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// field returns the field with the given name, or nil
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// List is a list of another type
+type List struct {
+	Of Type
+}
+
+func (l List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a type that cannot be null
+type NonNull struct {
+	Of Type
+}
+
+func (n NonNull) String() string { return n.Of.String() + "!" }
+
+// ResolveFunc returns the value of a field of source, which is the value the
+// parent field resolved to and nil for the fields of Query. Arguments that
+// were neither given nor have a default are missing from args.
+type ResolveFunc func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Field is a field of an object type. Without a resolver, the field's value
+// is the entry of the same name when source is a map[string]any.
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+	Resolve     ResolveFunc
+}
+
+// argument returns the argument with the given name, or nil
+func (f *Field) argument(name string) *Argument {
+	for _, a := range f.Args {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Argument is an argument of a field. Arguments can only have scalar types
+// and lists of them.
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     any
+}
+
+// Schema is a GraphQL schema with queries only
+type Schema struct {
+	Query *Object
+}
+
+// NewSchema returns a schema with the given query type
+func NewSchema(query *Object) *Schema {
+	return &Schema{Query: query}
+}
+
+// SDL returns the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	seen := map[string]bool{}
+	var types []Type
+	var visit func(t Type)
+	visit = func(t Type) {
+		switch t := t.(type) {
+		case List:
+			visit(t.Of)
+		case NonNull:
+			visit(t.Of)
+		case *Scalar:
+			if !seen[t.Name] {
+				seen[t.Name] = true
+				types = append(types, t)
+			}
+		case *Object:
+			if seen[t.Name] {
+				return
+			}
+			seen[t.Name] = true
+			types = append(types, t)
+			for _, f := range t.Fields {
+				for _, a := range f.Args {
+					visit(a.Type)
+				}
+				visit(f.Type)
+			}
+		}
+	}
+	visit(s.Query)
+
+	for _, t := range types {
+		switch t := t.(type) {
+		case *Scalar:
+			if _, builtin := builtinScalars[t.Name]; builtin && t != Time {
+				continue
+			}
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "scalar %s\n\n", t.Name)
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "type %s {\n", t.Name)
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, len(f.Args))
+					for i, a := range f.Args {
+						args[i] = a.Name + ": " + a.Type.String()
+						if a.Default != nil {
+							args[i] += " = " + literal(a.Default)
+						}
+					}
+					b.WriteString("(" + strings.Join(args, ", ") + ")")
+				}
+				b.WriteString(": " + f.Type.String() + "\n")
+			}
+			b.WriteString("}\n\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeDescription writes a description above a definition
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description != "" {
+		b.WriteString(indent + literal(description) + "\n")
+	}
+}
+
+// literal formats a Go value as a GraphQL literal
+func literal(v any) string {
+	switch v := v.(type) {
+	case string:
+		s := strings.ReplaceAll(v, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		s = strings.ReplaceAll(s, "\n", `\n`)
+		return `"` + s + `"`
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = literal(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// serializeInt serializes integer kinds as Int
+func serializeInt(v any) (any, error) {
+	n, err := serializeInt64(v)
+	if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+		return nil, fmt.Errorf("Int cannot represent %v", v)
+	}
+	return int(n), nil
+}
+
+func serializeInt64(v any) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil
+	}
+	return 0, fmt.Errorf("not an integer: %T", v)
+}
+
+// parseInt parses an integer input value
+func parseInt(v any) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case json.Number:
+		return v.Int64()
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+	}
+	return 0, fmt.Errorf("not an integer: %v", v)
+}
+
+// describeValue describes an input value for error messages
+func describeValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case enumValue:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
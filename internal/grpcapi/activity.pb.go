@@ -0,0 +1,936 @@
+// gRPC API of the activity server, for services that prefer typed clients
+// over the web pages. Calls authenticate with a workspace API token (created
+// on /admin/workspaces) in an "authorization: Bearer <token>" metadata entry
+// and only see the token's workspace.
+//
+// The server's code in activity.pb.go and activity_grpc.pb.go is generated
+// from this file with protoc-gen-go and protoc-gen-go-grpc; run go generate
+// after changing it.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: activity.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Repository struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Branch        string                 `protobuf:"bytes,4,opt,name=branch,proto3" json:"branch,omitempty"`
+	Active        bool                   `protobuf:"varint,5,opt,name=active,proto3" json:"active,omitempty"`
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LastRunAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_run_at,json=lastRunAt,proto3" json:"last_run_at,omitempty"` // Unset if never analyzed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Repository) Reset() {
+	*x = Repository{}
+	mi := &file_activity_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Repository) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Repository) ProtoMessage() {}
+
+func (x *Repository) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Repository.ProtoReflect.Descriptor instead.
+func (*Repository) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Repository) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Repository) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Repository) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Repository) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *Repository) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Repository) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Repository) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Repository) GetLastRunAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastRunAt
+	}
+	return nil
+}
+
+type ListRepositoriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActiveOnly    bool                   `protobuf:"varint,1,opt,name=active_only,json=activeOnly,proto3" json:"active_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRepositoriesRequest) Reset() {
+	*x = ListRepositoriesRequest{}
+	mi := &file_activity_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRepositoriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRepositoriesRequest) ProtoMessage() {}
+
+func (x *ListRepositoriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRepositoriesRequest.ProtoReflect.Descriptor instead.
+func (*ListRepositoriesRequest) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListRepositoriesRequest) GetActiveOnly() bool {
+	if x != nil {
+		return x.ActiveOnly
+	}
+	return false
+}
+
+type ListRepositoriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repositories  []*Repository          `protobuf:"bytes,1,rep,name=repositories,proto3" json:"repositories,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRepositoriesResponse) Reset() {
+	*x = ListRepositoriesResponse{}
+	mi := &file_activity_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRepositoriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRepositoriesResponse) ProtoMessage() {}
+
+func (x *ListRepositoriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRepositoriesResponse.ProtoReflect.Descriptor instead.
+func (*ListRepositoriesResponse) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListRepositoriesResponse) GetRepositories() []*Repository {
+	if x != nil {
+		return x.Repositories
+	}
+	return nil
+}
+
+type GetRepositoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRepositoryRequest) Reset() {
+	*x = GetRepositoryRequest{}
+	mi := &file_activity_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRepositoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRepositoryRequest) ProtoMessage() {}
+
+func (x *GetRepositoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRepositoryRequest.ProtoReflect.Descriptor instead.
+func (*GetRepositoryRequest) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRepositoryRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Report struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Repository    string                 `protobuf:"bytes,2,opt,name=repository,proto3" json:"repository,omitempty"`
+	Year          int32                  `protobuf:"varint,3,opt,name=year,proto3" json:"year,omitempty"`
+	Week          int32                  `protobuf:"varint,4,opt,name=week,proto3" json:"week,omitempty"`
+	WeekLabel     string                 `protobuf:"bytes,5,opt,name=week_label,json=weekLabel,proto3" json:"week_label,omitempty"` // e.g. "2026-W02"
+	WeekStart     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=week_start,json=weekStart,proto3" json:"week_start,omitempty"`
+	WeekEnd       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=week_end,json=weekEnd,proto3" json:"week_end,omitempty"`
+	Summary       string                 `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"` // Markdown
+	CommitCount   int32                  `protobuf:"varint,9,opt,name=commit_count,json=commitCount,proto3" json:"commit_count,omitempty"`
+	AgentMode     bool                   `protobuf:"varint,10,opt,name=agent_mode,json=agentMode,proto3" json:"agent_mode,omitempty"`
+	ReviewState   string                 `protobuf:"bytes,11,opt,name=review_state,json=reviewState,proto3" json:"review_state,omitempty"` // "draft" or "approved"
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	mi := &file_activity_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Report) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Report) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *Report) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *Report) GetWeek() int32 {
+	if x != nil {
+		return x.Week
+	}
+	return 0
+}
+
+func (x *Report) GetWeekLabel() string {
+	if x != nil {
+		return x.WeekLabel
+	}
+	return ""
+}
+
+func (x *Report) GetWeekStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WeekStart
+	}
+	return nil
+}
+
+func (x *Report) GetWeekEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WeekEnd
+	}
+	return nil
+}
+
+func (x *Report) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *Report) GetCommitCount() int32 {
+	if x != nil {
+		return x.CommitCount
+	}
+	return 0
+}
+
+func (x *Report) GetAgentMode() bool {
+	if x != nil {
+		return x.AgentMode
+	}
+	return false
+}
+
+func (x *Report) GetReviewState() string {
+	if x != nil {
+		return x.ReviewState
+	}
+	return ""
+}
+
+func (x *Report) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Report) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListReportsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repository    string                 `protobuf:"bytes,1,opt,name=repository,proto3" json:"repository,omitempty"` // All repositories if empty
+	Year          int32                  `protobuf:"varint,2,opt,name=year,proto3" json:"year,omitempty"`
+	MinCommits    int32                  `protobuf:"varint,3,opt,name=min_commits,json=minCommits,proto3" json:"min_commits,omitempty"`
+	PageSize      int32                  `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"` // Default 20, at most 100
+	Page          int32                  `protobuf:"varint,5,opt,name=page,proto3" json:"page,omitempty"`                         // From 1
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReportsRequest) Reset() {
+	*x = ListReportsRequest{}
+	mi := &file_activity_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReportsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReportsRequest) ProtoMessage() {}
+
+func (x *ListReportsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReportsRequest.ProtoReflect.Descriptor instead.
+func (*ListReportsRequest) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListReportsRequest) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *ListReportsRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *ListReportsRequest) GetMinCommits() int32 {
+	if x != nil {
+		return x.MinCommits
+	}
+	return 0
+}
+
+func (x *ListReportsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListReportsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+type ListReportsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reports       []*Report              `protobuf:"bytes,1,rep,name=reports,proto3" json:"reports,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"` // Reports matching the filter on all pages
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReportsResponse) Reset() {
+	*x = ListReportsResponse{}
+	mi := &file_activity_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReportsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReportsResponse) ProtoMessage() {}
+
+func (x *ListReportsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReportsResponse.ProtoReflect.Descriptor instead.
+func (*ListReportsResponse) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListReportsResponse) GetReports() []*Report {
+	if x != nil {
+		return x.Reports
+	}
+	return nil
+}
+
+func (x *ListReportsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReportRequest) Reset() {
+	*x = GetReportRequest{}
+	mi := &file_activity_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReportRequest) ProtoMessage() {}
+
+func (x *GetReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReportRequest.ProtoReflect.Descriptor instead.
+func (*GetReportRequest) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetReportRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GenerateReportsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repository    string                 `protobuf:"bytes,1,opt,name=repository,proto3" json:"repository,omitempty"` // All active repositories if empty
+	Week          string                 `protobuf:"bytes,2,opt,name=week,proto3" json:"week,omitempty"`             // ISO week, e.g. "2026-W02"
+	Since         string                 `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`           // Backfill from a date, YYYY-MM-DD
+	Force         bool                   `protobuf:"varint,4,opt,name=force,proto3" json:"force,omitempty"`          // Regenerate existing reports
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateReportsRequest) Reset() {
+	*x = GenerateReportsRequest{}
+	mi := &file_activity_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateReportsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateReportsRequest) ProtoMessage() {}
+
+func (x *GenerateReportsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateReportsRequest.ProtoReflect.Descriptor instead.
+func (*GenerateReportsRequest) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GenerateReportsRequest) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *GenerateReportsRequest) GetWeek() string {
+	if x != nil {
+		return x.Week
+	}
+	return ""
+}
+
+func (x *GenerateReportsRequest) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+func (x *GenerateReportsRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type GenerateReportsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*GenerateResult      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateReportsResponse) Reset() {
+	*x = GenerateReportsResponse{}
+	mi := &file_activity_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateReportsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateReportsResponse) ProtoMessage() {}
+
+func (x *GenerateReportsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateReportsResponse.ProtoReflect.Descriptor instead.
+func (*GenerateReportsResponse) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GenerateReportsResponse) GetResults() []*GenerateResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type GenerateResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repository    string                 `protobuf:"bytes,1,opt,name=repository,proto3" json:"repository,omitempty"`
+	WeekLabel     string                 `protobuf:"bytes,2,opt,name=week_label,json=weekLabel,proto3" json:"week_label,omitempty"`
+	Generated     int32                  `protobuf:"varint,3,opt,name=generated,proto3" json:"generated,omitempty"`
+	Skipped       int32                  `protobuf:"varint,4,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	NoCommits     int32                  `protobuf:"varint,5,opt,name=no_commits,json=noCommits,proto3" json:"no_commits,omitempty"`
+	ReportId      int64                  `protobuf:"varint,6,opt,name=report_id,json=reportId,proto3" json:"report_id,omitempty"` // Set when a single week was generated
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateResult) Reset() {
+	*x = GenerateResult{}
+	mi := &file_activity_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateResult) ProtoMessage() {}
+
+func (x *GenerateResult) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateResult.ProtoReflect.Descriptor instead.
+func (*GenerateResult) Descriptor() ([]byte, []int) {
+	return file_activity_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GenerateResult) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *GenerateResult) GetWeekLabel() string {
+	if x != nil {
+		return x.WeekLabel
+	}
+	return ""
+}
+
+func (x *GenerateResult) GetGenerated() int32 {
+	if x != nil {
+		return x.Generated
+	}
+	return 0
+}
+
+func (x *GenerateResult) GetSkipped() int32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+func (x *GenerateResult) GetNoCommits() int32 {
+	if x != nil {
+		return x.NoCommits
+	}
+	return 0
+}
+
+func (x *GenerateResult) GetReportId() int64 {
+	if x != nil {
+		return x.ReportId
+	}
+	return 0
+}
+
+var File_activity_proto protoreflect.FileDescriptor
+
+const file_activity_proto_rawDesc = "" +
+	"\n" +
+	"\x0eactivity.proto\x12\vactivity.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8b\x02\n" +
+	"\n" +
+	"Repository\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x16\n" +
+	"\x06branch\x18\x04 \x01(\tR\x06branch\x12\x16\n" +
+	"\x06active\x18\x05 \x01(\bR\x06active\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12:\n" +
+	"\vlast_run_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tlastRunAt\":\n" +
+	"\x17ListRepositoriesRequest\x12\x1f\n" +
+	"\vactive_only\x18\x01 \x01(\bR\n" +
+	"activeOnly\"W\n" +
+	"\x18ListRepositoriesResponse\x12;\n" +
+	"\frepositories\x18\x01 \x03(\v2\x17.activity.v1.RepositoryR\frepositories\"*\n" +
+	"\x14GetRepositoryRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xe6\x03\n" +
+	"\x06Report\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x02 \x01(\tR\n" +
+	"repository\x12\x12\n" +
+	"\x04year\x18\x03 \x01(\x05R\x04year\x12\x12\n" +
+	"\x04week\x18\x04 \x01(\x05R\x04week\x12\x1d\n" +
+	"\n" +
+	"week_label\x18\x05 \x01(\tR\tweekLabel\x129\n" +
+	"\n" +
+	"week_start\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tweekStart\x125\n" +
+	"\bweek_end\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aweekEnd\x12\x18\n" +
+	"\asummary\x18\b \x01(\tR\asummary\x12!\n" +
+	"\fcommit_count\x18\t \x01(\x05R\vcommitCount\x12\x1d\n" +
+	"\n" +
+	"agent_mode\x18\n" +
+	" \x01(\bR\tagentMode\x12!\n" +
+	"\freview_state\x18\v \x01(\tR\vreviewState\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x9a\x01\n" +
+	"\x12ListReportsRequest\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x01 \x01(\tR\n" +
+	"repository\x12\x12\n" +
+	"\x04year\x18\x02 \x01(\x05R\x04year\x12\x1f\n" +
+	"\vmin_commits\x18\x03 \x01(\x05R\n" +
+	"minCommits\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04page\x18\x05 \x01(\x05R\x04page\"Z\n" +
+	"\x13ListReportsResponse\x12-\n" +
+	"\areports\x18\x01 \x03(\v2\x13.activity.v1.ReportR\areports\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"\"\n" +
+	"\x10GetReportRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"x\n" +
+	"\x16GenerateReportsRequest\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x01 \x01(\tR\n" +
+	"repository\x12\x12\n" +
+	"\x04week\x18\x02 \x01(\tR\x04week\x12\x14\n" +
+	"\x05since\x18\x03 \x01(\tR\x05since\x12\x14\n" +
+	"\x05force\x18\x04 \x01(\bR\x05force\"P\n" +
+	"\x17GenerateReportsResponse\x125\n" +
+	"\aresults\x18\x01 \x03(\v2\x1b.activity.v1.GenerateResultR\aresults\"\xc3\x01\n" +
+	"\x0eGenerateResult\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x01 \x01(\tR\n" +
+	"repository\x12\x1d\n" +
+	"\n" +
+	"week_label\x18\x02 \x01(\tR\tweekLabel\x12\x1c\n" +
+	"\tgenerated\x18\x03 \x01(\x05R\tgenerated\x12\x18\n" +
+	"\askipped\x18\x04 \x01(\x05R\askipped\x12\x1d\n" +
+	"\n" +
+	"no_commits\x18\x05 \x01(\x05R\tnoCommits\x12\x1b\n" +
+	"\treport_id\x18\x06 \x01(\x03R\breportId2\xb0\x03\n" +
+	"\x0fActivityService\x12_\n" +
+	"\x10ListRepositories\x12$.activity.v1.ListRepositoriesRequest\x1a%.activity.v1.ListRepositoriesResponse\x12K\n" +
+	"\rGetRepository\x12!.activity.v1.GetRepositoryRequest\x1a\x17.activity.v1.Repository\x12P\n" +
+	"\vListReports\x12\x1f.activity.v1.ListReportsRequest\x1a .activity.v1.ListReportsResponse\x12?\n" +
+	"\tGetReport\x12\x1d.activity.v1.GetReportRequest\x1a\x13.activity.v1.Report\x12\\\n" +
+	"\x0fGenerateReports\x12#.activity.v1.GenerateReportsRequest\x1a$.activity.v1.GenerateReportsResponseB,Z*github.com/perbu/activity/internal/grpcapib\x06proto3"
+
+var (
+	file_activity_proto_rawDescOnce sync.Once
+	file_activity_proto_rawDescData []byte
+)
+
+func file_activity_proto_rawDescGZIP() []byte {
+	file_activity_proto_rawDescOnce.Do(func() {
+		file_activity_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_activity_proto_rawDesc), len(file_activity_proto_rawDesc)))
+	})
+	return file_activity_proto_rawDescData
+}
+
+var file_activity_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_activity_proto_goTypes = []any{
+	(*Repository)(nil),               // 0: activity.v1.Repository
+	(*ListRepositoriesRequest)(nil),  // 1: activity.v1.ListRepositoriesRequest
+	(*ListRepositoriesResponse)(nil), // 2: activity.v1.ListRepositoriesResponse
+	(*GetRepositoryRequest)(nil),     // 3: activity.v1.GetRepositoryRequest
+	(*Report)(nil),                   // 4: activity.v1.Report
+	(*ListReportsRequest)(nil),       // 5: activity.v1.ListReportsRequest
+	(*ListReportsResponse)(nil),      // 6: activity.v1.ListReportsResponse
+	(*GetReportRequest)(nil),         // 7: activity.v1.GetReportRequest
+	(*GenerateReportsRequest)(nil),   // 8: activity.v1.GenerateReportsRequest
+	(*GenerateReportsResponse)(nil),  // 9: activity.v1.GenerateReportsResponse
+	(*GenerateResult)(nil),           // 10: activity.v1.GenerateResult
+	(*timestamppb.Timestamp)(nil),    // 11: google.protobuf.Timestamp
+}
+var file_activity_proto_depIdxs = []int32{
+	11, // 0: activity.v1.Repository.created_at:type_name -> google.protobuf.Timestamp
+	11, // 1: activity.v1.Repository.last_run_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: activity.v1.ListRepositoriesResponse.repositories:type_name -> activity.v1.Repository
+	11, // 3: activity.v1.Report.week_start:type_name -> google.protobuf.Timestamp
+	11, // 4: activity.v1.Report.week_end:type_name -> google.protobuf.Timestamp
+	11, // 5: activity.v1.Report.created_at:type_name -> google.protobuf.Timestamp
+	11, // 6: activity.v1.Report.updated_at:type_name -> google.protobuf.Timestamp
+	4,  // 7: activity.v1.ListReportsResponse.reports:type_name -> activity.v1.Report
+	10, // 8: activity.v1.GenerateReportsResponse.results:type_name -> activity.v1.GenerateResult
+	1,  // 9: activity.v1.ActivityService.ListRepositories:input_type -> activity.v1.ListRepositoriesRequest
+	3,  // 10: activity.v1.ActivityService.GetRepository:input_type -> activity.v1.GetRepositoryRequest
+	5,  // 11: activity.v1.ActivityService.ListReports:input_type -> activity.v1.ListReportsRequest
+	7,  // 12: activity.v1.ActivityService.GetReport:input_type -> activity.v1.GetReportRequest
+	8,  // 13: activity.v1.ActivityService.GenerateReports:input_type -> activity.v1.GenerateReportsRequest
+	2,  // 14: activity.v1.ActivityService.ListRepositories:output_type -> activity.v1.ListRepositoriesResponse
+	0,  // 15: activity.v1.ActivityService.GetRepository:output_type -> activity.v1.Repository
+	6,  // 16: activity.v1.ActivityService.ListReports:output_type -> activity.v1.ListReportsResponse
+	4,  // 17: activity.v1.ActivityService.GetReport:output_type -> activity.v1.Report
+	9,  // 18: activity.v1.ActivityService.GenerateReports:output_type -> activity.v1.GenerateReportsResponse
+	14, // [14:19] is the sub-list for method output_type
+	9,  // [9:14] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_activity_proto_init() }
+func file_activity_proto_init() {
+	if File_activity_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_activity_proto_rawDesc), len(file_activity_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_activity_proto_goTypes,
+		DependencyIndexes: file_activity_proto_depIdxs,
+		MessageInfos:      file_activity_proto_msgTypes,
+	}.Build()
+	File_activity_proto = out.File
+	file_activity_proto_goTypes = nil
+	file_activity_proto_depIdxs = nil
+}
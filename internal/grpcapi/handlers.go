@@ -0,0 +1,209 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes of ListReports
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListRepositories lists the repositories of the token's workspace
+func (s *Server) ListRepositories(ctx context.Context, req *ListRepositoriesRequest) (*ListRepositoriesResponse, error) {
+	var activeOnly *bool
+	if req.ActiveOnly {
+		activeOnly = &req.ActiveOnly
+	}
+	repos, err := s.db.ListRepositories(ctx, activeOnly)
+	if err != nil {
+		return nil, internalError("failed to list repositories", err)
+	}
+	resp := &ListRepositoriesResponse{}
+	for _, repo := range repos {
+		resp.Repositories = append(resp.Repositories, toRepository(repo))
+	}
+	return resp, nil
+}
+
+// GetRepository returns a repository by name
+func (s *Server) GetRepository(ctx context.Context, req *GetRepositoryRequest) (*Repository, error) {
+	repo, err := s.db.GetRepositoryByName(ctx, req.Name)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "repository not found: "+req.Name)
+	}
+	return toRepository(repo), nil
+}
+
+// ListReports returns a page of reports, newest week first
+func (s *Server) ListReports(ctx context.Context, req *ListReportsRequest) (*ListReportsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	page := max(int(req.Page), 1)
+
+	filter := db.ReportFilter{
+		Year:       int(req.Year),
+		MinCommits: int(req.MinCommits),
+		Limit:      pageSize,
+		Offset:     (page - 1) * pageSize,
+	}
+	if req.Repository != "" {
+		if _, err := s.db.GetRepositoryByName(ctx, req.Repository); err != nil {
+			return nil, status.Error(codes.NotFound, "repository not found: "+req.Repository)
+		}
+	}
+	reports, total, err := s.services.Report.ListPage(ctx, req.Repository, filter)
+	if err != nil {
+		return nil, internalError("failed to list reports", err)
+	}
+
+	names, err := s.repoNames(ctx)
+	if err != nil {
+		return nil, internalError("failed to list repositories", err)
+	}
+	resp := &ListReportsResponse{Total: int32(total)}
+	for _, report := range reports {
+		resp.Reports = append(resp.Reports, toReport(report, names[report.RepoID]))
+	}
+	return resp, nil
+}
+
+// GetReport returns a report by ID
+func (s *Server) GetReport(ctx context.Context, req *GetReportRequest) (*Report, error) {
+	report, err := s.db.GetWeeklyReport(ctx, req.Id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("report not found: %d", req.Id))
+	}
+	repo, err := s.db.GetRepository(ctx, report.RepoID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("report not found: %d", req.Id))
+	}
+	return toReport(report, repo.Name), nil
+}
+
+// GenerateReports generates weekly reports if web.grpc_generate allows it
+func (s *Server) GenerateReports(ctx context.Context, req *GenerateReportsRequest) (*GenerateReportsResponse, error) {
+	if !s.cfg.Web.GRPCGenerate {
+		return nil, status.Error(codes.PermissionDenied, "API tokens are read-only; set web.grpc_generate to allow generation")
+	}
+	if req.Week != "" && req.Since != "" {
+		return nil, status.Error(codes.InvalidArgument, "use either a week or a since date, not both")
+	}
+	if req.Week != "" {
+		if _, _, err := git.ParseISOWeek(req.Week); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if req.Since != "" {
+		if _, err := time.Parse("2006-01-02", req.Since); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid since date, want YYYY-MM-DD: "+req.Since)
+		}
+	}
+	if req.Repository != "" {
+		if _, err := s.db.GetRepositoryByName(ctx, req.Repository); err != nil {
+			return nil, status.Error(codes.NotFound, "repository not found: "+req.Repository)
+		}
+	}
+
+	results, err := s.services.Report.Generate(ctx, service.GenerateOptions{
+		RepoName: req.Repository,
+		Week:     req.Week,
+		Since:    req.Since,
+		Force:    req.Force,
+	})
+	if err != nil {
+		return nil, internalError("failed to generate reports", err)
+	}
+
+	resp := &GenerateReportsResponse{}
+	generated := 0
+	for _, r := range results {
+		generated += r.Generated
+		resp.Results = append(resp.Results, &GenerateResult{
+			Repository: r.RepoName,
+			WeekLabel:  r.WeekLabel,
+			Generated:  int32(r.Generated),
+			Skipped:    int32(r.Skipped),
+			NoCommits:  int32(r.NoCommits),
+			ReportId:   r.ReportID,
+		})
+	}
+
+	target := req.Repository
+	if target == "" {
+		target = "all repositories"
+	}
+	details := fmt.Sprintf("Generated %d reports via gRPC", generated)
+	if err := s.db.RecordAudit(context.WithoutCancel(ctx), actor(ctx), "report.generate", target, details); err != nil {
+		slog.Error("Failed to record audit entry", "action", "report.generate", "target", target, "error", err)
+	}
+	return resp, nil
+}
+
+// repoNames maps the IDs of the context's repositories to their names
+func (s *Server) repoNames(ctx context.Context) (map[int64]string, error) {
+	repos, err := s.db.ListRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(repos))
+	for _, repo := range repos {
+		names[repo.ID] = repo.Name
+	}
+	return names, nil
+}
+
+// toRepository converts a repository to its message
+func toRepository(repo *db.Repository) *Repository {
+	return &Repository{
+		Id:          repo.ID,
+		Name:        repo.Name,
+		Url:         repo.URL,
+		Branch:      repo.Branch,
+		Active:      repo.Active,
+		Description: repo.Description.String,
+		CreatedAt:   timestamp(repo.CreatedAt),
+		LastRunAt:   timestamp(repo.LastRunAt.Time),
+	}
+}
+
+// toReport converts a weekly report to its message
+func toReport(report *db.WeeklyReport, repoName string) *Report {
+	return &Report{
+		Id:          report.ID,
+		Repository:  repoName,
+		Year:        int32(report.Year),
+		Week:        int32(report.Week),
+		WeekLabel:   git.FormatISOWeek(report.Year, report.Week),
+		WeekStart:   timestamp(report.WeekStart),
+		WeekEnd:     timestamp(report.WeekEnd),
+		Summary:     report.Summary.String,
+		CommitCount: int32(report.CommitCount),
+		AgentMode:   report.AgentMode,
+		ReviewState: report.ReviewState,
+		CreatedAt:   timestamp(report.CreatedAt),
+		UpdatedAt:   timestamp(report.UpdatedAt),
+	}
+}
+
+// timestamp converts a time to its message, leaving a zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
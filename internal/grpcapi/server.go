@@ -0,0 +1,123 @@
+// Package grpcapi serves the gRPC API described by activity.proto, on top of
+// the same service layer as the web UI.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative activity.proto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tokenKey is the context key of the API token a call authenticated with
+type tokenKey struct{}
+
+// Server is the gRPC server
+type Server struct {
+	UnimplementedActivityServiceServer
+
+	db       *db.DB
+	services *service.Services
+	cfg      *config.Config
+	grpc     *grpc.Server
+}
+
+// NewServer creates a gRPC server listening on the configured address
+func NewServer(database *db.DB, services *service.Services, cfg *config.Config) *Server {
+	s := &Server{
+		db:       database,
+		services: services,
+		cfg:      cfg,
+	}
+	s.grpc = grpc.NewServer(grpc.UnaryInterceptor(s.authenticate))
+	RegisterActivityServiceServer(s.grpc, s)
+	return s
+}
+
+// Start listens and serves calls until Shutdown
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.cfg.Web.GRPCAddress)
+	if err != nil {
+		return err
+	}
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown stops accepting calls and waits for running ones to finish. When
+// ctx expires first, the running calls are cancelled and the context's error
+// is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	return s.cfg.Web.GRPCAddress
+}
+
+// authenticate checks the API token of each call and scopes the call to the
+// token's workspace
+func (s *Server) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var value string
+	if values := md.Get("authorization"); len(values) > 0 {
+		value, _ = strings.CutPrefix(values[0], "Bearer ")
+		value = strings.TrimSpace(value)
+	}
+	if value == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing API token")
+	}
+	token, err := s.services.Workspace.Authenticate(ctx, value)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid API token")
+	}
+
+	slog.Info("grpc call", "method", info.FullMethod, "token", token.Name, "workspace_id", token.WorkspaceID)
+	ctx = db.WithWorkspace(ctx, token.WorkspaceID)
+	ctx = db.WithVisibility(ctx, db.VisibilityAuthenticated)
+	ctx = context.WithValue(ctx, tokenKey{}, token)
+	resp, err := handler(ctx, req)
+	if err != nil {
+		slog.Warn("grpc call failed", "method", info.FullMethod, "token", token.Name, "error", err)
+	}
+	return resp, err
+}
+
+// actor names the API token of a call in the audit log, as the web UI does
+func actor(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenKey{}).(*db.APIToken); ok {
+		return "token:" + token.Name
+	}
+	return "unknown"
+}
+
+// internalError returns err as an internal error status, or as a
+// cancellation status if the call was cancelled
+func internalError(msg string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, msg+": "+err.Error())
+}
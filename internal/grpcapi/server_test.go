@@ -0,0 +1,133 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// startServer serves s on a local port and returns a client of it
+func startServer(t *testing.T, s *Server) ActivityServiceClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go s.grpc.Serve(listener)
+	t.Cleanup(s.grpc.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewActivityServiceClient(conn)
+}
+
+func TestCallWithoutToken(t *testing.T) {
+	client := startServer(t, NewServer(nil, nil, config.DefaultConfig()))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.ListRepositories(ctx, &ListRepositoriesRequest{ActiveOnly: true})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ListRepositories() without a token error = %v, want Unauthenticated", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer ")
+	_, err = client.GetReport(ctx, &GetReportRequest{Id: 1})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetReport() with an empty token error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestGenerateReportsValidation(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s := NewServer(nil, nil, cfg)
+	ctx := context.Background()
+
+	if _, err := s.GenerateReports(ctx, &GenerateReportsRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("GenerateReports() without web.grpc_generate error = %v, want PermissionDenied", err)
+	}
+
+	cfg.Web.GRPCGenerate = true
+	tests := []struct {
+		name string
+		req  *GenerateReportsRequest
+	}{
+		{"week and since", &GenerateReportsRequest{Week: "2026-W02", Since: "2026-01-01"}},
+		{"invalid week", &GenerateReportsRequest{Week: "2026-02"}},
+		{"invalid since", &GenerateReportsRequest{Since: "01/01/2026"}},
+	}
+	for _, tt := range tests {
+		if _, err := s.GenerateReports(ctx, tt.req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GenerateReports() with %s error = %v, want InvalidArgument", tt.name, err)
+		}
+	}
+}
+
+func TestMessagesRoundTrip(t *testing.T) {
+	created := time.Date(2026, 1, 5, 10, 30, 0, 123456789, time.UTC)
+	repo := toRepository(&db.Repository{
+		ID:          7,
+		Name:        "activity",
+		URL:         "https://github.com/perbu/activity",
+		Branch:      "main",
+		Active:      true,
+		Description: sql.NullString{String: "Weekly reports", Valid: true},
+		CreatedAt:   created,
+	})
+	if repo.LastRunAt != nil {
+		t.Errorf("LastRunAt = %v, want unset for a repository never analyzed", repo.LastRunAt)
+	}
+
+	report := toReport(&db.WeeklyReport{
+		ID:          42,
+		RepoID:      7,
+		Year:        2026,
+		Week:        2,
+		WeekStart:   time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+		WeekEnd:     time.Date(2026, 1, 11, 23, 59, 59, 0, time.UTC),
+		Summary:     sql.NullString{String: "## Summary\nBusy week.", Valid: true},
+		CommitCount: 12,
+		AgentMode:   true,
+		ReviewState: "approved",
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}, "activity")
+	if report.WeekLabel != "2026-W02" {
+		t.Errorf("WeekLabel = %q, want 2026-W02", report.WeekLabel)
+	}
+
+	for _, msg := range []proto.Message{
+		&ListRepositoriesResponse{Repositories: []*Repository{repo}},
+		&ListReportsResponse{Reports: []*Report{report}, Total: 31},
+		&GenerateReportsResponse{Results: []*GenerateResult{{Repository: "activity", WeekLabel: "2026-W02", Generated: 1, ReportId: 42}}},
+	} {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("failed to marshal %T: %v", msg, err)
+		}
+		got := msg.ProtoReflect().New().Interface()
+		if err := proto.Unmarshal(data, got); err != nil {
+			t.Fatalf("failed to unmarshal %T: %v", msg, err)
+		}
+		if !proto.Equal(got, msg) {
+			t.Errorf("%T round trip = %v, want %v", msg, got, msg)
+		}
+	}
+
+	if got := report.CreatedAt.AsTime(); !got.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", got, created)
+	}
+}
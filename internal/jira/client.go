@@ -0,0 +1,134 @@
+// Package jira resolves Jira ticket keys mentioned in commit messages.
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLookups caps how many tickets are looked up for one analysis
+const maxLookups = 50
+
+// errNotFound is returned for keys that name no ticket visible to the token
+var errNotFound = errors.New("ticket not found")
+
+// Client reads tickets from Jira Cloud, Server or Data Center
+type Client struct {
+	baseURL    string
+	email      string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client for the Jira instance at baseURL. With an
+// email the token is sent as a Jira Cloud API token (basic auth), without one
+// as a Server or Data Center personal access token (bearer auth).
+func NewClient(baseURL, email, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		email:      email,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Ticket is a Jira issue
+type Ticket struct {
+	Key     string `json:"key"`
+	Summary string `json:"summary"`
+	Type    string `json:"type"`   // e.g. Bug, Story
+	Status  string `json:"status"` // e.g. In Progress, Done
+}
+
+// issueResponse is the part of Jira's issue resource we read
+type issueResponse struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary   string `json:"summary"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Status struct {
+			Name string `json:"name"`
+		} `json:"status"`
+	} `json:"fields"`
+}
+
+// GetTicket returns the ticket with the given key
+func (c *Client) GetTicket(ctx context.Context, key string) (*Ticket, error) {
+	endpoint := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=summary,issuetype,status", c.baseURL, url.PathEscape(key))
+
+	var issue issueResponse
+	if err := c.get(ctx, endpoint, &issue); err != nil {
+		return nil, err
+	}
+	return &Ticket{
+		Key:     issue.Key,
+		Summary: issue.Fields.Summary,
+		Type:    issue.Fields.IssueType.Name,
+		Status:  issue.Fields.Status.Name,
+	}, nil
+}
+
+// GetTickets returns the tickets with the given keys, in the same order, up
+// to maxLookups of them. Keys that name no ticket, such as version strings
+// like UTF-8 that look like keys, are skipped.
+func (c *Client) GetTickets(ctx context.Context, keys []string) ([]Ticket, error) {
+	if len(keys) > maxLookups {
+		keys = keys[:maxLookups]
+	}
+
+	var tickets []Ticket
+	for _, key := range keys {
+		ticket, err := c.GetTicket(ctx, key)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return tickets, fmt.Errorf("failed to get ticket %s: %w", key, err)
+		}
+		tickets = append(tickets, *ticket)
+	}
+	return tickets, nil
+}
+
+// get fetches a Jira API endpoint and decodes its JSON response into v
+func (c *Client) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.token == "":
+	case c.email != "":
+		req.SetBasicAuth(c.email, c.token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Jira API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Jira API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Jira API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package jira
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// keyPattern matches ticket keys: a project key of capitals, digits and
+// underscores starting with a letter, a dash and the ticket number
+var keyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// Keys returns the ticket keys mentioned in text, each once, in order of
+// first mention. With projects only keys of those projects are returned.
+func Keys(text string, projects []string) []string {
+	var keys []string
+	for _, key := range keyPattern.FindAllString(text, -1) {
+		if InProjects(key, projects) && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// InProjects reports whether key belongs to one of the projects, or to any
+// project if none are given
+func InProjects(key string, projects []string) bool {
+	if len(projects) == 0 {
+		return true
+	}
+	project, _, _ := strings.Cut(key, "-")
+	return slices.Contains(projects, project)
+}
+
+// BrowseURL returns the web page of a ticket
+func BrowseURL(baseURL, key string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/browse/" + key
+}
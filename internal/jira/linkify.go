@@ -0,0 +1,77 @@
+package jira
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// linkifier links ticket keys in markdown text to their pages in Jira
+type linkifier struct {
+	baseURL  string
+	projects []string
+}
+
+// Linkify returns a goldmark extension that links the ticket keys of the
+// given projects, or of any project if none are given, to the Jira instance
+// at baseURL. Keys in links and code are left alone.
+func Linkify(baseURL string, projects []string) goldmark.Extender {
+	return &linkifier{baseURL: baseURL, projects: projects}
+}
+
+// Extend implements goldmark.Extender
+func (l *linkifier) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(l, 999)))
+}
+
+// Transform implements parser.ASTTransformer
+func (l *linkifier) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	var texts []*ast.Text
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link, *ast.AutoLink, *ast.Image, *ast.CodeSpan, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			texts = append(texts, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, t := range texts {
+		l.linkText(t, reader.Source())
+	}
+}
+
+// linkText splits a text node around the ticket keys in it, inserting a link
+// for each key before the node. The node itself keeps the text after the
+// last key, and with it any line break that follows.
+func (l *linkifier) linkText(t *ast.Text, source []byte) {
+	seg := t.Segment
+	if seg.Padding > 0 {
+		return
+	}
+	parent := t.Parent()
+	pos := 0
+	for _, m := range keyPattern.FindAllIndex(seg.Value(source), -1) {
+		key := string(seg.Value(source)[m[0]:m[1]])
+		if !InProjects(key, l.projects) {
+			continue
+		}
+		if m[0] > pos {
+			parent.InsertBefore(parent, t, ast.NewTextSegment(text.NewSegment(seg.Start+pos, seg.Start+m[0])))
+		}
+		link := ast.NewLink()
+		link.Destination = []byte(BrowseURL(l.baseURL, key))
+		link.AppendChild(link, ast.NewTextSegment(text.NewSegment(seg.Start+m[0], seg.Start+m[1])))
+		parent.InsertBefore(parent, t, link)
+		pos = m[1]
+	}
+	if pos > 0 {
+		t.Segment = seg.WithStart(seg.Start + pos)
+	}
+}
@@ -0,0 +1,497 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// azureModel implements model.LLM on top of the Azure OpenAI chat completions API.
+// It translates between genai contents (used by the ADK agent runtime) and
+// OpenAI chat messages, including function calling for the agent tools.
+type azureModel struct {
+	endpoint   string
+	deployment string
+	apiVersion string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// newAzureModel creates an Azure OpenAI model from config
+func newAzureModel(cfg *config.Config) (*azureModel, error) {
+	endpoint := cfg.GetAzureEndpoint()
+	if endpoint == "" {
+		return nil, fmt.Errorf("azure endpoint not configured: set 'azure_endpoint' in config or set environment variable 'AZURE_OPENAI_ENDPOINT'")
+	}
+	deployment := cfg.GetAzureDeployment()
+	if deployment == "" {
+		return nil, fmt.Errorf("azure deployment not configured: set 'azure_deployment' or 'model' in config")
+	}
+	apiKey := cfg.GetAzureAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key not configured: set 'api_key' in config or set environment variable 'AZURE_OPENAI_API_KEY'")
+	}
+
+	return &azureModel{
+		endpoint:   endpoint,
+		deployment: deployment,
+		apiVersion: cfg.GetAzureAPIVersion(),
+		apiKey:     apiKey,
+		httpClient: &http.Client{}, // Bounded by the per-call context timeout
+	}, nil
+}
+
+// Name returns the deployment name
+func (m *azureModel) Name() string {
+	return m.deployment
+}
+
+// GenerateContent sends the request to Azure OpenAI. In streaming mode, text
+// deltas are yielded as partial responses followed by one complete response
+// (including any tool calls); otherwise a single complete response is yielded.
+func (m *azureModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if stream {
+		return m.generateStream(ctx, req)
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		resp, err := m.generate(ctx, req)
+		yield(resp, err)
+	}
+}
+
+// OpenAI chat completion wire types (subset used by this client)
+type azureMessage struct {
+	Role       string          `json:"role"`
+	Content    *string         `json:"content"`
+	ToolCalls  []azureToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type azureToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type azureTool struct {
+	Type     string            `json:"type"`
+	Function azureToolFunction `json:"function"`
+}
+
+type azureToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type azureChatRequest struct {
+	Messages      []azureMessage      `json:"messages"`
+	Tools         []azureTool         `json:"tools,omitempty"`
+	Temperature   *float32            `json:"temperature,omitempty"`
+	MaxTokens     int32               `json:"max_tokens,omitempty"`
+	Stream        bool                `json:"stream,omitempty"`
+	StreamOptions *azureStreamOptions `json:"stream_options,omitempty"`
+}
+
+type azureStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// azureStreamChunk is a single server-sent event payload in streaming mode
+type azureStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+		TotalTokens      int32 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+type azureChoice struct {
+	Message      azureMessage `json:"message"`
+	FinishReason string       `json:"finish_reason"`
+}
+
+type azureChatResponse struct {
+	Choices []azureChoice `json:"choices"`
+	Usage   struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+		TotalTokens      int32 `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// azureStatusError is returned when Azure OpenAI responds with an error status
+type azureStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *azureStatusError) Error() string {
+	return fmt.Sprintf("azure returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// post sends a chat completion request and returns the raw HTTP response
+func (m *azureModel) post(ctx context.Context, chatReq *azureChatRequest) (*http.Response, error) {
+	return m.postJSON(ctx, m.deployment, "chat/completions", chatReq)
+}
+
+// postJSON sends a JSON request to an operation of a deployment and returns the raw HTTP response
+func (m *azureModel) postJSON(ctx context.Context, deployment, operation string, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal azure request: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/openai/deployments/%s/%s?api-version=%s",
+		m.endpoint, url.PathEscape(deployment), operation, url.QueryEscape(m.apiVersion))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create azure request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", m.apiKey)
+
+	httpResp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("azure request failed: %w", err)
+	}
+	return httpResp, nil
+}
+
+// generate performs a single chat completion call
+func (m *azureModel) generate(ctx context.Context, req *model.LLMRequest) (*model.LLMResponse, error) {
+	chatReq, err := toAzureRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := m.post(ctx, chatReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read azure response: %w", err)
+	}
+
+	var chatResp azureChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse azure response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if chatResp.Error != nil {
+		return nil, &azureStatusError{StatusCode: httpResp.StatusCode, Message: chatResp.Error.Code + ": " + chatResp.Error.Message}
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &azureStatusError{StatusCode: httpResp.StatusCode, Message: string(respBody)}
+	}
+
+	return fromAzureResponse(&chatResp)
+}
+
+// generateStream performs a streaming chat completion call. Text deltas are
+// yielded as partial responses; tool call fragments are accumulated and
+// returned in the final aggregated response.
+func (m *azureModel) generateStream(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		chatReq, err := toAzureRequest(req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		chatReq.Stream = true
+		chatReq.StreamOptions = &azureStreamOptions{IncludeUsage: true}
+
+		httpResp, err := m.post(ctx, chatReq)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer httpResp.Body.Close()
+
+		if httpResp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(httpResp.Body)
+			yield(nil, &azureStatusError{StatusCode: httpResp.StatusCode, Message: string(body)})
+			return
+		}
+
+		// Accumulate the full response while streaming text deltas
+		final := azureChatResponse{Choices: make([]azureChoice, 1)}
+		var text strings.Builder
+		var toolCalls []azureToolCall
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				break
+			}
+
+			var chunk azureStreamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				yield(nil, fmt.Errorf("failed to parse azure stream chunk: %w", err))
+				return
+			}
+			if chunk.Usage != nil {
+				final.Usage.PromptTokens = chunk.Usage.PromptTokens
+				final.Usage.CompletionTokens = chunk.Usage.CompletionTokens
+				final.Usage.TotalTokens = chunk.Usage.TotalTokens
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				final.Choices[0].FinishReason = choice.FinishReason
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= tc.Index {
+					toolCalls = append(toolCalls, azureToolCall{Type: "function"})
+				}
+				if tc.ID != "" {
+					toolCalls[tc.Index].ID = tc.ID
+				}
+				toolCalls[tc.Index].Function.Name += tc.Function.Name
+				toolCalls[tc.Index].Function.Arguments += tc.Function.Arguments
+			}
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				partial := &model.LLMResponse{
+					Content: genai.NewContentFromText(choice.Delta.Content, genai.RoleModel),
+					Partial: true,
+				}
+				if !yield(partial, nil) {
+					return
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("failed to read azure stream: %w", err))
+			return
+		}
+
+		content := text.String()
+		final.Choices[0].Message = azureMessage{Role: "assistant", Content: &content, ToolCalls: toolCalls}
+		resp, err := fromAzureResponse(&final)
+		yield(resp, err)
+	}
+}
+
+// toAzureRequest converts an ADK request into an OpenAI chat completion request
+func toAzureRequest(req *model.LLMRequest) (*azureChatRequest, error) {
+	chatReq := &azureChatRequest{}
+
+	if req.Config != nil {
+		if req.Config.SystemInstruction != nil {
+			if text := contentText(req.Config.SystemInstruction); text != "" {
+				chatReq.Messages = append(chatReq.Messages, azureMessage{Role: "system", Content: &text})
+			}
+		}
+		chatReq.Temperature = req.Config.Temperature
+		chatReq.MaxTokens = req.Config.MaxOutputTokens
+
+		for _, t := range req.Config.Tools {
+			for _, decl := range t.FunctionDeclarations {
+				params := decl.ParametersJsonSchema
+				if params == nil && decl.Parameters != nil {
+					params = schemaToJSON(decl.Parameters)
+				}
+				chatReq.Tools = append(chatReq.Tools, azureTool{
+					Type: "function",
+					Function: azureToolFunction{
+						Name:        decl.Name,
+						Description: decl.Description,
+						Parameters:  params,
+					},
+				})
+			}
+		}
+	}
+
+	for _, content := range req.Contents {
+		msgs, err := toAzureMessages(content)
+		if err != nil {
+			return nil, err
+		}
+		chatReq.Messages = append(chatReq.Messages, msgs...)
+	}
+
+	return chatReq, nil
+}
+
+// toAzureMessages converts one genai content into chat messages.
+// Function responses become separate "tool" messages, as OpenAI requires.
+func toAzureMessages(content *genai.Content) ([]azureMessage, error) {
+	role := "user"
+	if content.Role == genai.RoleModel {
+		role = "assistant"
+	}
+
+	var msgs []azureMessage
+	msg := azureMessage{Role: role}
+	var text strings.Builder
+
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			args, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal function call args: %w", err)
+			}
+			call := azureToolCall{ID: part.FunctionCall.ID, Type: "function"}
+			call.Function.Name = part.FunctionCall.Name
+			call.Function.Arguments = string(args)
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		case part.FunctionResponse != nil:
+			result, err := json.Marshal(part.FunctionResponse.Response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal function response: %w", err)
+			}
+			resultStr := string(result)
+			msgs = append(msgs, azureMessage{
+				Role:       "tool",
+				Content:    &resultStr,
+				ToolCallID: part.FunctionResponse.ID,
+			})
+		case part.Text != "" && !part.Thought:
+			text.WriteString(part.Text)
+		}
+	}
+
+	if text.Len() > 0 || len(msg.ToolCalls) > 0 {
+		if text.Len() > 0 {
+			s := text.String()
+			msg.Content = &s
+		}
+		msgs = append([]azureMessage{msg}, msgs...)
+	}
+	return msgs, nil
+}
+
+// fromAzureResponse converts a chat completion response into an ADK response
+func fromAzureResponse(resp *azureChatResponse) (*model.LLMResponse, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("azure response contained no choices")
+	}
+	choice := resp.Choices[0]
+
+	content := &genai.Content{Role: genai.RoleModel}
+	if choice.Message.Content != nil && *choice.Message.Content != "" {
+		content.Parts = append(content.Parts, genai.NewPartFromText(*choice.Message.Content))
+	}
+	for _, call := range choice.Message.ToolCalls {
+		var args map[string]any
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("failed to parse tool call arguments for %s: %w", call.Function.Name, err)
+			}
+		}
+		content.Parts = append(content.Parts, &genai.Part{
+			FunctionCall: &genai.FunctionCall{
+				ID:   call.ID,
+				Name: call.Function.Name,
+				Args: args,
+			},
+		})
+	}
+
+	finishReason := genai.FinishReasonStop
+	if choice.FinishReason == "length" {
+		finishReason = genai.FinishReasonMaxTokens
+	}
+
+	return &model.LLMResponse{
+		Content: content,
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     resp.Usage.PromptTokens,
+			CandidatesTokenCount: resp.Usage.CompletionTokens,
+			TotalTokenCount:      resp.Usage.TotalTokens,
+		},
+		FinishReason: finishReason,
+		TurnComplete: true,
+	}, nil
+}
+
+// contentText concatenates the text parts of a content
+func contentText(content *genai.Content) string {
+	var sb strings.Builder
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// schemaToJSON converts a genai schema (OpenAPI subset with upper-case types)
+// into a JSON Schema object accepted by OpenAI function calling
+func schemaToJSON(s *genai.Schema) map[string]any {
+	out := map[string]any{}
+	if s.Type != "" {
+		out["type"] = strings.ToLower(string(s.Type))
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if s.Items != nil {
+		out["items"] = schemaToJSON(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = schemaToJSON(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	return out
+}
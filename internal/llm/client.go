@@ -3,44 +3,85 @@ package llm
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/model/gemini"
 	"google.golang.org/genai"
 )
 
 type Client struct {
-	genaiClient *genai.Client
-	model       string
-	apiKey      string
+	genaiClient  *genai.Client
+	model        string
+	clientConfig *genai.ClientConfig
+	azure        *azureModel // set when provider is "azure"; genai fields are unused
+	retry        retryPolicy
+
+	embeddingModel string
 }
 
 // NewClient creates a new LLM client based on config
 func NewClient(ctx context.Context, cfg *config.Config) (*Client, error) {
-	// Get API key: direct config value takes precedence over environment variable
-	apiKey := cfg.LLM.APIKey
-	if apiKey == "" && cfg.LLM.APIKeyEnv != "" {
-		apiKey = os.Getenv(cfg.LLM.APIKeyEnv)
+	if cfg.UsesAzureOpenAI() {
+		azure, err := newAzureModel(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &Client{
+			model:          azure.Name(),
+			azure:          azure,
+			retry:          newRetryPolicy(cfg),
+			embeddingModel: cfg.GetEmbeddingModel(),
+		}, nil
 	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("API key not configured: set 'api_key' in config or set environment variable '%s'", cfg.LLM.APIKeyEnv)
+
+	clientConfig, err := newClientConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// Initialize GenAI client with Gemini API backend
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
 	return &Client{
-		genaiClient: client,
-		model:       cfg.LLM.Model,
-		apiKey:      apiKey,
+		genaiClient:  client,
+		model:        cfg.LLM.Model,
+		clientConfig: clientConfig,
+		retry:        newRetryPolicy(cfg),
+
+		embeddingModel: cfg.GetEmbeddingModel(),
+	}, nil
+}
+
+// newClientConfig builds the genai client configuration for the configured provider.
+// The Gemini API backend authenticates with an API key, while Vertex AI uses
+// Application Default Credentials (service account, workload identity, gcloud login).
+func newClientConfig(cfg *config.Config) (*genai.ClientConfig, error) {
+	if cfg.UsesVertexAI() {
+		project := cfg.GetVertexProject()
+		if project == "" {
+			return nil, fmt.Errorf("vertex project not configured: set 'vertex_project' in config or set environment variable 'GOOGLE_CLOUD_PROJECT'")
+		}
+		return &genai.ClientConfig{
+			Backend:  genai.BackendVertexAI,
+			Project:  project,
+			Location: cfg.GetVertexLocation(),
+		}, nil
+	}
+
+	apiKey := cfg.GetGeminiAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key not configured: set 'api_key' in config or set environment variable '%s'", cfg.LLM.APIKeyEnv)
+	}
+
+	return &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
 	}, nil
 }
 
@@ -49,29 +90,116 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// GenerateText generates text from a prompt (non-streaming)
+// GenerateText generates text from a prompt (non-streaming). The call is
+// bounded by the configured timeout and transient failures are retried.
 func (c *Client) GenerateText(ctx context.Context, prompt string) (string, error) {
+	ctx, span := startSpan(ctx, "llm.GenerateText", c.model, attribute.Int("prompt_bytes", len(prompt)))
 	content := genai.NewContentFromText(prompt, genai.RoleUser)
 
-	resp, err := c.genaiClient.Models.GenerateContent(ctx, c.model,
-		[]*genai.Content{content},
-		nil)
+	var text string
+	err := c.retry.do(ctx, func(ctx context.Context) error {
+		if c.azure != nil {
+			resp, err := c.azure.generate(ctx, &model.LLMRequest{Contents: []*genai.Content{content}})
+			if err != nil {
+				return err
+			}
+			text = contentText(resp.Content)
+			recordUsage(span, resp.UsageMetadata)
+			return nil
+		}
+
+		resp, err := c.genaiClient.Models.GenerateContent(ctx, c.model,
+			[]*genai.Content{content},
+			nil)
+		if err != nil {
+			return err
+		}
+		text = resp.Text()
+		recordUsage(span, resp.UsageMetadata)
+		return nil
+	})
+	telemetry.End(span, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate content: %w", err)
 	}
 
-	return resp.Text(), nil
+	return text, nil
 }
 
-// GetGeminiModel returns a model.LLM instance for use with ADK agents
-func (c *Client) GetGeminiModel(ctx context.Context) (model.LLM, error) {
-	// Create a Gemini model using the ADK's gemini package
-	llmModel, err := gemini.NewModel(ctx, c.model, &genai.ClientConfig{
-		APIKey:  c.apiKey,
-		Backend: genai.BackendGeminiAPI,
+// GenerateTextStream generates text from a prompt, calling onChunk with each
+// partial piece of text as it arrives. Returns the complete text. A failed
+// call is only retried if no text has been streamed yet.
+func (c *Client) GenerateTextStream(ctx context.Context, prompt string, onChunk func(string)) (string, error) {
+	ctx, span := startSpan(ctx, "llm.GenerateTextStream", c.model, attribute.Int("prompt_bytes", len(prompt)))
+	content := genai.NewContentFromText(prompt, genai.RoleUser)
+
+	var text string
+	streamed := false
+	err := c.retry.do(ctx, func(ctx context.Context) error {
+		var err error
+		text, err = c.generateStream(ctx, content, func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		})
+		if err != nil && streamed {
+			return &permanentError{err}
+		}
+		return err
 	})
+	telemetry.End(span, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+	return text, nil
+}
+
+// generateStream performs a single streaming call
+func (c *Client) generateStream(ctx context.Context, content *genai.Content, onChunk func(string)) (string, error) {
+	if c.azure != nil {
+		req := &model.LLMRequest{Contents: []*genai.Content{content}}
+		var final *model.LLMResponse
+		for resp, err := range c.azure.GenerateContent(ctx, req, true) {
+			if err != nil {
+				return "", err
+			}
+			if resp.Partial {
+				onChunk(contentText(resp.Content))
+				continue
+			}
+			final = resp
+		}
+		if final == nil {
+			return "", fmt.Errorf("stream ended without a response")
+		}
+		return contentText(final.Content), nil
+	}
+
+	var sb strings.Builder
+	for resp, err := range c.genaiClient.Models.GenerateContentStream(ctx, c.model, []*genai.Content{content}, nil) {
+		if err != nil {
+			return "", err
+		}
+		if chunk := resp.Text(); chunk != "" {
+			sb.WriteString(chunk)
+			onChunk(chunk)
+		}
+	}
+	return sb.String(), nil
+}
+
+// GetModel returns a model.LLM instance for use with ADK agents. Each agent
+// turn is subject to the same timeout and retry policy as GenerateText.
+func (c *Client) GetModel(ctx context.Context) (model.LLM, error) {
+	if c.azure != nil {
+		return &retryModel{LLM: c.azure, policy: c.retry}, nil
+	}
+
+	// Create a Gemini model using the ADK's gemini package, sharing the
+	// backend configuration (API key or Vertex AI) of the text client
+	cc := *c.clientConfig
+	llmModel, err := gemini.NewModel(ctx, c.model, &cc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gemini model: %w", err)
 	}
-	return llmModel, nil
+	return &retryModel{LLM: llmModel, policy: c.retry}, nil
 }
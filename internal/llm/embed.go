@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/perbu/activity/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// Embedding task types. Documents and queries are embedded differently by
+// models that support retrieval tasks; other models ignore the task type.
+const (
+	TaskDocument = "RETRIEVAL_DOCUMENT"
+	TaskQuery    = "RETRIEVAL_QUERY"
+)
+
+// EmbeddingModel returns the name of the configured embedding model
+func (c *Client) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// Embed returns one embedding vector per text, in order, using the configured
+// embedding model. taskType is TaskDocument or TaskQuery.
+func (c *Client) Embed(ctx context.Context, texts []string, taskType string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	ctx, span := startSpan(ctx, "llm.Embed", c.embeddingModel, attribute.Int("texts", len(texts)))
+	var vectors [][]float32
+	err := c.retry.do(ctx, func(ctx context.Context) error {
+		var err error
+		if c.azure != nil {
+			vectors, err = c.azure.embed(ctx, c.embeddingModel, texts)
+			return err
+		}
+		vectors, err = c.embedGemini(ctx, texts, taskType)
+		return err
+	})
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("failed to generate embeddings: got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// embedGemini embeds texts with the Gemini or Vertex AI embeddings API
+func (c *Client) embedGemini(ctx context.Context, texts []string, taskType string) ([][]float32, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+
+	resp, err := c.genaiClient.Models.EmbedContent(ctx, c.embeddingModel, contents, &genai.EmbedContentConfig{TaskType: taskType})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+// azureEmbeddingRequest is the Azure OpenAI embeddings request body
+type azureEmbeddingRequest struct {
+	Input []string `json:"input"`
+}
+
+// azureEmbeddingResponse is the Azure OpenAI embeddings response body
+type azureEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embed calls the embeddings API of an Azure OpenAI deployment
+func (m *azureModel) embed(ctx context.Context, deployment string, texts []string) ([][]float32, error) {
+	httpResp, err := m.postJSON(ctx, deployment, "embeddings", azureEmbeddingRequest{Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read azure response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &azureStatusError{StatusCode: httpResp.StatusCode, Message: string(respBody)}
+	}
+
+	var embResp azureEmbeddingResponse
+	if err := json.Unmarshal(respBody, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to parse azure embeddings response: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("azure embeddings response has out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
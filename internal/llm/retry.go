@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// retryPolicy bounds each LLM call with a timeout and retries transient
+// failures (timeouts, rate limits, server errors) with exponential backoff
+type retryPolicy struct {
+	timeout        time.Duration // Per-attempt timeout
+	maxRetries     int           // Retries after the first attempt
+	initialBackoff time.Duration // Delay before the first retry, doubled per retry
+	maxBackoff     time.Duration // Upper bound on the delay
+}
+
+// newRetryPolicy creates a retry policy from config
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	initial, maxBackoff := cfg.GetLLMRetryBackoff()
+	return retryPolicy{
+		timeout:        cfg.GetLLMTimeout(),
+		maxRetries:     cfg.GetLLMMaxRetries(),
+		initialBackoff: initial,
+		maxBackoff:     maxBackoff,
+	}
+}
+
+// attemptContext derives the context for a single attempt
+func (p retryPolicy) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+// backoff returns the delay before the given retry (0-based)
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.initialBackoff
+	for range retry {
+		delay *= 2
+		if delay >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+	return min(delay, p.maxBackoff)
+}
+
+// attemptFailed inspects a failed attempt. It returns the error to report,
+// annotated if the attempt timed out, and whether the call should be retried.
+// Cancellation of the parent context is never retried.
+func (p retryPolicy) attemptFailed(ctx, attemptCtx context.Context, retry int, err error) (error, bool) {
+	if ctx.Err() != nil {
+		return ctx.Err(), false
+	}
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return perm.err, false
+	}
+	timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		err = fmt.Errorf("LLM call timed out after %s: %w", p.timeout, err)
+	}
+	return err, retry < p.maxRetries && (timedOut || isTransient(err))
+}
+
+// wait sleeps for the backoff delay, returning early if ctx is cancelled
+func (p retryPolicy) wait(ctx context.Context, retry int, err error) error {
+	delay := p.backoff(retry)
+	slog.Warn("LLM call failed, retrying", "retry", retry+1, "max_retries", p.maxRetries, "delay", delay, "error", err)
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attribute.Int("retry", retry+1),
+		attribute.String("delay", delay.String()),
+		attribute.String("error", err.Error()),
+	))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// do runs fn with a per-attempt timeout, retrying transient failures
+func (p retryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	for retry := 0; ; retry++ {
+		attemptCtx, cancel := p.attemptContext(ctx)
+		err := fn(attemptCtx)
+		if err == nil {
+			cancel()
+			return nil
+		}
+		err, again := p.attemptFailed(ctx, attemptCtx, retry, err)
+		cancel()
+		if !again {
+			return err
+		}
+		if err := p.wait(ctx, retry, err); err != nil {
+			return err
+		}
+	}
+}
+
+// retryModel wraps a model.LLM so that every agent turn gets the same
+// timeout and retry behaviour as direct text generation. A streamed call is
+// only retried if it failed before yielding anything, since the consumer
+// cannot take back partial output.
+type retryModel struct {
+	model.LLM
+	policy retryPolicy
+}
+
+// GenerateContent implements model.LLM
+func (m *retryModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		ctx, span := startSpan(ctx, "llm.GenerateContent", m.LLM.Name(), attribute.Bool("stream", stream))
+		var err error
+		defer func() { telemetry.End(span, err) }()
+
+		for retry := 0; ; retry++ {
+			attemptCtx, cancel := m.policy.attemptContext(ctx)
+			yielded := false
+			var failure error
+			for resp, err := range m.LLM.GenerateContent(attemptCtx, req, stream) {
+				if err != nil {
+					failure = err
+					break
+				}
+				yielded = true
+				recordUsage(span, resp.UsageMetadata)
+				if !yield(resp, nil) {
+					cancel()
+					return
+				}
+			}
+			if failure == nil {
+				cancel()
+				return
+			}
+
+			var again bool
+			err, again = m.policy.attemptFailed(ctx, attemptCtx, retry, failure)
+			cancel()
+			if !again || yielded {
+				yield(nil, err)
+				return
+			}
+			if err = m.policy.wait(ctx, retry, err); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// permanentError marks a failure that must not be retried, e.g. a stream
+// that already delivered output
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// isTransient reports whether an error is worth retrying: rate limiting,
+// server-side failures and network timeouts
+func isTransient(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return isTransientStatus(apiErr.Code)
+	}
+	var azureErr *azureStatusError
+	if errors.As(err, &azureErr) {
+		return isTransientStatus(azureErr.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTransientStatus reports whether an HTTP status code indicates a transient failure
+func isTransientStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
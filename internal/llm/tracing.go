@@ -0,0 +1,29 @@
+package llm
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+var tracer = otel.Tracer("github.com/perbu/activity/internal/llm")
+
+// startSpan starts the span of an LLM call to the given model
+func startSpan(ctx context.Context, name, model string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("gen_ai.request.model", model))
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// recordUsage adds a response's token counts to a span
+func recordUsage(span trace.Span, usage *genai.GenerateContentResponseUsageMetadata) {
+	if usage == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+		attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)),
+	)
+}
@@ -0,0 +1,38 @@
+package markdown
+
+import (
+	"slices"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// headingLevels renumbers the headings of a document so the highest one is
+// at level top and no level is skipped: a summary using # and ### under a
+// page's <h1> becomes <h2> and <h3>, which screen readers navigate by.
+type headingLevels struct {
+	top int
+}
+
+// Transform implements parser.ASTTransformer
+func (h *headingLevels) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	var headings []*ast.Heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			headings = append(headings, heading)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var used []int
+	for _, heading := range headings {
+		if !slices.Contains(used, heading.Level) {
+			used = append(used, heading.Level)
+		}
+	}
+	slices.Sort(used)
+	for _, heading := range headings {
+		heading.Level = min(h.top+slices.Index(used, heading.Level), 6)
+	}
+}
@@ -0,0 +1,197 @@
+package markdown
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// lexer describes the syntax of a language well enough to highlight its
+// keywords, strings, numbers and comments
+type lexer struct {
+	lineComments  []string
+	blockComment  [2]string // Start and end, empty if there are none
+	quotes        string    // Characters that delimit strings
+	keywords      map[string]bool
+	caseSensitive bool
+}
+
+var (
+	goLexer = &lexer{
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`", caseSensitive: true,
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false iota`),
+	}
+	pythonLexer = &lexer{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		keywords: words(`and as assert async await break class continue def del elif else except finally
+			for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False`),
+	}
+	jsLexer = &lexer{
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`", caseSensitive: true,
+		keywords: words(`async await break case catch class const continue default delete do else export
+			extends finally for from function if import in instanceof interface let new of return switch this throw
+			try type typeof var void while yield null undefined true false`),
+	}
+	shellLexer = &lexer{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		keywords: words(`if then else elif fi for while until do done case esac function in return
+			export local set unset echo exit`),
+	}
+	sqlLexer = &lexer{
+		lineComments: []string{"--"}, blockComment: [2]string{"/*", "*/"}, quotes: `'"`,
+		keywords: words(`select from where and or not insert into values update set delete create alter
+			drop table index view join left right inner outer on group by order having limit offset as distinct
+			union all null is in exists between like case when then else end primary key references default
+			begin commit rollback returning`),
+	}
+	rustLexer = &lexer{
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: `"`, caseSensitive: true,
+		keywords: words(`as async await break const continue crate else enum extern false fn for if impl
+			in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use
+			where while`),
+	}
+	javaLexer = &lexer{
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: `"'`, caseSensitive: true,
+		keywords: words(`abstract boolean break byte case catch char class const continue default do
+			double else enum extends final finally float for if implements import instanceof int interface long new
+			package private protected public return short static super switch this throw throws try void while
+			null true false val var fun when object`),
+	}
+	cLexer = &lexer{
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: `"'`, caseSensitive: true,
+		keywords: words(`auto break case char class const continue default do double else enum extern
+			float for goto if inline int long namespace new private protected public return short signed sizeof
+			static struct switch template this typedef union unsigned using virtual void volatile while NULL
+			nullptr true false`),
+	}
+	dataLexer = &lexer{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		keywords: words(`true false null yes no`),
+	}
+)
+
+// lexers maps the languages of fenced code blocks to their lexers
+var lexers = map[string]*lexer{
+	"go": goLexer, "golang": goLexer,
+	"python": pythonLexer, "py": pythonLexer,
+	"javascript": jsLexer, "js": jsLexer, "typescript": jsLexer, "ts": jsLexer, "jsx": jsLexer, "tsx": jsLexer,
+	"sh": shellLexer, "bash": shellLexer, "shell": shellLexer, "zsh": shellLexer, "console": shellLexer,
+	"sql": sqlLexer, "postgresql": sqlLexer, "postgres": sqlLexer,
+	"rust": rustLexer, "rs": rustLexer,
+	"java": javaLexer, "kotlin": javaLexer, "kt": javaLexer,
+	"c": cLexer, "h": cLexer, "cpp": cLexer, "c++": cLexer, "cc": cLexer,
+	"json": dataLexer, "yaml": dataLexer, "yml": dataLexer, "toml": dataLexer,
+}
+
+// highlight writes code as escaped HTML, with the tokens of lang wrapped in
+// spans with hl-* classes. Code in other languages is only escaped.
+func highlight(w io.Writer, lang, code string) {
+	lx, ok := lexers[lang]
+	if !ok {
+		template.HTMLEscape(w, []byte(code))
+		return
+	}
+	plain := 0 // Start of the text not yet written
+	emit := func(start, end int, class string) {
+		template.HTMLEscape(w, []byte(code[plain:start]))
+		io.WriteString(w, `<span class="`+class+`">`)
+		template.HTMLEscape(w, []byte(code[start:end]))
+		io.WriteString(w, `</span>`)
+		plain = end
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if end := lx.comment(rest); end > 0 {
+			emit(i, i+end, "hl-comment")
+			i += end
+			continue
+		}
+		if strings.IndexByte(lx.quotes, rest[0]) >= 0 {
+			end := stringEnd(rest)
+			emit(i, i+end, "hl-string")
+			i += end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		if unicode.IsDigit(r) {
+			end := strings.IndexFunc(rest, func(r rune) bool { return !isWordRune(r) && r != '.' })
+			if end < 0 {
+				end = len(rest)
+			}
+			emit(i, i+end, "hl-number")
+			i += end
+			continue
+		}
+		if isWordRune(r) {
+			end := strings.IndexFunc(rest, func(r rune) bool { return !isWordRune(r) })
+			if end < 0 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			if !lx.caseSensitive {
+				word = strings.ToLower(word)
+			}
+			if lx.keywords[word] {
+				emit(i, i+end, "hl-keyword")
+			}
+			i += end
+			continue
+		}
+		i += size
+	}
+	template.HTMLEscape(w, []byte(code[plain:]))
+}
+
+// comment returns the length of the comment code starts with, or 0
+func (lx *lexer) comment(code string) int {
+	for _, start := range lx.lineComments {
+		if strings.HasPrefix(code, start) {
+			if end := strings.IndexByte(code, '\n'); end >= 0 {
+				return end
+			}
+			return len(code)
+		}
+	}
+	if start, end := lx.blockComment[0], lx.blockComment[1]; start != "" && strings.HasPrefix(code, start) {
+		if i := strings.Index(code[len(start):], end); i >= 0 {
+			return len(start) + i + len(end)
+		}
+		return len(code)
+	}
+	return 0
+}
+
+// stringEnd returns the length of the string literal code starts with,
+// honoring backslash escapes except in backtick strings. Strings other than
+// backtick strings end at the end of the line if they are not closed.
+func stringEnd(code string) int {
+	quote := code[0]
+	for i := 1; i < len(code); i++ {
+		switch c := code[i]; {
+		case c == quote:
+			return i + 1
+		case c == '\\' && quote != '`':
+			i++
+		case c == '\n' && quote != '`':
+			return i
+		}
+	}
+	return len(code)
+}
+
+// words returns the set of the words in s
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
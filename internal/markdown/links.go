@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// externalLinkRel is the rel attribute of links to other sites
+const externalLinkRel = "noopener noreferrer nofollow"
+
+// linkPolicy makes links and images safe to show. Links keep only http,
+// https and mailto destinations, and relative ones unless absoluteOnly;
+// others are replaced by their text. Images become links to the image, so
+// rendered summaries never load remote content or track their readers.
+type linkPolicy struct {
+	absoluteOnly bool
+}
+
+// Transform implements parser.ASTTransformer
+func (p *linkPolicy) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+	var nodes []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.Link, *ast.AutoLink, *ast.Image:
+			nodes = append(nodes, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Link:
+			p.checkLink(n)
+		case *ast.Image:
+			link := ast.NewLink()
+			link.Destination = n.Destination
+			link.Title = n.Title
+			moveChildren(n, link)
+			if !link.HasChildren() {
+				link.AppendChild(link, ast.NewString(n.Destination))
+			}
+			n.Parent().ReplaceChild(n.Parent(), n, link)
+			p.checkLink(link)
+		case *ast.AutoLink:
+			dest := n.URL(source)
+			if n.AutoLinkType == ast.AutoLinkEmail {
+				dest = append([]byte("mailto:"), dest...)
+			}
+			if !p.allowed(dest) {
+				n.Parent().ReplaceChild(n.Parent(), n, ast.NewString(n.Label(source)))
+			} else if external(dest) {
+				n.SetAttributeString("rel", []byte(externalLinkRel))
+			}
+		}
+	}
+}
+
+// checkLink unlinks link if its destination isn't allowed, and marks links
+// to other sites
+func (p *linkPolicy) checkLink(link *ast.Link) {
+	dest := link.Destination
+	if !p.allowed(dest) {
+		parent := link.Parent()
+		for child := link.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			parent.InsertBefore(parent, link, child)
+			child = next
+		}
+		parent.RemoveChild(parent, link)
+		return
+	}
+	if external(dest) {
+		link.SetAttributeString("rel", []byte(externalLinkRel))
+	}
+}
+
+// allowed reports whether a link may point to dest. Character references are
+// resolved first, as the renderer does, so they can't hide a scheme.
+func (p *linkPolicy) allowed(dest []byte) bool {
+	resolved := strings.TrimSpace(string(util.URLEscape(dest, true)))
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	case "":
+		return !p.absoluteOnly || strings.HasPrefix(resolved, "//")
+	}
+	return false
+}
+
+// external reports whether dest is on another site
+func external(dest []byte) bool {
+	s := strings.ToLower(string(dest))
+	return strings.HasPrefix(s, "http:") || strings.HasPrefix(s, "https:") || strings.HasPrefix(s, "//")
+}
+
+// moveChildren moves the children of from to the end of to
+func moveChildren(from, to ast.Node) {
+	for child := from.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		to.AppendChild(to, child)
+		child = next
+	}
+}
@@ -0,0 +1,79 @@
+// Package markdown renders report summaries and other model output as HTML
+// for the web UI and emails. The output is safe to embed in a page: raw HTML
+// in the input is dropped, links and images are limited to safe
+// destinations, and fenced code blocks are highlighted with CSS classes
+// (hl-keyword, hl-string, hl-number and hl-comment) instead of inline styles.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+type options struct {
+	headingLinks  bool
+	absoluteLinks bool
+	topHeading    int
+}
+
+// Option configures a renderer created with New
+type Option func(*options)
+
+// WithHeadingLinks adds a link to its own anchor to each heading, so readers
+// can link to a section of a page
+func WithHeadingLinks() Option {
+	return func(o *options) { o.headingLinks = true }
+}
+
+// WithAbsoluteLinksOnly unlinks relative links, for HTML read outside the web
+// UI, such as emails, where they would not resolve
+func WithAbsoluteLinksOnly() Option {
+	return func(o *options) { o.absoluteLinks = true }
+}
+
+// WithTopHeading renumbers headings so the highest is at level top and none
+// skips a level, for HTML embedded below a page's own headings
+func WithTopHeading(top int) Option {
+	return func(o *options) { o.topHeading = top }
+}
+
+// New returns a markdown renderer with the given goldmark extensions, such as
+// the Jira ticket linkifier. Headings get IDs derived from their text.
+func New(extensions []goldmark.Extender, opts ...Option) goldmark.Markdown {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	transformers := []util.PrioritizedValue{
+		// After extensions that add links, such as the linkifier
+		util.Prioritized(&linkPolicy{absoluteOnly: o.absoluteLinks}, 1100),
+	}
+	if o.topHeading > 0 {
+		transformers = append(transformers, util.Prioritized(&headingLevels{top: o.topHeading}, 1100))
+	}
+	return goldmark.New(
+		goldmark.WithExtensions(extensions...),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithASTTransformers(transformers...),
+		),
+		// Before goldmark's own renderer, which has priority 1000
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(&htmlRenderer{headingLinks: o.headingLinks}, 500),
+		)),
+	)
+}
+
+// ToHTML renders markdown source with md
+func ToHTML(md goldmark.Markdown, source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
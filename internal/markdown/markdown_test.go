@@ -0,0 +1,111 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []Option
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "raw HTML is dropped",
+			input:   "before <script>alert(1)</script> after\n\n<div onclick=\"x()\">block</div>",
+			notWant: []string{"<script", "onclick"},
+		},
+		{
+			name:    "javascript links are unlinked",
+			input:   "[click](javascript:alert(1)) and [hidden](javascript&#58;alert(1))",
+			want:    []string{"click", "hidden"},
+			notWant: []string{"href", "javascript"},
+		},
+		{
+			name:  "external links get rel",
+			input: "[docs](https://example.com/docs) and <https://example.com>",
+			want:  []string{`<a href="https://example.com/docs" rel="noopener noreferrer nofollow">docs</a>`, `<a href="https://example.com" rel="noopener noreferrer nofollow">`},
+		},
+		{
+			name:  "relative links are kept",
+			input: "[report](/reports/1)",
+			want:  []string{`<a href="/reports/1">report</a>`},
+		},
+		{
+			name:    "relative links are unlinked in absolute mode",
+			input:   "[report](/reports/1) and [site](https://example.com)",
+			opts:    []Option{WithAbsoluteLinksOnly()},
+			want:    []string{"report and", `href="https://example.com"`},
+			notWant: []string{`href="/reports/1"`},
+		},
+		{
+			name:    "images become links",
+			input:   "![diagram](https://example.com/a.png) ![](data:image/png;base64,AAAA)",
+			want:    []string{`<a href="https://example.com/a.png" rel="noopener noreferrer nofollow">diagram</a>`, "data:image/png"},
+			notWant: []string{"<img", "href=\"data:"},
+		},
+		{
+			name:  "headings get anchors",
+			input: "## What changed\n\ntext",
+			opts:  []Option{WithHeadingLinks()},
+			want:  []string{`<h2 id="what-changed">What changed <a class="heading-anchor" href="#what-changed" aria-label="Link to this section">#</a></h2>`},
+		},
+		{
+			name:  "headings get IDs without anchors",
+			input: "## What changed",
+			want:  []string{`<h2 id="what-changed">What changed</h2>`},
+		},
+		{
+			name:  "headings are renumbered from the top level",
+			input: "# Summary\n\n### Details\n\n#### More\n\n# Next",
+			opts:  []Option{WithTopHeading(2)},
+			want:  []string{`<h2 id="summary">`, `<h3 id="details">`, `<h4 id="more">`, `<h2 id="next">`},
+		},
+		{
+			name:    "headings are not renumbered by default",
+			input:   "# Summary\n\n### Details",
+			want:    []string{`<h1 id="summary">`, `<h3 id="details">`},
+			notWant: []string{"<h2"},
+		},
+		{
+			name:  "code is highlighted",
+			input: "```go\n// Add adds\nfunc Add(a int) string { return \"<b>\" + 42 }\n```",
+			want: []string{
+				`<pre><code class="language-go">`,
+				`<span class="hl-comment">// Add adds</span>`,
+				`<span class="hl-keyword">func</span> Add(a int)`,
+				`<span class="hl-string">&#34;&lt;b&gt;&#34;</span>`,
+				`<span class="hl-number">42</span>`,
+			},
+		},
+		{
+			name:    "unknown languages are escaped only",
+			input:   "```brainfuck\n<+>\n```",
+			want:    []string{`<code class="language-brainfuck">&lt;+&gt;`},
+			notWant: []string{"<span"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := ToHTML(New(nil, tt.opts...), tt.input)
+			if err != nil {
+				t.Fatalf("ToHTML() error = %v", err)
+			}
+			got := string(html)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("ToHTML() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("ToHTML() = %q, want it not to contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
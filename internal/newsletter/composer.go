@@ -1,82 +1,116 @@
 package newsletter
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/email"
+	"github.com/perbu/activity/internal/git"
+	"github.com/yuin/goldmark"
 )
 
-// Composer builds newsletter content from activity runs
+// Composer builds newsletter content from weekly reports
 type Composer struct {
 	db            *db.DB
 	subjectPrefix string
+	maxSections   int
+	extensions    []goldmark.Extender
 }
 
-// NewComposer creates a new newsletter composer
-func NewComposer(database *db.DB, subjectPrefix string) *Composer {
+// NewComposer creates a new newsletter composer. Newsletters have a section
+// for at most maxSections repositories, or all of them if it is 0. The
+// markdown extensions are applied when converting report summaries to HTML.
+func NewComposer(database *db.DB, subjectPrefix string, maxSections int, extensions ...goldmark.Extender) *Composer {
 	return &Composer{
 		db:            database,
 		subjectPrefix: subjectPrefix,
+		maxSections:   maxSections,
+		extensions:    extensions,
 	}
 }
 
-// ComposeForSubscriber builds a newsletter email for a subscriber based on unsent activity runs
-func (c *Composer) ComposeForSubscriber(subscriber *db.Subscriber, runs []*db.ActivityRun) (*email.Email, error) {
-	if len(runs) == 0 {
+// ComposeForSubscriber builds a single newsletter email for a subscriber
+// from all their unsent weekly reports, with a section per repository
+// holding its weeks. Sections are ordered by commit count, and repositories
+// beyond the composer's section limit are only named at the end.
+func (c *Composer) ComposeForSubscriber(ctx context.Context, subscriber *db.Subscriber, reports []*db.WeeklyReport) (*email.Email, error) {
+	if len(reports) == 0 {
 		return nil, nil
 	}
 
-	// Build sections for each run
-	sections := make([]RepoSection, 0, len(runs))
-	for _, run := range runs {
-		// Get repo info
-		repo, err := c.db.GetRepository(run.RepoID)
-		if err != nil {
-			// Skip runs for deleted repos
+	// Group the reports by repository
+	var sections []*RepoSection
+	byRepo := make(map[int64]*RepoSection)
+	skipped := make(map[int64]bool)
+	for _, report := range reports {
+		if skipped[report.RepoID] {
 			continue
 		}
+		section, ok := byRepo[report.RepoID]
+		if !ok {
+			repo, err := c.db.GetRepository(ctx, report.RepoID)
+			if err != nil {
+				// Skip reports for deleted repos
+				skipped[report.RepoID] = true
+				continue
+			}
+			section = &RepoSection{RepoName: repo.Name}
+			byRepo[report.RepoID] = section
+			sections = append(sections, section)
+		}
 
 		summary := ""
-		if run.Summary.Valid {
-			summary = run.Summary.String
+		if report.Summary.Valid {
+			summary = report.Summary.String
 		}
 
 		// Convert markdown summary to HTML
-		summaryHTML, err := MarkdownToHTML(summary)
+		summaryHTML, err := MarkdownToHTML(summary, c.extensions...)
 		if err != nil {
 			// Fall back to plain text if conversion fails
 			summaryHTML = ""
 		}
 
-		// Format commit range
-		commitRange := fmt.Sprintf("%s...%s", shortSHA(run.StartSHA), shortSHA(run.EndSHA))
-
-		// Format analysis time
-		analyzedAt := ""
-		if run.CompletedAt.Valid {
-			analyzedAt = run.CompletedAt.Time.Format("2006-01-02 15:04")
-		}
-
-		sections = append(sections, RepoSection{
-			RepoName:    repo.Name,
+		section.Weeks = append(section.Weeks, WeekSection{
 			Summary:     summary,
 			SummaryHTML: summaryHTML,
-			CommitRange: commitRange,
-			AnalyzedAt:  analyzedAt,
+			Week:        git.FormatISOWeek(report.Year, report.Week),
+			Period:      report.WeekStart.Format("Jan 2") + " - " + report.WeekEnd.Format("Jan 2, 2006"),
+			CommitCount: report.CommitCount,
 		})
+		section.CommitCount += report.CommitCount
 	}
 
 	if len(sections) == 0 {
 		return nil, nil
 	}
 
+	for _, section := range sections {
+		sort.SliceStable(section.Weeks, func(i, j int) bool {
+			return section.Weeks[i].Week < section.Weeks[j].Week
+		})
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i].CommitCount != sections[j].CommitCount {
+			return sections[i].CommitCount > sections[j].CommitCount
+		}
+		return sections[i].RepoName < sections[j].RepoName
+	})
+
 	// Build newsletter data
 	data := &NewsletterData{
-		Sections:      sections,
 		TotalRepos:    len(sections),
 		SubjectPrefix: c.subjectPrefix,
 	}
+	for i, section := range sections {
+		if c.maxSections > 0 && i >= c.maxSections {
+			data.MoreRepos = append(data.MoreRepos, section.RepoName)
+			continue
+		}
+		data.Sections = append(data.Sections, *section)
+	}
 
 	// Render HTML and text versions
 	htmlContent, err := RenderHTML(data)
@@ -96,10 +130,3 @@ func (c *Composer) ComposeForSubscriber(subscriber *db.Subscriber, runs []*db.Ac
 		TextContent: textContent,
 	}, nil
 }
-
-func shortSHA(sha string) string {
-	if len(sha) > 7 {
-		return sha[:7]
-	}
-	return sha
-}
@@ -0,0 +1,242 @@
+package newsletter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/email"
+	"github.com/perbu/activity/internal/git"
+)
+
+// SummaryDiff is the difference between the summary of a report recipients
+// were sent and the summary it was regenerated with. Lines are compared
+// without markdown formatting, case or extra whitespace, so rewrapping or
+// restyling a line does not count as a change.
+type SummaryDiff struct {
+	Removed []string // Lines of the sent summary missing from the new one
+	Added   []string // Lines of the new summary missing from the sent one
+	Change  float64  // Share of the words of both summaries in changed lines, 0 to 1
+}
+
+// DiffSummaries compares the lines of the sent and regenerated summaries
+func DiffSummaries(sent, regenerated string) *SummaryDiff {
+	oldLines, newLines := summaryLines(sent), summaryLines(regenerated)
+
+	unmatched := make(map[string]int)
+	for _, line := range oldLines {
+		unmatched[line.key]++
+	}
+	diff := &SummaryDiff{}
+	var total, changed int
+	for _, line := range newLines {
+		total += line.words
+		if unmatched[line.key] > 0 {
+			unmatched[line.key]--
+			continue
+		}
+		diff.Added = append(diff.Added, line.text)
+		changed += line.words
+	}
+	for _, line := range oldLines {
+		total += line.words
+		if unmatched[line.key] > 0 {
+			unmatched[line.key]--
+			diff.Removed = append(diff.Removed, line.text)
+			changed += line.words
+		}
+	}
+	if total > 0 {
+		diff.Change = float64(changed) / float64(total)
+	}
+	return diff
+}
+
+// summaryLine is a non-empty line of a summary
+type summaryLine struct {
+	text  string // Line without heading and list markers
+	key   string // Plain text, lowercased, with whitespace collapsed
+	words int
+}
+
+// summaryLines splits a markdown summary into lines for comparison
+func summaryLines(summary string) []summaryLine {
+	var lines []summaryLine
+	for _, text := range strings.Split(summary, "\n") {
+		text = strings.TrimLeft(strings.TrimSpace(text), "#>-*+ ")
+		fields := strings.Fields(strings.ToLower(StripMarkdown(text)))
+		if len(fields) == 0 {
+			continue
+		}
+		lines = append(lines, summaryLine{text: text, key: strings.Join(fields, " "), words: len(fields)})
+	}
+	return lines
+}
+
+// CorrectionData holds the data of a correction email
+type CorrectionData struct {
+	RepoName      string
+	Week          string // ISO week, e.g. "2024-W01"
+	Period        string // Week start and end dates
+	Removed       []string
+	Added         []string
+	SummaryHTML   template.HTML
+	Summary       string
+	SubjectPrefix string
+}
+
+// Subject returns the subject line of a correction email
+func (c *CorrectionData) Subject() string {
+	return c.SubjectPrefix + " Correction: " + c.RepoName + " " + c.Week
+}
+
+var correctionHTMLTemplate = template.Must(template.New("correction-html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Correction</title>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 700px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        h1 {
+            color: #2c3e50;
+            border-bottom: 2px solid #3498db;
+            padding-bottom: 10px;
+        }
+        h2 {
+            color: #2980b9;
+            margin-top: 30px;
+        }
+        .removed { color: #c0392b; text-decoration: line-through; }
+        .added { color: #27ae60; }
+        .summary {
+            background: #f8f9fa;
+            border-left: 4px solid #3498db;
+            padding: 15px 20px;
+            margin: 20px 0;
+        }
+        .footer {
+            margin-top: 40px;
+            padding-top: 20px;
+            border-top: 1px solid #ddd;
+            color: #666;
+            font-size: 0.85em;
+        }
+    </style>
+</head>
+<body>
+    <h1>Correction: {{.RepoName}}</h1>
+    <p>The report for week {{.Week}} ({{.Period}}) in an earlier newsletter has been regenerated. This is what changed.</p>
+    {{with .Removed}}
+    <h2>No longer in the report</h2>
+    <ul>{{range .}}<li class="removed">{{.}}</li>{{end}}</ul>
+    {{end}}
+    {{with .Added}}
+    <h2>New in the report</h2>
+    <ul>{{range .}}<li class="added">{{.}}</li>{{end}}</ul>
+    {{end}}
+    <h2>Updated report</h2>
+    <div class="summary">
+        {{.SummaryHTML}}
+    </div>
+    <div class="footer">
+        <p>This email was sent by Activity - Git Repository Change Analyzer</p>
+    </div>
+</body>
+</html>`))
+
+var correctionTextTemplate = texttemplate.Must(texttemplate.New("correction-text").Parse(`CORRECTION: {{.RepoName}} {{.Week}}
+==========
+
+The report for week {{.Week}} ({{.Period}}) in an earlier newsletter has been
+regenerated. This is what changed.
+{{with .Removed}}
+No longer in the report:
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Added}}
+New in the report:
+{{range .}}+ {{.}}
+{{end}}{{end}}
+Updated report:
+
+{{.Summary}}
+
+This email was sent by Activity - Git Repository Change Analyzer
+`))
+
+// ComposeCorrection builds the email telling a subscriber who was sent a
+// report what changed when it was regenerated
+func (c *Composer) ComposeCorrection(subscriber *db.Subscriber, repoName string, report *db.WeeklyReport, diff *SummaryDiff) (*email.Email, error) {
+	summaryHTML, err := MarkdownToHTML(report.Summary.String, c.extensions...)
+	if err != nil {
+		summaryHTML = ""
+	}
+	data := &CorrectionData{
+		RepoName:      repoName,
+		Week:          git.FormatISOWeek(report.Year, report.Week),
+		Period:        report.WeekStart.Format("Jan 2") + " - " + report.WeekEnd.Format("Jan 2, 2006"),
+		Removed:       diff.Removed,
+		Added:         diff.Added,
+		SummaryHTML:   summaryHTML,
+		Summary:       report.Summary.String,
+		SubjectPrefix: c.subjectPrefix,
+	}
+
+	var html, text bytes.Buffer
+	if err := correctionHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML: %w", err)
+	}
+	if err := correctionTextTemplate.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text: %w", err)
+	}
+
+	return &email.Email{
+		To:          subscriber.Email,
+		Subject:     data.Subject(),
+		HTMLContent: html.String(),
+		TextContent: text.String(),
+	}, nil
+}
+
+// SendCorrection sends a correction of a regenerated report to each
+// subscriber it was sent to, except suppressed ones
+func (s *Sender) SendCorrection(ctx context.Context, repoName string, report *db.WeeklyReport, diff *SummaryDiff) (*SendResult, error) {
+	recipients, err := s.db.ListReportRecipients(ctx, report.RepoID, report.Year, report.Week)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SendResult{TotalSubscribers: len(recipients)}
+	for _, subscriber := range recipients {
+		composed, err := s.composer.ComposeCorrection(subscriber, repoName, report, diff)
+		if err != nil {
+			fmt.Fprintf(s.output, "Error composing correction for %s: %v\n", subscriber.Email, err)
+			result.Errors++
+			continue
+		}
+
+		if s.dryRun {
+			fmt.Fprintf(s.output, "[DRY RUN] Would send correction to %s: %s\n", subscriber.Email, composed.Subject)
+		} else {
+			if _, err := s.client.Send(ctx, *composed); err != nil {
+				fmt.Fprintf(s.output, "Error sending correction to %s: %v\n", subscriber.Email, err)
+				result.Errors++
+				continue
+			}
+			fmt.Fprintf(s.output, "Sent correction to %s: %s\n", subscriber.Email, composed.Subject)
+		}
+		result.Sent++
+	}
+	return result, nil
+}
@@ -0,0 +1,25 @@
+package newsletter
+
+import (
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// SendTime returns when the subscriber's most recent scheduled newsletter
+// was due: the latest Monday at their send hour, in their timezone, that is
+// not after now. An unknown timezone falls back to UTC.
+func SendTime(subscriber *db.Subscriber, now time.Time) time.Time {
+	loc, err := time.LoadLocation(subscriber.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	daysSinceMonday := (int(local.Weekday()) + 6) % 7
+	sendAt := time.Date(local.Year(), local.Month(), local.Day()-daysSinceMonday, subscriber.SendHour, 0, 0, 0, loc)
+	if sendAt.After(now) {
+		sendAt = sendAt.AddDate(0, 0, -7)
+	}
+	return sendAt
+}
@@ -2,6 +2,7 @@ package newsletter
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"time"
@@ -16,6 +17,7 @@ type SendResult struct {
 	Sent             int
 	Skipped          int
 	Errors           int
+	BatchIDs         []int64 // Send history batches, one per workspace sent to; none on dry runs
 }
 
 // Sender orchestrates the newsletter sending process
@@ -38,12 +40,35 @@ func NewSender(database *db.DB, composer *Composer, client email.Sender, dryRun
 	}
 }
 
-// SendAll sends newsletters to all subscribers with unsent activity runs
-func (s *Sender) SendAll(ctx context.Context, since time.Time) (*SendResult, error) {
+// SendAll sends newsletters to all subscribers with unsent weekly reports
+// for weeks that ended since since, regardless of their send schedule.
+// startedBy is the admin who started the send, for the send history.
+func (s *Sender) SendAll(ctx context.Context, since time.Time, startedBy string) (*SendResult, error) {
+	now := time.Now()
+	return s.send(ctx, s.newHistory(db.BatchManual, startedBy), func(*db.Subscriber) (time.Time, time.Time) {
+		return since, now
+	})
+}
+
+// SendScheduled sends each subscriber the reports released by their most
+// recent scheduled send time (see SendTime), i.e. the week that ended before
+// it. Sends are recorded, so calling this repeatedly delivers each week once,
+// on the first call after the subscriber's Monday send hour.
+func (s *Sender) SendScheduled(ctx context.Context, now time.Time) (*SendResult, error) {
+	return s.send(ctx, s.newHistory(db.BatchScheduled, ""), func(subscriber *db.Subscriber) (time.Time, time.Time) {
+		sendAt := SendTime(subscriber, now)
+		return sendAt.AddDate(0, 0, -7), sendAt
+	})
+}
+
+// send sends newsletters to all subscribers with unsent weekly reports in
+// the window returned for them: weeks that ended on or after since and
+// before the date of before
+func (s *Sender) send(ctx context.Context, h *history, window func(subscriber *db.Subscriber) (since, before time.Time)) (*SendResult, error) {
 	result := &SendResult{}
 
 	// Get all subscribers
-	subscribers, err := s.db.ListSubscribers()
+	subscribers, err := s.db.ListSubscribers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subscribers: %w", err)
 	}
@@ -51,110 +76,267 @@ func (s *Sender) SendAll(ctx context.Context, since time.Time) (*SendResult, err
 	result.TotalSubscribers = len(subscribers)
 
 	for _, subscriber := range subscribers {
-		// Get unsent activity runs for this subscriber
-		runs, err := s.db.GetUnsentActivityRuns(subscriber.ID, since)
-		if err != nil {
-			fmt.Fprintf(s.output, "Error getting unsent runs for %s: %v\n", subscriber.Email, err)
-			result.Errors++
-			continue
-		}
-
-		if len(runs) == 0 {
+		// Hard-bounced addresses are suppressed until an admin clears them
+		if subscriber.SuppressedAt.Valid {
 			result.Skipped++
 			continue
 		}
 
-		// Compose the newsletter
-		email, err := s.composer.ComposeForSubscriber(subscriber, runs)
-		if err != nil {
-			fmt.Fprintf(s.output, "Error composing newsletter for %s: %v\n", subscriber.Email, err)
+		since, before := window(subscriber)
+		delivery, err := s.deliver(ctx, subscriber, since, before)
+		h.record(ctx, subscriber, delivery)
+		switch {
+		case err != nil:
+			fmt.Fprintf(s.output, "Error sending to %s: %v\n", subscriber.Email, err)
 			result.Errors++
-			continue
-		}
-
-		if email == nil {
+		case delivery == nil:
 			result.Skipped++
-			continue
+		default:
+			result.Sent++
 		}
-
-		// Send or simulate sending
-		if s.dryRun {
-			fmt.Fprintf(s.output, "[DRY RUN] Would send to %s: %s (%d activity updates)\n",
-				subscriber.Email, email.Subject, len(runs))
-		} else {
-			messageID, err := s.client.Send(ctx, *email)
-			if err != nil {
-				fmt.Fprintf(s.output, "Error sending to %s: %v\n", subscriber.Email, err)
-				result.Errors++
-				continue
-			}
-
-			// Record sends for deduplication
-			for _, run := range runs {
-				_, err := s.db.CreateNewsletterSend(subscriber.ID, run.ID, messageID)
-				if err != nil {
-					fmt.Fprintf(s.output, "Warning: failed to record send for run %d: %v\n", run.ID, err)
-				}
-			}
-
-			fmt.Fprintf(s.output, "Sent to %s: %s (%d activity updates)\n",
-				subscriber.Email, email.Subject, len(runs))
-		}
-
-		result.Sent++
 	}
 
+	result.BatchIDs = h.batchIDs()
 	return result, nil
 }
 
-// SendToSubscriber sends a newsletter to a specific subscriber
-func (s *Sender) SendToSubscriber(ctx context.Context, email string, since time.Time) error {
-	subscriber, err := s.db.GetSubscriberByEmail(email)
+// SendToSubscriber sends a newsletter to a specific subscriber. startedBy is
+// the admin who started the send, for the send history.
+func (s *Sender) SendToSubscriber(ctx context.Context, email string, since time.Time, startedBy string) error {
+	subscriber, err := s.db.GetSubscriberByEmail(ctx, email)
 	if err != nil {
 		return fmt.Errorf("subscriber not found: %s", email)
 	}
+	if subscriber.SuppressedAt.Valid {
+		return fmt.Errorf("subscriber %s is suppressed after a hard bounce", email)
+	}
 
-	runs, err := s.db.GetUnsentActivityRuns(subscriber.ID, since)
+	delivery, err := s.deliver(ctx, subscriber, since, time.Now())
+	s.newHistory(db.BatchSingle, startedBy).record(ctx, subscriber, delivery)
 	if err != nil {
-		return fmt.Errorf("failed to get unsent runs: %w", err)
+		return err
+	}
+	if delivery == nil {
+		fmt.Fprintf(s.output, "No unsent weekly reports for %s\n", email)
 	}
+	return nil
+}
 
-	if len(runs) == 0 {
-		fmt.Fprintf(s.output, "No unsent activity runs for %s\n", email)
-		return nil
+// Resend retries a failed delivery of the send history with the reports of
+// its window the subscriber still has not been sent, and records the
+// outcome. If they have all been sent since, the delivery is marked skipped.
+func (s *Sender) Resend(ctx context.Context, delivery *db.NewsletterDelivery) error {
+	if !delivery.SubscriberID.Valid {
+		return fmt.Errorf("subscriber %s no longer exists", delivery.Email)
+	}
+	subscriber, err := s.db.GetSubscriber(ctx, delivery.SubscriberID.Int64)
+	if err != nil {
+		return fmt.Errorf("subscriber %s no longer exists", delivery.Email)
+	}
+	if subscriber.SuppressedAt.Valid {
+		return fmt.Errorf("subscriber %s is suppressed after a hard bounce", subscriber.Email)
+	}
+
+	resent, err := s.deliver(ctx, subscriber, delivery.WindowStart, delivery.WindowEnd)
+	if resent == nil && err == nil {
+		fmt.Fprintf(s.output, "No unsent weekly reports for %s\n", subscriber.Email)
+		resent = &db.NewsletterDelivery{Status: db.DeliverySkipped, Subject: delivery.Subject}
+	}
+	if s.dryRun {
+		return err
+	}
+	delivery.Status = resent.Status
+	delivery.Subject = resent.Subject
+	delivery.ReportCount = resent.ReportCount
+	delivery.MessageID = resent.MessageID
+	delivery.Error = resent.Error
+	if updateErr := s.db.UpdateNewsletterDelivery(ctx, delivery); updateErr != nil {
+		fmt.Fprintf(s.output, "Warning: failed to record resend to %s: %v\n", subscriber.Email, updateErr)
+	}
+	return err
+}
+
+// deliver sends a subscriber their unsent reports for weeks that ended on
+// or after since and before the date of before, and records them as sent.
+// It returns the delivery to record in the send history, with the error if
+// it failed, or nil if there was nothing to send.
+func (s *Sender) deliver(ctx context.Context, subscriber *db.Subscriber, since, before time.Time) (*db.NewsletterDelivery, error) {
+	delivery := &db.NewsletterDelivery{Status: db.DeliveryFailed, WindowStart: since, WindowEnd: before}
+	fail := func(err error) (*db.NewsletterDelivery, error) {
+		delivery.Error = sql.NullString{String: err.Error(), Valid: true}
+		return delivery, err
 	}
 
-	composed, err := s.composer.ComposeForSubscriber(subscriber, runs)
+	composed, reports, err := s.compose(ctx, subscriber, since, before)
 	if err != nil {
-		return fmt.Errorf("failed to compose newsletter: %w", err)
+		return fail(err)
 	}
+	if composed == nil {
+		return nil, nil
+	}
+	delivery.Subject = composed.Subject
+	delivery.ReportCount = len(reports)
+
+	// Send or simulate sending
+	if s.dryRun {
+		fmt.Fprintf(s.output, "[DRY RUN] Would send to %s: %s (%d weekly reports)\n",
+			subscriber.Email, composed.Subject, len(reports))
+		delivery.Status = db.DeliverySent
+		return delivery, nil
+	}
+
+	messageID, err := s.client.Send(ctx, *composed)
+	if err != nil {
+		return fail(fmt.Errorf("failed to send email: %w", err))
+	}
+
+	// Record sends for deduplication
+	s.recordSends(ctx, subscriber, reports, messageID)
+
+	fmt.Fprintf(s.output, "Sent to %s: %s (%d weekly reports)\n",
+		subscriber.Email, composed.Subject, len(reports))
+	delivery.Status = db.DeliverySent
+	delivery.MessageID = sql.NullString{String: messageID, Valid: messageID != ""}
+	return delivery, nil
+}
+
+// Preview returns the newsletter SendToSubscriber would send a subscriber
+// now, or nil if they have no unsent reports for weeks that ended since since
+func (s *Sender) Preview(ctx context.Context, subscriber *db.Subscriber, since time.Time) (*email.Email, error) {
+	composed, _, err := s.compose(ctx, subscriber, since, time.Now())
+	return composed, err
+}
 
+// SendTest sends the newsletter a subscriber would receive now to the
+// address to instead, with the subject marked as a test. The reports are
+// not recorded as sent, so the subscriber still receives them.
+func (s *Sender) SendTest(ctx context.Context, subscriber *db.Subscriber, to string, since time.Time) error {
+	composed, reports, err := s.compose(ctx, subscriber, since, time.Now())
+	if err != nil {
+		return err
+	}
 	if composed == nil {
-		fmt.Fprintf(s.output, "No content to send for %s\n", email)
+		fmt.Fprintf(s.output, "No unsent weekly reports for %s\n", subscriber.Email)
 		return nil
 	}
+	composed.To = to
+	composed.Subject = "[TEST] " + composed.Subject
 
 	if s.dryRun {
-		fmt.Fprintf(s.output, "[DRY RUN] Would send to %s: %s (%d activity updates)\n",
-			email, composed.Subject, len(runs))
+		fmt.Fprintf(s.output, "[DRY RUN] Would send test of %s's newsletter to %s: %s (%d weekly reports)\n",
+			subscriber.Email, to, composed.Subject, len(reports))
 		return nil
 	}
 
-	messageID, err := s.client.Send(ctx, *composed)
-	if err != nil {
+	if _, err := s.client.Send(ctx, *composed); err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	// Record sends
-	for _, run := range runs {
-		_, err := s.db.CreateNewsletterSend(subscriber.ID, run.ID, messageID)
+	fmt.Fprintf(s.output, "Sent test of %s's newsletter to %s: %s (%d weekly reports)\n",
+		subscriber.Email, to, composed.Subject, len(reports))
+	return nil
+}
+
+// compose builds the newsletter of a subscriber's unsent reports for weeks
+// that ended on or after since and before the date of before, returning a
+// nil email if there is nothing to send
+func (s *Sender) compose(ctx context.Context, subscriber *db.Subscriber, since, before time.Time) (*email.Email, []*db.WeeklyReport, error) {
+	reports, err := s.db.GetUnsentWeeklyReports(ctx, subscriber.ID, since, before)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get unsent reports: %w", err)
+	}
+	reports, err = s.personalize(ctx, subscriber, reports)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	composed, err := s.composer.ComposeForSubscriber(ctx, subscriber, reports)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compose newsletter: %w", err)
+	}
+	return composed, reports, nil
+}
+
+// personalize keeps only the reports of repositories the subscriber starred
+// in the web UI, if they chose favorites-only newsletters and starred any
+func (s *Sender) personalize(ctx context.Context, subscriber *db.Subscriber, reports []*db.WeeklyReport) ([]*db.WeeklyReport, error) {
+	prefs, err := s.db.GetUserPreferences(ctx, subscriber.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !prefs.DigestFavoritesOnly || len(prefs.FavoriteRepoIDs) == 0 {
+		return reports, nil
+	}
+	var kept []*db.WeeklyReport
+	for _, report := range reports {
+		if prefs.IsFavorite(report.RepoID) {
+			kept = append(kept, report)
+		}
+	}
+	return kept, nil
+}
+
+// recordSends marks reports as sent to a subscriber so they are not sent again
+func (s *Sender) recordSends(ctx context.Context, subscriber *db.Subscriber, reports []*db.WeeklyReport, messageID string) {
+	for _, report := range reports {
+		_, err := s.db.CreateNewsletterSend(ctx, subscriber.ID, report.RepoID, report.Year, report.Week, messageID)
 		if err != nil {
-			fmt.Fprintf(s.output, "Warning: failed to record send for run %d: %v\n", run.ID, err)
+			fmt.Fprintf(s.output, "Warning: failed to record send for report %d: %v\n", report.ID, err)
 		}
 	}
+}
 
-	fmt.Fprintf(s.output, "Sent to %s: %s (%d activity updates)\n",
-		email, composed.Subject, len(runs))
+// history records the deliveries of a send in the send history. A batch is
+// created in a subscriber's workspace on the first delivery there, so a
+// send that finds nothing to deliver leaves no trace.
+type history struct {
+	db        *db.DB
+	output    io.Writer
+	kind      string
+	startedBy string
+	batches   map[int64]int64 // Workspace ID to batch ID
+	ids       []int64
+}
 
-	return nil
+// newHistory returns the recorder of a send, or nil on dry runs, which are
+// not recorded
+func (s *Sender) newHistory(kind, startedBy string) *history {
+	if s.dryRun {
+		return nil
+	}
+	return &history{db: s.db, output: s.output, kind: kind, startedBy: startedBy, batches: make(map[int64]int64)}
+}
+
+// record adds a subscriber's delivery to the send history; a nil delivery,
+// when there was nothing to send, is not recorded
+func (h *history) record(ctx context.Context, subscriber *db.Subscriber, delivery *db.NewsletterDelivery) {
+	if h == nil || delivery == nil {
+		return
+	}
+	batchID, ok := h.batches[subscriber.WorkspaceID]
+	if !ok {
+		batch, err := h.db.CreateNewsletterBatch(db.WithWorkspace(ctx, subscriber.WorkspaceID), h.kind, h.startedBy)
+		if err != nil {
+			fmt.Fprintf(h.output, "Warning: failed to record send history: %v\n", err)
+			return
+		}
+		batchID = batch.ID
+		h.batches[subscriber.WorkspaceID] = batchID
+		h.ids = append(h.ids, batchID)
+	}
+
+	delivery.BatchID = batchID
+	delivery.SubscriberID = sql.NullInt64{Int64: subscriber.ID, Valid: true}
+	delivery.Email = subscriber.Email
+	if _, err := h.db.CreateNewsletterDelivery(ctx, delivery); err != nil {
+		fmt.Fprintf(h.output, "Warning: failed to record delivery to %s: %v\n", subscriber.Email, err)
+	}
+}
+
+// batchIDs returns the batches deliveries were recorded in
+func (h *history) batchIDs() []int64 {
+	if h == nil {
+		return nil
+	}
+	return h.ids
 }
@@ -5,21 +5,30 @@ import (
 	"html/template"
 	"strings"
 
+	"github.com/perbu/activity/internal/markdown"
 	"github.com/yuin/goldmark"
 )
 
-// RepoSection represents a section of the newsletter for a single repository
+// RepoSection is the section of the newsletter for one repository
 type RepoSection struct {
 	RepoName    string
+	Weeks       []WeekSection // Oldest first
+	CommitCount int           // Commits of all weeks
+}
+
+// WeekSection is one weekly report within a repository's section
+type WeekSection struct {
 	Summary     string
 	SummaryHTML template.HTML
-	CommitRange string
-	AnalyzedAt  string
+	Week        string // ISO week, e.g. "2024-W01"
+	Period      string // Week start and end dates
+	CommitCount int
 }
 
 // NewsletterData holds all data needed to render a newsletter
 type NewsletterData struct {
 	Sections      []RepoSection
+	MoreRepos     []string // Repositories with reports beyond the section limit
 	TotalRepos    int
 	SubjectPrefix string
 }
@@ -77,6 +86,19 @@ var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
         .summary ul, .summary ol {
             margin-left: 20px;
         }
+        .summary pre {
+            background: #f1f3f5;
+            padding: 10px 12px;
+            overflow-x: auto;
+        }
+        .hl-keyword { color: #8e44ad; }
+        .hl-string { color: #27ae60; }
+        .hl-number { color: #d35400; }
+        .hl-comment { color: #7f8c8d; font-style: italic; }
+        .more {
+            color: #666;
+            margin: 20px 0;
+        }
         .footer {
             margin-top: 40px;
             padding-top: 20px;
@@ -91,15 +113,20 @@ var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
     {{range .Sections}}
     <div class="repo-section">
         <h2>{{.RepoName}}</h2>
+        {{range .Weeks}}
         <div class="meta">
-            Commits: {{.CommitRange}}<br>
-            Analyzed: {{.AnalyzedAt}}
+            Week {{.Week}} ({{.Period}})<br>
+            Commits: {{.CommitCount}}
         </div>
         <div class="summary">
             {{.SummaryHTML}}
         </div>
+        {{end}}
     </div>
     {{end}}
+    {{with .MoreRepos}}
+    <p class="more">And {{len .}} more {{if eq (len .) 1}}repo{{else}}repos{{end}}: {{range $i, $name := .}}{{if $i}}, {{end}}{{$name}}{{end}}</p>
+    {{end}}
     <div class="footer">
         <p>This email was sent by Activity - Git Repository Change Analyzer</p>
     </div>
@@ -111,13 +138,15 @@ var textTemplate = template.Must(template.New("text").Parse(`ACTIVITY DIGEST
 
 {{range .Sections}}
 ## {{.RepoName}}
-
-Commits: {{.CommitRange}}
-Analyzed: {{.AnalyzedAt}}
+{{range .Weeks}}
+Week {{.Week}} ({{.Period}})
+Commits: {{.CommitCount}}
 
 {{.Summary}}
-
+{{end}}
 ---
+{{end}}{{with .MoreRepos}}
+And {{len .}} more {{if eq (len .) 1}}repo{{else}}repos{{end}}: {{range $i, $name := .}}{{if $i}}, {{end}}{{$name}}{{end}}
 {{end}}
 
 This email was sent by Activity - Git Repository Change Analyzer
@@ -141,13 +170,11 @@ func RenderText(data *NewsletterData) (string, error) {
 	return buf.String(), nil
 }
 
-// MarkdownToHTML converts markdown text to HTML
-func MarkdownToHTML(markdown string) (template.HTML, error) {
-	var buf bytes.Buffer
-	if err := goldmark.Convert([]byte(markdown), &buf); err != nil {
-		return "", err
-	}
-	return template.HTML(buf.String()), nil
+// MarkdownToHTML converts markdown text to HTML for emails, with optional
+// goldmark extensions. Relative links are unlinked, since they can't resolve
+// in a mail client.
+func MarkdownToHTML(source string, extensions ...goldmark.Extender) (template.HTML, error) {
+	return markdown.ToHTML(markdown.New(extensions, markdown.WithAbsoluteLinksOnly()), source)
 }
 
 // StripMarkdown attempts to convert markdown to plain text
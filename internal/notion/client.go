@@ -0,0 +1,214 @@
+// Package notion publishes pages to Notion databases through its REST API.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiURL is the Notion API base URL
+const apiURL = "https://api.notion.com/v1"
+
+// apiVersion is the Notion API version requests are made against
+const apiVersion = "2022-06-28"
+
+// maxBlocksPerRequest is how many blocks Notion accepts in one request
+const maxBlocksPerRequest = 100
+
+// Client creates and updates pages in Notion databases shared with an
+// integration
+type Client struct {
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client authenticated with an internal integration
+// token
+func NewClient(token string) *Client {
+	return &Client{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Page is a published Notion page
+type Page struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Block is a Notion block object, e.g.
+// {"type": "paragraph", "paragraph": {"rich_text": [...]}}
+type Block map[string]any
+
+// Publish creates a page with the given title and content in a database, or
+// replaces the content of the database's page with that title. The title is
+// set on the database's title property, whatever it is named.
+func (c *Client) Publish(ctx context.Context, databaseID, title string, blocks []Block) (*Page, error) {
+	property, err := c.titleProperty(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := c.findPage(ctx, databaseID, property, title)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if err := c.clearPage(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+		if err := c.appendBlocks(ctx, existing.ID, blocks); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	first := blocks[:min(len(blocks), maxBlocksPerRequest)]
+	req := map[string]any{
+		"parent": map[string]any{"database_id": databaseID},
+		"properties": map[string]any{
+			property: map[string]any{"title": []any{map[string]any{"text": map[string]any{"content": title}}}},
+		},
+		"children": first,
+	}
+	var page Page
+	if err := c.do(ctx, http.MethodPost, apiURL+"/pages", req, &page); err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	if err := c.appendBlocks(ctx, page.ID, blocks[len(first):]); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// titleProperty returns the name of a database's title property
+func (c *Client) titleProperty(ctx context.Context, databaseID string) (string, error) {
+	var db struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := c.do(ctx, http.MethodGet, apiURL+"/databases/"+url.PathEscape(databaseID), nil, &db); err != nil {
+		return "", fmt.Errorf("failed to get database: %w", err)
+	}
+	for name, p := range db.Properties {
+		if p.Type == "title" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("database %s has no title property", databaseID)
+}
+
+// findPage returns the database page with the given title, or nil if there is
+// none
+func (c *Client) findPage(ctx context.Context, databaseID, property, title string) (*Page, error) {
+	req := map[string]any{
+		"filter":    map[string]any{"property": property, "title": map[string]any{"equals": title}},
+		"page_size": 1,
+	}
+	var result struct {
+		Results []Page `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, apiURL+"/databases/"+url.PathEscape(databaseID)+"/query", req, &result); err != nil {
+		return nil, fmt.Errorf("failed to look up page %q: %w", title, err)
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
+
+// clearPage deletes the blocks of a page
+func (c *Client) clearPage(ctx context.Context, pageID string) error {
+	var ids []string
+	cursor := ""
+	for {
+		endpoint := apiURL + "/blocks/" + url.PathEscape(pageID) + "/children?page_size=100"
+		if cursor != "" {
+			endpoint += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		var result struct {
+			Results []struct {
+				ID string `json:"id"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, http.MethodGet, endpoint, nil, &result); err != nil {
+			return fmt.Errorf("failed to list page content: %w", err)
+		}
+		for _, b := range result.Results {
+			ids = append(ids, b.ID)
+		}
+		if !result.HasMore {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	for _, id := range ids {
+		if err := c.do(ctx, http.MethodDelete, apiURL+"/blocks/"+url.PathEscape(id), nil, &struct{}{}); err != nil {
+			return fmt.Errorf("failed to delete page content: %w", err)
+		}
+	}
+	return nil
+}
+
+// appendBlocks adds blocks to the end of a page, in as many requests as needed
+func (c *Client) appendBlocks(ctx context.Context, pageID string, blocks []Block) error {
+	for len(blocks) > 0 {
+		n := min(len(blocks), maxBlocksPerRequest)
+		req := map[string]any{"children": blocks[:n]}
+		if err := c.do(ctx, http.MethodPatch, apiURL+"/blocks/"+url.PathEscape(pageID)+"/children", req, &struct{}{}); err != nil {
+			return fmt.Errorf("failed to add page content: %w", err)
+		}
+		blocks = blocks[n:]
+	}
+	return nil
+}
+
+// do calls a Notion API endpoint with an optional JSON request body and
+// decodes the JSON response into v
+func (c *Client) do(ctx context.Context, method, endpoint string, in, v any) error {
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Notion-Version", apiVersion)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Notion API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Notion API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Notion API response: %w", err)
+	}
+	return nil
+}
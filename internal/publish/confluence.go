@@ -0,0 +1,57 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/perbu/activity/internal/confluence"
+	"github.com/yuin/goldmark"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// confluencePublisher publishes reports as pages in a Confluence space
+type confluencePublisher struct {
+	client     *confluence.Client
+	space      string
+	parentID   string
+	extensions []goldmark.Extender
+}
+
+// NewConfluence returns a Publisher creating a page per report in a space,
+// under the page parentID if set. Summaries are rendered with the given
+// goldmark extensions.
+func NewConfluence(client *confluence.Client, space, parentID string, extensions ...goldmark.Extender) Publisher {
+	return &confluencePublisher{client: client, space: space, parentID: parentID, extensions: extensions}
+}
+
+// Name implements Publisher
+func (p *confluencePublisher) Name() string { return "confluence" }
+
+// Publish implements Publisher. Titles are unique within a space, so they
+// name both the repo and the week.
+func (p *confluencePublisher) Publish(ctx context.Context, report *Report) (string, error) {
+	xhtml, err := confluencePage(report, p.extensions...)
+	if err != nil {
+		return "", err
+	}
+	page, err := p.client.Publish(ctx, p.space, p.parentID, report.Title(), xhtml)
+	if err != nil {
+		return "", err
+	}
+	return page.URL, nil
+}
+
+// confluencePage renders a weekly report as XHTML in Confluence's storage
+// format: the period and commit count followed by the summary
+func confluencePage(report *Report, extensions ...goldmark.Extender) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<p><em>%s</em></p>\n", html.EscapeString(report.Period()))
+
+	md := goldmark.New(goldmark.WithExtensions(extensions...), goldmark.WithRendererOptions(gmhtml.WithXHTML()))
+	if err := md.Convert([]byte(report.Summary), &buf); err != nil {
+		return "", fmt.Errorf("failed to convert summary: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,37 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perbu/activity/internal/github"
+)
+
+// discussionsPublisher posts reports as discussions in a GitHub repository
+type discussionsPublisher struct {
+	client   *github.Client
+	owner    string
+	repo     string
+	category string
+}
+
+// NewDiscussions returns a Publisher posting a discussion per report in a
+// category of a GitHub repository's Discussions
+func NewDiscussions(client *github.Client, owner, repo, category string) Publisher {
+	return &discussionsPublisher{client: client, owner: owner, repo: repo, category: category}
+}
+
+// Name implements Publisher
+func (p *discussionsPublisher) Name() string { return "github-discussions" }
+
+// Publish implements Publisher. Discussions live in the report's repository,
+// so their titles only name the week.
+func (p *discussionsPublisher) Publish(ctx context.Context, report *Report) (string, error) {
+	title := "Weekly report " + report.WeekLabel()
+	body := fmt.Sprintf("_%s_\n\n%s\n", report.Period(), report.Summary)
+	discussion, err := p.client.PublishDiscussion(ctx, p.owner, p.repo, p.category, title, body)
+	if err != nil {
+		return "", err
+	}
+	return discussion.URL, nil
+}
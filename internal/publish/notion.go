@@ -0,0 +1,243 @@
+package publish
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/perbu/activity/internal/notion"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// maxRichTextLength is the longest text Notion accepts in one rich text object
+const maxRichTextLength = 2000
+
+// notionPublisher publishes reports as pages in a Notion database
+type notionPublisher struct {
+	client     *notion.Client
+	databaseID string
+	extensions []goldmark.Extender
+}
+
+// NewNotion returns a Publisher creating a page per report in a Notion
+// database. Summaries are parsed with the given goldmark extensions.
+func NewNotion(client *notion.Client, databaseID string, extensions ...goldmark.Extender) Publisher {
+	return &notionPublisher{client: client, databaseID: databaseID, extensions: extensions}
+}
+
+// Name implements Publisher
+func (p *notionPublisher) Name() string { return "notion" }
+
+// Publish implements Publisher. A database may hold the reports of several
+// repos, so titles name both the repo and the week.
+func (p *notionPublisher) Publish(ctx context.Context, report *Report) (string, error) {
+	blocks := append([]notion.Block{
+		textBlock("paragraph", []any{richText(report.Period(), annotations{italic: true}, "")}),
+	}, notionBlocks([]byte(report.Summary), p.extensions...)...)
+	page, err := p.client.Publish(ctx, p.databaseID, report.Title(), blocks)
+	if err != nil {
+		return "", err
+	}
+	return page.URL, nil
+}
+
+// notionBlocks converts markdown to Notion blocks: headings, paragraphs,
+// bulleted and numbered lists (one level of nesting), code blocks, quotes and
+// dividers. Anything else becomes a paragraph of its text.
+func notionBlocks(source []byte, extensions ...goldmark.Extender) []notion.Block {
+	md := goldmark.New(goldmark.WithExtensions(extensions...))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var blocks []notion.Block
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		blocks = append(blocks, notionBlock(n, source, 0)...)
+	}
+	return blocks
+}
+
+// notionBlock converts a markdown block node at the given list depth
+func notionBlock(n ast.Node, source []byte, depth int) []notion.Block {
+	switch n := n.(type) {
+	case *ast.Heading:
+		kind := "heading_3"
+		if n.Level <= 2 {
+			kind = []string{"", "heading_1", "heading_2"}[n.Level]
+		}
+		return []notion.Block{textBlock(kind, inlineText(n, source))}
+
+	case *ast.List:
+		kind := "bulleted_list_item"
+		if n.IsOrdered() {
+			kind = "numbered_list_item"
+		}
+		var items []notion.Block
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			var content []any
+			var children []notion.Block
+			for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+				switch {
+				case content == nil && (c.Kind() == ast.KindParagraph || c.Kind() == ast.KindTextBlock):
+					content = inlineText(c, source)
+				case depth == 0:
+					children = append(children, notionBlock(c, source, depth+1)...)
+				default:
+					// Notion takes two levels of blocks per request
+					content = append(content, richText(" "+plainText(c, source), annotations{}, ""))
+				}
+			}
+			block := textBlock(kind, content)
+			if len(children) > 0 {
+				block[kind].(map[string]any)["children"] = children
+			}
+			items = append(items, block)
+		}
+		return items
+
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		var code strings.Builder
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			code.Write(seg.Value(source))
+		}
+		block := textBlock("code", splitText(strings.TrimSuffix(code.String(), "\n"), annotations{}, ""))
+		block["code"].(map[string]any)["language"] = "plain text"
+		return []notion.Block{block}
+
+	case *ast.Blockquote:
+		return []notion.Block{textBlock("quote", splitText(plainText(n, source), annotations{}, ""))}
+
+	case *ast.ThematicBreak:
+		return []notion.Block{{"type": "divider", "divider": map[string]any{}}}
+
+	case *ast.Paragraph, *ast.TextBlock:
+		return []notion.Block{textBlock("paragraph", inlineText(n, source))}
+
+	default:
+		if s := strings.TrimSpace(plainText(n, source)); s != "" {
+			return []notion.Block{textBlock("paragraph", splitText(s, annotations{}, ""))}
+		}
+		return nil
+	}
+}
+
+// textBlock returns a block of the given type with rich text
+func textBlock(kind string, content []any) notion.Block {
+	if content == nil {
+		content = []any{}
+	}
+	return notion.Block{"type": kind, kind: map[string]any{"rich_text": content}}
+}
+
+// annotations are the text styles carried into rich text
+type annotations struct {
+	bold, italic, code bool
+}
+
+// inlineText converts the inline children of a block node to rich text
+func inlineText(n ast.Node, source []byte) []any {
+	var content []any
+	var walk func(n ast.Node, a annotations, link string)
+	walk = func(n ast.Node, a annotations, link string) {
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c := c.(type) {
+			case *ast.Text:
+				s := string(c.Segment.Value(source))
+				if c.HardLineBreak() {
+					s += "\n"
+				} else if c.SoftLineBreak() {
+					s += " "
+				}
+				content = append(content, splitText(s, a, link)...)
+			case *ast.String:
+				content = append(content, splitText(string(c.Value), a, link)...)
+			case *ast.CodeSpan:
+				a := a
+				a.code = true
+				content = append(content, splitText(plainText(c, source), a, link)...)
+			case *ast.Emphasis:
+				a := a
+				if c.Level >= 2 {
+					a.bold = true
+				} else {
+					a.italic = true
+				}
+				walk(c, a, link)
+			case *ast.Link:
+				walk(c, a, string(c.Destination))
+			case *ast.AutoLink:
+				url := string(c.URL(source))
+				content = append(content, splitText(string(c.Label(source)), a, url)...)
+			default:
+				walk(c, a, link)
+			}
+		}
+	}
+	walk(n, annotations{}, "")
+	return content
+}
+
+// splitText returns rich text objects for s, split to Notion's length limit
+func splitText(s string, a annotations, link string) []any {
+	var content []any
+	for s != "" {
+		n := len(s)
+		if utf8.RuneCountInString(s) > maxRichTextLength {
+			n = 0
+			for i := 0; i < maxRichTextLength; i++ {
+				_, size := utf8.DecodeRuneInString(s[n:])
+				n += size
+			}
+		}
+		content = append(content, richText(s[:n], a, link))
+		s = s[n:]
+	}
+	return content
+}
+
+// richText returns a Notion rich text object
+func richText(s string, a annotations, link string) map[string]any {
+	t := map[string]any{"content": s}
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		t["link"] = map[string]any{"url": link}
+	}
+	return map[string]any{
+		"type":        "text",
+		"text":        t,
+		"annotations": map[string]any{"bold": a.bold, "italic": a.italic, "code": a.code},
+	}
+}
+
+// plainText returns the text of a node and its descendants
+func plainText(root ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			sb.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				sb.WriteString("\n")
+			}
+		case *ast.String:
+			sb.Write(n.Value)
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					sb.Write(t.Segment.Value(source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		// Separate blocks below the root by line breaks
+		if n != root && n.Type() == ast.TypeBlock && n.PreviousSibling() != nil {
+			sb.WriteString("\n")
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
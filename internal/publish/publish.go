@@ -0,0 +1,53 @@
+// Package publish pushes weekly reports to where teams read them: Confluence
+// spaces, Notion databases and GitHub Discussions. Publishers create a page
+// per repo and week and update it when the report is regenerated.
+package publish
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/perbu/activity/internal/git"
+)
+
+// Report is a weekly report to publish
+type Report struct {
+	Repo        string
+	Year        int
+	Week        int
+	WeekStart   time.Time
+	WeekEnd     time.Time
+	CommitCount int
+	Summary     string // Markdown
+}
+
+// Publisher publishes weekly reports to one destination
+type Publisher interface {
+	// Name names the destination in logs, e.g. "confluence"
+	Name() string
+	// Publish creates the report's page, or updates it if the report was
+	// published before, and returns its URL
+	Publish(ctx context.Context, report *Report) (string, error)
+}
+
+// WeekLabel returns the report's ISO week, e.g. 2026-W41
+func (r *Report) WeekLabel() string {
+	return git.FormatISOWeek(r.Year, r.Week)
+}
+
+// Title returns the title of the report's page in destinations shared by
+// repos, e.g. "my-repo 2026-W41". Updates find the page by this title.
+func (r *Report) Title() string {
+	return fmt.Sprintf("%s %s", r.Repo, r.WeekLabel())
+}
+
+// Period describes the week and its commit count, e.g.
+// "Oct 5 - Oct 11, 2026, 12 commits"
+func (r *Report) Period() string {
+	commits := "commits"
+	if r.CommitCount == 1 {
+		commits = "commit"
+	}
+	return fmt.Sprintf("%s - %s, %d %s", r.WeekStart.Format("Jan 2"), r.WeekEnd.Format("Jan 2, 2006"), r.CommitCount, commits)
+}
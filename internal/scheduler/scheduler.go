@@ -0,0 +1,195 @@
+// Package scheduler runs periodic background jobs inside the server process.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// job is a function run at a fixed interval
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+
+	// New intervals set after Start, received by the job's loop
+	reset chan time.Duration
+}
+
+// Scheduler runs registered jobs at fixed intervals until its context is
+// cancelled. Runs of the same job never overlap.
+type Scheduler struct {
+	jobs []*job
+
+	// Runs get their own context so that stopping the scheduler lets a run
+	// in progress finish; it is only cancelled by Stop giving up waiting
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	loops      sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]bool // Jobs with a run in progress
+
+	onFailure func(ctx context.Context, name string, err error)
+}
+
+// New creates an empty Scheduler
+func New() *Scheduler {
+	return &Scheduler{running: make(map[string]bool)}
+}
+
+// Add registers a job. A job with a non-positive interval is disabled until
+// SetInterval gives it a positive one. Must be called before Start.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, &job{name: name, interval: interval, run: run, reset: make(chan time.Duration, 1)})
+}
+
+// OnFailure sets a function called with the error of each failed run, e.g.
+// to notify admins. Must be called before Start.
+func (s *Scheduler) OnFailure(fn func(ctx context.Context, name string, err error)) {
+	s.onFailure = fn
+}
+
+// SetInterval changes how often a job runs, e.g. after the config is
+// reloaded. The next run is one new interval from now; a job that was
+// disabled runs immediately. A non-positive interval disables the job.
+// Unknown names are ignored. Calls must not be concurrent.
+func (s *Scheduler) SetInterval(name string, interval time.Duration) {
+	for _, j := range s.jobs {
+		if j.name != name {
+			continue
+		}
+		// Replace a pending interval the loop has not picked up yet
+		select {
+		case <-j.reset:
+		default:
+		}
+		j.reset <- interval
+	}
+}
+
+// Start runs each enabled job once immediately and then at its interval, in
+// the background. It returns immediately; cancel ctx to stop scheduling runs
+// and Stop to wait for runs in progress.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.runCtx, s.cancelRuns = context.WithCancel(context.WithoutCancel(ctx))
+	for _, j := range s.jobs {
+		s.loops.Add(1)
+		go func() {
+			defer s.loops.Done()
+			s.loop(ctx, j)
+		}()
+	}
+}
+
+// Stop waits for the runs in progress to finish once the context passed to
+// Start is cancelled. If ctx expires first, the runs are cancelled and Stop
+// returns an error naming the interrupted jobs after they have returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancelRuns == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancelRuns()
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	interrupted := make([]string, 0, len(s.running))
+	for name := range s.running {
+		interrupted = append(interrupted, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(interrupted)
+
+	s.cancelRuns()
+	<-done
+	return fmt.Errorf("cancelled scheduled jobs still running at shutdown: %s", strings.Join(interrupted, ", "))
+}
+
+// loop runs a job until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	interval := j.interval
+	for {
+		if interval <= 0 {
+			slog.Info("Scheduled job disabled", "job", j.name)
+			select {
+			case <-ctx.Done():
+				return
+			case interval = <-j.reset:
+				continue
+			}
+		}
+
+		slog.Info("Scheduled job", "job", j.name, "interval", interval)
+		interval = s.runEvery(ctx, j, interval)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runEvery runs a job now and then at interval until ctx is cancelled or the
+// job is disabled, returning the job's last interval
+func (s *Scheduler) runEvery(ctx context.Context, j *job, interval time.Duration) time.Duration {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.runOnce(ctx, j)
+	for {
+		select {
+		case <-ctx.Done():
+			return interval
+		case next := <-j.reset:
+			if next <= 0 {
+				return next
+			}
+			if next != interval {
+				slog.Info("Scheduled job rescheduled", "job", j.name, "interval", next)
+				interval = next
+				ticker.Reset(interval)
+			}
+		case <-ticker.C:
+			s.runOnce(ctx, j)
+		}
+	}
+}
+
+// runOnce runs a job and logs the outcome. The run gets the scheduler's run
+// context rather than ctx, so that stopping the scheduler does not interrupt it.
+func (s *Scheduler) runOnce(ctx context.Context, j *job) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	s.running[j.name] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, j.name)
+		s.mu.Unlock()
+	}()
+
+	start := time.Now()
+	if err := j.run(s.runCtx); err != nil {
+		slog.Error("Scheduled job failed", "job", j.name, "error", err)
+		if s.onFailure != nil {
+			s.onFailure(s.runCtx, j.name, err)
+		}
+	} else {
+		slog.Debug("Scheduled job completed", "job", j.name, "duration", time.Since(start))
+	}
+}
@@ -0,0 +1,68 @@
+package secretref
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/sigv4"
+)
+
+// resolveAWS reads a secret from AWS Secrets Manager with the credentials in
+// the standard AWS environment variables: ref is the secret's name or ARN,
+// optionally followed by #key to pick a key of a JSON secret. The region is
+// taken from an ARN, or else from AWS_REGION.
+func resolveAWS(ctx context.Context, ref string) (string, error) {
+	id, key, _ := strings.Cut(ref, "#")
+	creds := sigv4.EnvCredentials()
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	region := sigv4.EnvRegion()
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(id, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://secretsmanager."+region+".amazonaws.com/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, body, creds, region, "secretsmanager", time.Now())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Secrets Manager: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Secrets Manager returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Secrets Manager response: %w", err)
+	}
+	if key == "" {
+		return result.SecretString, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(result.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no key %q", key)
+	}
+	return field(fields, key)
+}
@@ -0,0 +1,68 @@
+package secretref
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+)
+
+// gcpScope is the OAuth scope needed to access Secret Manager
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// gcpEndpoint is the Secret Manager API, replaced in tests
+var gcpEndpoint = "https://secretmanager.googleapis.com"
+
+// resolveGCP accesses a secret version in Google Cloud Secret Manager with
+// Application Default Credentials: ref is the secret's resource name,
+// projects/<project>/secrets/<name>, optionally followed by /versions/<version>
+// (default: latest)
+func resolveGCP(ctx context.Context, ref string) (string, error) {
+	name := strings.Trim(ref, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{gcpScope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to find Google Cloud credentials: %w", err)
+	}
+	client, err := httptransport.NewClient(&httptransport.Options{Credentials: creds})
+	if err != nil {
+		return "", fmt.Errorf("failed to create Secret Manager client: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpEndpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Secret Manager: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Secret Manager returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Secret Manager response: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return string(data), nil
+}
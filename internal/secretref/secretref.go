@@ -0,0 +1,74 @@
+// Package secretref resolves references to secrets kept in HashiCorp Vault,
+// Google Cloud Secret Manager or AWS Secrets Manager, used in place of secret
+// config values:
+//
+//	vault://secret/data/activity#llm_api_key
+//	gcp-sm://projects/my-project/secrets/llm-api-key[/versions/3]
+//	aws-sm://activity/llm-api-key[#json_key]
+package secretref
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Reference schemes
+const (
+	vaultScheme = "vault://"
+	gcpScheme   = "gcp-sm://"
+	awsScheme   = "aws-sm://"
+)
+
+// httpClient is shared by the backends without their own authenticated client
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsRef reports whether a value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, vaultScheme) || strings.HasPrefix(value, gcpScheme) || strings.HasPrefix(value, awsScheme)
+}
+
+// Resolve returns the secret a reference points to, or value itself if it is
+// not a reference. Each backend is only contacted when a reference uses it.
+func Resolve(ctx context.Context, value string) (string, error) {
+	var secret string
+	var err error
+	switch {
+	case strings.HasPrefix(value, vaultScheme):
+		secret, err = resolveVault(ctx, strings.TrimPrefix(value, vaultScheme))
+	case strings.HasPrefix(value, gcpScheme):
+		secret, err = resolveGCP(ctx, strings.TrimPrefix(value, gcpScheme))
+	case strings.HasPrefix(value, awsScheme):
+		secret, err = resolveAWS(ctx, strings.TrimPrefix(value, awsScheme))
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", value, err)
+	}
+	return secret, nil
+}
+
+// field returns a field of a secret holding several, or its only field if
+// name is empty
+func field(fields map[string]any, name string) (string, error) {
+	if name == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields; name one after #", len(fields))
+		}
+		for k := range fields {
+			name = k
+		}
+	}
+	v, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", name)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q is not a string", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,308 @@
+package secretref
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// redirect sends the requests of httpClient to server instead, keeping
+// their original Host header
+func redirect(t *testing.T, server *httptest.Server) {
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	orig := httpClient
+	t.Cleanup(func() { httpClient = orig })
+	httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Host = req.URL.Host
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestIsRef(t *testing.T) {
+	tests := map[string]bool{
+		"vault://secret/data/activity#key": true,
+		"gcp-sm://projects/p/secrets/s":    true,
+		"aws-sm://activity/key":            true,
+		"sk-plain-api-key":                 false,
+		"https://example.com":              false,
+		"":                                 false,
+	}
+	for value, want := range tests {
+		if got := IsRef(value); got != want {
+			t.Errorf("IsRef(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolvePlainValue(t *testing.T) {
+	got, err := Resolve(context.Background(), "plain-value")
+	if err != nil || got != "plain-value" {
+		t.Errorf("Resolve() = %q, %v, want the value itself", got, err)
+	}
+}
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]any
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{"named field", map[string]any{"a": "1", "b": "2"}, "b", "2", false},
+		{"only field", map[string]any{"a": "1"}, "", "1", false},
+		{"several fields unnamed", map[string]any{"a": "1", "b": "2"}, "", "", true},
+		{"missing field", map[string]any{"a": "1"}, "b", "", true},
+		{"not a string", map[string]any{"a": 1.0}, "a", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := field(tt.fields, tt.field)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("field() = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/activity":
+			// KV version 2
+			writeJSON(w, map[string]any{"data": map[string]any{
+				"data":     map[string]any{"llm_api_key": "kv2-key", "other": "x"},
+				"metadata": map[string]any{"version": 3},
+			}})
+		case "/v1/kv/activity":
+			// KV version 1
+			writeJSON(w, map[string]any{"data": map[string]any{"llm_api_key": "kv1-key"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL+"/")
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_NAMESPACE", "team")
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{"vault://secret/data/activity#llm_api_key", "kv2-key", ""},
+		{"vault://kv/activity#llm_api_key", "kv1-key", ""},
+		{"vault://kv/activity", "kv1-key", ""},
+		{"vault://secret/data/activity", "", "2 fields"},
+		{"vault://secret/data/activity#missing", "", "no field"},
+		{"vault://secret/data/missing#key", "", "404"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(context.Background(), tt.ref)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Resolve(%q) error = %v, want it to mention %q", tt.ref, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.ref, got, err, tt.want)
+		}
+	}
+
+	t.Setenv("VAULT_TOKEN", "wrong")
+	if _, err := Resolve(context.Background(), "vault://kv/activity"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Resolve() error = %v, want Vault's error", err)
+	}
+}
+
+func TestVaultToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := vaultToken(); err == nil {
+		t.Error("vaultToken() should fail without a token")
+	}
+
+	if err := os.WriteFile(filepath.Join(home, ".vault-token"), []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := vaultToken(); err != nil || got != "file-token" {
+		t.Errorf("vaultToken() = %q, %v, want the token file's", got, err)
+	}
+
+	t.Setenv("VAULT_TOKEN", "env-token")
+	if got, err := vaultToken(); err != nil || got != "env-token" {
+		t.Errorf("vaultToken() = %q, %v, want VAULT_TOKEN first", got, err)
+	}
+}
+
+func TestResolveVaultNoAddr(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	if _, err := Resolve(context.Background(), "vault://kv/activity#key"); err == nil || !strings.Contains(err.Error(), "VAULT_ADDR") {
+		t.Errorf("Resolve() error = %v, want VAULT_ADDR to be required", err)
+	}
+}
+
+func TestResolveAWS(t *testing.T) {
+	var host, target, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, target, auth = r.Host, r.Header.Get("X-Amz-Target"), r.Header.Get("Authorization")
+		var body struct{ SecretId string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case strings.HasSuffix(body.SecretId, "activity/llm-api-key"):
+			writeJSON(w, map[string]string{"SecretString": "plain-secret"})
+		case strings.HasSuffix(body.SecretId, "activity/keys"):
+			writeJSON(w, map[string]string{"SecretString": `{"llm":"json-secret","other":"x"}`})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"__type":"ResourceNotFoundException"}`)
+		}
+	}))
+	defer server.Close()
+	redirect(t, server)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	got, err := Resolve(context.Background(), "aws-sm://activity/llm-api-key")
+	if err != nil || got != "plain-secret" {
+		t.Fatalf("Resolve() = %q, %v, want plain-secret", got, err)
+	}
+	if host != "secretsmanager.eu-west-1.amazonaws.com" || target != "secretsmanager.GetSecretValue" {
+		t.Errorf("request went to %s with target %q", host, target)
+	}
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for Secrets Manager", auth)
+	}
+
+	got, err = Resolve(context.Background(), "aws-sm://arn:aws:secretsmanager:us-west-2:123456789012:secret:activity/keys#llm")
+	if err != nil || got != "json-secret" {
+		t.Errorf("Resolve() = %q, %v, want json-secret", got, err)
+	}
+	if host != "secretsmanager.us-west-2.amazonaws.com" {
+		t.Errorf("request went to %s, want the ARN's region", host)
+	}
+
+	if _, err := Resolve(context.Background(), "aws-sm://activity/llm-api-key#llm"); err == nil || !strings.Contains(err.Error(), "not a JSON object") {
+		t.Errorf("Resolve() error = %v, want a key of a plain secret to fail", err)
+	}
+	if _, err := Resolve(context.Background(), "aws-sm://activity/missing"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("Resolve() error = %v, want Secrets Manager's error", err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := Resolve(context.Background(), "aws-sm://activity/llm-api-key"); err == nil || !strings.Contains(err.Error(), "AWS_ACCESS_KEY_ID") {
+		t.Errorf("Resolve() error = %v, want credentials to be required", err)
+	}
+}
+
+func TestResolveGCP(t *testing.T) {
+	var paths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"access_token": "gcp-token", "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("GET /v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcp-token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		paths = append(paths, r.URL.Path)
+		if strings.Contains(r.URL.Path, "/secrets/missing/") {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]any{"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("gcp-secret"))}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	orig := gcpEndpoint
+	gcpEndpoint = server.URL
+	t.Cleanup(func() { gcpEndpoint = orig })
+
+	// A service account whose tokens come from the test server
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "my-project",
+		"client_email": "activity@my-project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credsFile := filepath.Join(t.TempDir(), "creds.json")
+	if err := os.WriteFile(credsFile, creds, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsFile)
+
+	got, err := Resolve(context.Background(), "gcp-sm://projects/my-project/secrets/llm-api-key")
+	if err != nil || got != "gcp-secret" {
+		t.Fatalf("Resolve() = %q, %v, want gcp-secret", got, err)
+	}
+	if _, err := Resolve(context.Background(), "gcp-sm://projects/my-project/secrets/llm-api-key/versions/3/"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []string{
+		"/v1/projects/my-project/secrets/llm-api-key/versions/latest:access",
+		"/v1/projects/my-project/secrets/llm-api-key/versions/3:access",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+
+	if _, err := Resolve(context.Background(), "gcp-sm://projects/my-project/secrets/missing"); err == nil || !strings.Contains(err.Error(), "secret not found") {
+		t.Errorf("Resolve() error = %v, want Secret Manager's error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package secretref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveVault reads a field of a secret from Vault's KV engine: ref is the
+// API path after /v1/, e.g. secret/data/activity for a KV version 2 mount,
+// followed by #field. VAULT_ADDR, VAULT_TOKEN (or ~/.vault-token) and
+// VAULT_NAMESPACE are read as by the Vault CLI.
+func resolveVault(ctx context.Context, ref string) (string, error) {
+	path, name, _ := strings.Cut(ref, "#")
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Vault returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+	// KV version 2 nests the secret's fields under data.data
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]any); ok && fields["metadata"] != nil {
+		fields = nested
+	}
+	return field(fields, name)
+}
+
+// vaultToken returns the Vault token from VAULT_TOKEN or the Vault CLI's
+// token file
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+	return "", fmt.Errorf("VAULT_TOKEN is not set")
+}
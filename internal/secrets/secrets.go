@@ -0,0 +1,137 @@
+// Package secrets encrypts secrets stored in the database, such as GitHub App
+// private keys, with AES-256-GCM under a master key kept outside the database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of a master key in bytes
+const KeySize = 32
+
+// version prefixes each ciphertext, so the format can change later
+const version = "v1"
+
+// ErrUnknownKey is returned by Decrypt for a secret encrypted with a master
+// key that is not in the keyring
+var ErrUnknownKey = errors.New("secret was encrypted with an unknown master key")
+
+// Keyring encrypts secrets with the current master key and decrypts secrets
+// encrypted with the current or a previous one, so secrets stay readable
+// while the master key is rotated
+type Keyring struct {
+	current  key
+	previous []key
+}
+
+// key is a master key with the ID that ciphertexts refer to it by
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewKeyring creates a Keyring encrypting with current and also decrypting
+// with the previous keys. Keys must be KeySize bytes.
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	cur, err := newKey(current)
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{current: cur}
+	for _, p := range previous {
+		prev, err := newKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("previous master key: %w", err)
+		}
+		k.previous = append(k.previous, prev)
+	}
+	return k, nil
+}
+
+func newKey(k []byte) (key, error) {
+	if len(k) != KeySize {
+		return key{}, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(k))
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return key{}, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return key{}, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	sum := sha256.Sum256(k)
+	return key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// GenerateKey returns a new random master key, base64 encoded
+func GenerateKey() (string, error) {
+	k := make([]byte, KeySize)
+	if _, err := rand.Read(k); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(k), nil
+}
+
+// Encrypt encrypts a secret with the current master key. The secret's name is
+// authenticated with it, so a ciphertext cannot be passed off as another
+// secret. The result is "v1:<key ID>:<base64 nonce and ciphertext>".
+func (k *Keyring) Encrypt(name string, plaintext []byte) (string, error) {
+	nonce := make([]byte, k.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := k.current.aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return version + ":" + k.current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a secret encrypted by Encrypt with any key in the keyring
+func (k *Keyring) Decrypt(name, ciphertext string) ([]byte, error) {
+	id, data, err := parse(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	for _, mk := range append([]key{k.current}, k.previous...) {
+		if mk.id != id {
+			continue
+		}
+		n := mk.aead.NonceSize()
+		if len(data) < n {
+			return nil, fmt.Errorf("secret %s is truncated", name)
+		}
+		plaintext, err := mk.aead.Open(nil, data[:n], data[n:], []byte(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+		}
+		return plaintext, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// IsCurrent reports whether a ciphertext was encrypted with the current
+// master key, so that rotation can skip it
+func (k *Keyring) IsCurrent(ciphertext string) bool {
+	id, _, err := parse(ciphertext)
+	return err == nil && id == k.current.id
+}
+
+// parse splits a ciphertext into its key ID and the nonce and sealed data
+func parse(ciphertext string) (string, []byte, error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != version {
+		return "", nil, fmt.Errorf("unsupported secret format")
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return parts[1], data, nil
+}
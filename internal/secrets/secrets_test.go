@@ -0,0 +1,182 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	ciphertext, err := k.Encrypt("github.private_key", []byte("secret value"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, "v1:") || strings.Contains(ciphertext, "secret value") {
+		t.Errorf("Encrypt() = %q, want a v1 ciphertext", ciphertext)
+	}
+
+	plaintext, err := k.Decrypt("github.private_key", ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(plaintext) != "secret value" {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, "secret value")
+	}
+
+	again, err := k.Encrypt("github.private_key", []byte("secret value"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if again == ciphertext {
+		t.Error("Encrypt() should use a new nonce each time")
+	}
+}
+
+func TestDecryptAfterRotation(t *testing.T) {
+	old, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	ciphertext, err := old.Encrypt("jira.token", []byte("token"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	rotated, err := NewKeyring(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	plaintext, err := rotated.Decrypt("jira.token", ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() with the previous key error = %v", err)
+	}
+	if string(plaintext) != "token" {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, "token")
+	}
+
+	if rotated.IsCurrent(ciphertext) {
+		t.Error("IsCurrent() = true for a secret under the previous key")
+	}
+	reencrypted, err := rotated.Encrypt("jira.token", plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !rotated.IsCurrent(reencrypted) {
+		t.Error("IsCurrent() = false for a secret under the current key")
+	}
+}
+
+func TestDecryptUnknownKey(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	ciphertext, err := k.Encrypt("name", []byte("value"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	other, err := NewKeyring(testKey(2))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if _, err := other.Decrypt("name", ciphertext); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	ciphertext, err := k.Encrypt("postmark.token", []byte("value"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// Flip a bit in the sealed data, keeping the version and key ID
+	i := strings.LastIndex(ciphertext, ":")
+	data, err := base64.StdEncoding.DecodeString(ciphertext[i+1:])
+	if err != nil {
+		t.Fatalf("failed to decode ciphertext: %v", err)
+	}
+	data[len(data)-1] ^= 1
+	tampered := ciphertext[:i+1] + base64.StdEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name       string
+		secret     string
+		ciphertext string
+	}{
+		{"tampered ciphertext", "postmark.token", tampered},
+		{"swapped name", "sendgrid.api_key", ciphertext},
+		{"truncated", "postmark.token", ciphertext[:i+1] + base64.StdEncoding.EncodeToString(data[:4])},
+		{"unknown version", "postmark.token", "v2" + ciphertext[2:]},
+		{"not base64", "postmark.token", ciphertext[:i+1] + "!!!"},
+		{"no format", "postmark.token", "plaintext"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := k.Decrypt(tt.secret, tt.ciphertext)
+			if err == nil {
+				t.Errorf("Decrypt() = %q, want an error", plaintext)
+			}
+			if errors.Is(err, ErrUnknownKey) {
+				t.Errorf("Decrypt() error = %v, want a decryption error", err)
+			}
+		})
+	}
+}
+
+func TestIsCurrent(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	ciphertext, err := k.Encrypt("name", []byte("value"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	if !k.IsCurrent(ciphertext) {
+		t.Error("IsCurrent() = false for a secret under the current key")
+	}
+	if k.IsCurrent("plaintext") {
+		t.Error("IsCurrent() = true for an unparsable secret")
+	}
+}
+
+func TestNewKeyringKeySize(t *testing.T) {
+	if _, err := NewKeyring(make([]byte, 16)); err == nil {
+		t.Error("NewKeyring() should reject a short current key")
+	}
+	if _, err := NewKeyring(testKey(1), make([]byte, 16)); err == nil {
+		t.Error("NewKeyring() should reject a short previous key")
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("GenerateKey() = %q, not base64: %v", encoded, err)
+	}
+	if _, err := NewKeyring(key); err != nil {
+		t.Errorf("NewKeyring() with a generated key error = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
@@ -23,14 +24,14 @@ func NewAdminService(database *db.DB, cfg *config.Config) *AdminService {
 }
 
 // Add creates a new admin user
-func (s *AdminService) Add(email, createdBy string) (*db.Admin, error) {
+func (s *AdminService) Add(ctx context.Context, email, createdBy string) (*db.Admin, error) {
 	// Check if already exists
-	existing, err := s.db.GetAdminByEmail(email)
+	existing, err := s.db.GetAdminByEmail(ctx, email)
 	if err == nil && existing != nil {
 		return nil, fmt.Errorf("admin '%s' already exists", email)
 	}
 
-	admin, err := s.db.CreateAdmin(email, createdBy)
+	admin, err := s.db.CreateAdmin(ctx, email, createdBy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create admin: %w", err)
 	}
@@ -40,13 +41,13 @@ func (s *AdminService) Add(email, createdBy string) (*db.Admin, error) {
 }
 
 // Remove deletes an admin user by ID
-func (s *AdminService) Remove(id int64) error {
-	admin, err := s.db.GetAdmin(id)
+func (s *AdminService) Remove(ctx context.Context, id int64) error {
+	admin, err := s.db.GetAdmin(ctx, id)
 	if err != nil {
 		return fmt.Errorf("admin not found: %w", err)
 	}
 
-	if err := s.db.DeleteAdmin(id); err != nil {
+	if err := s.db.DeleteAdmin(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete admin: %w", err)
 	}
 
@@ -54,19 +55,19 @@ func (s *AdminService) Remove(id int64) error {
 	return nil
 }
 
-// IsAdmin checks if an email is an admin
-func (s *AdminService) IsAdmin(email string) (bool, error) {
-	return s.db.IsAdmin(email)
+// IsAdmin checks if an email is an admin of the context's workspace
+func (s *AdminService) IsAdmin(ctx context.Context, email string) (bool, error) {
+	return s.db.IsAdmin(ctx, email)
 }
 
 // List returns all admin users
-func (s *AdminService) List() ([]*db.Admin, error) {
-	return s.db.ListAdmins()
+func (s *AdminService) List(ctx context.Context) ([]*db.Admin, error) {
+	return s.db.ListAdmins(ctx)
 }
 
 // SeedIfNeeded creates the seed admin if no admins exist
-func (s *AdminService) SeedIfNeeded() error {
-	count, err := s.db.AdminCount()
+func (s *AdminService) SeedIfNeeded(ctx context.Context) error {
+	count, err := s.db.AdminCount(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to count admins: %w", err)
 	}
@@ -81,7 +82,7 @@ func (s *AdminService) SeedIfNeeded() error {
 		return nil
 	}
 
-	admin, err := s.db.CreateAdmin(seedEmail, "system")
+	admin, err := s.db.CreateAdmin(ctx, seedEmail, "system")
 	if err != nil {
 		return fmt.Errorf("failed to create seed admin: %w", err)
 	}
@@ -91,19 +92,19 @@ func (s *AdminService) SeedIfNeeded() error {
 }
 
 // EnsureDevAdmin ensures the dev user is an admin (for dev mode)
-func (s *AdminService) EnsureDevAdmin() error {
+func (s *AdminService) EnsureDevAdmin(ctx context.Context) error {
 	if !s.cfg.Web.DevMode {
 		return nil
 	}
 
 	devUser := s.cfg.GetDevUser()
-	isAdmin, err := s.db.IsAdmin(devUser)
+	isAdmin, err := s.db.IsAdmin(ctx, devUser)
 	if err != nil {
 		return fmt.Errorf("failed to check dev admin: %w", err)
 	}
 
 	if !isAdmin {
-		_, err := s.db.CreateAdmin(devUser, "dev_mode")
+		_, err := s.db.CreateAdmin(ctx, devUser, "dev_mode")
 		if err != nil {
 			return fmt.Errorf("failed to create dev admin: %w", err)
 		}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
+	"github.com/perbu/activity/internal/telemetry"
+)
+
+// AnalyzeResult contains the result of an incremental analysis
+type AnalyzeResult struct {
+	RepoName   string
+	FromSHA    string   // Last run SHA before this run (empty on the first run)
+	ToSHA      string   // Last run SHA after this run
+	NewCommits int      // Commits analyzed in this run
+	Weeks      []string // ISO weeks whose reports were created or appended to
+}
+
+// weekCommits groups commits belonging to one ISO week
+type weekCommits struct {
+	year, week int
+	commits    []git.Commit
+}
+
+// AnalyzeNew analyzes only the commits made since the repository's last run and
+// appends the summary to the matching weekly report, creating it if needed.
+// On the first run it starts at the beginning of the current week. Commits that
+// are already part of a report are skipped, so it can be mixed freely with full
+// weekly generation. Reports and the last run SHA are saved in one transaction.
+func (s *ReportService) AnalyzeNew(ctx context.Context, repoName string) (_ *AnalyzeResult, err error) {
+	ctx, span := startSpan(ctx, "ReportService.AnalyzeNew", repoName)
+	defer func() { telemetry.End(span, err) }()
+
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("repository not found: %s", repoName)
+	}
+
+	if err := s.fetchBranches(ctx, repo); err != nil {
+		slog.Warn("Failed to fetch branches", "error", err)
+	}
+
+	repoPath := s.repoPath(repo.Name)
+	headSHA, err := git.GetCurrentSHA(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current SHA: %w", err)
+	}
+
+	result := &AnalyzeResult{RepoName: repoName, FromSHA: repo.LastRunSHA.String, ToSHA: headSHA}
+	if repo.LastRunSHA.String == headSHA {
+		return result, nil
+	}
+
+	var commits []git.Commit
+	logSpan := gitSpan(ctx, "log", repo.Name)
+	if repo.LastRunSHA.String != "" {
+		commits, err = git.GetCommitRangeWithOptions(repoPath, repo.LastRunSHA.String, headSHA, s.logOptions(repo.Name))
+	} else {
+		year, week := git.CurrentISOWeek()
+		commits, err = git.GetCommitsForWeekWithOptions(repoPath, year, week, s.logOptions(repo.Name))
+	}
+	telemetry.End(logSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new commits: %w", err)
+	}
+
+	var llmAnalyzer *analyzer.Analyzer
+	var reports []*db.WeeklyReport
+	var runs []*db.ActivityRun
+	defer func() {
+		if err != nil {
+			for _, run := range runs {
+				llmAnalyzer.Discard(ctx, run)
+			}
+		}
+	}()
+	for _, wc := range groupCommitsByWeek(commits) {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("analysis cancelled: %w", err)
+		}
+
+		report, err := s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, wc.year, wc.week)
+		if err != nil {
+			return nil, err
+		}
+		var metadata ReportMetadata
+		if report != nil && report.Metadata.Valid {
+			if err := json.Unmarshal([]byte(report.Metadata.String), &metadata); err != nil {
+				slog.Warn("Failed to parse report metadata", "report", report.ID, "error", err)
+			}
+		}
+
+		// Skip commits already covered by the report (e.g. by full weekly generation)
+		pending := slices.DeleteFunc(wc.commits, func(c git.Commit) bool {
+			return slices.Contains(metadata.CommitSHAs, c.SHA)
+		})
+		kept, automated := s.filterCommits(ctx, repo, pending)
+		if len(kept) == 0 {
+			continue
+		}
+
+		if llmAnalyzer == nil {
+			llmClient, err := llm.NewClient(ctx, s.cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
+			}
+			defer llmClient.Close()
+			llmAnalyzer = analyzer.New(llmClient, s.db, s.cfg)
+		}
+
+		weekStr := git.FormatISOWeek(wc.year, wc.week)
+		slog.Info("Analyzing new commits", "repo", repo.Name, "week", weekStr, "commits", len(kept))
+
+		report, run, err := s.appendToWeeklyReport(ctx, llmAnalyzer, repo, wc.year, wc.week, report, metadata, kept, automated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %s: %w", weekStr, err)
+		}
+		reports = append(reports, report)
+		runs = append(runs, run)
+		result.NewCommits += len(kept)
+		result.Weeks = append(result.Weeks, weekStr)
+	}
+
+	if err := s.db.SaveIncrementalReports(ctx, repo.ID, runs, reports, repo.LastRunSHA, headSHA); err != nil {
+		return nil, err
+	}
+	s.indexReports(ctx, reports...)
+
+	return result, nil
+}
+
+// AnalyzeAllNew runs incremental analysis for all active repositories
+func (s *ReportService) AnalyzeAllNew(ctx context.Context) ([]*AnalyzeResult, error) {
+	activeOnly := true
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	var results []*AnalyzeResult
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("analysis cancelled: %w", err)
+		}
+		result, err := s.AnalyzeNew(ctx, repo.Name)
+		if err != nil {
+			slog.Error("Failed to analyze new commits", "repo", repo.Name, "error", err)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// appendToWeeklyReport analyzes new commits and returns the weekly report with
+// the summary appended as a dated update section, along with the run it came
+// from. If report is nil, a new report is returned. Neither is saved here.
+func (s *ReportService) appendToWeeklyReport(ctx context.Context, llmAnalyzer *analyzer.Analyzer, repo *db.Repository,
+	year, week int, report *db.WeeklyReport, metadata ReportMetadata, commits, automated []git.Commit) (*db.WeeklyReport, *db.ActivityRun, error) {
+
+	var fromSHA string
+	toSHA := commits[0].SHA
+	if len(commits) > 1 {
+		fromSHA = commits[len(commits)-1].SHA
+	}
+
+	// Give the model the report so far, or last week's report for a new one
+	var previousSummary string
+	if report != nil && report.Summary.Valid {
+		previousSummary = "Earlier this week (already reported; summarize only the new commits below):\n\n" + report.Summary.String
+	} else {
+		prevYear, prevWeek := previousWeek(year, week)
+		prevReport, err := s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, prevYear, prevWeek)
+		if err == nil && prevReport != nil && prevReport.Summary.Valid {
+			previousSummary = prevReport.Summary.String
+		}
+	}
+
+	run, err := llmAnalyzer.Analyze(ctx, repo, fromSHA, toSHA, commits, nil, previousSummary)
+	if err != nil {
+		return nil, nil, fmt.Errorf("analysis failed: %w", err)
+	}
+	section := run.Summary.String + analyzer.AutomatedChangesFootnote(automated)
+
+	if report == nil {
+		weekStart, weekEnd := git.ISOWeekBounds(year, week)
+		report = &db.WeeklyReport{
+			RepoID:    repo.ID,
+			Year:      year,
+			Week:      week,
+			WeekStart: weekStart,
+			WeekEnd:   weekEnd,
+			Summary:   sql.NullString{String: section, Valid: true},
+		}
+	} else {
+		report.Summary = sql.NullString{
+			String: fmt.Sprintf("%s\n\n### Update %s\n\n%s", report.Summary.String, time.Now().Format("Mon Jan 2 15:04"), section),
+			Valid:  true,
+		}
+	}
+
+	metadata.addCommits(commits)
+	metadata.addAutomated(automated)
+	metadata.addPullRequestLines(s.repoPath(repo.Name), commits)
+	churn, err := git.GetChurnForWeek(s.repoPath(repo.Name), year, week, s.logOptions(repo.Name))
+	if err != nil {
+		slog.Warn("Failed to compute churn", "repo", repo.Name, "week", git.FormatISOWeek(year, week), "error", err)
+	} else {
+		metadata.Additions = churn.Additions
+		metadata.Deletions = churn.Deletions
+		metadata.FilesChanged = churn.FilesChanged
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	report.CommitCount += len(commits)
+	report.Metadata = sql.NullString{String: string(metadataJSON), Valid: true}
+	report.AgentMode = run.AgentMode
+	report.ToolUsageStats = run.ToolUsageStats
+	report.SourceRunID = sql.NullInt64{Int64: run.ID, Valid: true}
+	// The summary changed, so it needs approving again
+	report.ReviewState = s.reviewState()
+	report.ApprovedBy = sql.NullString{}
+	report.ApprovedAt = sql.NullTime{}
+	return report, run, nil
+}
+
+// addCommits merges newly analyzed commits into report metadata, crediting
+// co-authors alongside authors
+func (m *ReportMetadata) addCommits(commits []git.Commit) {
+	if m.AuthorCounts == nil {
+		m.AuthorCounts = make(map[string]int)
+	}
+	for _, c := range commits {
+		if !slices.Contains(m.Authors, c.Author) {
+			m.Authors = append(m.Authors, c.Author)
+		}
+		m.AuthorCounts[c.Author]++
+		m.CommitSHAs = append(m.CommitSHAs, c.SHA)
+		if m.DailyCommits == nil {
+			m.DailyCommits = make(map[string]int)
+		}
+		m.DailyCommits[c.Date.Format("2006-01-02")]++
+
+		for _, name := range c.CoAuthorNames() {
+			if !slices.Contains(m.Authors, name) {
+				m.Authors = append(m.Authors, name)
+			}
+			if m.CoAuthorCounts == nil {
+				m.CoAuthorCounts = make(map[string]int)
+			}
+			m.CoAuthorCounts[name]++
+		}
+	}
+
+	merges := git.CountMerges(commits)
+	m.PullRequests += merges.PullRequests
+	m.MergeCommits += merges.MergeCommits
+	m.DirectCommits += merges.DirectCommits
+}
+
+// addPullRequestLines adds the size of the pull requests merged in commits.
+// Failures are logged, leaving the size out.
+func (m *ReportMetadata) addPullRequestLines(repoPath string, commits []git.Commit) {
+	lines, err := git.GetPullRequestLines(repoPath, commits)
+	if err != nil {
+		slog.Warn("Failed to compute pull request sizes", "error", err)
+		return
+	}
+	m.PullRequestLines += lines
+}
+
+// ResolveAuthors merges the authors recorded in the metadata under their
+// canonical names. Reports store the names as they were when generated, so
+// applying the current aliases when reading keeps older reports consistent.
+func (m *ReportMetadata) ResolveAuthors(authorMap git.AuthorMap) {
+	m.Authors = authorMap.ResolveNames(m.Authors)
+	m.AuthorCounts = authorMap.ResolveCounts(m.AuthorCounts)
+	m.CoAuthorCounts = authorMap.ResolveCounts(m.CoAuthorCounts)
+	m.AutomatedAuthorCounts = authorMap.ResolveCounts(m.AutomatedAuthorCounts)
+}
+
+// addAutomated counts commits by ignored authors, per author
+func (m *ReportMetadata) addAutomated(automated []git.Commit) {
+	if len(automated) == 0 {
+		return
+	}
+	if m.AutomatedAuthorCounts == nil {
+		m.AutomatedAuthorCounts = make(map[string]int)
+	}
+	for _, c := range automated {
+		m.AutomatedAuthorCounts[c.Author]++
+	}
+	m.AutomatedCommits += len(automated)
+}
+
+// groupCommitsByWeek splits commits (newest first) by the ISO week of their
+// date, returning the weeks oldest first with each week's commits newest first
+func groupCommitsByWeek(commits []git.Commit) []weekCommits {
+	var groups []weekCommits
+	index := make(map[[2]int]int)
+	for _, c := range commits {
+		year, week := c.Date.UTC().ISOWeek()
+		key := [2]int{year, week}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, weekCommits{year: year, week: week})
+		}
+		groups[i].commits = append(groups[i].commits, c)
+	}
+	slices.SortFunc(groups, func(a, b weekCommits) int {
+		if a.year != b.year {
+			return a.year - b.year
+		}
+		return a.week - b.week
+	})
+	return groups
+}
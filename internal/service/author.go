@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// AuthorService handles author identity aliases
+type AuthorService struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewAuthorService creates a new AuthorService
+func NewAuthorService(database *db.DB, cfg *config.Config) *AuthorService {
+	return &AuthorService{
+		db:  database,
+		cfg: cfg,
+	}
+}
+
+// AddAlias maps an alternate author name or email to a canonical name
+func (s *AuthorService) AddAlias(ctx context.Context, alias, canonicalName, createdBy string) (*db.AuthorAlias, error) {
+	alias = strings.TrimSpace(alias)
+	canonicalName = strings.TrimSpace(canonicalName)
+	if alias == "" || canonicalName == "" {
+		return nil, fmt.Errorf("alias and canonical name are required")
+	}
+	if strings.EqualFold(alias, canonicalName) {
+		return nil, fmt.Errorf("alias '%s' is the same as the canonical name", alias)
+	}
+
+	a, err := s.db.CreateAuthorAlias(ctx, alias, canonicalName, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add alias: %w", err)
+	}
+
+	slog.Info("Author alias added", "alias", a.Alias, "canonical", a.CanonicalName, "created_by", createdBy)
+	return a, nil
+}
+
+// RemoveAlias deletes an author alias by ID
+func (s *AuthorService) RemoveAlias(ctx context.Context, id int64) error {
+	if err := s.db.DeleteAuthorAlias(ctx, id); err != nil {
+		return fmt.Errorf("failed to remove alias: %w", err)
+	}
+
+	slog.Info("Author alias removed", "id", id)
+	return nil
+}
+
+// ListAliases returns all author aliases
+func (s *AuthorService) ListAliases(ctx context.Context) ([]*db.AuthorAlias, error) {
+	return s.db.ListAuthorAliases(ctx)
+}
+
+// AuthorMap returns the alias table as a git.AuthorMap for resolving commits
+func (s *AuthorService) AuthorMap(ctx context.Context) (git.AuthorMap, error) {
+	return loadAuthorMap(ctx, s.db)
+}
+
+// loadAuthorMap builds a git.AuthorMap from the author_aliases table
+func loadAuthorMap(ctx context.Context, database *db.DB) (git.AuthorMap, error) {
+	aliases, err := database.GetAuthorAliasMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load author aliases: %w", err)
+	}
+	return git.NewAuthorMap(aliases), nil
+}
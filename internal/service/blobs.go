@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/perbu/activity/internal/blob"
+	"github.com/perbu/activity/internal/db"
+)
+
+// deleteBlobs deletes the offloaded raw data of runs whose rows have been
+// deleted or cleared. Failures are only logged: an orphaned blob wastes space
+// but is never read.
+func deleteBlobs(ctx context.Context, store blob.Store, blobs []*db.RawDataBlob) {
+	if len(blobs) == 0 {
+		return
+	}
+	if store == nil {
+		slog.Warn("Raw run data is in a blob store that is no longer configured; delete it manually", "blobs", len(blobs))
+		return
+	}
+	for _, b := range blobs {
+		if err := store.Delete(ctx, b.Ref); err != nil {
+			slog.Warn("Failed to delete raw run data blob", "run", b.RunID, "ref", b.Ref, "error", err)
+		}
+	}
+}
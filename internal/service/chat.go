@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
+)
+
+// Retrieval limits for chat context
+const (
+	chatSearchLimit     = 6  // Reports retrieved by semantic similarity
+	chatRecentLimit     = 2  // Most recent reports, always included
+	chatFallbackLimit   = 8  // Most recent reports when semantic search is unavailable
+	chatMaxCommits      = 40 // Commit subjects listed per retrieved week
+	chatMaxHistoryTurns = 6  // Previous messages included in the prompt
+)
+
+// chatMaxMessageBytes bounds each previous message included in the prompt
+const chatMaxMessageBytes = 2000
+
+// ChatService answers questions about a repository's history using stored
+// weekly reports and commit metadata as retrieval context for the LLM
+type ChatService struct {
+	db     *db.DB
+	cfg    *config.Config
+	search *SearchService
+}
+
+// NewChatService creates a new ChatService
+func NewChatService(database *db.DB, cfg *config.Config, search *SearchService) *ChatService {
+	return &ChatService{
+		db:     database,
+		cfg:    cfg,
+		search: search,
+	}
+}
+
+// ChatMessage is a single turn in a conversation
+type ChatMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// ChatSource is a weekly report used to answer a question
+type ChatSource struct {
+	ReportID int64
+	Week     string
+	Score    float64 // Similarity to the question, 0 if not retrieved by search
+}
+
+// ChatAnswer contains the answer to a question and the reports it is based on
+type ChatAnswer struct {
+	Answer  string
+	Sources []ChatSource
+}
+
+// Ask answers a question about a repository. History holds the previous
+// turns of the conversation, oldest first, so follow-up questions work.
+func (s *ChatService) Ask(ctx context.Context, repoName, question string, history []ChatMessage) (*ChatAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("question is required")
+	}
+
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("repository not found: %s", repoName)
+	}
+
+	sources, reports, err := s.retrieve(ctx, repo, question)
+	if err != nil {
+		return nil, err
+	}
+
+	var description string
+	if repo.Description.Valid && repo.Description.String != "" {
+		description = "About the project: " + repo.Description.String + "\n"
+	}
+	if repo.ContextNotes.Valid && repo.ContextNotes.String != "" {
+		description += "Notes from the team on their vocabulary:\n" + repo.ContextNotes.String + "\n"
+	}
+	prompt := fmt.Sprintf(config.DefaultChatPrompt, repo.Name, description,
+		s.buildContext(ctx, repo, reports), formatHistory(history), question)
+
+	llmClient, err := llm.NewClient(ctx, s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	defer llmClient.Close()
+
+	answer, err := llmClient.GenerateText(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatAnswer{Answer: strings.TrimSpace(answer), Sources: sources}, nil
+}
+
+// AskAgent answers a question by letting an agent investigate the
+// repository's local clone (commit search, diffs, commit messages, author
+// stats and file contents) within the configured cost limits. Agent progress
+// such as tool calls is reported to onProgress if it is non-nil.
+func (s *ChatService) AskAgent(ctx context.Context, repoName, question string, onProgress analyzer.ProgressFunc) (string, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("question is required")
+	}
+
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
+	if err != nil {
+		return "", fmt.Errorf("repository not found: %s", repoName)
+	}
+
+	llmClient, err := llm.NewClient(ctx, s.cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	defer llmClient.Close()
+
+	if onProgress != nil {
+		ctx = analyzer.WithProgress(ctx, onProgress)
+	}
+	answer, _, err := analyzer.New(llmClient, s.db, s.cfg).Ask(ctx, repo, question)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// retrieve selects the reports relevant to a question: the best semantic
+// matches plus the most recent weeks, or only the most recent weeks if
+// search is disabled or fails. Reports are returned oldest first.
+func (s *ChatService) retrieve(ctx context.Context, repo *db.Repository, question string) ([]ChatSource, []*db.WeeklyReport, error) {
+	recent, err := s.db.ListWeeklyReportsByRepo(ctx, repo.ID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores := make(map[int64]float64)
+	var selected []*db.WeeklyReport
+	add := func(r *db.WeeklyReport, score float64) {
+		if _, ok := scores[r.ID]; ok {
+			return
+		}
+		scores[r.ID] = score
+		selected = append(selected, r)
+	}
+
+	recentLimit := chatFallbackLimit
+	if s.search.Enabled() {
+		hits, err := s.search.Search(ctx, question, repo.ID, chatSearchLimit)
+		if err != nil {
+			slog.Warn("Chat retrieval search failed, using recent reports", "repo", repo.Name, "error", err)
+		} else if len(hits) > 0 {
+			for _, hit := range hits {
+				add(hit.Report, hit.Score)
+			}
+			recentLimit = chatRecentLimit
+		}
+	}
+	for _, r := range recent[:min(recentLimit, len(recent))] {
+		add(r, 0)
+	}
+
+	slices.SortFunc(selected, func(a, b *db.WeeklyReport) int {
+		return a.WeekStart.Compare(b.WeekStart)
+	})
+	sources := make([]ChatSource, 0, len(selected))
+	for _, r := range selected {
+		sources = append(sources, ChatSource{
+			ReportID: r.ID,
+			Week:     git.FormatISOWeek(r.Year, r.Week),
+			Score:    scores[r.ID],
+		})
+	}
+	return sources, selected, nil
+}
+
+// buildContext formats the retrieved reports with their commit metadata
+// (authors, churn and commit subjects from the local clone) for the prompt
+func (s *ChatService) buildContext(ctx context.Context, repo *db.Repository, reports []*db.WeeklyReport) string {
+	if len(reports) == 0 {
+		return "No weekly reports have been generated for this repository yet."
+	}
+
+	authorMap, err := loadAuthorMap(ctx, s.db)
+	if err != nil {
+		slog.Warn("Failed to load author aliases", "error", err)
+	}
+
+	repoPath := db.RepoLocalPath(s.cfg.DataDir, repo.Name)
+	repoCfg := s.cfg.GetRepoConfig(repo.Name)
+	opts := git.LogOptions{FirstParent: repoCfg.FirstParent, NoMerges: repoCfg.NoMerges}
+
+	var b strings.Builder
+	for i, r := range reports {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## Week %s (%s to %s), %d commits\n",
+			git.FormatISOWeek(r.Year, r.Week), r.WeekStart.Format("2006-01-02"), r.WeekEnd.Format("2006-01-02"), r.CommitCount)
+
+		var metadata ReportMetadata
+		if r.Metadata.Valid && json.Unmarshal([]byte(r.Metadata.String), &metadata) == nil {
+			metadata.ResolveAuthors(authorMap)
+			if len(metadata.Authors) > 0 {
+				fmt.Fprintf(&b, "Authors: %s\n", strings.Join(metadata.Authors, ", "))
+			}
+			if metadata.FilesChanged > 0 {
+				fmt.Fprintf(&b, "Churn: +%d/-%d lines in %d files\n", metadata.Additions, metadata.Deletions, metadata.FilesChanged)
+			}
+			if metadata.PullRequests > 0 || metadata.MergeCommits > 0 {
+				fmt.Fprintf(&b, "Merged pull requests: %d (average %d lines), merge commits: %d, direct commits: %d\n",
+					metadata.PullRequests, metadata.AvgPullRequestSize(), metadata.MergeCommits, metadata.DirectCommits)
+			}
+		}
+
+		commits, err := git.GetCommitsForWeekWithOptions(repoPath, r.Year, r.Week, opts)
+		if err != nil {
+			slog.Debug("Failed to list commits for chat context", "repo", repo.Name, "week", git.FormatISOWeek(r.Year, r.Week), "error", err)
+		} else if len(commits) > 0 {
+			authorMap.ResolveCommits(commits)
+			b.WriteString("Commits:\n")
+			for _, c := range commits[:min(chatMaxCommits, len(commits))] {
+				author := c.Author
+				if len(c.CoAuthors) > 0 {
+					author += " with " + strings.Join(c.CoAuthorNames(), ", ")
+				}
+				fmt.Fprintf(&b, "- %s %s (%s, %s)\n", c.SHA[:min(8, len(c.SHA))], c.Message, author, c.Date.Format("2006-01-02"))
+			}
+			if len(commits) > chatMaxCommits {
+				fmt.Fprintf(&b, "- ... and %d more\n", len(commits)-chatMaxCommits)
+			}
+		}
+
+		if r.Summary.Valid {
+			fmt.Fprintf(&b, "Summary:\n%s\n", r.Summary.String)
+		}
+	}
+	return b.String()
+}
+
+// formatHistory formats the most recent conversation turns for the prompt,
+// with long messages truncated
+func formatHistory(history []ChatMessage) string {
+	if len(history) > chatMaxHistoryTurns {
+		history = history[len(history)-chatMaxHistoryTurns:]
+	}
+	if len(history) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nConversation so far:\n")
+	for _, m := range history {
+		role := "User"
+		if m.Role == "assistant" {
+			role = "Assistant"
+		}
+		content := strings.TrimSpace(m.Content)
+		if len(content) > chatMaxMessageBytes {
+			content = content[:chatMaxMessageBytes] + "... [truncated]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", role, content)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/github"
+)
+
+// ciHealth summarizes the GitHub Actions runs on a repo's branch between
+// since and until. It returns nil if the ci_health setting is off, the repo
+// is not on GitHub, no GitHub App is configured or the branch had no runs.
+// Failures, such as the App not being installed on the repo, are logged.
+func (s *ReportService) ciHealth(ctx context.Context, repo *db.Repository, since, until time.Time) *github.CIHealth {
+	if !s.cfg.Current().GitHub.CIHealth || s.tokenProvider == nil || !github.IsGitHubURL(repo.URL) {
+		return nil
+	}
+	owner, name, err := github.ParseRepoURL(repo.URL)
+	if err != nil {
+		slog.Debug("Skipping CI health", "repo", repo.Name, "error", err)
+		return nil
+	}
+
+	runs, err := github.NewClient(s.tokenProvider).ListWorkflowRuns(ctx, owner, name, repo.Branch, since, until)
+	if err != nil {
+		slog.Warn("Failed to fetch workflow runs", "repo", repo.Name, "error", err)
+		return nil
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	health := github.SummarizeRuns(runs)
+	return &health
+}
+
+// ciHealthSection formats CI health as a markdown section for the end of a
+// report summary
+func ciHealthSection(h *github.CIHealth, branch string) string {
+	if h == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## CI health\n\n")
+	fmt.Fprintf(&sb, "%d workflow %s on %s: %d passed, %d failed",
+		h.Runs, plural(h.Runs, "run", "runs"), branch, h.Succeeded, h.Failed)
+	if rate := h.SuccessRate(); rate >= 0 {
+		fmt.Fprintf(&sb, " (%d%% success rate)", int(math.Round(rate*100)))
+	}
+	sb.WriteString(".\n")
+
+	if flaky := h.FlakyWorkflows(); len(flaky) > 0 {
+		parts := make([]string, 0, len(flaky))
+		for _, w := range flaky {
+			parts = append(parts, fmt.Sprintf("**%s** (%d %s)", w.Name, w.Flaky, plural(w.Flaky, "commit", "commits")))
+		}
+		fmt.Fprintf(&sb, "\nFlaky workflows, which failed and passed on the same commit: %s.\n", strings.Join(parts, ", "))
+	}
+	return sb.String()
+}
+
+// plural returns one if n is 1 and other otherwise
+func plural(n int, one, other string) string {
+	if n == 1 {
+		return one
+	}
+	return other
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
+)
+
+// Comparison is a weekly report side by side with the report of the week
+// before it, with a description of what changed
+type Comparison struct {
+	Repo     *db.Repository
+	Current  *db.WeeklyReport
+	Previous *db.WeeklyReport // nil if there is no report for the previous week
+	Delta    string           // What changed since last week, empty without a previous report
+}
+
+// Compare compares a report with the previous week's report of the same
+// repository. The delta paragraph is generated by the LLM on every call.
+func (s *ReportService) Compare(ctx context.Context, reportID int64) (*Comparison, error) {
+	current, err := s.db.GetWeeklyReport(ctx, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("report not found: %d", reportID)
+	}
+	return s.compare(ctx, current)
+}
+
+// CompareWeek compares a repository's report for an ISO week ("2026-W02")
+// with the previous week's. An empty week compares the latest report.
+func (s *ReportService) CompareWeek(ctx context.Context, repoName, weekStr string) (*Comparison, error) {
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("repository not found: %s", repoName)
+	}
+
+	var current *db.WeeklyReport
+	if weekStr == "" {
+		current, err = s.db.GetLatestWeeklyReport(ctx, repo.ID)
+	} else {
+		year, week, perr := git.ParseISOWeek(weekStr)
+		if perr != nil {
+			return nil, perr
+		}
+		current, err = s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, year, week)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no report found for %s", repoName)
+	}
+	return s.compare(ctx, current)
+}
+
+// compare looks up the previous week's report and generates the delta
+func (s *ReportService) compare(ctx context.Context, current *db.WeeklyReport) (*Comparison, error) {
+	repo, err := s.db.GetRepository(ctx, current.RepoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	comparison := &Comparison{Repo: repo, Current: current}
+	prevYear, prevWeek := previousWeek(current.Year, current.Week)
+	previous, err := s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, prevYear, prevWeek)
+	if err != nil || previous == nil {
+		return comparison, nil
+	}
+	comparison.Previous = previous
+
+	llmClient, err := llm.NewClient(ctx, s.cfg)
+	if err != nil {
+		return comparison, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	defer llmClient.Close()
+
+	comparison.Delta, err = analyzer.New(llmClient, s.db, s.cfg).CompareWeeks(ctx, repo, previous, current)
+	if err != nil {
+		return comparison, err
+	}
+	return comparison, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// demoWeeks is how many weeks of reports each demo repository gets, up to
+// and including last week
+const demoWeeks = 26
+
+// demoRepo is a sample repository seeded in demo mode
+type demoRepo struct {
+	name        string
+	description string
+	authors     []string
+	areas       []string // Parts of the project the summaries talk about
+	commits     int      // Average commits per week
+	quietWeeks  int      // Weeks without commits at the end, so it shows as stale
+}
+
+// demoRepos are the repositories demo mode seeds. Their URLs point nowhere,
+// so they are never cloned.
+var demoRepos = []demoRepo{
+	{
+		name:        "demo-api",
+		description: "The HTTP API behind the storefront: catalog, carts, checkout and the order pipeline.",
+		authors:     []string{"Ada Lindqvist", "Bjørn Hauge", "Chiara Rossi", "Dev Patel"},
+		areas:       []string{"checkout", "order pipeline", "catalog search", "rate limiting", "payment webhooks", "database migrations"},
+		commits:     24,
+	},
+	{
+		name:        "demo-web",
+		description: "The storefront web app, server-rendered with a sprinkle of JavaScript.",
+		authors:     []string{"Emma Berg", "Chiara Rossi", "Farid Haddad"},
+		areas:       []string{"product pages", "the cart drawer", "accessibility", "image loading", "the design system", "translations"},
+		commits:     15,
+	},
+	{
+		name:        "demo-infra",
+		description: "Terraform and deployment scripts for the storefront's cloud setup.",
+		authors:     []string{"Bjørn Hauge", "Greta Nilsen"},
+		areas:       []string{"the staging cluster", "backups", "TLS certificates", "the CI runners", "alerting"},
+		commits:     5,
+		quietWeeks:  10,
+	},
+}
+
+// demoChanges are the kinds of work the demo summaries describe
+var demoChanges = []string{
+	"reworked %s to cut response times",
+	"fixed a long-standing bug in %s",
+	"added tests around %s",
+	"cleaned up error handling in %s",
+	"started a redesign of %s",
+	"documented %s for new team members",
+	"removed dead code from %s",
+	"upgraded the dependencies of %s",
+}
+
+// DemoResult reports what Seed added
+type DemoResult struct {
+	Repos   int
+	Reports int
+}
+
+// DemoService seeds sample data for trying out the web UI
+type DemoService struct {
+	db *db.DB
+}
+
+// NewDemoService creates a new DemoService
+func NewDemoService(database *db.DB) *DemoService {
+	return &DemoService{db: database}
+}
+
+// Seed adds the demo repositories with half a year of weekly reports, made
+// up without cloning anything or calling the LLM. The data is the same on
+// every run, relative to the current week. Repositories that already exist
+// are left alone, so seeding again adds nothing.
+func (s *DemoService) Seed(ctx context.Context) (*DemoResult, error) {
+	result := &DemoResult{}
+	for i, demo := range demoRepos {
+		if _, err := s.db.GetRepositoryByName(db.WithWorkspace(ctx, 0), demo.name); err == nil {
+			continue
+		}
+		rng := rand.New(rand.NewPCG(uint64(i), demoWeeks))
+		err := s.db.WithTx(ctx, func(tx *db.DB) error {
+			repo, err := tx.CreateRepository(ctx, demo.name, "https://example.com/demo/"+demo.name+".git", "main",
+				false, sql.NullString{String: demo.description, Valid: true})
+			if err != nil {
+				return err
+			}
+			last, n, err := seedDemoReports(ctx, tx, repo, demo, rng)
+			if err != nil {
+				return err
+			}
+			result.Reports += n
+			if last.IsZero() {
+				return nil
+			}
+			return tx.SetRepositoryLastCommit(ctx, repo.ID, last)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed %s: %w", demo.name, err)
+		}
+		result.Repos++
+		slog.Info("Seeded demo repository", "name", demo.name)
+	}
+	return result, nil
+}
+
+// seedDemoReports creates the weekly reports of a demo repository and
+// returns the time of its last made-up commit and the number of reports
+func seedDemoReports(ctx context.Context, tx *db.DB, repo *db.Repository, demo demoRepo, rng *rand.Rand) (time.Time, int, error) {
+	var last time.Time
+	reports := 0
+	lastWeek := time.Now().AddDate(0, 0, -7)
+	for w := demoWeeks - 1; w >= demo.quietWeeks; w-- {
+		year, week := lastWeek.AddDate(0, 0, -7*w).ISOWeek()
+		start, end := git.ISOWeekBounds(year, week)
+
+		// Busy and quiet weeks, with the odd holiday without commits
+		commits := demo.commits/2 + rng.IntN(demo.commits+1)
+		if rng.IntN(10) == 0 {
+			commits = 0
+		}
+		if commits == 0 {
+			continue
+		}
+
+		metadata := ReportMetadata{
+			AuthorCounts: make(map[string]int),
+			DailyCommits: make(map[string]int),
+		}
+		for range commits {
+			author := demo.authors[rng.IntN(len(demo.authors))]
+			metadata.AuthorCounts[author]++
+			// Mostly weekdays
+			day := start.AddDate(0, 0, rng.IntN(5))
+			if rng.IntN(8) == 0 {
+				day = start.AddDate(0, 0, 5+rng.IntN(2))
+			}
+			metadata.DailyCommits[day.Format("2006-01-02")]++
+			if at := day.Add(time.Duration(9+rng.IntN(9)) * time.Hour); at.After(last) {
+				last = at
+			}
+			metadata.Additions += 5 + rng.IntN(120)
+			metadata.Deletions += rng.IntN(60)
+		}
+		metadata.Authors = slices.Sorted(maps.Keys(metadata.AuthorCounts))
+		metadata.FilesChanged = commits + rng.IntN(commits*2)
+		metadata.PullRequests = commits / 4
+
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return last, reports, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		_, err = tx.CreateWeeklyReport(ctx, &db.WeeklyReport{
+			RepoID:      repo.ID,
+			Year:        year,
+			Week:        week,
+			WeekStart:   start,
+			WeekEnd:     end,
+			Summary:     sql.NullString{String: demoSummary(demo, metadata, rng), Valid: true},
+			CommitCount: commits,
+			Metadata:    sql.NullString{String: string(encoded), Valid: true},
+			ReviewState: db.ReviewApproved,
+		})
+		if err != nil {
+			return last, reports, err
+		}
+		reports++
+	}
+	return last, reports, nil
+}
+
+// demoSummary makes up a weekly summary crediting up to three of the week's
+// authors with changes to the repository's areas
+func demoSummary(demo demoRepo, metadata ReportMetadata, rng *rand.Rand) string {
+	var sb strings.Builder
+	total := 0
+	for _, n := range metadata.AuthorCounts {
+		total += n
+	}
+	fmt.Fprintf(&sb, "A week of %d commits by %d people in %s.\n\n## Highlights\n\n",
+		total, len(metadata.AuthorCounts), demo.name)
+
+	authors := append([]string(nil), metadata.Authors...)
+	rng.Shuffle(len(authors), func(i, j int) { authors[i], authors[j] = authors[j], authors[i] })
+	for _, author := range authors[:min(len(authors), 3)] {
+		area := demo.areas[rng.IntN(len(demo.areas))]
+		change := fmt.Sprintf(demoChanges[rng.IntN(len(demoChanges))], area)
+		fmt.Fprintf(&sb, "- **%s** %s (%d commits)\n", author, change, metadata.AuthorCounts[author])
+	}
+	fmt.Fprintf(&sb, "\nThis is sample data from demo mode; no real repository was analyzed.\n")
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// Kinds of problems Fsck finds
+const (
+	FsckMissing  = "missing"   // Repository without a clone
+	FsckBroken   = "broken"    // Clone that is not a bare git repository
+	FsckURLDrift = "url-drift" // Clone whose origin is not the repository's URL
+	FsckOrphan   = "orphan"    // Clone without a repository
+)
+
+// FsckIssue is a problem with a repository's clone in the data directory
+type FsckIssue struct {
+	Kind     string
+	Repo     string // Repository name, or the clone's name for orphans
+	Path     string
+	Detail   string
+	Repaired bool
+}
+
+// FsckResult reports what Fsck checked and found
+type FsckResult struct {
+	Repos  int
+	Issues []FsckIssue
+}
+
+// Unrepaired returns the number of issues left after Fsck
+func (r *FsckResult) Unrepaired() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Fsck checks that every repository has a bare clone in the data directory
+// whose origin is the repository's URL, and that every clone there belongs
+// to a repository. With repair, missing and broken clones are cloned again
+// and drifted origins are reset to the repository's URL; orphaned clones are
+// only reported, since removing a repository on /admin/repos can keep its
+// files on purpose.
+func (s *RepoService) Fsck(ctx context.Context, repair bool) (*FsckResult, error) {
+	repos, err := s.db.ListRepositories(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	result := &FsckResult{Repos: len(repos)}
+	known := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		path := s.repoPath(repo.Name)
+		known[filepath.Base(path)] = true
+		issue := s.checkClone(repo, path)
+		if issue == nil {
+			continue
+		}
+		if repair {
+			if err := s.repairClone(repo, issue); err != nil {
+				issue.Detail += "; repair failed: " + err.Error()
+			} else {
+				issue.Repaired = true
+				slog.Info("Repaired repository clone", "name", repo.Name, "problem", issue.Kind)
+			}
+		}
+		result.Issues = append(result.Issues, *issue)
+	}
+
+	entries, err := os.ReadDir(s.cfg.DataDir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") || known[entry.Name()] {
+			continue
+		}
+		result.Issues = append(result.Issues, FsckIssue{
+			Kind:   FsckOrphan,
+			Repo:   strings.TrimSuffix(entry.Name(), ".git"),
+			Path:   filepath.Join(s.cfg.DataDir, entry.Name()),
+			Detail: "no repository uses this clone",
+		})
+	}
+	return result, nil
+}
+
+// checkClone returns the problem with a repository's clone at path, or nil
+func (s *RepoService) checkClone(repo *db.Repository, path string) *FsckIssue {
+	issue := &FsckIssue{Repo: repo.Name, Path: path}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		issue.Kind, issue.Detail = FsckMissing, "not cloned"
+	case err != nil:
+		issue.Kind, issue.Detail = FsckBroken, err.Error()
+	case !info.IsDir() || !git.IsBareRepo(path):
+		issue.Kind, issue.Detail = FsckBroken, "not a bare git repository"
+	default:
+		origin, err := git.GetRemoteURL(path)
+		if err != nil {
+			issue.Kind, issue.Detail = FsckBroken, "no origin remote"
+		} else if origin != repo.URL {
+			issue.Kind, issue.Detail = FsckURLDrift, fmt.Sprintf("origin is %s, repository URL is %s", origin, repo.URL)
+		} else {
+			return nil
+		}
+	}
+	return issue
+}
+
+// repairClone fixes a problem found by checkClone
+func (s *RepoService) repairClone(repo *db.Repository, issue *FsckIssue) error {
+	switch issue.Kind {
+	case FsckBroken:
+		if err := os.RemoveAll(issue.Path); err != nil {
+			return fmt.Errorf("failed to remove broken clone: %w", err)
+		}
+		return s.cloneRepo(repo, issue.Path)
+	case FsckMissing:
+		return s.cloneRepo(repo, issue.Path)
+	case FsckURLDrift:
+		return git.SetRemoteURL(issue.Path, repo.URL)
+	}
+	return fmt.Errorf("cannot repair %s", issue.Kind)
+}
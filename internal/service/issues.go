@@ -0,0 +1,157 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/github"
+	"github.com/perbu/activity/internal/gitlab"
+)
+
+// maxNotableIssues is how many issues a report's issue section lists
+const maxNotableIssues = 5
+
+// IssueActivity summarizes a week of a repository's issue tracker
+type IssueActivity struct {
+	Opened  int            `json:"opened"`
+	Closed  int            `json:"closed"`
+	Notable []NotableIssue `json:"notable,omitempty"`
+}
+
+// NotableIssue is an issue opened or closed during the week, listed in the
+// report by how much discussion it drew
+type NotableIssue struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Comments int    `json:"comments"`
+	Opened   bool   `json:"opened"` // Opened during the week
+	Closed   bool   `json:"closed"` // Closed during the week
+}
+
+// trackerIssue is an issue from either tracker
+type trackerIssue struct {
+	number    int
+	title     string
+	url       string
+	comments  int
+	createdAt time.Time
+	closedAt  *time.Time
+}
+
+// issueActivity summarizes the issues of a repo's GitHub or GitLab tracker
+// opened and closed between since and until. It returns nil if the issues
+// section is disabled, the repo is on neither host or no issue was opened or
+// closed. Failures, such as a private repo without credentials, are logged.
+func (s *ReportService) issueActivity(ctx context.Context, repo *db.Repository, since, until time.Time) *IssueActivity {
+	ic := s.cfg.Current().Issues
+	if !ic.Enabled {
+		return nil
+	}
+
+	var issues []trackerIssue
+	switch project, isGitLab := gitlab.ParseProjectURL(repo.URL, ic.GitLabHosts); {
+	case github.IsGitHubURL(repo.URL):
+		owner, name, err := github.ParseRepoURL(repo.URL)
+		if err != nil {
+			slog.Debug("Skipping issue activity", "repo", repo.Name, "error", err)
+			return nil
+		}
+		ghIssues, err := github.NewClient(s.tokenProvider).ListIssues(ctx, owner, name, since)
+		if err != nil {
+			slog.Warn("Failed to fetch GitHub issues", "repo", repo.Name, "error", err)
+			return nil
+		}
+		for _, i := range ghIssues {
+			issues = append(issues, trackerIssue{i.Number, i.Title, i.HTMLURL, i.Comments, i.CreatedAt, i.ClosedAt})
+		}
+	case isGitLab:
+		glIssues, err := gitlab.NewClient(s.cfg.GetGitLabToken()).ListIssues(ctx, project, since)
+		if err != nil {
+			slog.Warn("Failed to fetch GitLab issues", "repo", repo.Name, "error", err)
+			return nil
+		}
+		for _, i := range glIssues {
+			issues = append(issues, trackerIssue{i.IID, i.Title, i.WebURL, i.UserNotesCount, i.CreatedAt, i.ClosedAt})
+		}
+	default:
+		return nil
+	}
+
+	return summarizeIssues(issues, since, until)
+}
+
+// summarizeIssues counts the issues opened and closed between since and until
+// and picks the most discussed of them as notable. It returns nil if none
+// were opened or closed.
+func summarizeIssues(issues []trackerIssue, since, until time.Time) *IssueActivity {
+	within := func(t time.Time) bool { return !t.Before(since) && !t.After(until) }
+
+	var activity IssueActivity
+	for _, i := range issues {
+		n := NotableIssue{Number: i.number, Title: i.title, URL: i.url, Comments: i.comments}
+		n.Opened = within(i.createdAt)
+		n.Closed = i.closedAt != nil && within(*i.closedAt)
+		if n.Opened {
+			activity.Opened++
+		}
+		if n.Closed {
+			activity.Closed++
+		}
+		if (n.Opened || n.Closed) && n.Comments > 0 {
+			activity.Notable = append(activity.Notable, n)
+		}
+	}
+	if activity.Opened == 0 && activity.Closed == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(activity.Notable, func(a, b NotableIssue) int {
+		return cmp.Or(cmp.Compare(b.Comments, a.Comments), cmp.Compare(a.Number, b.Number))
+	})
+	if len(activity.Notable) > maxNotableIssues {
+		activity.Notable = activity.Notable[:maxNotableIssues]
+	}
+	return &activity
+}
+
+// issueSection formats issue activity as a markdown section for the end of a
+// report summary
+func issueSection(a *IssueActivity) string {
+	if a == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## Issues\n\n")
+	fmt.Fprintf(&sb, "%d %s opened, %d closed.\n", a.Opened, plural(a.Opened, "issue", "issues"), a.Closed)
+
+	if len(a.Notable) > 0 {
+		sb.WriteString("\nMost discussed:\n\n")
+		for _, n := range a.Notable {
+			var status string
+			switch {
+			case n.Opened && n.Closed:
+				status = "opened and closed"
+			case n.Closed:
+				status = "closed"
+			default:
+				status = "opened"
+			}
+			fmt.Fprintf(&sb, "- [#%d %s](%s) (%s, %d %s)\n", n.Number, escapeLinkText(n.Title), n.URL,
+				status, n.Comments, plural(n.Comments, "comment", "comments"))
+		}
+	}
+	return sb.String()
+}
+
+// escapeLinkText escapes the characters that would end a markdown link's text
+func escapeLinkText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(s)
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/jira"
+	"github.com/yuin/goldmark"
+)
+
+// MarkdownExtensions returns the goldmark extensions for rendering report
+// summaries: with Jira configured, ticket keys are linked to their tickets
+func MarkdownExtensions(cfg *config.Config) []goldmark.Extender {
+	jc := cfg.Current().Jira
+	if jc.BaseURL == "" {
+		return nil
+	}
+	return []goldmark.Extender{jira.Linkify(jc.BaseURL, jc.Projects)}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"log/slog"
@@ -10,6 +11,7 @@ import (
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/email"
+	"github.com/perbu/activity/internal/git"
 	"github.com/perbu/activity/internal/newsletter"
 )
 
@@ -27,31 +29,81 @@ func NewNewsletterService(database *db.DB, cfg *config.Config) *NewsletterServic
 	}
 }
 
-// AddSubscriber creates a new subscriber
-func (s *NewsletterService) AddSubscriber(email string, subscribeAll bool) (*db.Subscriber, error) {
+// AddSubscriber creates a new subscriber who receives scheduled newsletters
+// on Monday at sendHour in timezone (an IANA name such as "Europe/Oslo")
+func (s *NewsletterService) AddSubscriber(ctx context.Context, email string, subscribeAll bool, timezone string, sendHour int) (*db.Subscriber, error) {
+	if err := validateSchedule(timezone, sendHour); err != nil {
+		return nil, err
+	}
+
 	// Check if subscriber already exists
-	_, err := s.db.GetSubscriberByEmail(email)
+	_, err := s.db.GetSubscriberByEmail(ctx, email)
 	if err == nil {
 		return nil, fmt.Errorf("subscriber '%s' already exists", email)
 	}
 
-	sub, err := s.db.CreateSubscriber(email, subscribeAll)
+	var sub *db.Subscriber
+	err = s.db.WithTx(ctx, func(tx *db.DB) error {
+		sub, err = tx.CreateSubscriber(ctx, email, subscribeAll)
+		if err != nil {
+			return err
+		}
+		sub.Timezone = timezone
+		sub.SendHour = sendHour
+		return tx.UpdateSubscriber(ctx, sub)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create subscriber: %w", err)
 	}
 
-	slog.Info("Subscriber added", "email", email, "subscribe_all", subscribeAll)
+	slog.Info("Subscriber added", "email", email, "subscribe_all", subscribeAll, "timezone", timezone, "send_hour", sendHour)
 	return sub, nil
 }
 
+// SetSchedule changes when a subscriber receives scheduled newsletters
+func (s *NewsletterService) SetSchedule(ctx context.Context, email, timezone string, sendHour int) error {
+	if err := validateSchedule(timezone, sendHour); err != nil {
+		return err
+	}
+
+	sub, err := s.db.GetSubscriberByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("subscriber not found: %s", email)
+	}
+
+	sub.Timezone = timezone
+	sub.SendHour = sendHour
+	if err := s.db.UpdateSubscriber(ctx, sub); err != nil {
+		return fmt.Errorf("failed to update subscriber: %w", err)
+	}
+
+	slog.Info("Subscriber schedule updated", "email", email, "timezone", timezone, "send_hour", sendHour)
+	return nil
+}
+
+// validateSchedule checks that timezone is a known IANA timezone and
+// sendHour is an hour of the day
+func validateSchedule(timezone string, sendHour int) error {
+	if timezone == "" || timezone == "Local" {
+		return fmt.Errorf("timezone must be an IANA name such as Europe/Oslo")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("unknown timezone: %s", timezone)
+	}
+	if sendHour < 0 || sendHour > 23 {
+		return fmt.Errorf("send hour must be between 0 and 23, got %d", sendHour)
+	}
+	return nil
+}
+
 // RemoveSubscriber deletes a subscriber by email
-func (s *NewsletterService) RemoveSubscriber(email string) error {
-	sub, err := s.db.GetSubscriberByEmail(email)
+func (s *NewsletterService) RemoveSubscriber(ctx context.Context, email string) error {
+	sub, err := s.db.GetSubscriberByEmail(ctx, email)
 	if err != nil {
 		return fmt.Errorf("subscriber not found: %s", email)
 	}
 
-	if err := s.db.DeleteSubscriber(sub.ID); err != nil {
+	if err := s.db.DeleteSubscriber(ctx, sub.ID); err != nil {
 		return fmt.Errorf("failed to delete subscriber: %w", err)
 	}
 
@@ -60,18 +112,18 @@ func (s *NewsletterService) RemoveSubscriber(email string) error {
 }
 
 // ListSubscribers returns all subscribers
-func (s *NewsletterService) ListSubscribers() ([]*db.Subscriber, error) {
-	return s.db.ListSubscribers()
+func (s *NewsletterService) ListSubscribers(ctx context.Context) ([]*db.Subscriber, error) {
+	return s.db.ListSubscribers(ctx)
 }
 
 // GetSubscriber returns a subscriber by email
-func (s *NewsletterService) GetSubscriber(email string) (*db.Subscriber, error) {
-	return s.db.GetSubscriberByEmail(email)
+func (s *NewsletterService) GetSubscriber(ctx context.Context, email string) (*db.Subscriber, error) {
+	return s.db.GetSubscriberByEmail(ctx, email)
 }
 
 // Subscribe adds a subscription for a subscriber to a repository
-func (s *NewsletterService) Subscribe(email, repoName string) error {
-	sub, err := s.db.GetSubscriberByEmail(email)
+func (s *NewsletterService) Subscribe(ctx context.Context, email, repoName string) error {
+	sub, err := s.db.GetSubscriberByEmail(ctx, email)
 	if err != nil {
 		return fmt.Errorf("subscriber not found: %s", email)
 	}
@@ -80,18 +132,18 @@ func (s *NewsletterService) Subscribe(email, repoName string) error {
 		return fmt.Errorf("subscriber '%s' is already subscribed to all repositories", email)
 	}
 
-	repo, err := s.db.GetRepositoryByName(repoName)
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", repoName)
 	}
 
 	// Check if already subscribed
-	_, err = s.db.GetSubscriptionBySubscriberAndRepo(sub.ID, repo.ID)
+	_, err = s.db.GetSubscriptionBySubscriberAndRepo(ctx, sub.ID, repo.ID)
 	if err == nil {
 		return fmt.Errorf("'%s' is already subscribed to '%s'", email, repoName)
 	}
 
-	_, err = s.db.CreateSubscription(sub.ID, repo.ID)
+	_, err = s.db.CreateSubscription(ctx, sub.ID, repo.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create subscription: %w", err)
 	}
@@ -101,18 +153,18 @@ func (s *NewsletterService) Subscribe(email, repoName string) error {
 }
 
 // Unsubscribe removes a subscription
-func (s *NewsletterService) Unsubscribe(email, repoName string) error {
-	sub, err := s.db.GetSubscriberByEmail(email)
+func (s *NewsletterService) Unsubscribe(ctx context.Context, email, repoName string) error {
+	sub, err := s.db.GetSubscriberByEmail(ctx, email)
 	if err != nil {
 		return fmt.Errorf("subscriber not found: %s", email)
 	}
 
-	repo, err := s.db.GetRepositoryByName(repoName)
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", repoName)
 	}
 
-	if err := s.db.DeleteSubscriptionBySubscriberAndRepo(sub.ID, repo.ID); err != nil {
+	if err := s.db.DeleteSubscriptionBySubscriberAndRepo(ctx, sub.ID, repo.ID); err != nil {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
 
@@ -121,8 +173,85 @@ func (s *NewsletterService) Unsubscribe(email, repoName string) error {
 }
 
 // GetSubscriptions returns subscriptions for a subscriber
-func (s *NewsletterService) GetSubscriptions(subscriberID int64) ([]*db.Subscription, error) {
-	return s.db.ListSubscriptionsBySubscriber(subscriberID)
+func (s *NewsletterService) GetSubscriptions(ctx context.Context, subscriberID int64) ([]*db.Subscription, error) {
+	return s.db.ListSubscriptionsBySubscriber(ctx, subscriberID)
+}
+
+// recordedEvents are the webhook event types stored for delivery stats;
+// clicks and the like are ignored
+var recordedEvents = map[string]bool{
+	"delivered":  true,
+	"open":       true,
+	"bounce":     true,
+	"dropped":    true,
+	"spamreport": true,
+}
+
+// EventResult contains the result of processing webhook events
+type EventResult struct {
+	Recorded   int // Events stored, counted once per matching subscriber
+	Suppressed int // Subscribers suppressed because their address hard-bounced
+	Ignored    int // Events of other types, for unknown addresses, or already recorded
+}
+
+// RecordEvents stores delivery events from the SendGrid event webhook and
+// suppresses subscribers whose address hard-bounced. Addresses are matched
+// in all workspaces, since the webhook is not tied to one.
+func (s *NewsletterService) RecordEvents(ctx context.Context, events []email.Event) (*EventResult, error) {
+	ctx = db.WithWorkspace(ctx, 0)
+	result := &EventResult{}
+
+	err := s.db.WithTx(ctx, func(tx *db.DB) error {
+		for _, e := range events {
+			if !recordedEvents[e.Event] || e.Email == "" || e.EventID == "" {
+				result.Ignored++
+				continue
+			}
+
+			n, err := tx.RecordEmailEvent(ctx, e.Email, e.Event, e.Reason, e.EventID, e.SentMessageID(), e.Time())
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				result.Ignored++
+				continue
+			}
+			result.Recorded += int(n)
+
+			if e.HardBounce() {
+				n, err := tx.SuppressSubscribers(ctx, e.Email, e.Reason)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					slog.Warn("Subscriber suppressed after hard bounce", "email", e.Email, "reason", e.Reason)
+				}
+				result.Suppressed += int(n)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record email events: %w", err)
+	}
+
+	return result, nil
+}
+
+// Unsuppress clears a subscriber's bounce suppression so newsletters are
+// sent to them again
+func (s *NewsletterService) Unsuppress(ctx context.Context, email string) error {
+	sub, err := s.db.GetSubscriberByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("subscriber not found: %s", email)
+	}
+
+	if err := s.db.UnsuppressSubscriber(ctx, sub.ID); err != nil {
+		return err
+	}
+
+	slog.Info("Subscriber unsuppressed", "email", email)
+	return nil
 }
 
 // SendResult contains the result of sending newsletters
@@ -131,38 +260,78 @@ type SendResult struct {
 	Skipped          int
 	Errors           int
 	TotalSubscribers int
+	BatchIDs         []int64 // Send history batches the send was recorded in
 }
 
-// Send sends newsletters to all subscribers
-func (s *NewsletterService) Send(ctx context.Context, since time.Duration, dryRun bool, output io.Writer) (*SendResult, error) {
+// newSender creates a newsletter sender using the configured email client,
+// or a dry run client that only prints what would be sent
+func (s *NewsletterService) newSender(dryRun bool, output io.Writer) (*newsletter.Sender, error) {
 	// Check if newsletter is enabled
-	if !s.cfg.Newsletter.Enabled && !dryRun {
+	nc := s.cfg.Current().Newsletter
+	if !nc.Enabled && !dryRun {
 		return nil, fmt.Errorf("newsletter is not enabled in config (set newsletter.enabled: true)")
 	}
 
-	// Get or validate API key
-	apiKey := s.cfg.GetSendGridAPIKey()
-	if apiKey == "" && !dryRun {
-		return nil, fmt.Errorf("SendGrid API key not configured")
-	}
-
 	// Create email client
 	var client email.Sender
 	if dryRun {
-		client = email.NewDryRunClient(s.cfg.Newsletter.FromEmail, s.cfg.Newsletter.FromName)
+		client = email.NewDryRunClient(nc.FromEmail, nc.FromName)
 	} else {
-		client = email.NewClient(apiKey, s.cfg.Newsletter.FromEmail, s.cfg.Newsletter.FromName)
+		var err error
+		client, err = NewEmailClient(s.cfg)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create composer and sender
-	composer := newsletter.NewComposer(s.db, s.cfg.Newsletter.SubjectPrefix)
-	sender := newsletter.NewSender(s.db, composer, client, dryRun, output)
+	composer := newsletter.NewComposer(s.db, nc.SubjectPrefix, nc.MaxSections, MarkdownExtensions(s.cfg)...)
+	return newsletter.NewSender(s.db, composer, client, dryRun, output), nil
+}
+
+// NewEmailClient creates a client for the configured email provider
+func NewEmailClient(cfg *config.Config) (email.Sender, error) {
+	nc := cfg.Current().Newsletter
+	switch nc.Provider {
+	case "", "sendgrid":
+		apiKey := cfg.GetSendGridAPIKey()
+		if apiKey == "" {
+			return nil, fmt.Errorf("SendGrid API key not configured")
+		}
+		return email.NewClient(apiKey, nc.FromEmail, nc.FromName), nil
+	case "postmark":
+		token := cfg.GetPostmarkToken()
+		if token == "" {
+			return nil, fmt.Errorf("Postmark server token not configured")
+		}
+		return email.NewPostmarkClient(token, nc.FromEmail, nc.FromName), nil
+	case "mailgun":
+		apiKey := cfg.GetMailgunAPIKey()
+		if apiKey == "" {
+			return nil, fmt.Errorf("Mailgun API key not configured")
+		}
+		if nc.MailgunDomain == "" {
+			return nil, fmt.Errorf("Mailgun domain not configured (newsletter.mailgun_domain)")
+		}
+		return email.NewMailgunClient(apiKey, nc.MailgunDomain, nc.MailgunBaseURL, nc.FromEmail, nc.FromName), nil
+	default:
+		return nil, fmt.Errorf("unknown newsletter provider %q (use sendgrid, postmark or mailgun)", nc.Provider)
+	}
+}
+
+// Send sends newsletters to all subscribers immediately, ignoring their
+// send schedule. startedBy is the admin who started the send, or empty.
+func (s *NewsletterService) Send(ctx context.Context, since time.Duration, dryRun bool, startedBy string, output io.Writer) (*SendResult, error) {
+	sender, err := s.newSender(dryRun, output)
+	if err != nil {
+		return nil, err
+	}
 
 	sinceTime := time.Now().Add(-since)
 	slog.Info("Sending newsletters", "since", sinceTime.Format("2006-01-02 15:04"), "dry_run", dryRun)
 
 	// Send to all subscribers
-	result, err := sender.SendAll(ctx, sinceTime)
+	result, err := sender.SendAll(ctx, sinceTime, startedBy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send newsletters: %w", err)
 	}
@@ -174,9 +343,117 @@ func (s *NewsletterService) Send(ctx context.Context, since time.Duration, dryRu
 		Skipped:          result.Skipped,
 		Errors:           result.Errors,
 		TotalSubscribers: result.TotalSubscribers,
+		BatchIDs:         result.BatchIDs,
+	}, nil
+}
+
+// SendTo sends a subscriber their unsent reports for weeks that ended within
+// since, ignoring their send schedule. startedBy is the admin who started
+// the send, or empty.
+func (s *NewsletterService) SendTo(ctx context.Context, email string, since time.Duration, dryRun bool, startedBy string, output io.Writer) error {
+	sender, err := s.newSender(dryRun, output)
+	if err != nil {
+		return err
+	}
+	return sender.SendToSubscriber(ctx, email, time.Now().Add(-since), startedBy)
+}
+
+// Preview returns the newsletter a subscriber would be sent now for weeks
+// that ended within since, rendered exactly as it would be sent, or nil if
+// they have no unsent reports. Newsletters need not be enabled.
+func (s *NewsletterService) Preview(ctx context.Context, subscriberEmail string, since time.Duration) (*email.Email, error) {
+	sub, err := s.db.GetSubscriberByEmail(ctx, subscriberEmail)
+	if err != nil {
+		return nil, fmt.Errorf("subscriber not found: %s", subscriberEmail)
+	}
+	sender, err := s.newSender(true, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return sender.Preview(ctx, sub, time.Now().Add(-since))
+}
+
+// SendTest sends the newsletter a subscriber would be sent now to the
+// address to only, without recording it as sent to the subscriber
+func (s *NewsletterService) SendTest(ctx context.Context, subscriberEmail, to string, since time.Duration, dryRun bool, output io.Writer) error {
+	sub, err := s.db.GetSubscriberByEmail(ctx, subscriberEmail)
+	if err != nil {
+		return fmt.Errorf("subscriber not found: %s", subscriberEmail)
+	}
+	sender, err := s.newSender(dryRun, output)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Sending test newsletter", "subscriber", subscriberEmail, "to", to, "dry_run", dryRun)
+	return sender.SendTest(ctx, sub, to, time.Now().Add(-since))
+}
+
+// SendScheduled sends last week's reports to the subscribers whose Monday
+// send hour has passed in their timezone and who have not received them yet.
+// The server runs this periodically when newsletter.scheduled is set.
+func (s *NewsletterService) SendScheduled(ctx context.Context, output io.Writer) (*SendResult, error) {
+	sender, err := s.newSender(false, output)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := sender.SendScheduled(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to send scheduled newsletters: %w", err)
+	}
+
+	if result.Sent > 0 || result.Errors > 0 {
+		slog.Info("Scheduled newsletter send complete", "sent", result.Sent, "errors", result.Errors)
+	}
+
+	return &SendResult{
+		Sent:             result.Sent,
+		Skipped:          result.Skipped,
+		Errors:           result.Errors,
+		TotalSubscribers: result.TotalSubscribers,
+		BatchIDs:         result.BatchIDs,
 	}, nil
 }
 
+// ResendFailed resends the failed deliveries of a send history batch in the
+// context's workspace. Each is sent the reports of its original window that
+// have not been sent to the subscriber since.
+func (s *NewsletterService) ResendFailed(ctx context.Context, batchID int64, output io.Writer) (*SendResult, error) {
+	if _, err := s.db.GetNewsletterBatch(ctx, batchID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.db.ListNewsletterDeliveries(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := s.newSender(false, output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SendResult{BatchIDs: []int64{batchID}}
+	for _, delivery := range deliveries {
+		if delivery.Status != db.DeliveryFailed {
+			continue
+		}
+		result.TotalSubscribers++
+		if err := sender.Resend(ctx, delivery); err != nil {
+			fmt.Fprintf(output, "Error resending to %s: %v\n", delivery.Email, err)
+			result.Errors++
+			continue
+		}
+		if delivery.Status == db.DeliverySkipped {
+			result.Skipped++
+		} else {
+			result.Sent++
+		}
+	}
+
+	slog.Info("Newsletter resend complete", "batch", batchID, "sent", result.Sent, "skipped", result.Skipped, "errors", result.Errors)
+	return result, nil
+}
+
 // ParseSinceDuration parses a duration string like "7d", "1w", "24h"
 func ParseSinceDuration(s string) (time.Duration, error) {
 	if len(s) == 0 {
@@ -208,3 +485,46 @@ func ParseSinceDuration(s string) (time.Duration, error) {
 
 	return time.Duration(num) * multiplier, nil
 }
+
+// SendCorrection emails the recipients of a report that was regenerated
+// after it was sent what changed, if newsletter.send_corrections is set and
+// the report is approved. Changes below newsletter.correction_threshold are
+// not worth a correction. The sent summary is forgotten either way, so each
+// regeneration is corrected at most once. Returns nil if nothing was sent.
+func (s *NewsletterService) SendCorrection(ctx context.Context, report *db.WeeklyReport) (*SendResult, error) {
+	if !report.SentSummary.Valid || !report.Summary.Valid || report.ReviewState != db.ReviewApproved {
+		return nil, nil
+	}
+	if nc := s.cfg.Current().Newsletter; !nc.Enabled || !nc.SendCorrections {
+		return nil, nil
+	}
+
+	repo, err := s.db.GetRepository(ctx, report.RepoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+	week := git.FormatISOWeek(report.Year, report.Week)
+
+	diff := newsletter.DiffSummaries(report.SentSummary.String, report.Summary.String)
+	var result *SendResult
+	if diff.Change < s.cfg.GetCorrectionThreshold() {
+		slog.Info("Regenerated report changed too little for a correction", "repo", repo.Name, "week", week, "change", diff.Change)
+	} else {
+		sender, err := s.newSender(false, io.Discard)
+		if err != nil {
+			return nil, err
+		}
+		sent, err := sender.SendCorrection(ctx, repo.Name, report, diff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send corrections: %w", err)
+		}
+		slog.Info("Correction sent", "repo", repo.Name, "week", week, "change", diff.Change, "sent", sent.Sent, "errors", sent.Errors)
+		result = &SendResult{Sent: sent.Sent, Errors: sent.Errors, TotalSubscribers: sent.TotalSubscribers}
+	}
+
+	if err := s.db.ClearSentSummary(ctx, report.ID); err != nil {
+		return result, err
+	}
+	report.SentSummary = sql.NullString{}
+	return result, nil
+}
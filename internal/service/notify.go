@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// notifyFavorites adds a notification about a new or regenerated report for
+// each user who starred its repository
+func (s *ReportService) notifyFavorites(ctx context.Context, repo *db.Repository, report *db.WeeklyReport) {
+	title := fmt.Sprintf("New report for %s %s (%d commits)", repo.Name, git.FormatISOWeek(report.Year, report.Week), report.CommitCount)
+	if _, err := s.db.NotifyFavorites(ctx, repo.ID, title, fmt.Sprintf("/reports/%d", report.ID)); err != nil {
+		slog.Warn("Failed to notify users who starred the repository", "repo", repo.Name, "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// PlannedReport describes a weekly report that report generation would
+// create, skip or leave empty
+type PlannedReport struct {
+	RepoName  string
+	WeekLabel string
+	Commits   int  // Commits that would be analyzed
+	Automated int  // Commits by ignored authors, noted but not analyzed
+	Skipped   bool // A report exists and Force is not set
+	Estimate  *analyzer.Estimate
+}
+
+// Generate returns whether the report would be generated
+func (p *PlannedReport) Generate() bool {
+	return p.Estimate != nil
+}
+
+// Plan collects the commits report generation would analyze and estimates
+// the tokens and cost of each report without calling the LLM. It reads the
+// local clones as they are, without fetching. opts.Week selects one week,
+// opts.Since all weeks since a date, and neither the previous complete week;
+// an empty opts.RepoName plans all active repositories.
+func (s *ReportService) Plan(ctx context.Context, opts GenerateOptions) ([]*PlannedReport, error) {
+	weeks, err := planWeeks(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*db.Repository
+	if opts.RepoName == "" {
+		activeOnly := true
+		repos, err = s.db.ListRepositories(ctx, &activeOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories: %w", err)
+		}
+	} else {
+		repo, err := s.db.GetRepositoryByName(ctx, opts.RepoName)
+		if err != nil {
+			return nil, fmt.Errorf("repository not found: %s", opts.RepoName)
+		}
+		repos = []*db.Repository{repo}
+	}
+
+	var plan []*PlannedReport
+	for _, repo := range repos {
+		repoPath := s.repoPath(repo.Name)
+		for _, yw := range weeks {
+			year, week := yw[0], yw[1]
+			p := &PlannedReport{RepoName: repo.Name, WeekLabel: git.FormatISOWeek(year, week)}
+			plan = append(plan, p)
+
+			exists, err := s.db.WeeklyReportExists(ctx, repo.ID, year, week)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check existing report: %w", err)
+			}
+			if exists && !opts.Force {
+				p.Skipped = true
+				continue
+			}
+
+			commits, err := git.GetCommitsForWeekWithOptions(repoPath, year, week, s.logOptions(repo.Name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get commits for %s in %s: %w", p.WeekLabel, repo.Name, err)
+			}
+			commits, automated := s.filterCommits(ctx, repo, commits)
+			p.Commits, p.Automated = len(commits), len(automated)
+			if len(commits) == 0 {
+				continue
+			}
+
+			branchActivity, err := git.GetFeatureBranchActivity(repoPath, repo.Branch, year, week)
+			if err != nil {
+				branchActivity = nil
+			}
+			branchActivity = s.filterBranchActivity(ctx, repo, branchActivity)
+
+			var previousSummary string
+			prevYear, prevWeek := previousWeek(year, week)
+			if prev, err := s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, prevYear, prevWeek); err == nil && prev != nil && prev.Summary.Valid {
+				previousSummary = prev.Summary.String
+			}
+
+			p.Estimate = analyzer.EstimateAnalysis(s.cfg, repo, commits, branchActivity, previousSummary)
+		}
+	}
+	return plan, nil
+}
+
+// planWeeks returns the ISO weeks selected by opts as [year, week] pairs
+func planWeeks(opts GenerateOptions) ([][2]int, error) {
+	switch {
+	case opts.Week != "" && opts.Since != "":
+		return nil, fmt.Errorf("use either a week or a since date, not both")
+	case opts.Week != "":
+		year, week, err := git.ParseISOWeek(opts.Week)
+		if err != nil {
+			return nil, err
+		}
+		return [][2]int{{year, week}}, nil
+	case opts.Since != "":
+		since, err := time.Parse("2006-01-02", opts.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format: %s (expected YYYY-MM-DD)", opts.Since)
+		}
+		return git.WeeksInRange(since, time.Now()), nil
+	default:
+		year, week := time.Now().ISOWeek()
+		year, week = previousWeek(year, week)
+		return [][2]int{{year, week}}, nil
+	}
+}
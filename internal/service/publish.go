@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/perbu/activity/internal/confluence"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/github"
+	"github.com/perbu/activity/internal/notion"
+	"github.com/perbu/activity/internal/publish"
+)
+
+// publishers returns the publishers configured for a repo: its Confluence
+// space, Notion database and GitHub Discussions category
+func (s *ReportService) publishers(repo *db.Repository) []publish.Publisher {
+	rc := s.cfg.GetRepoConfig(repo.Name)
+	var publishers []publish.Publisher
+
+	if cc := s.cfg.Current().Confluence; rc.Confluence.Space != "" && cc.BaseURL != "" {
+		client := confluence.NewClient(cc.BaseURL, cc.Email, s.cfg.GetConfluenceToken())
+		publishers = append(publishers, publish.NewConfluence(client, rc.Confluence.Space, rc.Confluence.ParentID, MarkdownExtensions(s.cfg)...))
+	}
+
+	if rc.Notion.DatabaseID != "" {
+		if token := s.cfg.GetNotionToken(); token != "" {
+			publishers = append(publishers, publish.NewNotion(notion.NewClient(token), rc.Notion.DatabaseID, MarkdownExtensions(s.cfg)...))
+		}
+	}
+
+	if rc.Discussions.Category != "" && github.IsGitHubURL(repo.URL) {
+		owner, name, err := github.ParseRepoURL(repo.URL)
+		switch {
+		case s.tokenProvider == nil:
+			slog.Warn("Not posting report to GitHub Discussions: no GitHub App configured", "repo", repo.Name)
+		case err != nil:
+			slog.Warn("Not posting report to GitHub Discussions", "repo", repo.Name, "error", err)
+		default:
+			publishers = append(publishers, publish.NewDiscussions(github.NewClient(s.tokenProvider), owner, name, rc.Discussions.Category))
+		}
+	}
+
+	return publishers
+}
+
+// publishReport publishes a weekly report to each destination configured for
+// its repo, creating a page per week that is updated when the report is
+// regenerated. Failures are only logged.
+func (s *ReportService) publishReport(ctx context.Context, repo *db.Repository, report *db.WeeklyReport) {
+	publishers := s.publishers(repo)
+	if len(publishers) == 0 {
+		return
+	}
+
+	r := &publish.Report{
+		Repo:        repo.Name,
+		Year:        report.Year,
+		Week:        report.Week,
+		WeekStart:   report.WeekStart,
+		WeekEnd:     report.WeekEnd,
+		CommitCount: report.CommitCount,
+		Summary:     report.Summary.String,
+	}
+	for _, p := range publishers {
+		url, err := p.Publish(ctx, r)
+		if err != nil {
+			slog.Warn("Failed to publish report", "repo", repo.Name, "to", p.Name(), "error", err)
+			continue
+		}
+		slog.Info("Published report", "repo", repo.Name, "week", r.WeekLabel(), "to", p.Name(), "url", url)
+	}
+}
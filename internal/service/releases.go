@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// weekReleases returns the tags made in a repository's clone between since
+// and until, newest first, or nil if there are none or they can't be read
+func (s *ReportService) weekReleases(repo *db.Repository, since, until time.Time) []git.Tag {
+	tags, err := git.GetTags(s.repoPath(repo.Name), since, until)
+	if err != nil {
+		slog.Warn("Failed to list tags", "repo", repo.Name, "error", err)
+		return nil
+	}
+	return tags
+}
+
+// releaseSection formats the week's tags as a markdown section for the end of
+// a report summary, oldest first
+func releaseSection(tags []git.Tag) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## Releases\n\n")
+	for i := len(tags) - 1; i >= 0; i-- {
+		tag := tags[i]
+		fmt.Fprintf(&sb, "- **%s** (%s)", tag.Name, tag.Date.Format("Mon Jan 2"))
+		if tag.Subject != "" {
+			fmt.Fprintf(&sb, ": %s", tag.Subject)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Releases returns a repository's newest tags, at most limit of them, for
+// its release timeline
+func (s *RepoService) Releases(repo *db.Repository, limit int) ([]git.Tag, error) {
+	tags, err := git.GetTags(s.repoPath(repo.Name), time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return tags[:min(len(tags), limit)], nil
+}
@@ -2,18 +2,22 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/perbu/activity/internal/blob"
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/git"
 	"github.com/perbu/activity/internal/github"
 	"github.com/perbu/activity/internal/llm"
+	"github.com/perbu/activity/internal/telemetry"
 )
 
 // RepoService handles repository management operations
@@ -21,6 +25,7 @@ type RepoService struct {
 	db            *db.DB
 	cfg           *config.Config
 	tokenProvider *github.TokenProvider
+	blobs         blob.Store
 }
 
 // NewRepoService creates a new RepoService
@@ -29,6 +34,7 @@ func NewRepoService(database *db.DB, cfg *config.Config, tokenProvider *github.T
 		db:            database,
 		cfg:           cfg,
 		tokenProvider: tokenProvider,
+		blobs:         blob.New(cfg),
 	}
 }
 
@@ -45,7 +51,7 @@ func (s *RepoService) ensureRepoReady(repo *db.Repository) error {
 	// Check if repo exists
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 		slog.Info("Repository missing, re-cloning", "name", repo.Name)
-		return s.cloneRepo(repo)
+		return s.cloneRepo(repo, repoPath)
 	}
 
 	// Check if it's a bare repo
@@ -54,16 +60,15 @@ func (s *RepoService) ensureRepoReady(repo *db.Repository) error {
 		if err := os.RemoveAll(repoPath); err != nil {
 			return fmt.Errorf("failed to remove old repo: %w", err)
 		}
-		return s.cloneRepo(repo)
+		return s.cloneRepo(repo, repoPath)
 	}
 
 	return nil
 }
 
-// cloneRepo clones a repository as a bare mirror
-func (s *RepoService) cloneRepo(repo *db.Repository) error {
-	repoPath := s.repoPath(repo.Name)
-
+// cloneRepo clones a repository as a bare mirror to path, with HEAD on the
+// repository's branch if the clone has it
+func (s *RepoService) cloneRepo(repo *db.Repository, path string) error {
 	if repo.Private {
 		if s.tokenProvider == nil {
 			return fmt.Errorf("repository '%s' is private but no GitHub App is configured", repo.Name)
@@ -72,9 +77,62 @@ func (s *RepoService) cloneRepo(repo *db.Repository) error {
 		if err != nil {
 			return fmt.Errorf("failed to get GitHub token: %w", err)
 		}
-		return git.CloneMirrorWithAuth(repo.URL, repoPath, token)
+		if err := git.CloneMirrorWithAuth(repo.URL, path, token); err != nil {
+			return err
+		}
+	} else if err := git.CloneMirror(repo.URL, path); err != nil {
+		return err
+	}
+
+	// The mirror's HEAD is the remote's default branch, which need not be
+	// the one the repository follows
+	if _, err := git.GetBranchSHA(path, repo.Branch); err == nil {
+		return git.SetHEAD(path, repo.Branch)
+	}
+	return nil
+}
+
+// Reclone replaces a repository's clone with a fresh one, e.g. when the
+// clone is corrupted. The new clone is made next to the old one, which is
+// only removed once cloning succeeded. Reports and the last run are kept.
+func (s *RepoService) Reclone(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "RepoService.Reclone", name)
+	defer func() { telemetry.End(span, err) }()
+
+	repo, err := s.db.GetRepositoryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("repository not found: %s", name)
+	}
+
+	repoPath := s.repoPath(repo.Name)
+	tmpPath := repoPath + ".reclone"
+	// Left behind by an interrupted reclone
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("failed to remove old temporary clone: %w", err)
+	}
+
+	slog.Info("Re-cloning repository", "name", name, "url", repo.URL, "path", repoPath)
+	cloneSpan := gitSpan(ctx, "clone", repo.Name)
+	err = s.cloneRepo(repo, tmpPath)
+	telemetry.End(cloneSpan, err)
+	if err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to remove old clone: %w", err)
+	}
+	if err := os.Rename(tmpPath, repoPath); err != nil {
+		return fmt.Errorf("failed to move new clone into place: %w", err)
+	}
+
+	if sha, err := git.GetBranchSHA(repoPath, repo.Branch); err == nil {
+		s.recordLastCommit(ctx, repo, sha)
 	}
-	return git.CloneMirror(repo.URL, repoPath)
+	slog.Info("Repository re-cloned", "name", name)
+	return nil
 }
 
 // AddOptions contains options for adding a repository
@@ -86,9 +144,13 @@ type AddOptions struct {
 }
 
 // Add creates a new tracked repository
-func (s *RepoService) Add(ctx context.Context, opts AddOptions) (*db.Repository, error) {
-	// Check if repo already exists
-	_, err := s.db.GetRepositoryByName(opts.Name)
+func (s *RepoService) Add(ctx context.Context, opts AddOptions) (_ *db.Repository, err error) {
+	ctx, span := startSpan(ctx, "RepoService.Add", opts.Name)
+	defer func() { telemetry.End(span, err) }()
+
+	// Check if repo already exists. Names are unique across workspaces since
+	// they name the local clone.
+	_, err = s.db.GetRepositoryByName(db.WithWorkspace(ctx, 0), opts.Name)
 	if err == nil {
 		return nil, fmt.Errorf("repository '%s' already exists", opts.Name)
 	}
@@ -98,9 +160,16 @@ func (s *RepoService) Add(ctx context.Context, opts AddOptions) (*db.Repository,
 		return nil, fmt.Errorf("private repositories require GitHub App configuration")
 	}
 
-	// Default branch
+	// Follow the remote's default branch unless told otherwise, since older
+	// repositories often use master
 	if opts.Branch == "" {
-		opts.Branch = "main"
+		opts.Branch, err = s.remoteDefaultBranch(&db.Repository{Name: opts.Name, URL: opts.URL, Private: opts.Private})
+		if err != nil {
+			slog.Warn("Could not detect the default branch, assuming main", "url", opts.URL, "error", err)
+			opts.Branch = "main"
+		} else {
+			slog.Info("Detected default branch", "name", opts.Name, "branch", opts.Branch)
+		}
 	}
 
 	// Compute local path from data dir and repo name
@@ -114,52 +183,91 @@ func (s *RepoService) Add(ctx context.Context, opts AddOptions) (*db.Repository,
 	slog.Info("Cloning repository as bare mirror", "url", opts.URL, "path", localPath, "private", opts.Private)
 
 	// Clone repository as bare mirror (with auth if private)
+	cloneSpan := gitSpan(ctx, "clone", opts.Name)
 	if opts.Private {
-		token, err := s.tokenProvider.GetToken()
+		var token string
+		token, err = s.tokenProvider.GetToken()
 		if err != nil {
+			telemetry.End(cloneSpan, err)
 			return nil, fmt.Errorf("failed to get GitHub token: %w", err)
 		}
-		if err := git.CloneMirrorWithAuth(opts.URL, localPath, token); err != nil {
-			return nil, fmt.Errorf("failed to clone repository: %w", err)
-		}
+		err = git.CloneMirrorWithAuth(opts.URL, localPath, token)
 	} else {
-		if err := git.CloneMirror(opts.URL, localPath); err != nil {
-			return nil, fmt.Errorf("failed to clone repository: %w", err)
-		}
+		err = git.CloneMirror(opts.URL, localPath)
+	}
+	telemetry.End(cloneSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	// Analysis reads HEAD, which the mirror points at the default branch
+	if _, err = git.GetBranchSHA(localPath, opts.Branch); err != nil {
+		os.RemoveAll(localPath)
+		return nil, fmt.Errorf("branch %s not found in %s", opts.Branch, opts.URL)
+	}
+	if err = git.SetHEAD(localPath, opts.Branch); err != nil {
+		os.RemoveAll(localPath)
+		return nil, fmt.Errorf("failed to switch to branch %s: %w", opts.Branch, err)
 	}
 
 	// Generate description from README
-	var description sql.NullString
+	var description, readmeHash sql.NullString
 	slog.Info("Generating description from README")
-	desc, err := s.generateDescription(ctx, localPath)
+	readme, err := findAndReadREADME(localPath)
+	if err == nil {
+		var desc string
+		desc, err = s.generateDescription(ctx, readme)
+		if err == nil && desc != "" {
+			description = sql.NullString{String: desc, Valid: true}
+			readmeHash = sql.NullString{String: hashREADME(readme), Valid: true}
+		}
+	}
 	if err != nil {
 		slog.Warn("Could not generate description", "error", err)
-	} else if desc != "" {
-		description = sql.NullString{String: desc, Valid: true}
 	}
 
-	// Create database entry
-	repo, err := s.db.CreateRepository(opts.Name, opts.URL, opts.Branch, opts.Private, description)
+	// Create database entry, checking again for a repository added while cloning
+	var repo *db.Repository
+	err = s.db.WithTx(ctx, func(tx *db.DB) error {
+		if _, err := tx.GetRepositoryByName(db.WithWorkspace(ctx, 0), opts.Name); err == nil {
+			return fmt.Errorf("repository '%s' already exists", opts.Name)
+		}
+		repo, err = tx.CreateRepository(ctx, opts.Name, opts.URL, opts.Branch, opts.Private, description)
+		if err != nil || !readmeHash.Valid {
+			return err
+		}
+		repo.ReadmeHash = readmeHash
+		return tx.SetRepositoryDescription(ctx, repo.ID, description, readmeHash)
+	})
 	if err != nil {
 		// Clean up cloned directory on failure
 		os.RemoveAll(localPath)
 		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
 
+	if sha, err := git.GetBranchSHA(localPath, repo.Branch); err == nil {
+		s.recordLastCommit(ctx, repo, sha)
+	}
+
 	slog.Info("Repository added", "name", opts.Name, "id", repo.ID)
 	return repo, nil
 }
 
 // Remove deletes a repository
-func (s *RepoService) Remove(name string, keepFiles bool) error {
-	repo, err := s.db.GetRepositoryByName(name)
+func (s *RepoService) Remove(ctx context.Context, name string, keepFiles bool) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", name)
 	}
 
-	if err := s.db.DeleteRepository(repo.ID); err != nil {
+	blobs, err := s.db.ListRawDataBlobs(ctx, time.Time{}, repo.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.DeleteRepository(ctx, repo.ID); err != nil {
 		return fmt.Errorf("failed to delete repository: %w", err)
 	}
+	deleteBlobs(ctx, s.blobs, blobs)
 
 	if !keepFiles {
 		repoPath := s.repoPath(repo.Name)
@@ -174,8 +282,8 @@ func (s *RepoService) Remove(name string, keepFiles bool) error {
 }
 
 // Activate enables a repository for analysis
-func (s *RepoService) Activate(name string) error {
-	repo, err := s.db.GetRepositoryByName(name)
+func (s *RepoService) Activate(ctx context.Context, name string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", name)
 	}
@@ -184,7 +292,7 @@ func (s *RepoService) Activate(name string) error {
 		return nil // Already active
 	}
 
-	if err := s.db.SetRepositoryActive(repo.ID, true); err != nil {
+	if err := s.db.SetRepositoryActive(ctx, repo.ID, true); err != nil {
 		return fmt.Errorf("failed to activate repository: %w", err)
 	}
 
@@ -193,8 +301,8 @@ func (s *RepoService) Activate(name string) error {
 }
 
 // Deactivate disables a repository for analysis
-func (s *RepoService) Deactivate(name string) error {
-	repo, err := s.db.GetRepositoryByName(name)
+func (s *RepoService) Deactivate(ctx context.Context, name string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", name)
 	}
@@ -203,7 +311,7 @@ func (s *RepoService) Deactivate(name string) error {
 		return nil // Already inactive
 	}
 
-	if err := s.db.SetRepositoryActive(repo.ID, false); err != nil {
+	if err := s.db.SetRepositoryActive(ctx, repo.ID, false); err != nil {
 		return fmt.Errorf("failed to deactivate repository: %w", err)
 	}
 
@@ -212,8 +320,8 @@ func (s *RepoService) Deactivate(name string) error {
 }
 
 // SetURL updates the remote URL for a repository
-func (s *RepoService) SetURL(name, newURL string) error {
-	repo, err := s.db.GetRepositoryByName(name)
+func (s *RepoService) SetURL(ctx context.Context, name, newURL string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
 		return fmt.Errorf("repository not found: %s", name)
 	}
@@ -228,7 +336,7 @@ func (s *RepoService) SetURL(name, newURL string) error {
 
 	// Update database
 	repo.URL = newURL
-	if err := s.db.UpdateRepository(repo); err != nil {
+	if err := s.db.UpdateRepository(ctx, repo); err != nil {
 		// Try to rollback git remote on DB failure
 		_ = git.SetRemoteURL(repoPath, oldURL)
 		return fmt.Errorf("failed to update database: %w", err)
@@ -238,18 +346,62 @@ func (s *RepoService) SetURL(name, newURL string) error {
 	return nil
 }
 
+// maxContextNotesLength bounds context notes, which are sent with every
+// analysis prompt
+const maxContextNotesLength = 4000
+
+// SetContextNotes sets the notes added to a repository's analyzer prompts.
+// Empty notes remove them.
+func (s *RepoService) SetContextNotes(ctx context.Context, name, notes string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("repository not found: %s", name)
+	}
+
+	notes = strings.TrimSpace(strings.ReplaceAll(notes, "\r\n", "\n"))
+	if len(notes) > maxContextNotesLength {
+		return fmt.Errorf("context notes are too long (%d characters, max %d)", len(notes), maxContextNotesLength)
+	}
+
+	if err := s.db.SetRepositoryContextNotes(ctx, repo.ID, sql.NullString{String: notes, Valid: notes != ""}); err != nil {
+		return err
+	}
+
+	slog.Info("Repository context notes updated", "name", name, "length", len(notes))
+	return nil
+}
+
+// SetVisibility sets who may read a repository's reports in the web UI:
+// db.VisibilityPublic, db.VisibilityAuthenticated or db.VisibilityAdmin
+func (s *RepoService) SetVisibility(ctx context.Context, name, visibility string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("repository not found: %s", name)
+	}
+
+	if err := s.db.SetRepositoryVisibility(ctx, repo.ID, visibility); err != nil {
+		return err
+	}
+
+	slog.Info("Repository visibility updated", "name", name, "visibility", visibility)
+	return nil
+}
+
 // UpdateResult contains the result of updating a repository
 type UpdateResult struct {
-	Name          string
-	BeforeSHA     string
-	AfterSHA      string
-	CommitCount   int
+	Name            string
+	BeforeSHA       string
+	AfterSHA        string
+	CommitCount     int
 	AlreadyUpToDate bool
 }
 
 // Update fetches the latest changes for a repository
-func (s *RepoService) Update(ctx context.Context, name string) (*UpdateResult, error) {
-	repo, err := s.db.GetRepositoryByName(name)
+func (s *RepoService) Update(ctx context.Context, name string) (_ *UpdateResult, err error) {
+	ctx, span := startSpan(ctx, "RepoService.Update", name)
+	defer func() { telemetry.End(span, err) }()
+
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("repository not found: %s", name)
 	}
@@ -269,36 +421,28 @@ func (s *RepoService) Update(ctx context.Context, name string) (*UpdateResult, e
 		return nil, fmt.Errorf("failed to get current SHA: %w", err)
 	}
 
-	// Fetch updates (with auth if private)
-	if repo.Private {
-		if s.tokenProvider == nil {
-			return nil, fmt.Errorf("repository '%s' is private but no GitHub App is configured", name)
-		}
-		token, err := s.tokenProvider.GetToken()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get GitHub token: %w", err)
-		}
-		if err := git.FetchWithAuth(repoPath, repo.URL, token); err != nil {
-			return nil, fmt.Errorf("failed to fetch: %w", err)
-		}
-	} else {
-		if err := git.Fetch(repoPath); err != nil {
-			return nil, fmt.Errorf("failed to fetch: %w", err)
-		}
+	if err := s.fetch(ctx, repo); err != nil {
+		return nil, err
 	}
 
-	// Get SHA after fetch for the tracked branch
+	// Get SHA after fetch for the tracked branch. The fetch prunes branches
+	// deleted upstream, such as a default branch renamed from master to main.
 	afterSHA, err := git.GetBranchSHA(repoPath, repo.Branch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updated SHA: %w", err)
+		afterSHA, err = s.followDefaultBranch(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get updated SHA: %w", err)
+		}
 	}
 
 	// Update repository timestamp
 	repo.UpdatedAt = time.Now()
-	if err := s.db.UpdateRepository(repo); err != nil {
+	if err := s.db.UpdateRepository(ctx, repo); err != nil {
 		return nil, fmt.Errorf("failed to update repository: %w", err)
 	}
 
+	s.recordLastCommit(ctx, repo, afterSHA)
+
 	result := &UpdateResult{
 		Name:      name,
 		BeforeSHA: beforeSHA,
@@ -320,10 +464,144 @@ func (s *RepoService) Update(ctx context.Context, name string) (*UpdateResult, e
 	return result, nil
 }
 
+// fetch fetches all refs of a repository's clone, with auth if private
+func (s *RepoService) fetch(ctx context.Context, repo *db.Repository) (err error) {
+	fetchSpan := gitSpan(ctx, "fetch", repo.Name)
+	defer func() { telemetry.End(fetchSpan, err) }()
+
+	repoPath := s.repoPath(repo.Name)
+	if repo.Private {
+		var token string
+		if token, err = s.token(repo); err != nil {
+			return err
+		}
+		err = git.FetchWithAuth(repoPath, repo.URL, token)
+	} else {
+		err = git.Fetch(repoPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	return nil
+}
+
+// token returns a GitHub token for a private repository
+func (s *RepoService) token(repo *db.Repository) (string, error) {
+	if s.tokenProvider == nil {
+		return "", fmt.Errorf("repository '%s' is private but no GitHub App is configured", repo.Name)
+	}
+	token, err := s.tokenProvider.GetToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to get GitHub token: %w", err)
+	}
+	return token, nil
+}
+
+// remoteDefaultBranch asks the remote of a repository for its default branch
+func (s *RepoService) remoteDefaultBranch(repo *db.Repository) (string, error) {
+	if repo.Private {
+		token, err := s.token(repo)
+		if err != nil {
+			return "", err
+		}
+		return git.GetRemoteDefaultBranchWithAuth(repo.URL, token)
+	}
+	return git.GetRemoteDefaultBranch(repo.URL)
+}
+
+// followDefaultBranch handles a tracked branch that no longer exists in the
+// clone after a fetch. If the remote's default branch is another one, the
+// repository is switched to it when follow_default_branch is set, and the
+// switch is recorded in the audit log; otherwise the error names the branch
+// to switch to. It returns the SHA of the branch now tracked.
+func (s *RepoService) followDefaultBranch(ctx context.Context, repo *db.Repository) (string, error) {
+	defaultBranch, err := s.remoteDefaultBranch(repo)
+	if err != nil {
+		return "", fmt.Errorf("branch %s not found, and failed to detect the default branch: %w", repo.Branch, err)
+	}
+	if defaultBranch == repo.Branch {
+		return "", fmt.Errorf("branch %s not found", repo.Branch)
+	}
+	if !s.cfg.Current().FollowDefaultBranch {
+		return "", fmt.Errorf("branch %s no longer exists upstream, whose default branch is now %s "+
+			"(run `activity repo set-branch %s %s` or set follow_default_branch)", repo.Branch, defaultBranch, repo.Name, defaultBranch)
+	}
+
+	oldBranch := repo.Branch
+	if err := s.switchBranch(ctx, repo, defaultBranch); err != nil {
+		return "", err
+	}
+	slog.Warn("Tracked branch deleted upstream, switched to the default branch",
+		"name", repo.Name, "old_branch", oldBranch, "new_branch", defaultBranch)
+
+	details := fmt.Sprintf("%s -> %s (default branch changed upstream)", oldBranch, defaultBranch)
+	if err := s.db.RecordAudit(db.WithWorkspace(ctx, repo.WorkspaceID), "system", "repo.set_branch", repo.Name, details); err != nil {
+		slog.Error("Failed to record audit entry", "action", "repo.set_branch", "target", repo.Name, "error", err)
+	}
+
+	return git.GetBranchSHA(s.repoPath(repo.Name), defaultBranch)
+}
+
+// SetBranch changes the branch a repository's reports follow, keeping its
+// reports and run history. The clone is fetched if it does not have the
+// branch yet, and its HEAD is switched to it, since analysis reads HEAD.
+func (s *RepoService) SetBranch(ctx context.Context, name, branch string) error {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("repository not found: %s", name)
+	}
+	if branch == repo.Branch {
+		return nil
+	}
+
+	if err := s.ensureRepoReady(repo); err != nil {
+		return fmt.Errorf("failed to ensure repo ready: %w", err)
+	}
+	repoPath := s.repoPath(repo.Name)
+	if _, err := git.GetBranchSHA(repoPath, branch); err != nil {
+		if err := s.fetch(ctx, repo); err != nil {
+			return err
+		}
+		if _, err := git.GetBranchSHA(repoPath, branch); err != nil {
+			return fmt.Errorf("branch %s not found in %s", branch, name)
+		}
+	}
+
+	oldBranch := repo.Branch
+	if err := s.switchBranch(ctx, repo, branch); err != nil {
+		return err
+	}
+	if sha, err := git.GetBranchSHA(repoPath, branch); err == nil {
+		s.recordLastCommit(ctx, repo, sha)
+	}
+
+	slog.Info("Repository branch updated", "name", name, "old_branch", oldBranch, "new_branch", branch)
+	return nil
+}
+
+// switchBranch points the clone's HEAD at a branch the clone has and saves
+// it as the repository's tracked branch
+func (s *RepoService) switchBranch(ctx context.Context, repo *db.Repository, branch string) error {
+	repoPath := s.repoPath(repo.Name)
+	if err := git.SetHEAD(repoPath, branch); err != nil {
+		return fmt.Errorf("failed to switch branch: %w", err)
+	}
+
+	oldBranch := repo.Branch
+	repo.Branch = branch
+	if err := s.db.UpdateRepository(ctx, repo); err != nil {
+		// Try to rollback HEAD on DB failure
+		_ = git.SetHEAD(repoPath, oldBranch)
+		repo.Branch = oldBranch
+		return fmt.Errorf("failed to update database: %w", err)
+	}
+	return nil
+}
+
 // UpdateAll updates all active repositories
 func (s *RepoService) UpdateAll(ctx context.Context) ([]*UpdateResult, error) {
 	activeOnly := true
-	repos, err := s.db.ListRepositories(&activeOnly)
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
@@ -342,28 +620,97 @@ func (s *RepoService) UpdateAll(ctx context.Context) ([]*UpdateResult, error) {
 }
 
 // List returns all repositories
-func (s *RepoService) List(activeOnly *bool) ([]*db.Repository, error) {
-	return s.db.ListRepositories(activeOnly)
+func (s *RepoService) List(ctx context.Context, activeOnly *bool) ([]*db.Repository, error) {
+	return s.db.ListRepositories(ctx, activeOnly)
 }
 
 // Get returns a repository by name
-func (s *RepoService) Get(name string) (*db.Repository, error) {
-	return s.db.GetRepositoryByName(name)
+func (s *RepoService) Get(ctx context.Context, name string) (*db.Repository, error) {
+	return s.db.GetRepositoryByName(ctx, name)
 }
 
 // GetByID returns a repository by ID
-func (s *RepoService) GetByID(id int64) (*db.Repository, error) {
-	return s.db.GetRepository(id)
+func (s *RepoService) GetByID(ctx context.Context, id int64) (*db.Repository, error) {
+	return s.db.GetRepository(ctx, id)
+}
+
+// DescribeResult contains the result of refreshing a repository's description
+type DescribeResult struct {
+	Name        string
+	Description string
+	Regenerated bool // False if the README was unchanged and the description kept
 }
 
-// generateDescription reads the README and uses LLM to generate a project description
-func (s *RepoService) generateDescription(ctx context.Context, repoPath string) (string, error) {
-	// Try to find README file
-	readmeContent, err := findAndReadREADME(repoPath)
+// Describe regenerates a repository's description from the README in its
+// local clone. With onlyIfChanged the description is kept when the README
+// hash matches the one it was last generated from.
+func (s *RepoService) Describe(ctx context.Context, name string, onlyIfChanged bool) (*DescribeResult, error) {
+	repo, err := s.db.GetRepositoryByName(ctx, name)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("repository not found: %s", name)
+	}
+
+	readme, err := findAndReadREADME(s.repoPath(repo.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DescribeResult{Name: repo.Name, Description: repo.Description.String}
+	hash := hashREADME(readme)
+	if onlyIfChanged && repo.ReadmeHash.Valid && repo.ReadmeHash.String == hash {
+		return result, nil
+	}
+
+	desc, err := s.generateDescription(ctx, readme)
+	if err != nil {
+		return nil, err
+	}
+	if desc == "" {
+		return nil, fmt.Errorf("LLM returned an empty description")
 	}
 
+	description := sql.NullString{String: desc, Valid: true}
+	if err := s.db.SetRepositoryDescription(ctx, repo.ID, description, sql.NullString{String: hash, Valid: true}); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Repository description updated", "name", repo.Name)
+	result.Description = desc
+	result.Regenerated = true
+	return result, nil
+}
+
+// DescribeAll refreshes the descriptions of all active repositories.
+// Repositories that fail, e.g. because they have no README, are logged and
+// skipped.
+func (s *RepoService) DescribeAll(ctx context.Context, onlyIfChanged bool) ([]*DescribeResult, error) {
+	activeOnly := true
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	var results []*DescribeResult
+	for _, repo := range repos {
+		result, err := s.Describe(ctx, repo.Name, onlyIfChanged)
+		if err != nil {
+			slog.Warn("Failed to refresh repository description", "name", repo.Name, "error", err)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// hashREADME returns the hex SHA-256 of README content
+func hashREADME(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateDescription uses the LLM to generate a project description from README content
+func (s *RepoService) generateDescription(ctx context.Context, readmeContent string) (string, error) {
 	// Truncate if too long (max 4000 chars)
 	if len(readmeContent) > 4000 {
 		readmeContent = readmeContent[:4000]
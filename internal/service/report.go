@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"runtime"
 	"time"
 
 	"github.com/perbu/activity/internal/analyzer"
@@ -14,6 +15,9 @@ import (
 	"github.com/perbu/activity/internal/git"
 	"github.com/perbu/activity/internal/github"
 	"github.com/perbu/activity/internal/llm"
+	"github.com/perbu/activity/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
 // ReportService handles weekly report generation
@@ -21,14 +25,21 @@ type ReportService struct {
 	db            *db.DB
 	cfg           *config.Config
 	tokenProvider *github.TokenProvider
+	search        *SearchService
+	newsletter    *NewsletterService
 }
 
-// NewReportService creates a new ReportService
-func NewReportService(database *db.DB, cfg *config.Config, tokenProvider *github.TokenProvider) *ReportService {
+// NewReportService creates a new ReportService. Generated reports are indexed
+// for semantic search through the given SearchService (may be nil), and
+// corrections of regenerated reports that were already sent go out through
+// the given NewsletterService (may be nil).
+func NewReportService(database *db.DB, cfg *config.Config, tokenProvider *github.TokenProvider, search *SearchService, newsletter *NewsletterService) *ReportService {
 	return &ReportService{
 		db:            database,
 		cfg:           cfg,
 		tokenProvider: tokenProvider,
+		search:        search,
+		newsletter:    newsletter,
 	}
 }
 
@@ -37,6 +48,15 @@ func (s *ReportService) repoPath(repoName string) string {
 	return db.RepoLocalPath(s.cfg.DataDir, repoName)
 }
 
+// reviewState returns the review state of newly generated summaries: drafts
+// unless the newsletter auto-approves reports
+func (s *ReportService) reviewState() string {
+	if s.cfg.Current().Newsletter.AutoApprove {
+		return db.ReviewApproved
+	}
+	return db.ReviewDraft
+}
+
 // GenerateOptions contains options for report generation
 type GenerateOptions struct {
 	RepoName string // Repository name (or empty for all active repos)
@@ -47,17 +67,79 @@ type GenerateOptions struct {
 
 // GenerateResult contains the result of report generation
 type GenerateResult struct {
-	Generated  int
-	Skipped    int
-	NoCommits  int
-	RepoName   string
-	WeekLabel  string
-	ReportID   int64
+	Generated int
+	Skipped   int
+	NoCommits int
+	RepoName  string
+	WeekLabel string
+	ReportID  int64
+}
+
+// Generate generates the reports selected by opts: all weeks since
+// opts.Since, the week opts.Week, or the previous complete week, for
+// opts.RepoName or all active repositories
+func (s *ReportService) Generate(ctx context.Context, opts GenerateOptions) ([]*GenerateResult, error) {
+	if opts.Week != "" && opts.Since != "" {
+		return nil, fmt.Errorf("use either a week or a since date, not both")
+	}
+
+	if opts.Since != "" {
+		if opts.RepoName == "" {
+			return s.GenerateAllReposSince(ctx, opts.Since, opts.Force)
+		}
+		result, err := s.GenerateSince(ctx, opts.RepoName, opts.Since, opts.Force)
+		if err != nil {
+			return nil, err
+		}
+		return []*GenerateResult{result}, nil
+	}
+
+	if opts.Week == "" && opts.RepoName == "" {
+		return s.GenerateLastWeek(ctx, opts.Force)
+	}
+	weekStr := opts.Week
+	if weekStr == "" {
+		year, week := time.Now().ISOWeek()
+		weekStr = git.FormatISOWeek(previousWeek(year, week))
+	}
+
+	names := []string{opts.RepoName}
+	if opts.RepoName == "" {
+		activeOnly := true
+		repos, err := s.db.ListRepositories(ctx, &activeOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories: %w", err)
+		}
+		names = names[:0]
+		for _, repo := range repos {
+			names = append(names, repo.Name)
+		}
+	}
+
+	var results []*GenerateResult
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("report generation cancelled: %w", err)
+		}
+		result, err := s.GenerateForWeek(ctx, name, weekStr, opts.Force)
+		if err != nil {
+			if opts.RepoName != "" {
+				return nil, err
+			}
+			slog.Error("Failed to generate report", "repo", name, "error", err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
 }
 
 // GenerateForWeek generates a report for a specific ISO week
-func (s *ReportService) GenerateForWeek(ctx context.Context, repoName string, weekStr string, force bool) (*GenerateResult, error) {
-	repo, err := s.db.GetRepositoryByName(repoName)
+func (s *ReportService) GenerateForWeek(ctx context.Context, repoName string, weekStr string, force bool) (_ *GenerateResult, err error) {
+	ctx, span := startSpan(ctx, "ReportService.GenerateForWeek", repoName, attribute.String("week", weekStr))
+	defer func() { telemetry.End(span, err) }()
+
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
 	if err != nil {
 		return nil, fmt.Errorf("repository not found: %s", repoName)
 	}
@@ -68,7 +150,7 @@ func (s *ReportService) GenerateForWeek(ctx context.Context, repoName string, we
 	}
 
 	// Check if report exists
-	exists, err := s.db.WeeklyReportExists(repo.ID, year, week)
+	exists, err := s.db.WeeklyReportExists(ctx, repo.ID, year, week)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing report: %w", err)
 	}
@@ -78,33 +160,39 @@ func (s *ReportService) GenerateForWeek(ctx context.Context, repoName string, we
 	}
 
 	// Fetch all remote branches
-	if err := s.fetchBranches(repo); err != nil {
+	if err := s.fetchBranches(ctx, repo); err != nil {
 		slog.Warn("Failed to fetch branches", "error", err)
 	}
 
 	repoPath := s.repoPath(repo.Name)
 
 	// Get commits for this week
-	commits, err := git.GetCommitsForWeek(repoPath, year, week)
+	logSpan := gitSpan(ctx, "log", repo.Name)
+	commits, err := git.GetCommitsForWeekWithOptions(repoPath, year, week, s.logOptions(repo.Name))
+	telemetry.End(logSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commits for %s: %w", weekStr, err)
 	}
 
+	commits, automated := s.filterCommits(ctx, repo, commits)
 	if len(commits) == 0 {
 		return &GenerateResult{NoCommits: 1, RepoName: repoName, WeekLabel: weekStr}, nil
 	}
 
 	// Get feature branch activity
+	branchSpan := gitSpan(ctx, "branch_activity", repo.Name)
 	branchActivity, err := git.GetFeatureBranchActivity(repoPath, repo.Branch, year, week)
+	telemetry.End(branchSpan, err)
 	if err != nil {
 		slog.Warn("Failed to get branch activity", "week", weekStr, "error", err)
 		branchActivity = nil
 	}
+	branchActivity = s.filterBranchActivity(ctx, repo, branchActivity)
 
 	slog.Info("Analyzing commits", "week", weekStr, "commits", len(commits), "branches", len(branchActivity))
 
 	// Generate report
-	report, err := s.generateWeeklyReport(ctx, repo, year, week, commits, branchActivity, exists)
+	report, err := s.generateWeeklyReport(ctx, repo, year, week, commits, automated, branchActivity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate report: %w", err)
 	}
@@ -118,8 +206,11 @@ func (s *ReportService) GenerateForWeek(ctx context.Context, repoName string, we
 }
 
 // GenerateSince generates reports for all weeks since a date
-func (s *ReportService) GenerateSince(ctx context.Context, repoName string, sinceDate string, force bool) (*GenerateResult, error) {
-	repo, err := s.db.GetRepositoryByName(repoName)
+func (s *ReportService) GenerateSince(ctx context.Context, repoName string, sinceDate string, force bool) (_ *GenerateResult, err error) {
+	ctx, span := startSpan(ctx, "ReportService.GenerateSince", repoName, attribute.String("since", sinceDate))
+	defer func() { telemetry.End(span, err) }()
+
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
 	if err != nil {
 		return nil, fmt.Errorf("repository not found: %s", repoName)
 	}
@@ -133,12 +224,10 @@ func (s *ReportService) GenerateSince(ctx context.Context, repoName string, sinc
 	slog.Info("Generating reports", "count", len(weeksToGenerate), "repo", repoName)
 
 	// Fetch all remote branches
-	if err := s.fetchBranches(repo); err != nil {
+	if err := s.fetchBranches(ctx, repo); err != nil {
 		slog.Warn("Failed to fetch branches", "error", err)
 	}
 
-	repoPath := s.repoPath(repo.Name)
-
 	// Initialize LLM client once for all reports
 	llmClient, err := llm.NewClient(ctx, s.cfg)
 	if err != nil {
@@ -150,67 +239,172 @@ func (s *ReportService) GenerateSince(ctx context.Context, repoName string, sinc
 
 	result := &GenerateResult{RepoName: repoName}
 
-	for _, yw := range weeksToGenerate {
-		year, wk := yw[0], yw[1]
-		weekStr := git.FormatISOWeek(year, wk)
-
-		// Check if report exists
-		exists, err := s.db.WeeklyReportExists(repo.ID, year, wk)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check existing report: %w", err)
-		}
+	// Collect the commits of every week first, in parallel, then analyze the
+	// weeks with commits a few at a time, oldest first. Each summary gets the
+	// previous week's as context, so a week whose previous week is generated
+	// in this run waits for that report; weeks after a gap run alongside.
+	weeks, err := s.collectWeeks(ctx, repo, weeksToGenerate, force)
+	if err != nil {
+		return nil, err
+	}
 
-		if exists && !force {
+	reports := make([]*db.WeeklyReport, len(weeks))
+	done := make([]chan struct{}, len(weeks))
+	g := new(errgroup.Group)
+	g.SetLimit(s.cfg.GetBackfillConcurrency())
+	for i, w := range weeks {
+		switch {
+		case w.skipped:
 			result.Skipped++
 			continue
-		}
-
-		// Get commits for this week
-		commits, err := git.GetCommitsForWeek(repoPath, year, wk)
-		if err != nil {
-			slog.Error("Failed to get commits", "week", weekStr, "error", err)
-			continue
-		}
-
-		if len(commits) == 0 {
-			result.NoCommits++
+		case len(w.commits) == 0:
+			if w.err == nil {
+				result.NoCommits++
+			}
 			continue
 		}
 
-		// Get feature branch activity
-		branchActivity, err := git.GetFeatureBranchActivity(repoPath, repo.Branch, year, wk)
-		if err != nil {
-			slog.Warn("Failed to get branch activity", "week", weekStr, "error", err)
-			branchActivity = nil
+		// Weeks are started oldest first, so the previous week already holds
+		// a slot and waiting for it cannot deadlock
+		done[i] = make(chan struct{})
+		var previous chan struct{}
+		if i > 0 {
+			previous = done[i-1]
 		}
 
-		slog.Info("Analyzing commits", "week", weekStr, "commits", len(commits), "branches", len(branchActivity))
-
-		// Generate report using shared analyzer
-		report, err := s.generateWeeklyReportWithAnalyzer(ctx, llmAnalyzer, repo, year, wk, commits, branchActivity, exists)
-		if err != nil {
-			slog.Error("Failed to generate report", "week", weekStr, "error", err)
+		g.Go(func() error {
+			defer close(done[i])
+
+			previousSummary := ""
+			if previous != nil {
+				<-previous
+				if prev := reports[i-1]; prev != nil && prev.Summary.Valid {
+					previousSummary = prev.Summary.String
+				}
+			}
+
+			// Stop the backfill promptly when cancelled instead of failing every remaining week
+			if ctx.Err() != nil {
+				return nil
+			}
+			if previousSummary == "" {
+				previousSummary = s.previousSummary(ctx, repo, w.year, w.week)
+			}
+
+			weekStr := git.FormatISOWeek(w.year, w.week)
+			slog.Info("Analyzing commits", "week", weekStr, "commits", len(w.commits), "branches", len(w.branchActivity))
+
+			// Generate report using shared analyzer
+			report, err := s.generateWeeklyReportWithAnalyzer(ctx, llmAnalyzer, repo, w.year, w.week, w.commits, w.automated, w.branchActivity, previousSummary)
+			if err != nil {
+				slog.Error("Failed to generate report", "week", weekStr, "error", err)
+				return nil
+			}
+			reports[i] = report
+			return nil
+		})
+	}
+	g.Wait()
+
+	for i, report := range reports {
+		if report == nil {
 			continue
 		}
-
 		result.Generated++
 		result.ReportID = report.ID
-		result.WeekLabel = weekStr
+		result.WeekLabel = git.FormatISOWeek(weeks[i].year, weeks[i].week)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("report generation cancelled after %d reports: %w", result.Generated, err)
 	}
 
 	return result, nil
 }
 
+// backfillWeek is a week of a backfill and the activity to analyze for it
+type backfillWeek struct {
+	year, week     int
+	skipped        bool // Report exists and is not regenerated
+	err            error
+	commits        []git.Commit
+	automated      []git.Commit
+	branchActivity []git.BranchActivity
+}
+
+// collectWeeks reads the commits and feature branch activity of the given
+// weeks from the local clone, several weeks at a time. Weeks whose commits
+// cannot be read are logged and returned with err set.
+func (s *ReportService) collectWeeks(ctx context.Context, repo *db.Repository, yearWeeks [][2]int, force bool) ([]backfillWeek, error) {
+	repoPath := s.repoPath(repo.Name)
+	weeks := make([]backfillWeek, len(yearWeeks))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for i, yw := range yearWeeks {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("report generation cancelled: %w", err)
+			}
+			w := &weeks[i]
+			w.year, w.week = yw[0], yw[1]
+			weekStr := git.FormatISOWeek(w.year, w.week)
+
+			// Check if report exists
+			exists, err := s.db.WeeklyReportExists(gctx, repo.ID, w.year, w.week)
+			if err != nil {
+				return fmt.Errorf("failed to check existing report: %w", err)
+			}
+			if exists && !force {
+				w.skipped = true
+				return nil
+			}
+
+			// Get commits for this week
+			logSpan := gitSpan(gctx, "log", repo.Name)
+			commits, err := git.GetCommitsForWeekWithOptions(repoPath, w.year, w.week, s.logOptions(repo.Name))
+			telemetry.End(logSpan, err)
+			if err != nil {
+				slog.Error("Failed to get commits", "week", weekStr, "error", err)
+				w.err = err
+				return nil
+			}
+
+			w.commits, w.automated = s.filterCommits(ctx, repo, commits)
+			if len(w.commits) == 0 {
+				return nil
+			}
+
+			// Get feature branch activity
+			branchSpan := gitSpan(gctx, "branch_activity", repo.Name)
+			branchActivity, err := git.GetFeatureBranchActivity(repoPath, repo.Branch, w.year, w.week)
+			telemetry.End(branchSpan, err)
+			if err != nil {
+				slog.Warn("Failed to get branch activity", "week", weekStr, "error", err)
+				branchActivity = nil
+			}
+			w.branchActivity = s.filterBranchActivity(ctx, repo, branchActivity)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return weeks, nil
+}
+
 // GenerateAllReposSince generates reports for all active repos since a date
 func (s *ReportService) GenerateAllReposSince(ctx context.Context, sinceDate string, force bool) ([]*GenerateResult, error) {
 	activeOnly := true
-	repos, err := s.db.ListRepositories(&activeOnly)
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
 
 	var results []*GenerateResult
 	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("report generation cancelled: %w", err)
+		}
 		result, err := s.GenerateSince(ctx, repo.Name, sinceDate, force)
 		if err != nil {
 			slog.Error("Failed to generate reports", "repo", repo.Name, "error", err)
@@ -239,13 +433,16 @@ func (s *ReportService) GenerateLastWeek(ctx context.Context, force bool) ([]*Ge
 	weekStr := git.FormatISOWeek(year, week)
 
 	activeOnly := true
-	repos, err := s.db.ListRepositories(&activeOnly)
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
 
 	var results []*GenerateResult
 	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("report generation cancelled: %w", err)
+		}
 		result, err := s.GenerateForWeek(ctx, repo.Name, weekStr, force)
 		if err != nil {
 			slog.Error("Failed to generate report", "repo", repo.Name, "error", err)
@@ -258,31 +455,56 @@ func (s *ReportService) GenerateLastWeek(ctx context.Context, force bool) ([]*Ge
 }
 
 // GetReport retrieves a report by ID
-func (s *ReportService) GetReport(id int64) (*db.WeeklyReport, error) {
-	return s.db.GetWeeklyReport(id)
+func (s *ReportService) GetReport(ctx context.Context, id int64) (*db.WeeklyReport, error) {
+	return s.db.GetWeeklyReport(ctx, id)
 }
 
 // GetLatestReport retrieves the most recent report for a repository
-func (s *ReportService) GetLatestReport(repoName string) (*db.WeeklyReport, error) {
-	repo, err := s.db.GetRepositoryByName(repoName)
+func (s *ReportService) GetLatestReport(ctx context.Context, repoName string) (*db.WeeklyReport, error) {
+	repo, err := s.db.GetRepositoryByName(ctx, repoName)
 	if err != nil {
 		return nil, fmt.Errorf("repository not found: %s", repoName)
 	}
-	return s.db.GetLatestWeeklyReport(repo.ID)
+	return s.db.GetLatestWeeklyReport(ctx, repo.ID)
 }
 
 // ListReports retrieves reports for a repository
-func (s *ReportService) ListReports(repoID int64, year *int) ([]*db.WeeklyReport, error) {
-	return s.db.ListWeeklyReportsByRepo(repoID, year)
+func (s *ReportService) ListReports(ctx context.Context, repoID int64, year *int) ([]*db.WeeklyReport, error) {
+	return s.db.ListWeeklyReportsByRepo(ctx, repoID, year)
 }
 
 // ListAllReports retrieves all reports
-func (s *ReportService) ListAllReports(year *int) ([]*db.WeeklyReport, error) {
-	return s.db.ListAllWeeklyReports(year)
+func (s *ReportService) ListAllReports(ctx context.Context, year *int) ([]*db.WeeklyReport, error) {
+	return s.db.ListAllWeeklyReports(ctx, year)
+}
+
+// ListPage retrieves the page of reports selected by filter, of one
+// repository if repoName is set, and the number of reports matching the
+// filter on all pages
+func (s *ReportService) ListPage(ctx context.Context, repoName string, filter db.ReportFilter) ([]*db.WeeklyReport, int, error) {
+	if repoName != "" {
+		repo, err := s.db.GetRepositoryByName(ctx, repoName)
+		if err != nil {
+			return nil, 0, fmt.Errorf("repository not found: %s", repoName)
+		}
+		filter.RepoID = repo.ID
+	}
+	total, err := s.db.CountWeeklyReports(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	reports, err := s.db.ListWeeklyReports(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
 }
 
 // fetchBranches fetches all remote branches for a repository
-func (s *ReportService) fetchBranches(repo *db.Repository) error {
+func (s *ReportService) fetchBranches(ctx context.Context, repo *db.Repository) (err error) {
+	span := gitSpan(ctx, "fetch", repo.Name)
+	defer func() { telemetry.End(span, err) }()
+
 	repoPath := s.repoPath(repo.Name)
 	if repo.Private {
 		if s.tokenProvider == nil {
@@ -297,9 +519,45 @@ func (s *ReportService) fetchBranches(repo *db.Repository) error {
 	return git.FetchAll(repoPath)
 }
 
+// logOptions returns the commit collection options configured for a repository
+func (s *ReportService) logOptions(repoName string) git.LogOptions {
+	repoCfg := s.cfg.GetRepoConfig(repoName)
+	return git.LogOptions{
+		FirstParent: repoCfg.FirstParent,
+		NoMerges:    repoCfg.NoMerges,
+	}
+}
+
+// filterCommits merges author identities (database aliases on top of .mailmap)
+// and separates out commits by ignored authors such as bots
+func (s *ReportService) filterCommits(ctx context.Context, repo *db.Repository, commits []git.Commit) (kept, automated []git.Commit) {
+	authorMap, err := loadAuthorMap(ctx, s.db)
+	if err != nil {
+		slog.Warn("Failed to load author aliases", "error", err)
+	}
+	authorMap.ResolveCommits(commits)
+
+	kept, automated = analyzer.FilterIgnoredAuthors(commits, s.cfg.GetIgnoredAuthors(repo.Name))
+	if len(automated) > 0 {
+		slog.Info("Excluding commits by ignored authors", "repo", repo.Name, "excluded", len(automated))
+	}
+	return kept, automated
+}
+
+// filterBranchActivity applies author aliases and the ignore list to feature branch activity
+func (s *ReportService) filterBranchActivity(ctx context.Context, repo *db.Repository, branchActivity []git.BranchActivity) []git.BranchActivity {
+	authorMap, err := loadAuthorMap(ctx, s.db)
+	if err != nil {
+		slog.Warn("Failed to load author aliases", "error", err)
+	}
+	authorMap.ResolveBranchActivity(branchActivity)
+
+	return analyzer.FilterIgnoredBranchActivity(branchActivity, s.cfg.GetIgnoredAuthors(repo.Name))
+}
+
 // generateWeeklyReport generates a report using a new LLM client
 func (s *ReportService) generateWeeklyReport(ctx context.Context, repo *db.Repository,
-	year, week int, commits []git.Commit, branchActivity []git.BranchActivity, exists bool) (*db.WeeklyReport, error) {
+	year, week int, commits, automated []git.Commit, branchActivity []git.BranchActivity) (*db.WeeklyReport, error) {
 
 	llmClient, err := llm.NewClient(ctx, s.cfg)
 	if err != nil {
@@ -308,12 +566,31 @@ func (s *ReportService) generateWeeklyReport(ctx context.Context, repo *db.Repos
 	defer llmClient.Close()
 
 	llmAnalyzer := analyzer.New(llmClient, s.db, s.cfg)
-	return s.generateWeeklyReportWithAnalyzer(ctx, llmAnalyzer, repo, year, week, commits, branchActivity, exists)
+	previousSummary := s.previousSummary(ctx, repo, year, week)
+	return s.generateWeeklyReportWithAnalyzer(ctx, llmAnalyzer, repo, year, week, commits, automated, branchActivity, previousSummary)
+}
+
+// previousSummary returns the stored summary of the week before year/week,
+// given to the analyzer as context, or "" if there is none
+func (s *ReportService) previousSummary(ctx context.Context, repo *db.Repository, year, week int) string {
+	prevYear, prevWeek := previousWeek(year, week)
+	prevReport, err := s.db.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, prevYear, prevWeek)
+	if err != nil || prevReport == nil || !prevReport.Summary.Valid {
+		return ""
+	}
+	return prevReport.Summary.String
 }
 
-// generateWeeklyReportWithAnalyzer generates a report using an existing analyzer
+// generateWeeklyReportWithAnalyzer generates a report using an existing analyzer,
+// with the previous week's summary as context.
+// Commits by ignored authors (automated) are not analyzed but noted in a footnote.
 func (s *ReportService) generateWeeklyReportWithAnalyzer(ctx context.Context, llmAnalyzer *analyzer.Analyzer,
-	repo *db.Repository, year, week int, commits []git.Commit, branchActivity []git.BranchActivity, exists bool) (*db.WeeklyReport, error) {
+	repo *db.Repository, year, week int, commits, automated []git.Commit, branchActivity []git.BranchActivity,
+	previousSummary string) (_ *db.WeeklyReport, err error) {
+
+	ctx, span := startSpan(ctx, "ReportService.generateWeeklyReport", repo.Name,
+		attribute.String("week", git.FormatISOWeek(year, week)), attribute.Int("commits", len(commits)))
+	defer func() { telemetry.End(span, err) }()
 
 	weekStart, weekEnd := git.ISOWeekBounds(year, week)
 
@@ -324,61 +601,148 @@ func (s *ReportService) generateWeeklyReportWithAnalyzer(ctx context.Context, ll
 		fromSHA = commits[len(commits)-1].SHA
 	}
 
-	// Fetch previous week's report for context
-	prevYear, prevWeek := previousWeek(year, week)
-	var previousSummary string
-	prevReport, err := s.db.GetWeeklyReportByRepoAndWeek(repo.ID, prevYear, prevWeek)
-	if err == nil && prevReport != nil && prevReport.Summary.Valid {
-		previousSummary = prevReport.Summary.String
-	}
-
 	// Analyze commits
-	run, err := llmAnalyzer.AnalyzeAndSave(ctx, repo, fromSHA, toSHA, commits, branchActivity, previousSummary)
+	run, err := llmAnalyzer.Analyze(ctx, repo, fromSHA, toSHA, commits, branchActivity, previousSummary)
 	if err != nil {
 		return nil, fmt.Errorf("analysis failed: %w", err)
 	}
+	defer func() {
+		if err != nil {
+			llmAnalyzer.Discard(ctx, run)
+		}
+	}()
+
+	// Add the week's releases, issue activity and CI health and note excluded
+	// automated changes at the end of the summary
+	summary := run.Summary
+	releases := s.weekReleases(repo, weekStart, weekEnd)
+	issues := s.issueActivity(ctx, repo, weekStart, weekEnd)
+	ci := s.ciHealth(ctx, repo, weekStart, weekEnd)
+	if summary.Valid {
+		summary.String += releaseSection(releases) + issueSection(issues) + ciHealthSection(ci, repo.Branch)
+	}
+	if footnote := analyzer.AutomatedChangesFootnote(automated); footnote != "" && summary.Valid {
+		summary.String += footnote
+	}
 
 	// Build metadata
 	metadata := buildReportMetadata(commits)
+	metadata.addAutomated(automated)
+	metadata.addPullRequestLines(s.repoPath(repo.Name), commits)
+	metadata.Releases = releases
+	metadata.Issues = issues
+	metadata.CIHealth = ci
+	churnSpan := gitSpan(ctx, "churn", repo.Name)
+	churn, err := git.GetChurnForWeek(s.repoPath(repo.Name), year, week, s.logOptions(repo.Name))
+	telemetry.End(churnSpan, err)
+	if err != nil {
+		slog.Warn("Failed to compute churn", "repo", repo.Name, "week", git.FormatISOWeek(year, week), "error", err)
+	} else {
+		metadata.Additions = churn.Additions
+		metadata.Deletions = churn.Deletions
+		metadata.FilesChanged = churn.FilesChanged
+	}
 	metadataJSON, _ := json.Marshal(metadata)
 
-	// Create or update report
-	if exists {
-		existingReport, err := s.db.GetWeeklyReportByRepoAndWeek(repo.ID, year, week)
+	// Complete the run and create or update the report linking to it in one
+	// transaction, so a report never points at an unfinished run. The
+	// existing report is looked up again inside it, since it may have been
+	// created or deleted while the commits were being analyzed.
+	var saved *db.WeeklyReport
+	err = s.db.WithTx(ctx, func(tx *db.DB) error {
+		if err := tx.UpdateActivityRun(ctx, run); err != nil {
+			return err
+		}
+		existingReport, err := tx.GetWeeklyReportByRepoAndWeek(ctx, repo.ID, year, week)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get existing report: %w", err)
+			return fmt.Errorf("failed to get existing report: %w", err)
 		}
 
-		existingReport.Summary = run.Summary
-		existingReport.CommitCount = len(commits)
-		existingReport.Metadata = sql.NullString{String: string(metadataJSON), Valid: true}
-		existingReport.AgentMode = run.AgentMode
-		existingReport.ToolUsageStats = run.ToolUsageStats
-		existingReport.SourceRunID = sql.NullInt64{Int64: run.ID, Valid: true}
-
-		if err := s.db.UpdateWeeklyReport(existingReport); err != nil {
-			return nil, fmt.Errorf("failed to update report: %w", err)
+		if existingReport != nil {
+			// Keep the summary recipients were sent until they get a
+			// correction; after earlier regenerations it is already kept
+			if !existingReport.SentSummary.Valid && existingReport.Summary.Valid {
+				recipients, err := tx.ListReportRecipients(ctx, repo.ID, year, week)
+				if err != nil {
+					return err
+				}
+				if len(recipients) > 0 {
+					existingReport.SentSummary = existingReport.Summary
+				}
+			}
+			existingReport.Summary = summary
+			existingReport.CommitCount = len(commits)
+			existingReport.Metadata = sql.NullString{String: string(metadataJSON), Valid: true}
+			existingReport.AgentMode = run.AgentMode
+			existingReport.ToolUsageStats = run.ToolUsageStats
+			existingReport.SourceRunID = sql.NullInt64{Int64: run.ID, Valid: true}
+			// The summary is new, so earlier edits and regeneration notes no
+			// longer apply
+			existingReport.OriginalSummary = sql.NullString{}
+			existingReport.EditedBy = sql.NullString{}
+			existingReport.EditedAt = sql.NullTime{}
+			existingReport.RegenerationNote = sql.NullString{}
+			existingReport.RegeneratedBy = sql.NullString{}
+			existingReport.RegeneratedAt = sql.NullTime{}
+			existingReport.ReviewState = s.reviewState()
+			existingReport.ApprovedBy = sql.NullString{}
+			existingReport.ApprovedAt = sql.NullTime{}
+
+			if err := tx.UpdateWeeklyReport(ctx, existingReport); err != nil {
+				return fmt.Errorf("failed to update report: %w", err)
+			}
+			saved = existingReport
+			return nil
 		}
 
-		return existingReport, nil
+		// Create new report
+		saved, err = tx.CreateWeeklyReport(ctx, &db.WeeklyReport{
+			RepoID:         repo.ID,
+			Year:           year,
+			Week:           week,
+			WeekStart:      weekStart,
+			WeekEnd:        weekEnd,
+			Summary:        summary,
+			CommitCount:    len(commits),
+			Metadata:       sql.NullString{String: string(metadataJSON), Valid: true},
+			AgentMode:      run.AgentMode,
+			ToolUsageStats: run.ToolUsageStats,
+			SourceRunID:    sql.NullInt64{Int64: run.ID, Valid: true},
+			ReviewState:    s.reviewState(),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.indexReports(ctx, saved)
+	s.publishReport(ctx, repo, saved)
+	s.notifyFavorites(ctx, repo, saved)
+	s.sendCorrection(ctx, saved)
+
+	return saved, nil
+}
 
-	// Create new report
-	report := &db.WeeklyReport{
-		RepoID:         repo.ID,
-		Year:           year,
-		Week:           week,
-		WeekStart:      weekStart,
-		WeekEnd:        weekEnd,
-		Summary:        run.Summary,
-		CommitCount:    len(commits),
-		Metadata:       sql.NullString{String: string(metadataJSON), Valid: true},
-		AgentMode:      run.AgentMode,
-		ToolUsageStats: run.ToolUsageStats,
-		SourceRunID:    sql.NullInt64{Int64: run.ID, Valid: true},
+// sendCorrection tells the recipients of a regenerated report what changed.
+// Failures are only logged.
+func (s *ReportService) sendCorrection(ctx context.Context, report *db.WeeklyReport) {
+	if s.newsletter == nil {
+		return
+	}
+	if _, err := s.newsletter.SendCorrection(ctx, report); err != nil {
+		slog.Warn("Failed to send correction of regenerated report", "report_id", report.ID, "error", err)
 	}
+}
 
-	return s.db.CreateWeeklyReport(report)
+// indexReports refreshes the search embeddings of saved reports. Failures are
+// only logged; missing vectors can be filled in later from the admin actions.
+func (s *ReportService) indexReports(ctx context.Context, reports ...*db.WeeklyReport) {
+	if s.search == nil || len(reports) == 0 {
+		return
+	}
+	if _, err := s.search.IndexReports(ctx, reports); err != nil {
+		slog.Warn("Failed to index report embeddings", "error", err)
+	}
 }
 
 // previousWeek returns the previous ISO week, handling year boundaries
@@ -396,27 +760,51 @@ type ReportMetadata struct {
 	Authors      []string       `json:"authors"`
 	CommitSHAs   []string       `json:"commit_shas"`
 	AuthorCounts map[string]int `json:"author_counts"`
+	Additions    int            `json:"additions,omitempty"`
+	Deletions    int            `json:"deletions,omitempty"`
+	FilesChanged int            `json:"files_changed,omitempty"`
+
+	// Commits each person is credited on as a co-author (Co-authored-by
+	// trailers, merged branches). Co-authors are also listed in Authors.
+	CoAuthorCounts map[string]int `json:"co_author_counts,omitempty"`
+
+	// Commits per day by author date, keyed 2006-01-02 in the server's time
+	// zone, for the repository heatmap. Reports generated before this was
+	// recorded don't have it.
+	DailyCommits map[string]int `json:"daily_commits,omitempty"`
+
+	// Merged pull requests and merge commits, counted separately from direct
+	// commits, and the lines added plus deleted by the pull requests
+	PullRequests     int `json:"pull_requests,omitempty"`
+	MergeCommits     int `json:"merge_commits,omitempty"`
+	DirectCommits    int `json:"direct_commits,omitempty"`
+	PullRequestLines int `json:"pull_request_lines,omitempty"`
+
+	// Commits by ignored authors (bots), excluded from the summary and commit count
+	AutomatedCommits      int            `json:"automated_commits,omitempty"`
+	AutomatedAuthorCounts map[string]int `json:"automated_author_counts,omitempty"`
+
+	// Tags made during the week, newest first
+	Releases []git.Tag `json:"releases,omitempty"`
+
+	// Issues opened and closed during the week, if the issues section is enabled
+	Issues *IssueActivity `json:"issues,omitempty"`
+
+	// Outcomes of the week's GitHub Actions runs on the report's branch
+	CIHealth *github.CIHealth `json:"ci_health,omitempty"`
 }
 
-func buildReportMetadata(commits []git.Commit) ReportMetadata {
-	authorSet := make(map[string]bool)
-	authorCounts := make(map[string]int)
-	var shas []string
-
-	for _, c := range commits {
-		authorSet[c.Author] = true
-		authorCounts[c.Author]++
-		shas = append(shas, c.SHA)
-	}
-
-	var authors []string
-	for a := range authorSet {
-		authors = append(authors, a)
+// AvgPullRequestSize returns the average lines changed per merged pull
+// request, or 0 if none were merged
+func (m *ReportMetadata) AvgPullRequestSize() int {
+	if m.PullRequests == 0 {
+		return 0
 	}
+	return m.PullRequestLines / m.PullRequests
+}
 
-	return ReportMetadata{
-		Authors:      authors,
-		CommitSHAs:   shas,
-		AuthorCounts: authorCounts,
-	}
+func buildReportMetadata(commits []git.Commit) ReportMetadata {
+	var metadata ReportMetadata
+	metadata.addCommits(commits)
+	return metadata
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/perbu/activity/internal/blob"
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+)
+
+// RetentionService deletes expired data according to the retention config
+type RetentionService struct {
+	db    *db.DB
+	cfg   *config.Config
+	blobs blob.Store
+}
+
+// NewRetentionService creates a new RetentionService
+func NewRetentionService(database *db.DB, cfg *config.Config) *RetentionService {
+	return &RetentionService{
+		db:    database,
+		cfg:   cfg,
+		blobs: blob.New(cfg),
+	}
+}
+
+// Prune deletes activity runs, newsletter send records and weekly reports
+// older than their configured retention, along with report vectors from
+// embedding models no longer in use, and clears the raw data of runs past
+// its own retention, deleting offloaded blobs. With dryRun set nothing is
+// deleted and the result reports what would be.
+func (s *RetentionService) Prune(ctx context.Context, dryRun bool) (*db.PruneResult, error) {
+	now := time.Now()
+	runs, sends, reports := s.cfg.GetRetentionCutoffs(now)
+	opts := db.PruneOptions{
+		ActivityRunsBefore:    runs,
+		RawDataBefore:         s.cfg.GetRawDataCutoff(now),
+		NewsletterSendsBefore: sends,
+		WeeklyReportsBefore:   reports,
+		DryRun:                dryRun,
+	}
+	if s.cfg.UsesEmbeddings() {
+		opts.VectorModel = s.cfg.GetEmbeddingModel()
+	}
+
+	// The blobs of runs that are deleted or cleared go once the database no
+	// longer references them
+	blobsBefore := opts.RawDataBefore
+	if runs.After(blobsBefore) {
+		blobsBefore = runs
+	}
+	var blobs []*db.RawDataBlob
+	if !dryRun && !blobsBefore.IsZero() {
+		var err error
+		if blobs, err = s.db.ListRawDataBlobs(ctx, blobsBefore, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.db.Prune(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune: %w", err)
+	}
+	deleteBlobs(ctx, s.blobs, blobs)
+
+	if !dryRun {
+		slog.Info("Pruned expired data",
+			"activity_runs", result.ActivityRuns,
+			"raw_data", result.RawData,
+			"newsletter_sends", result.NewsletterSends,
+			"newsletter_batches", result.NewsletterBatches,
+			"weekly_reports", result.WeeklyReports,
+			"report_vectors", result.ReportVectors,
+			"commit_vectors", result.CommitVectors)
+	}
+	return result, nil
+}
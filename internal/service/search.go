@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/llm"
+)
+
+// embedBatchSize limits how many summaries are embedded per API call
+const embedBatchSize = 20
+
+// maxEmbedChars truncates long summaries to stay within embedding model input limits
+const maxEmbedChars = 8000
+
+// SearchService provides semantic search over weekly reports and commits
+// using embeddings of report summaries (report_vectors) and of the subjects of
+// the commits in each report's week (commit_vectors)
+type SearchService struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(database *db.DB, cfg *config.Config) *SearchService {
+	return &SearchService{
+		db:  database,
+		cfg: cfg,
+	}
+}
+
+// SearchHit is a report matched by semantic similarity
+type SearchHit struct {
+	Report   *db.WeeklyReport
+	RepoName string
+	Score    float64 // Cosine similarity, higher is more similar
+}
+
+// CommitHit is a commit matched by semantic similarity
+type CommitHit struct {
+	Commit   *db.CommitVector
+	Report   *db.WeeklyReport // Report of the week containing the commit
+	RepoName string
+	Score    float64 // Cosine similarity, higher is more similar
+}
+
+// IndexResult counts the embeddings computed by an indexing run
+type IndexResult struct {
+	Reports int
+	Commits int
+}
+
+// pendingCommit is a commit whose embedding is missing or stale
+type pendingCommit struct {
+	reportID int64
+	commit   git.Commit
+}
+
+// Enabled returns true if embeddings are configured
+func (s *SearchService) Enabled() bool {
+	return s.cfg.UsesEmbeddings()
+}
+
+// IndexReports computes embeddings for reports whose vector is missing or
+// stale (summary or embedding model changed), and for the commits in their
+// weeks, read from the local clones. Commits by ignored authors are skipped.
+func (s *SearchService) IndexReports(ctx context.Context, reports []*db.WeeklyReport) (*IndexResult, error) {
+	result := &IndexResult{}
+	if !s.Enabled() {
+		return result, nil
+	}
+
+	model := s.cfg.GetEmbeddingModel()
+	existing, err := s.db.ListReportVectors(ctx, model)
+	if err != nil {
+		return result, err
+	}
+	hashes := make(map[int64]string, len(existing))
+	for _, v := range existing {
+		hashes[v.ReportID] = v.ContentHash
+	}
+
+	var pending []*db.WeeklyReport
+	for _, r := range reports {
+		if text := embeddingText(r); text != "" && hashes[r.ID] != contentHash(model, text) {
+			pending = append(pending, r)
+		}
+	}
+	pendingCommits, err := s.pendingCommits(ctx, model, reports)
+	if err != nil {
+		return result, err
+	}
+	if len(pending) == 0 && len(pendingCommits) == 0 {
+		return result, nil
+	}
+
+	llmClient, err := llm.NewClient(ctx, s.cfg)
+	if err != nil {
+		return result, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	defer llmClient.Close()
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		batch := pending[start:min(start+embedBatchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, r := range batch {
+			texts[i] = embeddingText(r)
+		}
+
+		vectors, err := llmClient.Embed(ctx, texts, llm.TaskDocument)
+		if err != nil {
+			return result, err
+		}
+		for i, r := range batch {
+			err := s.db.UpsertReportVector(ctx, &db.ReportVector{
+				ReportID:    r.ID,
+				Model:       model,
+				ContentHash: contentHash(model, texts[i]),
+				Embedding:   vectors[i],
+			})
+			if err != nil {
+				return result, err
+			}
+			result.Reports++
+		}
+	}
+
+	for start := 0; start < len(pendingCommits); start += embedBatchSize {
+		batch := pendingCommits[start:min(start+embedBatchSize, len(pendingCommits))]
+		texts := make([]string, len(batch))
+		for i, p := range batch {
+			texts[i] = p.commit.Message
+		}
+
+		vectors, err := llmClient.Embed(ctx, texts, llm.TaskDocument)
+		if err != nil {
+			return result, err
+		}
+		for i, p := range batch {
+			err := s.db.UpsertCommitVector(ctx, &db.CommitVector{
+				ReportID:    p.reportID,
+				SHA:         p.commit.SHA,
+				Message:     p.commit.Message,
+				Author:      p.commit.Author,
+				CommittedAt: p.commit.Date,
+				Model:       model,
+				ContentHash: contentHash(model, texts[i]),
+				Embedding:   vectors[i],
+			})
+			if err != nil {
+				return result, err
+			}
+			result.Commits++
+		}
+	}
+
+	slog.Info("Indexed embeddings", "reports", result.Reports, "commits", result.Commits, "model", model)
+	return result, nil
+}
+
+// pendingCommits lists the commits in the weeks of the given reports whose
+// vector is missing or stale. Reports whose repository clone cannot be read
+// are skipped, so indexing still succeeds for the rest.
+func (s *SearchService) pendingCommits(ctx context.Context, model string, reports []*db.WeeklyReport) ([]pendingCommit, error) {
+	existing, err := s.db.ListCommitVectors(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(existing))
+	for _, v := range existing {
+		hashes[fmt.Sprintf("%d/%s", v.ReportID, v.SHA)] = v.ContentHash
+	}
+
+	repos := make(map[int64]*db.Repository)
+	var pending []pendingCommit
+	for _, r := range reports {
+		repo, ok := repos[r.RepoID]
+		if !ok {
+			repo, err = s.db.GetRepository(ctx, r.RepoID)
+			if err != nil {
+				return nil, err
+			}
+			repos[r.RepoID] = repo
+		}
+
+		repoCfg := s.cfg.GetRepoConfig(repo.Name)
+		opts := git.LogOptions{FirstParent: repoCfg.FirstParent, NoMerges: repoCfg.NoMerges}
+		commits, err := git.GetCommitsForWeekWithOptions(db.RepoLocalPath(s.cfg.DataDir, repo.Name), r.Year, r.Week, opts)
+		if err != nil {
+			slog.Debug("Failed to list commits for indexing", "repo", repo.Name, "week", git.FormatISOWeek(r.Year, r.Week), "error", err)
+			continue
+		}
+		commits, _ = analyzer.FilterIgnoredAuthors(commits, s.cfg.GetIgnoredAuthors(repo.Name))
+
+		for _, c := range commits {
+			if c.Message == "" {
+				continue
+			}
+			if hashes[fmt.Sprintf("%d/%s", r.ID, c.SHA)] != contentHash(model, c.Message) {
+				pending = append(pending, pendingCommit{reportID: r.ID, commit: c})
+			}
+		}
+	}
+	return pending, nil
+}
+
+// IndexAll computes embeddings for all reports and commits missing an
+// up-to-date vector
+func (s *SearchService) IndexAll(ctx context.Context) (*IndexResult, error) {
+	reports, err := s.db.ListAllWeeklyReports(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.IndexReports(ctx, reports)
+}
+
+// Search returns the reports most similar to a natural language query, e.g.
+// "when did we rework caching". If repoID is non-zero, only that repository's
+// reports are searched.
+func (s *SearchService) Search(ctx context.Context, query string, repoID int64, limit int) ([]SearchHit, error) {
+	vector, err := s.embedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, inRepo(repoID))
+	if err != nil {
+		return nil, err
+	}
+	return s.rank(ctx, vector, limit, scope)
+}
+
+// SearchWithCommits returns the reports and the commits most similar to a
+// query, embedding the query and loading the searchable reports once for both
+func (s *SearchService) SearchWithCommits(ctx context.Context, query string, repoID int64, limit int) ([]SearchHit, []CommitHit, error) {
+	vector, err := s.embedQuery(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	scope, err := s.scope(ctx, inRepo(repoID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reports, err := s.rank(ctx, vector, limit, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	commits, err := s.rankCommits(ctx, vector, limit, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reports, commits, nil
+}
+
+// inRepo returns a report filter for a repository, or accepting all reports
+// if repoID is zero
+func inRepo(repoID int64) func(*db.WeeklyReport) bool {
+	return func(r *db.WeeklyReport) bool {
+		return repoID == 0 || r.RepoID == repoID
+	}
+}
+
+// embedQuery returns the embedding of a search query
+func (s *SearchService) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("semantic search is disabled (llm.disable_embeddings)")
+	}
+
+	llmClient, err := llm.NewClient(ctx, s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	defer llmClient.Close()
+
+	vectors, err := llmClient.Embed(ctx, []string{query}, llm.TaskQuery)
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// Related returns the reports of the same repository whose summaries are most
+// similar to the given report. It uses stored vectors only, so it returns
+// nothing if the report has not been indexed yet.
+func (s *SearchService) Related(ctx context.Context, reportID int64, limit int) ([]SearchHit, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	v, err := s.db.GetReportVector(ctx, reportID)
+	if err != nil || v == nil || v.Model != s.cfg.GetEmbeddingModel() {
+		return nil, err
+	}
+	report, err := s.db.GetWeeklyReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := s.scope(ctx, func(r *db.WeeklyReport) bool {
+		return r.RepoID == report.RepoID && r.ID != reportID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.rank(ctx, v.Embedding, limit, scope)
+}
+
+// searchScope is the reports a search ranks, with the names of their
+// repositories
+type searchScope struct {
+	reports   map[int64]*db.WeeklyReport
+	repoNames map[int64]string
+	ids       []int64
+}
+
+// scope returns the reports visible in ctx's workspace that are accepted by
+// keep
+func (s *SearchService) scope(ctx context.Context, keep func(*db.WeeklyReport) bool) (*searchScope, error) {
+	reports, err := s.db.ListAllWeeklyReports(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := s.db.ListRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &searchScope{
+		reports:   make(map[int64]*db.WeeklyReport),
+		repoNames: make(map[int64]string, len(repos)),
+	}
+	for _, r := range reports {
+		if keep(r) {
+			scope.reports[r.ID] = r
+			scope.ids = append(scope.ids, r.ID)
+		}
+	}
+	for _, repo := range repos {
+		scope.repoNames[repo.ID] = repo.Name
+	}
+	return scope, nil
+}
+
+// rank scores the indexed reports in scope against a query vector and
+// returns the top matches, most similar first
+func (s *SearchService) rank(ctx context.Context, query []float32, limit int, scope *searchScope) ([]SearchHit, error) {
+	if len(scope.ids) == 0 {
+		return nil, nil
+	}
+	vectors, err := s.db.ListReportVectorsFor(ctx, s.cfg.GetEmbeddingModel(), scope.ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(vectors))
+	for _, v := range vectors {
+		r := scope.reports[v.ReportID]
+		hits = append(hits, SearchHit{
+			Report:   r,
+			RepoName: scope.repoNames[r.RepoID],
+			Score:    cosineSimilarity(query, v.Embedding),
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// rankCommits scores the indexed commits of the reports in scope against a
+// query vector and returns the top matches, most similar first
+func (s *SearchService) rankCommits(ctx context.Context, query []float32, limit int, scope *searchScope) ([]CommitHit, error) {
+	if len(scope.ids) == 0 {
+		return nil, nil
+	}
+	vectors, err := s.db.ListCommitVectorsFor(ctx, s.cfg.GetEmbeddingModel(), scope.ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]CommitHit, 0, len(vectors))
+	for _, v := range vectors {
+		r := scope.reports[v.ReportID]
+		hits = append(hits, CommitHit{
+			Commit:   v,
+			Report:   r,
+			RepoName: scope.repoNames[r.RepoID],
+			Score:    cosineSimilarity(query, v.Embedding),
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// embeddingText returns the text embedded for a report
+func embeddingText(r *db.WeeklyReport) string {
+	if !r.Summary.Valid {
+		return ""
+	}
+	text := r.Summary.String
+	if len(text) > maxEmbedChars {
+		text = strings.ToValidUTF8(text[:maxEmbedChars], "")
+	}
+	return text
+}
+
+// contentHash identifies the text and model a vector was computed from
+func contentHash(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if they differ in length or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/secrets"
+)
+
+// GitHubPrivateKeySecret is the name of the secret holding the GitHub App
+// private key, used when the config names no key file or variable
+const GitHubPrivateKeySecret = "github.private_key"
+
+// secretNamePattern matches valid secret names: dotted lowercase paths such
+// as github.private_key or repo.backend.token
+var secretNamePattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// SecretService stores secrets in the database, encrypted with the master key
+// from the config
+type SecretService struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewSecretService creates a new SecretService
+func NewSecretService(database *db.DB, cfg *config.Config) *SecretService {
+	return &SecretService{db: database, cfg: cfg}
+}
+
+// keyring returns the keyring of the configured master keys
+func (s *SecretService) keyring() (*secrets.Keyring, error) {
+	current, previous, err := s.cfg.GetMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return secrets.NewKeyring(current)
+	}
+	return secrets.NewKeyring(current, previous)
+}
+
+// Set encrypts a secret and stores it, replacing any secret with that name
+func (s *SecretService) Set(ctx context.Context, name string, value []byte) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: use lowercase dotted names such as %s", name, GitHubPrivateKeySecret)
+	}
+	if len(value) == 0 {
+		return fmt.Errorf("secret %s is empty", name)
+	}
+	keyring, err := s.keyring()
+	if err != nil {
+		return err
+	}
+	ciphertext, err := keyring.Encrypt(name, value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	return s.db.PutSecret(ctx, name, ciphertext)
+}
+
+// Get returns a decrypted secret, or nil if there is no secret with that name
+func (s *SecretService) Get(ctx context.Context, name string) ([]byte, error) {
+	secret, err := s.db.GetSecret(ctx, name)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	keyring, err := s.keyring()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Decrypt(name, secret.Ciphertext)
+}
+
+// List returns the stored secrets, still encrypted
+func (s *SecretService) List(ctx context.Context) ([]*db.Secret, error) {
+	return s.db.ListSecrets(ctx)
+}
+
+// Delete deletes a secret
+func (s *SecretService) Delete(ctx context.Context, name string) error {
+	deleted, err := s.db.DeleteSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("secret %s not found", name)
+	}
+	return nil
+}
+
+// IsCurrent reports whether a stored secret is encrypted with the current
+// master key
+func (s *SecretService) IsCurrent(secret *db.Secret) bool {
+	keyring, err := s.keyring()
+	return err == nil && keyring.IsCurrent(secret.Ciphertext)
+}
+
+// Rotate re-encrypts the secrets encrypted with the previous master key with
+// the current one, in one transaction, and returns how many it re-encrypted.
+// Afterwards the previous key can be removed from the config.
+func (s *SecretService) Rotate(ctx context.Context) (int, error) {
+	keyring, err := s.keyring()
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	err = s.db.WithTx(ctx, func(tx *db.DB) error {
+		stored, err := tx.ListSecrets(ctx)
+		if err != nil {
+			return err
+		}
+		for _, secret := range stored {
+			if keyring.IsCurrent(secret.Ciphertext) {
+				continue
+			}
+			value, err := keyring.Decrypt(secret.Name, secret.Ciphertext)
+			if errors.Is(err, secrets.ErrUnknownKey) {
+				return fmt.Errorf("secret %s: %w; set the key it was encrypted with as the previous master key", secret.Name, err)
+			}
+			if err != nil {
+				return err
+			}
+			ciphertext, err := keyring.Encrypt(secret.Name, value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt secret: %w", err)
+			}
+			if err := tx.PutSecret(ctx, secret.Name, ciphertext); err != nil {
+				return err
+			}
+			rotated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
+
+// GitHubPrivateKey returns the GitHub App private key from the config's key
+// file or environment variable, or else from the database
+func (s *SecretService) GitHubPrivateKey(ctx context.Context) ([]byte, error) {
+	key, err := s.cfg.GetGitHubPrivateKey()
+	if !errors.Is(err, config.ErrNoGitHubPrivateKey) || !s.cfg.HasMasterKey() {
+		return key, err
+	}
+	key, err = s.Get(ctx, GitHubPrivateKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s secret: %w", GitHubPrivateKeySecret, err)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w (file, environment variable or %s secret)", config.ErrNoGitHubPrivateKey, GitHubPrivateKeySecret)
+	}
+	return key, nil
+}
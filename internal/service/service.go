@@ -14,14 +14,30 @@ type Services struct {
 	Report     *ReportService
 	Newsletter *NewsletterService
 	Admin      *AdminService
+	Author     *AuthorService
+	Search     *SearchService
+	Chat       *ChatService
+	Retention  *RetentionService
+	Workspace  *WorkspaceService
+	Secrets    *SecretService
+	Demo       *DemoService
 }
 
 // New creates a new Services container with all dependencies
 func New(database *db.DB, cfg *config.Config, tokenProvider *github.TokenProvider) *Services {
+	search := NewSearchService(database, cfg)
+	newsletter := NewNewsletterService(database, cfg)
 	return &Services{
 		Repo:       NewRepoService(database, cfg, tokenProvider),
-		Report:     NewReportService(database, cfg, tokenProvider),
-		Newsletter: NewNewsletterService(database, cfg),
+		Report:     NewReportService(database, cfg, tokenProvider, search, newsletter),
+		Newsletter: newsletter,
 		Admin:      NewAdminService(database, cfg),
+		Author:     NewAuthorService(database, cfg),
+		Search:     search,
+		Chat:       NewChatService(database, cfg, search),
+		Retention:  NewRetentionService(database, cfg),
+		Workspace:  NewWorkspaceService(database, cfg),
+		Secrets:    NewSecretService(database, cfg),
+		Demo:       NewDemoService(database),
 	}
 }
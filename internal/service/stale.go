@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// StaleRepo is an active repository without commits for at least its
+// stale_weeks
+type StaleRepo struct {
+	Repo  *db.Repository
+	Weeks int // Full weeks since the last commit
+}
+
+// recordLastCommit stores when the commit at sha was made as the newest
+// commit of a repository, for stale repository detection
+func (s *RepoService) recordLastCommit(ctx context.Context, repo *db.Repository, sha string) {
+	at, err := git.GetCommitTime(s.repoPath(repo.Name), sha)
+	if err == nil {
+		err = s.db.SetRepositoryLastCommit(ctx, repo.ID, at)
+	}
+	if err != nil {
+		slog.Warn("Failed to record last commit", "name", repo.Name, "error", err)
+		return
+	}
+	repo.LastCommitAt.Time, repo.LastCommitAt.Valid = at, true
+}
+
+// StaleWeeks returns the full weeks since a repository's last commit and
+// whether that makes it stale. Inactive repositories, repositories not
+// updated since stale detection was added and those with stale_weeks
+// unset or -1 are never stale.
+func (s *RepoService) StaleWeeks(repo *db.Repository, now time.Time) (int, bool) {
+	threshold := s.cfg.GetStaleWeeks(repo.Name)
+	if !repo.Active || !repo.LastCommitAt.Valid || threshold == 0 {
+		return 0, false
+	}
+	weeks := int(now.Sub(repo.LastCommitAt.Time) / (7 * 24 * time.Hour))
+	return weeks, weeks >= threshold
+}
+
+// Stale returns the stale repositories, longest without commits first
+func (s *RepoService) Stale(ctx context.Context) ([]StaleRepo, error) {
+	activeOnly := true
+	repos, err := s.db.ListRepositories(ctx, &activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	now := time.Now()
+	var stale []StaleRepo
+	for _, repo := range repos {
+		if weeks, ok := s.StaleWeeks(repo, now); ok {
+			stale = append(stale, StaleRepo{Repo: repo, Weeks: weeks})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Repo.LastCommitAt.Time.Before(stale[j].Repo.LastCommitAt.Time)
+	})
+	return stale, nil
+}
+
+// NotifyStale notifies the admins of each repository's workspace once when
+// it goes stale, and again only after it has seen new commits and gone
+// stale anew. It returns the number of repositories notified about.
+func (s *RepoService) NotifyStale(ctx context.Context) (int, error) {
+	stale, err := s.Stale(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for _, st := range stale {
+		repo := st.Repo
+		if repo.StaleNotifiedAt.Valid && repo.StaleNotifiedAt.Time.After(repo.LastCommitAt.Time) {
+			continue
+		}
+		title := fmt.Sprintf("Repository %s has had no commits for %d weeks", repo.Name, st.Weeks)
+		if _, err := s.db.NotifyAdmins(db.WithWorkspace(ctx, repo.WorkspaceID), db.NotificationStaleRepo, title, "/admin/repos"); err != nil {
+			return notified, err
+		}
+		if err := s.db.MarkRepositoryStaleNotified(ctx, repo.ID); err != nil {
+			return notified, err
+		}
+		slog.Info("Notified admins of stale repository", "name", repo.Name, "weeks", st.Weeks)
+		notified++
+	}
+	return notified, nil
+}
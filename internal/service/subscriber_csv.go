@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// subscriberCSVHeader is the header row of exported subscriber CSV files
+var subscriberCSVHeader = []string{"email", "subscribe_all", "timezone", "send_hour", "repositories"}
+
+// Schedule of imported subscribers whose row leaves it out, as in the add form
+const (
+	defaultImportTimezone = "UTC"
+	defaultImportSendHour = 8
+)
+
+// ImportRowError is a row of a subscriber CSV file that was not imported
+type ImportRowError struct {
+	Line  int // Line in the file, starting at 1
+	Email string
+	Err   string
+}
+
+// ImportResult summarizes a subscriber CSV import
+type ImportResult struct {
+	Added      int
+	Duplicates []string // Addresses that already subscribe, or repeat an earlier row
+	Errors     []ImportRowError
+	DryRun     bool // Rows were only checked; nothing was added
+}
+
+// importRow is a parsed row of a subscriber CSV file
+type importRow struct {
+	email        string
+	subscribeAll bool
+	timezone     string
+	sendHour     int
+	repos        []*db.Repository
+}
+
+// ImportSubscribers adds the subscribers listed in a CSV file to the current
+// workspace. A header row names the columns: email (or email_address),
+// subscribe_all, timezone, send_hour and repositories, separated by
+// semicolons or spaces; other columns are ignored. Without a header, the
+// columns are in that order, so a plain list of addresses works. Rows
+// without a schedule get Monday 08:00 UTC, and rows without repositories
+// subscribe to all. Addresses that already subscribe are skipped, and rows
+// that fail are reported in the result while the others are still added.
+// With dryRun, rows are only checked.
+func (s *NewsletterService) ImportSubscribers(ctx context.Context, r io.Reader, dryRun bool) (*ImportResult, error) {
+	existing, err := s.db.ListSubscribers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscribers: %w", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, sub := range existing {
+		seen[strings.ToLower(sub.Email)] = true
+	}
+
+	repos := make(map[string]*db.Repository)
+	lookupRepo := func(name string) (*db.Repository, error) {
+		if repo, ok := repos[name]; ok {
+			return repo, nil
+		}
+		repo, err := s.db.GetRepositoryByName(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("repository not found: %s", name)
+		}
+		repos[name] = repo
+		return repo, nil
+	}
+
+	result := &ImportResult{DryRun: dryRun}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	columns := map[string]int{}
+	for i, name := range subscriberCSVHeader {
+		columns[name] = i
+	}
+
+	first := true
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			result.Errors = append(result.Errors, ImportRowError{Line: parseErr.Line, Err: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+
+		if first {
+			first = false
+			// Spreadsheet apps start UTF-8 files with a byte order mark
+			record[0] = strings.TrimPrefix(record[0], "\uFEFF")
+			if header, ok := parseSubscriberHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row, err := parseSubscriberRow(field, lookupRepo)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Email: field("email"), Err: err.Error()})
+			continue
+		}
+		key := strings.ToLower(row.email)
+		if seen[key] {
+			result.Duplicates = append(result.Duplicates, row.email)
+			continue
+		}
+		seen[key] = true
+
+		if !dryRun {
+			if err := s.createImported(ctx, row); err != nil {
+				result.Errors = append(result.Errors, ImportRowError{Line: line, Email: row.email, Err: err.Error()})
+				continue
+			}
+		}
+		result.Added++
+	}
+
+	slog.Info("Subscribers imported", "added", result.Added, "duplicates", len(result.Duplicates),
+		"errors", len(result.Errors), "dry_run", dryRun)
+	return result, nil
+}
+
+// parseSubscriberHeader returns the column of each known field if record is
+// a header row, that is if it has an email column
+func parseSubscriberHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if name == "email_address" || name == "e_mail" {
+			name = "email"
+		}
+		if _, dup := columns[name]; !dup {
+			columns[name] = i
+		}
+	}
+	_, ok := columns["email"]
+	return columns, ok
+}
+
+// parseSubscriberRow validates the fields of a subscriber CSV row
+func parseSubscriberRow(field func(name string) string, lookupRepo func(name string) (*db.Repository, error)) (*importRow, error) {
+	addr, err := mail.ParseAddress(field("email"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email address %q", field("email"))
+	}
+	row := &importRow{
+		email:    addr.Address,
+		timezone: defaultImportTimezone,
+		sendHour: defaultImportSendHour,
+	}
+
+	if v := field("timezone"); v != "" {
+		row.timezone = v
+	}
+	if v := field("send_hour"); v != "" {
+		row.sendHour, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid send hour: %s", v)
+		}
+	}
+	if err := validateSchedule(row.timezone, row.sendHour); err != nil {
+		return nil, err
+	}
+
+	names := strings.FieldsFunc(field("repositories"), func(r rune) bool {
+		return r == ';' || r == ',' || unicode.IsSpace(r)
+	})
+	for _, name := range names {
+		repo, err := lookupRepo(name)
+		if err != nil {
+			return nil, err
+		}
+		row.repos = append(row.repos, repo)
+	}
+
+	switch v := strings.ToLower(field("subscribe_all")); v {
+	case "":
+		row.subscribeAll = len(row.repos) == 0
+	case "yes", "y":
+		row.subscribeAll = true
+	case "no", "n":
+	default:
+		row.subscribeAll, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscribe_all value: %s", v)
+		}
+	}
+	if row.subscribeAll && len(row.repos) > 0 {
+		return nil, fmt.Errorf("subscribe_all is set but repositories are listed")
+	}
+	return row, nil
+}
+
+// createImported adds an imported subscriber and their subscriptions
+func (s *NewsletterService) createImported(ctx context.Context, row *importRow) error {
+	err := s.db.WithTx(ctx, func(tx *db.DB) error {
+		sub, err := tx.CreateSubscriber(ctx, row.email, row.subscribeAll)
+		if err != nil {
+			return err
+		}
+		sub.Timezone = row.timezone
+		sub.SendHour = row.sendHour
+		if err := tx.UpdateSubscriber(ctx, sub); err != nil {
+			return err
+		}
+		for _, repo := range row.repos {
+			if _, err := tx.CreateSubscription(ctx, sub.ID, repo.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create subscriber: %w", err)
+	}
+	return nil
+}
+
+// ExportSubscribers writes the workspace's subscribers as CSV in the format
+// ImportSubscribers reads, with a header row
+func (s *NewsletterService) ExportSubscribers(ctx context.Context, w io.Writer) error {
+	subscribers, err := s.db.ListSubscribers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list subscribers: %w", err)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(subscriberCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, sub := range subscribers {
+		var names []string
+		if !sub.SubscribeAll {
+			repos, err := s.db.GetReposForSubscriber(ctx, sub.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get repositories of %s: %w", sub.Email, err)
+			}
+			for _, repo := range repos {
+				names = append(names, repo.Name)
+			}
+		}
+		record := []string{
+			sub.Email,
+			strconv.FormatBool(sub.SubscribeAll),
+			sub.Timezone,
+			strconv.Itoa(sub.SendHour),
+			strings.Join(names, ";"),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/perbu/activity/internal/service")
+
+// startSpan starts the span of a service operation on a repository
+func startSpan(ctx context.Context, name, repoName string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("repo", repoName))
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// gitSpan starts the span of a git operation on a repository. The git
+// package runs commands without a context, so its callers trace them.
+func gitSpan(ctx context.Context, op, repoName string) trace.Span {
+	_, span := startSpan(ctx, "git."+op, repoName)
+	return span
+}
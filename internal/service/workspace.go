@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+)
+
+// apiTokenPrefix marks API tokens so they are recognizable in logs and
+// secret scanners
+const apiTokenPrefix = "act_"
+
+// workspaceNamePattern restricts workspace names to URL and cookie safe slugs
+var workspaceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// ErrNotWorkspaceAdmin is returned when a workspace is managed by someone
+// who is not allowed to
+var ErrNotWorkspaceAdmin = errors.New("not an admin of the workspace")
+
+// WorkspaceService handles workspaces and their API tokens
+type WorkspaceService struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewWorkspaceService creates a new WorkspaceService
+func NewWorkspaceService(database *db.DB, cfg *config.Config) *WorkspaceService {
+	return &WorkspaceService{
+		db:  database,
+		cfg: cfg,
+	}
+}
+
+// List returns all workspaces, the default workspace first
+func (s *WorkspaceService) List(ctx context.Context) ([]*db.Workspace, error) {
+	return s.db.ListWorkspaces(ctx)
+}
+
+// ListAdministered returns the workspaces email is an admin of, the default
+// workspace first
+func (s *WorkspaceService) ListAdministered(ctx context.Context, email string) ([]*db.Workspace, error) {
+	return s.db.ListAdminWorkspaces(ctx, email)
+}
+
+// Get returns a workspace by name
+func (s *WorkspaceService) Get(ctx context.Context, name string) (*db.Workspace, error) {
+	return s.db.GetWorkspaceByName(ctx, name)
+}
+
+// GetByID returns a workspace by ID
+func (s *WorkspaceService) GetByID(ctx context.Context, id int64) (*db.Workspace, error) {
+	return s.db.GetWorkspace(ctx, id)
+}
+
+// Create creates a new workspace and makes createdBy its first admin. Only
+// admins of the default workspace may create workspaces.
+func (s *WorkspaceService) Create(ctx context.Context, name, createdBy string) (*db.Workspace, error) {
+	if err := s.requireAdmin(ctx, db.DefaultWorkspaceID, createdBy); err != nil {
+		return nil, err
+	}
+	if !workspaceNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid workspace name '%s': use lowercase letters, digits and dashes", name)
+	}
+	if _, err := s.db.GetWorkspaceByName(ctx, name); err == nil {
+		return nil, fmt.Errorf("workspace '%s' already exists", name)
+	}
+
+	var ws *db.Workspace
+	err := s.db.WithTx(ctx, func(tx *db.DB) error {
+		var err error
+		ws, err = tx.CreateWorkspace(ctx, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateAdmin(db.WithWorkspace(ctx, ws.ID), createdBy, createdBy)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	slog.Info("Workspace created", "name", name, "created_by", createdBy)
+	return ws, nil
+}
+
+// Delete deletes a workspace with all of its data, including the local
+// clones of its repositories. Only admins of the workspace may delete it.
+func (s *WorkspaceService) Delete(ctx context.Context, name, deletedBy string) error {
+	ws, err := s.db.GetWorkspaceByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("workspace not found: %s", name)
+	}
+	if err := s.requireAdmin(ctx, ws.ID, deletedBy); err != nil {
+		return err
+	}
+
+	repos, err := s.db.ListRepositories(db.WithWorkspace(ctx, ws.ID), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.DeleteWorkspace(ctx, ws.ID); err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	for _, repo := range repos {
+		repoPath := db.RepoLocalPath(s.cfg.DataDir, repo.Name)
+		if err := os.RemoveAll(repoPath); err != nil {
+			slog.Warn("Failed to remove files", "path", repoPath, "error", err)
+		}
+	}
+
+	slog.Info("Workspace deleted", "name", name, "deleted_by", deletedBy, "repos", len(repos))
+	return nil
+}
+
+// IsAdminOf reports whether email is an admin of the workspace, regardless of
+// the workspace ctx is scoped to
+func (s *WorkspaceService) IsAdminOf(ctx context.Context, workspaceID int64, email string) (bool, error) {
+	if email == "" {
+		return false, nil
+	}
+	return s.db.IsAdmin(db.WithWorkspace(ctx, workspaceID), email)
+}
+
+// requireAdmin returns ErrNotWorkspaceAdmin unless email is an admin of the
+// workspace
+func (s *WorkspaceService) requireAdmin(ctx context.Context, workspaceID int64, email string) error {
+	isAdmin, err := s.IsAdminOf(ctx, workspaceID, email)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrNotWorkspaceAdmin
+	}
+	return nil
+}
+
+// CreateToken creates an API token for the context's workspace and returns
+// the token value, which is not stored and cannot be shown again
+func (s *WorkspaceService) CreateToken(ctx context.Context, name, createdBy string) (string, *db.APIToken, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	value := apiTokenPrefix + hex.EncodeToString(secret)
+
+	token, err := s.db.CreateAPIToken(ctx, name, hashToken(value), createdBy)
+	if err != nil {
+		return "", nil, err
+	}
+
+	slog.Info("API token created", "name", name, "workspace_id", token.WorkspaceID, "created_by", createdBy)
+	return value, token, nil
+}
+
+// ListTokens returns the API tokens of the context's workspace
+func (s *WorkspaceService) ListTokens(ctx context.Context) ([]*db.APIToken, error) {
+	return s.db.ListAPITokens(ctx)
+}
+
+// RevokeToken deletes an API token of the context's workspace
+func (s *WorkspaceService) RevokeToken(ctx context.Context, id int64) error {
+	if err := s.db.DeleteAPIToken(ctx, id); err != nil {
+		return err
+	}
+	slog.Info("API token revoked", "id", id)
+	return nil
+}
+
+// Authenticate returns the API token with the given value and records its use
+func (s *WorkspaceService) Authenticate(ctx context.Context, value string) (*db.APIToken, error) {
+	token, err := s.db.GetAPITokenByHash(ctx, hashToken(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API token")
+	}
+	if err := s.db.TouchAPIToken(ctx, token.ID); err != nil {
+		slog.Warn("Failed to record API token use", "id", token.ID, "error", err)
+	}
+	return token, nil
+}
+
+// hashToken returns the hex SHA-256 hash an API token is stored as. Tokens
+// are random, so a fast unsalted hash is sufficient.
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
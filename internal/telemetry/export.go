@@ -0,0 +1,299 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpExporter posts spans to an OTLP/HTTP endpoint in the protocol's JSON
+// encoding, which collectors, Jaeger and Tempo all accept
+type otlpExporter struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// newOTLPExporter creates an exporter posting to <endpoint>/v1/traces
+func newOTLPExporter(endpoint string, headers map[string]string) *otlpExporter {
+	return &otlpExporter{
+		url:        strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		headers:    headers,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(toOTLP(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e *otlpExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// jsonExporter writes each span as a line of OTLP JSON
+type jsonExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newJSONExporter creates an exporter writing to w
+func newJSONExporter(w io.Writer) *jsonExporter {
+	return &jsonExporter{w: w}
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *jsonExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		if err := enc.Encode(toOTLPSpan(s)); err != nil {
+			return fmt.Errorf("failed to write span: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e *jsonExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// otlpHeaders returns the headers to send to the OTLP endpoint, such as an
+// API key, from the standard OTEL_EXPORTER_OTLP_HEADERS variable
+// (key1=value1,key2=value2 with URL-encoded values)
+func otlpHeaders() map[string]string {
+	raw := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")
+	if raw == "" {
+		raw = os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		headers[strings.TrimSpace(k)] = v
+	}
+	return headers
+}
+
+// The OTLP JSON encoding of spans (opentelemetry-proto, trace/v1)
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 0 unset, 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// toOTLP groups spans by instrumentation scope under the resource of the
+// first span; all spans of one tracer provider share a resource
+func toOTLP(spans []sdktrace.ReadOnlySpan) otlpTraces {
+	rs := otlpResourceSpans{}
+	if res := spans[0].Resource(); res != nil {
+		rs.Resource.Attributes = toOTLPAttributes(res.Attributes())
+	}
+
+	scopes := make(map[string]int)
+	for _, s := range spans {
+		scope := s.InstrumentationScope()
+		i, ok := scopes[scope.Name]
+		if !ok {
+			i = len(rs.ScopeSpans)
+			scopes[scope.Name] = i
+			rs.ScopeSpans = append(rs.ScopeSpans, otlpScopeSpans{Scope: otlpScope{Name: scope.Name, Version: scope.Version}})
+		}
+		rs.ScopeSpans[i].Spans = append(rs.ScopeSpans[i].Spans, toOTLPSpan(s))
+	}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// toOTLPSpan converts a span to its OTLP JSON encoding
+func toOTLPSpan(s sdktrace.ReadOnlySpan) otlpSpan {
+	span := otlpSpan{
+		TraceID:           s.SpanContext().TraceID().String(),
+		SpanID:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()), // Same numbering as OTLP
+		StartTimeUnixNano: unixNano(s.StartTime()),
+		EndTimeUnixNano:   unixNano(s.EndTime()),
+		Attributes:        toOTLPAttributes(s.Attributes()),
+	}
+	if s.Parent().HasSpanID() {
+		span.ParentSpanID = s.Parent().SpanID().String()
+	}
+	for _, e := range s.Events() {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: unixNano(e.Time),
+			Name:         e.Name,
+			Attributes:   toOTLPAttributes(e.Attributes),
+		})
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		span.Status.Code = 1
+	case codes.Error:
+		span.Status = otlpStatus{Code: 2, Message: s.Status().Description}
+	}
+	return span
+}
+
+// toOTLPAttributes converts attributes to their OTLP JSON encoding
+func toOTLPAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, otlpKeyValue{Key: string(kv.Key), Value: toOTLPValue(kv.Value)})
+	}
+	return out
+}
+
+// toOTLPValue converts an attribute value to its OTLP JSON encoding, in
+// which 64-bit integers are strings
+func toOTLPValue(v attribute.Value) otlpAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		n := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &n}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpAnyValue{DoubleValue: &f}
+	case attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+		var values []otlpAnyValue
+		switch v.Type() {
+		case attribute.BOOLSLICE:
+			for _, b := range v.AsBoolSlice() {
+				values = append(values, toOTLPValue(attribute.BoolValue(b)))
+			}
+		case attribute.INT64SLICE:
+			for _, n := range v.AsInt64Slice() {
+				values = append(values, toOTLPValue(attribute.Int64Value(n)))
+			}
+		case attribute.FLOAT64SLICE:
+			for _, f := range v.AsFloat64Slice() {
+				values = append(values, toOTLPValue(attribute.Float64Value(f)))
+			}
+		default:
+			for _, s := range v.AsStringSlice() {
+				values = append(values, toOTLPValue(attribute.StringValue(s)))
+			}
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+// unixNano formats a time as OTLP JSON nanoseconds since the epoch
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,65 @@
+// Package telemetry sets up OpenTelemetry tracing and exports spans to an
+// OTLP endpoint or stdout.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/perbu/activity/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs the global tracer provider for the configured exporter and
+// returns a function that flushes buffered spans and stops exporting. Without
+// an exporter tracing stays disabled and spans cost next to nothing.
+func Setup(ctx context.Context, cfg *config.Config, version string) (func(context.Context) error, error) {
+	tc := cfg.Tracing
+	var exporter sdktrace.SpanExporter
+	switch tc.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter = newOTLPExporter(cfg.GetTracingEndpoint(), otlpHeaders())
+	case "stdout":
+		exporter = newJSONExporter(os.Stderr)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", tc.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.GetTracingServiceName()),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetTracingSampleRatio()))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	slog.Info("Tracing enabled", "exporter", tc.Exporter, "sample_ratio", cfg.GetTracingSampleRatio())
+
+	return provider.Shutdown, nil
+}
+
+// End ends a span, recording err on it if it is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,23 +1,29 @@
 package web
 
 import (
-	"context"
 	"fmt"
 	"html/template"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
 	"github.com/perbu/activity/internal/service"
 )
 
 // handleAdmin serves the admin dashboard
 func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
-	repos, _ := s.db.ListRepositories(nil)
-	reports, _ := s.db.ListAllWeeklyReports(nil)
-	subscribers, _ := s.db.ListSubscribers()
-	admins, _ := s.db.ListAdmins()
+	repos, _ := s.db.ListRepositories(r.Context(), nil)
+	reportCount, _ := s.db.CountWeeklyReports(r.Context(), db.ReportFilter{})
+	subscribers, _ := s.db.ListSubscribers(r.Context())
+	admins, _ := s.db.ListAdmins(r.Context())
+	draftCount, _ := s.db.CountWeeklyReports(r.Context(), db.ReportFilter{ReviewState: db.ReviewDraft})
 
 	data := PageData{
 		Title:     "Admin",
@@ -25,38 +31,46 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 		User:      GetUser(r),
 		Content: AdminDashboardData{
 			RepoCount:       len(repos),
-			ReportCount:     len(reports),
+			ReportCount:     reportCount,
 			SubscriberCount: len(subscribers),
 			AdminCount:      len(admins),
+			DraftCount:      draftCount,
 		},
 	}
 
-	s.render(w, s.templates.admin, data)
+	s.render(w, r, s.templates.admin, data)
 }
 
 // handleAdminRepos serves the repository management page
 func (s *Server) handleAdminRepos(w http.ResponseWriter, r *http.Request) {
-	repos, err := s.db.ListRepositories(nil)
+	repos, err := s.db.ListRepositories(r.Context(), nil)
 	if err != nil {
 		s.renderError(w, r, "Failed to load repositories", err)
 		return
 	}
 
+	now := time.Now()
 	summaries := make([]RepoSummary, 0, len(repos))
 	for _, repo := range repos {
-		reports, _ := s.db.ListWeeklyReportsByRepo(repo.ID, nil)
+		latest, _ := s.db.ListWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID, Limit: 1})
+		count, _ := s.db.CountWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID})
 		summary := RepoSummary{
-			ID:          repo.ID,
-			Name:        repo.Name,
-			URL:         repo.URL,
-			Branch:      repo.Branch,
-			Active:      repo.Active,
-			Description: repo.Description.String,
-			ReportCount: len(reports),
-			LastReport:  "No reports",
+			ID:           repo.ID,
+			Name:         repo.Name,
+			URL:          repo.URL,
+			Branch:       repo.Branch,
+			Active:       repo.Active,
+			Description:  repo.Description.String,
+			ContextNotes: repo.ContextNotes.String,
+			Visibility:   repo.Visibility,
+			ReportCount:  count,
+			LastReport:   "No reports",
 		}
-		if len(reports) > 0 {
-			summary.LastReport = reports[0].CreatedAt.Format("2006-01-02")
+		if len(latest) > 0 {
+			summary.LastReport = latest[0].CreatedAt.Format("2006-01-02")
+		}
+		if weeks, ok := s.services.Repo.StaleWeeks(repo, now); ok {
+			summary.StaleWeeks = weeks
 		}
 		summaries = append(summaries, summary)
 	}
@@ -70,7 +84,7 @@ func (s *Server) handleAdminRepos(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	s.render(w, s.templates.adminRepos, data)
+	s.render(w, r, s.templates.adminRepos, data)
 }
 
 // handleAdminRepoAdd handles adding a new repository
@@ -82,18 +96,18 @@ func (s *Server) handleAdminRepoAdd(w http.ResponseWriter, r *http.Request) {
 
 	name := r.FormValue("name")
 	url := r.FormValue("url")
-	branch := r.FormValue("branch")
+	branch := strings.TrimSpace(r.FormValue("branch"))
 	private := r.FormValue("private") == "on"
 
 	if name == "" || url == "" {
 		http.Error(w, "Name and URL are required", http.StatusBadRequest)
 		return
 	}
-	if branch == "" {
-		branch = "main"
-	}
 
-	_, err := s.services.Repo.Add(context.Background(), service.AddOptions{
+	// Without a branch, Add follows the remote's default branch
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	repo, err := s.services.Repo.Add(ctx, service.AddOptions{
 		Name:    name,
 		URL:     url,
 		Branch:  branch,
@@ -104,6 +118,7 @@ func (s *Server) handleAdminRepoAdd(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to add repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, auditRepoAdd, name, fmt.Sprintf("%s (branch %s)", url, repo.Branch))
 
 	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
 }
@@ -123,11 +138,16 @@ func (s *Server) handleAdminRepoRemove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.services.Repo.Remove(name, keepFiles); err != nil {
+	if err := s.services.Repo.Remove(r.Context(), name, keepFiles); err != nil {
 		slog.Error("Failed to remove repository", "name", name, "error", err)
 		http.Error(w, "Failed to remove repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	details := ""
+	if keepFiles {
+		details = "kept local files"
+	}
+	s.audit(r, auditRepoRemove, name, details)
 
 	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
 }
@@ -149,9 +169,9 @@ func (s *Server) handleAdminRepoToggle(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 	if action == "activate" {
-		err = s.services.Repo.Activate(name)
+		err = s.services.Repo.Activate(r.Context(), name)
 	} else {
-		err = s.services.Repo.Deactivate(name)
+		err = s.services.Repo.Deactivate(r.Context(), name)
 	}
 
 	if err != nil {
@@ -159,6 +179,11 @@ func (s *Server) handleAdminRepoToggle(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to toggle repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if action == "activate" {
+		s.audit(r, auditRepoActivate, name, "")
+	} else {
+		s.audit(r, auditRepoDeactivate, name, "")
+	}
 
 	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
 }
@@ -178,37 +203,154 @@ func (s *Server) handleAdminRepoSetURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.services.Repo.SetURL(name, url); err != nil {
+	if err := s.services.Repo.SetURL(r.Context(), name, url); err != nil {
 		slog.Error("Failed to set repository URL", "name", name, "error", err)
 		http.Error(w, "Failed to set repository URL: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, auditRepoSetURL, name, url)
+
+	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
+}
+
+// handleAdminRepoSetBranch handles switching the branch a repository's
+// reports follow
+func (s *Server) handleAdminRepoSetBranch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	branch := strings.TrimSpace(r.FormValue("branch"))
+
+	if name == "" || branch == "" {
+		http.Error(w, "Repository name and branch are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Repo.SetBranch(r.Context(), name, branch); err != nil {
+		slog.Error("Failed to set repository branch", "name", name, "error", err)
+		http.Error(w, "Failed to set branch: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditRepoSetBranch, name, branch)
+
+	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
+}
+
+// handleAdminRepoSetNotes handles updating a repository's context notes
+func (s *Server) handleAdminRepoSetNotes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		http.Error(w, "Repository name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Repo.SetContextNotes(r.Context(), name, r.FormValue("notes")); err != nil {
+		slog.Error("Failed to set repository context notes", "name", name, "error", err)
+		http.Error(w, "Failed to set context notes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditRepoSetNotes, name, "")
+
+	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
+}
+
+// handleAdminRepoSetVisibility handles changing who may read a repository's reports
+func (s *Server) handleAdminRepoSetVisibility(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	visibility := r.FormValue("visibility")
+	if name == "" {
+		http.Error(w, "Repository name is required", http.StatusBadRequest)
+		return
+	}
+	if !db.ValidVisibility(visibility) {
+		http.Error(w, "Invalid visibility: "+visibility, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Repo.SetVisibility(r.Context(), name, visibility); err != nil {
+		slog.Error("Failed to set repository visibility", "name", name, "error", err)
+		http.Error(w, "Failed to set visibility: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditRepoSetVisibility, name, visibility)
 
 	http.Redirect(w, r, "/admin/repos", http.StatusSeeOther)
 }
 
 // handleAdminSubscribers serves the subscriber management page
 func (s *Server) handleAdminSubscribers(w http.ResponseWriter, r *http.Request) {
-	subscribers, err := s.db.ListSubscribers()
+	s.renderAdminSubscribers(w, r, nil)
+}
+
+// renderAdminSubscribers renders the subscriber management page, with the
+// summary of an import if imported is not nil
+func (s *Server) renderAdminSubscribers(w http.ResponseWriter, r *http.Request, imported *service.ImportResult) {
+	subscribers, err := s.db.ListSubscribers(r.Context())
 	if err != nil {
 		s.renderError(w, r, "Failed to load subscribers", err)
 		return
 	}
 
+	stats, err := s.db.ListEmailEventStats(r.Context())
+	if err != nil {
+		s.renderError(w, r, "Failed to load delivery stats", err)
+		return
+	}
+	statsBySubscriber := make(map[int64]*db.EmailEventStats, len(stats))
+	for _, st := range stats {
+		statsBySubscriber[st.SubscriberID] = st
+	}
+
+	var totals DeliveryStats
 	summaries := make([]SubscriberSummary, 0, len(subscribers))
 	for _, sub := range subscribers {
 		summary := SubscriberSummary{
-			ID:           sub.ID,
-			Email:        sub.Email,
-			SubscribeAll: sub.SubscribeAll,
-			CreatedAt:    sub.CreatedAt.Format("2006-01-02"),
+			ID:               sub.ID,
+			Email:            sub.Email,
+			SubscribeAll:     sub.SubscribeAll,
+			Timezone:         sub.Timezone,
+			SendHour:         sub.SendHour,
+			CreatedAt:        sub.CreatedAt.Format("2006-01-02"),
+			Suppressed:       sub.SuppressedAt.Valid,
+			SuppressedReason: sub.SuppressedReason.String,
+		}
+		if st, ok := statsBySubscriber[sub.ID]; ok {
+			summary.Delivery = DeliveryStats{
+				Delivered:   st.Delivered,
+				Bounces:     st.Bounces,
+				SpamReports: st.SpamReports,
+				Dropped:     st.Dropped,
+				Opened:      st.Opened,
+			}
+			summary.LastEvent = st.LastEventAt.Format("2006-01-02")
+			totals.Delivered += st.Delivered
+			totals.Bounces += st.Bounces
+			totals.SpamReports += st.SpamReports
+			totals.Dropped += st.Dropped
+			totals.Opened += st.Opened
+		}
+		if summary.Suppressed {
+			totals.Suppressed++
 		}
 
 		// Get subscribed repos if not subscribe_all
 		if !sub.SubscribeAll {
-			subs, _ := s.db.ListSubscriptionsBySubscriber(sub.ID)
+			subs, _ := s.db.ListSubscriptionsBySubscriber(r.Context(), sub.ID)
 			for _, subscription := range subs {
-				repo, err := s.db.GetRepository(subscription.RepoID)
+				repo, err := s.db.GetRepository(r.Context(), subscription.RepoID)
 				if err == nil {
 					summary.Repos = append(summary.Repos, repo.Name)
 				}
@@ -224,10 +366,12 @@ func (s *Server) handleAdminSubscribers(w http.ResponseWriter, r *http.Request)
 		User:      GetUser(r),
 		Content: AdminSubscribersData{
 			Subscribers: summaries,
+			Delivery:    totals,
+			Import:      imported,
 		},
 	}
 
-	s.render(w, s.templates.adminSubscribers, data)
+	s.render(w, r, s.templates.adminSubscribers, data)
 }
 
 // handleAdminSubscriberAdd handles adding a new subscriber
@@ -245,16 +389,76 @@ func (s *Server) handleAdminSubscriberAdd(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err := s.services.Newsletter.AddSubscriber(email, subscribeAll)
+	timezone, sendHour, err := parseSchedule(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = s.services.Newsletter.AddSubscriber(r.Context(), email, subscribeAll, timezone, sendHour)
 	if err != nil {
 		slog.Error("Failed to add subscriber", "email", email, "error", err)
 		http.Error(w, "Failed to add subscriber: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	details := fmt.Sprintf("%02d:00 %s", sendHour, timezone)
+	if subscribeAll {
+		details = "all repositories, " + details
+	}
+	s.audit(r, auditSubscriberAdd, email, details)
+
+	http.Redirect(w, r, "/admin/subscribers", http.StatusSeeOther)
+}
+
+// handleAdminSubscriberSchedule handles changing when a subscriber receives
+// scheduled newsletters
+func (s *Server) handleAdminSubscriberSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	timezone, sendHour, err := parseSchedule(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Newsletter.SetSchedule(r.Context(), email, timezone, sendHour); err != nil {
+		slog.Error("Failed to update subscriber schedule", "email", email, "error", err)
+		http.Error(w, "Failed to update schedule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.audit(r, auditSubscriberSchedule, email, fmt.Sprintf("%02d:00 %s", sendHour, timezone))
 
 	http.Redirect(w, r, "/admin/subscribers", http.StatusSeeOther)
 }
 
+// parseSchedule reads the timezone and send_hour form fields, defaulting
+// to 08:00 UTC
+func parseSchedule(r *http.Request) (string, int, error) {
+	timezone := strings.TrimSpace(r.FormValue("timezone"))
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	sendHour := 8
+	if v := r.FormValue("send_hour"); v != "" {
+		hour, err := strconv.Atoi(v)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid send hour: %s", v)
+		}
+		sendHour = hour
+	}
+	return timezone, sendHour, nil
+}
+
 // handleAdminSubscriberRemove handles removing a subscriber
 func (s *Server) handleAdminSubscriberRemove(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -269,30 +473,77 @@ func (s *Server) handleAdminSubscriberRemove(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := s.services.Newsletter.RemoveSubscriber(email); err != nil {
+	if err := s.services.Newsletter.RemoveSubscriber(r.Context(), email); err != nil {
 		slog.Error("Failed to remove subscriber", "email", email, "error", err)
 		http.Error(w, "Failed to remove subscriber: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, auditSubscriberRemove, email, "")
+
+	http.Redirect(w, r, "/admin/subscribers", http.StatusSeeOther)
+}
+
+// handleAdminSubscriberUnsuppress handles clearing a subscriber's bounce suppression
+func (s *Server) handleAdminSubscriberUnsuppress(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Newsletter.Unsuppress(r.Context(), email); err != nil {
+		slog.Error("Failed to unsuppress subscriber", "email", email, "error", err)
+		http.Error(w, "Failed to unsuppress subscriber: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditSubscriberUnsuppress, email, "")
 
 	http.Redirect(w, r, "/admin/subscribers", http.StatusSeeOther)
 }
 
 // handleAdminActions serves the actions page for manual triggers
 func (s *Server) handleAdminActions(w http.ResponseWriter, r *http.Request) {
+	activeOnly := true
+	repos, err := s.db.ListRepositories(r.Context(), &activeOnly)
+	if err != nil {
+		s.renderError(w, r, "Failed to load repositories", err)
+		return
+	}
+
+	repoNames := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		repoNames = append(repoNames, repo.Name)
+	}
+
+	// Default the live preview to the previous complete week
+	year, week := git.CurrentISOWeek()
+	weekStart, _ := git.ISOWeekBounds(year, week)
+	prevYear, prevWeek := weekStart.AddDate(0, 0, -7).ISOWeek()
+
 	data := PageData{
 		Title:     "Admin - Actions",
 		ActiveNav: "admin",
 		User:      GetUser(r),
-		Content:   AdminActionsData{},
+		Content: AdminActionsData{
+			Repos:       repoNames,
+			DefaultWeek: git.FormatISOWeek(prevYear, prevWeek),
+		},
 	}
 
-	s.render(w, s.templates.adminActions, data)
+	s.render(w, r, s.templates.adminActions, data)
 }
 
 // handleAdminUpdateRepos handles updating all repositories
 func (s *Server) handleAdminUpdateRepos(w http.ResponseWriter, r *http.Request) {
-	results, err := s.services.Repo.UpdateAll(context.Background())
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	results, err := s.services.Repo.UpdateAll(ctx)
 	if err != nil {
 		slog.Error("Failed to update repositories", "error", err)
 		http.Error(w, "Failed to update repositories: "+err.Error(), http.StatusInternalServerError)
@@ -301,6 +552,7 @@ func (s *Server) handleAdminUpdateRepos(w http.ResponseWriter, r *http.Request)
 
 	msg := fmt.Sprintf("Updated %d repositories", len(results))
 	slog.Info(msg)
+	s.audit(r, auditReposUpdate, "", msg)
 
 	http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
 }
@@ -313,7 +565,9 @@ func (s *Server) handleAdminGenerateReport(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Generate reports for last week for all repos
-	results, err := s.services.Report.GenerateLastWeek(context.Background(), false)
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	results, err := s.services.Report.GenerateLastWeek(ctx, false)
 	if err != nil {
 		slog.Error("Failed to generate reports", "error", err)
 		http.Error(w, "Failed to generate reports: "+err.Error(), http.StatusInternalServerError)
@@ -327,10 +581,113 @@ func (s *Server) handleAdminGenerateReport(w http.ResponseWriter, r *http.Reques
 
 	msg := fmt.Sprintf("Generated %d reports for %d repositories", generated, len(results))
 	slog.Info(msg)
+	s.audit(r, auditReportGenerate, "last week", msg)
 
 	http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
 }
 
+// handleAdminAnalyzeNew handles incremental analysis of commits since the last run
+func (s *Server) handleAdminAnalyzeNew(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	results, err := s.services.Report.AnalyzeAllNew(ctx)
+	if err != nil {
+		slog.Error("Failed to analyze new commits", "error", err)
+		http.Error(w, "Failed to analyze new commits: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	commits, updated := 0, 0
+	for _, r := range results {
+		commits += r.NewCommits
+		if r.NewCommits > 0 {
+			updated++
+		}
+	}
+
+	msg := fmt.Sprintf("Analyzed %d new commits in %d of %d repositories", commits, updated, len(results))
+	slog.Info(msg)
+	s.audit(r, auditReportAnalyze, "", msg)
+
+	http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
+}
+
+// handleAdminGenerateStream generates a single report and streams progress and
+// partial summary text to the browser as server-sent events. It is a GET
+// endpoint because EventSource cannot POST; it is still admin-only.
+func (s *Server) handleAdminGenerateStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	repoName := r.URL.Query().Get("repo")
+	weekStr := r.URL.Query().Get("week")
+	force := r.URL.Query().Get("force") == "on"
+	if repoName == "" || weekStr == "" {
+		http.Error(w, "Repository and week are required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(event, data string) {
+		writeSSE(w, event, data)
+		flusher.Flush()
+	}
+
+	send(analyzer.ProgressStatus, fmt.Sprintf("Generating %s for %s", weekStr, repoName))
+
+	// The request context is cancelled if the browser disconnects
+	ctx := analyzer.WithProgress(r.Context(), func(ev analyzer.ProgressEvent) {
+		send(ev.Type, ev.Text)
+	})
+
+	result, err := s.services.Report.GenerateForWeek(ctx, repoName, weekStr, force)
+	if err != nil {
+		slog.Error("Failed to generate report", "repo", repoName, "week", weekStr, "error", err)
+		send("failed", err.Error())
+		return
+	}
+
+	if result.Generated > 0 {
+		details := ""
+		if force {
+			details = "regenerated"
+			note := strings.TrimSpace(r.URL.Query().Get("note"))
+			if note != "" {
+				details += ": " + note
+			}
+			if err := s.db.SetRegenerationNote(r.Context(), result.ReportID, GetUser(r).Email, note); err != nil {
+				slog.Error("Failed to record regeneration note", "report_id", result.ReportID, "error", err)
+			}
+		}
+		s.audit(r, auditReportGenerate, repoName+" "+weekStr, details)
+	}
+
+	switch {
+	case result.Generated > 0:
+		send("done", s.appURL(fmt.Sprintf("/reports/%d", result.ReportID)))
+	case result.Skipped > 0:
+		send("failed", "Report already exists (check 'force' to regenerate)")
+	default:
+		send("failed", "No commits found for "+weekStr)
+	}
+}
+
+// writeSSE writes a single server-sent event. Multi-line data is split into
+// multiple data fields as required by the SSE format.
+func writeSSE(w io.Writer, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
 // handleAdminSendNewsletter handles sending newsletters
 func (s *Server) handleAdminSendNewsletter(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -351,7 +708,13 @@ func (s *Server) handleAdminSendNewsletter(w http.ResponseWriter, r *http.Reques
 
 	dryRun := r.FormValue("dry_run") == "on"
 
-	result, err := s.services.Newsletter.Send(context.Background(), since, dryRun, os.Stdout)
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	var startedBy string
+	if user := GetUser(r); user != nil {
+		startedBy = user.Email
+	}
+	result, err := s.services.Newsletter.Send(ctx, since, dryRun, startedBy, os.Stdout)
 	if err != nil {
 		slog.Error("Failed to send newsletters", "error", err)
 		http.Error(w, "Failed to send newsletters: "+err.Error(), http.StatusInternalServerError)
@@ -363,13 +726,23 @@ func (s *Server) handleAdminSendNewsletter(w http.ResponseWriter, r *http.Reques
 		msg = "[DRY RUN] " + msg
 	}
 	slog.Info(msg)
-
-	http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
+	s.audit(r, auditNewsletterSend, "since "+sinceStr, msg)
+
+	// Show the outcome per recipient in the send history; dry runs and sends
+	// that found nothing to deliver are not recorded
+	switch len(result.BatchIDs) {
+	case 0:
+		http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
+	case 1:
+		http.Redirect(w, r, fmt.Sprintf("/admin/newsletter/sends/%d", result.BatchIDs[0]), http.StatusSeeOther)
+	default:
+		http.Redirect(w, r, "/admin/newsletter/sends", http.StatusSeeOther)
+	}
 }
 
 // handleAdminAdmins serves the admin user management page
 func (s *Server) handleAdminAdmins(w http.ResponseWriter, r *http.Request) {
-	admins, err := s.db.ListAdmins()
+	admins, err := s.db.ListAdmins(r.Context())
 	if err != nil {
 		s.renderError(w, r, "Failed to load admins", err)
 		return
@@ -400,7 +773,7 @@ func (s *Server) handleAdminAdmins(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	s.render(w, s.templates.adminAdmins, data)
+	s.render(w, r, s.templates.adminAdmins, data)
 }
 
 // handleAdminAdminAdd handles adding a new admin
@@ -418,12 +791,13 @@ func (s *Server) handleAdminAdminAdd(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := GetUser(r)
-	_, err := s.services.Admin.Add(email, user.Email)
+	_, err := s.services.Admin.Add(r.Context(), email, user.Email)
 	if err != nil {
 		slog.Error("Failed to add admin", "email", email, "error", err)
 		http.Error(w, "Failed to add admin: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, auditAdminAdd, email, "")
 
 	http.Redirect(w, r, "/admin/admins", http.StatusSeeOther)
 }
@@ -444,7 +818,7 @@ func (s *Server) handleAdminAdminRemove(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Prevent removing yourself
-	admin, err := s.db.GetAdmin(id)
+	admin, err := s.db.GetAdmin(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Admin not found", http.StatusNotFound)
 		return
@@ -456,15 +830,100 @@ func (s *Server) handleAdminAdminRemove(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := s.services.Admin.Remove(id); err != nil {
+	if err := s.services.Admin.Remove(r.Context(), id); err != nil {
 		slog.Error("Failed to remove admin", "id", id, "error", err)
 		http.Error(w, "Failed to remove admin: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, auditAdminRemove, admin.Email, "")
 
 	http.Redirect(w, r, "/admin/admins", http.StatusSeeOther)
 }
 
+// handleAdminAuthors serves the author alias management page
+func (s *Server) handleAdminAuthors(w http.ResponseWriter, r *http.Request) {
+	aliases, err := s.services.Author.ListAliases(r.Context())
+	if err != nil {
+		s.renderError(w, r, "Failed to load author aliases", err)
+		return
+	}
+
+	summaries := make([]AuthorAliasSummary, 0, len(aliases))
+	for _, a := range aliases {
+		createdBy := "system"
+		if a.CreatedBy.Valid {
+			createdBy = a.CreatedBy.String
+		}
+		summaries = append(summaries, AuthorAliasSummary{
+			ID:            a.ID,
+			Alias:         a.Alias,
+			CanonicalName: a.CanonicalName,
+			CreatedAt:     a.CreatedAt.Format("2006-01-02"),
+			CreatedBy:     createdBy,
+		})
+	}
+
+	data := PageData{
+		Title:     "Admin - Author Aliases",
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content: AdminAuthorsData{
+			Aliases: summaries,
+		},
+	}
+
+	s.render(w, r, s.templates.adminAuthors, data)
+}
+
+// handleAdminAuthorAdd handles adding a new author alias
+func (s *Server) handleAdminAuthorAdd(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	alias := r.FormValue("alias")
+	canonicalName := r.FormValue("canonical_name")
+
+	if alias == "" || canonicalName == "" {
+		http.Error(w, "Alias and canonical name are required", http.StatusBadRequest)
+		return
+	}
+
+	user := GetUser(r)
+	if _, err := s.services.Author.AddAlias(r.Context(), alias, canonicalName, user.Email); err != nil {
+		slog.Error("Failed to add author alias", "alias", alias, "error", err)
+		http.Error(w, "Failed to add author alias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditAuthorAliasAdd, alias, "as "+canonicalName)
+
+	http.Redirect(w, r, "/admin/authors", http.StatusSeeOther)
+}
+
+// handleAdminAuthorRemove handles removing an author alias
+func (s *Server) handleAdminAuthorRemove(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid alias ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Author.RemoveAlias(r.Context(), id); err != nil {
+		slog.Error("Failed to remove author alias", "id", id, "error", err)
+		http.Error(w, "Failed to remove author alias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditAuthorAliasRemove, "#"+strconv.FormatInt(id, 10), "")
+
+	http.Redirect(w, r, "/admin/authors", http.StatusSeeOther)
+}
+
 // renderAdminError renders an error for admin pages
 func (s *Server) renderAdminError(w http.ResponseWriter, r *http.Request, tmpl *template.Template, message string, err error) {
 	errMsg := message
@@ -481,5 +940,5 @@ func (s *Server) renderAdminError(w http.ResponseWriter, r *http.Request, tmpl *
 	}
 
 	w.WriteHeader(http.StatusInternalServerError)
-	s.render(w, tmpl, data)
+	s.render(w, r, tmpl, data)
 }
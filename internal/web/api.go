@@ -0,0 +1,417 @@
+package web
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/graphql"
+)
+
+// openAPIVersion is the version of the API described in the OpenAPI document
+const openAPIVersion = "1.0.0"
+
+// apiRoute describes a route of the JSON API. registerRoutes registers the
+// routes in apiRoutes and the OpenAPI document is generated from the same
+// list, so the document can't drift from the routes that are served.
+type apiRoute struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Params      []apiParam
+	Request     any            // Request body, decoded as JSON; nil if there is none
+	Response    any            // Response body, encoded as JSON; nil if ContentType is set
+	ContentType string         // Media type of responses that aren't JSON
+	Errors      map[int]string // Error statuses, answered in plain text
+	ReadOnly    bool           // Also served in read-only mode (web.read_only)
+	Auth        bool           // Requires a signed-in user or an API token
+	Handler     http.HandlerFunc
+}
+
+// apiParam is a path or query parameter of an API route
+type apiParam struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // "string" or "integer"
+	Required    bool
+	Description string
+}
+
+// apiRoutes returns the routes of the JSON API, leaving out those not
+// served in read-only mode if it is on
+func (s *Server) apiRoutes() []apiRoute {
+	routes := s.allAPIRoutes()
+	if !s.cfg.Web.ReadOnly {
+		return routes
+	}
+	var served []apiRoute
+	for _, route := range routes {
+		if route.ReadOnly {
+			served = append(served, route)
+		}
+	}
+	return served
+}
+
+// allAPIRoutes returns all routes of the JSON API
+func (s *Server) allAPIRoutes() []apiRoute {
+	repoParam := apiParam{Name: "name", In: "path", Type: "string", Required: true, Description: "Repository name"}
+
+	return []apiRoute{
+		{
+			Method:   http.MethodGet,
+			Path:     "/repos/{name}/trends.json",
+			Tag:      "Reports",
+			Summary:  "Weekly activity trends of a repository",
+			Params:   []apiParam{repoParam},
+			Response: TrendsResponse{},
+			Errors:   map[int]string{404: "Repository not found"},
+			ReadOnly: true,
+			Handler:  s.handleRepoTrends,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/repos/{name}/heatmap.json",
+			Tag:         "Reports",
+			Summary:     "Commits per day of a repository",
+			Description: "Commits per day from the stored report metadata, for a calendar year or else the past 53 weeks. Days of weeks whose report was generated before daily counts were recorded are left out.",
+			Params: []apiParam{
+				repoParam,
+				{Name: "year", In: "query", Type: "integer", Description: "Calendar year (default the past year)"},
+			},
+			Response: HeatmapResponse{},
+			Errors:   map[int]string{400: "Invalid year", 404: "Repository not found"},
+			ReadOnly: true,
+			Handler:  s.handleRepoHeatmap,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/search.json",
+			Tag:     "Search",
+			Summary: "Semantic search over reports and commits",
+			Params: []apiParam{
+				{Name: "q", In: "query", Type: "string", Required: true, Description: "Search query"},
+				{Name: "repo", In: "query", Type: "string", Description: "Only search this repository"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 10, at most 50)"},
+			},
+			Response: SearchResponse{},
+			Errors:   map[int]string{400: "No query given", 401: "Not signed in", 404: "Repository not found, or semantic search is disabled"},
+			Auth:     true,
+			Handler:  s.handleSearchJSON,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/search",
+			Tag:         "Search",
+			Summary:     "Find repositories and reports by text",
+			Description: "Repositories whose name or description contains the query, then reports whose summary contains it, or the reports of a week given as 2026-W02. Backs the search palette (Ctrl+K) of the web UI.",
+			Params: []apiParam{
+				{Name: "q", In: "query", Type: "string", Required: true, Description: "Search text or ISO week"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of repositories and of reports (default 10, at most 50)"},
+			},
+			Response: PaletteResponse{},
+			Errors:   map[int]string{400: "No query given"},
+			ReadOnly: true,
+			Handler:  s.handlePaletteSearch,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/repos/{name}/chat.json",
+			Tag:         "Chat",
+			Summary:     "Ask a question about a repository",
+			Description: "Answers are based on the repository's weekly reports. Pass earlier turns in history to continue a conversation; only the latest turns are used.",
+			Params:      []apiParam{repoParam},
+			Request:     ChatRequest{},
+			Response:    ChatResponse{},
+			Errors:      map[int]string{400: "Invalid request body or no question", 401: "Not signed in", 404: "Repository not found"},
+			Auth:        true,
+			Handler:     s.handleRepoChatJSON,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/repos/{name}/ask",
+			Tag:      "Chat",
+			Summary:  "Ask a question about a repository",
+			Params:   []apiParam{repoParam},
+			Request:  ChatRequest{},
+			Response: ChatResponse{},
+			Errors:   map[int]string{400: "Invalid request body or no question", 401: "Not signed in", 404: "Repository not found"},
+			Auth:     true,
+			Handler:  s.handleRepoChatJSON,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/graphql",
+			Tag:     "GraphQL",
+			Summary: "Run a GraphQL query",
+			Params: []apiParam{
+				{Name: "query", In: "query", Type: "string", Required: true, Description: "GraphQL query"},
+				{Name: "operationName", In: "query", Type: "string", Description: "Operation to run when the query has several"},
+				{Name: "variables", In: "query", Type: "string", Description: "Variables as a JSON object"},
+			},
+			Response: graphql.Response{},
+			Errors:   map[int]string{400: "No query given, or invalid variables"},
+			ReadOnly: true,
+			Handler:  s.handleGraphQL,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/graphql",
+			Tag:         "GraphQL",
+			Summary:     "Run a GraphQL query",
+			Description: "The schema is served at /graphql/schema.graphql.",
+			Request:     graphql.Request{},
+			Response:    graphql.Response{},
+			Errors:      map[int]string{400: "Invalid request body or no query"},
+			ReadOnly:    true,
+			Handler:     s.handleGraphQL,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/graphql/schema.graphql",
+			Tag:         "GraphQL",
+			Summary:     "GraphQL schema in the schema definition language",
+			ContentType: "text/plain",
+			ReadOnly:    true,
+			Handler:     s.handleGraphQLSchema,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/calendar.ics",
+			Tag:         "Calendar",
+			Summary:     "iCalendar feed of report weeks and newsletter sends",
+			Params:      []apiParam{{Name: "workspace", In: "query", Type: "string", Description: "Workspace, for calendar apps that can't send a token"}},
+			ContentType: "text/calendar",
+			Errors:      map[int]string{403: "Token is bound to another workspace", 404: "Workspace not found"},
+			ReadOnly:    true,
+			Handler:     s.handleCalendar,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/repos/{name}/calendar.ics",
+			Tag:         "Calendar",
+			Summary:     "iCalendar feed of a repository's report weeks",
+			Params:      []apiParam{repoParam, {Name: "workspace", In: "query", Type: "string", Description: "Workspace, for calendar apps that can't send a token"}},
+			ContentType: "text/calendar",
+			Errors:      map[int]string{403: "Token is bound to another workspace", 404: "Repository or workspace not found"},
+			ReadOnly:    true,
+			Handler:     s.handleCalendar,
+		},
+	}
+}
+
+// handleOpenAPI serves the OpenAPI document describing the JSON API
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildOpenAPI(s.apiRoutes(), s.basePath))
+}
+
+// handleAPIDocs serves Swagger UI for the OpenAPI document, with a reference
+// rendered from the same routes for browsers without JavaScript
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	content := APIDocsData{SpecURL: s.appURL("/api/openapi.json")}
+	for _, route := range s.apiRoutes() {
+		doc := APIRoute{
+			Method:      route.Method,
+			Path:        route.Path,
+			Summary:     route.Summary,
+			Description: route.Description,
+		}
+		for _, p := range route.Params {
+			doc.Params = append(doc.Params, APIParam(p))
+		}
+		for _, status := range slices.Sorted(maps.Keys(route.Errors)) {
+			doc.Errors = append(doc.Errors, APIError{Status: status, Message: route.Errors[status]})
+		}
+		content.Routes = append(content.Routes, doc)
+	}
+
+	data := PageData{
+		Title:   "API",
+		User:    GetUser(r),
+		Content: content,
+	}
+	s.render(w, r, s.templates.apiDocs, data)
+}
+
+// buildOpenAPI returns an OpenAPI 3 document describing routes. Request and
+// response schemas are derived from the Go types' JSON encoding; named struct
+// types become shared component schemas. Paths are relative to basePath.
+func buildOpenAPI(routes []apiRoute, basePath string) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	for _, route := range routes {
+		op := map[string]any{
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"tags":        []string{route.Tag},
+		}
+		if route.Description != "" {
+			op["description"] = route.Description
+		}
+
+		var params []map[string]any
+		for _, p := range route.Params {
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if route.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(route.Request), schemas)},
+				},
+			}
+		}
+
+		ok := map[string]any{"description": "OK"}
+		if route.Response != nil {
+			ok["content"] = map[string]any{
+				"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(route.Response), schemas)},
+			}
+		} else {
+			ok["content"] = map[string]any{
+				route.ContentType: map[string]any{"schema": map[string]any{"type": "string"}},
+			}
+		}
+		responses := map[string]any{"200": ok}
+		for status, description := range route.Errors {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": description,
+				"content": map[string]any{
+					"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+				},
+			}
+		}
+		op["responses"] = responses
+		if route.Auth {
+			op["security"] = []map[string][]string{{"apiToken": {}}}
+		}
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "activity API",
+			"version":     openAPIVersion,
+			"description": "JSON API of activity. Requests are served in the workspace of the API token given as a bearer token, or the selected workspace.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []map[string][]string{{"apiToken": {}}, {}},
+	}
+	if basePath != "" {
+		doc["servers"] = []map[string]string{{"url": basePath}}
+	}
+	return doc
+}
+
+// operationID names an operation after its method and path, as in
+// getReposNameTrendsJson
+func operationID(route apiRoute) string {
+	id := strings.ToLower(route.Method)
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '.' || r == '{' || r == '}'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema returns the schema of the JSON encoding of t. Named struct
+// types are added to schemas and referenced.
+func jsonSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return structSchema(t, schemas)
+		}
+		if _, ok := schemas[name]; !ok {
+			schemas[name] = nil // placeholder for recursive types
+			schemas[name] = structSchema(t, schemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	// Interfaces hold any JSON value
+	return map[string]any{}
+}
+
+// structSchema returns the object schema of a struct's exported fields.
+// Fields without omitempty are required.
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = jsonSchema(f.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
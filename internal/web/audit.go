@@ -0,0 +1,165 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// Audited admin actions. The part before the dot names the kind of object
+// acted on, so the audit page can filter by prefix.
+const (
+	auditRepoAdd              = "repo.add"
+	auditRepoRemove           = "repo.remove"
+	auditRepoActivate         = "repo.activate"
+	auditRepoDeactivate       = "repo.deactivate"
+	auditRepoSetURL           = "repo.set_url"
+	auditRepoSetBranch        = "repo.set_branch"
+	auditRepoSetNotes         = "repo.set_notes"
+	auditRepoSetVisibility    = "repo.set_visibility"
+	auditReposUpdate          = "repo.update_all"
+	auditSubscriberAdd        = "subscriber.add"
+	auditSubscriberRemove     = "subscriber.remove"
+	auditSubscriberSchedule   = "subscriber.schedule"
+	auditSubscriberUnsuppress = "subscriber.unsuppress"
+	auditSubscriberImport     = "subscriber.import"
+	auditAdminAdd             = "admin.add"
+	auditAdminRemove          = "admin.remove"
+	auditAuthorAliasAdd       = "author_alias.add"
+	auditAuthorAliasRemove    = "author_alias.remove"
+	auditWorkspaceAdd         = "workspace.add"
+	auditWorkspaceRemove      = "workspace.remove"
+	auditTokenAdd             = "token.add"
+	auditTokenRevoke          = "token.revoke"
+	auditReportGenerate       = "report.generate"
+	auditReportEdit           = "report.edit"
+	auditReportApprove        = "report.approve"
+	auditReportUnapprove      = "report.unapprove"
+	auditReportAnalyze        = "report.analyze"
+	auditReportIndex          = "report.index_embeddings"
+	auditNewsletterSend       = "newsletter.send"
+	auditNewsletterResend     = "newsletter.resend"
+)
+
+// auditCategories are the action prefixes offered as filters on the audit page
+var auditCategories = []string{"repo.", "subscriber.", "admin.", "author_alias.", "workspace.", "token.", "report.", "newsletter."}
+
+// auditPageSize is the number of entries per audit page
+const auditPageSize = 100
+
+// audit records an admin action taken by the signed-in user in the request's
+// workspace. It is recorded even if the browser has disconnected during a long
+// action. A failure to record is logged but does not fail the action, which
+// has already happened.
+func (s *Server) audit(r *http.Request, action, target, details string) {
+	actor := "unknown"
+	if user := GetUser(r); user != nil {
+		actor = user.Email
+	}
+	if err := s.db.RecordAudit(context.WithoutCancel(r.Context()), actor, action, target, details); err != nil {
+		slog.Error("Failed to record audit entry", "action", action, "target", target, "actor", actor, "error", err)
+	}
+}
+
+// auditFilter reads the actor, action and page query parameters
+func auditFilter(r *http.Request) (db.AuditFilter, int) {
+	filter := db.AuditFilter{
+		Actor:  r.URL.Query().Get("actor"),
+		Action: r.URL.Query().Get("action"),
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	return filter, page
+}
+
+// handleAdminAudit serves the audit log page
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	filter, page := auditFilter(r)
+	// Fetch one extra entry to know whether there is a next page
+	filter.Limit = auditPageSize + 1
+	filter.Offset = (page - 1) * auditPageSize
+
+	entries, err := s.db.ListAuditLog(r.Context(), filter)
+	if err != nil {
+		s.renderError(w, r, "Failed to load audit log", err)
+		return
+	}
+
+	query := url.Values{}
+	if filter.Actor != "" {
+		query.Set("actor", filter.Actor)
+	}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	content := AdminAuditData{
+		Actor:      filter.Actor,
+		Action:     filter.Action,
+		Categories: auditCategories,
+		Page:       page,
+		CSVURL:     s.appURL("/admin/audit.csv?" + query.Encode()),
+	}
+	content.PrevURL, content.NextURL, entries = pager(s.appURL("/admin/audit"), query, page, entries, auditPageSize)
+	for _, e := range entries {
+		content.Entries = append(content.Entries, AuditEntrySummary{
+			Time:    e.CreatedAt.Format("2006-01-02 15:04:05"),
+			Actor:   e.Actor,
+			Action:  e.Action,
+			Target:  e.Target,
+			Details: e.Details,
+		})
+	}
+
+	data := PageData{
+		Title:     "Admin - Audit Log",
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.adminAudit, data)
+}
+
+// handleAdminAuditCSV exports the audit log entries matching the page's
+// filters, all pages, as CSV
+func (s *Server) handleAdminAuditCSV(w http.ResponseWriter, r *http.Request) {
+	filter, _ := auditFilter(r)
+	entries, err := s.db.ListAuditLog(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to export audit log", "error", err)
+		http.Error(w, "Failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log-%s.csv"`, time.Now().Format("2006-01-02")))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "actor", "action", "target", "details"})
+	for _, e := range entries {
+		cw.Write([]string{e.CreatedAt.UTC().Format(time.RFC3339), csvCell(e.Actor), e.Action, csvCell(e.Target), csvCell(e.Details)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		slog.Error("Failed to write audit log CSV", "error", err)
+	}
+}
+
+// csvCell escapes a value that a spreadsheet would otherwise evaluate as a
+// formula
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
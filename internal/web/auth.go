@@ -3,9 +3,13 @@ package web
 import (
 	"context"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/service"
 )
 
@@ -13,75 +17,283 @@ import (
 type AuthUser struct {
 	Email   string
 	IsAdmin bool
+	Token   bool // Authenticated with an API token, which is bound to one workspace
 }
 
 // contextKey is a custom type for context keys to avoid collisions
 type contextKey string
 
-const authUserKey contextKey = "authUser"
+const (
+	authUserKey        contextKey = "authUser"
+	workspaceKey       contextKey = "workspace"
+	workspacePinnedKey contextKey = "workspacePinned"
+)
+
+// workspaceCookie holds the name of the workspace selected in the switcher
+const workspaceCookie = "workspace"
+
+// workspacePathPrefix starts paths that select a workspace, as in
+// /w/team/repos; the rest of the path is served in that workspace
+const workspacePathPrefix = "/w/"
 
 // AuthMiddleware handles user authentication
 type AuthMiddleware struct {
-	headerName   string
-	adminService *service.AdminService
-	devMode      bool
-	devUser      string
+	headerName       string
+	adminService     *service.AdminService
+	workspaceService *service.WorkspaceService
+	devMode          bool
+	devUser          string
+	workspaceDomain  string // Subdomains select a workspace; empty if unused
+	readOnly         bool   // Never authenticate requests (web.read_only)
 }
 
 // NewAuthMiddleware creates a new AuthMiddleware
-func NewAuthMiddleware(cfg *config.Config, adminService *service.AdminService) *AuthMiddleware {
+func NewAuthMiddleware(cfg *config.Config, adminService *service.AdminService, workspaceService *service.WorkspaceService) *AuthMiddleware {
 	return &AuthMiddleware{
-		headerName:   cfg.GetAuthHeader(),
-		adminService: adminService,
-		devMode:      cfg.Web.DevMode,
-		devUser:      cfg.GetDevUser(),
+		headerName:       cfg.GetAuthHeader(),
+		adminService:     adminService,
+		workspaceService: workspaceService,
+		devMode:          cfg.Web.DevMode,
+		devUser:          cfg.GetDevUser(),
+		workspaceDomain:  strings.ToLower(cfg.Web.WorkspaceDomain),
+		readOnly:         cfg.Web.ReadOnly,
 	}
 }
 
-// Middleware wraps an http.Handler and injects user info into the request context
+// Middleware wraps an http.Handler and injects user info and the current
+// workspace into the request context. Requests to a subdomain of
+// web.workspace_domain are pinned to that subdomain's workspace. Requests
+// with an API token in the Authorization header are scoped to the token's
+// workspace; other requests use the workspace named by a /w/{name}/ path
+// prefix, which is stripped and remembered in the switcher cookie, or the
+// workspace selected in the switcher, or the default workspace. The request
+// context is scoped to the workspace (see db.WithWorkspace) and to the
+// repositories the user may see (see viewerVisibility). In read-only mode all
+// requests are anonymous, so auth headers and tokens are ignored.
 func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var user *AuthUser
+		var ws *db.Workspace
+
+		pinned, err := m.hostWorkspace(r)
+		if err != nil {
+			http.Error(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
 
-		if m.devMode {
-			// In dev mode, always use the dev user and treat as admin
+		if name, rest, ok := workspacePath(r.URL.Path); ok {
+			prefixed, err := m.workspaceService.Get(r.Context(), name)
+			if err != nil || (pinned != nil && prefixed.ID != pinned.ID) {
+				http.Error(w, "Workspace not found: "+name, http.StatusNotFound)
+				return
+			}
+			if pinned == nil {
+				setWorkspaceCookie(w, prefixed.Name)
+			}
+			ws = prefixed
+			r = stripPath(r, rest)
+		}
+
+		if value, ok := bearerToken(r); ok && !m.readOnly {
+			token, err := m.workspaceService.Authenticate(r.Context(), value)
+			if err != nil {
+				http.Error(w, "Unauthorized: invalid API token", http.StatusUnauthorized)
+				return
+			}
+			if pinned != nil && token.WorkspaceID != pinned.ID {
+				http.Error(w, "Unauthorized: API token belongs to another workspace", http.StatusUnauthorized)
+				return
+			}
+			ws, err = m.workspaceService.GetByID(r.Context(), token.WorkspaceID)
+			if err != nil {
+				http.Error(w, "Unauthorized: workspace not found", http.StatusUnauthorized)
+				return
+			}
 			user = &AuthUser{
-				Email:   m.devUser,
-				IsAdmin: true,
+				Email: "token:" + token.Name,
+				Token: true,
 			}
 		} else {
-			// Production mode: read email from header
-			email := r.Header.Get(m.headerName)
-			if email != "" {
-				isAdmin, err := m.adminService.IsAdmin(email)
-				if err != nil {
-					slog.Error("Failed to check admin status", "email", email, "error", err)
-					isAdmin = false
-				}
-				user = &AuthUser{
-					Email:   email,
-					IsAdmin: isAdmin,
-				}
+			switch {
+			case pinned != nil:
+				ws = pinned
+			case ws == nil:
+				ws = m.selectedWorkspace(r)
+			}
+			if !m.readOnly {
+				user = m.authenticate(db.WithWorkspace(r.Context(), ws.ID), r)
 			}
 		}
 
-		// Store user in context (can be nil for anonymous users)
-		ctx := context.WithValue(r.Context(), authUserKey, user)
+		// Store workspace and user in context (user can be nil for anonymous users)
+		ctx := db.WithWorkspace(r.Context(), ws.ID)
+		ctx = db.WithVisibility(ctx, viewerVisibility(user))
+		ctx = context.WithValue(ctx, workspaceKey, ws)
+		ctx = context.WithValue(ctx, workspacePinnedKey, pinned != nil)
+		ctx = context.WithValue(ctx, authUserKey, user)
 
 		// Log the request
 		if user != nil {
-			slog.Info("request", "method", r.Method, "path", r.URL.Path, "user", user.Email, "admin", user.IsAdmin)
+			slog.Info("request", "method", r.Method, "path", r.URL.Path, "user", user.Email, "admin", user.IsAdmin, "workspace", ws.Name)
 		} else {
-			slog.Info("request", "method", r.Method, "path", r.URL.Path, "user", "anonymous", "header", m.headerName, "header_value", r.Header.Get(m.headerName))
+			slog.Info("request", "method", r.Method, "path", r.URL.Path, "user", "anonymous", "header", m.headerName, "header_value", r.Header.Get(m.headerName), "workspace", ws.Name)
 		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// authenticate returns the user identified by the auth header, or nil for
+// anonymous requests. Admin status is checked in the context's workspace.
+func (m *AuthMiddleware) authenticate(ctx context.Context, r *http.Request) *AuthUser {
+	var user *AuthUser
+
+	if m.devMode {
+		// In dev mode, always use the dev user and treat as admin
+		user = &AuthUser{
+			Email:   m.devUser,
+			IsAdmin: true,
+		}
+	} else {
+		// Production mode: read email from header
+		email := r.Header.Get(m.headerName)
+		if email != "" {
+			isAdmin, err := m.adminService.IsAdmin(ctx, email)
+			if err != nil {
+				slog.Error("Failed to check admin status", "email", email, "error", err)
+				isAdmin = false
+			}
+			user = &AuthUser{
+				Email:   email,
+				IsAdmin: isAdmin,
+			}
+		}
+	}
+
+	return user
+}
+
+// viewerVisibility returns the most restricted repository visibility user
+// may see: admin-only repositories for admins, authenticated ones for other
+// signed in users and API tokens, and only public ones for anonymous readers
+func viewerVisibility(user *AuthUser) string {
+	switch {
+	case user == nil:
+		return db.VisibilityPublic
+	case user.IsAdmin:
+		return db.VisibilityAdmin
+	default:
+		return db.VisibilityAuthenticated
+	}
+}
+
+// selectedWorkspace returns the workspace named in the switcher cookie, or
+// the default workspace if there is none or it no longer exists
+func (m *AuthMiddleware) selectedWorkspace(r *http.Request) *db.Workspace {
+	if c, err := r.Cookie(workspaceCookie); err == nil && c.Value != "" {
+		if ws, err := m.workspaceService.Get(r.Context(), c.Value); err == nil {
+			return ws
+		}
+	}
+	ws, err := m.workspaceService.GetByID(r.Context(), db.DefaultWorkspaceID)
+	if err != nil {
+		slog.Error("Failed to load default workspace", "error", err)
+		return &db.Workspace{ID: db.DefaultWorkspaceID, Name: "default"}
+	}
+	return ws
+}
+
+// hostWorkspace returns the workspace named by the request's subdomain of
+// the workspace domain, or nil if the request is not to a subdomain of it. A
+// subdomain naming no workspace is an error.
+func (m *AuthMiddleware) hostWorkspace(r *http.Request) (*db.Workspace, error) {
+	if m.workspaceDomain == "" {
+		return nil, nil
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	name, ok := strings.CutSuffix(strings.ToLower(host), "."+m.workspaceDomain)
+	if !ok || name == "" || strings.Contains(name, ".") {
+		return nil, nil
+	}
+	return m.workspaceService.Get(r.Context(), name)
+}
+
+// workspacePath splits a /w/{name}/rest path into the workspace name and the
+// rest of the path
+func workspacePath(path string) (name, rest string, ok bool) {
+	after, ok := strings.CutPrefix(path, workspacePathPrefix)
+	if !ok {
+		return "", "", false
+	}
+	name, rest, _ = strings.Cut(after, "/")
+	if name == "" {
+		return "", "", false
+	}
+	return name, "/" + rest, true
+}
+
+// stripPath returns a shallow copy of r for the given path, as
+// http.StripPrefix does
+func stripPath(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = ""
+	return r2
+}
+
+// setWorkspaceCookie remembers the workspace selected in the switcher
+func setWorkspaceCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     workspaceCookie,
+		Value:    name,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// canSwitchWorkspace reports whether the request may select another
+// workspace: requests with an API token are bound to the token's workspace,
+// and requests to a workspace subdomain to that workspace
+func canSwitchWorkspace(r *http.Request) bool {
+	if user := GetUser(r); user != nil && user.Token {
+		return false
+	}
+	pinned, _ := r.Context().Value(workspacePinnedKey).(bool)
+	return !pinned
+}
+
+// bearerToken returns the API token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	value = strings.TrimSpace(value)
+	return value, ok && value != ""
+}
+
+// GetWorkspace retrieves the current workspace from the request context
+func GetWorkspace(r *http.Request) *db.Workspace {
+	ws, ok := r.Context().Value(workspaceKey).(*db.Workspace)
+	if !ok {
+		return nil
+	}
+	return ws
+}
+
 // GetUser retrieves the AuthUser from the request context
 func GetUser(r *http.Request) *AuthUser {
-	user, ok := r.Context().Value(authUserKey).(*AuthUser)
+	return userFromContext(r.Context())
+}
+
+// userFromContext returns the authenticated user stored in a request context
+func userFromContext(ctx context.Context) *AuthUser {
+	user, ok := ctx.Value(authUserKey).(*AuthUser)
 	if !ok {
 		return nil
 	}
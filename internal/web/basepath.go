@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// appURL returns the URL of an application path such as /reports/1, under
+// web.base_path. Handlers route and redirect with application paths; URLs
+// built for the client (in JSON, feeds or template data) go through appURL,
+// and literal paths in templates are prefixed with {{base}}.
+func (s *Server) appURL(path string) string {
+	return s.basePath + path
+}
+
+// mountBasePath serves next under web.base_path. The prefix is stripped from
+// request paths before routing; paths without it are served as they are, for
+// reverse proxies that strip it themselves. Redirects to application paths
+// are prefixed with it.
+func (s *Server) mountBasePath(next http.Handler) http.Handler {
+	if s.basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(s.basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == s.basePath {
+			target := s.basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		w = &basePathWriter{ResponseWriter: w, basePath: s.basePath}
+		if strings.HasPrefix(r.URL.Path, s.basePath+"/") {
+			stripped.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// basePathWriter prefixes the Location header of redirects to local paths
+// with the base path
+type basePathWriter struct {
+	http.ResponseWriter
+	basePath string
+}
+
+func (w *basePathWriter) WriteHeader(code int) {
+	if loc := w.Header().Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+		w.Header().Set("Location", w.basePath+loc)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes on flushes, which server-sent event streams rely on
+func (w *basePathWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *basePathWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/markdown"
+)
+
+// summaryCacheSize is the number of rendered report summaries kept in memory
+const summaryCacheSize = 1024
+
+// summaryCache keeps the HTML of recently rendered report summaries. An
+// entry is used while the report's updated_at is unchanged, which every
+// change to its summary bumps.
+type summaryCache struct {
+	mu      sync.Mutex
+	entries map[int64]cachedSummary
+}
+
+type cachedSummary struct {
+	updatedAt time.Time
+	html      template.HTML
+}
+
+func newSummaryCache() *summaryCache {
+	return &summaryCache{entries: make(map[int64]cachedSummary)}
+}
+
+// get returns the cached HTML of report's summary
+func (c *summaryCache) get(report *db.WeeklyReport) (template.HTML, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[report.ID]
+	if !ok || !entry.updatedAt.Equal(report.UpdatedAt) {
+		return "", false
+	}
+	return entry.html, true
+}
+
+// put caches the HTML of report's summary, evicting an arbitrary entry if
+// the cache is full
+func (c *summaryCache) put(report *db.WeeklyReport, html template.HTML) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[report.ID]; !ok && len(c.entries) >= summaryCacheSize {
+		for id := range c.entries {
+			delete(c.entries, id)
+			break
+		}
+	}
+	c.entries[report.ID] = cachedSummary{updatedAt: report.UpdatedAt, html: html}
+}
+
+// clear empties the cache, for when the markdown settings change
+func (c *summaryCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// summaryHTML renders a report's markdown summary as HTML, from the cache if
+// the report is unchanged since it was last rendered
+func (s *Server) summaryHTML(report *db.WeeklyReport) template.HTML {
+	if !report.Summary.Valid || report.Summary.String == "" {
+		return ""
+	}
+	if html, ok := s.summaries.get(report); ok {
+		return html
+	}
+	html, err := markdown.ToHTML(s.markdown(), report.Summary.String)
+	if err != nil {
+		return ""
+	}
+	s.summaries.put(report, html)
+	return html
+}
+
+// notModified sets the validators of a page that changes with lastModified
+// and the values in parts, and answers 304 Not Modified if the client's
+// cached copy is still current. Pages also show the viewer's name,
+// notifications, forms, theme, plain mode and language, so the ETag covers
+// the viewer and the server's config too, and Cache-Control makes browsers
+// revalidate on every visit without letting shared caches keep the page.
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, lastModified time.Time, parts ...any) bool {
+	h := sha256.New()
+	fmt.Fprint(h, s.cacheEpoch.Load(), CSRFToken(r), theme(r), plain(r), language(r), s.branding())
+	if ws := GetWorkspace(r); ws != nil {
+		fmt.Fprint(h, ws.Name)
+	}
+	if user := GetUser(r); user != nil {
+		fmt.Fprint(h, user.Email, user.IsAdmin)
+	}
+	if recipient := notificationRecipient(r); recipient != "" {
+		unread, _ := s.db.CountUnreadNotifications(r.Context(), recipient)
+		fmt.Fprint(h, unread)
+	}
+	for _, part := range parts {
+		fmt.Fprintf(h, "|%v", part)
+	}
+	etag := `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-Modified-Since alone isn't enough, as the page depends on more than
+	// lastModified; browsers send If-None-Match along with it
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	// The cached page carries the nonce of the policy it was served with,
+	// which the browser keeps when no new policy comes with the 304
+	w.Header().Del("Content-Security-Policy")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// latestUpdate returns when the most recently updated of reports changed
+func latestUpdate(reports []*db.WeeklyReport) time.Time {
+	var latest time.Time
+	for _, r := range reports {
+		if r.UpdatedAt.After(latest) {
+			latest = r.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
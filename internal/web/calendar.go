@@ -0,0 +1,306 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/newsletter"
+)
+
+// calendarPastWeeks limits how far back the calendar feed lists report weeks
+const calendarPastWeeks = 52
+
+// calendarUpcomingWeeks is how many weeks ahead the calendar feed lists
+// report due dates and scheduled newsletter sends
+const calendarUpcomingWeeks = 4
+
+// calendarEvent is a VEVENT in the calendar feed. All-day events have a
+// date-only start and an exclusive end date.
+type calendarEvent struct {
+	uid         string
+	summary     string
+	description string
+	url         string
+	start, end  time.Time
+	allDay      bool
+	tentative   bool
+}
+
+// handleCalendar serves an iCalendar feed of the workspace's report weeks,
+// the weeks reports are due for and the upcoming scheduled newsletter sends,
+// at /calendar.ics, or of one repository's at /repos/{name}/calendar.ics.
+// Calendar apps send neither the switcher cookie nor API tokens, so the
+// workspace can also be named with ?workspace=.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ws := GetWorkspace(r)
+	if name := r.URL.Query().Get("workspace"); name != "" && name != ws.Name {
+		if !canSwitchWorkspace(r) {
+			http.Error(w, "Forbidden: this feed is bound to its workspace", http.StatusForbidden)
+			return
+		}
+		var err error
+		ws, err = s.services.Workspace.Get(ctx, name)
+		if err != nil {
+			http.Error(w, "Workspace not found: "+name, http.StatusNotFound)
+			return
+		}
+		ctx = db.WithWorkspace(ctx, ws.ID)
+	}
+
+	var reports []*db.WeeklyReport
+	var repo *db.Repository
+	var err error
+	if name := r.PathValue("name"); name != "" {
+		repo, err = s.db.GetRepositoryByName(ctx, name)
+		if err != nil {
+			http.Error(w, "Repository not found: "+name, http.StatusNotFound)
+			return
+		}
+		reports, err = s.db.ListWeeklyReportsByRepo(ctx, repo.ID, nil)
+	} else {
+		reports, err = s.db.ListAllWeeklyReports(ctx, nil)
+	}
+	if err != nil {
+		http.Error(w, "Failed to load reports: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	repoNames := make(map[int64]string)
+	repos, _ := s.db.ListRepositories(ctx, nil)
+	for _, rp := range repos {
+		repoNames[rp.ID] = rp.Name
+	}
+
+	now := time.Now()
+	baseURL := requestBaseURL(r) + s.basePath
+	scope := fmt.Sprintf("ws%d", ws.ID)
+	calName := "Activity reports"
+	if repo != nil {
+		scope += "-" + repo.Name
+		calName = "Activity reports: " + repo.Name
+	} else if ws.ID != db.DefaultWorkspaceID {
+		calName += " (" + ws.Name + ")"
+	}
+	uid := func(kind, id string) string {
+		return fmt.Sprintf("%s-%s-%s@%s", kind, id, scope, r.Host)
+	}
+
+	events := reportWeekEvents(reports, repoNames, now, baseURL, uid)
+	events = append(events, reportDueEvents(now, baseURL, uid)...)
+	if repo == nil && s.cfg.GetNewsletterScheduleInterval() > 0 {
+		subscribers, err := s.db.ListSubscribers(ctx)
+		if err != nil {
+			http.Error(w, "Failed to load subscribers: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		events = append(events, newsletterEvents(subscribers, now, uid)...)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="activity.ics"`)
+	writeCalendar(w, calName, events, now)
+}
+
+// reportWeekEvents returns an all-day event per week of the last
+// calendarPastWeeks with reports, linking to each report
+func reportWeekEvents(reports []*db.WeeklyReport, repoNames map[int64]string, now time.Time, baseURL string, uid func(kind, id string) string) []calendarEvent {
+	cutoff := now.AddDate(0, 0, -7*calendarPastWeeks)
+	byWeek := make(map[string][]*db.WeeklyReport)
+	var weeks []string
+	for _, rpt := range reports {
+		if rpt.WeekStart.Before(cutoff) {
+			continue
+		}
+		label := git.FormatISOWeek(rpt.Year, rpt.Week)
+		if _, ok := byWeek[label]; !ok {
+			weeks = append(weeks, label)
+		}
+		byWeek[label] = append(byWeek[label], rpt)
+	}
+
+	var events []calendarEvent
+	for _, label := range weeks {
+		week := byWeek[label]
+		sort.Slice(week, func(i, j int) bool { return repoNames[week[i].RepoID] < repoNames[week[j].RepoID] })
+
+		var lines []string
+		for _, rpt := range week {
+			lines = append(lines, fmt.Sprintf("%s: %d commits, %s/reports/%d", repoNames[rpt.RepoID], rpt.CommitCount, baseURL, rpt.ID))
+		}
+		e := calendarEvent{
+			uid:         uid("reports", label),
+			summary:     fmt.Sprintf("Weekly reports %s (%d repos)", label, len(week)),
+			description: strings.Join(lines, "\n"),
+			url:         baseURL + "/",
+			start:       week[0].WeekStart,
+			end:         week[0].WeekStart.AddDate(0, 0, 7),
+			allDay:      true,
+		}
+		if len(week) == 1 {
+			e.summary = fmt.Sprintf("%s %s: %d commits", repoNames[week[0].RepoID], label, week[0].CommitCount)
+			e.url = fmt.Sprintf("%s/reports/%d", baseURL, week[0].ID)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// reportDueEvents returns a tentative all-day event on each of the next
+// calendarUpcomingWeeks Mondays, when the week before can be reported on
+func reportDueEvents(now time.Time, baseURL string, uid func(kind, id string) string) []calendarEvent {
+	year, week := now.ISOWeek()
+	weekStart, _ := git.ISOWeekBounds(year, week)
+
+	var events []calendarEvent
+	for i := 0; i < calendarUpcomingWeeks; i++ {
+		start := weekStart.AddDate(0, 0, 7*i)
+		y, wk := start.ISOWeek()
+		label := git.FormatISOWeek(y, wk)
+		due := start.AddDate(0, 0, 7)
+		events = append(events, calendarEvent{
+			uid:         uid("due", label),
+			summary:     "Weekly reports due for " + label,
+			description: fmt.Sprintf("Reports for %s can be generated once the week ends (activity report generate).", label),
+			url:         baseURL + "/",
+			start:       due,
+			end:         due.AddDate(0, 0, 1),
+			allDay:      true,
+			tentative:   true,
+		})
+	}
+	return events
+}
+
+// newsletterEvents returns an event per upcoming scheduled newsletter send in
+// the next calendarUpcomingWeeks weeks, one per distinct subscriber schedule
+// (timezone and send hour), with the number of subscribers on it
+func newsletterEvents(subscribers []*db.Subscriber, now time.Time, uid func(kind, id string) string) []calendarEvent {
+	type schedule struct {
+		timezone string
+		hour     int
+	}
+	counts := make(map[schedule]int)
+	first := make(map[schedule]*db.Subscriber)
+	var schedules []schedule
+	for _, sub := range subscribers {
+		if sub.SuppressedAt.Valid {
+			continue
+		}
+		sc := schedule{sub.Timezone, sub.SendHour}
+		if counts[sc] == 0 {
+			schedules = append(schedules, sc)
+			first[sc] = sub
+		}
+		counts[sc]++
+	}
+
+	var events []calendarEvent
+	for _, sc := range schedules {
+		last := newsletter.SendTime(first[sc], now)
+		for i := 1; i <= calendarUpcomingWeeks; i++ {
+			sendAt := last.AddDate(0, 0, 7*i)
+			events = append(events, calendarEvent{
+				uid:         uid("newsletter", fmt.Sprintf("%s-%s-%02d", sendAt.Format("20060102"), strings.ReplaceAll(sc.timezone, "/", "_"), sc.hour)),
+				summary:     fmt.Sprintf("Newsletter send (%d subscribers)", counts[sc]),
+				description: fmt.Sprintf("Last week's reports are emailed at %02d:00 %s.", sc.hour, sc.timezone),
+				start:       sendAt,
+				end:         sendAt.Add(config.NewsletterCheckInterval),
+			})
+		}
+	}
+	return events
+}
+
+// requestBaseURL returns the scheme and host the request was made to, as
+// seen through a reverse proxy
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// writeCalendar writes events as an iCalendar (RFC 5545) document
+func writeCalendar(w http.ResponseWriter, name string, events []calendarEvent, now time.Time) {
+	var sb strings.Builder
+	line := func(prop, value string) {
+		sb.WriteString(foldLine(prop + ":" + value))
+		sb.WriteString("\r\n")
+	}
+	const utcFormat = "20060102T150405Z"
+	const dateFormat = "20060102"
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//activity//weekly reports//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeText(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.uid)
+		line("DTSTAMP", now.UTC().Format(utcFormat))
+		if e.allDay {
+			line("DTSTART;VALUE=DATE", e.start.Format(dateFormat))
+			line("DTEND;VALUE=DATE", e.end.Format(dateFormat))
+			line("TRANSP", "TRANSPARENT")
+		} else {
+			line("DTSTART", e.start.UTC().Format(utcFormat))
+			line("DTEND", e.end.UTC().Format(utcFormat))
+		}
+		line("SUMMARY", escapeText(e.summary))
+		if e.description != "" {
+			line("DESCRIPTION", escapeText(e.description))
+		}
+		if e.url != "" {
+			line("URL", e.url)
+		}
+		if e.tentative {
+			line("STATUS", "TENTATIVE")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	w.Write([]byte(sb.String()))
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine folds a content line into lines of at most 75 octets, continued
+// with a leading space, without splitting UTF-8 characters
+func foldLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var sb strings.Builder
+	width := limit
+	for len(s) > width {
+		n := width
+		for n > 0 && s[n]&0xC0 == 0x80 {
+			n--
+		}
+		sb.WriteString(s[:n])
+		sb.WriteString("\r\n ")
+		s = s[n:]
+		width = limit - 1 // The leading space counts
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
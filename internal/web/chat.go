@@ -0,0 +1,138 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/perbu/activity/internal/markdown"
+	"github.com/perbu/activity/internal/service"
+)
+
+// maxChatRequestBytes bounds the size of a chat request body
+const maxChatRequestBytes = 64 << 10
+
+// ChatRequest is the JSON body accepted at /repos/{name}/chat.json
+type ChatRequest struct {
+	Question string                `json:"question"`
+	History  []service.ChatMessage `json:"history,omitempty"` // previous turns, oldest first
+}
+
+// ChatSourceJSON is a report an answer is based on
+type ChatSourceJSON struct {
+	ReportID int64   `json:"report_id"`
+	Week     string  `json:"week"`
+	Score    float64 `json:"score,omitempty"`
+	URL      string  `json:"url"`
+}
+
+// ChatResponse is the JSON payload served at /repos/{name}/chat.json
+type ChatResponse struct {
+	Repo       string           `json:"repo"`
+	Answer     string           `json:"answer"`
+	AnswerHTML string           `json:"answer_html"`
+	Sources    []ChatSourceJSON `json:"sources"`
+}
+
+// handleRepoChat serves the chat page for a repository. A question posted
+// from the page's form is answered server-side, so the page works without
+// JavaScript; the script on the page uses handleRepoChatJSON instead. GET
+// only renders the form, so prefetching or unfurling a link never calls the
+// LLM.
+func (s *Server) handleRepoChat(w http.ResponseWriter, r *http.Request) {
+	repoName := r.PathValue("name")
+	repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
+	if err != nil {
+		s.renderError(w, r, "Repository not found: "+repoName, err)
+		return
+	}
+
+	content := ChatData{Repo: repo.Name}
+	if r.Method == http.MethodPost {
+		content.Question = strings.TrimSpace(r.FormValue("q"))
+	}
+
+	var errMsg string
+	if content.Question != "" {
+		answer, err := s.services.Chat.Ask(r.Context(), repo.Name, content.Question, nil)
+		if err != nil {
+			errMsg = "Failed to answer: " + err.Error()
+		} else {
+			content.Answer = renderMarkdown(answer.Answer)
+			content.Sources = s.toChatSources(answer.Sources)
+		}
+	}
+
+	data := PageData{
+		Title:     "Ask " + repo.Name,
+		ActiveNav: "repos",
+		User:      GetUser(r),
+		Error:     errMsg,
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.chat, data)
+}
+
+// handleRepoChatJSON answers a question about a repository as JSON.
+// The request body is a ChatRequest; include earlier turns in history
+// to ask follow-up questions.
+func (s *Server) handleRepoChatJSON(w http.ResponseWriter, r *http.Request) {
+	repoName := r.PathValue("name")
+	if _, err := s.db.GetRepositoryByName(r.Context(), repoName); err != nil {
+		http.Error(w, "Repository not found: "+repoName, http.StatusNotFound)
+		return
+	}
+
+	var req ChatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		http.Error(w, "Field 'question' is required", http.StatusBadRequest)
+		return
+	}
+
+	answer, err := s.services.Chat.Ask(r.Context(), repoName, req.Question, req.History)
+	if err != nil {
+		http.Error(w, "Failed to answer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ChatResponse{
+		Repo:       repoName,
+		Answer:     answer.Answer,
+		AnswerHTML: string(renderMarkdown(answer.Answer)),
+		Sources:    s.toChatSources(answer.Sources),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// toChatSources converts chat sources for display and JSON output
+func (s *Server) toChatSources(sources []service.ChatSource) []ChatSourceJSON {
+	result := make([]ChatSourceJSON, 0, len(sources))
+	for _, src := range sources {
+		result = append(result, ChatSourceJSON{
+			ReportID: src.ReportID,
+			Week:     src.Week,
+			Score:    src.Score,
+			URL:      s.appURL(fmt.Sprintf("/reports/%d", src.ReportID)),
+		})
+	}
+	return result
+}
+
+// renderMarkdown converts LLM output to HTML, dropping raw HTML and unsafe
+// links (see markdown.New), with headings below the page's title
+func renderMarkdown(text string) template.HTML {
+	html, err := markdown.ToHTML(markdown.New(nil, markdown.WithTopHeading(2)), text)
+	if err != nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	return html
+}
@@ -0,0 +1,147 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sessionCookie holds the random ID of the browser session that CSRF tokens
+// are bound to
+const sessionCookie = "session"
+
+// csrfField is the form field, and csrfHeader the header, carrying the token
+const (
+	csrfField  = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+)
+
+// csrfTokenKey is the context key for the current session's CSRF token
+const csrfTokenKey contextKey = "csrfToken"
+
+// CSRF protects state-changing requests from cross-site forgery. Each browser
+// gets a session cookie with a random ID, and forms carry a token that is an
+// HMAC of that ID, which other sites can neither read nor compute. Browsers'
+// Sec-Fetch-Site and Origin headers are checked as well.
+type CSRF struct {
+	key []byte
+}
+
+// NewCSRF creates a CSRF protection signing tokens with key. Without a key a
+// random one is used, so pages opened before a restart must be reloaded
+// before their forms can be submitted, and replicas cannot share sessions.
+func NewCSRF(key string) (*CSRF, error) {
+	if key != "" {
+		return &CSRF{key: []byte(key)}, nil
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	return &CSRF{key: random}, nil
+}
+
+// Middleware rejects unsafe requests without a valid CSRF token and puts the
+// session's token in the request context for forms (see CSRFToken). Requests
+// authenticated with an API token, JSON requests (which browsers cannot send
+// cross-site without CORS) and signed webhooks are exempt. It must run inside
+// AuthMiddleware.
+func (c *CSRF) Middleware(next http.Handler) http.Handler {
+	return http.NewCrossOriginProtection().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := sessionID(r)
+		if id == "" {
+			id = rand.Text()
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookie,
+				Value:    id,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		token := c.token(id)
+
+		if needsCSRFToken(r) && !validCSRFToken(r, token) {
+			slog.Warn("Rejected request without valid CSRF token", "method", r.Method, "path", r.URL.Path)
+			http.Error(w, "Forbidden: missing or expired form token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey, token)))
+	}))
+}
+
+// RequireCSRF returns middleware that requires a CSRF token on a safe method,
+// for GET endpoints with side effects such as EventSource streams. The token
+// is passed as the csrf_token query parameter.
+func RequireCSRF(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := CSRFToken(r); token == "" || !validCSRFToken(r, token) {
+			http.Error(w, "Forbidden: missing or expired form token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// CSRFToken returns the CSRF token of the request's session
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey).(string)
+	return token
+}
+
+// token returns the CSRF token for a session ID
+func (c *CSRF) token(id string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sessionID returns the session ID from the session cookie, or "" if there is none
+func sessionID(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || len(cookie.Value) < 16 {
+		return ""
+	}
+	return cookie.Value
+}
+
+// needsCSRFToken reports whether a request must carry a CSRF token
+func needsCSRFToken(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	if user := GetUser(r); user != nil && user.Token {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, "/webhooks/") {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType != "application/json"
+}
+
+// validCSRFToken reports whether the request carries the expected token in
+// the X-CSRF-Token header, the form body or the query string
+func validCSRFToken(r *http.Request, want string) bool {
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		got = r.FormValue(csrfField)
+	}
+	return got != "" && hmac.Equal([]byte(got), []byte(want))
+}
+
+// isHTTPS reports whether the request reached the server, or the proxy in
+// front of it, over HTTPS
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -1,15 +1,53 @@
 package web
 
-import "html/template"
+import (
+	"html/template"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/service"
+)
 
 // PageData is the common data structure for all pages
 type PageData struct {
-	Title      string
-	ActiveNav  string // "dashboard", "repos", "admin", ""
-	Content    any
-	Error      string
-	CurrentURL string
-	User       *AuthUser
+	Title       string
+	ActiveNav   string // "dashboard", "repos", "search", "leaderboard", "admin", ""
+	Content     any
+	Error       string
+	CurrentURL  string
+	User        *AuthUser
+	Workspace   string     // Name of the current workspace
+	Workspaces  []string   // Workspaces to switch to, empty if there is only one
+	CSRFToken   string     // Token that POST forms must include (see CSRF)
+	CSPNonce    string     // Nonce that inline <script> and <style> elements must carry
+	ReadOnly    bool       // Only public pages are served (web.read_only)
+	Branding    Branding   // Site name, logo, colors and extra links
+	Theme       string     // Color theme, "dark" or "light" (see handleTheme)
+	Plain       bool       // Pages are shown without the stylesheet (see handlePlain)
+	Leaderboard bool       // The leaderboard is enabled and linked in the nav bar
+	Lang        string     // UI language code, for the t template function (see language)
+	Languages   []Language // Languages to switch to (see handleLanguage)
+
+	// The signed-in user's latest notifications for the nav bar panel
+	Notifications []NotificationItem
+	UnreadCount   int
+}
+
+// Branding is the site's look, from the branding config
+type Branding struct {
+	SiteName         string
+	LogoURL          string
+	FooterText       string
+	AccentColor      string
+	AccentHoverColor string
+	NavLinks         []config.NavLink
+}
+
+// NotificationItem is a view model for an in-app notification
+type NotificationItem struct {
+	ID        int64
+	Title     string
+	CreatedAt string
+	Unread    bool
 }
 
 // ReportSummary is a lightweight view model for report listings
@@ -44,36 +82,88 @@ type ReportDetail struct {
 	UpdatedAt   string
 	Summary     string
 	SummaryHTML template.HTML
+
+	// Commits by ignored authors, excluded from CommitCount, and a
+	// "name (count)" entry per author
+	AutomatedCommits int
+	AutomatedAuthors []string
+
+	// Merged pull requests and their average size in lines changed, and the
+	// merge and direct commits among CommitCount
+	PullRequests  int
+	AvgPRSize     int
+	MergeCommits  int
+	DirectCommits int
+
+	// Hand edits and the note of a forced regeneration, empty if none
+	EditedBy         string
+	EditedAt         string
+	RegenerationNote string
+	RegeneratedBy    string
+	RegeneratedAt    string
+
+	// Draft reports are left out of newsletters until approved; ApprovedBy
+	// is empty for automatically approved reports
+	Draft      bool
+	ApprovedBy string
+	ApprovedAt string
 }
 
 // RepoSummary is a view model for repository listings
 type RepoSummary struct {
-	ID          int64
-	Name        string
-	URL         string
-	Branch      string
-	Active      bool
-	Description string // AI-generated description from README
-	ReportCount int
-	LastReport  string // formatted date or "No reports"
-	Sparkline []SparklineBar // commit activity for last 8 weeks (oldest to newest)
+	ID           int64
+	Name         string
+	URL          string
+	Branch       string
+	Active       bool
+	Description  string // AI-generated description from README
+	ContextNotes string // Admin-written vocabulary added to analyzer prompts
+	Visibility   string // Who may read its reports: public, authenticated or admin
+	ReportCount  int
+	LastReport   string         // formatted date or "No reports"
+	Sparkline    []SparklineBar // commit activity for last 8 weeks (oldest to newest)
+	Favorite     bool           // Starred by the signed-in user
+	StaleWeeks   int            // Weeks without commits if stale, else 0
 }
 
 // SparklineBar represents a single bar in a sparkline chart
 type SparklineBar struct {
-	Value   int // raw commit count
-	Height  int // percentage height (0-100)
+	Value  int // raw commit count
+	Height int // percentage height (0-100)
+	Y      int // top of the bar, 100 - Height
 }
 
 // DashboardData is the view model for the dashboard/index page
 type DashboardData struct {
 	Reports    []ReportSummary
-	TotalCount int
+	TotalCount int // Reports matching the filters, on all pages
+
+	// The signed-in user's starred repositories' latest reports, shown first,
+	// and their newsletter personalization. CanStar is false for anonymous
+	// users and API tokens.
+	Favorites           []ReportSummary
+	CanStar             bool
+	DigestFavoritesOnly bool
+
+	// Repositories without commits for their stale_weeks
+	Stale []RepoSummary
+
+	// Filter choices and the selected filters
+	Repos      []string
+	Years      []int
+	Repo       string
+	Year       int
+	MinCommits int
+	Filtered   bool
+
+	Page             int
+	PrevURL, NextURL string // Empty on the first and last page
 }
 
 // RepoListData is the view model for the repository list page
 type RepoListData struct {
-	Repos []RepoSummary
+	Repos   []RepoSummary
+	CanStar bool // The user is signed in and can star repositories
 }
 
 // RepoReportsData is the view model for a single repo's reports
@@ -82,11 +172,121 @@ type RepoReportsData struct {
 	Reports     []ReportSummary
 	Years       []int
 	CurrentYear int // 0 means "all"
+	Charts      []TrendChart
+	Heatmap     *HeatmapChart  // Commits per day of CurrentYear, or the past year
+	CanStar     bool           // The user is signed in and can star the repository
+	Releases    []ReleaseEntry // Newest tags first
+
+	Page             int
+	PrevURL, NextURL string // Empty on the first and last page
+}
+
+// ReleaseEntry is a tag on the repo page's release timeline
+type ReleaseEntry struct {
+	Name    string
+	Date    string
+	Week    string // ISO week the tag was made in, e.g. 2026-W02
+	Subject string // Message of an annotated tag
+}
+
+// TrendChart is a server-rendered SVG bar chart on the repo page
+type TrendChart struct {
+	Title   string
+	Max     int // largest value in the series (top of the scale)
+	Width   int // SVG viewBox width
+	Height  int // SVG viewBox height
+	First   string
+	Last    string
+	Midline int // y of the zero line for mirrored charts, 0 if unused
+	Bars    []ChartBar
+}
+
+// HeatmapChart is a server-rendered SVG grid of commits per day on the repo
+// page, a column per week
+type HeatmapChart struct {
+	Width, Height int // SVG viewBox size
+	Total         int
+	From, To      string
+	Months        []HeatmapLabel
+	Cells         []HeatmapCell
+}
+
+// HeatmapLabel is a month name above the week it starts in
+type HeatmapLabel struct {
+	X    int
+	Text string
+}
+
+// HeatmapCell is a day in a HeatmapChart
+type HeatmapCell struct {
+	X, Y, Size int
+	Class      string // heat-0 (no commits) to heat-4, or heat-none without data
+	Label      string // tooltip text
+}
+
+// ChartBar is a single rectangle in a TrendChart
+type ChartBar struct {
+	X, Y, Width, Height int
+	Label               string // tooltip text
+	Class               string // optional CSS class override
+}
+
+// LeaderboardData is the view model for the contributor leaderboard
+type LeaderboardData struct {
+	Month            string // e.g. "January 2026"
+	Rows             []LeaderboardRow
+	PrevURL, NextURL string // Empty for the current month
+}
+
+// LeaderboardRow is a contributor's activity in a month
+type LeaderboardRow struct {
+	Rank       int
+	Name       string
+	Commits    int // Commits authored
+	CoAuthored int // Commits credited as a co-author
+	Repos      int // Repositories contributed to
+	Mentions   int // Weekly summaries mentioning the contributor by name
 }
 
 // ReportViewData is the view model for a single report detail
 type ReportViewData struct {
-	Report ReportDetail
+	Report  ReportDetail
+	Related []SearchResult // semantically similar weeks of the same repository
+}
+
+// ReportCompareData is the view model for comparing a report with the
+// previous week's
+type ReportCompareData struct {
+	Current    ReportDetail
+	Previous   *ReportDetail // nil if there is no report for the previous week
+	Delta      template.HTML // what changed since last week
+	DeltaError string
+}
+
+// SearchData is the view model for the semantic search page
+type SearchData struct {
+	Query    string
+	Enabled  bool
+	SignedIn bool // Searching is limited to signed-in users, since each query is an embedding call
+	Results  []SearchResult
+	Commits  []CommitResult
+}
+
+// CommitResult is a commit matched by semantic search
+type CommitResult struct {
+	SHA      string
+	ShortSHA string
+	Message  string
+	Author   string
+	Date     string
+	Report   ReportSummary // report of the commit's week
+	Score    string
+}
+
+// SearchResult is a report matched by semantic search
+type SearchResult struct {
+	Report ReportSummary
+	Score  string // similarity as a percentage, e.g. "82%"
 }
 
 // AdminDashboardData is the view model for the admin dashboard
@@ -95,6 +295,7 @@ type AdminDashboardData struct {
 	ReportCount     int
 	SubscriberCount int
 	AdminCount      int
+	DraftCount      int // Reports awaiting approval
 }
 
 // AdminReposData is the view model for admin repository management
@@ -105,6 +306,18 @@ type AdminReposData struct {
 // AdminSubscribersData is the view model for admin subscriber management
 type AdminSubscribersData struct {
 	Subscribers []SubscriberSummary
+	Delivery    DeliveryStats         // Totals over all subscribers
+	Import      *service.ImportResult // Result of the CSV import just submitted
+}
+
+// DeliveryStats counts delivery events reported by the SendGrid webhook
+type DeliveryStats struct {
+	Delivered   int
+	Bounces     int
+	SpamReports int
+	Dropped     int
+	Opened      int // Newsletters opened, if open tracking is enabled in SendGrid
+	Suppressed  int // Subscribers suppressed after a hard bounce
 }
 
 // SubscriberSummary is a view model for subscriber listings
@@ -112,8 +325,15 @@ type SubscriberSummary struct {
 	ID           int64
 	Email        string
 	SubscribeAll bool
+	Timezone     string
+	SendHour     int
 	CreatedAt    string
 	Repos        []string // Names of subscribed repos (if not subscribe_all)
+
+	Delivery         DeliveryStats
+	LastEvent        string // Date of the latest delivery event, empty if none
+	Suppressed       bool
+	SuppressedReason string
 }
 
 // AdminAdminsData is the view model for admin user management
@@ -130,9 +350,184 @@ type AdminSummary struct {
 	CreatedBy string
 }
 
+// AdminWorkspacesData is the view model for workspace and API token management
+type AdminWorkspacesData struct {
+	Workspaces []WorkspaceSummary
+	Current    string
+	Tokens     []APITokenSummary
+	NewToken   string // Value of a token just created, shown only once
+	Isolated   bool   // Served on a workspace subdomain, which cannot see other workspaces
+	CanCreate  bool   // Admin of the default workspace, who may create workspaces
+}
+
+// WorkspaceSummary is a view model for workspace listings
+type WorkspaceSummary struct {
+	Name      string
+	CreatedAt string
+	Default   bool
+}
+
+// APITokenSummary is a view model for API token listings
+type APITokenSummary struct {
+	ID         int64
+	Name       string
+	CreatedAt  string
+	CreatedBy  string
+	LastUsedAt string
+}
+
+// AdminAuthorsData is the view model for author alias management
+type AdminAuthorsData struct {
+	Aliases []AuthorAliasSummary
+}
+
+// AuthorAliasSummary is a view model for author alias listings
+type AuthorAliasSummary struct {
+	ID            int64
+	Alias         string
+	CanonicalName string
+	CreatedAt     string
+	CreatedBy     string
+}
+
+// AdminAuditData is the view model for the audit log page
+type AdminAuditData struct {
+	Entries    []AuditEntrySummary
+	Actor      string   // Actor filter, empty for all
+	Action     string   // Action or action prefix filter, empty for all
+	Categories []string // Action prefixes to filter by
+	Page       int
+	PrevURL    string // Empty on the first page
+	NextURL    string // Empty on the last page
+	CSVURL     string // Export of all entries matching the filters
+}
+
+// AuditEntrySummary is a view model for audit log listings
+type AuditEntrySummary struct {
+	Time    string
+	Actor   string
+	Action  string
+	Target  string
+	Details string
+}
+
+// AdminNewsletterSendsData is the view model for the newsletter send
+// history page
+type AdminNewsletterSendsData struct {
+	Batches []NewsletterBatchSummary
+	Page    int
+	PrevURL string // Empty on the first page
+	NextURL string // Empty on the last page
+}
+
+// AdminNewsletterSendData is the view model for a batch of the newsletter
+// send history
+type AdminNewsletterSendData struct {
+	Batch      NewsletterBatchSummary
+	Deliveries []NewsletterDeliverySummary // Failed ones first
+}
+
+// NewsletterBatchSummary is a view model for newsletter send history
+// listings
+type NewsletterBatchSummary struct {
+	ID        int64
+	StartedAt string
+	Kind      string // "manual", "scheduled" or "single"
+	StartedBy string // Admin email, "schedule" or "command line"
+	Sent      int
+	Failed    int
+	URL       string
+}
+
+// NewsletterDeliverySummary is a view model for the outcome of sending a
+// newsletter to one subscriber
+type NewsletterDeliverySummary struct {
+	Email     string
+	Status    string // "sent", "failed" or "skipped"
+	Subject   string
+	Reports   int
+	MessageID string
+	Error     string
+	Attempts  int
+	Window    string // Range of week ends the reports were picked from
+	UpdatedAt string
+	Delivered bool // Reported by the SendGrid event webhook
+	Bounced   bool
+	Opened    bool
+}
+
 // AdminActionsData is the view model for admin actions page
 type AdminActionsData struct {
 	LastUpdate     string
 	LastReportGen  string
 	LastNewsletter string
+	Repos          []string // active repository names for single-report generation
+	DefaultWeek    string   // previous complete ISO week, e.g. "2026-W02"
+}
+
+// AdminNewsletterPreviewData is the view model for previewing a subscriber's
+// newsletter
+type AdminNewsletterPreviewData struct {
+	Subscribers []string // emails to choose from
+	Subscriber  string
+	Since       string
+	Subject     string // empty if there is nothing to send
+	HTML        string // shown in a sandboxed frame
+	Text        string
+}
+
+// NotificationsData is the view model for the notifications page
+type NotificationsData struct {
+	Notifications []NotificationItem
+}
+
+// AdminReportEditData is the view model for editing a report summary
+type AdminReportEditData struct {
+	Report   ReportDetail
+	Original string // Generated summary if the report was edited before
+}
+
+// AdminReviewsData is the view model for approving draft reports
+type AdminReviewsData struct {
+	Drafts      []ReportSummary
+	AutoApprove bool // New reports are approved as they are generated
+}
+
+// ChatData is the view model for the repository chat page
+type ChatData struct {
+	Repo     string
+	Question string
+	Answer   template.HTML // answer to Question when the page is submitted without JavaScript
+	Sources  []ChatSourceJSON
+}
+
+// APIRoute is an API route described on the API docs page
+type APIRoute struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []APIParam
+	Errors      []APIError
+}
+
+// APIParam is a path or query parameter of an API route
+type APIParam struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// APIError is an error status an API route answers with
+type APIError struct {
+	Status  int
+	Message string
+}
+
+// APIDocsData is the view model for the API docs page
+type APIDocsData struct {
+	SpecURL string
+	Routes  []APIRoute
 }
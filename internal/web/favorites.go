@@ -0,0 +1,99 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// userPreferences returns the signed-in user's preferences, or nil for
+// anonymous requests and API tokens, which cannot star repositories
+func (s *Server) userPreferences(r *http.Request) *db.UserPreferences {
+	user := GetUser(r)
+	if user == nil || user.Token {
+		return nil
+	}
+	prefs, err := s.db.GetUserPreferences(r.Context(), user.Email)
+	if err != nil {
+		slog.Error("Failed to load user preferences", "user", user.Email, "error", err)
+		return nil
+	}
+	return prefs
+}
+
+// favoriteReports returns the latest report of each starred repository among
+// repos, ordered by repository name
+func (s *Server) favoriteReports(r *http.Request, prefs *db.UserPreferences, repoNames map[int64]string) []ReportSummary {
+	var summaries []ReportSummary
+	for _, id := range prefs.FavoriteRepoIDs {
+		name, ok := repoNames[id]
+		if !ok {
+			continue // Starred in another workspace
+		}
+		rpt, err := s.db.GetLatestWeeklyReport(r.Context(), id)
+		if err != nil || rpt == nil {
+			continue
+		}
+		summaries = append(summaries, toReportSummary(rpt, name))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].RepoName < summaries[j].RepoName })
+	return summaries
+}
+
+// handleRepoFavorite stars or unstars a repository for the signed-in user
+// and returns to the page the form was on
+func (s *Server) handleRepoFavorite(w http.ResponseWriter, r *http.Request) {
+	user := GetUser(r)
+	if user.Token {
+		http.Error(w, "Forbidden: API tokens cannot star repositories", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	repo, err := s.db.GetRepositoryByName(r.Context(), name)
+	if err != nil {
+		http.Error(w, "Repository not found: "+name, http.StatusNotFound)
+		return
+	}
+	if err := s.db.SetFavorite(r.Context(), user.Email, repo.ID, r.FormValue("favorite") == "true"); err != nil {
+		s.renderError(w, r, "Failed to update favorites", err)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/repos/"+repo.Name), http.StatusSeeOther)
+}
+
+// handlePreferences saves the signed-in user's newsletter personalization
+func (s *Server) handlePreferences(w http.ResponseWriter, r *http.Request) {
+	user := GetUser(r)
+	if user.Token {
+		http.Error(w, "Forbidden: API tokens have no preferences", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.SetDigestFavoritesOnly(r.Context(), user.Email, r.FormValue("digest_favorites_only") == "true"); err != nil {
+		s.renderError(w, r, "Failed to save preferences", err)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
+
+// returnPath returns the local path in the form's "return" field, or
+// fallback if it is missing or points to another site
+func returnPath(r *http.Request, fallback string) string {
+	path := r.FormValue("return")
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, `/\`) {
+		return fallback
+	}
+	return path
+}
@@ -0,0 +1,326 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/graphql"
+	"github.com/perbu/activity/internal/service"
+)
+
+// maxGraphQLRequestBytes limits the size of a GraphQL request body
+const maxGraphQLRequestBytes = 64 << 10
+
+// Page sizes of report lists in GraphQL queries
+const (
+	defaultGraphQLReports = 50
+	maxGraphQLReports     = 100
+)
+
+// graphqlAuthorsKey is the context key of the author aliases loaded for a
+// GraphQL request
+const graphqlAuthorsKey contextKey = "graphqlAuthors"
+
+// reportPage is the source of the ReportList type: the reports a filter
+// selects, counted and listed only when those fields are queried
+type reportPage struct {
+	filter db.ReportFilter
+}
+
+// handleGraphQL executes a GraphQL query posted as JSON, or passed in the
+// query, operationName and variables parameters of a GET request
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			dec := json.NewDecoder(strings.NewReader(vars))
+			dec.UseNumber()
+			if err := dec.Decode(&req.Variables); err != nil {
+				http.Error(w, "Invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	} else {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestBytes))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		http.Error(w, "Field 'query' is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), graphqlAuthorsKey, s.authorMap(r.Context()))
+	resp := s.schema.Execute(ctx, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGraphQLSchema serves the GraphQL schema in the schema definition
+// language, for client code generators and editors
+func (s *Server) handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, s.schema.SDL())
+}
+
+// graphqlSchema builds the schema served at /graphql
+func (s *Server) graphqlSchema() *graphql.Schema {
+	repositoryType := &graphql.Object{Name: "Repository", Description: "A git repository with weekly reports"}
+	reportType := &graphql.Object{Name: "Report", Description: "A weekly report of a repository"}
+	reportListType := &graphql.Object{Name: "ReportList", Description: "A page of weekly reports, newest week first"}
+	subscriberType := &graphql.Object{Name: "Subscriber", Description: "A newsletter subscriber"}
+
+	nonNull := func(t graphql.Type) graphql.Type { return graphql.NonNull{Of: t} }
+	listOf := func(t graphql.Type) graphql.Type {
+		return graphql.NonNull{Of: graphql.List{Of: graphql.NonNull{Of: t}}}
+	}
+	reportArgs := []*graphql.Argument{
+		{Name: "fromWeek", Type: graphql.String, Description: "First ISO week, e.g. \"2026-W02\""},
+		{Name: "toWeek", Type: graphql.String, Description: "Last ISO week, inclusive"},
+		{Name: "author", Type: graphql.String, Description: "Reports crediting this author or one of their aliases"},
+		{Name: "minCommits", Type: graphql.Int},
+		{Name: "maxCommits", Type: graphql.Int},
+		{Name: "limit", Type: graphql.Int, Default: defaultGraphQLReports, Description: "At most 100"},
+		{Name: "offset", Type: graphql.Int, Default: 0},
+	}
+
+	repositoryType.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID), Resolve: repoField(func(r *db.Repository) any { return r.ID })},
+		{Name: "name", Type: nonNull(graphql.String), Resolve: repoField(func(r *db.Repository) any { return r.Name })},
+		{Name: "url", Type: nonNull(graphql.String), Resolve: repoField(func(r *db.Repository) any { return r.URL })},
+		{Name: "branch", Type: nonNull(graphql.String), Resolve: repoField(func(r *db.Repository) any { return r.Branch })},
+		{Name: "active", Type: nonNull(graphql.Boolean), Resolve: repoField(func(r *db.Repository) any { return r.Active })},
+		{Name: "description", Type: graphql.String, Resolve: repoField(func(r *db.Repository) any {
+			if !r.Description.Valid {
+				return nil
+			}
+			return r.Description.String
+		})},
+		{Name: "lastRunAt", Type: graphql.Time, Description: "Unset if never analyzed", Resolve: repoField(func(r *db.Repository) any {
+			if !r.LastRunAt.Valid {
+				return nil
+			}
+			return r.LastRunAt.Time
+		})},
+		{Name: "reports", Type: nonNull(reportListType), Args: reportArgs, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			filter, err := s.reportFilter(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			filter.RepoID = source.(*db.Repository).ID
+			return &reportPage{filter: filter}, nil
+		}},
+		{Name: "latestReport", Type: reportType, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return s.db.GetLatestWeeklyReport(ctx, source.(*db.Repository).ID)
+		}},
+	}
+
+	reportType.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID), Resolve: reportField(func(r *db.WeeklyReport) any { return r.ID })},
+		{Name: "repository", Type: nonNull(repositoryType), Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return s.db.GetRepository(ctx, source.(*db.WeeklyReport).RepoID)
+		}},
+		{Name: "year", Type: nonNull(graphql.Int), Resolve: reportField(func(r *db.WeeklyReport) any { return r.Year })},
+		{Name: "week", Type: nonNull(graphql.Int), Resolve: reportField(func(r *db.WeeklyReport) any { return r.Week })},
+		{Name: "weekLabel", Type: nonNull(graphql.String), Description: "ISO week, e.g. \"2026-W02\"", Resolve: reportField(func(r *db.WeeklyReport) any {
+			return git.FormatISOWeek(r.Year, r.Week)
+		})},
+		{Name: "weekStart", Type: nonNull(graphql.Time), Resolve: reportField(func(r *db.WeeklyReport) any { return r.WeekStart })},
+		{Name: "weekEnd", Type: nonNull(graphql.Time), Resolve: reportField(func(r *db.WeeklyReport) any { return r.WeekEnd })},
+		{Name: "summary", Type: graphql.String, Description: "Markdown", Resolve: reportField(func(r *db.WeeklyReport) any {
+			if !r.Summary.Valid {
+				return nil
+			}
+			return r.Summary.String
+		})},
+		{Name: "summaryHTML", Type: graphql.String, Resolve: reportField(func(r *db.WeeklyReport) any {
+			if !r.Summary.Valid {
+				return nil
+			}
+			return string(s.summaryHTML(r))
+		})},
+		{Name: "commitCount", Type: nonNull(graphql.Int), Resolve: reportField(func(r *db.WeeklyReport) any { return r.CommitCount })},
+		{Name: "authors", Type: listOf(graphql.String), Description: "Authors under their canonical names", Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			r := source.(*db.WeeklyReport)
+			if !r.Metadata.Valid || r.Metadata.String == "" {
+				return []string{}, nil
+			}
+			var metadata service.ReportMetadata
+			if err := json.Unmarshal([]byte(r.Metadata.String), &metadata); err != nil {
+				return nil, fmt.Errorf("failed to parse report metadata: %w", err)
+			}
+			authorMap, _ := ctx.Value(graphqlAuthorsKey).(git.AuthorMap)
+			metadata.ResolveAuthors(authorMap)
+			return metadata.Authors, nil
+		}},
+		{Name: "agentMode", Type: nonNull(graphql.Boolean), Resolve: reportField(func(r *db.WeeklyReport) any { return r.AgentMode })},
+		{Name: "reviewState", Type: nonNull(graphql.String), Description: "\"draft\" or \"approved\"", Resolve: reportField(func(r *db.WeeklyReport) any { return r.ReviewState })},
+		{Name: "createdAt", Type: nonNull(graphql.Time), Resolve: reportField(func(r *db.WeeklyReport) any { return r.CreatedAt })},
+		{Name: "updatedAt", Type: nonNull(graphql.Time), Resolve: reportField(func(r *db.WeeklyReport) any { return r.UpdatedAt })},
+	}
+
+	reportListType.Fields = []*graphql.Field{
+		{Name: "total", Type: nonNull(graphql.Int), Description: "Reports matching the filter on all pages", Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return s.db.CountWeeklyReports(ctx, source.(*reportPage).filter)
+		}},
+		{Name: "items", Type: listOf(reportType), Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return s.db.ListWeeklyReports(ctx, source.(*reportPage).filter)
+		}},
+	}
+
+	subscriberType.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID), Resolve: subscriberField(func(s *db.Subscriber) any { return s.ID })},
+		{Name: "email", Type: nonNull(graphql.String), Resolve: subscriberField(func(s *db.Subscriber) any { return s.Email })},
+		{Name: "subscribeAll", Type: nonNull(graphql.Boolean), Resolve: subscriberField(func(s *db.Subscriber) any { return s.SubscribeAll })},
+		{Name: "timezone", Type: nonNull(graphql.String), Resolve: subscriberField(func(s *db.Subscriber) any { return s.Timezone })},
+		{Name: "sendHour", Type: nonNull(graphql.Int), Resolve: subscriberField(func(s *db.Subscriber) any { return s.SendHour })},
+		{Name: "suppressed", Type: nonNull(graphql.Boolean), Description: "Skipped by newsletters after a hard bounce", Resolve: subscriberField(func(s *db.Subscriber) any {
+			return s.SuppressedAt.Valid
+		})},
+		{Name: "repositories", Type: listOf(repositoryType), Description: "Repositories the newsletter covers", Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return s.db.GetReposForSubscriber(ctx, source.(*db.Subscriber).ID)
+		}},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{
+			Name:        "repositories",
+			Description: "Repositories of the workspace, by name",
+			Type:        listOf(repositoryType),
+			Args:        []*graphql.Argument{{Name: "active", Type: graphql.Boolean, Description: "Only active or inactive repositories"}},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				var active *bool
+				if a, ok := args["active"].(bool); ok {
+					active = &a
+				}
+				return s.db.ListRepositories(ctx, active)
+			},
+		},
+		{
+			Name: "repository",
+			Type: repositoryType,
+			Args: []*graphql.Argument{{Name: "name", Type: nonNull(graphql.String)}},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				repo, err := s.db.GetRepositoryByName(ctx, args["name"].(string))
+				if err != nil {
+					return nil, nil
+				}
+				return repo, nil
+			},
+		},
+		{
+			Name:        "reports",
+			Description: "Weekly reports of the workspace",
+			Type:        nonNull(reportListType),
+			Args:        append([]*graphql.Argument{{Name: "repository", Type: graphql.String, Description: "All repositories if unset"}}, reportArgs...),
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				filter, err := s.reportFilter(ctx, args)
+				if err != nil {
+					return nil, err
+				}
+				if name, ok := args["repository"].(string); ok {
+					repo, err := s.db.GetRepositoryByName(ctx, name)
+					if err != nil {
+						return nil, fmt.Errorf("repository not found: %s", name)
+					}
+					filter.RepoID = repo.ID
+				}
+				return &reportPage{filter: filter}, nil
+			},
+		},
+		{
+			Name: "report",
+			Type: reportType,
+			Args: []*graphql.Argument{{Name: "id", Type: nonNull(graphql.ID)}},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				id, err := strconv.ParseInt(args["id"].(string), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid report ID: %s", args["id"])
+				}
+				report, err := s.db.GetWeeklyReport(ctx, id)
+				if err != nil {
+					return nil, nil
+				}
+				return report, nil
+			},
+		},
+		{
+			Name:        "subscriptions",
+			Description: "Newsletter subscribers of the workspace and their repositories; admins only",
+			Type:        listOf(subscriberType),
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				if user := userFromContext(ctx); user == nil || !user.IsAdmin {
+					return nil, fmt.Errorf("admin access required")
+				}
+				return s.db.ListSubscribers(ctx)
+			},
+		},
+	}}
+
+	return graphql.NewSchema(query)
+}
+
+// reportFilter converts the report arguments of a GraphQL field to a filter,
+// expanding the author to all of their aliases
+func (s *Server) reportFilter(ctx context.Context, args map[string]any) (db.ReportFilter, error) {
+	filter := db.ReportFilter{
+		Limit:  min(max(args["limit"].(int), 1), maxGraphQLReports),
+		Offset: max(args["offset"].(int), 0),
+	}
+	if n, ok := args["minCommits"].(int); ok {
+		filter.MinCommits = n
+	}
+	if n, ok := args["maxCommits"].(int); ok {
+		filter.MaxCommits = n
+	}
+	for arg, week := range map[string]*int{"fromWeek": &filter.FromWeek, "toWeek": &filter.ToWeek} {
+		label, ok := args[arg].(string)
+		if !ok {
+			continue
+		}
+		year, w, err := git.ParseISOWeek(label)
+		if err != nil {
+			return filter, err
+		}
+		*week = year*100 + w
+	}
+	if author, ok := args["author"].(string); ok && author != "" {
+		authorMap, _ := ctx.Value(graphqlAuthorsKey).(git.AuthorMap)
+		filter.Authors = authorMap.Identities(author)
+	}
+	return filter, nil
+}
+
+// repoField resolves a field of a Repository with get
+func repoField(get func(*db.Repository) any) graphql.ResolveFunc {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*db.Repository)), nil
+	}
+}
+
+// reportField resolves a field of a Report with get
+func reportField(get func(*db.WeeklyReport) any) graphql.ResolveFunc {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*db.WeeklyReport)), nil
+	}
+}
+
+// subscriberField resolves a field of a Subscriber with get
+func subscriberField(get func(*db.Subscriber) any) graphql.ResolveFunc {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*db.Subscriber)), nil
+	}
+}
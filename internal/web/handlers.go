@@ -1,10 +1,13 @@
 package web
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"html/template"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,27 +15,111 @@ import (
 
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/markdown"
+	"github.com/perbu/activity/internal/service"
 	"github.com/yuin/goldmark"
 )
 
-// handleIndex serves the dashboard with recent reports
+// reportPageSize is the number of reports per page of the report lists
+const reportPageSize = 25
+
+// reportList reads the year, min_commits and page query parameters of a
+// report list into a filter for one page, fetching one extra report to know
+// whether there is a next page. It returns the filter, the page number and
+// the query to carry over to other pages.
+func reportList(r *http.Request) (db.ReportFilter, int, url.Values) {
+	query := url.Values{}
+	filter := db.ReportFilter{Limit: reportPageSize + 1}
+	if y, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil && y > 0 {
+		filter.Year = y
+		query.Set("year", strconv.Itoa(y))
+	}
+	if n, err := strconv.Atoi(r.URL.Query().Get("min_commits")); err == nil && n > 0 {
+		filter.MinCommits = n
+		query.Set("min_commits", strconv.Itoa(n))
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	filter.Offset = (page - 1) * reportPageSize
+	return filter, page, query
+}
+
+// pager returns the previous and next page links of a list at path, and
+// trims the extra item fetched to detect a next page
+func pager[T any](path string, query url.Values, page int, items []T, pageSize int) (prev, next string, trimmed []T) {
+	pageURL := func(page int) string {
+		q := url.Values{"page": {strconv.Itoa(page)}}
+		for k, v := range query {
+			q[k] = v
+		}
+		return path + "?" + q.Encode()
+	}
+	if page > 1 {
+		prev = pageURL(page - 1)
+	}
+	if len(items) > pageSize {
+		next = pageURL(page + 1)
+		items = items[:pageSize]
+	}
+	return prev, next, items
+}
+
+// handleIndex serves the dashboard with the latest reports, a page at a time,
+// filtered by repository, year and minimum commit count
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	reports, err := s.db.ListAllWeeklyReports(nil)
+	filter, page, query := reportList(r)
+
+	// Get repo names for all reports
+	repoNames := make(map[int64]string)
+	repos, _ := s.db.ListRepositories(r.Context(), nil)
+	var names []string
+	for _, repo := range repos {
+		repoNames[repo.ID] = repo.Name
+		names = append(names, repo.Name)
+	}
+	repoName := r.URL.Query().Get("repo")
+	if repoName != "" {
+		repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
+		if err != nil {
+			s.renderError(w, r, "Repository not found: "+repoName, err)
+			return
+		}
+		filter.RepoID = repo.ID
+		query.Set("repo", repoName)
+	}
+
+	reports, err := s.db.ListWeeklyReports(r.Context(), filter)
+	if err != nil {
+		s.renderError(w, r, "Failed to load reports", err)
+		return
+	}
+	total, err := s.db.CountWeeklyReports(r.Context(), filter)
 	if err != nil {
 		s.renderError(w, r, "Failed to load reports", err)
 		return
 	}
+	years, _ := s.db.ListWeeklyReportYears(r.Context(), 0)
+
+	prev, next, reports := pager(s.appURL("/"), query, page, reports, reportPageSize)
 
-	// Limit to 20 most recent
-	if len(reports) > 20 {
-		reports = reports[:20]
+	// Starred repositories' latest reports lead the unfiltered first page
+	prefs := s.userPreferences(r)
+	var favorites []ReportSummary
+	if prefs != nil && page == 1 && len(query) == 0 {
+		favorites = s.favoriteReports(r, prefs, repoNames)
 	}
 
-	// Get repo names for all reports
-	repoNames := make(map[int64]string)
-	repos, _ := s.db.ListRepositories(nil)
-	for _, repo := range repos {
-		repoNames[repo.ID] = repo.Name
+	// Stale repositories are listed on the unfiltered first page
+	var stale []RepoSummary
+	if page == 1 && len(query) == 0 {
+		now := time.Now()
+		for _, repo := range repos {
+			if weeks, ok := s.services.Repo.StaleWeeks(repo, now); ok {
+				stale = append(stale, RepoSummary{Name: repo.Name, StaleWeeks: weeks})
+			}
+		}
 	}
 
 	// Convert to view models
@@ -46,26 +133,50 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		ActiveNav: "dashboard",
 		User:      GetUser(r),
 		Content: DashboardData{
-			Reports:    summaries,
-			TotalCount: len(reports),
+			Reports:             summaries,
+			TotalCount:          total,
+			Favorites:           favorites,
+			CanStar:             prefs != nil,
+			DigestFavoritesOnly: prefs != nil && prefs.DigestFavoritesOnly,
+			Stale:               stale,
+			Repos:               names,
+			Years:               years,
+			Repo:                repoName,
+			Year:                filter.Year,
+			MinCommits:          filter.MinCommits,
+			Filtered:            len(query) > 0,
+			Page:                page,
+			PrevURL:             prev,
+			NextURL:             next,
 		},
 	}
+	if s.notModified(w, r, latestUpdate(reports), data.Content) {
+		return
+	}
 
-	s.render(w, s.templates.index, data)
+	s.render(w, r, s.templates.index, data)
 }
 
+// sparklineWeeks is the number of weeks in the repository list sparklines
+const sparklineWeeks = 12
+
 // handleRepoList serves the repository list page
 func (s *Server) handleRepoList(w http.ResponseWriter, r *http.Request) {
-	repos, err := s.db.ListRepositories(nil)
+	repos, err := s.db.ListRepositories(r.Context(), nil)
 	if err != nil {
 		s.renderError(w, r, "Failed to load repositories", err)
 		return
 	}
 
+	prefs := s.userPreferences(r)
+	now := time.Now()
+
 	// Build view models with report counts
 	summaries := make([]RepoSummary, 0, len(repos))
 	for _, repo := range repos {
-		reports, _ := s.db.ListWeeklyReportsByRepo(repo.ID, nil)
+		// The sparkline covers the last sparklineWeeks weeks, one report each
+		reports, _ := s.db.ListWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID, Limit: sparklineWeeks})
+		count, _ := s.db.CountWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID})
 		summary := RepoSummary{
 			ID:          repo.ID,
 			Name:        repo.Name,
@@ -73,13 +184,17 @@ func (s *Server) handleRepoList(w http.ResponseWriter, r *http.Request) {
 			Branch:      repo.Branch,
 			Active:      repo.Active,
 			Description: repo.Description.String,
-			ReportCount: len(reports),
+			ReportCount: count,
 			LastReport:  "No reports",
-			Sparkline:   buildSparkline(reports, 12),
+			Sparkline:   buildSparkline(reports, sparklineWeeks),
+			Favorite:    prefs != nil && prefs.IsFavorite(repo.ID),
 		}
 		if len(reports) > 0 {
 			summary.LastReport = reports[0].CreatedAt.Format("2006-01-02")
 		}
+		if weeks, ok := s.services.Repo.StaleWeeks(repo, now); ok {
+			summary.StaleWeeks = weeks
+		}
 		summaries = append(summaries, summary)
 	}
 
@@ -88,11 +203,12 @@ func (s *Server) handleRepoList(w http.ResponseWriter, r *http.Request) {
 		ActiveNav: "repos",
 		User:      GetUser(r),
 		Content: RepoListData{
-			Repos: summaries,
+			Repos:   summaries,
+			CanStar: prefs != nil,
 		},
 	}
 
-	s.render(w, s.templates.repos, data)
+	s.render(w, r, s.templates.repos, data)
 }
 
 // handleRepoReports serves the reports page for a specific repository
@@ -103,26 +219,20 @@ func (s *Server) handleRepoReports(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo, err := s.db.GetRepositoryByName(repoName)
+	repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
 	if err != nil {
 		s.renderError(w, r, "Repository not found: "+repoName, err)
 		return
 	}
 
-	// Parse year filter
-	var yearFilter *int
-	yearStr := r.URL.Query().Get("year")
-	if yearStr != "" {
-		if y, err := strconv.Atoi(yearStr); err == nil && y > 0 {
-			yearFilter = &y
-		}
-	}
-
-	reports, err := s.db.ListWeeklyReportsByRepo(repo.ID, yearFilter)
+	filter, page, query := reportList(r)
+	filter.RepoID = repo.ID
+	reports, err := s.db.ListWeeklyReports(r.Context(), filter)
 	if err != nil {
 		s.renderError(w, r, "Failed to load reports", err)
 		return
 	}
+	prev, next, reports := pager(s.appURL("/repos/"+url.PathEscape(repo.Name)), query, page, reports, reportPageSize)
 
 	// Build report summaries
 	summaries := make([]ReportSummary, 0, len(reports))
@@ -130,22 +240,12 @@ func (s *Server) handleRepoReports(w http.ResponseWriter, r *http.Request) {
 		summaries = append(summaries, toReportSummary(rpt, repo.Name))
 	}
 
-	// Collect unique years for filter
-	allReports, _ := s.db.ListWeeklyReportsByRepo(repo.ID, nil)
-	yearSet := make(map[int]bool)
-	for _, rpt := range allReports {
-		yearSet[rpt.Year] = true
-	}
-	var years []int
-	for y := range yearSet {
-		years = append(years, y)
-	}
-	sort.Sort(sort.Reverse(sort.IntSlice(years)))
-
-	currentYear := 0
-	if yearFilter != nil {
-		currentYear = *yearFilter
-	}
+	years, _ := s.db.ListWeeklyReportYears(r.Context(), repo.ID)
+	count, _ := s.db.CountWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID})
+	// The charts cover at most maxTrendWeeks weeks, one report each
+	recent, _ := s.db.ListWeeklyReports(r.Context(), db.ReportFilter{RepoID: repo.ID, Limit: maxTrendWeeks})
+	from, to := heatmapRange(filter.Year, time.Now())
+	heatmapReports, _ := s.heatmapReports(r, repo.ID, from, to)
 
 	repoSummary := RepoSummary{
 		ID:          repo.ID,
@@ -154,11 +254,26 @@ func (s *Server) handleRepoReports(w http.ResponseWriter, r *http.Request) {
 		Branch:      repo.Branch,
 		Active:      repo.Active,
 		Description: repo.Description.String,
-		ReportCount: len(allReports),
+		ReportCount: count,
 		LastReport:  "No reports",
 	}
-	if len(allReports) > 0 {
-		repoSummary.LastReport = allReports[0].CreatedAt.Format("2006-01-02")
+	prefs := s.userPreferences(r)
+	repoSummary.Favorite = prefs != nil && prefs.IsFavorite(repo.ID)
+	if len(recent) > 0 {
+		repoSummary.LastReport = recent[0].CreatedAt.Format("2006-01-02")
+	}
+
+	// The timeline is left out if the clone can't be read, e.g. in demo mode
+	tags, _ := s.services.Repo.Releases(repo, maxReleases)
+	releases := make([]ReleaseEntry, 0, len(tags))
+	for _, tag := range tags {
+		year, week := tag.Date.ISOWeek()
+		releases = append(releases, ReleaseEntry{
+			Name:    tag.Name,
+			Date:    tag.Date.Format("2006-01-02"),
+			Week:    git.FormatISOWeek(year, week),
+			Subject: tag.Subject,
+		})
 	}
 
 	data := PageData{
@@ -169,11 +284,45 @@ func (s *Server) handleRepoReports(w http.ResponseWriter, r *http.Request) {
 			Repo:        repoSummary,
 			Reports:     summaries,
 			Years:       years,
-			CurrentYear: currentYear,
+			CurrentYear: filter.Year,
+			Charts:      buildTrendCharts(buildTrends(recent, s.authorMap(r.Context()))),
+			Heatmap:     buildHeatmapChart(buildHeatmap(heatmapReports, from, to), from, to),
+			CanStar:     prefs != nil,
+			Releases:    releases,
+			Page:        page,
+			PrevURL:     prev,
+			NextURL:     next,
 		},
 	}
 
-	s.render(w, s.templates.repoDetail, data)
+	s.render(w, r, s.templates.repoDetail, data)
+}
+
+// handleRepoTrends serves the aggregated weekly trend series for a repository as JSON
+func (s *Server) handleRepoTrends(w http.ResponseWriter, r *http.Request) {
+	repoName := r.PathValue("name")
+	repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
+	if err != nil {
+		http.Error(w, "Repository not found: "+repoName, http.StatusNotFound)
+		return
+	}
+
+	reports, err := s.db.ListWeeklyReportsByRepo(r.Context(), repo.ID, nil)
+	if err != nil {
+		http.Error(w, "Failed to load reports: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := TrendsResponse{
+		Repo:   repo.Name,
+		Points: buildTrends(reports, s.authorMap(r.Context())),
+	}
+	if resp.Points == nil {
+		resp.Points = []TrendPoint{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 // handleReportView serves a single report detail page
@@ -185,41 +334,130 @@ func (s *Server) handleReportView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	report, err := s.db.GetWeeklyReport(id)
+	report, err := s.db.GetWeeklyReport(r.Context(), id)
 	if err != nil {
 		s.renderError(w, r, "Report not found", err)
 		return
 	}
 
 	// Get repo name
-	repo, err := s.db.GetRepository(report.RepoID)
+	repo, err := s.db.GetRepository(r.Context(), report.RepoID)
 	if err != nil {
 		s.renderError(w, r, "Repository not found", err)
 		return
 	}
 
-	detail := toReportDetail(report, repo.Name)
+	related := s.relatedWeeks(r.Context(), report.ID)
+	if s.notModified(w, r, report.UpdatedAt, report.ID, report.UpdatedAt.UnixNano(), report.ReviewState,
+		report.ApprovedAt.Time.UnixNano(), report.RegeneratedAt.Time.UnixNano(), repo.Name, related) {
+		return
+	}
+
+	detail := toReportDetail(report, repo.Name, s.authorMap(r.Context()), s.summaryHTML)
 
 	data := PageData{
 		Title:     repo.Name + " " + detail.WeekLabel,
 		ActiveNav: "",
 		User:      GetUser(r),
 		Content: ReportViewData{
-			Report: detail,
+			Report:  detail,
+			Related: related,
 		},
 	}
 
-	s.render(w, s.templates.report, data)
+	s.render(w, r, s.templates.report, data)
+}
+
+// handleReportCompare serves a report side by side with the previous week's
+// report and a generated summary of what changed
+func (s *Server) handleReportCompare(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.renderError(w, r, "Invalid report ID", err)
+		return
+	}
+
+	comparison, err := s.services.Report.Compare(r.Context(), id)
+	if comparison == nil {
+		s.renderError(w, r, "Report not found", err)
+		return
+	}
+
+	authorMap := s.authorMap(r.Context())
+	content := ReportCompareData{
+		Current: toReportDetail(comparison.Current, comparison.Repo.Name, authorMap, s.summaryHTML),
+		Delta:   renderMarkdown(comparison.Delta),
+	}
+	if comparison.Previous != nil {
+		previous := toReportDetail(comparison.Previous, comparison.Repo.Name, authorMap, s.summaryHTML)
+		content.Previous = &previous
+	}
+	if err != nil {
+		slog.Error("Failed to compare reports", "report_id", id, "error", err)
+		content.DeltaError = err.Error()
+	}
+
+	data := PageData{
+		Title:     comparison.Repo.Name + " " + content.Current.WeekLabel + " compared",
+		ActiveNav: "",
+		User:      GetUser(r),
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.compare, data)
 }
 
-// render executes a template and writes to the response
-func (s *Server) render(w http.ResponseWriter, tmpl *template.Template, data PageData) {
+// render executes a template and writes to the response. The current
+// workspace, the workspaces to switch to and the user's notifications are
+// filled in for the nav bar.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, tmpl *template.Template, data PageData) {
+	if ws := GetWorkspace(r); ws != nil {
+		data.Workspace = ws.Name
+	}
+	data.CSRFToken = CSRFToken(r)
+	data.CSPNonce = CSPNonce(r)
+	data.CurrentURL = r.URL.RequestURI()
+	data.Theme = theme(r)
+	data.Plain = plain(r)
+	data.Leaderboard = s.cfg.Current().Leaderboard.Enabled
+	data.Lang = language(r)
+	data.Languages = otherLanguages(data.Lang)
+	data.ReadOnly = s.cfg.Web.ReadOnly
+	data.Branding = s.branding()
+	s.loadNotifications(r, &data)
+	if canSwitchWorkspace(r) && !data.ReadOnly {
+		if workspaces, err := s.services.Workspace.List(r.Context()); err == nil && len(workspaces) > 1 {
+			for _, ws := range workspaces {
+				data.Workspaces = append(data.Workspaces, ws.Name)
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Add("Vary", "Accept-Language")
 	if err := tmpl.Execute(w, data); err != nil {
 		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// branding returns the configured look of the site. It is read on each
+// render, so a reloaded config applies without a restart.
+func (s *Server) branding() Branding {
+	b := s.cfg.Current().Branding
+	hover := b.AccentHoverColor
+	if hover == "" {
+		hover = b.AccentColor
+	}
+	return Branding{
+		SiteName:         s.cfg.GetSiteName(),
+		LogoURL:          b.LogoURL,
+		FooterText:       b.FooterText,
+		AccentColor:      b.AccentColor,
+		AccentHoverColor: hover,
+		NavLinks:         s.cfg.GetNavLinks(),
+	}
+}
+
 // renderError renders an error page
 func (s *Server) renderError(w http.ResponseWriter, r *http.Request, message string, err error) {
 	errMsg := message
@@ -236,7 +474,7 @@ func (s *Server) renderError(w http.ResponseWriter, r *http.Request, message str
 	}
 
 	w.WriteHeader(http.StatusInternalServerError)
-	s.render(w, s.templates.index, data)
+	s.render(w, r, s.templates.index, data)
 }
 
 // toReportSummary converts a db.WeeklyReport to a ReportSummary view model
@@ -320,13 +558,33 @@ func buildSparkline(reports []*db.WeeklyReport, weeks int) []SparklineBar {
 		sparkline[i] = SparklineBar{
 			Value:  count,
 			Height: height,
+			Y:      100 - height,
 		}
 	}
 	return sparkline
 }
 
-// toReportDetail converts a db.WeeklyReport to a ReportDetail view model
-func toReportDetail(r *db.WeeklyReport, repoName string) ReportDetail {
+// authorMap returns the author aliases for merging identities in views. A
+// failure to load them is logged and leaves author names unmerged.
+func (s *Server) authorMap(ctx context.Context) git.AuthorMap {
+	authorMap, err := s.services.Author.AuthorMap(ctx)
+	if err != nil {
+		slog.Warn("Failed to load author aliases", "error", err)
+	}
+	return authorMap
+}
+
+// markdown returns the converter for report summaries, which links Jira
+// ticket keys when Jira is configured and headings to themselves. Headings
+// start at <h2>, below the page's title.
+func (s *Server) markdown() goldmark.Markdown {
+	return markdown.New(service.MarkdownExtensions(s.cfg), markdown.WithHeadingLinks(), markdown.WithTopHeading(2))
+}
+
+// toReportDetail converts a db.WeeklyReport to a ReportDetail view model,
+// showing authors under their canonical names and rendering the summary with
+// renderSummary
+func toReportDetail(r *db.WeeklyReport, repoName string, authorMap git.AuthorMap, renderSummary func(*db.WeeklyReport) template.HTML) ReportDetail {
 	detail := ReportDetail{
 		ID:          r.ID,
 		RepoID:      r.RepoID,
@@ -342,23 +600,43 @@ func toReportDetail(r *db.WeeklyReport, repoName string) ReportDetail {
 		UpdatedAt:   r.UpdatedAt.Format("2006-01-02 15:04"),
 	}
 
-	// Parse authors from metadata
+	// Parse authors and excluded automated commits from metadata
 	if r.Metadata.Valid && r.Metadata.String != "" {
-		var metadata struct {
-			Authors []string `json:"authors"`
-		}
+		var metadata service.ReportMetadata
 		if err := json.Unmarshal([]byte(r.Metadata.String), &metadata); err == nil {
+			metadata.ResolveAuthors(authorMap)
 			detail.Authors = metadata.Authors
+			detail.AutomatedCommits = metadata.AutomatedCommits
+			for author, count := range metadata.AutomatedAuthorCounts {
+				detail.AutomatedAuthors = append(detail.AutomatedAuthors, fmt.Sprintf("%s (%d)", author, count))
+			}
+			sort.Strings(detail.AutomatedAuthors)
+			detail.PullRequests = metadata.PullRequests
+			detail.AvgPRSize = metadata.AvgPullRequestSize()
+			detail.MergeCommits = metadata.MergeCommits
+			detail.DirectCommits = metadata.DirectCommits
 		}
 	}
 
+	if r.EditedAt.Valid {
+		detail.EditedBy = r.EditedBy.String
+		detail.EditedAt = r.EditedAt.Time.Format("2006-01-02 15:04")
+	}
+	if r.RegeneratedAt.Valid {
+		detail.RegenerationNote = r.RegenerationNote.String
+		detail.RegeneratedBy = r.RegeneratedBy.String
+		detail.RegeneratedAt = r.RegeneratedAt.Time.Format("2006-01-02 15:04")
+	}
+	detail.Draft = r.ReviewState == db.ReviewDraft
+	if r.ApprovedAt.Valid {
+		detail.ApprovedBy = r.ApprovedBy.String
+		detail.ApprovedAt = r.ApprovedAt.Time.Format("2006-01-02 15:04")
+	}
+
 	// Convert summary markdown to HTML
 	if r.Summary.Valid && r.Summary.String != "" {
 		detail.Summary = r.Summary.String
-		var buf bytes.Buffer
-		if err := goldmark.Convert([]byte(r.Summary.String), &buf); err == nil {
-			detail.SummaryHTML = template.HTML(buf.String())
-		}
+		detail.SummaryHTML = renderSummary(r)
 	}
 
 	return detail
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/service"
+)
+
+// heatmapWeeks is how many weeks the heatmap covers without a year
+const heatmapWeeks = 53
+
+// Heatmap geometry in SVG user units: a column of seven day cells per week,
+// Monday at the top, below a row of month labels
+const (
+	heatmapCell   = 10
+	heatmapSlot   = 12
+	heatmapLabels = 12
+)
+
+// HeatmapDay is the number of commits on a day
+type HeatmapDay struct {
+	Date    string `json:"date"`
+	Commits int    `json:"commits"`
+}
+
+// HeatmapResponse is the JSON payload served at /repos/{name}/heatmap.json
+type HeatmapResponse struct {
+	Repo  string       `json:"repo"`
+	From  string       `json:"from"`
+	To    string       `json:"to"`
+	Total int          `json:"total"`
+	Days  []HeatmapDay `json:"days"`
+}
+
+// heatmapRange returns the first and last day of the heatmap: the calendar
+// year up to today, or with year 0 the heatmapWeeks weeks up to and
+// including today
+func heatmapRange(year int, today time.Time) (from, to time.Time) {
+	to = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	if year != 0 {
+		end := time.Date(year, 12, 31, 0, 0, 0, 0, time.Local)
+		if end.Before(to) {
+			to = end
+		}
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.Local), to
+	}
+	monday := to.AddDate(0, 0, -(int(to.Weekday())+6)%7)
+	return monday.AddDate(0, 0, -7*(heatmapWeeks-1)), to
+}
+
+// heatmapReports loads the reports of a repository's weeks that overlap the
+// heatmap's days
+func (s *Server) heatmapReports(r *http.Request, repoID int64, from, to time.Time) ([]*db.WeeklyReport, error) {
+	fromYear, fromWeek := from.ISOWeek()
+	toYear, toWeek := to.ISOWeek()
+	return s.db.ListWeeklyReports(r.Context(), db.ReportFilter{
+		RepoID:   repoID,
+		FromWeek: fromYear*100 + fromWeek,
+		ToWeek:   toYear*100 + toWeek,
+	})
+}
+
+// buildHeatmap counts the commits of each day from the daily counts in the
+// reports' metadata. Days without a report had no commits. Days of weeks
+// whose report predates daily counts are left out, as their commits can't
+// be placed on a day.
+func buildHeatmap(reports []*db.WeeklyReport, from, to time.Time) []HeatmapDay {
+	counts := make(map[string]int)
+	unknown := make(map[string]bool)
+	for _, r := range reports {
+		var metadata service.ReportMetadata
+		if r.Metadata.Valid {
+			_ = json.Unmarshal([]byte(r.Metadata.String), &metadata)
+		}
+		if metadata.DailyCommits == nil && r.CommitCount > 0 {
+			unknown[git.FormatISOWeek(r.Year, r.Week)] = true
+			continue
+		}
+		for day, n := range metadata.DailyCommits {
+			counts[day] += n
+		}
+	}
+
+	days := []HeatmapDay{}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if unknown[git.FormatISOWeek(d.ISOWeek())] {
+			continue
+		}
+		date := d.Format("2006-01-02")
+		days = append(days, HeatmapDay{Date: date, Commits: counts[date]})
+	}
+	return days
+}
+
+// buildHeatmapChart lays out the days from "from" to "to" as an SVG grid,
+// shading each day in five levels relative to the busiest day. Days missing
+// from days have no data.
+func buildHeatmapChart(days []HeatmapDay, from, to time.Time) *HeatmapChart {
+	byDate := make(map[string]int, len(days))
+	maxVal := 1
+	total := 0
+	for _, d := range days {
+		byDate[d.Date] = d.Commits
+		maxVal = max(maxVal, d.Commits)
+		total += d.Commits
+	}
+
+	chart := &HeatmapChart{
+		Height: heatmapLabels + 7*heatmapSlot,
+		Total:  total,
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+	}
+	// The grid starts on the Monday of the first day's week
+	offset := (int(from.Weekday()) + 6) % 7
+	for d := from; !d.After(to); d, offset = d.AddDate(0, 0, 1), offset+1 {
+		x := offset / 7 * heatmapSlot
+		if d.Day() == 1 || (d.Equal(from) && d.Day() < 15) {
+			chart.Months = append(chart.Months, HeatmapLabel{X: x, Text: d.Format("Jan")})
+		}
+
+		date := d.Format("2006-01-02")
+		cell := HeatmapCell{X: x, Y: heatmapLabels + offset%7*heatmapSlot, Size: heatmapCell, Class: "heat-none"}
+		if n, ok := byDate[date]; ok {
+			cell.Class = fmt.Sprintf("heat-%d", heatLevel(n, maxVal))
+			cell.Label = fmt.Sprintf("%s: %d commits", date, n)
+		} else {
+			cell.Label = date + ": no daily data"
+		}
+		chart.Cells = append(chart.Cells, cell)
+		chart.Width = x + heatmapSlot
+	}
+	return chart
+}
+
+// heatLevel maps a day's commits to a shade from 0 (none) to 4 (the
+// busiest days)
+func heatLevel(commits, maxVal int) int {
+	if commits == 0 {
+		return 0
+	}
+	return (commits*4 + maxVal - 1) / maxVal
+}
+
+// handleRepoHeatmap serves the commits per day of a repository as JSON, for
+// a calendar year with ?year=2026 or else the past year
+func (s *Server) handleRepoHeatmap(w http.ResponseWriter, r *http.Request) {
+	repoName := r.PathValue("name")
+	repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
+	if err != nil {
+		http.Error(w, "Repository not found: "+repoName, http.StatusNotFound)
+		return
+	}
+	year := 0
+	if v := r.URL.Query().Get("year"); v != "" {
+		if year, err = strconv.Atoi(v); err != nil || year < 1970 || year > 9999 {
+			http.Error(w, "Invalid year: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	from, to := heatmapRange(year, time.Now())
+	reports, err := s.heatmapReports(r, repo.ID, from, to)
+	if err != nil {
+		http.Error(w, "Failed to load reports: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := HeatmapResponse{
+		Repo: repo.Name,
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+		Days: buildHeatmap(reports, from, to),
+	}
+	for _, d := range resp.Days {
+		resp.Total += d.Commits
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
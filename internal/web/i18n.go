@@ -0,0 +1,185 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// langCookie holds the UI language chosen in the browser. Without it the
+// language is negotiated from the Accept-Language header.
+const langCookie = "lang"
+
+// langCookieMaxAge is how long a chosen language is remembered, in seconds
+const langCookieMaxAge = 365 * 24 * 60 * 60
+
+// defaultLanguage is the language of the UI text in the templates, used when
+// the browser asks for none of the supported languages
+const defaultLanguage = "en"
+
+// Language is a language the UI chrome can be shown in
+type Language struct {
+	Code   string // BCP 47 primary tag, as in <html lang>
+	Name   string // Name of the language in itself
+	Switch string // Title of the button that switches to it, in itself
+}
+
+// languages are the supported UI languages, the default first
+var languages = []Language{
+	{Code: "en", Name: "english", Switch: "Show the UI in English"},
+	{Code: "nb", Name: "norsk", Switch: "Vis grensesnittet på norsk"},
+}
+
+// languageAliases maps the tags browsers send for a supported language to
+// its code. Norwegian is sent as "no", "nb" or "nn", and Nynorsk readers
+// are better served by Bokmål than by English.
+var languageAliases = map[string]string{
+	"no": "nb",
+	"nn": "nb",
+}
+
+// messages translates the UI text in the templates, keyed by language and
+// the English text. Text without a translation is shown in English, so a
+// template override can use t with text of its own.
+var messages = map[string]map[string]string{
+	"nb": {
+		"dashboard":                            "oversikt",
+		"repos":                                "repoer",
+		"search":                               "søk",
+		"leaderboard":                          "toppliste",
+		"switch":                               "bytt",
+		"Workspace":                            "Arbeidsområde",
+		"Notifications":                        "Varsler",
+		"unread":                               "ulest",
+		"No notifications":                     "Ingen varsler",
+		"all notifications":                    "alle varsler",
+		"mark all read":                        "merk alle som lest",
+		"dark":                                 "mørk",
+		"light":                                "lys",
+		"Switch to the dark theme":             "Bytt til mørkt tema",
+		"Switch to the light theme":            "Bytt til lyst tema",
+		"Find a repository or report (Ctrl+K)": "Finn et repo eller en rapport (Ctrl+K)",
+		"Find a repository or report":          "Finn et repo eller en rapport",
+		"Find a repository, report text or week (2026-W02)": "Finn et repo, rapporttekst eller uke (2026-W02)",
+		"Results":         "Resultater",
+		"move":            "flytt",
+		"open":            "åpne",
+		"close":           "lukk",
+		"No matches":      "Ingen treff",
+		"Search failed":   "Søket mislyktes",
+		"Skip to content": "Hopp til innholdet",
+		"Main":            "Hovedmeny",
+		"styled":          "stilsatt",
+		"plain":           "enkel",
+		"Show pages as plain HTML, for screen readers": "Vis sidene som enkel HTML, for skjermlesere",
+	},
+}
+
+// translate returns the text of msg in lang, or msg if it has no translation.
+// It is the templates' t function: {{t $.Lang "dashboard"}}.
+func translate(lang, msg string) string {
+	if s, ok := messages[lang][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// supportedLanguage returns the code of the supported language tag names,
+// or "" if it is not supported
+func supportedLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if code, ok := languageAliases[primary]; ok {
+		return code
+	}
+	if slices.ContainsFunc(languages, func(l Language) bool { return l.Code == primary }) {
+		return primary
+	}
+	return ""
+}
+
+// language returns the UI language of the request: the one chosen in the
+// browser, or else the most preferred supported one in Accept-Language
+func language(r *http.Request) string {
+	if c, err := r.Cookie(langCookie); err == nil {
+		if code := supportedLanguage(c.Value); code != "" {
+			return code
+		}
+	}
+	return negotiateLanguage(r.Header.Get("Accept-Language"))
+}
+
+// negotiateLanguage returns the supported language with the highest quality
+// in an Accept-Language header, or the default language
+func negotiateLanguage(header string) string {
+	type candidate struct {
+		code string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		code := supportedLanguage(tag)
+		if code == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{code, q})
+		}
+	}
+	// Stable, so equally preferred languages keep the header's order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	if len(candidates) == 0 {
+		return defaultLanguage
+	}
+	return candidates[0].code
+}
+
+// otherLanguages returns the supported languages other than lang, for the
+// language switcher in the nav bar
+func otherLanguages(lang string) []Language {
+	var others []Language
+	for _, l := range languages {
+		if l.Code != lang {
+			others = append(others, l)
+		}
+	}
+	return others
+}
+
+// handleLanguage switches the browser's UI language and returns to the page
+// the form was on. Like the theme, it is kept in a cookie, so anonymous
+// readers and read-only mirrors can use it too.
+func (s *Server) handleLanguage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	value := r.FormValue("lang")
+	code := supportedLanguage(value)
+	if code == "" {
+		http.Error(w, "Unknown language: "+value, http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     langCookie,
+		Value:    code,
+		Path:     "/",
+		MaxAge:   langCookieMaxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
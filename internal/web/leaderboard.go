@@ -0,0 +1,138 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/analyzer"
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/service"
+)
+
+// minMentionLength is the shortest name counted as mentioned in a summary,
+// so initials and short handles don't match inside other words
+const minMentionLength = 4
+
+// handleLeaderboard serves the contributor leaderboard of a month (?month=
+// 2026-01, default the current one) when leaderboard.enabled is set
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg.Current().Leaderboard.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	month := thisMonth
+	if m, err := time.ParseInLocation("2006-01", r.URL.Query().Get("month"), time.Local); err == nil && !m.After(thisMonth) {
+		month = m
+	}
+	end := month.AddDate(0, 1, -1)
+
+	fromYear, fromWeek := month.ISOWeek()
+	toYear, toWeek := end.ISOWeek()
+	reports, err := s.db.ListWeeklyReports(r.Context(), db.ReportFilter{
+		FromWeek: fromYear*100 + fromWeek,
+		ToWeek:   toYear*100 + toWeek,
+	})
+	if err != nil {
+		s.renderError(w, r, "Failed to load reports", err)
+		return
+	}
+
+	content := LeaderboardData{
+		Month:   month.Format("January 2006"),
+		Rows:    buildLeaderboard(reports, month, s.authorMap(r.Context()), s.cfg.Current().Leaderboard.OptOut),
+		PrevURL: s.appURL("/leaderboard?month=" + month.AddDate(0, -1, 0).Format("2006-01")),
+	}
+	if month.Before(thisMonth) {
+		content.NextURL = s.appURL("/leaderboard?month=" + month.AddDate(0, 1, 0).Format("2006-01"))
+	}
+
+	data := PageData{
+		Title:     "Leaderboard " + content.Month,
+		ActiveNav: "leaderboard",
+		User:      GetUser(r),
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.leaderboard, data)
+}
+
+// buildLeaderboard ranks the contributors of the reports whose ISO week
+// falls in month (by its Thursday, as ISO weeks are assigned to years) by
+// commits, then by the number of summaries mentioning them by name. Authors
+// are counted under their canonical names in authorMap; those matching
+// optOut are left out.
+func buildLeaderboard(reports []*db.WeeklyReport, month time.Time, authorMap git.AuthorMap, optOut []string) []LeaderboardRow {
+	rows := make(map[string]*LeaderboardRow)
+	repos := make(map[string]map[int64]bool)
+	row := func(name string, repoID int64) *LeaderboardRow {
+		if rows[name] == nil {
+			rows[name] = &LeaderboardRow{Name: name}
+			repos[name] = make(map[int64]bool)
+		}
+		if !repos[name][repoID] {
+			repos[name][repoID] = true
+			rows[name].Repos++
+		}
+		return rows[name]
+	}
+
+	var summaries []string
+	for _, r := range reports {
+		thursday := r.WeekStart.AddDate(0, 0, 3)
+		if thursday.Year() != month.Year() || thursday.Month() != month.Month() {
+			continue
+		}
+		summaries = append(summaries, strings.ToLower(r.Summary.String))
+		if !r.Metadata.Valid {
+			continue
+		}
+		var metadata service.ReportMetadata
+		if err := json.Unmarshal([]byte(r.Metadata.String), &metadata); err != nil {
+			continue
+		}
+		metadata.ResolveAuthors(authorMap)
+		for name, n := range metadata.AuthorCounts {
+			row(name, r.RepoID).Commits += n
+		}
+		for name, n := range metadata.CoAuthorCounts {
+			row(name, r.RepoID).CoAuthored += n
+		}
+	}
+
+	var ranked []LeaderboardRow
+	for name, row := range rows {
+		if analyzer.MatchesAuthor(name, optOut) {
+			continue
+		}
+		if len(name) >= minMentionLength {
+			lower := strings.ToLower(name)
+			for _, summary := range summaries {
+				if strings.Contains(summary, lower) {
+					row.Mentions++
+				}
+			}
+		}
+		ranked = append(ranked, *row)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		if a.Mentions != b.Mentions {
+			return a.Mentions > b.Mentions
+		}
+		return a.Name < b.Name
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// newsletterSendsPageSize is the number of batches per send history page
+const newsletterSendsPageSize = 50
+
+// handleAdminNewsletterSends serves the newsletter send history, a page of
+// batches at a time
+func (s *Server) handleAdminNewsletterSends(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	// Fetch one extra batch to know whether there is a next page
+	batches, err := s.db.ListNewsletterBatches(r.Context(), newsletterSendsPageSize+1, (page-1)*newsletterSendsPageSize)
+	if err != nil {
+		s.renderError(w, r, "Failed to load send history", err)
+		return
+	}
+
+	content := AdminNewsletterSendsData{Page: page}
+	content.PrevURL, content.NextURL, batches = pager(s.appURL("/admin/newsletter/sends"), nil, page, batches, newsletterSendsPageSize)
+	for _, batch := range batches {
+		content.Batches = append(content.Batches, s.toBatchSummary(batch))
+	}
+
+	data := PageData{
+		Title:     "Admin - Newsletter Sends",
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.adminNewsletterSends, data)
+}
+
+// handleAdminNewsletterSend serves a batch of the send history with the
+// outcome of each delivery
+func (s *Server) handleAdminNewsletterSend(w http.ResponseWriter, r *http.Request) {
+	batch, ok := s.newsletterBatch(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := s.db.ListNewsletterDeliveries(r.Context(), batch.ID)
+	if err != nil {
+		s.renderError(w, r, "Failed to load deliveries", err)
+		return
+	}
+	events, err := s.db.ListNewsletterDeliveryEvents(r.Context(), batch.ID)
+	if err != nil {
+		s.renderError(w, r, "Failed to load delivery events", err)
+		return
+	}
+	eventsByDelivery := make(map[int64]*db.DeliveryEvents, len(events))
+	for _, e := range events {
+		eventsByDelivery[e.DeliveryID] = e
+	}
+
+	content := AdminNewsletterSendData{Batch: s.toBatchSummary(batch)}
+	for _, d := range deliveries {
+		summary := NewsletterDeliverySummary{
+			Email:     d.Email,
+			Status:    d.Status,
+			Subject:   d.Subject,
+			Reports:   d.ReportCount,
+			MessageID: d.MessageID.String,
+			Error:     d.Error.String,
+			Attempts:  d.Attempts,
+			Window:    d.WindowStart.Format("2006-01-02") + " - " + d.WindowEnd.Format("2006-01-02"),
+			UpdatedAt: d.UpdatedAt.Format("2006-01-02 15:04"),
+		}
+		if e, ok := eventsByDelivery[d.ID]; ok {
+			summary.Delivered, summary.Bounced, summary.Opened = e.Delivered, e.Bounced, e.Opened
+		}
+		content.Deliveries = append(content.Deliveries, summary)
+	}
+
+	data := PageData{
+		Title:     fmt.Sprintf("Admin - Newsletter Send #%d", batch.ID),
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.adminNewsletterSend, data)
+}
+
+// handleAdminNewsletterResend resends the failed deliveries of a batch and
+// returns to it
+func (s *Server) handleAdminNewsletterResend(w http.ResponseWriter, r *http.Request) {
+	batch, ok := s.newsletterBatch(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	result, err := s.services.Newsletter.ResendFailed(ctx, batch.ID, os.Stdout)
+	if err != nil {
+		slog.Error("Failed to resend newsletters", "batch", batch.ID, "error", err)
+		http.Error(w, "Failed to resend newsletters: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	msg := fmt.Sprintf("Resent %d newsletters (skipped %d, errors %d)", result.Sent, result.Skipped, result.Errors)
+	s.audit(r, auditNewsletterResend, fmt.Sprintf("send #%d", batch.ID), msg)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/newsletter/sends/%d", batch.ID), http.StatusSeeOther)
+}
+
+// newsletterBatch loads the batch named by the "id" path value, rendering
+// an error if it is not found in the request's workspace
+func (s *Server) newsletterBatch(w http.ResponseWriter, r *http.Request) (*db.NewsletterBatch, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.renderError(w, r, "Invalid send ID", err)
+		return nil, false
+	}
+	batch, err := s.db.GetNewsletterBatch(r.Context(), id)
+	if err != nil {
+		s.renderError(w, r, "Send not found", err)
+		return nil, false
+	}
+	return batch, true
+}
+
+// toBatchSummary converts a send history batch for display
+func (s *Server) toBatchSummary(batch *db.NewsletterBatch) NewsletterBatchSummary {
+	startedBy := batch.StartedBy.String
+	switch {
+	case batch.Kind == db.BatchScheduled:
+		startedBy = "schedule"
+	case startedBy == "":
+		startedBy = "command line"
+	}
+	return NewsletterBatchSummary{
+		ID:        batch.ID,
+		StartedAt: batch.StartedAt.Format("2006-01-02 15:04"),
+		Kind:      batch.Kind,
+		StartedBy: startedBy,
+		Sent:      batch.Sent,
+		Failed:    batch.Failed,
+		URL:       s.appURL(fmt.Sprintf("/admin/newsletter/sends/%d", batch.ID)),
+	}
+}
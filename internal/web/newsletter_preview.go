@@ -0,0 +1,58 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/perbu/activity/internal/service"
+)
+
+// handleAdminNewsletterPreview shows the newsletter a subscriber would be
+// sent now, rendered exactly as it would be sent. Query parameters:
+// subscriber (email, default the first subscriber) and since (default 7d).
+func (s *Server) handleAdminNewsletterPreview(w http.ResponseWriter, r *http.Request) {
+	subscribers, err := s.db.ListSubscribers(r.Context())
+	if err != nil {
+		s.renderError(w, r, "Failed to load subscribers", err)
+		return
+	}
+
+	content := AdminNewsletterPreviewData{
+		Subscriber: r.URL.Query().Get("subscriber"),
+		Since:      r.URL.Query().Get("since"),
+	}
+	for _, sub := range subscribers {
+		content.Subscribers = append(content.Subscribers, sub.Email)
+	}
+	if content.Subscriber == "" && len(content.Subscribers) > 0 {
+		content.Subscriber = content.Subscribers[0]
+	}
+	if content.Since == "" {
+		content.Since = "7d"
+	}
+
+	var errMsg string
+	if content.Subscriber != "" {
+		since, err := service.ParseSinceDuration(content.Since)
+		if err != nil {
+			errMsg = "Invalid duration: " + err.Error()
+		} else if preview, err := s.services.Newsletter.Preview(r.Context(), content.Subscriber, since); err != nil {
+			errMsg = "Failed to preview newsletter: " + err.Error()
+		} else if preview != nil {
+			content.Subject = preview.Subject
+			content.HTML = preview.HTMLContent
+			content.Text = preview.TextContent
+		}
+	}
+
+	data := PageData{
+		Title:     "Admin - Newsletter Preview",
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Error:     errMsg,
+		Content:   content,
+	}
+
+	// The preview frame inherits the page's policy, and email HTML is styled inline
+	s.allowInlineStyles(w, r)
+	s.render(w, r, s.templates.adminNewsletterPreview, data)
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/perbu/activity/internal/db"
+)
+
+// notificationPanelSize is the number of notifications in the nav bar panel
+const notificationPanelSize = 8
+
+// notificationPageSize bounds the notifications page
+const notificationPageSize = 100
+
+// notificationRecipient returns the email notifications are addressed to,
+// or "" for anonymous requests and API tokens
+func notificationRecipient(r *http.Request) string {
+	user := GetUser(r)
+	if user == nil || user.Token {
+		return ""
+	}
+	return user.Email
+}
+
+// loadNotifications fills in the nav bar notification panel
+func (s *Server) loadNotifications(r *http.Request, data *PageData) {
+	recipient := notificationRecipient(r)
+	if recipient == "" {
+		return
+	}
+	unread, err := s.db.CountUnreadNotifications(r.Context(), recipient)
+	if err != nil {
+		slog.Error("Failed to count notifications", "user", recipient, "error", err)
+		return
+	}
+	notifications, err := s.db.ListNotifications(r.Context(), recipient, notificationPanelSize)
+	if err != nil {
+		slog.Error("Failed to load notifications", "user", recipient, "error", err)
+		return
+	}
+	data.UnreadCount = unread
+	data.Notifications = toNotificationItems(notifications)
+}
+
+// toNotificationItems converts notifications to view models
+func toNotificationItems(notifications []*db.Notification) []NotificationItem {
+	items := make([]NotificationItem, 0, len(notifications))
+	for _, n := range notifications {
+		items = append(items, NotificationItem{
+			ID:        n.ID,
+			Title:     n.Title,
+			CreatedAt: n.CreatedAt.Format("2006-01-02 15:04"),
+			Unread:    !n.ReadAt.Valid,
+		})
+	}
+	return items
+}
+
+// handleNotifications serves the signed-in user's notifications
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	recipient := notificationRecipient(r)
+	if recipient == "" {
+		http.Error(w, "Forbidden: API tokens have no notifications", http.StatusForbidden)
+		return
+	}
+	notifications, err := s.db.ListNotifications(r.Context(), recipient, notificationPageSize)
+	if err != nil {
+		s.renderError(w, r, "Failed to load notifications", err)
+		return
+	}
+
+	data := PageData{
+		Title:   "Notifications",
+		User:    GetUser(r),
+		Content: NotificationsData{Notifications: toNotificationItems(notifications)},
+	}
+	s.render(w, r, s.templates.notifications, data)
+}
+
+// handleNotificationOpen marks a notification read and redirects to what it
+// is about
+func (s *Server) handleNotificationOpen(w http.ResponseWriter, r *http.Request) {
+	recipient := notificationRecipient(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if recipient == "" || err != nil {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	notification, err := s.db.GetNotification(r.Context(), recipient, id)
+	if err != nil {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err := s.db.MarkNotificationsRead(r.Context(), recipient, id); err != nil {
+		slog.Error("Failed to mark notification read", "id", id, "error", err)
+	}
+
+	link := notification.Link
+	if link == "" {
+		link = "/notifications"
+	}
+	http.Redirect(w, r, link, http.StatusSeeOther)
+}
+
+// handleNotificationsRead marks all of the user's notifications read
+func (s *Server) handleNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	recipient := notificationRecipient(r)
+	if recipient == "" {
+		http.Error(w, "Forbidden: API tokens have no notifications", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	if err := s.db.MarkNotificationsRead(r.Context(), recipient, 0); err != nil {
+		s.renderError(w, r, "Failed to mark notifications read", err)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/notifications"), http.StatusSeeOther)
+}
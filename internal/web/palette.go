@@ -0,0 +1,176 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// snippetLength is the length of the summary excerpt shown with a report
+// found by the search palette
+const snippetLength = 80
+
+// PaletteResult is a repository or report found by the search palette
+type PaletteResult struct {
+	Kind   string `json:"kind"` // "repo" or "report"
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url"`
+}
+
+// PaletteResponse is the JSON payload served at /api/search
+type PaletteResponse struct {
+	Query   string          `json:"query"`
+	Results []PaletteResult `json:"results"`
+}
+
+// handlePaletteSearch serves the search palette: repositories whose name or
+// description contains the query, then reports whose summary contains it, or
+// the reports of a week given as 2026-W02. Unlike /search.json it matches
+// text, so it answers quickly and works without embeddings.
+func (s *Server) handlePaletteSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, maxSearchLimit)
+	}
+
+	repos, err := s.db.ListRepositories(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := PaletteResponse{Query: query, Results: []PaletteResult{}}
+	for _, repo := range matchRepos(repos, query, limit) {
+		resp.Results = append(resp.Results, PaletteResult{
+			Kind:   "repo",
+			Title:  repo.Name,
+			Detail: repo.Description.String,
+			URL:    s.appURL("/repos/" + repo.Name),
+		})
+	}
+
+	filter := db.ReportFilter{Text: query, Limit: limit}
+	if year, week, err := git.ParseISOWeek(query); err == nil {
+		filter = db.ReportFilter{FromWeek: year*100 + week, ToWeek: year*100 + week, Limit: limit}
+	}
+	reports, err := s.db.ListWeeklyReports(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	repoNames := make(map[int64]string, len(repos))
+	for _, repo := range repos {
+		repoNames[repo.ID] = repo.Name
+	}
+	for _, report := range reports {
+		detail := summarySnippet(report.Summary.String, filter.Text)
+		if detail == "" {
+			detail = toReportSummary(report, "").Preview
+		}
+		resp.Results = append(resp.Results, PaletteResult{
+			Kind:   "report",
+			Title:  repoNames[report.RepoID] + " " + git.FormatISOWeek(report.Year, report.Week),
+			Detail: detail,
+			URL:    s.appURL(fmt.Sprintf("/reports/%d", report.ID)),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// matchRepos returns at most limit repositories whose name or description
+// contains query, case-insensitively: names starting with it first, then
+// other name matches, then description matches
+func matchRepos(repos []*db.Repository, query string, limit int) []*db.Repository {
+	query = strings.ToLower(query)
+	rank := func(repo *db.Repository) int {
+		name := strings.ToLower(repo.Name)
+		switch {
+		case strings.HasPrefix(name, query):
+			return 0
+		case strings.Contains(name, query):
+			return 1
+		case strings.Contains(strings.ToLower(repo.Description.String), query):
+			return 2
+		}
+		return -1
+	}
+
+	var matched []*db.Repository
+	for _, repo := range repos {
+		if rank(repo) >= 0 {
+			matched = append(matched, repo)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b *db.Repository) int {
+		return rank(a) - rank(b)
+	})
+	return matched[:min(len(matched), limit)]
+}
+
+// summarySnippet returns the text around the first occurrence of query in a
+// summary, on one line, or "" if it does not occur
+func summarySnippet(summary, query string) string {
+	if query == "" {
+		return ""
+	}
+	text := strings.Join(strings.Fields(summary), " ")
+	i, j := indexFold(text, query)
+	if i < 0 {
+		return ""
+	}
+
+	start := max(0, i-(snippetLength-(j-i))/2)
+	end := min(len(text), start+snippetLength)
+	start = max(0, end-snippetLength)
+	// Don't cut multi-byte characters in half
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	snippet := text[start:end]
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(text) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// indexFold returns the byte offsets in s of the start and end of the first
+// case-insensitive match of substr, or -1, -1. Runes are compared in place,
+// since lowercasing can change the length of the text before a match.
+func indexFold(s, substr string) (int, int) {
+	for i := range s {
+		j, k := i, 0
+		for j < len(s) && k < len(substr) {
+			_, n := utf8.DecodeRuneInString(s[j:])
+			_, m := utf8.DecodeRuneInString(substr[k:])
+			if !strings.EqualFold(s[j:j+n], substr[k:k+m]) {
+				break
+			}
+			j, k = j+n, k+m
+		}
+		if k == len(substr) {
+			return i, j
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestIndexFold(t *testing.T) {
+	tests := []struct {
+		s, substr  string
+		start, end int
+	}{
+		{"Reworked the Cache layer", "cache", 13, 18},
+		{"ÆØÅ: Straße repaved", "STRASSE", -1, -1},
+		{"ÆØÅ: straße repaved", "STRAßE", 8, 15},
+		{"İİİ added Ünicode support", "ünicode", 13, 21},
+		{"Kelvin sign K", "k", 0, 1},
+		{"short", "shorter", -1, -1},
+	}
+	for _, tt := range tests {
+		start, end := indexFold(tt.s, tt.substr)
+		if start != tt.start || end != tt.end {
+			t.Errorf("indexFold(%q, %q) = %d, %d, want %d, %d", tt.s, tt.substr, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestSummarySnippet(t *testing.T) {
+	// Lowercasing İ takes three bytes instead of two, so offsets into the
+	// lowercased text point past the match in the original
+	summary := strings.Repeat("İ", 60) + " Migrated the Ünicode handling to goose. " + strings.Repeat("ø", 60)
+	snippet := summarySnippet(summary, "ünicode")
+	if !strings.Contains(snippet, "Ünicode") {
+		t.Errorf("summarySnippet() = %q, want it to contain the match", snippet)
+	}
+	if !utf8.ValidString(snippet) {
+		t.Errorf("summarySnippet() = %q, want valid UTF-8", snippet)
+	}
+	if !strings.HasPrefix(snippet, "...") || !strings.HasSuffix(snippet, "...") {
+		t.Errorf("summarySnippet() = %q, want it cut on both sides", snippet)
+	}
+
+	if got := summarySnippet("Added\n  retries   to the  fetcher", "RETRIES"); got != "Added retries to the fetcher" {
+		t.Errorf("summarySnippet() = %q, want the whole summary on one line", got)
+	}
+	if got := summarySnippet("Nothing to see", "missing"); got != "" {
+		t.Errorf("summarySnippet() without a match = %q, want empty", got)
+	}
+}
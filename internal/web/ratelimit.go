@@ -0,0 +1,149 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitIdle is how long a client's bucket is kept after its last request
+const rateLimitIdle = 10 * time.Minute
+
+// RateLimiter limits each client IP to a steady request rate with a token
+// bucket, allowing short bursts up to the bucket size
+type RateLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64 // Bucket size
+
+	mu        sync.Mutex
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is a client's token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perMinute requests a minute per
+// client, in bursts of up to burst requests. It returns nil, which allows
+// everything, if perMinute is 0 or less.
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(max(burst, 1)),
+		clients: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the client's bucket. If the bucket is empty it
+// returns false and how long until the next token.
+func (l *RateLimiter) Allow(client string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdle {
+		for c, b := range l.clients {
+			if now.Sub(b.last) > rateLimitIdle {
+				delete(l.clients, c)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.clients[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// rateLimits are the settings the server's rate limiters are built from
+type rateLimits struct {
+	perMinute, burst, webhookPerMinute int
+}
+
+// loadRateLimiters builds the server's rate limiters from the config. The
+// current limiters, and their clients' buckets, are kept if the limits did
+// not change; it returns whether they did.
+func (s *Server) loadRateLimiters() bool {
+	web := s.cfg.Current().Web
+	limits := rateLimits{web.RateLimitPerMinute, web.RateLimitBurst, web.WebhookRateLimitPerMinute}
+	if limits == s.limits {
+		return false
+	}
+	s.limits = limits
+	s.limiter.Store(NewRateLimiter(limits.perMinute, limits.burst))
+	s.webhookLimiter.Store(NewRateLimiter(limits.webhookPerMinute, limits.burst))
+	return true
+}
+
+// limitRequests rejects requests from clients over their rate limit and
+// bounds request bodies. The public webhook endpoints have a rate limit of
+// their own, lower than the rest of the site's by default, and bound their
+// bodies themselves.
+func (s *Server) limitRequests(next http.Handler) http.Handler {
+	maxBody := int64(s.cfg.Web.MaxBodyKB) << 10
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, bodyLimit := s.limiter.Load(), maxBody
+		if strings.HasPrefix(r.URL.Path, "/webhooks/") {
+			limiter, bodyLimit = s.webhookLimiter.Load(), 0
+		}
+		client := clientIP(r, s.cfg.Web.ClientIPHeader)
+		if ok, wait := limiter.Allow(client, time.Now()); !ok {
+			slog.Warn("Rate limited request", "client", client, "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		if bodyLimit > 0 && r.Body != nil {
+			if r.ContentLength > bodyLimit {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, bodyLimit)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the IP address of the client that sent a request. Behind
+// a reverse proxy header names the header the proxy puts the client address
+// in; for X-Forwarded-For the last address, which the proxy appended, is used.
+func clientIP(r *http.Request, header string) string {
+	if values := r.Header.Values(header); header != "" && len(values) > 0 {
+		addrs := strings.Split(values[len(values)-1], ",")
+		if addr := strings.TrimSpace(addrs[len(addrs)-1]); addr != "" {
+			return addr
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
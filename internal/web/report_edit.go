@@ -0,0 +1,108 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// maxSummaryLength bounds a hand-edited report summary
+const maxSummaryLength = 100000
+
+// adminReport loads the report named by the {id} path value, writing an
+// error response and returning nil if there is none
+func (s *Server) adminReport(w http.ResponseWriter, r *http.Request) (*db.WeeklyReport, *db.Repository) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return nil, nil
+	}
+	report, err := s.db.GetWeeklyReport(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return nil, nil
+	}
+	repo, err := s.db.GetRepository(r.Context(), report.RepoID)
+	if err != nil {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return nil, nil
+	}
+	return report, repo
+}
+
+// handleAdminReportEdit serves the form for hand-editing a report summary
+func (s *Server) handleAdminReportEdit(w http.ResponseWriter, r *http.Request) {
+	report, repo := s.adminReport(w, r)
+	if report == nil {
+		return
+	}
+
+	data := PageData{
+		Title:     "Edit " + repo.Name + " " + git.FormatISOWeek(report.Year, report.Week),
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content: AdminReportEditData{
+			Report:   toReportDetail(report, repo.Name, s.authorMap(r.Context()), s.summaryHTML),
+			Original: report.OriginalSummary.String,
+		},
+	}
+	s.render(w, r, s.templates.adminReportEdit, data)
+}
+
+// handleAdminReportSave saves a hand-edited report summary
+func (s *Server) handleAdminReportSave(w http.ResponseWriter, r *http.Request) {
+	report, repo := s.adminReport(w, r)
+	if report == nil {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	summary := strings.ReplaceAll(r.FormValue("summary"), "\r\n", "\n")
+	if strings.TrimSpace(summary) == "" {
+		http.Error(w, "Summary is required", http.StatusBadRequest)
+		return
+	}
+	if len(summary) > maxSummaryLength {
+		http.Error(w, fmt.Sprintf("Summary is longer than %d bytes", maxSummaryLength), http.StatusBadRequest)
+		return
+	}
+	if summary == report.Summary.String {
+		http.Redirect(w, r, fmt.Sprintf("/reports/%d", report.ID), http.StatusSeeOther)
+		return
+	}
+
+	user := GetUser(r)
+	if err := s.db.EditWeeklyReportSummary(r.Context(), report.ID, summary, user.Email); err != nil {
+		slog.Error("Failed to edit report", "report_id", report.ID, "error", err)
+		http.Error(w, "Failed to save report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditReportEdit, repo.Name+" "+git.FormatISOWeek(report.Year, report.Week), "")
+
+	http.Redirect(w, r, fmt.Sprintf("/reports/%d", report.ID), http.StatusSeeOther)
+}
+
+// handleAdminReportRestore restores the generated summary of an edited report
+func (s *Server) handleAdminReportRestore(w http.ResponseWriter, r *http.Request) {
+	report, repo := s.adminReport(w, r)
+	if report == nil {
+		return
+	}
+
+	if err := s.db.RestoreWeeklyReportSummary(r.Context(), report.ID); err != nil {
+		slog.Error("Failed to restore report", "report_id", report.ID, "error", err)
+		http.Error(w, "Failed to restore report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditReportEdit, repo.Name+" "+git.FormatISOWeek(report.Year, report.Week), "restored original")
+
+	http.Redirect(w, r, fmt.Sprintf("/reports/%d", report.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+)
+
+// handleAdminReviews serves the draft reports awaiting approval
+func (s *Server) handleAdminReviews(w http.ResponseWriter, r *http.Request) {
+	drafts, err := s.db.ListWeeklyReports(r.Context(), db.ReportFilter{ReviewState: db.ReviewDraft})
+	if err != nil {
+		s.renderError(w, r, "Failed to load drafts", err)
+		return
+	}
+	repoNames := make(map[int64]string)
+	repos, _ := s.db.ListRepositories(r.Context(), nil)
+	for _, repo := range repos {
+		repoNames[repo.ID] = repo.Name
+	}
+
+	summaries := make([]ReportSummary, 0, len(drafts))
+	for _, rpt := range drafts {
+		summaries = append(summaries, toReportSummary(rpt, repoNames[rpt.RepoID]))
+	}
+
+	data := PageData{
+		Title:     "Review Reports",
+		ActiveNav: "admin",
+		User:      GetUser(r),
+		Content: AdminReviewsData{
+			Drafts:      summaries,
+			AutoApprove: s.cfg.Current().Newsletter.AutoApprove,
+		},
+	}
+	s.render(w, r, s.templates.adminReviews, data)
+}
+
+// handleAdminReviewApprove approves the reports in the form's "id" fields
+func (s *Server) handleAdminReviewApprove(w http.ResponseWriter, r *http.Request) {
+	s.setReviewState(w, r, db.ReviewApproved, auditReportApprove)
+}
+
+// handleAdminReviewUnapprove returns the reports in the form's "id" fields
+// to draft
+func (s *Server) handleAdminReviewUnapprove(w http.ResponseWriter, r *http.Request) {
+	s.setReviewState(w, r, db.ReviewDraft, auditReportUnapprove)
+}
+
+// setReviewState sets the review state of the reports in the form's "id"
+// fields and returns to the page the form was on
+func (s *Server) setReviewState(w http.ResponseWriter, r *http.Request, state, action string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	if len(r.Form["id"]) == 0 {
+		http.Error(w, "No reports selected", http.StatusBadRequest)
+		return
+	}
+
+	user := GetUser(r)
+	for _, value := range r.Form["id"] {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid report ID", http.StatusBadRequest)
+			return
+		}
+		report, err := s.db.GetWeeklyReport(r.Context(), id)
+		if err != nil {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		repo, err := s.db.GetRepository(r.Context(), report.RepoID)
+		if err != nil {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		if report.ReviewState == state {
+			continue
+		}
+		if err := s.db.SetReportReviewState(r.Context(), id, state, user.Email); err != nil {
+			slog.Error("Failed to set review state", "report_id", id, "state", state, "error", err)
+			http.Error(w, "Failed to update report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, action, repo.Name+" "+git.FormatISOWeek(report.Year, report.Week), "")
+
+		// A regenerated report that was already sent is corrected once approved
+		if state == db.ReviewApproved && report.SentSummary.Valid {
+			report.ReviewState = state
+			s.sendCorrection(r, report)
+		}
+	}
+
+	http.Redirect(w, r, returnPath(r, "/admin/reviews"), http.StatusSeeOther)
+}
+
+// sendCorrection emails the recipients of an approved regenerated report
+// what changed. Failures are only logged.
+func (s *Server) sendCorrection(r *http.Request, report *db.WeeklyReport) {
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	if _, err := s.services.Newsletter.SendCorrection(ctx, report); err != nil {
+		slog.Warn("Failed to send correction of regenerated report", "report_id", report.ID, "error", err)
+	}
+}
@@ -0,0 +1,197 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perbu/activity/internal/git"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	relatedWeeksLimit  = 3
+)
+
+// SearchResultJSON is a single hit in the /search.json response
+type SearchResultJSON struct {
+	ReportID int64   `json:"report_id"`
+	Repo     string  `json:"repo"`
+	Week     string  `json:"week"`
+	Score    float64 `json:"score"`
+	Preview  string  `json:"preview"`
+	URL      string  `json:"url"`
+}
+
+// CommitResultJSON is a commit hit in the /search.json response
+type CommitResultJSON struct {
+	SHA     string    `json:"sha"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Repo    string    `json:"repo"`
+	Week    string    `json:"week"`
+	Score   float64   `json:"score"`
+	URL     string    `json:"url"` // report of the commit's week
+}
+
+// SearchResponse is the JSON payload served at /search.json
+type SearchResponse struct {
+	Query   string             `json:"query"`
+	Results []SearchResultJSON `json:"results"`
+	Commits []CommitResultJSON `json:"commits"`
+}
+
+// handleSearch serves the semantic search page
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	content := SearchData{
+		Query:    query,
+		Enabled:  s.services.Search.Enabled(),
+		SignedIn: GetUser(r) != nil,
+	}
+
+	var errMsg string
+	if query != "" && content.Enabled && content.SignedIn {
+		hits, commits, err := s.services.Search.SearchWithCommits(r.Context(), query, 0, defaultSearchLimit)
+		if err != nil {
+			errMsg = "Search failed: " + err.Error()
+		}
+		for _, hit := range hits {
+			content.Results = append(content.Results, SearchResult{
+				Report: toReportSummary(hit.Report, hit.RepoName),
+				Score:  formatScore(hit.Score),
+			})
+		}
+		for _, hit := range commits {
+			content.Commits = append(content.Commits, CommitResult{
+				SHA:      hit.Commit.SHA,
+				ShortSHA: hit.Commit.SHA[:min(8, len(hit.Commit.SHA))],
+				Message:  hit.Commit.Message,
+				Author:   hit.Commit.Author,
+				Date:     hit.Commit.CommittedAt.Format("2006-01-02"),
+				Report:   toReportSummary(hit.Report, hit.RepoName),
+				Score:    formatScore(hit.Score),
+			})
+		}
+	}
+
+	data := PageData{
+		Title:     "Search",
+		ActiveNav: "search",
+		User:      GetUser(r),
+		Error:     errMsg,
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.search, data)
+}
+
+// handleSearchJSON serves semantic search results as JSON.
+// Query parameters: q (required), repo (optional name filter), limit.
+func (s *Server) handleSearchJSON(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+	if !s.services.Search.Enabled() {
+		http.Error(w, "Semantic search is disabled", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, maxSearchLimit)
+	}
+
+	var repoID int64
+	if repoName := r.URL.Query().Get("repo"); repoName != "" {
+		repo, err := s.db.GetRepositoryByName(r.Context(), repoName)
+		if err != nil {
+			http.Error(w, "Repository not found: "+repoName, http.StatusNotFound)
+			return
+		}
+		repoID = repo.ID
+	}
+
+	hits, commits, err := s.services.Search.SearchWithCommits(r.Context(), query, repoID, limit)
+	if err != nil {
+		http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := SearchResponse{
+		Query:   query,
+		Results: make([]SearchResultJSON, 0, len(hits)),
+		Commits: make([]CommitResultJSON, 0, len(commits)),
+	}
+	for _, hit := range commits {
+		resp.Commits = append(resp.Commits, CommitResultJSON{
+			SHA:     hit.Commit.SHA,
+			Message: hit.Commit.Message,
+			Author:  hit.Commit.Author,
+			Date:    hit.Commit.CommittedAt,
+			Repo:    hit.RepoName,
+			Week:    git.FormatISOWeek(hit.Report.Year, hit.Report.Week),
+			Score:   hit.Score,
+			URL:     s.appURL(fmt.Sprintf("/reports/%d", hit.Report.ID)),
+		})
+	}
+	for _, hit := range hits {
+		resp.Results = append(resp.Results, SearchResultJSON{
+			ReportID: hit.Report.ID,
+			Repo:     hit.RepoName,
+			Week:     git.FormatISOWeek(hit.Report.Year, hit.Report.Week),
+			Score:    hit.Score,
+			Preview:  toReportSummary(hit.Report, hit.RepoName).Preview,
+			URL:      s.appURL(fmt.Sprintf("/reports/%d", hit.Report.ID)),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleAdminIndexEmbeddings computes missing or stale report embeddings
+func (s *Server) handleAdminIndexEmbeddings(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	indexed, err := s.services.Search.IndexAll(ctx)
+	if err != nil {
+		http.Error(w, "Failed to index reports: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	msg := fmt.Sprintf("Indexed %d reports and %d commits for search", indexed.Reports, indexed.Commits)
+	s.audit(r, auditReportIndex, "", msg)
+	http.Redirect(w, r, "/admin/actions?success="+msg, http.StatusSeeOther)
+}
+
+// relatedWeeks returns the reports most similar to the given one for the
+// report page. Errors are ignored since the section is optional.
+func (s *Server) relatedWeeks(ctx context.Context, reportID int64) []SearchResult {
+	hits, err := s.services.Search.Related(ctx, reportID, relatedWeeksLimit)
+	if err != nil {
+		return nil
+	}
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, SearchResult{
+			Report: toReportSummary(hit.Report, hit.RepoName),
+			Score:  formatScore(hit.Score),
+		})
+	}
+	return results
+}
+
+// formatScore formats a cosine similarity as a percentage
+func formatScore(score float64) string {
+	return fmt.Sprintf("%.0f%%", score*100)
+}
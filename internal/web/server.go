@@ -1,13 +1,21 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/email"
+	"github.com/perbu/activity/internal/graphql"
 	"github.com/perbu/activity/internal/service"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Server is the HTTP server for the web UI
@@ -16,20 +24,52 @@ type Server struct {
 	services  *service.Services
 	cfg       *config.Config
 	templates *Templates
+	schema    *graphql.Schema // served at /graphql
 	mux       *http.ServeMux
 	auth      *AuthMiddleware
+	csrf      *CSRF
 	host      string
 	port      int
+	basePath  string // web.base_path, "" at the root
+
+	// Rendered report summaries, and a value that changes on each start and
+	// config reload to invalidate the ETags of pages (see notModified)
+	summaries  *summaryCache
+	cacheEpoch atomic.Int64
+
+	// Per-client rate limits for the site and for the webhooks; nil if
+	// disabled. Replaced when the config is reloaded with other limits.
+	limiter        atomic.Pointer[RateLimiter]
+	webhookLimiter atomic.Pointer[RateLimiter]
+	limits         rateLimits
+
+	// nil if the SendGrid webhook is disabled; replaced when the config is reloaded
+	webhookVerifier atomic.Pointer[email.WebhookVerifier]
+	webhookKey      string
+
+	httpServer *http.Server
+	// Cancelled when Shutdown gives up waiting, to stop the requests and the
+	// jobs they started that are still running
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
 }
 
+// forcedShutdownGrace is how long Shutdown waits for requests to return after
+// cancelling them
+const forcedShutdownGrace = 5 * time.Second
+
 // NewServer creates a new web server
 func NewServer(database *db.DB, services *service.Services, cfg *config.Config, host string, port int) (*Server, error) {
-	templates, err := ParseTemplates()
+	templates, err := ParseTemplates(cfg.GetBasePath(), cfg.GetTemplatesDir())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
-	auth := NewAuthMiddleware(cfg, services.Admin)
+	auth := NewAuthMiddleware(cfg, services.Admin, services.Workspace)
+	csrf, err := NewCSRF(cfg.Web.SessionKey)
+	if err != nil {
+		return nil, err
+	}
 
 	s := &Server{
 		db:        database,
@@ -38,10 +78,47 @@ func NewServer(database *db.DB, services *service.Services, cfg *config.Config,
 		templates: templates,
 		mux:       http.NewServeMux(),
 		auth:      auth,
+		csrf:      csrf,
 		host:      host,
 		port:      port,
+		basePath:  cfg.GetBasePath(),
+		summaries: newSummaryCache(),
 	}
+	s.loadRateLimiters()
+	s.baseCtx, s.cancelBase = context.WithCancel(context.Background())
+	s.cacheEpoch.Store(time.Now().UnixNano())
+	s.schema = s.graphqlSchema()
 
+	// A read-only server never authenticates anyone, so it has no admins
+	// to set up
+	if cfg.Web.ReadOnly {
+		slog.Info("Running in read-only mode: serving public pages and feeds only")
+	} else {
+		setupAdmins(s.baseCtx, cfg, services)
+	}
+
+	if err := s.loadWebhookVerifier(); err != nil {
+		return nil, err
+	}
+
+	s.registerRoutes()
+	s.httpServer = &http.Server{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+		// Wrap the mux with auth middleware to populate user context on all
+		// requests, and check CSRF tokens once the user is known. Rate and
+		// body limits apply first, before any work is done, inside the
+		// request's trace span, once web.base_path is stripped. Responses
+		// are compressed on the way out.
+		Handler:     otelhttp.NewHandler(s.secureHeaders(compress(s.mountBasePath(s.limitRequests(s.auth.Middleware(s.csrf.Middleware(s.mux)))))), "activity", otelhttp.WithSpanNameFormatter(spanName)),
+		BaseContext: func(net.Listener) context.Context { return s.baseCtx },
+	}
+
+	return s, nil
+}
+
+// setupAdmins seeds the first admin and the dev mode admin if needed, and
+// logs how users are authenticated and who the admins are
+func setupAdmins(ctx context.Context, cfg *config.Config, services *service.Services) {
 	// Log configured seed admin
 	if seedAdmin := cfg.GetSeedAdmin(); seedAdmin != "" {
 		slog.Info("Seed admin configured", "email", seedAdmin)
@@ -50,12 +127,12 @@ func NewServer(database *db.DB, services *service.Services, cfg *config.Config,
 	}
 
 	// Seed admin if needed
-	if err := services.Admin.SeedIfNeeded(); err != nil {
+	if err := services.Admin.SeedIfNeeded(ctx); err != nil {
 		slog.Error("Failed to seed admin", "error", err)
 	}
 
 	// Ensure dev admin if in dev mode
-	if err := services.Admin.EnsureDevAdmin(); err != nil {
+	if err := services.Admin.EnsureDevAdmin(ctx); err != nil {
 		slog.Error("Failed to ensure dev admin", "error", err)
 	}
 
@@ -65,7 +142,7 @@ func NewServer(database *db.DB, services *service.Services, cfg *config.Config,
 
 	// Log current admins and auth header
 	slog.Info("Auth header configured", "header", cfg.GetAuthHeader())
-	admins, err := services.Admin.List()
+	admins, err := services.Admin.List(ctx)
 	if err != nil {
 		slog.Error("Failed to list admins", "error", err)
 	} else if len(admins) == 0 {
@@ -75,10 +152,52 @@ func NewServer(database *db.DB, services *service.Services, cfg *config.Config,
 			slog.Info("Admin user", "email", admin.Email, "created_by", admin.CreatedBy)
 		}
 	}
+}
 
-	s.registerRoutes()
+// spanName names a request's trace span after its method and path
+func spanName(_ string, r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
 
-	return s, nil
+// Reload applies a reloaded config to the state the server derives from it
+// on startup. The reloaded settings themselves are read through
+// config.Current as they are used.
+func (s *Server) Reload() error {
+	// Branding and the markdown extensions may have changed
+	s.summaries.clear()
+	s.cacheEpoch.Store(time.Now().UnixNano())
+	if s.loadRateLimiters() {
+		slog.Info("Rate limits changed", "per_minute", s.limits.perMinute, "burst", s.limits.burst,
+			"webhook_per_minute", s.limits.webhookPerMinute)
+	}
+	return s.loadWebhookVerifier()
+}
+
+// loadWebhookVerifier enables the SendGrid event webhook if a verification
+// key is configured, or disables it. An invalid key leaves the webhook as it was.
+func (s *Server) loadWebhookVerifier() error {
+	if s.cfg.Web.ReadOnly {
+		return nil // The webhook is not served
+	}
+	key := s.cfg.GetSendGridWebhookKey()
+	if key == s.webhookKey {
+		return nil
+	}
+	if key == "" {
+		s.webhookVerifier.Store(nil)
+		s.webhookKey = ""
+		slog.Info("SendGrid event webhook disabled")
+		return nil
+	}
+
+	verifier, err := email.NewWebhookVerifier(key)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid webhook key: %w", err)
+	}
+	s.webhookVerifier.Store(verifier)
+	s.webhookKey = key
+	slog.Info("SendGrid event webhook enabled", "path", "/webhooks/sendgrid")
+	return nil
 }
 
 // registerRoutes registers all HTTP routes
@@ -86,11 +205,56 @@ func (s *Server) registerRoutes() {
 	// Serve static files from embedded filesystem
 	s.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(StaticFS()))))
 
-	// Public routes (wrapped with auth middleware to populate user context)
-	s.mux.HandleFunc("GET /", s.handleIndex)
-	s.mux.HandleFunc("GET /repos", s.handleRepoList)
-	s.mux.HandleFunc("GET /repos/{name}", s.handleRepoReports)
-	s.mux.HandleFunc("GET /reports/{id}", s.handleReportView)
+	// Public routes (wrapped with auth middleware to populate user context).
+	// Each repository's visibility limits whose reports they show, and
+	// web.require_login closes them to anonymous readers.
+	public := func(h http.HandlerFunc) http.HandlerFunc {
+		if s.cfg.Web.RequireLogin {
+			return RequireAuth(h)
+		}
+		return h
+	}
+	s.mux.HandleFunc("GET /", public(s.handleIndex))
+	s.mux.HandleFunc("GET /repos", public(s.handleRepoList))
+	s.mux.HandleFunc("GET /repos/{name}", public(s.handleRepoReports))
+	s.mux.HandleFunc("GET /reports/{id}", public(s.handleReportView))
+	s.mux.HandleFunc("GET /reports/{id}/compare", public(s.handleReportCompare))
+	s.mux.HandleFunc("GET /leaderboard", public(s.handleLeaderboard))
+
+	// JSON API, described by the OpenAPI document
+	for _, route := range s.apiRoutes() {
+		handler := public(route.Handler)
+		if route.Auth {
+			handler = RequireAuth(route.Handler)
+		}
+		s.mux.HandleFunc(route.Method+" "+route.Path, handler)
+	}
+	s.mux.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /api/docs", s.handleAPIDocs)
+	s.mux.HandleFunc("POST /theme", s.handleTheme)
+	s.mux.HandleFunc("POST /lang", s.handleLanguage)
+	s.mux.HandleFunc("POST /plain", s.handlePlain)
+
+	// A read-only server serves only the public pages and feeds above
+	if s.cfg.Web.ReadOnly {
+		return
+	}
+
+	s.mux.HandleFunc("POST /workspace", s.handleWorkspaceSwitch)
+	s.mux.HandleFunc("POST /webhooks/sendgrid", s.handleSendGridWebhook)
+
+	// Semantic search asks anonymous users to sign in, since each query is
+	// an embedding call
+	s.mux.HandleFunc("GET /search", public(s.handleSearch))
+
+	// Signed-in user routes
+	s.mux.HandleFunc("GET /repos/{name}/chat", RequireAuth(s.handleRepoChat))
+	s.mux.HandleFunc("POST /repos/{name}/chat", RequireAuth(s.handleRepoChat))
+	s.mux.HandleFunc("POST /repos/{name}/favorite", RequireAuth(s.handleRepoFavorite))
+	s.mux.HandleFunc("POST /preferences", RequireAuth(s.handlePreferences))
+	s.mux.HandleFunc("GET /notifications", RequireAuth(s.handleNotifications))
+	s.mux.HandleFunc("GET /notifications/{id}", RequireAuth(s.handleNotificationOpen))
+	s.mux.HandleFunc("POST /notifications/read", RequireAuth(s.handleNotificationsRead))
 
 	// Admin routes (require admin privileges)
 	s.mux.HandleFunc("GET /admin", RequireAdmin(s.handleAdmin))
@@ -99,24 +263,87 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("POST /admin/repos/remove", RequireAdmin(s.handleAdminRepoRemove))
 	s.mux.HandleFunc("POST /admin/repos/toggle", RequireAdmin(s.handleAdminRepoToggle))
 	s.mux.HandleFunc("POST /admin/repos/set-url", RequireAdmin(s.handleAdminRepoSetURL))
+	s.mux.HandleFunc("POST /admin/repos/set-branch", RequireAdmin(s.handleAdminRepoSetBranch))
+	s.mux.HandleFunc("POST /admin/repos/set-notes", RequireAdmin(s.handleAdminRepoSetNotes))
+	s.mux.HandleFunc("POST /admin/repos/set-visibility", RequireAdmin(s.handleAdminRepoSetVisibility))
 	s.mux.HandleFunc("GET /admin/subscribers", RequireAdmin(s.handleAdminSubscribers))
 	s.mux.HandleFunc("POST /admin/subscribers/add", RequireAdmin(s.handleAdminSubscriberAdd))
+	s.mux.HandleFunc("POST /admin/subscribers/schedule", RequireAdmin(s.handleAdminSubscriberSchedule))
 	s.mux.HandleFunc("POST /admin/subscribers/remove", RequireAdmin(s.handleAdminSubscriberRemove))
+	s.mux.HandleFunc("POST /admin/subscribers/unsuppress", RequireAdmin(s.handleAdminSubscriberUnsuppress))
+	s.mux.HandleFunc("POST /admin/subscribers/import", RequireAdmin(s.handleAdminSubscribersImport))
+	s.mux.HandleFunc("GET /admin/subscribers.csv", RequireAdmin(s.handleAdminSubscribersExport))
 	s.mux.HandleFunc("GET /admin/actions", RequireAdmin(s.handleAdminActions))
 	s.mux.HandleFunc("POST /admin/update", RequireAdmin(s.handleAdminUpdateRepos))
 	s.mux.HandleFunc("POST /admin/generate", RequireAdmin(s.handleAdminGenerateReport))
+	s.mux.HandleFunc("GET /admin/generate/stream", RequireAdmin(RequireCSRF(s.handleAdminGenerateStream)))
+	s.mux.HandleFunc("POST /admin/analyze", RequireAdmin(s.handleAdminAnalyzeNew))
+	s.mux.HandleFunc("POST /admin/index-embeddings", RequireAdmin(s.handleAdminIndexEmbeddings))
 	s.mux.HandleFunc("POST /admin/send", RequireAdmin(s.handleAdminSendNewsletter))
+	s.mux.HandleFunc("GET /admin/newsletter/preview", RequireAdmin(s.handleAdminNewsletterPreview))
+	s.mux.HandleFunc("GET /admin/newsletter/sends", RequireAdmin(s.handleAdminNewsletterSends))
+	s.mux.HandleFunc("GET /admin/newsletter/sends/{id}", RequireAdmin(s.handleAdminNewsletterSend))
+	s.mux.HandleFunc("POST /admin/newsletter/sends/{id}/resend", RequireAdmin(s.handleAdminNewsletterResend))
 	s.mux.HandleFunc("GET /admin/admins", RequireAdmin(s.handleAdminAdmins))
 	s.mux.HandleFunc("POST /admin/admins/add", RequireAdmin(s.handleAdminAdminAdd))
 	s.mux.HandleFunc("POST /admin/admins/remove", RequireAdmin(s.handleAdminAdminRemove))
+	s.mux.HandleFunc("GET /admin/authors", RequireAdmin(s.handleAdminAuthors))
+	s.mux.HandleFunc("POST /admin/authors/add", RequireAdmin(s.handleAdminAuthorAdd))
+	s.mux.HandleFunc("POST /admin/authors/remove", RequireAdmin(s.handleAdminAuthorRemove))
+	s.mux.HandleFunc("GET /admin/workspaces", RequireAdmin(s.handleAdminWorkspaces))
+	s.mux.HandleFunc("POST /admin/workspaces/add", RequireAdmin(s.handleAdminWorkspaceAdd))
+	s.mux.HandleFunc("POST /admin/workspaces/remove", RequireAdmin(s.handleAdminWorkspaceRemove))
+	s.mux.HandleFunc("POST /admin/tokens/add", RequireAdmin(s.handleAdminTokenAdd))
+	s.mux.HandleFunc("POST /admin/tokens/revoke", RequireAdmin(s.handleAdminTokenRevoke))
+	s.mux.HandleFunc("GET /admin/audit", RequireAdmin(s.handleAdminAudit))
+	s.mux.HandleFunc("GET /admin/audit.csv", RequireAdmin(s.handleAdminAuditCSV))
+	s.mux.HandleFunc("GET /admin/reports/{id}/edit", RequireAdmin(s.handleAdminReportEdit))
+	s.mux.HandleFunc("POST /admin/reports/{id}/edit", RequireAdmin(s.handleAdminReportSave))
+	s.mux.HandleFunc("POST /admin/reports/{id}/restore", RequireAdmin(s.handleAdminReportRestore))
+	s.mux.HandleFunc("GET /admin/reviews", RequireAdmin(s.handleAdminReviews))
+	s.mux.HandleFunc("POST /admin/reviews/approve", RequireAdmin(s.handleAdminReviewApprove))
+	s.mux.HandleFunc("POST /admin/reviews/unapprove", RequireAdmin(s.handleAdminReviewUnapprove))
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server. It blocks until the server fails or Shutdown
+// is called, in which case it returns nil.
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.host, s.port)
-	// Wrap the mux with auth middleware to populate user context on all requests
-	handler := s.auth.Middleware(s.mux)
-	return http.ListenAndServe(addr, handler)
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests,
+// including admin actions such as report generation, to finish. When ctx
+// expires first, the remaining requests and their jobs are cancelled so they
+// stop before writing partial results, and the context's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("Cancelling requests still running at shutdown", "error", err)
+	s.cancelBase()
+	graceCtx, cancel := context.WithTimeout(context.Background(), forcedShutdownGrace)
+	defer cancel()
+	if err := s.httpServer.Shutdown(graceCtx); err != nil {
+		s.httpServer.Close()
+	}
+	return err
+}
+
+// detach returns a context for work that should outlive the request, such as
+// an admin action that keeps running if the browser disconnects. It is only
+// cancelled by a forced shutdown; call the cancel function when done.
+func (s *Server) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(s.baseCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
 
 // Address returns the server address
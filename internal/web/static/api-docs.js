@@ -0,0 +1,16 @@
+// /api/docs: shows the OpenAPI document in the embedded Swagger UI in place of
+// the server-rendered route reference, which stays for browsers without
+// JavaScript and the plain layout
+(function() {
+    var root = document.getElementById('swagger-ui');
+    if (!root || typeof SwaggerUIBundle === 'undefined') {
+        return;
+    }
+    document.getElementById('api-routes').hidden = true;
+    root.hidden = false;
+    SwaggerUIBundle({
+        url: root.dataset.specUrl,
+        domNode: root,
+        deepLinking: true
+    });
+})();
@@ -0,0 +1,169 @@
+// Behavior shared by all pages. Inline event handlers are blocked by the
+// Content-Security-Policy, so pages mark elements with data attributes.
+
+// Forms with data-confirm ask before submitting
+document.addEventListener('submit', function(e) {
+    var message = e.target.dataset.confirm;
+    if (message && !window.confirm(message)) {
+        e.preventDefault();
+    }
+});
+
+// Search palette: Ctrl+K (Cmd+K on macOS) or "/" opens a dialog that finds
+// repositories and reports through /api/search as you type
+(function() {
+    var dialog = document.querySelector('dialog.palette');
+    if (!dialog) {
+        return;
+    }
+    var input = dialog.querySelector('.palette-input');
+    var list = dialog.querySelector('.palette-results');
+    var searchURL = dialog.dataset.searchUrl;
+    var isMac = /Mac|iPhone|iPad/.test(navigator.platform);
+    var timer = null;
+    var pending = null;
+    var selected = -1;
+
+    document.querySelectorAll('[data-palette-open]').forEach(function(button) {
+        if (isMac) {
+            button.querySelector('kbd').textContent = '⌘K';
+        }
+        button.addEventListener('click', open);
+    });
+
+    function open() {
+        if (dialog.open) {
+            return;
+        }
+        dialog.showModal();
+        input.select();
+    }
+
+    function typing(target) {
+        return target.isContentEditable || /^(INPUT|TEXTAREA|SELECT)$/.test(target.tagName);
+    }
+
+    document.addEventListener('keydown', function(e) {
+        if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'k') {
+            e.preventDefault();
+            open();
+        } else if (e.key === '/' && !typing(e.target) && !dialog.open) {
+            e.preventDefault();
+            open();
+        }
+    });
+
+    // Clicking the backdrop closes the dialog
+    dialog.addEventListener('click', function(e) {
+        if (e.target === dialog) {
+            dialog.close();
+        }
+    });
+
+    input.addEventListener('input', function() {
+        clearTimeout(timer);
+        timer = setTimeout(search, 150);
+    });
+
+    input.addEventListener('keydown', function(e) {
+        var items = list.querySelectorAll('[role=option]');
+        if (e.key === 'ArrowDown' || e.key === 'ArrowUp') {
+            e.preventDefault();
+            if (items.length) {
+                var step = e.key === 'ArrowDown' ? 1 : -1;
+                select((selected + step + items.length) % items.length);
+            }
+        } else if (e.key === 'Enter' && selected >= 0 && items[selected]) {
+            e.preventDefault();
+            window.location.href = items[selected].dataset.url;
+        }
+    });
+
+    function select(index) {
+        var items = list.querySelectorAll('[role=option]');
+        items.forEach(function(item, i) {
+            item.setAttribute('aria-selected', i === index ? 'true' : 'false');
+        });
+        selected = index;
+        if (items[index]) {
+            input.setAttribute('aria-activedescendant', items[index].id);
+            items[index].scrollIntoView({block: 'nearest'});
+        } else {
+            input.removeAttribute('aria-activedescendant');
+        }
+    }
+
+    function search() {
+        var query = input.value.trim();
+        if (pending) {
+            pending.abort();
+        }
+        if (!query) {
+            show([], '');
+            return;
+        }
+        pending = new AbortController();
+        fetch(searchURL + '?q=' + encodeURIComponent(query), {
+            credentials: 'same-origin',
+            headers: {'Accept': 'application/json'},
+            signal: pending.signal
+        }).then(function(resp) {
+            if (!resp.ok) {
+                throw new Error(resp.statusText);
+            }
+            return resp.json();
+        }).then(function(data) {
+            show(data.results, dialog.dataset.noMatches || 'No matches');
+        }).catch(function(err) {
+            if (err.name !== 'AbortError') {
+                show([], dialog.dataset.searchFailed || 'Search failed');
+            }
+        });
+    }
+
+    function show(results, emptyText) {
+        list.replaceChildren();
+        results.forEach(function(result, i) {
+            var item = document.createElement('li');
+            item.id = 'palette-result-' + i;
+            item.setAttribute('role', 'option');
+            item.className = 'palette-item';
+            item.dataset.url = result.url;
+
+            var kind = document.createElement('span');
+            kind.className = 'palette-kind';
+            kind.textContent = result.kind;
+            var title = document.createElement('a');
+            title.className = 'palette-title';
+            title.href = result.url;
+            title.tabIndex = -1;
+            title.textContent = result.title;
+            item.append(kind, title);
+            if (result.detail) {
+                var detail = document.createElement('span');
+                detail.className = 'palette-detail';
+                detail.textContent = result.detail;
+                item.append(detail);
+            }
+            item.addEventListener('click', function(e) {
+                if (e.target !== title) {
+                    window.location.href = result.url;
+                }
+            });
+            item.addEventListener('mousemove', function() {
+                if (selected !== i) {
+                    select(i);
+                }
+            });
+            list.append(item);
+        });
+        if (!results.length && emptyText) {
+            var empty = document.createElement('li');
+            empty.className = 'palette-empty';
+            empty.textContent = emptyText;
+            list.append(empty);
+        }
+        input.setAttribute('aria-expanded', results.length ? 'true' : 'false');
+        select(results.length ? 0 : -1);
+    }
+})();
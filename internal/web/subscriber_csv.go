@@ -0,0 +1,43 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// handleAdminSubscribersImport adds the subscribers of an uploaded CSV file
+// and shows the subscriber page with a summary of the import
+func (s *Server) handleAdminSubscribersImport(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "A CSV file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	dryRun := r.FormValue("dry_run") == "on"
+
+	result, err := s.services.Newsletter.ImportSubscribers(r.Context(), file, dryRun)
+	if err != nil {
+		slog.Error("Failed to import subscribers", "error", err)
+		s.renderError(w, r, "Failed to import subscribers", err)
+		return
+	}
+	if !dryRun {
+		s.audit(r, auditSubscriberImport, "", fmt.Sprintf("%d added, %d duplicates skipped, %d errors",
+			result.Added, len(result.Duplicates), len(result.Errors)))
+	}
+
+	s.renderAdminSubscribers(w, r, result)
+}
+
+// handleAdminSubscribersExport serves the subscribers as a CSV file that
+// the import reads
+func (s *Server) handleAdminSubscribersExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="subscribers-%s.csv"`, time.Now().Format("2006-01-02")))
+	if err := s.services.Newsletter.ExportSubscribers(r.Context(), w); err != nil {
+		slog.Error("Failed to export subscribers", "error", err)
+	}
+}
@@ -2,8 +2,14 @@ package web
 
 import (
 	"embed"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
 )
 
 //go:embed templates/*.html
@@ -14,15 +20,29 @@ var staticFS embed.FS
 
 // Templates holds all parsed templates
 type Templates struct {
-	index            *template.Template
-	repos            *template.Template
-	repoDetail       *template.Template
-	report           *template.Template
-	admin            *template.Template
-	adminRepos       *template.Template
-	adminSubscribers *template.Template
-	adminActions     *template.Template
-	adminAdmins      *template.Template
+	index                  *template.Template
+	repos                  *template.Template
+	repoDetail             *template.Template
+	report                 *template.Template
+	compare                *template.Template
+	search                 *template.Template
+	chat                   *template.Template
+	notifications          *template.Template
+	leaderboard            *template.Template
+	admin                  *template.Template
+	adminRepos             *template.Template
+	adminSubscribers       *template.Template
+	adminActions           *template.Template
+	adminAdmins            *template.Template
+	adminAuthors           *template.Template
+	adminWorkspaces        *template.Template
+	adminAudit             *template.Template
+	adminReportEdit        *template.Template
+	adminReviews           *template.Template
+	adminNewsletterPreview *template.Template
+	adminNewsletterSends   *template.Template
+	adminNewsletterSend    *template.Template
+	apiDocs                *template.Template
 }
 
 // StaticFS returns the embedded static files filesystem
@@ -31,76 +51,137 @@ func StaticFS() fs.FS {
 	return sub
 }
 
-// ParseTemplates parses all templates and returns a Templates struct
-func ParseTemplates() (*Templates, error) {
-	funcs := template.FuncMap{
-		"safe": func(s string) template.HTML {
-			return template.HTML(s)
-		},
-	}
+// overlayFS serves files from override, falling back to base for files it
+// doesn't have
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
 
-	// Parse base template
-	base, err := template.New("base.html").Funcs(funcs).ParseFS(templateFS, "templates/base.html")
-	if err != nil {
-		return nil, err
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.override.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.base.Open(name)
 	}
+	return f, err
+}
 
-	// Parse each page template by cloning base and adding the page
-	index, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/index.html")
-	if err != nil {
-		return nil, err
+// templateFiles returns the templates to parse: the embedded ones, with the
+// files in dir (web.templates_dir) replacing those of the same name
+func templateFiles(dir string) (fs.FS, error) {
+	embedded, _ := fs.Sub(templateFS, "templates")
+	if dir == "" {
+		return embedded, nil
 	}
 
-	repos, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/repos.html")
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".html" {
+			continue
+		}
+		if _, err := fs.Stat(embedded, name); err != nil {
+			slog.Warn("Ignoring unknown template in templates directory", "dir", dir, "file", name)
+			continue
+		}
+		slog.Info("Using template from templates directory", "dir", dir, "file", name)
 	}
+	return overlayFS{override: os.DirFS(dir), base: embedded}, nil
+}
 
-	repoDetail, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/repo_detail.html")
+// ParseTemplates parses all templates and returns a Templates struct. The
+// templates are embedded in the binary; files in dir, if set, replace those
+// of the same name. Each template is checked on startup: base.html must
+// define the csrf and pager templates pages use, each page must define
+// content, and all must escape cleanly, so a broken override fails here
+// rather than on the first request. Links in the templates start with
+// {{base}}, the path prefix the UI is served under (see config.GetBasePath),
+// and UI text is translated with {{t $.Lang "text"}} (see i18n.go).
+func ParseTemplates(basePath, dir string) (*Templates, error) {
+	files, err := templateFiles(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	report, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/report.html")
-	if err != nil {
-		return nil, err
+	funcs := template.FuncMap{
+		"base": func() string {
+			return basePath
+		},
+		"t": translate,
 	}
 
-	// Admin templates
-	admin, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/admin.html")
+	// Parse base template
+	base, err := template.New("base.html").Funcs(funcs).ParseFS(files, "base.html")
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("base.html: %w", err)
+	}
+	for _, name := range []string{"csrf", "pager"} {
+		if base.Lookup(name) == nil {
+			return nil, fmt.Errorf("base.html: does not define the %q template", name)
+		}
 	}
 
-	adminRepos, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/admin_repos.html")
-	if err != nil {
-		return nil, err
+	// Parse each page template by cloning base and adding the page,
+	// collecting the errors of all pages
+	var errs []error
+	page := func(name string) *template.Template {
+		t, err := parsePage(base, files, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		return t
 	}
 
-	adminSubscribers, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/admin_subscribers.html")
-	if err != nil {
+	templates := &Templates{
+		index:                  page("index.html"),
+		repos:                  page("repos.html"),
+		repoDetail:             page("repo_detail.html"),
+		report:                 page("report.html"),
+		compare:                page("compare.html"),
+		search:                 page("search.html"),
+		chat:                   page("chat.html"),
+		notifications:          page("notifications.html"),
+		leaderboard:            page("leaderboard.html"),
+		admin:                  page("admin.html"),
+		adminRepos:             page("admin_repos.html"),
+		adminSubscribers:       page("admin_subscribers.html"),
+		adminActions:           page("admin_actions.html"),
+		adminAdmins:            page("admin_admins.html"),
+		adminAuthors:           page("admin_authors.html"),
+		adminWorkspaces:        page("admin_workspaces.html"),
+		adminAudit:             page("admin_audit.html"),
+		adminReportEdit:        page("admin_report_edit.html"),
+		adminReviews:           page("admin_reviews.html"),
+		adminNewsletterPreview: page("admin_newsletter_preview.html"),
+		adminNewsletterSends:   page("admin_newsletter_sends.html"),
+		adminNewsletterSend:    page("admin_newsletter_send.html"),
+		apiDocs:                page("api_docs.html"),
+	}
+	if err := errors.Join(errs...); err != nil {
 		return nil, err
 	}
+	return templates, nil
+}
 
-	adminActions, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/admin_actions.html")
+// parsePage parses a page template on a clone of base and checks that it
+// defines content and can be escaped
+func parsePage(base *template.Template, files fs.FS, name string) (*template.Template, error) {
+	t, err := template.Must(base.Clone()).ParseFS(files, name)
 	if err != nil {
 		return nil, err
 	}
-
-	adminAdmins, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/admin_admins.html")
-	if err != nil {
+	if t.Lookup("content") == nil {
+		return nil, fmt.Errorf("does not define the \"content\" template")
+	}
+	// html/template escapes a template on its first execution. Executing it
+	// without data fails on the first field of the page's content, but only
+	// after escaping, whose errors are the ones that matter here.
+	var escapeErr *template.Error
+	if err := t.Execute(io.Discard, PageData{}); errors.As(err, &escapeErr) {
 		return nil, err
 	}
-
-	return &Templates{
-		index:            index,
-		repos:            repos,
-		repoDetail:       repoDetail,
-		report:           report,
-		admin:            admin,
-		adminRepos:       adminRepos,
-		adminSubscribers: adminSubscribers,
-		adminActions:     adminActions,
-		adminAdmins:      adminAdmins,
-	}, nil
+	return t, nil
 }
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"net/http"
+)
+
+// themeCookie holds the color theme chosen in the browser, which persists it
+// for a year. Without it the UI is dark.
+const themeCookie = "theme"
+
+// themeCookieMaxAge is how long a chosen theme is remembered, in seconds
+const themeCookieMaxAge = 365 * 24 * 60 * 60
+
+// theme returns the color theme of the request, "dark" or "light"
+func theme(r *http.Request) string {
+	if c, err := r.Cookie(themeCookie); err == nil && c.Value == "light" {
+		return "light"
+	}
+	return "dark"
+}
+
+// handleTheme switches the browser's color theme and returns to the page the
+// form was on. It only sets a cookie, so anonymous readers and read-only
+// mirrors can use it too.
+func (s *Server) handleTheme(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	value := r.FormValue("theme")
+	if value != "dark" && value != "light" {
+		http.Error(w, "Unknown theme: "+value, http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     themeCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   themeCookieMaxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
+
+// plainCookie is set while the browser shows pages as plain HTML, without
+// the stylesheet, for screen readers and text browsers
+const plainCookie = "plain"
+
+// plain reports whether the request asks for pages without the stylesheet
+func plain(r *http.Request) bool {
+	c, err := r.Cookie(plainCookie)
+	return err == nil && c.Value == "on"
+}
+
+// handlePlain switches plain HTML rendering on or off and returns to the
+// page the form was on. Like the theme, it is kept in a cookie.
+func (s *Server) handlePlain(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     plainCookie,
+		Value:    "on",
+		Path:     "/",
+		MaxAge:   themeCookieMaxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	switch value := r.FormValue("plain"); value {
+	case "on":
+	case "off":
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	default:
+		http.Error(w, "Unknown plain mode: "+value, http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
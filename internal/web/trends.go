@@ -0,0 +1,188 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/git"
+	"github.com/perbu/activity/internal/service"
+)
+
+// maxTrendWeeks limits how far back the trend charts reach
+const maxTrendWeeks = 52
+
+// maxReleases is how many tags the repo page's release timeline lists
+const maxReleases = 10
+
+// Chart geometry in SVG user units. Each week gets a fixed-width slot and the
+// SVG is scaled to the container width with preserveAspectRatio="none".
+const (
+	chartSlotWidth = 10
+	chartBarWidth  = 8
+	chartHeight    = 100
+)
+
+// TrendPoint holds aggregated activity for a single ISO week
+type TrendPoint struct {
+	Week      string `json:"week"`
+	WeekStart string `json:"week_start"`
+	Commits   int    `json:"commits"`
+	Authors   int    `json:"authors"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	HasReport bool   `json:"has_report"`
+}
+
+// TrendsResponse is the JSON payload served at /repos/{name}/trends.json
+type TrendsResponse struct {
+	Repo   string       `json:"repo"`
+	Points []TrendPoint `json:"points"`
+}
+
+// buildTrends aggregates stored weekly reports into a continuous weekly series.
+// The series ends at the most recent report and covers at most maxTrendWeeks
+// weeks; weeks without a report are included with zero values.
+// Authors are counted under their canonical names in authorMap.
+// Returns points ordered oldest to newest.
+func buildTrends(reports []*db.WeeklyReport, authorMap git.AuthorMap) []TrendPoint {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	byWeek := make(map[string]*db.WeeklyReport)
+	first, last := reports[0], reports[0]
+	for _, r := range reports {
+		byWeek[git.FormatISOWeek(r.Year, r.Week)] = r
+		if r.WeekStart.Before(first.WeekStart) {
+			first = r
+		}
+		if r.WeekStart.After(last.WeekStart) {
+			last = r
+		}
+	}
+
+	start := first.WeekStart
+	if earliest := last.WeekStart.AddDate(0, 0, -7*(maxTrendWeeks-1)); start.Before(earliest) {
+		start = earliest
+	}
+
+	var points []TrendPoint
+	for _, yw := range git.WeeksInRange(start, last.WeekStart) {
+		weekStart, _ := git.ISOWeekBounds(yw[0], yw[1])
+		label := git.FormatISOWeek(yw[0], yw[1])
+		point := TrendPoint{
+			Week:      label,
+			WeekStart: weekStart.Format("2006-01-02"),
+		}
+		if r, ok := byWeek[label]; ok {
+			point.HasReport = true
+			point.Commits = r.CommitCount
+			if r.Metadata.Valid {
+				var metadata service.ReportMetadata
+				if err := json.Unmarshal([]byte(r.Metadata.String), &metadata); err == nil {
+					metadata.ResolveAuthors(authorMap)
+					point.Authors = len(metadata.Authors)
+					point.Additions = metadata.Additions
+					point.Deletions = metadata.Deletions
+				}
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// buildTrendCharts converts a trend series into SVG chart view models
+func buildTrendCharts(points []TrendPoint) []TrendChart {
+	if len(points) == 0 {
+		return nil
+	}
+
+	commits := make([]int, len(points))
+	authors := make([]int, len(points))
+	additions := make([]int, len(points))
+	deletions := make([]int, len(points))
+	for i, p := range points {
+		commits[i] = p.Commits
+		authors[i] = p.Authors
+		additions[i] = p.Additions
+		deletions[i] = p.Deletions
+	}
+
+	return []TrendChart{
+		barChart("Commits per week", "commits", points, commits),
+		barChart("Active authors", "authors", points, authors),
+		churnChart(points, additions, deletions),
+	}
+}
+
+// barChart builds a simple bar chart scaled to the series maximum
+func barChart(title, unit string, points []TrendPoint, values []int) TrendChart {
+	maxVal := 1
+	for _, v := range values {
+		maxVal = max(maxVal, v)
+	}
+
+	chart := TrendChart{
+		Title:  title,
+		Max:    maxVal,
+		Width:  len(points) * chartSlotWidth,
+		Height: chartHeight,
+		First:  points[0].Week,
+		Last:   points[len(points)-1].Week,
+	}
+	for i, v := range values {
+		h := scaleBar(v, maxVal, chartHeight)
+		chart.Bars = append(chart.Bars, ChartBar{
+			X:      i*chartSlotWidth + (chartSlotWidth-chartBarWidth)/2,
+			Y:      chartHeight - h,
+			Width:  chartBarWidth,
+			Height: h,
+			Label:  fmt.Sprintf("%s: %d %s", points[i].Week, v, unit),
+		})
+	}
+	return chart
+}
+
+// churnChart builds a mirrored chart: additions grow up from the midline and
+// deletions grow down from it, both scaled to the largest single value
+func churnChart(points []TrendPoint, additions, deletions []int) TrendChart {
+	maxVal := 1
+	for i := range additions {
+		maxVal = max(maxVal, additions[i], deletions[i])
+	}
+
+	mid := chartHeight / 2
+	chart := TrendChart{
+		Title:   "Churn (lines added / deleted)",
+		Max:     maxVal,
+		Width:   len(points) * chartSlotWidth,
+		Height:  chartHeight,
+		First:   points[0].Week,
+		Last:    points[len(points)-1].Week,
+		Midline: mid,
+	}
+	for i := range points {
+		x := i*chartSlotWidth + (chartSlotWidth-chartBarWidth)/2
+		label := fmt.Sprintf("%s: +%d / -%d lines", points[i].Week, additions[i], deletions[i])
+		up := scaleBar(additions[i], maxVal, mid)
+		down := scaleBar(deletions[i], maxVal, mid)
+		chart.Bars = append(chart.Bars, ChartBar{
+			X: x, Y: mid - up, Width: chartBarWidth, Height: up, Label: label, Class: "chart-bar-add",
+		})
+		chart.Bars = append(chart.Bars, ChartBar{
+			X: x, Y: mid, Width: chartBarWidth, Height: down, Label: label, Class: "chart-bar-del",
+		})
+	}
+	return chart
+}
+
+// scaleBar scales a value to a bar height, keeping non-zero values visible
+func scaleBar(value, maxVal, height int) int {
+	h := value * height / maxVal
+	if value > 0 && h < 2 {
+		h = 2
+	}
+	return h
+}
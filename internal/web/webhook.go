@@ -0,0 +1,56 @@
+package web
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/perbu/activity/internal/email"
+)
+
+// maxWebhookBody limits the size of a webhook request. SendGrid batches
+// events into posts well below this.
+const maxWebhookBody = 5 << 20
+
+// handleSendGridWebhook receives delivery events from SendGrid's signed event
+// webhook, records them for the admin delivery stats and suppresses
+// hard-bounced subscribers. It is disabled without a verification key.
+func (s *Server) handleSendGridWebhook(w http.ResponseWriter, r *http.Request) {
+	verifier := s.webhookVerifier.Load()
+	if verifier == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	signature := r.Header.Get(email.SignatureHeader)
+	timestamp := r.Header.Get(email.TimestampHeader)
+	if err := verifier.Verify(signature, timestamp, payload); err != nil {
+		slog.Warn("Rejected SendGrid webhook", "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := email.ParseEvents(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.services.Newsletter.RecordEvents(r.Context(), events)
+	if err != nil {
+		// SendGrid retries failed posts, and recorded events are not duplicated
+		slog.Error("Failed to record SendGrid events", "error", err)
+		http.Error(w, "Failed to record events", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("SendGrid events received", "events", len(events), "recorded", result.Recorded,
+		"suppressed", result.Suppressed, "ignored", result.Ignored)
+	w.WriteHeader(http.StatusNoContent)
+}
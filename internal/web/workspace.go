@@ -0,0 +1,238 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/perbu/activity/internal/db"
+	"github.com/perbu/activity/internal/service"
+)
+
+// handleWorkspaceSwitch selects the workspace shown in the UI. The choice is
+// kept in a cookie; API token requests are bound to their token's workspace,
+// and requests to a workspace subdomain to that workspace.
+func (s *Server) handleWorkspaceSwitch(w http.ResponseWriter, r *http.Request) {
+	if !canSwitchWorkspace(r) {
+		http.Error(w, "This workspace cannot be switched from here", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	ws, err := s.services.Workspace.Get(r.Context(), r.FormValue("workspace"))
+	if err != nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	setWorkspaceCookie(w, ws.Name)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleAdminWorkspaces serves the workspace and API token management page
+func (s *Server) handleAdminWorkspaces(w http.ResponseWriter, r *http.Request) {
+	s.renderAdminWorkspaces(w, r, "")
+}
+
+// renderAdminWorkspaces renders the workspace page, showing newToken if a
+// token was just created. Only the workspaces the user is an admin of are
+// listed.
+func (s *Server) renderAdminWorkspaces(w http.ResponseWriter, r *http.Request, newToken string) {
+	user := GetUser(r)
+	isolated := !canSwitchWorkspace(r)
+	var workspaces []*db.Workspace
+	if !isolated {
+		var err error
+		workspaces, err = s.services.Workspace.ListAdministered(r.Context(), user.Email)
+		if err != nil {
+			s.renderError(w, r, "Failed to load workspaces", err)
+			return
+		}
+	}
+	tokens, err := s.services.Workspace.ListTokens(r.Context())
+	if err != nil {
+		s.renderError(w, r, "Failed to load API tokens", err)
+		return
+	}
+
+	content := AdminWorkspacesData{NewToken: newToken, Isolated: isolated}
+	if ws := GetWorkspace(r); ws != nil {
+		content.Current = ws.Name
+	}
+	for _, ws := range workspaces {
+		content.Workspaces = append(content.Workspaces, WorkspaceSummary{
+			Name:      ws.Name,
+			CreatedAt: ws.CreatedAt.Format("2006-01-02"),
+			Default:   ws.ID == db.DefaultWorkspaceID,
+		})
+		if ws.ID == db.DefaultWorkspaceID {
+			content.CanCreate = true
+		}
+	}
+	for _, t := range tokens {
+		summary := APITokenSummary{
+			ID:         t.ID,
+			Name:       t.Name,
+			CreatedAt:  t.CreatedAt.Format("2006-01-02"),
+			CreatedBy:  "system",
+			LastUsedAt: "never",
+		}
+		if t.CreatedBy.Valid {
+			summary.CreatedBy = t.CreatedBy.String
+		}
+		if t.LastUsedAt.Valid {
+			summary.LastUsedAt = t.LastUsedAt.Time.Format("2006-01-02 15:04")
+		}
+		content.Tokens = append(content.Tokens, summary)
+	}
+
+	data := PageData{
+		Title:     "Admin - Workspaces",
+		ActiveNav: "admin",
+		User:      user,
+		Content:   content,
+	}
+
+	s.render(w, r, s.templates.adminWorkspaces, data)
+}
+
+// handleAdminWorkspaceAdd handles creating a workspace. Only admins of the
+// default workspace may create one, and the creating admin becomes the first
+// admin of the new workspace. Workspaces are only created and deleted from
+// outside a workspace subdomain, which is isolated from the other workspaces.
+func (s *Server) handleAdminWorkspaceAdd(w http.ResponseWriter, r *http.Request) {
+	if !canSwitchWorkspace(r) {
+		http.Error(w, "Forbidden: manage workspaces from the main domain", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	user := GetUser(r)
+	if !s.isWorkspaceAdmin(r, db.DefaultWorkspaceID) {
+		http.Error(w, "Forbidden: only admins of the default workspace can create workspaces", http.StatusForbidden)
+		return
+	}
+	if _, err := s.services.Workspace.Create(r.Context(), name, user.Email); err != nil {
+		if errors.Is(err, service.ErrNotWorkspaceAdmin) {
+			http.Error(w, "Forbidden: only admins of the default workspace can create workspaces", http.StatusForbidden)
+			return
+		}
+		slog.Error("Failed to create workspace", "name", name, "error", err)
+		http.Error(w, "Failed to create workspace: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.audit(r, auditWorkspaceAdd, name, "")
+
+	http.Redirect(w, r, "/admin/workspaces", http.StatusSeeOther)
+}
+
+// handleAdminWorkspaceRemove handles deleting a workspace with all its data.
+// Only admins of that workspace may delete it; being an admin of the current
+// workspace is not enough.
+func (s *Server) handleAdminWorkspaceRemove(w http.ResponseWriter, r *http.Request) {
+	if !canSwitchWorkspace(r) {
+		http.Error(w, "Forbidden: manage workspaces from the main domain", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	if ws := GetWorkspace(r); ws != nil && ws.Name == name {
+		http.Error(w, "Cannot delete the current workspace; switch to another one first", http.StatusBadRequest)
+		return
+	}
+
+	target, err := s.services.Workspace.Get(r.Context(), name)
+	if err != nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+	if !s.isWorkspaceAdmin(r, target.ID) {
+		http.Error(w, "Forbidden: you are not an admin of workspace "+name, http.StatusForbidden)
+		return
+	}
+
+	if err := s.services.Workspace.Delete(r.Context(), name, GetUser(r).Email); err != nil {
+		if errors.Is(err, service.ErrNotWorkspaceAdmin) {
+			http.Error(w, "Forbidden: you are not an admin of workspace "+name, http.StatusForbidden)
+			return
+		}
+		slog.Error("Failed to delete workspace", "name", name, "error", err)
+		http.Error(w, "Failed to delete workspace: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditWorkspaceRemove, name, "")
+
+	http.Redirect(w, r, "/admin/workspaces", http.StatusSeeOther)
+}
+
+// isWorkspaceAdmin reports whether the user is an admin of the workspace.
+// RequireAdmin only checks the request's workspace, which the user picks with
+// the switcher or the path prefix, so managing another workspace needs this.
+func (s *Server) isWorkspaceAdmin(r *http.Request, workspaceID int64) bool {
+	user := GetUser(r)
+	if user == nil || user.Token {
+		return false
+	}
+	isAdmin, err := s.services.Workspace.IsAdminOf(r.Context(), workspaceID, user.Email)
+	if err != nil {
+		slog.Error("Failed to check workspace admin status", "workspace_id", workspaceID, "user", user.Email, "error", err)
+		return false
+	}
+	return isAdmin
+}
+
+// handleAdminTokenAdd handles creating an API token for the current workspace
+func (s *Server) handleAdminTokenAdd(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	user := GetUser(r)
+	value, _, err := s.services.Workspace.CreateToken(r.Context(), name, user.Email)
+	if err != nil {
+		slog.Error("Failed to create API token", "name", name, "error", err)
+		http.Error(w, "Failed to create API token: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.audit(r, auditTokenAdd, name, "")
+
+	// Render directly instead of redirecting: the token value is not stored
+	s.renderAdminWorkspaces(w, r, value)
+}
+
+// handleAdminTokenRevoke handles revoking an API token of the current workspace
+func (s *Server) handleAdminTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.services.Workspace.RevokeToken(r.Context(), id); err != nil {
+		slog.Error("Failed to revoke API token", "id", id, "error", err)
+		http.Error(w, "Failed to revoke API token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auditTokenRevoke, "#"+strconv.FormatInt(id, 10), "")
+
+	http.Redirect(w, r, "/admin/workspaces", http.StatusSeeOther)
+}
@@ -1,37 +1,55 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/perbu/activity/internal/config"
 	"github.com/perbu/activity/internal/db"
 	"github.com/perbu/activity/internal/github"
+	"github.com/perbu/activity/internal/grpcapi"
+	"github.com/perbu/activity/internal/scheduler"
+	"github.com/perbu/activity/internal/secretref"
+	"github.com/perbu/activity/internal/secrets"
 	"github.com/perbu/activity/internal/service"
+	"github.com/perbu/activity/internal/telemetry"
 	"github.com/perbu/activity/internal/web"
 )
 
 //go:embed .version
 var version string
 
+// logLevel is the level of the global logger, changed when the config is reloaded
+var logLevel = new(slog.LevelVar)
+
 // setupLogger configures the global slog logger based on debug setting
 func setupLogger(debug bool) {
-	level := slog.LevelInfo
-	if debug {
-		level = slog.LevelDebug
-	}
-
+	setLogLevel(debug)
 	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: level,
+		Level: logLevel,
 	})
 	slog.SetDefault(slog.New(handler))
 }
 
+// setLogLevel switches the global logger between info and debug logging
+func setLogLevel(debug bool) {
+	if debug {
+		logLevel.Set(slog.LevelDebug)
+	} else {
+		logLevel.Set(slog.LevelInfo)
+	}
+}
+
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -51,14 +69,72 @@ func run() error {
 		dataDir    = flag.String("data-dir", "", "Data directory")
 		debug      = flag.Bool("debug", false, "Enable debug logging")
 		showVer    = flag.Bool("version", false, "Show version")
+		demo       = flag.Bool("demo", false, "Seed sample repositories and reports (no cloning or LLM calls) and skip background jobs")
 	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Commands:")
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve                      Run the web server (default)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  init [--force]             Create the config file interactively and optionally add a first repository")
+		fmt.Fprintln(flag.CommandLine.Output(), "  analyze [repo...]          Analyze commits since the last run and append them to this week's reports")
+		fmt.Fprintln(flag.CommandLine.Output(), "  ask [--reports] <repo> <q> Ask an agent (or, with --reports, the stored reports) about a repository")
+		fmt.Fprintln(flag.CommandLine.Output(), "  fsck [--repair]            Check repository clones against the database (--repair: re-clone missing ones)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  repo describe [repo...]    Regenerate descriptions from READMEs (--auto-refresh: only changed ones)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  repo set-branch <repo> <b> Follow another branch, e.g. after upstream renamed master to main")
+		fmt.Fprintln(flag.CommandLine.Output(), "  repo reclone <repo>        Replace a repository's clone with a fresh one, e.g. when it is corrupted")
+		fmt.Fprintln(flag.CommandLine.Output(), "  report generate [repo]     Generate weekly reports (--week, --since, --force; --dry-run: estimate cost)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  report diff <repo> [week]  Compare a weekly report (default: latest) with the previous week's")
+		fmt.Fprintln(flag.CommandLine.Output(), "  report list [repo]         List weekly reports, newest first (--year, --min-commits, --limit, --page)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  newsletter send            Send newsletters now (--since, --dry-run; --to: one subscriber; --test: only to --to)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  newsletter subscriber      Import subscribers from CSV (import [--dry-run] <file>) or export them (export [file])")
+		fmt.Fprintln(flag.CommandLine.Output(), "  config check               Validate the config and test LLM, email and GitHub App credentials")
+		fmt.Fprintln(flag.CommandLine.Output(), "  config show [--effective]  Print the config as YAML (--effective: each setting with its source and env var)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db backup                  Write a gzipped JSON backup of the database to a timestamped file")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db export                  Export all tables as JSON")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db import <file>           Import a JSON export or backup into an empty database")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db prune                   Delete data older than the configured retention")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db status                  List the schema migrations and which are applied")
+		fmt.Fprintln(flag.CommandLine.Output(), "  db migrate [--to=N]        Apply pending migrations, or roll back to version N")
+		fmt.Fprintln(flag.CommandLine.Output(), "  secrets set <name> [file]  Store a secret (e.g. github.private_key) encrypted in the database")
+		fmt.Fprintln(flag.CommandLine.Output(), "  secrets list|delete        List stored secrets, or delete one")
+		fmt.Fprintln(flag.CommandLine.Output(), "  secrets rotate             Re-encrypt secrets with the current master key")
+		fmt.Fprintln(flag.CommandLine.Output(), "  secrets keygen             Print a new random master key")
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	command := flag.Arg(0)
+	if command != "" && command != "serve" && command != "init" && command != "analyze" && command != "ask" && command != "fsck" && command != "repo" && command != "report" && command != "newsletter" && command != "config" && command != "db" && command != "secrets" {
+		flag.Usage()
+		return fmt.Errorf("unknown command: %s", command)
+	}
+
 	if *showVer {
 		fmt.Println(strings.TrimSpace(version))
 		return nil
 	}
 
+	// Generating a master key needs neither config nor database
+	if command == "secrets" && flag.Arg(1) == "keygen" {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		return nil
+	}
+
+	// The init wizard writes the config file the rest of the setup reads, and
+	// adds the first repository, if any, once the database is open
+	var firstRepo *service.AddOptions
+	if command == "init" {
+		var err error
+		if firstRepo, err = runInit(*configPath, flag.Args()[1:]); err != nil || firstRepo == nil {
+			return err
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -68,16 +144,48 @@ func run() error {
 	// Override data dir if specified
 	if *dataDir != "" {
 		cfg.DataDir = *dataDir
+		cfg.SetSource("data_dir", config.SourceFlag)
 	}
 
 	// Override debug if specified via CLI flag
 	if *debug {
 		cfg.Debug = true
+		cfg.SetSource("debug", config.SourceFlag)
 	}
 
 	// Set up slog based on debug setting
 	setupLogger(cfg.Debug)
 	slog.Info("starting activity", "version", strings.TrimSpace(version))
+	for _, name := range cfg.UnknownEnv() {
+		slog.Warn("Environment variable matches no config setting", "name", name)
+	}
+
+	if command == "config" {
+		return runConfig(cfg, flag.Args()[1:])
+	}
+
+	// Replace references to secrets in Vault or a cloud secret manager with the
+	// secrets themselves
+	if err := cfg.ResolveSecrets(context.Background(), secretref.Resolve); err != nil {
+		return err
+	}
+
+	// The db and secrets commands only need the database, so they run with an
+	// otherwise incomplete config
+	if command != "db" && command != "secrets" {
+		if err := cfg.Validate(); err != nil {
+			if !*demo {
+				return fmt.Errorf("invalid config:\n%w", err)
+			}
+			// The demo needs no LLM, so it runs without credentials
+			slog.Warn("Invalid config, ignored in demo mode", "error", err)
+		}
+	}
+
+	// The demo clones nothing, so any directory will do
+	if *demo && cfg.DataDir == "" {
+		cfg.DataDir = filepath.Join(os.TempDir(), "activity-demo")
+	}
 
 	// Require data directory for git repository storage
 	if cfg.DataDir == "" {
@@ -95,22 +203,44 @@ func run() error {
 		return fmt.Errorf("database DSN must be specified via config file or DATABASE_URL environment variable")
 	}
 
-	// Open database
+	// Open database. The migration commands leave the schema alone, so they
+	// can show or roll back what is applied.
 	database, err := db.Open(db.OpenConfig{
 		DSN:                    dsn,
 		MaxOpenConns:           cfg.Database.MaxOpenConns,
 		MaxIdleConns:           cfg.Database.MaxIdleConns,
 		ConnMaxLifetimeSeconds: cfg.Database.ConnMaxLifetimeSeconds,
+		SkipMigrations:         command == "db" && (flag.Arg(1) == "status" || flag.Arg(1) == "migrate"),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer database.Close()
 
+	if command == "db" {
+		return runDB(database, cfg, flag.Args()[1:])
+	}
+	if command == "secrets" {
+		return runSecrets(service.NewSecretService(database, cfg), flag.Args()[1:])
+	}
+
+	// Export traces if configured, flushing buffered spans on exit
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg, strings.TrimSpace(version))
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
 	// Initialize GitHub App token provider if configured
 	var tokenProvider *github.TokenProvider
 	if cfg.HasGitHubApp() {
-		privateKey, err := cfg.GetGitHubPrivateKey()
+		privateKey, err := service.NewSecretService(database, cfg).GitHubPrivateKey(context.Background())
 		if err != nil {
 			return fmt.Errorf("failed to get GitHub App private key: %w", err)
 		}
@@ -123,12 +253,150 @@ func run() error {
 	// Create services
 	services := service.New(database, cfg, tokenProvider)
 
+	if *demo {
+		result, err := services.Demo.Seed(context.Background())
+		if err != nil {
+			return err
+		}
+		slog.Info("Demo mode", "seeded_repos", result.Repos, "seeded_reports", result.Reports)
+	}
+
+	switch command {
+	case "init":
+		return runInitAddRepo(services, *firstRepo)
+	case "analyze":
+		return runAnalyze(services, flag.Args()[1:])
+	case "ask":
+		return runAsk(services, flag.Args()[1:])
+	case "fsck":
+		return runFsck(services, flag.Args()[1:])
+	case "repo":
+		return runRepo(services, flag.Args()[1:])
+	case "report":
+		return runReport(services, flag.Args()[1:])
+	case "newsletter":
+		return runNewsletter(services, flag.Args()[1:])
+	}
+
+	// Shut down gracefully on SIGINT/SIGTERM; a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs, except on a read-only mirror, which leaves them
+	// to the primary server, and in demo mode, whose repositories have no
+	// clones to describe
+	jobs := scheduler.New()
+	if !cfg.Web.ReadOnly && !*demo {
+		jobs.Add("prune", cfg.GetPruneInterval(), func(ctx context.Context) error {
+			_, err := services.Retention.Prune(ctx, false)
+			return err
+		})
+		jobs.Add("describe", cfg.GetDescriptionRefreshInterval(), func(ctx context.Context) error {
+			_, err := services.Repo.DescribeAll(ctx, true)
+			return err
+		})
+		jobs.Add("newsletter", cfg.GetNewsletterScheduleInterval(), func(ctx context.Context) error {
+			_, err := services.Newsletter.SendScheduled(ctx, os.Stdout)
+			return err
+		})
+		jobs.Add("stale", cfg.GetStaleCheckInterval(), func(ctx context.Context) error {
+			_, err := services.Repo.NotifyStale(ctx)
+			return err
+		})
+	}
+	jobs.OnFailure(func(ctx context.Context, name string, err error) {
+		title := fmt.Sprintf("Scheduled job %s failed: %v", name, err)
+		if _, err := database.NotifyAdmins(ctx, db.NotificationJobFailed, title, "/admin"); err != nil {
+			slog.Error("Failed to notify admins", "job", name, "error", err)
+		}
+	})
+	jobs.Start(ctx)
+
 	// Create and start web server
 	server, err := web.NewServer(database, services, cfg, *host, *port)
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 
+	// Pick up config changes without restarting the server
+	configFile, err := config.ResolvePath(*configPath)
+	if err != nil {
+		return err
+	}
+	go config.Watch(ctx, configFile, config.WatchInterval, secretref.Resolve, func(next *config.Config) {
+		// Command line flags keep precedence over the reloaded file
+		if *debug {
+			next.Debug = true
+			next.SetSource("debug", config.SourceFlag)
+		}
+		if *dataDir != "" {
+			next.DataDir = *dataDir
+		}
+
+		applied, restart := cfg.ApplyReload(next)
+		if len(restart) > 0 {
+			slog.Warn("Changed settings take effect after a restart", "settings", strings.Join(restart, ", "))
+		}
+		if len(applied) == 0 {
+			slog.Info("Config reloaded, no changes applied")
+			return
+		}
+		setLogLevel(cfg.Current().Debug)
+		jobs.SetInterval("prune", cfg.GetPruneInterval())
+		jobs.SetInterval("describe", cfg.GetDescriptionRefreshInterval())
+		jobs.SetInterval("newsletter", cfg.GetNewsletterScheduleInterval())
+		jobs.SetInterval("stale", cfg.GetStaleCheckInterval())
+		if err := server.Reload(); err != nil {
+			slog.Error("Failed to apply reloaded config to the web server", "error", err)
+		}
+		slog.Info("Config reloaded", "settings", strings.Join(applied, ", "))
+	})
+
 	slog.Info("Starting web server", "address", server.Address())
-	return server.Start()
+	serveErr := make(chan error, 2)
+	go func() { serveErr <- server.Start() }()
+
+	var grpcServer *grpcapi.Server
+	if cfg.Web.GRPCAddress != "" && cfg.Web.ReadOnly {
+		slog.Warn("Not starting the gRPC server in read-only mode", "address", cfg.Web.GRPCAddress)
+	} else if cfg.Web.GRPCAddress != "" {
+		grpcServer = grpcapi.NewServer(database, services, cfg)
+		slog.Info("Starting gRPC server", "address", grpcServer.Address())
+		go func() { serveErr <- grpcServer.Start() }()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Drain requests and scheduled jobs in parallel under one deadline
+	timeout := cfg.GetShutdownTimeout()
+	slog.Info("Shutting down", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	jobsDone := make(chan error, 1)
+	go func() { jobsDone <- jobs.Stop(shutdownCtx) }()
+	grpcDone := make(chan error, 1)
+	go func() {
+		if grpcServer != nil {
+			grpcDone <- grpcServer.Shutdown(shutdownCtx)
+		} else {
+			grpcDone <- nil
+		}
+	}()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Web server did not shut down cleanly", "error", err)
+	}
+	if err := <-grpcDone; err != nil {
+		slog.Warn("gRPC server did not shut down cleanly", "error", err)
+	}
+	if err := <-jobsDone; err != nil {
+		slog.Warn("Scheduled jobs did not finish", "error", err)
+	}
+	slog.Info("Shutdown complete")
+	return nil
 }
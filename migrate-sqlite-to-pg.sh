@@ -42,6 +42,22 @@ echo "  Done."
 
 echo "Step 2: Exporting from SQLite..."
 
+# SQLite did not enforce foreign keys, so deleting a repository or subscriber
+# could leave orphaned rows behind. PostgreSQL rejects them, so only rows whose
+# parents exist are exported; dangling source_run_id references become NULL.
+VALID_REPOS="SELECT id FROM repositories"
+VALID_RUNS="SELECT id FROM activity_runs WHERE repo_id IN ($VALID_REPOS)"
+VALID_SUBSCRIBERS="SELECT id FROM subscribers"
+
+ORPHANS=$(sqlite3 "$SQLITE_DB" "SELECT
+    (SELECT COUNT(*) FROM activity_runs WHERE repo_id NOT IN ($VALID_REPOS)) +
+    (SELECT COUNT(*) FROM weekly_reports WHERE repo_id NOT IN ($VALID_REPOS)) +
+    (SELECT COUNT(*) FROM subscriptions WHERE repo_id NOT IN ($VALID_REPOS) OR subscriber_id NOT IN ($VALID_SUBSCRIBERS)) +
+    (SELECT COUNT(*) FROM newsletter_sends WHERE activity_run_id NOT IN ($VALID_RUNS) OR subscriber_id NOT IN ($VALID_SUBSCRIBERS));")
+if [ "$ORPHANS" != "0" ]; then
+    echo "  Skipping $ORPHANS orphaned rows (parent repository, run or subscriber was deleted)"
+fi
+
 # Export repositories with boolean conversion
 sqlite3 -csv "$SQLITE_DB" "SELECT id, name, url, branch, CASE WHEN active THEN 'true' ELSE 'false' END, CASE WHEN private THEN 'true' ELSE 'false' END, description, created_at, updated_at, last_run_at, last_run_sha FROM repositories;" | clean_timestamps > "$TMPDIR/repositories.csv"
 
@@ -49,19 +65,20 @@ sqlite3 -csv "$SQLITE_DB" "SELECT id, name, url, branch, CASE WHEN active THEN '
 sqlite3 -csv "$SQLITE_DB" "SELECT id, email, created_at, created_by FROM admins;" | clean_timestamps > "$TMPDIR/admins.csv"
 
 # Export activity_runs with boolean conversion
-sqlite3 -csv "$SQLITE_DB" "SELECT id, repo_id, start_sha, end_sha, started_at, completed_at, summary, raw_data, CASE WHEN agent_mode THEN 'true' ELSE 'false' END, tool_usage_stats FROM activity_runs;" | clean_timestamps > "$TMPDIR/activity_runs.csv"
+sqlite3 -csv "$SQLITE_DB" "SELECT id, repo_id, start_sha, end_sha, started_at, completed_at, summary, raw_data, CASE WHEN agent_mode THEN 'true' ELSE 'false' END, tool_usage_stats FROM activity_runs WHERE repo_id IN ($VALID_REPOS);" | clean_timestamps > "$TMPDIR/activity_runs.csv"
 
 # Export weekly_reports with boolean conversion
-sqlite3 -csv "$SQLITE_DB" "SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count, metadata, CASE WHEN agent_mode THEN 'true' ELSE 'false' END, tool_usage_stats, created_at, updated_at, source_run_id FROM weekly_reports;" | clean_timestamps > "$TMPDIR/weekly_reports.csv"
+sqlite3 -csv "$SQLITE_DB" "SELECT id, repo_id, year, week, week_start, week_end, summary, commit_count, metadata, CASE WHEN agent_mode THEN 'true' ELSE 'false' END, tool_usage_stats, created_at, updated_at, CASE WHEN source_run_id IN ($VALID_RUNS) THEN source_run_id END FROM weekly_reports WHERE repo_id IN ($VALID_REPOS);" | clean_timestamps > "$TMPDIR/weekly_reports.csv"
 
 # Export subscribers with boolean conversion
 sqlite3 -csv "$SQLITE_DB" "SELECT id, email, CASE WHEN subscribe_all THEN 'true' ELSE 'false' END, created_at FROM subscribers;" | clean_timestamps > "$TMPDIR/subscribers.csv"
 
 # Export subscriptions
-sqlite3 -csv "$SQLITE_DB" "SELECT id, subscriber_id, repo_id, created_at FROM subscriptions;" | clean_timestamps > "$TMPDIR/subscriptions.csv"
+sqlite3 -csv "$SQLITE_DB" "SELECT id, subscriber_id, repo_id, created_at FROM subscriptions WHERE repo_id IN ($VALID_REPOS) AND subscriber_id IN ($VALID_SUBSCRIBERS);" | clean_timestamps > "$TMPDIR/subscriptions.csv"
 
-# Export newsletter_sends
-sqlite3 -csv "$SQLITE_DB" "SELECT id, subscriber_id, activity_run_id, sent_at, sendgrid_message_id FROM newsletter_sends;" | clean_timestamps > "$TMPDIR/newsletter_sends.csv"
+# Export newsletter_sends with the repository and completion time of their run,
+# which determine the weekly report (repo, ISO week) the send is recorded for
+sqlite3 -csv "$SQLITE_DB" "SELECT ns.id, ns.subscriber_id, ar.repo_id, COALESCE(ar.completed_at, ar.started_at), ns.sent_at, ns.sendgrid_message_id FROM newsletter_sends ns JOIN activity_runs ar ON ar.id = ns.activity_run_id WHERE ns.activity_run_id IN ($VALID_RUNS) AND ns.subscriber_id IN ($VALID_SUBSCRIBERS);" | clean_timestamps > "$TMPDIR/newsletter_sends.csv"
 
 echo "  Done."
 
@@ -109,9 +126,17 @@ fi
 COUNT=$(psql -d "$PG_DB" -tAc "SELECT COUNT(*) FROM subscriptions")
 echo "  subscriptions: $COUNT"
 
-# Import newsletter_sends
+# Import newsletter_sends, keeping the earliest send per subscriber and week
 if [ -s "$TMPDIR/newsletter_sends.csv" ]; then
-    psql -d "$PG_DB" -q -c "\COPY newsletter_sends(id, subscriber_id, activity_run_id, sent_at, sendgrid_message_id) FROM '$TMPDIR/newsletter_sends.csv' WITH CSV"
+    psql -d "$PG_DB" -q <<EOF
+CREATE TEMP TABLE sqlite_newsletter_sends (id INTEGER, subscriber_id INTEGER, repo_id INTEGER, run_at TIMESTAMPTZ, sent_at TIMESTAMPTZ, sendgrid_message_id TEXT);
+\COPY sqlite_newsletter_sends FROM '$TMPDIR/newsletter_sends.csv' WITH CSV
+INSERT INTO newsletter_sends (id, subscriber_id, repo_id, year, week, sent_at, sendgrid_message_id)
+SELECT DISTINCT ON (subscriber_id, repo_id, EXTRACT(ISOYEAR FROM run_at), EXTRACT(WEEK FROM run_at))
+       id, subscriber_id, repo_id, EXTRACT(ISOYEAR FROM run_at), EXTRACT(WEEK FROM run_at), sent_at, sendgrid_message_id
+FROM sqlite_newsletter_sends
+ORDER BY subscriber_id, repo_id, EXTRACT(ISOYEAR FROM run_at), EXTRACT(WEEK FROM run_at), id;
+EOF
 fi
 COUNT=$(psql -d "$PG_DB" -tAc "SELECT COUNT(*) FROM newsletter_sends")
 echo "  newsletter_sends: $COUNT"
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/perbu/activity/internal/config"
+	"github.com/perbu/activity/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// initConfig is the part of the config file the init wizard asks for, in
+// the order it is written. Everything else keeps its default.
+type initConfig struct {
+	DataDir    string          `yaml:"data_dir"`
+	Database   initDatabase    `yaml:"database,omitempty"`
+	LLM        initLLM         `yaml:"llm"`
+	Newsletter *initNewsletter `yaml:"newsletter,omitempty"`
+}
+
+type initDatabase struct {
+	DSN string `yaml:"dsn,omitempty"`
+}
+
+type initLLM struct {
+	Provider       string `yaml:"provider"`
+	Model          string `yaml:"model,omitempty"`
+	APIKey         string `yaml:"api_key,omitempty"`
+	APIKeyEnv      string `yaml:"api_key_env,omitempty"`
+	VertexProject  string `yaml:"vertex_project,omitempty"`
+	VertexLocation string `yaml:"vertex_location,omitempty"`
+	AzureEndpoint  string `yaml:"azure_endpoint,omitempty"`
+}
+
+type initNewsletter struct {
+	Enabled          bool   `yaml:"enabled"`
+	Provider         string `yaml:"provider"`
+	FromEmail        string `yaml:"from_email"`
+	FromName         string `yaml:"from_name"`
+	SendGridAPIKey   string `yaml:"sendgrid_api_key,omitempty"`
+	SendGridKeyEnv   string `yaml:"sendgrid_api_key_env,omitempty"`
+	PostmarkToken    string `yaml:"postmark_token,omitempty"`
+	PostmarkTokenEnv string `yaml:"postmark_token_env,omitempty"`
+	MailgunAPIKey    string `yaml:"mailgun_api_key,omitempty"`
+	MailgunKeyEnv    string `yaml:"mailgun_api_key_env,omitempty"`
+	MailgunDomain    string `yaml:"mailgun_domain,omitempty"`
+	Scheduled        bool   `yaml:"scheduled"`
+}
+
+// prompter asks questions on the terminal. At the end of the input every
+// question takes its default, so the wizard can be scripted.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask asks for a line of text, returning def if the answer is empty
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		fmt.Fprintln(p.out)
+		return def
+	}
+	if answer := strings.TrimSpace(p.in.Text()); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(p.ask(question+" ("+hint+")", "")) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// choose asks for one of options, returning def if the answer is empty
+func (p *prompter) choose(question string, options []string, def string) string {
+	for {
+		answer := strings.ToLower(p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), def))
+		if slices.Contains(options, answer) {
+			return answer
+		}
+		fmt.Fprintf(p.out, "Please answer one of %s.\n", strings.Join(options, ", "))
+	}
+}
+
+// secret asks for a credential, either the name of the environment variable
+// holding it or the value itself. It returns one of the two; a value is
+// anything that doesn't look like a variable name, such as a key or a
+// vault: reference.
+func (p *prompter) secret(what, defEnv string) (value, env string) {
+	answer := p.ask(what+": environment variable holding it, or the value itself", defEnv)
+	if isEnvName(answer) {
+		if _, ok := os.LookupEnv(answer); !ok {
+			fmt.Fprintf(p.out, "  Note: %s is not set in this shell; set it before starting the server.\n", answer)
+		}
+		return "", answer
+	}
+	return answer, ""
+}
+
+// isEnvName reports whether s looks like an environment variable name, such
+// as GOOGLE_API_KEY
+func isEnvName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// runInit interactively writes a config file with the data directory,
+// database, LLM provider and newsletter settings, then verifies it like
+// config check. With --force an existing file is overwritten. If the user
+// wants to add a first repository, it returns the repository to add once
+// the database is open.
+func runInit(configPath string, args []string) (*service.AddOptions, error) {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	file, err := config.ResolvePath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(file); err == nil && !*force {
+		return nil, fmt.Errorf("config file %s already exists (--force overwrites it)", file)
+	}
+
+	p := &prompter{in: bufio.NewScanner(os.Stdin), out: os.Stdout}
+	defaults := config.DefaultConfig()
+	fmt.Printf("Writing %s. Press Enter to accept the [default].\n\n", file)
+
+	cfg := initConfig{
+		DataDir: p.ask("Data directory for repository clones", "~/.local/share/activity"),
+	}
+	cfg.Database.DSN = p.ask("PostgreSQL connection string (empty: DATABASE_URL)", "")
+
+	fmt.Println()
+	cfg.LLM.Provider = p.choose("LLM provider", []string{"gemini", "vertex", "azure"}, defaults.LLM.Provider)
+	switch cfg.LLM.Provider {
+	case "gemini":
+		cfg.LLM.Model = p.ask("Model", defaults.LLM.Model)
+		cfg.LLM.APIKey, cfg.LLM.APIKeyEnv = p.secret("Gemini API key", defaults.LLM.APIKeyEnv)
+	case "vertex":
+		cfg.LLM.Model = p.ask("Model", defaults.LLM.Model)
+		cfg.LLM.VertexProject = p.ask("GCP project (empty: GOOGLE_CLOUD_PROJECT)", "")
+		cfg.LLM.VertexLocation = p.ask("GCP region (empty: GOOGLE_CLOUD_LOCATION or us-central1)", "")
+		fmt.Println("  Vertex AI uses Application Default Credentials (gcloud auth application-default login).")
+	case "azure":
+		cfg.LLM.AzureEndpoint = p.ask("Azure OpenAI endpoint, e.g. https://myresource.openai.azure.com (empty: AZURE_OPENAI_ENDPOINT)", "")
+		cfg.LLM.Model = p.ask("Deployment name", "")
+		cfg.LLM.APIKey, cfg.LLM.APIKeyEnv = p.secret("Azure OpenAI API key", "AZURE_OPENAI_API_KEY")
+	}
+
+	fmt.Println()
+	if p.confirm("Send weekly newsletters by email?", false) {
+		nc := &initNewsletter{Enabled: true}
+		nc.Provider = p.choose("Email provider", []string{"sendgrid", "postmark", "mailgun"}, defaults.Newsletter.Provider)
+		nc.FromEmail = p.ask("Sender address", defaults.Newsletter.FromEmail)
+		nc.FromName = p.ask("Sender name", defaults.Newsletter.FromName)
+		switch nc.Provider {
+		case "sendgrid":
+			nc.SendGridAPIKey, nc.SendGridKeyEnv = p.secret("SendGrid API key", defaults.Newsletter.SendGridKeyEnv)
+		case "postmark":
+			nc.PostmarkToken, nc.PostmarkTokenEnv = p.secret("Postmark server token", defaults.Newsletter.PostmarkTokenEnv)
+		case "mailgun":
+			nc.MailgunAPIKey, nc.MailgunKeyEnv = p.secret("Mailgun API key", defaults.Newsletter.MailgunKeyEnv)
+			nc.MailgunDomain = p.ask("Mailgun sending domain", "")
+		}
+		nc.Scheduled = p.confirm("Send them every Monday at each subscriber's send hour?", true)
+		cfg.Newsletter = nc
+	}
+
+	if err := writeInitConfig(file, &cfg); err != nil {
+		return nil, err
+	}
+	fmt.Printf("\nWrote %s. See config_example.yaml for the other settings.\n\nChecking the config and credentials:\n", file)
+
+	loaded, err := config.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := runConfigCheck(loaded); err != nil {
+		fmt.Println("\nFix the settings that failed and run `activity config check` again.")
+		return nil, err
+	}
+
+	fmt.Println()
+	if !p.confirm("Add a first repository now?", true) {
+		fmt.Println("Add repositories on /admin/repos once the server is running.")
+		return nil, nil
+	}
+	repo := &service.AddOptions{URL: p.ask("Clone URL, e.g. https://github.com/owner/repo.git", "")}
+	if repo.URL == "" {
+		return nil, nil
+	}
+	repo.Name = p.ask("Name", strings.TrimSuffix(path.Base(strings.TrimRight(repo.URL, "/")), ".git"))
+	repo.Branch = p.ask("Branch (empty: the remote's default branch)", "")
+	return repo, nil
+}
+
+// writeInitConfig writes the wizard's config file, creating its directory.
+// It may hold credentials, so only the owner can read it.
+func writeInitConfig(path string, cfg *initConfig) error {
+	var out bytes.Buffer
+	out.WriteString("# Written by `activity init`; see config_example.yaml for all settings\n")
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// runInitAddRepo clones the repository chosen in the init wizard
+func runInitAddRepo(services *service.Services, opts service.AddOptions) error {
+	repo, err := services.Repo.Add(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added %s. Run `activity report generate %s` for its first reports, or start the server.\n", repo.Name, repo.Name)
+	return nil
+}